@@ -0,0 +1,116 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CalendarSpec imports events from an iCalendar feed, such as a
+// change-freeze or public holiday calendar, or from a list of dates.
+// +kubebuilder:validation:XValidation:rule="has(self.url) || has(self.dates)",message="url or dates is required"
+type CalendarSpec struct {
+	// URL of the iCalendar (.ics) feed
+	// +kubebuilder:validation:Pattern=`^https?://`
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// SecretRef holds the username and password of a feed behind basic
+	// auth
+	// +optional
+	SecretRef *SecretReference `json:"secretRef,omitempty"`
+
+	// Dates are all-day events as YYYY-MM-DD, e.g. holidays missing from
+	// the feed
+	// +optional
+	Dates []CalendarDate `json:"dates,omitempty"`
+
+	// TimeZone is the IANA time zone of all-day events and of feed times
+	// without one (defaults to UTC)
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// RefreshInterval is how often the feed is fetched (defaults to 1h)
+	// +kubebuilder:validation:XValidation:rule="duration(self) >= duration('1m')",message="refreshInterval must be at least 1m"
+	// +optional
+	RefreshInterval *metav1.Duration `json:"refreshInterval,omitempty"`
+}
+
+// +kubebuilder:validation:Pattern=`^[0-9]{4}-[0-9]{2}-[0-9]{2}$`
+type CalendarDate string
+
+// CalendarEvent is a single occurrence of an event. Recurring events are
+// expanded into one entry per occurrence.
+type CalendarEvent struct {
+	// +optional
+	Summary string `json:"summary,omitempty"`
+
+	Start metav1.Time `json:"start"`
+
+	End metav1.Time `json:"end"`
+}
+
+// CalendarStatus defines the observed state of Calendar.
+type CalendarStatus struct {
+	// Events lists the ongoing and upcoming events of the next 90 days,
+	// sorted by start. They are kept if a refresh fails.
+	// +optional
+	Events []CalendarEvent `json:"events,omitempty"`
+
+	// Skipped is the number of feed events with recurrence rules that
+	// can't be expanded
+	// +optional
+	Skipped int32 `json:"skipped,omitempty"`
+
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// Conditions include Ready, which is false while the feed can't be
+	// fetched or parsed
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Next Event",type=date,JSONPath=`.status.events[0].start`
+// +kubebuilder:printcolumn:name="Last Sync",type=date,JSONPath=`.status.lastSyncTime`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// Calendar is the Schema for the calendars API.
+type Calendar struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CalendarSpec   `json:"spec,omitempty"`
+	Status CalendarStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// CalendarList contains a list of Calendar.
+type CalendarList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Calendar `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Calendar{}, &CalendarList{})
+}
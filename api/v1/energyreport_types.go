@@ -0,0 +1,99 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EnergyReportSpec is the period a report covers and the price it was
+// costed at. Reports are written by the controller once the period ends.
+type EnergyReportSpec struct {
+	Start metav1.Time `json:"start"`
+
+	End metav1.Time `json:"end"`
+
+	// PricePerKWh is the electricity price the cost was computed with
+	PricePerKWh string `json:"pricePerKWh"`
+
+	// +optional
+	Currency string `json:"currency,omitempty"`
+
+	// PoolLabel is the Server label pools were grouped by, empty for the
+	// ServerClass
+	// +optional
+	PoolLabel string `json:"poolLabel,omitempty"`
+}
+
+// EnergyUsage is the energy a server or pool used during the period
+type EnergyUsage struct {
+	// Name of the server or pool, empty for servers without one
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// EnergyWattHours is the estimated energy drawn while powered on
+	EnergyWattHours int64 `json:"energyWattHours"`
+
+	// PoweredOnSeconds is how long the servers were powered on, added up
+	PoweredOnSeconds int64 `json:"poweredOnSeconds"`
+
+	// Cost of the energy, in the report's currency
+	Cost string `json:"cost"`
+}
+
+// EnergyReportStatus holds the usage of the period.
+type EnergyReportStatus struct {
+	// +optional
+	Total EnergyUsage `json:"total,omitempty"`
+
+	// +optional
+	Pools []EnergyUsage `json:"pools,omitempty"`
+
+	// +optional
+	Servers []EnergyUsage `json:"servers,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Start",type=string,JSONPath=`.spec.start`
+// +kubebuilder:printcolumn:name="End",type=string,JSONPath=`.spec.end`
+// +kubebuilder:printcolumn:name="Energy (Wh)",type=integer,JSONPath=`.status.total.energyWattHours`
+// +kubebuilder:printcolumn:name="Cost",type=string,JSONPath=`.status.total.cost`
+// +kubebuilder:printcolumn:name="Currency",type=string,JSONPath=`.spec.currency`
+
+// EnergyReport is the Schema for the energyreports API.
+type EnergyReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   EnergyReportSpec   `json:"spec,omitempty"`
+	Status EnergyReportStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// EnergyReportList contains a list of EnergyReport.
+type EnergyReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []EnergyReport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&EnergyReport{}, &EnergyReportList{})
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HibernationPolicySpec powers a pool of servers down to a floor during
+// off-hours.
+type HibernationPolicySpec struct {
+	// Selector picks the servers of the pool. Servers excluded from
+	// autoscaling, protected by their role or kept on standby are left
+	// alone.
+	// +kubebuilder:validation:Required
+	Selector metav1.LabelSelector `json:"selector"`
+
+	// OffHours are the windows the pool hibernates in
+	// +kubebuilder:validation:MinItems=1
+	OffHours []MaintenanceWindow `json:"offHours"`
+
+	// Floor is the number of powered on servers kept on while hibernating
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Floor int32 `json:"floor,omitempty"`
+
+	// Drain cordons the node with the server's name and evicts its pods
+	// before powering off, and uncordons it once the server is restored
+	// +optional
+	Drain bool `json:"drain,omitempty"`
+}
+
+type HibernationPhase string
+
+const (
+	HibernationPhaseAwake       HibernationPhase = "Awake"
+	HibernationPhaseHibernating HibernationPhase = "Hibernating"
+)
+
+// HibernationPolicyStatus defines the observed state of HibernationPolicy.
+type HibernationPolicyStatus struct {
+	// +optional
+	Phase HibernationPhase `json:"phase,omitempty"`
+
+	// Hibernated lists the servers that were on when the pool started
+	// hibernating and are powered off for it, namespaced Servers as
+	// <namespace>/<name>. Exactly these are powered on again once the
+	// off-hours end.
+	// +optional
+	Hibernated []string `json:"hibernated,omitempty"`
+
+	// +optional
+	HibernatedAt *metav1.Time `json:"hibernatedAt,omitempty"`
+
+	// +optional
+	RestoredAt *metav1.Time `json:"restoredAt,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Floor",type=integer,JSONPath=`.spec.floor`
+// +kubebuilder:printcolumn:name="Hibernated At",type=date,JSONPath=`.status.hibernatedAt`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// HibernationPolicy is the Schema for the hibernationpolicies API.
+type HibernationPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HibernationPolicySpec   `json:"spec,omitempty"`
+	Status HibernationPolicyStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// HibernationPolicyList contains a list of HibernationPolicy.
+type HibernationPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HibernationPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HibernationPolicy{}, &HibernationPolicyList{})
+}
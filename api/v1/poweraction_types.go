@@ -0,0 +1,137 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:validation:Enum=on;off;cycle
+type PowerActionType string
+
+const (
+	PowerActionOn    PowerActionType = "on"
+	PowerActionOff   PowerActionType = "off"
+	PowerActionCycle PowerActionType = "cycle"
+)
+
+// PowerActionSpec defines a one-off power operation on a set of servers.
+// +kubebuilder:validation:XValidation:rule="has(self.selector) || has(self.servers)",message="selector or servers is required"
+type PowerActionSpec struct {
+	// Action to perform on every target
+	// +kubebuilder:validation:Required
+	Action PowerActionType `json:"action"`
+
+	// Selector picks the target servers when the action starts
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Servers lists target servers by name, in addition to the selector.
+	// Namespaced Servers may be given as <namespace>/<name>; names alone
+	// are looked up in every namespace.
+	// +optional
+	Servers []string `json:"servers,omitempty"`
+
+	// StartTime delays the action until the given time
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// Timeout is how long each target may take to reach the requested
+	// state before it is marked failed
+	// +kubebuilder:default="15m"
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+type PowerActionPhase string
+
+const (
+	PowerActionPhaseScheduled PowerActionPhase = "Scheduled"
+	PowerActionPhaseRunning   PowerActionPhase = "Running"
+	PowerActionPhaseSucceeded PowerActionPhase = "Succeeded"
+	PowerActionPhaseFailed    PowerActionPhase = "Failed"
+)
+
+type PowerActionTargetPhase string
+
+const (
+	PowerActionTargetPoweringOff PowerActionTargetPhase = "PoweringOff"
+	PowerActionTargetPoweringOn  PowerActionTargetPhase = "PoweringOn"
+	PowerActionTargetSucceeded   PowerActionTargetPhase = "Succeeded"
+	PowerActionTargetFailed      PowerActionTargetPhase = "Failed"
+)
+
+// PowerActionTargetStatus is the result of the action on a single server.
+type PowerActionTargetStatus struct {
+	Name string `json:"name"`
+
+	// Namespace of the server, empty for cluster-scoped Servers
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	Phase PowerActionTargetPhase `json:"phase"`
+
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// +optional
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+}
+
+// PowerActionStatus defines the observed state of PowerAction.
+type PowerActionStatus struct {
+	// +optional
+	Phase PowerActionPhase `json:"phase,omitempty"`
+
+	// +optional
+	Targets []PowerActionTargetStatus `json:"targets,omitempty"`
+
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Action",type=string,JSONPath=`.spec.action`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// PowerAction is the Schema for the poweractions API.
+type PowerAction struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PowerActionSpec   `json:"spec,omitempty"`
+	Status PowerActionStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PowerActionList contains a list of PowerAction.
+type PowerActionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PowerAction `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PowerAction{}, &PowerActionList{})
+}
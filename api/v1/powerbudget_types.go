@@ -0,0 +1,96 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PowerBudgetSpec limits the servers powered on at once on a rack or
+// circuit.
+// +kubebuilder:validation:XValidation:rule="has(self.maxPoweredOn) || has(self.maxWatts)",message="maxPoweredOn or maxWatts is required"
+// +kubebuilder:validation:XValidation:rule="!has(self.maxWatts) || has(self.serverWatts)",message="serverWatts is required with maxWatts"
+type PowerBudgetSpec struct {
+	// Selector picks the servers sharing the budget, e.g. by a rack label
+	// +kubebuilder:validation:Required
+	Selector metav1.LabelSelector `json:"selector"`
+
+	// MaxPoweredOn is the number of selected servers that may be powered on
+	// at once
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxPoweredOn *int32 `json:"maxPoweredOn,omitempty"`
+
+	// MaxWatts is the power the selected servers may draw at once
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxWatts *int32 `json:"maxWatts,omitempty"`
+
+	// ServerWatts is the draw assumed for a powered on server without
+	// spec.powerCapWatts, which counts as its draw otherwise
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ServerWatts *int32 `json:"serverWatts,omitempty"`
+}
+
+// PowerBudgetStatus defines the observed state of PowerBudget.
+type PowerBudgetStatus struct {
+	// PoweredOn is the number of selected servers that are on, booting or
+	// shutting down
+	// +optional
+	PoweredOn int32 `json:"poweredOn,omitempty"`
+
+	// Watts is the estimated draw of the powered on servers
+	// +optional
+	Watts int32 `json:"watts,omitempty"`
+
+	// Waiting lists the servers held off until the budget allows powering
+	// them on
+	// +optional
+	Waiting []string `json:"waiting,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Powered On",type=integer,JSONPath=`.status.poweredOn`
+// +kubebuilder:printcolumn:name="Max",type=integer,JSONPath=`.spec.maxPoweredOn`
+// +kubebuilder:printcolumn:name="Watts",type=integer,JSONPath=`.status.watts`
+// +kubebuilder:printcolumn:name="Max Watts",type=integer,JSONPath=`.spec.maxWatts`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// PowerBudget is the Schema for the powerbudgets API.
+type PowerBudget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PowerBudgetSpec   `json:"spec,omitempty"`
+	Status PowerBudgetStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PowerBudgetList contains a list of PowerBudget.
+type PowerBudgetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PowerBudget `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PowerBudget{}, &PowerBudgetList{})
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RebootCampaignSpec defines a rolling reboot across a set of servers.
+type RebootCampaignSpec struct {
+	// Selector picks the servers to reboot. Servers are selected once, when
+	// the campaign starts, and only servers that are powered on are rebooted.
+	// +kubebuilder:validation:Required
+	Selector metav1.LabelSelector `json:"selector"`
+
+	// MaxUnavailable is the number of servers rebooted at the same time.
+	// Failed servers count towards it until they are active again, which
+	// pauses the campaign.
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxUnavailable int `json:"maxUnavailable,omitempty"`
+
+	// Drain cordons the node with the server's name and evicts its pods
+	// before powering off, and uncordons it once the server is back
+	// +optional
+	Drain bool `json:"drain,omitempty"`
+
+	// MaintenanceWindow restricts when new reboots are started. Reboots
+	// in progress are always finished.
+	// +optional
+	MaintenanceWindow *MaintenanceWindow `json:"maintenanceWindow,omitempty"`
+
+	// FreezeCalendar names a Calendar whose events, e.g. a change freeze,
+	// hold back new reboots even while the maintenance window is open
+	// +optional
+	FreezeCalendar string `json:"freezeCalendar,omitempty"`
+}
+
+// MaintenanceWindow is a recurring daily window in UTC, or the events of a
+// Calendar.
+// +kubebuilder:validation:XValidation:rule="has(self.start) != has(self.calendar)",message="exactly one of start or calendar is required"
+type MaintenanceWindow struct {
+	// Start is the time of day the window opens, as HH:MM in UTC
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	// +optional
+	Start string `json:"start,omitempty"`
+
+	// Duration of the window, e.g. 4h
+	// +optional
+	Duration metav1.Duration `json:"duration,omitempty"`
+
+	// Days the window opens on (defaults to every day)
+	// +optional
+	Days []Weekday `json:"days,omitempty"`
+
+	// Calendar names a Calendar whose events are the window, instead of a
+	// daily start and duration
+	// +optional
+	Calendar string `json:"calendar,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=Mon;Tue;Wed;Thu;Fri;Sat;Sun
+type Weekday string
+
+type CampaignPhase string
+
+const (
+	CampaignPhaseRunning   CampaignPhase = "Running"
+	CampaignPhaseCompleted CampaignPhase = "Completed"
+	CampaignPhaseFailed    CampaignPhase = "Failed"
+)
+
+type ServerRebootPhase string
+
+const (
+	ServerRebootPending     ServerRebootPhase = "Pending"
+	ServerRebootDraining    ServerRebootPhase = "Draining"
+	ServerRebootPoweringOff ServerRebootPhase = "PoweringOff"
+	ServerRebootPoweringOn  ServerRebootPhase = "PoweringOn"
+	ServerRebootCompleted   ServerRebootPhase = "Completed"
+	ServerRebootSkipped     ServerRebootPhase = "Skipped"
+	ServerRebootFailed      ServerRebootPhase = "Failed"
+)
+
+// ServerRebootStatus is the progress of a single server in a campaign.
+type ServerRebootStatus struct {
+	Name string `json:"name"`
+
+	// Namespace of the server, empty for cluster-scoped Servers
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	Phase ServerRebootPhase `json:"phase"`
+
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// +optional
+	StartedAt *metav1.Time `json:"startedAt,omitempty"`
+
+	// +optional
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+}
+
+// RebootCampaignStatus defines the observed state of RebootCampaign.
+type RebootCampaignStatus struct {
+	// +optional
+	Phase CampaignPhase `json:"phase,omitempty"`
+
+	// +optional
+	Servers []ServerRebootStatus `json:"servers,omitempty"`
+
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+
+// RebootCampaign is the Schema for the rebootcampaigns API.
+type RebootCampaign struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RebootCampaignSpec   `json:"spec,omitempty"`
+	Status RebootCampaignStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// RebootCampaignList contains a list of RebootCampaign.
+type RebootCampaignList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RebootCampaign `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RebootCampaign{}, &RebootCampaignList{})
+}
@@ -17,6 +17,10 @@ limitations under the License.
 package v1
 
 import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -25,10 +29,114 @@ import (
 
 // ServerSpec defines the desired state of Server.
 type ServerSpec struct {
-	// +kubebuilder:validation:Enum=on;off
-	PowerState PowerState   `json:"powerState"`
-	Type       ControlType  `json:"type,omitempty"`
-	Control    ControlSpecs `json:"control,omitempty"`
+	// PowerState the server is kept in, off if unset
+	// +kubebuilder:validation:Enum=on;off;reboot
+	// +optional
+	PowerState PowerState `json:"powerState,omitempty"`
+	// Type of the control backend. It may be omitted when exactly one
+	// block of control is set and the defaulting webhook is enabled.
+	// +optional
+	Type    ControlType  `json:"type,omitempty"`
+	Control ControlSpecs `json:"control,omitempty"`
+
+	// ServerClassName is the ServerClass this server belongs to
+	// +optional
+	ServerClassName string `json:"serverClassName,omitempty"`
+
+	// Role of the server's node. Control-plane and storage servers are
+	// protected: they are outside the autoscaler node group and never
+	// powered off automatically, only by PowerActions, RebootCampaigns or
+	// edits of powerState. Defaults to worker.
+	// +optional
+	Role ServerRole `json:"role,omitempty"`
+
+	// ProviderID is the spec.providerID of the Node running on this server,
+	// used to match Nodes and autoscaler instances to the server. Defaults
+	// to matching by name.
+	// +optional
+	ProviderID string `json:"providerID,omitempty"`
+
+	// NodeName is the name of the Node running on this server, when it
+	// differs from the server's name, e.g. because the OS hostname does.
+	// Defaults to the server's name.
+	// +optional
+	NodeName string `json:"nodeName,omitempty"`
+
+	// Provisioning delegates OS provisioning to an external stack
+	// +optional
+	Provisioning *ProvisioningSpec `json:"provisioning,omitempty"`
+
+	// Storage declares the RAID layout applied before the server is powered on
+	// +optional
+	Storage *StorageSpec `json:"storage,omitempty"`
+
+	// BootPolicy forces boot sources through the BMC on each power-on
+	// +optional
+	BootPolicy *BootPolicySpec `json:"bootPolicy,omitempty"`
+
+	// BootDevice is forced persistently through the BMC before every
+	// power-on and reboot, e.g. pxe to netboot for provisioning and disk
+	// afterwards. It can't be combined with BootPolicy.
+	// +optional
+	BootDevice BootSource `json:"bootDevice,omitempty"`
+
+	// Attestation requires the server to prove its measured boot state
+	// before it is marked active
+	// +optional
+	Attestation *AttestationSpec `json:"attestation,omitempty"`
+
+	// PowerCapWatts limits the server's power draw through the DCMI or
+	// Redfish power limit of its BMC. Removing it lifts the limit.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	PowerCapWatts *int32 `json:"powerCapWatts,omitempty"`
+
+	// Watchdog arms the BMC hardware watchdog once the server has booted,
+	// so a hung OS is reset by the BMC even while the controller is down.
+	// Removing it disarms the watchdog.
+	// +optional
+	Watchdog *WatchdogSpec `json:"watchdog,omitempty"`
+
+	// HealthCheck replaces the ICMP ping that decides whether the server is
+	// up, for networks that drop ICMP or hosts that answer pings long
+	// before their services are ready
+	// +optional
+	HealthCheck *HealthCheckSpec `json:"healthCheck,omitempty"`
+
+	// ReconcileInterval overrides how often the server is checked, while
+	// waiting for a power change and once it has settled (default 60s).
+	// +kubebuilder:validation:XValidation:rule="duration(self) >= duration('5s')",message="reconcileInterval must be at least 5s"
+	// +optional
+	ReconcileInterval *metav1.Duration `json:"reconcileInterval,omitempty"`
+
+	// Timeouts of the checks of a server waiting for a power change, for
+	// servers that take longer to boot or shut down than the defaults allow
+	// +optional
+	Timeouts *TimeoutsSpec `json:"timeouts,omitempty"`
+
+	// DriftPolicy decides what happens when the server is found powered on
+	// or off out of band, e.g. by its power button. Defaults to reconcile.
+	// +optional
+	DriftPolicy DriftPolicy `json:"driftPolicy,omitempty"`
+
+	// ClusterRef names the Kubernetes cluster the server's node joins, if
+	// not the controller's own. Its node is labeled, drained and deleted and
+	// its kubelet CSRs are approved in that cluster.
+	// +optional
+	ClusterRef *ClusterReference `json:"clusterRef,omitempty"`
+}
+
+// ClusterReference names a workload cluster and the kubeconfig the
+// controller reaches it with
+type ClusterReference struct {
+	// Name of the cluster, as shown in logs and events
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// KubeconfigSecretRef references the Secret whose "kubeconfig" key holds
+	// the kubeconfig of the cluster
+	// +kubebuilder:validation:Required
+	KubeconfigSecretRef SecretReference `json:"kubeconfigSecretRef"`
 }
 
 type PowerState string
@@ -36,30 +144,125 @@ type PowerState string
 const (
 	PowerStateOn  PowerState = "on"
 	PowerStateOff PowerState = "off"
+	// PowerStateReboot reboots the server once for every generation of the
+	// spec that asks for it, and keeps it on otherwise. A server that is
+	// off is powered on instead.
+	PowerStateReboot PowerState = "reboot"
+)
+
+// Settled returns the power state a server is kept in, on for a reboot
+func (p PowerState) Settled() PowerState {
+	if p == PowerStateReboot {
+		return PowerStateOn
+	}
+	return p
+}
+
+// +kubebuilder:validation:Enum=reconcile;adopt;alert
+type DriftPolicy string
+
+const (
+	// DriftPolicyReconcile powers the server back to spec.powerState
+	DriftPolicyReconcile DriftPolicy = "reconcile"
+	// DriftPolicyAdopt sets spec.powerState to the observed power state
+	DriftPolicyAdopt DriftPolicy = "adopt"
+	// DriftPolicyAlert leaves the server as it is until spec.powerState or
+	// the policy changes
+	DriftPolicyAlert DriftPolicy = "alert"
 )
 
-// +kubebuilder:validation:Enum=wol;ipmi
+// +kubebuilder:validation:Enum=worker;control-plane;storage
+type ServerRole string
+
+const (
+	ServerRoleWorker       ServerRole = "worker"
+	ServerRoleControlPlane ServerRole = "control-plane"
+	ServerRoleStorage      ServerRole = "storage"
+)
+
+// Protected returns true for roles whose servers are never powered off
+// automatically
+func (r ServerRole) Protected() bool {
+	return r == ServerRoleControlPlane || r == ServerRoleStorage
+}
+
+// +kubebuilder:validation:Enum=wol;ipmi;maas;redfish;equinix;hetzner;esxi
 type ControlType string
 
 const (
-	ControlTypeWOL  ControlType = "wol"
-	ControlTypeIPMI ControlType = "ipmi"
+	ControlTypeWOL     ControlType = "wol"
+	ControlTypeIPMI    ControlType = "ipmi"
+	ControlTypeMAAS    ControlType = "maas"
+	ControlTypeRedfish ControlType = "redfish"
+	ControlTypeEquinix ControlType = "equinix"
+	ControlTypeHetzner ControlType = "hetzner"
+	ControlTypeESXi    ControlType = "esxi"
 )
 
 type ControlSpecs struct {
-	IPMI *IPMISpecs `json:"ipmi,omitempty"`
-	WOL  *WOLSpecs  `json:"wol,omitempty"`
+	IPMI    *IPMISpecs    `json:"ipmi,omitempty"`
+	WOL     *WOLSpecs     `json:"wol,omitempty"`
+	MAAS    *MAASSpecs    `json:"maas,omitempty"`
+	Redfish *RedfishSpecs `json:"redfish,omitempty"`
+	Equinix *EquinixSpecs `json:"equinix,omitempty"`
+	Hetzner *HetznerSpecs `json:"hetzner,omitempty"`
+	ESXi    *ESXiSpecs    `json:"esxi,omitempty"`
 }
 
 type IPMISpecs struct {
+	// Address of the BMC, an IP address or a hostname that is resolved
+	// again whenever its DNS record expires
 	// +kubebuilder:validation:Required
-	Address  string `json:"address,omitempty"`
+	Address string `json:"address,omitempty"`
+
+	// Username and Password of the BMC, prefer CredentialsSecretRef to
+	// keep them out of the Server
+	// +optional
 	Username string `json:"username,omitempty"`
+	// +optional
 	Password string `json:"password,omitempty"`
+
+	// CredentialsSecretRef points to a Secret with the username and
+	// password of the BMC, read whenever the BMC is contacted. It takes
+	// precedence over Username and Password.
+	// +optional
+	CredentialsSecretRef *CredentialsSecretReference `json:"credentialsSecretRef,omitempty"`
+}
+
+// CredentialsSecretReference points to a Secret with a username and a
+// password
+type CredentialsSecretReference struct {
+	SecretReference `json:",inline"`
+
+	// UsernameKey is the key of the username in the Secret
+	// +kubebuilder:default=username
+	// +optional
+	UsernameKey string `json:"usernameKey,omitempty"`
+
+	// PasswordKey is the key of the password in the Secret
+	// +kubebuilder:default=password
+	// +optional
+	PasswordKey string `json:"passwordKey,omitempty"`
+}
+
+// Keys returns the keys of the username and the password in the Secret,
+// "username" and "password" unless set otherwise
+func (r *CredentialsSecretReference) Keys() (usernameKey string, passwordKey string) {
+	usernameKey, passwordKey = r.UsernameKey, r.PasswordKey
+	if usernameKey == "" {
+		usernameKey = "username"
+	}
+	if passwordKey == "" {
+		passwordKey = "password"
+	}
+	return usernameKey, passwordKey
 }
 
 type WOLSpecs struct {
-	// +kubebuilder:validation:Required
+	// Address of the server's OS, an IP address or a hostname. It may be
+	// omitted for servers without a reserved address, which are reached at
+	// the address last discovered for their MAC address in status.addresses.
+	// +optional
 	Address string `json:"address,omitempty"`
 	// +kubebuilder:validation:Required
 	MACAddress string `json:"macAddress,omitempty"`
@@ -67,9 +270,235 @@ type WOLSpecs struct {
 	BroadcastAddress string `json:"broadcastAddress,omitempty"`
 
 	// +kubebuilder:default=9
-	Port         int              `json:"port,omitempty"`
-	User         string           `json:"user,omitempty"`
+	Port int `json:"port,omitempty"`
+
+	// User is the SSH user the server is shut down as. Defaults to the
+	// username key of the SSH Secret.
+	// +optional
+	User string `json:"user,omitempty"`
+
+	// SSHSecretRef points to a Secret with the private key the server is
+	// shut down with in "ssh-privatekey", and optionally the user in
+	// "username"
+	// +optional
 	SSHSecretRef *SecretReference `json:"sshSecretRef,omitempty"`
+
+	// SSHAddress is where SSH connects to shut the server down, when it
+	// differs from the address it is pinged at, e.g. a NAT gateway with a
+	// port forward to the server. Defaults to the server address.
+	// +optional
+	SSHAddress string `json:"sshAddress,omitempty"`
+
+	// SSHPort is the port SSH connects to (defaults to 22, or the port in
+	// sshAddress)
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	SSHPort int32 `json:"sshPort,omitempty"`
+
+	// Relay names the relay agent on the server's subnet, from the
+	// controller's --wol-relay-addresses. The agent sends the magic packets
+	// and pings the server, for servers behind a router.
+	// +optional
+	Relay string `json:"relay,omitempty"`
+}
+
+// MAASSpecs drives a machine through a MAAS region controller
+type MAASSpecs struct {
+	// Address of the machine used for reachability checks
+	// +kubebuilder:validation:Required
+	Address string `json:"address,omitempty"`
+
+	// Endpoint is the MAAS URL, e.g. http://maas.example.com:5240/MAAS
+	// +kubebuilder:validation:Required
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// SystemID of the machine in MAAS
+	// +kubebuilder:validation:Required
+	SystemID string `json:"systemID,omitempty"`
+
+	// APIKeySecretRef points to a Secret with the MAAS API key in "api-key"
+	// +kubebuilder:validation:Required
+	APIKeySecretRef *SecretReference `json:"apiKeySecretRef,omitempty"`
+
+	// Deploy makes power on deploy the machine and power off release it,
+	// instead of only toggling power
+	// +optional
+	Deploy bool `json:"deploy,omitempty"`
+
+	// DistroSeries to deploy (defaults to the MAAS default)
+	// +optional
+	DistroSeries string `json:"distroSeries,omitempty"`
+}
+
+// EquinixSpecs controls a hosted server through the Equinix Metal API
+type EquinixSpecs struct {
+	// Address of the device used for reachability checks
+	// +kubebuilder:validation:Required
+	Address string `json:"address,omitempty"`
+
+	// ProjectID of the project the device belongs to
+	// +kubebuilder:validation:Required
+	ProjectID string `json:"projectID,omitempty"`
+
+	// DeviceID of the device. If empty, the device is found in the project
+	// by hostname.
+	// +optional
+	DeviceID string `json:"deviceID,omitempty"`
+
+	// Hostname of the device to match when no device ID is set (defaults to
+	// the server name)
+	// +optional
+	Hostname string `json:"hostname,omitempty"`
+
+	// APITokenSecretRef points to a Secret with the API token in "api-token"
+	// +kubebuilder:validation:Required
+	APITokenSecretRef *SecretReference `json:"apiTokenSecretRef,omitempty"`
+}
+
+// HetznerSpecs controls a dedicated server through the Hetzner Robot API.
+// Robot wakes the server with Wake-on-LAN and powers it off by pressing its
+// power button.
+type HetznerSpecs struct {
+	// Address of the server used for reachability checks
+	// +kubebuilder:validation:Required
+	Address string `json:"address,omitempty"`
+
+	// ServerNumber of the server in Robot
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=1
+	ServerNumber int32 `json:"serverNumber,omitempty"`
+
+	// CredentialsSecretRef points to a Secret with the Robot webservice
+	// "username" and "password"
+	// +kubebuilder:validation:Required
+	CredentialsSecretRef *SecretReference `json:"credentialsSecretRef,omitempty"`
+
+	// ForceOff powers the server off with a long press of the power button
+	// instead of a short one, for OSes that ignore ACPI shutdown requests
+	// +optional
+	ForceOff bool `json:"forceOff,omitempty"`
+
+	// Rescue boots the server into the Robot rescue system at every power
+	// on, e.g. to install an OS from it
+	// +optional
+	Rescue *HetznerRescue `json:"rescue,omitempty"`
+}
+
+// HetznerRescue selects the rescue system a Hetzner server boots into
+type HetznerRescue struct {
+	// OS of the rescue system
+	// +kubebuilder:default=linux
+	// +optional
+	OS string `json:"os,omitempty"`
+
+	// AuthorizedKeys are the fingerprints of Robot SSH keys that may log in
+	// to the rescue system
+	// +optional
+	AuthorizedKeys []string `json:"authorizedKeys,omitempty"`
+}
+
+// ESXiSpecs shuts down a standalone ESXi host through its vSphere API, after
+// putting it into maintenance mode. A host that is off has no API, so it is
+// powered on through the IPMI, Redfish or WoL config next to this one, and
+// leaves maintenance mode once it answers again.
+type ESXiSpecs struct {
+	// Address of the host's management interface, also used for
+	// reachability checks
+	// +kubebuilder:validation:Required
+	Address string `json:"address,omitempty"`
+
+	// CredentialsSecretRef points to a Secret with the "username" and
+	// "password" of a host user allowed to change maintenance mode and shut
+	// the host down
+	// +kubebuilder:validation:Required
+	CredentialsSecretRef *SecretReference `json:"credentialsSecretRef,omitempty"`
+
+	// TLS configures how the host's HTTPS certificate is verified. Without
+	// it, the certificate is not verified, since hosts ship self-signed
+	// certificates.
+	// +optional
+	TLS *TLSSpecs `json:"tls,omitempty"`
+
+	// MaintenanceTimeout is how long to wait for the host to enter
+	// maintenance mode, which it only does once its VMs are powered off or
+	// moved. The host isn't shut down if it doesn't.
+	// +kubebuilder:default="5m"
+	// +optional
+	MaintenanceTimeout *metav1.Duration `json:"maintenanceTimeout,omitempty"`
+}
+
+// RedfishSpecs controls a server through its BMC's Redfish API
+type RedfishSpecs struct {
+	// Address of the BMC, e.g. 10.0.0.10 or https://10.0.0.10:8443
+	// +kubebuilder:validation:Required
+	Address string `json:"address,omitempty"`
+
+	// SystemID of the ComputerSystem (defaults to the first system)
+	// +optional
+	SystemID string `json:"systemID,omitempty"`
+
+	// CredentialsSecretRef points to a Secret with "username" and "password"
+	// +kubebuilder:validation:Required
+	CredentialsSecretRef *SecretReference `json:"credentialsSecretRef,omitempty"`
+
+	// TLS configures how the BMC's HTTPS certificate is verified. Without
+	// it, the certificate is not verified, since BMCs almost always ship
+	// self-signed certificates.
+	// +optional
+	TLS *TLSSpecs `json:"tls,omitempty"`
+}
+
+// TLSSpecs configures verification of a BMC's HTTPS certificate. Once set,
+// the certificate is verified unless InsecureSkipVerify is true.
+type TLSSpecs struct {
+	// CASecretRef points to a Secret with PEM certificates in "ca.crt" to
+	// verify the BMC against, e.g. its self-signed certificate. Defaults to
+	// the system roots.
+	// +optional
+	CASecretRef *SecretReference `json:"caSecretRef,omitempty"`
+
+	// ServerName is sent as SNI and checked against the certificate instead
+	// of the address, e.g. when the BMC is addressed by IP
+	// +optional
+	ServerName string `json:"serverName,omitempty"`
+
+	// InsecureSkipVerify accepts any certificate
+	// +optional
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+// StorageSpec declares the desired RAID layout of a server
+type StorageSpec struct {
+	// Controller is the Redfish Storage ID (defaults to the first controller)
+	// +optional
+	Controller string `json:"controller,omitempty"`
+
+	// Volumes is the desired set of logical volumes
+	// +kubebuilder:validation:MinItems=1
+	Volumes []VolumeSpec `json:"volumes"`
+
+	// WipeExisting deletes volumes that are not declared before applying
+	// the layout
+	// +optional
+	WipeExisting bool `json:"wipeExisting,omitempty"`
+}
+
+// VolumeSpec declares a single logical volume
+type VolumeSpec struct {
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// +kubebuilder:validation:Enum=None;RAID0;RAID1;RAID5;RAID6;RAID10
+	RAIDType string `json:"raidType"`
+
+	// Drives are the Redfish Drive IDs that make up the volume
+	// +kubebuilder:validation:MinItems=1
+	Drives []string `json:"drives"`
+
+	// CapacityGiB of the volume (defaults to all available space)
+	// +optional
+	CapacityGiB int64 `json:"capacityGiB,omitempty"`
 }
 
 // SecretReference points to a Kubernetes Secret
@@ -78,24 +507,843 @@ type SecretReference struct {
 	// +kubebuilder:validation:Required
 	Name string `json:"name"`
 
-	// Namespace of the Secret (defaults to Server's namespace, but since
-	// Server is cluster-scoped, this should be required)
+	// Namespace of the Secret. Secrets of namespaced Servers must be in the
+	// Server's namespace.
 	// +kubebuilder:validation:Required
 	Namespace string `json:"namespace"`
 }
 
+// ProvisioningSpec configures the external provisioning stack used to
+// install the server. Power state is still owned by this controller.
+type ProvisioningSpec struct {
+	// +optional
+	Tinkerbell *TinkerbellSpec `json:"tinkerbell,omitempty"`
+}
+
+// TinkerbellSpec delegates provisioning to an existing Tinkerbell stack by
+// creating a Hardware and a Workflow for the server.
+type TinkerbellSpec struct {
+	// Namespace the Hardware and Workflow are created in
+	// +kubebuilder:validation:Required
+	Namespace string `json:"namespace"`
+
+	// TemplateRef is the name of the Tinkerbell Template to run
+	// +kubebuilder:validation:Required
+	TemplateRef string `json:"templateRef"`
+
+	// MACAddress of the interface to netboot from (defaults to the WOL MAC address)
+	// +optional
+	MACAddress string `json:"macAddress,omitempty"`
+
+	// IPAddress handed out by Tinkerbell's DHCP (defaults to the server address)
+	// +optional
+	IPAddress string `json:"ipAddress,omitempty"`
+
+	// +optional
+	Netmask string `json:"netmask,omitempty"`
+
+	// +optional
+	Gateway string `json:"gateway,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=pxe;disk;cdrom;bios
+type BootSource string
+
+const (
+	BootSourcePXE   BootSource = "pxe"
+	BootSourceDisk  BootSource = "disk"
+	BootSourceCDROM BootSource = "cdrom"
+	BootSourceBIOS  BootSource = "bios"
+)
+
+// BootPolicySpec lists boot sources that are used in order, one per
+// successful boot. The last source is kept for every boot after that, so
+// [pxe, disk] netboots once and then always boots from disk.
+type BootPolicySpec struct {
+	// +kubebuilder:validation:MinItems=1
+	Sources []BootSource `json:"sources"`
+}
+
+// WatchdogAction is what the BMC does when the watchdog expires
+// +kubebuilder:validation:Enum=Reset;PowerCycle;PowerOff
+type WatchdogAction string
+
+const (
+	WatchdogActionReset      WatchdogAction = "Reset"
+	WatchdogActionPowerCycle WatchdogAction = "PowerCycle"
+	WatchdogActionPowerOff   WatchdogAction = "PowerOff"
+)
+
+// HealthCheckMethod is how a server is checked for being up
+// +kubebuilder:validation:Enum=icmp;tcp;http;ssh
+type HealthCheckMethod string
+
+const (
+	// HealthCheckICMP pings the server
+	HealthCheckICMP HealthCheckMethod = "icmp"
+	// HealthCheckTCP connects to a TCP port
+	HealthCheckTCP HealthCheckMethod = "tcp"
+	// HealthCheckHTTP sends a GET request, which must be answered with a
+	// 2xx or 3xx status
+	HealthCheckHTTP HealthCheckMethod = "http"
+	// HealthCheckSSH connects to the SSH server and waits for its banner,
+	// without logging in
+	HealthCheckSSH HealthCheckMethod = "ssh"
+)
+
+// HealthCheckSpec configures how a server is checked for being up. Servers
+// woken through a relay are pinged by the relay, so only icmp works for them.
+type HealthCheckSpec struct {
+	// Method of the check
+	// +kubebuilder:default=icmp
+	// +optional
+	Method HealthCheckMethod `json:"method,omitempty"`
+
+	// Port checked by tcp, http and ssh. Required for tcp, defaults to 80
+	// for http and 22 for ssh.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Port int32 `json:"port,omitempty"`
+
+	// Path requested by http
+	// +kubebuilder:default="/"
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Timeout of one check
+	// +kubebuilder:default="2s"
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// SuccessThreshold is the number of checks in a row that must succeed
+	// before the server counts as up. A single failed check counts as down.
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	SuccessThreshold int32 `json:"successThreshold,omitempty"`
+}
+
+// TimeoutsSpec configures how long a server may take to boot or shut down
+// before it is marked failed
+type TimeoutsSpec struct {
+	// BootTimeout is how long a powered on server may stay down before it
+	// is marked failed, counted from the first check that found it down.
+	// Unset fails the server after failureThreshold checks alone.
+	// +kubebuilder:validation:XValidation:rule="duration(self) >= duration('0s')",message="bootTimeout must not be negative"
+	// +optional
+	BootTimeout *metav1.Duration `json:"bootTimeout,omitempty"`
+
+	// ShutdownTimeout is how long a powered off server may stay up before
+	// it is marked failed, counted from the first check that found it up.
+	// Rebooted servers must go down within it too, 5m if unset.
+	// +kubebuilder:validation:XValidation:rule="duration(self) >= duration('0s')",message="shutdownTimeout must not be negative"
+	// +optional
+	ShutdownTimeout *metav1.Duration `json:"shutdownTimeout,omitempty"`
+
+	// RequeueInterval is how often the server is checked, overriding
+	// reconcileInterval. Defaults to reconcileInterval, or 60s.
+	// +kubebuilder:validation:XValidation:rule="duration(self) >= duration('5s')",message="requeueInterval must be at least 5s"
+	// +optional
+	RequeueInterval *metav1.Duration `json:"requeueInterval,omitempty"`
+
+	// FailureThreshold is the number of failed checks in a row before the
+	// server is marked failed. With a boot or shutdown timeout, the server
+	// must also have been waiting for longer than it.
+	// +kubebuilder:default=3
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+}
+
+// WatchdogSpec configures the BMC hardware watchdog. The booted OS must
+// reset the watchdog within the timeout, e.g. through the ipmi_watchdog
+// driver and systemd's RuntimeWatchdogSec.
+type WatchdogSpec struct {
+	// Timeout is the expected heartbeat: the watchdog expires if the OS
+	// doesn't reset it for this long. Redfish BMCs use their own timeout.
+	// +kubebuilder:default="5m"
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// Action is taken by the BMC when the watchdog expires
+	// +kubebuilder:default=Reset
+	// +optional
+	Action WatchdogAction `json:"action,omitempty"`
+}
+
+// AttestationSpec verifies a TPM 2.0 quote from the booted server against
+// expected PCR values. Secure boot state is measured into PCR 7.
+type AttestationSpec struct {
+	// Address of the booted OS (defaults to the server address)
+	// +optional
+	Address string `json:"address,omitempty"`
+
+	// User to run tpm2_quote as over SSH
+	// +kubebuilder:validation:Required
+	User string `json:"user"`
+
+	// SSHSecretRef points to a Secret with "ssh-privatekey"
+	// +kubebuilder:validation:Required
+	SSHSecretRef *SecretReference `json:"sshSecretRef"`
+
+	// AKHandle is the persistent handle of the attestation key
+	// +kubebuilder:default="0x81010002"
+	// +kubebuilder:validation:Pattern=`^0x[0-9a-fA-F]{8}$`
+	// +optional
+	AKHandle string `json:"akHandle,omitempty"`
+
+	// AKPublicKey is the PEM encoded public part of the attestation key,
+	// recorded when the server was enrolled
+	// +kubebuilder:validation:Required
+	AKPublicKey string `json:"akPublicKey"`
+
+	// PCRs maps a SHA-256 PCR index to its expected hex digest
+	// +kubebuilder:validation:MinProperties=1
+	PCRs map[string]string `json:"pcrs"`
+}
+
 // ServerStatus defines the observed state of Server.
 type ServerStatus struct {
 	Status CurrentStatus `json:"status,omitempty"`
 
+	// ObservedGeneration is the generation of the spec the controller last
+	// acted on. While it is behind metadata.generation, the latest spec
+	// change wasn't applied yet.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
 	// +optional
 	Message string `json:"message,omitempty"`
 
+	// Reason is a machine-readable code for the last failure, cleared once
+	// the server recovers
+	// +optional
+	Reason FailureReason `json:"reason,omitempty"`
+
 	// +optional
 	FailingSince *metav1.Time `json:"failingSince,omitempty"`
 
 	// +optional
 	FailureCount int `json:"failureCount,omitempty"`
+
+	// +optional
+	Provisioning *ProvisioningStatus `json:"provisioning,omitempty"`
+
+	// +optional
+	Storage *StorageStatus `json:"storage,omitempty"`
+
+	// +optional
+	Attestation *AttestationStatus `json:"attestation,omitempty"`
+
+	// Hardware is the inventory reported by the BMC
+	// +optional
+	Hardware *HardwareStatus `json:"hardware,omitempty"`
+
+	// Inventory is the hardware the server's OS or Redfish service reports
+	// once it booted
+	// +optional
+	Inventory *InventoryStatus `json:"inventory,omitempty"`
+
+	// +optional
+	Boot *BootStatus `json:"boot,omitempty"`
+
+	// LLDP lists the switch ports the server is cabled to
+	// +optional
+	LLDP *LLDPStatus `json:"lldp,omitempty"`
+
+	// PowerCap is the power limit and draw reported by the BMC
+	// +optional
+	PowerCap *PowerCapStatus `json:"powerCap,omitempty"`
+
+	// BMCReset records the cold resets of a BMC that stopped answering IPMI
+	// sessions
+	// +optional
+	BMCReset *BMCResetStatus `json:"bmcReset,omitempty"`
+
+	// Reboot records the last reboot asked for with powerState reboot
+	// +optional
+	Reboot *RebootStatus `json:"reboot,omitempty"`
+
+	// Thermal holds the temperatures read under the ServerClass thermal
+	// policy
+	// +optional
+	Thermal *ThermalStatus `json:"thermal,omitempty"`
+
+	// Address is the address the server was last pinged at, taken from the
+	// control spec of its type or from a DHCP lease
+	// +optional
+	Address string `json:"address,omitempty"`
+
+	// Addresses are the OS addresses leased to the server's interfaces by
+	// DHCP or found by neighbor scans, as last seen by the lease tracker
+	// +optional
+	Addresses []ServerAddress `json:"addresses,omitempty"`
+
+	// ResolvedAddresses are the IP addresses the hostnames in the server's
+	// control addresses last resolved to
+	// +optional
+	ResolvedAddresses []ResolvedAddress `json:"resolvedAddresses,omitempty"`
+
+	// Ping holds the recent round trip times and losses of the server's
+	// reachability checks
+	// +optional
+	Ping *PingStatus `json:"ping,omitempty"`
+
+	// Telemetry reports the subscriptions of the server's BMC to Redfish
+	// metric reports and events
+	// +optional
+	Telemetry *TelemetryStatus `json:"telemetry,omitempty"`
+
+	// Wear counts the server's power cycles and time powered on, which the
+	// autoscaler can spread across the fleet
+	// +optional
+	Wear *WearStatus `json:"wear,omitempty"`
+
+	// NodeFeatures are the hardware labels node-feature-discovery put on
+	// the server's Node, as last seen while the Node existed
+	// +optional
+	NodeFeatures map[string]string `json:"nodeFeatures,omitempty"`
+
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// ServerAddress is an address leased to or found on one of the server's
+// interfaces
+type ServerAddress struct {
+	Address string `json:"address"`
+
+	// MACAddress of the interface the address belongs to
+	MACAddress string `json:"macAddress"`
+
+	// Hostname the server sent with its request, if any
+	// +optional
+	Hostname string `json:"hostname,omitempty"`
+
+	// Source is the DHCP server, relay or neighbor scan the address was
+	// read from
+	Source string `json:"source"`
+
+	// Expires is when the lease ends, unset for infinite leases and
+	// neighbor scans
+	// +optional
+	Expires *metav1.Time `json:"expires,omitempty"`
+}
+
+// ResolvedAddress is the IP address a hostname resolved to
+type ResolvedAddress struct {
+	Hostname string `json:"hostname"`
+	Address  string `json:"address"`
+}
+
+// ProvisioningStatus reports the progress of external provisioning.
+type ProvisioningStatus struct {
+	// Workflow is the namespace/name of the provisioning workflow
+	Workflow string `json:"workflow,omitempty"`
+
+	// State is the workflow state reported by the provisioning stack
+	State string `json:"state,omitempty"`
+}
+
+type StoragePhase string
+
+const (
+	// StoragePhaseApplied means the layout was sent to the controller and
+	// takes effect on the next boot
+	StoragePhaseApplied StoragePhase = "applied"
+	// StoragePhaseVerified means the observed volumes match the spec
+	StoragePhaseVerified StoragePhase = "verified"
+	// StoragePhaseFailed means the layout could not be applied or verified
+	StoragePhaseFailed StoragePhase = "failed"
+)
+
+// HardwareStatus is the hardware and firmware inventory of a server
+type HardwareStatus struct {
+	// +optional
+	Manufacturer string `json:"manufacturer,omitempty"`
+
+	// +optional
+	Model string `json:"model,omitempty"`
+
+	// +optional
+	SerialNumber string `json:"serialNumber,omitempty"`
+
+	// +optional
+	BIOSVersion string `json:"biosVersion,omitempty"`
+
+	// +optional
+	BMCFirmwareVersion string `json:"bmcFirmwareVersion,omitempty"`
+
+	// CPUs is the number of logical processors
+	// +optional
+	CPUs int32 `json:"cpus,omitempty"`
+
+	// Memory is the installed system memory
+	// +optional
+	Memory *resource.Quantity `json:"memory,omitempty"`
+
+	// +optional
+	LastUpdated *metav1.Time `json:"lastUpdated,omitempty"`
+}
+
+// InventorySource is where the inventory of a server was read from
+// +kubebuilder:validation:Enum=SSH;Redfish
+type InventorySource string
+
+const (
+	// InventorySourceSSH is the running OS, read over SSH
+	InventorySourceSSH InventorySource = "SSH"
+	// InventorySourceRedfish is the system resources of the Redfish service
+	InventorySourceRedfish InventorySource = "Redfish"
+)
+
+// InventoryStatus is the hardware of a server, in more detail than its BMC's
+// FRU data in HardwareStatus
+type InventoryStatus struct {
+	// +optional
+	CPUModel string `json:"cpuModel,omitempty"`
+
+	// CPUCores is the number of physical cores of all sockets
+	// +optional
+	CPUCores int32 `json:"cpuCores,omitempty"`
+
+	// CPUThreads is the number of logical processors
+	// +optional
+	CPUThreads int32 `json:"cpuThreads,omitempty"`
+
+	// Memory is the system memory
+	// +optional
+	Memory *resource.Quantity `json:"memory,omitempty"`
+
+	// +optional
+	Disks []DiskInventory `json:"disks,omitempty"`
+
+	// NICs are the physical Ethernet interfaces
+	// +optional
+	NICs []NICInventory `json:"nics,omitempty"`
+
+	// GPUs is the number of GPUs
+	// +optional
+	GPUs int32 `json:"gpus,omitempty"`
+
+	// +optional
+	Source InventorySource `json:"source,omitempty"`
+
+	// +optional
+	LastUpdated *metav1.Time `json:"lastUpdated,omitempty"`
+}
+
+// DiskInventory is a physical disk of a server
+type DiskInventory struct {
+	// Name is the kernel name, e.g. nvme0n1, or the Redfish drive ID
+	Name string `json:"name"`
+
+	// +optional
+	Model string `json:"model,omitempty"`
+
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// Rotational is true for spinning disks
+	// +optional
+	Rotational bool `json:"rotational,omitempty"`
+}
+
+// NICInventory is a physical Ethernet interface of a server
+type NICInventory struct {
+	// Name is the interface name, e.g. eno1, or the Redfish interface ID
+	Name string `json:"name"`
+
+	// +optional
+	MACAddress string `json:"macAddress,omitempty"`
+}
+
+// PingStatus holds recent reachability probes of the server, at most one a
+// minute
+type PingStatus struct {
+	// Samples are the most recent probes, the oldest first
+	// +optional
+	// +kubebuilder:validation:MaxItems=10
+	Samples []PingSample `json:"samples,omitempty"`
+}
+
+// PingSample is the result of one reachability probe
+type PingSample struct {
+	Time metav1.Time `json:"time"`
+
+	// RTT is the average round trip time of the echo replies, unset if
+	// there were none
+	// +optional
+	RTT *metav1.Duration `json:"rtt,omitempty"`
+
+	// LossPercent is the share of echo requests that went unanswered
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	LossPercent int32 `json:"lossPercent"`
+}
+
+// TelemetryStatus reports the subscriptions of a Redfish BMC to the metric
+// report and event receiver
+type TelemetryStatus struct {
+	// Subscription is the URI of the event subscription on the BMC, unset
+	// if the BMC couldn't be subscribed
+	// +optional
+	Subscription string `json:"subscription,omitempty"`
+
+	// Destination is the URL the BMC posts its metric reports to
+	// +optional
+	Destination string `json:"destination,omitempty"`
+
+	// Message says why the BMC couldn't be subscribed, e.g. because it has
+	// no TelemetryService. Its power and temperatures are polled instead.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// EventSubscription is the URI of the subscription to the BMC's
+	// EventService, unset if the BMC couldn't be subscribed
+	// +optional
+	EventSubscription string `json:"eventSubscription,omitempty"`
+
+	// EventDestination is the URL the BMC posts its events to
+	// +optional
+	EventDestination string `json:"eventDestination,omitempty"`
+
+	// EventMessage says why the BMC couldn't be subscribed to events. Out
+	// of band power changes are then only noticed by polling.
+	// +optional
+	EventMessage string `json:"eventMessage,omitempty"`
+
+	// LastUpdated is when the subscription was last checked
+	// +optional
+	LastUpdated *metav1.Time `json:"lastUpdated,omitempty"`
+}
+
+// WearStatus tracks the mechanical wear of a server: fans, power supplies
+// and spinning disks age with every power cycle and hour powered on
+type WearStatus struct {
+	// PowerCycles is the number of times the server came online
+	// +optional
+	PowerCycles int64 `json:"powerCycles,omitempty"`
+
+	// Runtime is the time the server was online, up to its last shutdown
+	// +optional
+	Runtime metav1.Duration `json:"runtime,omitempty"`
+
+	// ActiveSince is when the server last came online, unset while it is
+	// offline
+	// +optional
+	ActiveSince *metav1.Time `json:"activeSince,omitempty"`
+}
+
+// TotalRuntime returns the time the server was online, including the time
+// since it last came online
+func (w *WearStatus) TotalRuntime(now time.Time) time.Duration {
+	runtime := w.Runtime.Duration
+	if w.ActiveSince != nil {
+		runtime += now.Sub(w.ActiveSince.Time)
+	}
+	return runtime
+}
+
+// BootStatus tracks progress through the boot policy
+type BootStatus struct {
+	// Sources is the policy the progress refers to. Progress restarts when
+	// the policy changes.
+	// +optional
+	Sources []BootSource `json:"sources,omitempty"`
+
+	// Completed is the number of successful boots under the policy
+	// +optional
+	Completed int `json:"completed,omitempty"`
+
+	// LastSource is the source forced on the last power-on
+	// +optional
+	LastSource BootSource `json:"lastSource,omitempty"`
+}
+
+// LLDPStatus holds the LLDP neighbors reported by the host
+type LLDPStatus struct {
+	// +optional
+	Neighbors []LLDPNeighbor `json:"neighbors,omitempty"`
+
+	// +optional
+	LastUpdated *metav1.Time `json:"lastUpdated,omitempty"`
+}
+
+// LLDPNeighbor is the switch port seen on one of the server's interfaces
+type LLDPNeighbor struct {
+	// Interface on the server, e.g. eno1
+	Interface string `json:"interface"`
+
+	// +optional
+	ChassisID string `json:"chassisID,omitempty"`
+
+	// SystemName of the switch
+	// +optional
+	SystemName string `json:"systemName,omitempty"`
+
+	// +optional
+	PortID string `json:"portID,omitempty"`
+
+	// +optional
+	PortDescription string `json:"portDescription,omitempty"`
+
+	// +optional
+	VLAN string `json:"vlan,omitempty"`
+}
+
+// PowerCapStatus is the power limit enforced by the BMC
+type PowerCapStatus struct {
+	// LimitWatts is the limit the BMC reports as active, 0 if none
+	// +optional
+	LimitWatts int32 `json:"limitWatts,omitempty"`
+
+	// ConsumedWatts is the power draw at the last reading
+	// +optional
+	ConsumedWatts int32 `json:"consumedWatts,omitempty"`
+
+	// +optional
+	LastUpdated *metav1.Time `json:"lastUpdated,omitempty"`
+}
+
+// BMCResetStatus records automatic cold resets of the BMC
+type BMCResetStatus struct {
+	// Count is the number of cold resets sent
+	// +optional
+	Count int32 `json:"count,omitempty"`
+
+	// LastReset is when the last cold reset was sent
+	// +optional
+	LastReset *metav1.Time `json:"lastReset,omitempty"`
+}
+
+// RebootStatus records the last reboot of a server
+type RebootStatus struct {
+	// Generation is the generation of the spec the reboot was done for
+	// +optional
+	Generation int64 `json:"generation,omitempty"`
+
+	// LastReboot is when the reboot was sent
+	// +optional
+	LastReboot *metav1.Time `json:"lastReboot,omitempty"`
+}
+
+// ThermalStatus holds the last temperature readings of a server
+type ThermalStatus struct {
+	// +optional
+	Sensors []TemperatureSensor `json:"sensors,omitempty"`
+
+	// CriticalSince is when a sensor became critical, cleared once all
+	// sensors are below their critical temperature
+	// +optional
+	CriticalSince *metav1.Time `json:"criticalSince,omitempty"`
+
+	// +optional
+	LastUpdated *metav1.Time `json:"lastUpdated,omitempty"`
+}
+
+// TemperatureSensor is a temperature reading reported by the BMC
+type TemperatureSensor struct {
+	Name string `json:"name"`
+
+	// Celsius is the reading, rounded to a whole degree
+	Celsius int32 `json:"celsius"`
+
+	// CriticalCelsius is the temperature at which the sensor is critical, 0
+	// if unknown
+	// +optional
+	CriticalCelsius int32 `json:"criticalCelsius,omitempty"`
+}
+
+// SimulateAnnotation set to "true" makes the controller drive the server
+// with an in-memory backend instead of its BMC or network, recording the
+// actions it would have taken as events.
+const SimulateAnnotation = "baremetal.io/simulate"
+
+// AutoscalerExcludeAnnotation set to "true" removes the server from the
+// autoscaler node group. Its powerState can still be changed manually.
+const AutoscalerExcludeAnnotation = "baremetal.io/autoscaler-exclude"
+
+// UPSPriorityLabel opts a server into power-loss shutdown. Servers are shut
+// down in ascending priority when the UPS runs on battery, and powered on
+// again in descending priority.
+const UPSPriorityLabel = "baremetal.io/ups-priority"
+
+// UPSShutdownAnnotation is set to "true" on servers powered off for power
+// loss, until utility power returns and they are powered on again.
+const UPSShutdownAnnotation = "baremetal.io/ups-shutdown"
+
+// IdlePowerOffAnnotation is set to "true" on servers powered off because
+// their nodes were idle. Their nodes stay cordoned until the server is
+// powered on again.
+const IdlePowerOffAnnotation = "baremetal.io/idle-powered-off"
+
+// ThermalShutdownAnnotation is set to "true" on servers powered off because
+// their temperature stayed critical. The autoscaler leaves them off, and
+// their nodes stay cordoned, until they are powered on by hand.
+const ThermalShutdownAnnotation = "baremetal.io/thermal-shutdown"
+
+// StandbyAnnotation is set to "true" on warm spares kept on for their
+// ServerClass standby policy. Their nodes are cordoned until the autoscaler
+// promotes them, which removes the annotation.
+const StandbyAnnotation = "baremetal.io/standby"
+
+// HibernatedAnnotation holds the name of the HibernationPolicy that powered
+// the server off for off-hours. The autoscaler leaves it off until the
+// policy powers it on again.
+const HibernatedAnnotation = "baremetal.io/hibernated"
+
+// ReclaimAnnotation holds the name of the reserved server a spot server is
+// reclaimed for. The server controller drains its node and powers it off,
+// then removes the annotation.
+const ReclaimAnnotation = "baremetal.io/reclaim"
+
+// TenantLabel holds the namespace of the tenant a Server belongs to. The
+// Server's Secret references must point into that namespace, and its power
+// actions count against the tenant's share of the power workers.
+const TenantLabel = "baremetal.io/tenant"
+
+// Tenant returns the namespace of the tenant the server belongs to, empty
+// if it belongs to none
+func (s *Server) Tenant() string {
+	if s.Namespace != "" {
+		return s.Namespace
+	}
+	return s.Labels[TenantLabel]
+}
+
+// NodeName returns the name of the server's Node
+func (s *Server) NodeName() string {
+	if s.Spec.NodeName != "" {
+		return s.Spec.NodeName
+	}
+	return s.Name
+}
+
+// CheckSecretRef returns an error if the Secret is outside the namespace
+// of the server's tenant
+func (s *Server) CheckSecretRef(ref *SecretReference) error {
+	if tenant := s.Tenant(); tenant != "" && ref.Namespace != tenant {
+		return fmt.Errorf("secret %s/%s is outside namespace %s of the server's tenant", ref.Namespace, ref.Name, tenant)
+	}
+	return nil
+}
+
+// ServerLabel holds the name of the Server a Node runs on. It is set with
+// --node-cleanup, which deletes labeled Nodes whose Server was removed.
+const ServerLabel = "baremetal.io/server"
+
+// NodeCleanupFinalizer drains and deletes the Node of a Server before the
+// Server is removed
+const NodeCleanupFinalizer = "baremetal.io/node-cleanup"
+
+// DNSFinalizer removes the DNS records of a Server before the Server is
+// removed
+const DNSFinalizer = "baremetal.io/dns"
+
+const (
+	// ConditionReady is true while the server is active. Its reason is the
+	// current status, e.g. Pending or Failed.
+	ConditionReady = "Ready"
+
+	// ConditionReachable is true while the server answers the reachability
+	// check, whatever its power state is supposed to be
+	ConditionReachable = "Reachable"
+
+	// ConditionPowerActionSucceeded is the result of the last power action
+	// sent to the server. Its reason is the failure reason if it failed.
+	ConditionPowerActionSucceeded = "PowerActionSucceeded"
+
+	// ConditionDegraded is true while the server is failed or another
+	// condition reports a problem with it, e.g. HardwareFault. Its reason is
+	// the failure reason or the type of that condition.
+	ConditionDegraded = "Degraded"
+
+	// ConditionFirmwareDrift is true when the firmware versions differ from
+	// the ServerClass baseline
+	ConditionFirmwareDrift = "FirmwareDrift"
+
+	// ConditionOperationInProgress is true while a power action for the
+	// server is queued or running on a power worker
+	ConditionOperationInProgress = "OperationInProgress"
+
+	// ConditionPowerCapCompliant is true when the BMC enforces
+	// spec.powerCapWatts and the server draws no more than it
+	ConditionPowerCapCompliant = "PowerCapCompliant"
+
+	// ConditionThermalCritical is true while a temperature sensor of the
+	// server is at or above its critical temperature
+	ConditionThermalCritical = "ThermalCritical"
+
+	// ConditionPowerBudgetExceeded is true while powering on the server is
+	// held off because it would exceed a PowerBudget
+	ConditionPowerBudgetExceeded = "PowerBudgetExceeded"
+
+	// ConditionPowerDrift is true while the server is powered on or off out
+	// of band and spec.powerState wasn't applied yet
+	ConditionPowerDrift = "PowerDrift"
+
+	// ConditionWatchdogArmed is true while the BMC watchdog is armed with
+	// spec.watchdog for the running OS
+	ConditionWatchdogArmed = "WatchdogArmed"
+
+	// ConditionPowerActionsHalted is true while a power action for the
+	// server is held off by the circuit breaker
+	ConditionPowerActionsHalted = "PowerActionsHalted"
+
+	// ConditionBootOrderCorrected is true when the persistent boot device
+	// no longer matched spec.bootDevice or spec.bootPolicy after the last
+	// boot and was set again
+	ConditionBootOrderCorrected = "BootOrderCorrected"
+
+	// ConditionHardwareFault is true after the BMC sent a critical platform
+	// event, e.g. a power supply losing its input, until it sends the
+	// event's deassertion
+	ConditionHardwareFault = "HardwareFault"
+)
+
+type AttestationPhase string
+
+const (
+	// AttestationPhaseVerified means the last quote matched the expected values
+	AttestationPhaseVerified AttestationPhase = "verified"
+	// AttestationPhaseFailed means the server could not prove its boot state
+	AttestationPhaseFailed AttestationPhase = "failed"
+)
+
+// AttestationStatus reports the result of the last attestation
+type AttestationStatus struct {
+	Phase AttestationPhase `json:"phase,omitempty"`
+
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// +optional
+	LastAttestationTime *metav1.Time `json:"lastAttestationTime,omitempty"`
+}
+
+// StorageStatus reports the RAID layout observed on the server
+type StorageStatus struct {
+	Phase StoragePhase `json:"phase,omitempty"`
+
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Volumes observed on the storage controller
+	// +optional
+	Volumes []VolumeStatus `json:"volumes,omitempty"`
+}
+
+// VolumeStatus describes an observed logical volume
+type VolumeStatus struct {
+	Name          string   `json:"name"`
+	RAIDType      string   `json:"raidType,omitempty"`
+	CapacityBytes int64    `json:"capacityBytes,omitempty"`
+	Drives        []string `json:"drives,omitempty"`
 }
 
 type CurrentStatus string
@@ -106,11 +1354,46 @@ const (
 	StatusOffline  CurrentStatus = "offline"
 	StatusDraining CurrentStatus = "draining"
 	StatusFailed   CurrentStatus = "failed"
+	// StatusRebooting waits for a rebooted server to go down, after which
+	// it is pending until it is back
+	StatusRebooting CurrentStatus = "rebooting"
+)
+
+// FailureReason is a stable, machine-readable code for why a server failed,
+// meant for alerts and automation. Message carries the human readable detail.
+// +kubebuilder:validation:Enum=WOLSendFailed;SSHAuthFailed;SSHUnreachable;SSHCommandFailed;BootTimeout;ShutdownTimeout;BMCUnreachable;BMCAuthFailed;BMCCertificateUntrusted;BMCBusy;BMCUnsupported;BMCCommandFailed;SpecInvalid;SecretMissing;AttestationFailed;StorageFailed;RebootTimeout
+type FailureReason string
+
+const (
+	ReasonWOLSendFailed           FailureReason = "WOLSendFailed"
+	ReasonSSHAuthFailed           FailureReason = "SSHAuthFailed"
+	ReasonSSHUnreachable          FailureReason = "SSHUnreachable"
+	ReasonSSHCommandFailed        FailureReason = "SSHCommandFailed"
+	ReasonBootTimeout             FailureReason = "BootTimeout"
+	ReasonShutdownTimeout         FailureReason = "ShutdownTimeout"
+	ReasonBMCUnreachable          FailureReason = "BMCUnreachable"
+	ReasonBMCAuthFailed           FailureReason = "BMCAuthFailed"
+	ReasonBMCCertificateUntrusted FailureReason = "BMCCertificateUntrusted"
+	ReasonBMCBusy                 FailureReason = "BMCBusy"
+	ReasonBMCUnsupported          FailureReason = "BMCUnsupported"
+	ReasonBMCCommandFailed        FailureReason = "BMCCommandFailed"
+	ReasonSpecInvalid             FailureReason = "SpecInvalid"
+	ReasonSecretMissing           FailureReason = "SecretMissing"
+	ReasonAttestationFailed       FailureReason = "AttestationFailed"
+	ReasonStorageFailed           FailureReason = "StorageFailed"
+	ReasonRebootTimeout           FailureReason = "RebootTimeout"
 )
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
-// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:resource:scope=Cluster,shortName=srv;bm
+// +kubebuilder:printcolumn:name="Power",type=string,JSONPath=`.spec.powerState`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.status`
+// +kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.type`
+// +kubebuilder:printcolumn:name="Address",type=string,JSONPath=`.status.address`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.reason`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // Server is the Schema for the servers API.
 type Server struct {
@@ -0,0 +1,164 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ServerClassSpec defines the shared expectations for a group of servers.
+type ServerClassSpec struct {
+	// Tier places the servers of this class in the autoscaler node group of
+	// the tier. Spot servers are reclaimed when a reserved scale-up needs
+	// their power budget. Defaults to reserved.
+	// +optional
+	Tier ServerTier `json:"tier,omitempty"`
+
+	// Firmware is the baseline servers of this class are expected to run
+	// +optional
+	Firmware *FirmwareBaseline `json:"firmware,omitempty"`
+
+	// Thermal watches the temperature sensors of running servers of this
+	// class and acts when they stay critical, e.g. during a cooling failure
+	// +optional
+	Thermal *ThermalPolicy `json:"thermal,omitempty"`
+
+	// Standby keeps servers of this class powered on as warm spares, which
+	// the autoscaler promotes instead of booting cold servers
+	// +optional
+	Standby *StandbyPolicy `json:"standby,omitempty"`
+
+	// Carbon marks scale-ups of this class as non-urgent, so they are held
+	// back while the grid's carbon intensity is high
+	// +optional
+	Carbon *CarbonPolicy `json:"carbon,omitempty"`
+
+	// NodeTemplate describes the Nodes servers of this class register as,
+	// for the autoscaler's scale-from-zero simulations
+	// +optional
+	NodeTemplate *NodeTemplate `json:"nodeTemplate,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=reserved;spot
+type ServerTier string
+
+const (
+	ServerTierReserved ServerTier = "reserved"
+	ServerTierSpot     ServerTier = "spot"
+)
+
+// FirmwareBaseline declares expected firmware versions. Empty fields are not
+// checked.
+type FirmwareBaseline struct {
+	// +optional
+	BIOSVersion string `json:"biosVersion,omitempty"`
+
+	// +optional
+	BMCFirmwareVersion string `json:"bmcFirmwareVersion,omitempty"`
+}
+
+// ThermalPolicy decides when a server is too hot and what is done about it.
+// An event is always emitted once a sensor has been critical for too long.
+type ThermalPolicy struct {
+	// CriticalCelsius is the temperature at which any sensor is critical.
+	// Defaults to the upper critical threshold each sensor reports.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	CriticalCelsius *int32 `json:"criticalCelsius,omitempty"`
+
+	// SustainedFor is how long a sensor must stay critical before acting
+	// (default 2m)
+	// +optional
+	SustainedFor *metav1.Duration `json:"sustainedFor,omitempty"`
+
+	// Interval is how often sensors are read while the server is active
+	// (default 30s)
+	// +kubebuilder:validation:XValidation:rule="duration(self) >= duration('5s')",message="interval must be at least 5s"
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// PowerOff powers the server off once a sensor has been critical for
+	// too long
+	// +optional
+	PowerOff bool `json:"powerOff,omitempty"`
+
+	// DrainTimeout drains the server's node before it's powered off, for up
+	// to this long. Unset powers off without draining.
+	// +optional
+	DrainTimeout *metav1.Duration `json:"drainTimeout,omitempty"`
+}
+
+// StandbyPolicy sizes the warm standby pool of a ServerClass. Standby
+// servers are on with their nodes cordoned, and are outside the autoscaler
+// node group until promoted.
+type StandbyPolicy struct {
+	// Servers is the number of servers kept on standby
+	// +kubebuilder:validation:Minimum=0
+	Servers int32 `json:"servers"`
+}
+
+// CarbonPolicy defers the autoscaler powering on servers of a ServerClass to
+// low-carbon windows. It has no effect without a carbon intensity source.
+type CarbonPolicy struct {
+	// MaxDelay is how long after the intensity turned high scale-ups are
+	// held back at most. Unset holds them back until it drops.
+	// +optional
+	MaxDelay *metav1.Duration `json:"maxDelay,omitempty"`
+}
+
+// NodeTemplate is the shape of the Nodes of a ServerClass. Resources not
+// set are taken from the hardware inventory of the servers.
+type NodeTemplate struct {
+	// Allocatable are the resources a Node offers to pods, e.g. cpu, memory
+	// and nvidia.com/gpu. cpu and memory default to the CPUs and memory the
+	// BMC reports.
+	// +optional
+	Allocatable corev1.ResourceList `json:"allocatable,omitempty"`
+
+	// Labels the Nodes are registered with
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Taints the Nodes are registered with
+	// +optional
+	Taints []corev1.Taint `json:"taints,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+// ServerClass is the Schema for the serverclasses API.
+type ServerClass struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ServerClassSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ServerClassList contains a list of ServerClass.
+type ServerClassList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ServerClass `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ServerClass{}, &ServerClassList{})
+}
@@ -21,22 +21,329 @@ limitations under the License.
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AttestationSpec) DeepCopyInto(out *AttestationSpec) {
+	*out = *in
+	if in.SSHSecretRef != nil {
+		in, out := &in.SSHSecretRef, &out.SSHSecretRef
+		*out = new(SecretReference)
+		**out = **in
+	}
+	if in.PCRs != nil {
+		in, out := &in.PCRs, &out.PCRs
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AttestationSpec.
+func (in *AttestationSpec) DeepCopy() *AttestationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AttestationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AttestationStatus) DeepCopyInto(out *AttestationStatus) {
+	*out = *in
+	if in.LastAttestationTime != nil {
+		in, out := &in.LastAttestationTime, &out.LastAttestationTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AttestationStatus.
+func (in *AttestationStatus) DeepCopy() *AttestationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AttestationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BMCResetStatus) DeepCopyInto(out *BMCResetStatus) {
+	*out = *in
+	if in.LastReset != nil {
+		in, out := &in.LastReset, &out.LastReset
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BMCResetStatus.
+func (in *BMCResetStatus) DeepCopy() *BMCResetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BMCResetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BootPolicySpec) DeepCopyInto(out *BootPolicySpec) {
+	*out = *in
+	if in.Sources != nil {
+		in, out := &in.Sources, &out.Sources
+		*out = make([]BootSource, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootPolicySpec.
+func (in *BootPolicySpec) DeepCopy() *BootPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BootPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BootStatus) DeepCopyInto(out *BootStatus) {
+	*out = *in
+	if in.Sources != nil {
+		in, out := &in.Sources, &out.Sources
+		*out = make([]BootSource, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootStatus.
+func (in *BootStatus) DeepCopy() *BootStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BootStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Calendar) DeepCopyInto(out *Calendar) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Calendar.
+func (in *Calendar) DeepCopy() *Calendar {
+	if in == nil {
+		return nil
+	}
+	out := new(Calendar)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Calendar) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CalendarEvent) DeepCopyInto(out *CalendarEvent) {
+	*out = *in
+	in.Start.DeepCopyInto(&out.Start)
+	in.End.DeepCopyInto(&out.End)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CalendarEvent.
+func (in *CalendarEvent) DeepCopy() *CalendarEvent {
+	if in == nil {
+		return nil
+	}
+	out := new(CalendarEvent)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CalendarList) DeepCopyInto(out *CalendarList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Calendar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CalendarList.
+func (in *CalendarList) DeepCopy() *CalendarList {
+	if in == nil {
+		return nil
+	}
+	out := new(CalendarList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CalendarList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CalendarSpec) DeepCopyInto(out *CalendarSpec) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(SecretReference)
+		**out = **in
+	}
+	if in.Dates != nil {
+		in, out := &in.Dates, &out.Dates
+		*out = make([]CalendarDate, len(*in))
+		copy(*out, *in)
+	}
+	if in.RefreshInterval != nil {
+		in, out := &in.RefreshInterval, &out.RefreshInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CalendarSpec.
+func (in *CalendarSpec) DeepCopy() *CalendarSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CalendarSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CalendarStatus) DeepCopyInto(out *CalendarStatus) {
+	*out = *in
+	if in.Events != nil {
+		in, out := &in.Events, &out.Events
+		*out = make([]CalendarEvent, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CalendarStatus.
+func (in *CalendarStatus) DeepCopy() *CalendarStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CalendarStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CarbonPolicy) DeepCopyInto(out *CarbonPolicy) {
+	*out = *in
+	if in.MaxDelay != nil {
+		in, out := &in.MaxDelay, &out.MaxDelay
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CarbonPolicy.
+func (in *CarbonPolicy) DeepCopy() *CarbonPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(CarbonPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterReference) DeepCopyInto(out *ClusterReference) {
+	*out = *in
+	out.KubeconfigSecretRef = in.KubeconfigSecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterReference.
+func (in *ClusterReference) DeepCopy() *ClusterReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ControlSpecs) DeepCopyInto(out *ControlSpecs) {
 	*out = *in
 	if in.IPMI != nil {
 		in, out := &in.IPMI, &out.IPMI
 		*out = new(IPMISpecs)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.WOL != nil {
 		in, out := &in.WOL, &out.WOL
 		*out = new(WOLSpecs)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.MAAS != nil {
+		in, out := &in.MAAS, &out.MAAS
+		*out = new(MAASSpecs)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Redfish != nil {
+		in, out := &in.Redfish, &out.Redfish
+		*out = new(RedfishSpecs)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Equinix != nil {
+		in, out := &in.Equinix, &out.Equinix
+		*out = new(EquinixSpecs)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Hetzner != nil {
+		in, out := &in.Hetzner, &out.Hetzner
+		*out = new(HetznerSpecs)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ESXi != nil {
+		in, out := &in.ESXi, &out.ESXi
+		*out = new(ESXiSpecs)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlSpecs.
@@ -50,37 +357,73 @@ func (in *ControlSpecs) DeepCopy() *ControlSpecs {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *IPMISpecs) DeepCopyInto(out *IPMISpecs) {
+func (in *CredentialsSecretReference) DeepCopyInto(out *CredentialsSecretReference) {
 	*out = *in
+	out.SecretReference = in.SecretReference
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPMISpecs.
-func (in *IPMISpecs) DeepCopy() *IPMISpecs {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CredentialsSecretReference.
+func (in *CredentialsSecretReference) DeepCopy() *CredentialsSecretReference {
 	if in == nil {
 		return nil
 	}
-	out := new(IPMISpecs)
+	out := new(CredentialsSecretReference)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SecretReference) DeepCopyInto(out *SecretReference) {
+func (in *DiskInventory) DeepCopyInto(out *DiskInventory) {
 	*out = *in
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretReference.
-func (in *SecretReference) DeepCopy() *SecretReference {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskInventory.
+func (in *DiskInventory) DeepCopy() *DiskInventory {
 	if in == nil {
 		return nil
 	}
-	out := new(SecretReference)
+	out := new(DiskInventory)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Server) DeepCopyInto(out *Server) {
+func (in *ESXiSpecs) DeepCopyInto(out *ESXiSpecs) {
+	*out = *in
+	if in.CredentialsSecretRef != nil {
+		in, out := &in.CredentialsSecretRef, &out.CredentialsSecretRef
+		*out = new(SecretReference)
+		**out = **in
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(TLSSpecs)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MaintenanceTimeout != nil {
+		in, out := &in.MaintenanceTimeout, &out.MaintenanceTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ESXiSpecs.
+func (in *ESXiSpecs) DeepCopy() *ESXiSpecs {
+	if in == nil {
+		return nil
+	}
+	out := new(ESXiSpecs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EnergyReport) DeepCopyInto(out *EnergyReport) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
@@ -88,18 +431,18 @@ func (in *Server) DeepCopyInto(out *Server) {
 	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Server.
-func (in *Server) DeepCopy() *Server {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnergyReport.
+func (in *EnergyReport) DeepCopy() *EnergyReport {
 	if in == nil {
 		return nil
 	}
-	out := new(Server)
+	out := new(EnergyReport)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Server) DeepCopyObject() runtime.Object {
+func (in *EnergyReport) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -107,31 +450,31 @@ func (in *Server) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ServerList) DeepCopyInto(out *ServerList) {
+func (in *EnergyReportList) DeepCopyInto(out *EnergyReportList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]Server, len(*in))
+		*out = make([]EnergyReport, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerList.
-func (in *ServerList) DeepCopy() *ServerList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnergyReportList.
+func (in *EnergyReportList) DeepCopy() *EnergyReportList {
 	if in == nil {
 		return nil
 	}
-	out := new(ServerList)
+	out := new(EnergyReportList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ServerList) DeepCopyObject() runtime.Object {
+func (in *EnergyReportList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -139,46 +482,1660 @@ func (in *ServerList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ServerSpec) DeepCopyInto(out *ServerSpec) {
+func (in *EnergyReportSpec) DeepCopyInto(out *EnergyReportSpec) {
 	*out = *in
-	in.Control.DeepCopyInto(&out.Control)
+	in.Start.DeepCopyInto(&out.Start)
+	in.End.DeepCopyInto(&out.End)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerSpec.
-func (in *ServerSpec) DeepCopy() *ServerSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnergyReportSpec.
+func (in *EnergyReportSpec) DeepCopy() *EnergyReportSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(ServerSpec)
+	out := new(EnergyReportSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ServerStatus) DeepCopyInto(out *ServerStatus) {
+func (in *EnergyReportStatus) DeepCopyInto(out *EnergyReportStatus) {
 	*out = *in
-	if in.FailingSince != nil {
-		in, out := &in.FailingSince, &out.FailingSince
-		*out = (*in).DeepCopy()
+	out.Total = in.Total
+	if in.Pools != nil {
+		in, out := &in.Pools, &out.Pools
+		*out = make([]EnergyUsage, len(*in))
+		copy(*out, *in)
+	}
+	if in.Servers != nil {
+		in, out := &in.Servers, &out.Servers
+		*out = make([]EnergyUsage, len(*in))
+		copy(*out, *in)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerStatus.
-func (in *ServerStatus) DeepCopy() *ServerStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnergyReportStatus.
+func (in *EnergyReportStatus) DeepCopy() *EnergyReportStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(ServerStatus)
+	out := new(EnergyReportStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *WOLSpecs) DeepCopyInto(out *WOLSpecs) {
+func (in *EnergyUsage) DeepCopyInto(out *EnergyUsage) {
 	*out = *in
-	if in.SSHSecretRef != nil {
-		in, out := &in.SSHSecretRef, &out.SSHSecretRef
-		*out = new(SecretReference)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnergyUsage.
+func (in *EnergyUsage) DeepCopy() *EnergyUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(EnergyUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EquinixSpecs) DeepCopyInto(out *EquinixSpecs) {
+	*out = *in
+	if in.APITokenSecretRef != nil {
+		in, out := &in.APITokenSecretRef, &out.APITokenSecretRef
+		*out = new(SecretReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EquinixSpecs.
+func (in *EquinixSpecs) DeepCopy() *EquinixSpecs {
+	if in == nil {
+		return nil
+	}
+	out := new(EquinixSpecs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FirmwareBaseline) DeepCopyInto(out *FirmwareBaseline) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FirmwareBaseline.
+func (in *FirmwareBaseline) DeepCopy() *FirmwareBaseline {
+	if in == nil {
+		return nil
+	}
+	out := new(FirmwareBaseline)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HardwareStatus) DeepCopyInto(out *HardwareStatus) {
+	*out = *in
+	if in.Memory != nil {
+		in, out := &in.Memory, &out.Memory
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.LastUpdated != nil {
+		in, out := &in.LastUpdated, &out.LastUpdated
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HardwareStatus.
+func (in *HardwareStatus) DeepCopy() *HardwareStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HardwareStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthCheckSpec) DeepCopyInto(out *HealthCheckSpec) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthCheckSpec.
+func (in *HealthCheckSpec) DeepCopy() *HealthCheckSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthCheckSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HetznerRescue) DeepCopyInto(out *HetznerRescue) {
+	*out = *in
+	if in.AuthorizedKeys != nil {
+		in, out := &in.AuthorizedKeys, &out.AuthorizedKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HetznerRescue.
+func (in *HetznerRescue) DeepCopy() *HetznerRescue {
+	if in == nil {
+		return nil
+	}
+	out := new(HetznerRescue)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HetznerSpecs) DeepCopyInto(out *HetznerSpecs) {
+	*out = *in
+	if in.CredentialsSecretRef != nil {
+		in, out := &in.CredentialsSecretRef, &out.CredentialsSecretRef
+		*out = new(SecretReference)
+		**out = **in
+	}
+	if in.Rescue != nil {
+		in, out := &in.Rescue, &out.Rescue
+		*out = new(HetznerRescue)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HetznerSpecs.
+func (in *HetznerSpecs) DeepCopy() *HetznerSpecs {
+	if in == nil {
+		return nil
+	}
+	out := new(HetznerSpecs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HibernationPolicy) DeepCopyInto(out *HibernationPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HibernationPolicy.
+func (in *HibernationPolicy) DeepCopy() *HibernationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(HibernationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HibernationPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HibernationPolicyList) DeepCopyInto(out *HibernationPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HibernationPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HibernationPolicyList.
+func (in *HibernationPolicyList) DeepCopy() *HibernationPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(HibernationPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HibernationPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HibernationPolicySpec) DeepCopyInto(out *HibernationPolicySpec) {
+	*out = *in
+	in.Selector.DeepCopyInto(&out.Selector)
+	if in.OffHours != nil {
+		in, out := &in.OffHours, &out.OffHours
+		*out = make([]MaintenanceWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HibernationPolicySpec.
+func (in *HibernationPolicySpec) DeepCopy() *HibernationPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HibernationPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HibernationPolicyStatus) DeepCopyInto(out *HibernationPolicyStatus) {
+	*out = *in
+	if in.Hibernated != nil {
+		in, out := &in.Hibernated, &out.Hibernated
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.HibernatedAt != nil {
+		in, out := &in.HibernatedAt, &out.HibernatedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.RestoredAt != nil {
+		in, out := &in.RestoredAt, &out.RestoredAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HibernationPolicyStatus.
+func (in *HibernationPolicyStatus) DeepCopy() *HibernationPolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HibernationPolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPMISpecs) DeepCopyInto(out *IPMISpecs) {
+	*out = *in
+	if in.CredentialsSecretRef != nil {
+		in, out := &in.CredentialsSecretRef, &out.CredentialsSecretRef
+		*out = new(CredentialsSecretReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPMISpecs.
+func (in *IPMISpecs) DeepCopy() *IPMISpecs {
+	if in == nil {
+		return nil
+	}
+	out := new(IPMISpecs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InventoryStatus) DeepCopyInto(out *InventoryStatus) {
+	*out = *in
+	if in.Memory != nil {
+		in, out := &in.Memory, &out.Memory
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Disks != nil {
+		in, out := &in.Disks, &out.Disks
+		*out = make([]DiskInventory, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NICs != nil {
+		in, out := &in.NICs, &out.NICs
+		*out = make([]NICInventory, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastUpdated != nil {
+		in, out := &in.LastUpdated, &out.LastUpdated
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InventoryStatus.
+func (in *InventoryStatus) DeepCopy() *InventoryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(InventoryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LLDPNeighbor) DeepCopyInto(out *LLDPNeighbor) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LLDPNeighbor.
+func (in *LLDPNeighbor) DeepCopy() *LLDPNeighbor {
+	if in == nil {
+		return nil
+	}
+	out := new(LLDPNeighbor)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LLDPStatus) DeepCopyInto(out *LLDPStatus) {
+	*out = *in
+	if in.Neighbors != nil {
+		in, out := &in.Neighbors, &out.Neighbors
+		*out = make([]LLDPNeighbor, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastUpdated != nil {
+		in, out := &in.LastUpdated, &out.LastUpdated
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LLDPStatus.
+func (in *LLDPStatus) DeepCopy() *LLDPStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(LLDPStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MAASSpecs) DeepCopyInto(out *MAASSpecs) {
+	*out = *in
+	if in.APIKeySecretRef != nil {
+		in, out := &in.APIKeySecretRef, &out.APIKeySecretRef
+		*out = new(SecretReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MAASSpecs.
+func (in *MAASSpecs) DeepCopy() *MAASSpecs {
+	if in == nil {
+		return nil
+	}
+	out := new(MAASSpecs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+	out.Duration = in.Duration
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = make([]Weekday, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NICInventory) DeepCopyInto(out *NICInventory) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NICInventory.
+func (in *NICInventory) DeepCopy() *NICInventory {
+	if in == nil {
+		return nil
+	}
+	out := new(NICInventory)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeTemplate) DeepCopyInto(out *NodeTemplate) {
+	*out = *in
+	if in.Allocatable != nil {
+		in, out := &in.Allocatable, &out.Allocatable
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Taints != nil {
+		in, out := &in.Taints, &out.Taints
+		*out = make([]corev1.Taint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeTemplate.
+func (in *NodeTemplate) DeepCopy() *NodeTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PingSample) DeepCopyInto(out *PingSample) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	if in.RTT != nil {
+		in, out := &in.RTT, &out.RTT
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PingSample.
+func (in *PingSample) DeepCopy() *PingSample {
+	if in == nil {
+		return nil
+	}
+	out := new(PingSample)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PingStatus) DeepCopyInto(out *PingStatus) {
+	*out = *in
+	if in.Samples != nil {
+		in, out := &in.Samples, &out.Samples
+		*out = make([]PingSample, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PingStatus.
+func (in *PingStatus) DeepCopy() *PingStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PingStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerAction) DeepCopyInto(out *PowerAction) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerAction.
+func (in *PowerAction) DeepCopy() *PowerAction {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerAction)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PowerAction) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerActionList) DeepCopyInto(out *PowerActionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PowerAction, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerActionList.
+func (in *PowerActionList) DeepCopy() *PowerActionList {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerActionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PowerActionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerActionSpec) DeepCopyInto(out *PowerActionSpec) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Servers != nil {
+		in, out := &in.Servers, &out.Servers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerActionSpec.
+func (in *PowerActionSpec) DeepCopy() *PowerActionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerActionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerActionStatus) DeepCopyInto(out *PowerActionStatus) {
+	*out = *in
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]PowerActionTargetStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerActionStatus.
+func (in *PowerActionStatus) DeepCopy() *PowerActionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerActionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerActionTargetStatus) DeepCopyInto(out *PowerActionTargetStatus) {
+	*out = *in
+	if in.CompletedAt != nil {
+		in, out := &in.CompletedAt, &out.CompletedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerActionTargetStatus.
+func (in *PowerActionTargetStatus) DeepCopy() *PowerActionTargetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerActionTargetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerBudget) DeepCopyInto(out *PowerBudget) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerBudget.
+func (in *PowerBudget) DeepCopy() *PowerBudget {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerBudget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PowerBudget) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerBudgetList) DeepCopyInto(out *PowerBudgetList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PowerBudget, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerBudgetList.
+func (in *PowerBudgetList) DeepCopy() *PowerBudgetList {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerBudgetList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PowerBudgetList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerBudgetSpec) DeepCopyInto(out *PowerBudgetSpec) {
+	*out = *in
+	in.Selector.DeepCopyInto(&out.Selector)
+	if in.MaxPoweredOn != nil {
+		in, out := &in.MaxPoweredOn, &out.MaxPoweredOn
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxWatts != nil {
+		in, out := &in.MaxWatts, &out.MaxWatts
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ServerWatts != nil {
+		in, out := &in.ServerWatts, &out.ServerWatts
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerBudgetSpec.
+func (in *PowerBudgetSpec) DeepCopy() *PowerBudgetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerBudgetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerBudgetStatus) DeepCopyInto(out *PowerBudgetStatus) {
+	*out = *in
+	if in.Waiting != nil {
+		in, out := &in.Waiting, &out.Waiting
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerBudgetStatus.
+func (in *PowerBudgetStatus) DeepCopy() *PowerBudgetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerBudgetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerCapStatus) DeepCopyInto(out *PowerCapStatus) {
+	*out = *in
+	if in.LastUpdated != nil {
+		in, out := &in.LastUpdated, &out.LastUpdated
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerCapStatus.
+func (in *PowerCapStatus) DeepCopy() *PowerCapStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerCapStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisioningSpec) DeepCopyInto(out *ProvisioningSpec) {
+	*out = *in
+	if in.Tinkerbell != nil {
+		in, out := &in.Tinkerbell, &out.Tinkerbell
+		*out = new(TinkerbellSpec)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisioningSpec.
+func (in *ProvisioningSpec) DeepCopy() *ProvisioningSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisioningSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisioningStatus) DeepCopyInto(out *ProvisioningStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisioningStatus.
+func (in *ProvisioningStatus) DeepCopy() *ProvisioningStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisioningStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RebootCampaign) DeepCopyInto(out *RebootCampaign) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RebootCampaign.
+func (in *RebootCampaign) DeepCopy() *RebootCampaign {
+	if in == nil {
+		return nil
+	}
+	out := new(RebootCampaign)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RebootCampaign) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RebootCampaignList) DeepCopyInto(out *RebootCampaignList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RebootCampaign, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RebootCampaignList.
+func (in *RebootCampaignList) DeepCopy() *RebootCampaignList {
+	if in == nil {
+		return nil
+	}
+	out := new(RebootCampaignList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RebootCampaignList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RebootCampaignSpec) DeepCopyInto(out *RebootCampaignSpec) {
+	*out = *in
+	in.Selector.DeepCopyInto(&out.Selector)
+	if in.MaintenanceWindow != nil {
+		in, out := &in.MaintenanceWindow, &out.MaintenanceWindow
+		*out = new(MaintenanceWindow)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RebootCampaignSpec.
+func (in *RebootCampaignSpec) DeepCopy() *RebootCampaignSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RebootCampaignSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RebootCampaignStatus) DeepCopyInto(out *RebootCampaignStatus) {
+	*out = *in
+	if in.Servers != nil {
+		in, out := &in.Servers, &out.Servers
+		*out = make([]ServerRebootStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RebootCampaignStatus.
+func (in *RebootCampaignStatus) DeepCopy() *RebootCampaignStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RebootCampaignStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RebootStatus) DeepCopyInto(out *RebootStatus) {
+	*out = *in
+	if in.LastReboot != nil {
+		in, out := &in.LastReboot, &out.LastReboot
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RebootStatus.
+func (in *RebootStatus) DeepCopy() *RebootStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RebootStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedfishSpecs) DeepCopyInto(out *RedfishSpecs) {
+	*out = *in
+	if in.CredentialsSecretRef != nil {
+		in, out := &in.CredentialsSecretRef, &out.CredentialsSecretRef
+		*out = new(SecretReference)
+		**out = **in
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(TLSSpecs)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedfishSpecs.
+func (in *RedfishSpecs) DeepCopy() *RedfishSpecs {
+	if in == nil {
+		return nil
+	}
+	out := new(RedfishSpecs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResolvedAddress) DeepCopyInto(out *ResolvedAddress) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResolvedAddress.
+func (in *ResolvedAddress) DeepCopy() *ResolvedAddress {
+	if in == nil {
+		return nil
+	}
+	out := new(ResolvedAddress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretReference) DeepCopyInto(out *SecretReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretReference.
+func (in *SecretReference) DeepCopy() *SecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Server) DeepCopyInto(out *Server) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Server.
+func (in *Server) DeepCopy() *Server {
+	if in == nil {
+		return nil
+	}
+	out := new(Server)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Server) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerAddress) DeepCopyInto(out *ServerAddress) {
+	*out = *in
+	if in.Expires != nil {
+		in, out := &in.Expires, &out.Expires
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerAddress.
+func (in *ServerAddress) DeepCopy() *ServerAddress {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerAddress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerClass) DeepCopyInto(out *ServerClass) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerClass.
+func (in *ServerClass) DeepCopy() *ServerClass {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerClass)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ServerClass) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerClassList) DeepCopyInto(out *ServerClassList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ServerClass, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerClassList.
+func (in *ServerClassList) DeepCopy() *ServerClassList {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerClassList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ServerClassList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerClassSpec) DeepCopyInto(out *ServerClassSpec) {
+	*out = *in
+	if in.Firmware != nil {
+		in, out := &in.Firmware, &out.Firmware
+		*out = new(FirmwareBaseline)
+		**out = **in
+	}
+	if in.Thermal != nil {
+		in, out := &in.Thermal, &out.Thermal
+		*out = new(ThermalPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Standby != nil {
+		in, out := &in.Standby, &out.Standby
+		*out = new(StandbyPolicy)
+		**out = **in
+	}
+	if in.Carbon != nil {
+		in, out := &in.Carbon, &out.Carbon
+		*out = new(CarbonPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeTemplate != nil {
+		in, out := &in.NodeTemplate, &out.NodeTemplate
+		*out = new(NodeTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerClassSpec.
+func (in *ServerClassSpec) DeepCopy() *ServerClassSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerClassSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerList) DeepCopyInto(out *ServerList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Server, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerList.
+func (in *ServerList) DeepCopy() *ServerList {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ServerList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerRebootStatus) DeepCopyInto(out *ServerRebootStatus) {
+	*out = *in
+	if in.StartedAt != nil {
+		in, out := &in.StartedAt, &out.StartedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletedAt != nil {
+		in, out := &in.CompletedAt, &out.CompletedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerRebootStatus.
+func (in *ServerRebootStatus) DeepCopy() *ServerRebootStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerRebootStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerSpec) DeepCopyInto(out *ServerSpec) {
+	*out = *in
+	in.Control.DeepCopyInto(&out.Control)
+	if in.Provisioning != nil {
+		in, out := &in.Provisioning, &out.Provisioning
+		*out = new(ProvisioningSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		*out = new(StorageSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BootPolicy != nil {
+		in, out := &in.BootPolicy, &out.BootPolicy
+		*out = new(BootPolicySpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Attestation != nil {
+		in, out := &in.Attestation, &out.Attestation
+		*out = new(AttestationSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PowerCapWatts != nil {
+		in, out := &in.PowerCapWatts, &out.PowerCapWatts
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Watchdog != nil {
+		in, out := &in.Watchdog, &out.Watchdog
+		*out = new(WatchdogSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HealthCheck != nil {
+		in, out := &in.HealthCheck, &out.HealthCheck
+		*out = new(HealthCheckSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReconcileInterval != nil {
+		in, out := &in.ReconcileInterval, &out.ReconcileInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Timeouts != nil {
+		in, out := &in.Timeouts, &out.Timeouts
+		*out = new(TimeoutsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClusterRef != nil {
+		in, out := &in.ClusterRef, &out.ClusterRef
+		*out = new(ClusterReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerSpec.
+func (in *ServerSpec) DeepCopy() *ServerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerStatus) DeepCopyInto(out *ServerStatus) {
+	*out = *in
+	if in.FailingSince != nil {
+		in, out := &in.FailingSince, &out.FailingSince
+		*out = (*in).DeepCopy()
+	}
+	if in.Provisioning != nil {
+		in, out := &in.Provisioning, &out.Provisioning
+		*out = new(ProvisioningStatus)
+		**out = **in
+	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		*out = new(StorageStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Attestation != nil {
+		in, out := &in.Attestation, &out.Attestation
+		*out = new(AttestationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Hardware != nil {
+		in, out := &in.Hardware, &out.Hardware
+		*out = new(HardwareStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Inventory != nil {
+		in, out := &in.Inventory, &out.Inventory
+		*out = new(InventoryStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Boot != nil {
+		in, out := &in.Boot, &out.Boot
+		*out = new(BootStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LLDP != nil {
+		in, out := &in.LLDP, &out.LLDP
+		*out = new(LLDPStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PowerCap != nil {
+		in, out := &in.PowerCap, &out.PowerCap
+		*out = new(PowerCapStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BMCReset != nil {
+		in, out := &in.BMCReset, &out.BMCReset
+		*out = new(BMCResetStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Reboot != nil {
+		in, out := &in.Reboot, &out.Reboot
+		*out = new(RebootStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Thermal != nil {
+		in, out := &in.Thermal, &out.Thermal
+		*out = new(ThermalStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Addresses != nil {
+		in, out := &in.Addresses, &out.Addresses
+		*out = make([]ServerAddress, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ResolvedAddresses != nil {
+		in, out := &in.ResolvedAddresses, &out.ResolvedAddresses
+		*out = make([]ResolvedAddress, len(*in))
+		copy(*out, *in)
+	}
+	if in.Ping != nil {
+		in, out := &in.Ping, &out.Ping
+		*out = new(PingStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Telemetry != nil {
+		in, out := &in.Telemetry, &out.Telemetry
+		*out = new(TelemetryStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Wear != nil {
+		in, out := &in.Wear, &out.Wear
+		*out = new(WearStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeFeatures != nil {
+		in, out := &in.NodeFeatures, &out.NodeFeatures
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerStatus.
+func (in *ServerStatus) DeepCopy() *ServerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StandbyPolicy) DeepCopyInto(out *StandbyPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StandbyPolicy.
+func (in *StandbyPolicy) DeepCopy() *StandbyPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(StandbyPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageSpec) DeepCopyInto(out *StorageSpec) {
+	*out = *in
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]VolumeSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageSpec.
+func (in *StorageSpec) DeepCopy() *StorageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageStatus) DeepCopyInto(out *StorageStatus) {
+	*out = *in
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]VolumeStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageStatus.
+func (in *StorageStatus) DeepCopy() *StorageStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSSpecs) DeepCopyInto(out *TLSSpecs) {
+	*out = *in
+	if in.CASecretRef != nil {
+		in, out := &in.CASecretRef, &out.CASecretRef
+		*out = new(SecretReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSSpecs.
+func (in *TLSSpecs) DeepCopy() *TLSSpecs {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSSpecs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TelemetryStatus) DeepCopyInto(out *TelemetryStatus) {
+	*out = *in
+	if in.LastUpdated != nil {
+		in, out := &in.LastUpdated, &out.LastUpdated
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TelemetryStatus.
+func (in *TelemetryStatus) DeepCopy() *TelemetryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TelemetryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TemperatureSensor) DeepCopyInto(out *TemperatureSensor) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemperatureSensor.
+func (in *TemperatureSensor) DeepCopy() *TemperatureSensor {
+	if in == nil {
+		return nil
+	}
+	out := new(TemperatureSensor)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThermalPolicy) DeepCopyInto(out *ThermalPolicy) {
+	*out = *in
+	if in.CriticalCelsius != nil {
+		in, out := &in.CriticalCelsius, &out.CriticalCelsius
+		*out = new(int32)
+		**out = **in
+	}
+	if in.SustainedFor != nil {
+		in, out := &in.SustainedFor, &out.SustainedFor
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.DrainTimeout != nil {
+		in, out := &in.DrainTimeout, &out.DrainTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThermalPolicy.
+func (in *ThermalPolicy) DeepCopy() *ThermalPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ThermalPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThermalStatus) DeepCopyInto(out *ThermalStatus) {
+	*out = *in
+	if in.Sensors != nil {
+		in, out := &in.Sensors, &out.Sensors
+		*out = make([]TemperatureSensor, len(*in))
+		copy(*out, *in)
+	}
+	if in.CriticalSince != nil {
+		in, out := &in.CriticalSince, &out.CriticalSince
+		*out = (*in).DeepCopy()
+	}
+	if in.LastUpdated != nil {
+		in, out := &in.LastUpdated, &out.LastUpdated
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThermalStatus.
+func (in *ThermalStatus) DeepCopy() *ThermalStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ThermalStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TimeoutsSpec) DeepCopyInto(out *TimeoutsSpec) {
+	*out = *in
+	if in.BootTimeout != nil {
+		in, out := &in.BootTimeout, &out.BootTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.ShutdownTimeout != nil {
+		in, out := &in.ShutdownTimeout, &out.ShutdownTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.RequeueInterval != nil {
+		in, out := &in.RequeueInterval, &out.RequeueInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TimeoutsSpec.
+func (in *TimeoutsSpec) DeepCopy() *TimeoutsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TimeoutsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TinkerbellSpec) DeepCopyInto(out *TinkerbellSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TinkerbellSpec.
+func (in *TinkerbellSpec) DeepCopy() *TinkerbellSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TinkerbellSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeSpec) DeepCopyInto(out *VolumeSpec) {
+	*out = *in
+	if in.Drives != nil {
+		in, out := &in.Drives, &out.Drives
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeSpec.
+func (in *VolumeSpec) DeepCopy() *VolumeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeStatus) DeepCopyInto(out *VolumeStatus) {
+	*out = *in
+	if in.Drives != nil {
+		in, out := &in.Drives, &out.Drives
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeStatus.
+func (in *VolumeStatus) DeepCopy() *VolumeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WOLSpecs) DeepCopyInto(out *WOLSpecs) {
+	*out = *in
+	if in.SSHSecretRef != nil {
+		in, out := &in.SSHSecretRef, &out.SSHSecretRef
+		*out = new(SecretReference)
 		**out = **in
 	}
 }
@@ -192,3 +2149,43 @@ func (in *WOLSpecs) DeepCopy() *WOLSpecs {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WatchdogSpec) DeepCopyInto(out *WatchdogSpec) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WatchdogSpec.
+func (in *WatchdogSpec) DeepCopy() *WatchdogSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(WatchdogSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WearStatus) DeepCopyInto(out *WearStatus) {
+	*out = *in
+	out.Runtime = in.Runtime
+	if in.ActiveSince != nil {
+		in, out := &in.ActiveSince, &out.ActiveSince
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WearStatus.
+func (in *WearStatus) DeepCopy() *WearStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(WearStatus)
+	in.DeepCopyInto(out)
+	return out
+}
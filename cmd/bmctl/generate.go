@@ -0,0 +1,209 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/importer"
+)
+
+// generateFlags maps command line flags to importer fields
+var generateFlags = []struct {
+	flag  string
+	field string
+	usage string
+}{
+	{"name", importer.FieldName, "Server name, also the name of its Kubernetes node"},
+	{"address", importer.FieldAddress, "Host address used for reachability checks and SSH (wol, maas, equinix, hetzner, esxi)"},
+	{"mac", importer.FieldMAC, "MAC address to wake (wol, esxi)"},
+	{"broadcast", importer.FieldBroadcast, "Broadcast address of the host's subnet (wol, esxi)"},
+	{"user", importer.FieldUser, "SSH user for shutdown (wol)"},
+	{"ssh-secret", importer.FieldSSHSecret, "Secret with the SSH private key, [namespace/]name (wol, defaults to <name>-ssh)"},
+	{"bmc", importer.FieldBMC, "BMC address (ipmi, redfish, esxi)"},
+	{"bmc-username", importer.FieldBMCUsername, "BMC user (ipmi, esxi)"},
+	{"bmc-password", importer.FieldBMCPassword, "BMC password (ipmi, esxi)"},
+	{"credentials-secret", importer.FieldCredentialsSecret, "Secret with BMC, Robot or ESXi host credentials, MAAS API key or Equinix Metal API token, [namespace/]name (ipmi, redfish, maas, equinix, hetzner, esxi, defaults to <name>-credentials)"},
+	{"system-id", importer.FieldSystemID, "Redfish system, MAAS machine or Equinix Metal device ID, or Hetzner server number"},
+	{"endpoint", importer.FieldEndpoint, "MAAS URL (maas)"},
+	{"project-id", importer.FieldProjectID, "Equinix Metal project ID (equinix)"},
+	{"server-class", importer.FieldServerClass, "ServerClass the server belongs to"},
+	{"power-state", importer.FieldPowerState, "Initial powerState, on or off (defaults to off)"},
+}
+
+// secretKeys returns the keys the controller reads from a secret field
+func secretKeys(field string, controlType baremetalcontrollerv1.ControlType) string {
+	switch {
+	case field == importer.FieldSSHSecret:
+		return "ssh-privatekey"
+	case controlType == baremetalcontrollerv1.ControlTypeMAAS:
+		return "api-key"
+	case controlType == baremetalcontrollerv1.ControlTypeEquinix:
+		return "api-token"
+	default:
+		return "username, password"
+	}
+}
+
+func generateCommand(args []string) error {
+	if len(args) == 0 || args[0] != "server" {
+		fmt.Fprintln(os.Stderr, "Usage: bmctl generate server [flags]")
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("generate server", flag.ExitOnError)
+	controlType := fs.String("type", "", "Control type: wol, ipmi, redfish, maas, equinix, hetzner or esxi")
+	secretNamespace := fs.String("secret-namespace", "default", "Namespace of secrets given without one")
+	labels := fs.String("labels", "", "Labels, e.g. rack=r1,pool=workers")
+	interactive := fs.Bool("i", false, "Prompt for every field of the control type, using flags as defaults")
+	values := map[string]*string{}
+	for _, f := range generateFlags {
+		values[f.field] = fs.String(f.flag, "", f.usage)
+	}
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: bmctl generate server [flags]")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args[1:])
+	if fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	host := importer.Host{}
+	for field, value := range values {
+		if *value != "" {
+			host[field] = *value
+		}
+	}
+	labelFields, err := importer.ParseMapping(*labels)
+	if err != nil {
+		return fmt.Errorf("invalid --labels: %w", err)
+	}
+	for key, value := range labelFields {
+		host[importer.LabelPrefix+key] = value
+	}
+	if *controlType != "" {
+		host[importer.FieldType] = *controlType
+	}
+
+	if *interactive {
+		if err := promptHost(host, bufio.NewReader(os.Stdin), os.Stderr); err != nil {
+			return err
+		}
+	}
+
+	// Point at conventionally named secrets, so the manifest is complete
+	// and only the secrets need to be created
+	name := strings.ToLower(host[importer.FieldName])
+	typ := baremetalcontrollerv1.ControlType(host[importer.FieldType])
+	var secrets []string
+	if name != "" {
+		required, optional := importer.Fields(typ)
+		for _, field := range append(required, optional...) {
+			suffix := map[string]string{importer.FieldSSHSecret: "-ssh", importer.FieldCredentialsSecret: "-credentials"}[field]
+			if suffix == "" {
+				continue
+			}
+			// IPMI credentials given inline need no secret
+			if typ == baremetalcontrollerv1.ControlTypeIPMI && field == importer.FieldCredentialsSecret &&
+				(host[importer.FieldBMCUsername] != "" || host[importer.FieldBMCPassword] != "") {
+				continue
+			}
+			if host[field] == "" {
+				host[field] = name + suffix
+			}
+			ref := host[field]
+			if !strings.Contains(ref, "/") {
+				ref = *secretNamespace + "/" + ref
+			}
+			secrets = append(secrets, fmt.Sprintf("# Secret %s needs keys: %s\n", ref, secretKeys(field, typ)))
+		}
+	}
+
+	server, err := importer.ServerForHost(host, importer.Defaults{SecretNamespace: *secretNamespace})
+	if err != nil {
+		return err
+	}
+	doc, err := marshalServer(server)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(os.Stdout, strings.Join(secrets, "")+doc)
+	return err
+}
+
+// promptHost asks for the type and every field of it, keeping the current
+// values as defaults
+func promptHost(host importer.Host, in *bufio.Reader, out io.Writer) error {
+	ask := func(field string, required bool) error {
+		for {
+			def := host[field]
+			if def != "" {
+				fmt.Fprintf(out, "%s [%s]: ", field, def)
+			} else {
+				fmt.Fprintf(out, "%s: ", field)
+			}
+			line, err := in.ReadString('\n')
+			if err != nil && (err != io.EOF || line == "") {
+				return fmt.Errorf("no value for %s", field)
+			}
+			if line = strings.TrimSpace(line); line != "" {
+				host[field] = line
+			}
+			if host[field] != "" || !required {
+				return nil
+			}
+			fmt.Fprintf(out, "%s is required\n", field)
+		}
+	}
+
+	if err := ask(importer.FieldName, true); err != nil {
+		return err
+	}
+	for {
+		if err := ask(importer.FieldType, true); err != nil {
+			return err
+		}
+		if required, _ := importer.Fields(baremetalcontrollerv1.ControlType(host[importer.FieldType])); required != nil {
+			break
+		}
+		fmt.Fprintln(out, "type must be one of wol, ipmi, redfish, maas, equinix, hetzner or esxi")
+		delete(host, importer.FieldType)
+	}
+
+	required, optional := importer.Fields(baremetalcontrollerv1.ControlType(host[importer.FieldType]))
+	for _, field := range required {
+		// Secrets default to a conventional name
+		isSecret := field == importer.FieldCredentialsSecret
+		if err := ask(field, !isSecret); err != nil {
+			return err
+		}
+	}
+	for _, field := range append(optional, importer.FieldServerClass) {
+		if err := ask(field, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
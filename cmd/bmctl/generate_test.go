@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/importer"
+)
+
+func TestPromptHost(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    importer.Host
+		input   string
+		want    importer.Host
+		wantErr bool
+	}{
+		{
+			name:  "WoL",
+			host:  importer.Host{},
+			input: "worker-01\nwol\n10.0.0.11\n00:11:22:33:44:55\n\n\n\ngeneral\n",
+			want: importer.Host{
+				importer.FieldName:        "worker-01",
+				importer.FieldType:        "wol",
+				importer.FieldAddress:     "10.0.0.11",
+				importer.FieldMAC:         "00:11:22:33:44:55",
+				importer.FieldServerClass: "general",
+			},
+		},
+		{
+			name: "flags are defaults",
+			host: importer.Host{importer.FieldName: "worker-02", importer.FieldBMC: "10.0.1.12"},
+			// The credentials secret defaults to a conventional name, so it
+			// can be skipped
+			input: "\nredfish\n\n\n\n\n",
+			want: importer.Host{
+				importer.FieldName: "worker-02",
+				importer.FieldType: "redfish",
+				importer.FieldBMC:  "10.0.1.12",
+			},
+		},
+		{
+			name:  "asks again",
+			host:  importer.Host{},
+			input: "\nworker-03\npdu\nipmi\n\n10.0.1.13\n\nadmin\n\n\n",
+			want: importer.Host{
+				importer.FieldName:        "worker-03",
+				importer.FieldType:        "ipmi",
+				importer.FieldBMC:         "10.0.1.13",
+				importer.FieldBMCUsername: "admin",
+			},
+		},
+		{
+			name:  "ESXi",
+			host:  importer.Host{},
+			input: "esxi-01\nesxi\n10.0.2.11\n\n\n\n\n00:11:22:33:44:66\n\n\n",
+			want: importer.Host{
+				importer.FieldName:    "esxi-01",
+				importer.FieldType:    "esxi",
+				importer.FieldAddress: "10.0.2.11",
+				importer.FieldMAC:     "00:11:22:33:44:66",
+			},
+		},
+		{name: "input ends", host: importer.Host{}, input: "worker-04\nwol\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := promptHost(tt.host, bufio.NewReader(strings.NewReader(tt.input)), io.Discard)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("promptHost() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(tt.host, tt.want) {
+				t.Errorf("promptHost() = %v, want %v", tt.host, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecretKeys(t *testing.T) {
+	tests := []struct {
+		field       string
+		controlType string
+		want        string
+	}{
+		{field: importer.FieldSSHSecret, controlType: "wol", want: "ssh-privatekey"},
+		{field: importer.FieldCredentialsSecret, controlType: "redfish", want: "username, password"},
+		{field: importer.FieldCredentialsSecret, controlType: "hetzner", want: "username, password"},
+		{field: importer.FieldCredentialsSecret, controlType: "esxi", want: "username, password"},
+		{field: importer.FieldCredentialsSecret, controlType: "maas", want: "api-key"},
+		{field: importer.FieldCredentialsSecret, controlType: "equinix", want: "api-token"},
+	}
+	for _, tt := range tests {
+		if got := secretKeys(tt.field, baremetalcontrollerv1.ControlType(tt.controlType)); got != tt.want {
+			t.Errorf("secretKeys(%s, %s) = %q, want %q", tt.field, tt.controlType, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/importer"
+)
+
+const importFields = `
+Fields: name, type, address, mac, broadcast, user, sshSecret, bmc, bmcUsername,
+bmcPassword, credentialsSecret, systemID, endpoint, serverClass, powerState and
+label.<key>. Secrets are given as name or namespace/name.
+`
+
+func importCommand(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	format := fs.String("format", "", "Inventory format: csv, yaml or ansible (defaults to the file extension)")
+	controlType := fs.String("type", string(baremetalcontrollerv1.ControlTypeWOL), "Control type of hosts without a type field")
+	mapFlag := fs.String("map", "", "Map inventory columns to fields, e.g. hostname=name,ipmi_ip=bmc")
+	setFlag := fs.String("set", "", "Default field values, e.g. credentialsSecret=bmc-creds,label.rack=r1")
+	secretNamespace := fs.String("secret-namespace", "default", "Namespace of secrets given without one")
+	output := fs.String("o", "", "Write manifests to this file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: bmctl import [flags] <file|->")
+		fs.PrintDefaults()
+		fmt.Fprint(os.Stderr, importFields)
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	path := fs.Arg(0)
+
+	mapping, err := importer.ParseMapping(*mapFlag)
+	if err != nil {
+		return err
+	}
+	defaultFields, err := importer.ParseMapping(*setFlag)
+	if err != nil {
+		return fmt.Errorf("invalid --set: %w", err)
+	}
+
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	if *format == "" {
+		*format = formatFromExtension(path)
+	}
+	var hosts []importer.Host
+	switch *format {
+	case "csv":
+		hosts, err = importer.ReadCSV(in, mapping)
+	case "yaml", "json":
+		hosts, err = importer.ReadYAML(in, mapping)
+	case "ansible", "ini":
+		hosts, err = importer.ReadAnsible(in, mapping)
+	default:
+		return fmt.Errorf("unknown format %q, pass --format csv, yaml or ansible", *format)
+	}
+	if err != nil {
+		return err
+	}
+
+	defaults := importer.Defaults{
+		Type:            baremetalcontrollerv1.ControlType(*controlType),
+		SecretNamespace: *secretNamespace,
+		Fields:          importer.Host(defaultFields),
+	}
+
+	// Report every bad host at once instead of making the operator fix
+	// them one run at a time
+	var docs []string
+	var errs []error
+	seen := map[string]bool{}
+	for _, host := range hosts {
+		server, err := importer.ServerForHost(host, defaults)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if seen[server.Name] {
+			errs = append(errs, fmt.Errorf("host %s: duplicate name", server.Name))
+			continue
+		}
+		seen[server.Name] = true
+
+		doc, err := marshalServer(server)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, doc)
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	if _, err := io.WriteString(out, strings.Join(docs, "---\n")); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "converted %d hosts\n", len(docs))
+	return nil
+}
+
+// marshalServer renders a Server as YAML without status and other fields
+// that only the API server sets
+func marshalServer(server *baremetalcontrollerv1.Server) (string, error) {
+	data, err := yaml.Marshal(server)
+	if err != nil {
+		return "", err
+	}
+	var obj map[string]interface{}
+	if err := yaml.Unmarshal(data, &obj); err != nil {
+		return "", err
+	}
+	delete(obj, "status")
+	if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+		delete(metadata, "creationTimestamp")
+	}
+	data, err = yaml.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func formatFromExtension(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return "csv"
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".json":
+		return "json"
+	case ".ini", ".cfg", "":
+		return "ansible"
+	}
+	return ""
+}
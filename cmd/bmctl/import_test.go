@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+func TestFormatFromExtension(t *testing.T) {
+	tests := map[string]string{
+		"hosts.csv":       "csv",
+		"hosts.YAML":      "yaml",
+		"hosts.yml":       "yaml",
+		"hosts.json":      "json",
+		"inventory.ini":   "ansible",
+		"ansible.cfg":     "ansible",
+		"inventory/hosts": "ansible",
+		"-":               "ansible",
+		"hosts.txt":       "",
+	}
+	for path, want := range tests {
+		if got := formatFromExtension(path); got != want {
+			t.Errorf("formatFromExtension(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestMarshalServer(t *testing.T) {
+	server := &baremetalcontrollerv1.Server{
+		TypeMeta:   metav1.TypeMeta{APIVersion: baremetalcontrollerv1.GroupVersion.String(), Kind: "Server"},
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-01"},
+		Spec: baremetalcontrollerv1.ServerSpec{
+			PowerState: baremetalcontrollerv1.PowerStateOff,
+			Type:       baremetalcontrollerv1.ControlTypeIPMI,
+			Control:    baremetalcontrollerv1.ControlSpecs{IPMI: &baremetalcontrollerv1.IPMISpecs{Address: "10.0.1.11"}},
+		},
+	}
+	doc, err := marshalServer(server)
+	if err != nil {
+		t.Fatalf("marshalServer() error = %v", err)
+	}
+	if strings.Contains(doc, "status:") || strings.Contains(doc, "creationTimestamp") {
+		t.Errorf("manifest has fields only the API server sets:\n%s", doc)
+	}
+
+	var got baremetalcontrollerv1.Server
+	if err := yaml.UnmarshalStrict([]byte(doc), &got); err != nil {
+		t.Fatalf("manifest doesn't parse: %v\n%s", err, doc)
+	}
+	if got.Name != "worker-01" || got.Kind != "Server" || got.Spec.Control.IPMI == nil || got.Spec.Control.IPMI.Address != "10.0.1.11" {
+		t.Errorf("manifest round trip = %+v", got)
+	}
+}
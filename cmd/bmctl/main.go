@@ -0,0 +1,389 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// bmctl exercises the controller's power backends against ad-hoc targets, so
+// credentials and network paths can be checked before writing Server
+// manifests, and generates those manifests from existing inventories. It
+// doesn't talk to Kubernetes.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/Unbounder1/bare-metal-controller/internal/power"
+)
+
+const usage = `Diagnose bare metal power backends.
+
+Usage:
+  bmctl <command> [flags] [args]
+
+Flags go before the arguments of a command.
+
+Commands:
+  wol <mac>                                         Send a Wake-on-LAN magic packet
+  ping <address>                                    Check if a host answers ICMP echo
+  ssh shutdown|lldp <host>                          Shut down a host or list its LLDP neighbors over SSH
+  ipmi status|on|off|inventory|power <address>      Query or change power through IPMI
+  redfish status|on|off|inventory|power <address>   Query or change power through Redfish
+  maas status|on|off <endpoint> <system-id>         Query or change power through MAAS
+  equinix status|on|off <device-id>                 Query or change power through Equinix Metal
+  hetzner on|off|reset|rescue <server-number>       Change power through Hetzner Robot
+  import <file>                                     Convert a CSV, YAML or Ansible inventory into Server manifests
+  generate server                                   Print a Server manifest from flags or prompts
+
+Run "bmctl <command> --help" for the flags of a command.
+`
+
+func main() {
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	commands := map[string]func([]string) error{
+		"wol":      wolCommand,
+		"ping":     pingCommand,
+		"ssh":      sshCommand,
+		"ipmi":     ipmiCommand,
+		"redfish":  redfishCommand,
+		"maas":     maasCommand,
+		"equinix":  equinixCommand,
+		"hetzner":  hetznerCommand,
+		"import":   importCommand,
+		"generate": generateCommand,
+	}
+	command, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := command(args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// parseArgs parses a command's flags and exits with its usage unless exactly
+// n arguments remain
+func parseArgs(fs *flag.FlagSet, args []string, synopsis string, n int) []string {
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: bmctl %s %s\n", fs.Name(), synopsis)
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != n {
+		fs.Usage()
+		os.Exit(2)
+	}
+	return fs.Args()
+}
+
+func wolCommand(args []string) error {
+	fs := flag.NewFlagSet("wol", flag.ExitOnError)
+	port := fs.Int("port", 9, "UDP port to send the magic packet to")
+	broadcast := fs.String("broadcast", "255.255.255.255", "Broadcast address of the target's subnet")
+	mac := parseArgs(fs, args, "[flags] <mac>", 1)[0]
+
+	sender := &power.RealWolSender{DefaultPort: *port, DefaultBroadcastAddress: *broadcast}
+	if err := sender.Wake(context.Background(), mac, *port, *broadcast); err != nil {
+		return err
+	}
+	fmt.Printf("magic packet sent to %s via %s:%d\n", mac, *broadcast, *port)
+	return nil
+}
+
+func pingCommand(args []string) error {
+	fs := flag.NewFlagSet("ping", flag.ExitOnError)
+	address := parseArgs(fs, args, "<address>", 1)[0]
+
+	// Same check the controller uses to decide if a server is up
+	if !(&power.RealPinger{}).IsReachable(context.Background(), address) {
+		return fmt.Errorf("%s is not reachable (ICMP needs root or CAP_NET_RAW)", address)
+	}
+	fmt.Printf("%s is reachable\n", address)
+	return nil
+}
+
+func sshCommand(args []string) error {
+	fs := flag.NewFlagSet("ssh", flag.ExitOnError)
+	user := fs.String("user", "root", "SSH user")
+	keyFile := fs.String("key", "", "Path to the SSH private key (required)")
+	rest := parseArgs(fs, args, "[flags] shutdown|lldp <host>", 2)
+	action, host := rest[0], rest[1]
+
+	if *keyFile == "" {
+		return fmt.Errorf("--key is required")
+	}
+	key, err := os.ReadFile(*keyFile)
+	if err != nil {
+		return fmt.Errorf("unable to read SSH key: %w", err)
+	}
+
+	ctx := context.Background()
+	client := &power.RealSSHClient{}
+	switch action {
+	case "shutdown":
+		if err := client.Shutdown(ctx, host, *user, string(key)); err != nil {
+			return err
+		}
+		fmt.Printf("shutdown sent to %s\n", host)
+		return nil
+	case "lldp":
+		neighbors, err := client.GetLLDPNeighbors(ctx, host, *user, string(key))
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "INTERFACE\tSWITCH\tPORT\tVLAN")
+		for _, n := range neighbors {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", n.Interface, firstNonEmpty(n.SystemName, n.ChassisID), n.PortID, n.VLAN)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown ssh action %q, expected shutdown or lldp", action)
+	}
+}
+
+func ipmiCommand(args []string) error {
+	fs := flag.NewFlagSet("ipmi", flag.ExitOnError)
+	user := fs.String("user", "ADMIN", "IPMI user")
+	password := fs.String("password", os.Getenv("IPMI_PASSWORD"), "IPMI password (env IPMI_PASSWORD)")
+	rest := parseArgs(fs, args, "[flags] status|on|off|inventory|power <address>", 2)
+	action, address := rest[0], rest[1]
+
+	ctx := context.Background()
+	client := &power.RealIPMIClient{}
+	switch action {
+	case "status":
+		on, err := client.GetPowerStatus(ctx, address, *user, *password)
+		if err != nil {
+			return err
+		}
+		printPower(address, on)
+		return nil
+	case "on":
+		return client.PowerOn(ctx, address, *user, *password)
+	case "off":
+		return client.PowerOff(ctx, address, *user, *password)
+	case "inventory":
+		inventory, err := client.GetInventory(ctx, address, *user, *password)
+		if err != nil {
+			return err
+		}
+		return printInventory(inventory)
+	case "power":
+		limit, err := client.GetPowerLimit(ctx, address, *user, *password)
+		if err != nil {
+			return err
+		}
+		return printPowerLimit(limit)
+	default:
+		return fmt.Errorf("unknown ipmi action %q, expected status, on, off, inventory or power", action)
+	}
+}
+
+func redfishCommand(args []string) error {
+	fs := flag.NewFlagSet("redfish", flag.ExitOnError)
+	user := fs.String("user", "root", "Redfish user")
+	password := fs.String("password", os.Getenv("REDFISH_PASSWORD"), "Redfish password (env REDFISH_PASSWORD)")
+	systemID := fs.String("system", "", "System ID, defaults to the first system of the BMC")
+	caFile := fs.String("ca", "", "PEM file of certificates to verify the BMC against")
+	serverName := fs.String("server-name", "", "Name to verify the BMC certificate against instead of the address")
+	insecure := fs.Bool("insecure", true, "Skip verification of the BMC certificate, unless -ca or -server-name is set")
+	rest := parseArgs(fs, args, "[flags] status|on|off|inventory|power <address>", 2)
+	action := rest[0]
+
+	target := power.RedfishTarget{
+		Address:  rest[1],
+		Username: *user,
+		Password: *password,
+		SystemID: *systemID,
+		TLS: power.RedfishTLS{
+			ServerName:         *serverName,
+			InsecureSkipVerify: *insecure && *caFile == "" && *serverName == "",
+		},
+	}
+	if *caFile != "" {
+		caBundle, err := os.ReadFile(*caFile)
+		if err != nil {
+			return fmt.Errorf("failed to read CA file: %w", err)
+		}
+		target.TLS.CABundle = caBundle
+	}
+	client := &power.RealRedfishClient{}
+	switch action {
+	case "status":
+		on, err := client.GetPowerStatus(target)
+		if err != nil {
+			return err
+		}
+		printPower(target.Address, on)
+		return nil
+	case "on":
+		return client.PowerOn(target)
+	case "off":
+		return client.PowerOff(target)
+	case "inventory":
+		inventory, err := client.GetInventory(target)
+		if err != nil {
+			return err
+		}
+		return printInventory(inventory)
+	case "power":
+		limit, err := client.GetPowerLimit(target)
+		if err != nil {
+			return err
+		}
+		return printPowerLimit(limit)
+	default:
+		return fmt.Errorf("unknown redfish action %q, expected status, on, off, inventory or power", action)
+	}
+}
+
+func maasCommand(args []string) error {
+	fs := flag.NewFlagSet("maas", flag.ExitOnError)
+	apiKey := fs.String("api-key", os.Getenv("MAAS_API_KEY"), "MAAS API key (env MAAS_API_KEY)")
+	rest := parseArgs(fs, args, "[flags] status|on|off <endpoint> <system-id>", 3)
+	action, endpoint, systemID := rest[0], rest[1], rest[2]
+
+	client := &power.RealMAASClient{}
+	switch action {
+	case "status":
+		on, err := client.GetPowerStatus(endpoint, *apiKey, systemID)
+		if err != nil {
+			return err
+		}
+		printPower(systemID, on)
+		return nil
+	case "on":
+		return client.PowerOn(endpoint, *apiKey, systemID)
+	case "off":
+		return client.PowerOff(endpoint, *apiKey, systemID)
+	default:
+		return fmt.Errorf("unknown maas action %q, expected status, on or off", action)
+	}
+}
+
+func equinixCommand(args []string) error {
+	fs := flag.NewFlagSet("equinix", flag.ExitOnError)
+	token := fs.String("api-token", os.Getenv("METAL_AUTH_TOKEN"), "Equinix Metal API token (env METAL_AUTH_TOKEN)")
+	project := fs.String("project", "", "Project to find the device in by hostname, instead of a device ID")
+	rest := parseArgs(fs, args, "[flags] status|on|off <device-id>|<hostname>", 2)
+	action, deviceID := rest[0], rest[1]
+
+	client := &power.RealEquinixClient{}
+	if *project != "" {
+		id, err := client.FindDevice(*token, *project, deviceID)
+		if err != nil {
+			return err
+		}
+		deviceID = id
+	}
+	switch action {
+	case "status":
+		on, err := client.GetPowerStatus(*token, deviceID)
+		if err != nil {
+			return err
+		}
+		printPower(deviceID, on)
+		return nil
+	case "on":
+		return client.PowerOn(*token, deviceID)
+	case "off":
+		return client.PowerOff(*token, deviceID)
+	default:
+		return fmt.Errorf("unknown equinix action %q, expected status, on or off", action)
+	}
+}
+
+func hetznerCommand(args []string) error {
+	fs := flag.NewFlagSet("hetzner", flag.ExitOnError)
+	user := fs.String("user", "", "Robot webservice user")
+	password := fs.String("password", os.Getenv("HETZNER_ROBOT_PASSWORD"), "Robot webservice password (env HETZNER_ROBOT_PASSWORD)")
+	resetType := fs.String("type", "hw", "Reset type for reset, e.g. hw, sw or power")
+	rescueOS := fs.String("os", "linux", "Rescue system OS for rescue")
+	rest := parseArgs(fs, args, "[flags] on|off|reset|rescue <server-number>", 2)
+	action := rest[0]
+	serverNumber, err := strconv.ParseInt(rest[1], 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid server number %q", rest[1])
+	}
+	number := int32(serverNumber)
+
+	client := &power.RealHetznerClient{}
+	switch action {
+	case "on":
+		return client.Wake(*user, *password, number)
+	case "off":
+		return client.Reset(*user, *password, number, "power")
+	case "reset":
+		return client.Reset(*user, *password, number, *resetType)
+	case "rescue":
+		return client.EnableRescue(*user, *password, number, *rescueOS, nil)
+	default:
+		return fmt.Errorf("unknown hetzner action %q, expected on, off, reset or rescue", action)
+	}
+}
+
+func printPower(target string, on bool) {
+	state := "off"
+	if on {
+		state = "on"
+	}
+	fmt.Printf("%s is powered %s\n", target, state)
+}
+
+func printInventory(inventory power.Inventory) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "Manufacturer:\t%s\n", inventory.Manufacturer)
+	fmt.Fprintf(w, "Model:\t%s\n", inventory.Model)
+	fmt.Fprintf(w, "Serial number:\t%s\n", inventory.SerialNumber)
+	fmt.Fprintf(w, "BIOS version:\t%s\n", inventory.BIOSVersion)
+	fmt.Fprintf(w, "BMC firmware version:\t%s\n", inventory.BMCFirmwareVersion)
+	return w.Flush()
+}
+
+func printPowerLimit(limit power.PowerLimit) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "Power draw:\t%dW\n", limit.ConsumedWatts)
+	if limit.LimitWatts > 0 {
+		fmt.Fprintf(w, "Power limit:\t%dW\n", limit.LimitWatts)
+	} else {
+		fmt.Fprintf(w, "Power limit:\tnone\n")
+	}
+	return w.Flush()
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
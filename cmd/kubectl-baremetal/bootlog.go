@@ -0,0 +1,107 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Unbounder1/bare-metal-controller/internal/bootlog"
+	"github.com/Unbounder1/bare-metal-controller/internal/index"
+)
+
+func bootlogCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("bootlog", flag.ExitOnError)
+	endpoint := fs.String("endpoint", os.Getenv("BAREMETAL_CONSOLE_ENDPOINT"),
+		"URL of the controller's console proxy, e.g. https://localhost:8087 (env BAREMETAL_CONSOLE_ENDPOINT)")
+	token := fs.String("token", "", "Bearer token to authenticate with. Defaults to the kubeconfig token")
+	caFile := fs.String("certificate-authority", "", "Path to the CA certificate of the console proxy")
+	insecure := fs.Bool("insecure-skip-tls-verify", false, "Don't verify the console proxy certificate")
+	tail := fs.Int("tail", 0, "Show only the last lines of the boot, 0 for all kept lines")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: kubectl baremetal bootlog [flags] <server>")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	key := serverKey(fs.Arg(0))
+
+	if *endpoint == "" {
+		return fmt.Errorf("--endpoint is required")
+	}
+	if *token == "" {
+		t, err := kubeconfigToken()
+		if err != nil {
+			return err
+		}
+		*token = t
+	}
+	tlsConfig, err := proxyTLSConfig(*caFile, *insecure)
+	if err != nil {
+		return err
+	}
+	return fetchBootLog(ctx, *endpoint, key, *token, *tail, tlsConfig, os.Stdout)
+}
+
+// fetchBootLog copies the boot log of the server to w
+func fetchBootLog(ctx context.Context, endpoint string, server client.ObjectKey, token string, tail int, tlsConfig *tls.Config, w io.Writer) error {
+	name := index.ServerPath(server)
+	base, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return fmt.Errorf("invalid endpoint: %w", err)
+	}
+	if base.Scheme != "https" {
+		return fmt.Errorf("endpoint must use https")
+	}
+	base.Path += bootlog.PathPrefix + name
+	if tail > 0 {
+		base.RawQuery = url.Values{"tail": {strconv.Itoa(tail)}}.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to get boot log of %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unable to get boot log of %s: %s: %s", name, resp.Status, strings.TrimSpace(string(body)))
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
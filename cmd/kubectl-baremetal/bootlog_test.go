@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestFetchBootLog(t *testing.T) {
+	worker01 := client.ObjectKey{Name: "worker-01"}
+	proxy := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if req.URL.Path != "/bootlog/worker-01" && req.URL.Path != "/bootlog/team-a/worker-03" {
+			http.Error(w, "no boot log of server", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("tail=" + req.URL.Query().Get("tail") + "\n"))
+	}))
+	defer proxy.Close()
+	tlsConfig := &tls.Config{InsecureSkipVerify: true} //nolint:gosec
+
+	tests := []struct {
+		name     string
+		endpoint string
+		server   client.ObjectKey
+		token    string
+		tail     int
+		want     string
+		wantErr  string
+	}{
+		{name: "all lines", endpoint: proxy.URL, server: worker01, token: "secret", want: "tail=\n"},
+		{name: "tail", endpoint: proxy.URL + "/", server: worker01, token: "secret", tail: 20, want: "tail=20\n"},
+		{name: "namespaced", endpoint: proxy.URL, server: client.ObjectKey{Namespace: "team-a", Name: "worker-03"}, token: "secret", want: "tail=\n"},
+		{name: "unauthorized", endpoint: proxy.URL, server: worker01, token: "wrong", wantErr: "401 Unauthorized"},
+		{name: "no log", endpoint: proxy.URL, server: client.ObjectKey{Name: "worker-02"}, token: "secret", wantErr: "no boot log of server"},
+		{name: "plain http", endpoint: "http://localhost:8087", server: worker01, token: "secret", wantErr: "must use https"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := fetchBootLog(context.Background(), tt.endpoint, tt.server, tt.token, tt.tail, tlsConfig, &out)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("fetchBootLog() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fetchBootLog() error = %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("fetchBootLog() wrote %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
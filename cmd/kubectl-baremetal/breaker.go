@@ -0,0 +1,78 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Unbounder1/bare-metal-controller/internal/breaker"
+)
+
+func haltCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("halt", flag.ExitOnError)
+	configMap := fs.String("configmap", breaker.DefaultOptions().ConfigMap, "Namespace/name of the controller's circuit breaker ConfigMap")
+	reason := fs.String("reason", "", "Why power actions are halted, shown on held servers")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: kubectl baremetal halt [flags]")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	return setBreaker(ctx, *configMap, true, *reason)
+}
+
+func resumeCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("resume", flag.ExitOnError)
+	configMap := fs.String("configmap", breaker.DefaultOptions().ConfigMap, "Namespace/name of the controller's circuit breaker ConfigMap")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: kubectl baremetal resume [flags]")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	return setBreaker(ctx, *configMap, false, "")
+}
+
+// setBreaker opens or closes the circuit breaker
+func setBreaker(ctx context.Context, configMap string, halted bool, reason string) error {
+	key, err := breaker.ParseKey(configMap)
+	if err != nil {
+		return err
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	if err := breaker.Set(ctx, c, key, halted, reason); err != nil {
+		return fmt.Errorf("unable to update circuit breaker %s: %w", key, err)
+	}
+	if halted {
+		fmt.Printf("power actions halted by configmap/%s\n", key.Name)
+	} else {
+		fmt.Println("power actions resumed")
+	}
+	return nil
+}
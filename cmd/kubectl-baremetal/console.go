@@ -0,0 +1,204 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/net/websocket"
+	"golang.org/x/term"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Unbounder1/bare-metal-controller/internal/console"
+	"github.com/Unbounder1/bare-metal-controller/internal/index"
+)
+
+// escapeByte ends a console session (Ctrl-])
+const escapeByte = 0x1d
+
+func consoleCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("console", flag.ExitOnError)
+	endpoint := fs.String("endpoint", os.Getenv("BAREMETAL_CONSOLE_ENDPOINT"),
+		"URL of the controller's console proxy, e.g. https://localhost:8087 (env BAREMETAL_CONSOLE_ENDPOINT)")
+	token := fs.String("token", "", "Bearer token to authenticate with. Defaults to the kubeconfig token")
+	caFile := fs.String("certificate-authority", "", "Path to the CA certificate of the console proxy")
+	insecure := fs.Bool("insecure-skip-tls-verify", false, "Don't verify the console proxy certificate")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: kubectl baremetal console [flags] <server>")
+		fmt.Fprintln(os.Stderr, "Press Ctrl-] to disconnect.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	key := serverKey(fs.Arg(0))
+
+	if *endpoint == "" {
+		return fmt.Errorf("--endpoint is required")
+	}
+	if *token == "" {
+		t, err := kubeconfigToken()
+		if err != nil {
+			return err
+		}
+		*token = t
+	}
+
+	tlsConfig, err := proxyTLSConfig(*caFile, *insecure)
+	if err != nil {
+		return err
+	}
+
+	ws, err := dialConsole(*endpoint, key, *token, tlsConfig)
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		state, err := term.MakeRaw(int(os.Stdin.Fd()))
+		if err != nil {
+			return fmt.Errorf("unable to set terminal to raw mode: %w", err)
+		}
+		defer func() { _ = term.Restore(int(os.Stdin.Fd()), state) }()
+	}
+	fmt.Fprintf(os.Stderr, "Connected to server/%s, press Ctrl-] to disconnect\r\n", index.ServerPath(key))
+
+	done := make(chan error, 2)
+	go func() {
+		_, err := io.Copy(os.Stdout, ws)
+		done <- err
+	}()
+	go func() {
+		done <- copyUntilEscape(ws, os.Stdin)
+	}()
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+	}
+	fmt.Fprint(os.Stderr, "\r\nDisconnected\r\n")
+	return err
+}
+
+// proxyTLSConfig verifies the console proxy with the CA certificate in
+// caFile, or the system roots if it is empty
+func proxyTLSConfig(caFile string, insecure bool) (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: insecure} //nolint:gosec
+	if caFile != "" {
+		ca, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA certificate: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+	}
+	return tlsConfig, nil
+}
+
+// dialConsole opens a websocket to the console of the server
+func dialConsole(endpoint string, server client.ObjectKey, token string, tlsConfig *tls.Config) (*websocket.Conn, error) {
+	name := index.ServerPath(server)
+	base, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+	origin := *base
+	switch base.Scheme {
+	case "https", "wss":
+		base.Scheme = "wss"
+		origin.Scheme = "https"
+	default:
+		return nil, fmt.Errorf("endpoint must use https")
+	}
+	base.Path += console.PathPrefix + name
+
+	config, err := websocket.NewConfig(base.String(), origin.String())
+	if err != nil {
+		return nil, err
+	}
+	config.Header.Set("Authorization", "Bearer "+token)
+	config.TlsConfig = tlsConfig
+
+	ws, err := websocket.DialConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to console of %s: %w", name, err)
+	}
+	ws.PayloadType = websocket.BinaryFrame
+	return ws, nil
+}
+
+// copyUntilEscape copies src to dst until src ends or the escape byte is read
+func copyUntilEscape(dst io.Writer, src io.Reader) error {
+	buf := make([]byte, 1024)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			data := buf[:n]
+			i := bytes.IndexByte(data, escapeByte)
+			if i >= 0 {
+				data = data[:i]
+			}
+			if _, werr := dst.Write(data); werr != nil {
+				return werr
+			}
+			if i >= 0 {
+				return nil
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// kubeconfigToken returns the bearer token of the current kubeconfig context
+func kubeconfigToken() (string, error) {
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return "", fmt.Errorf("unable to load kubeconfig: %w", err)
+	}
+	if cfg.BearerToken != "" {
+		return cfg.BearerToken, nil
+	}
+	if cfg.BearerTokenFile != "" {
+		data, err := os.ReadFile(cfg.BearerTokenFile)
+		if err != nil {
+			return "", fmt.Errorf("unable to read token file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	return "", fmt.Errorf("kubeconfig has no bearer token, pass --token (e.g. from kubectl create token)")
+}
@@ -0,0 +1,382 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// kubectl-baremetal is a kubectl plugin for operating Servers. Install it on
+// the PATH and run it as "kubectl baremetal".
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/drain"
+	"github.com/Unbounder1/bare-metal-controller/internal/index"
+	"github.com/Unbounder1/bare-metal-controller/internal/listing"
+)
+
+const usage = `Operate bare metal Servers.
+
+Usage:
+  kubectl baremetal [--kubeconfig=PATH] [-n NAMESPACE] <command> [flags] [args]
+
+Flags go before the arguments of a command. Namespaced Servers are found with
+-n/--namespace, which is empty for cluster-scoped Servers.
+
+Commands:
+  power on|off|cycle <server>  Power on, off or reboot and wait for it
+  status [server...]           Show power state and status of servers
+  drain <server>               Cordon the server's node and evict its pods
+  uncordon <server>            Make the server's node schedulable again
+  console <server>             Attach to the server's serial console
+  bootlog <server>             Show what the server logged while booting
+  halt [--reason=TEXT]         Halt all power actions of the controller
+  resume                       Resume power actions after a halt
+
+Run "kubectl baremetal <command> --help" for the flags of a command.
+`
+
+var scheme = runtime.NewScheme()
+
+// namespace of the servers given as arguments, empty for cluster-scoped
+// Servers
+var namespace string
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(baremetalcontrollerv1.AddToScheme(scheme))
+}
+
+func main() {
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	// --kubeconfig is registered by controller-runtime
+	flag.StringVar(&namespace, "namespace", "", "Namespace of the servers, empty for cluster-scoped Servers")
+	flag.StringVar(&namespace, "n", "", "Shorthand for --namespace")
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	commands := map[string]func(context.Context, []string) error{
+		"power":    powerCommand,
+		"status":   statusCommand,
+		"drain":    drainCommand,
+		"uncordon": uncordonCommand,
+		"console":  consoleCommand,
+		"bootlog":  bootlogCommand,
+		"halt":     haltCommand,
+		"resume":   resumeCommand,
+	}
+	command, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := command(context.Background(), args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// newClient connects to the cluster from the kubeconfig
+func newClient() (client.Client, error) {
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("unable to load kubeconfig: %w", err)
+	}
+	return client.New(cfg, client.Options{Scheme: scheme})
+}
+
+// serverKey returns the key of the named server in the --namespace
+func serverKey(name string) client.ObjectKey {
+	return client.ObjectKey{Namespace: namespace, Name: name}
+}
+
+func powerCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("power", flag.ExitOnError)
+	noWait := fs.Bool("no-wait", false, "Return without waiting for the server to reach the new state")
+	timeout := fs.Duration("timeout", 15*time.Minute, "How long to wait for the server")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: kubectl baremetal power [flags] on|off|cycle <server>")
+		fmt.Fprintln(os.Stderr, "\ncycle sets powerState to reboot and waits for the server to reboot and be active again.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	action, key := fs.Arg(0), serverKey(fs.Arg(1))
+
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "on":
+		return setPower(ctx, c, key, baremetalcontrollerv1.PowerStateOn, !*noWait, *timeout)
+	case "off":
+		return setPower(ctx, c, key, baremetalcontrollerv1.PowerStateOff, !*noWait, *timeout)
+	case "cycle":
+		return cycle(ctx, c, key, !*noWait, *timeout)
+	default:
+		return fmt.Errorf("unknown power action %q, expected on, off or cycle", action)
+	}
+}
+
+// setPower patches spec.powerState and optionally waits for the matching status
+func setPower(ctx context.Context, c client.Client, key client.ObjectKey, state baremetalcontrollerv1.PowerState, waitForStatus bool, timeout time.Duration) error {
+	var server baremetalcontrollerv1.Server
+	if err := c.Get(ctx, key, &server); err != nil {
+		return err
+	}
+	name := index.ServerPath(key)
+
+	if server.Spec.PowerState != state {
+		if err := patchPowerState(ctx, c, &server, state); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("server/%s powerState set to %s\n", name, state)
+
+	if !waitForStatus {
+		return nil
+	}
+
+	want := baremetalcontrollerv1.StatusActive
+	if state == baremetalcontrollerv1.PowerStateOff {
+		want = baremetalcontrollerv1.StatusOffline
+	}
+	fmt.Printf("waiting for server/%s to be %s...\n", name, want)
+
+	err := waitForServer(ctx, c, key, timeout, func(server *baremetalcontrollerv1.Server) bool {
+		return server.Status.Status == want
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("server/%s is %s\n", name, want)
+	return nil
+}
+
+// cycle sets spec.powerState to reboot and optionally waits for the server
+// to go through rebooting and be active again. The controller reboots once
+// per generation of the spec, so a server already set to reboot is set to
+// on first.
+func cycle(ctx context.Context, c client.Client, key client.ObjectKey, waitForStatus bool, timeout time.Duration) error {
+	var server baremetalcontrollerv1.Server
+	if err := c.Get(ctx, key, &server); err != nil {
+		return err
+	}
+	name := index.ServerPath(key)
+
+	if server.Spec.PowerState == baremetalcontrollerv1.PowerStateReboot {
+		if err := patchPowerState(ctx, c, &server, baremetalcontrollerv1.PowerStateOn); err != nil {
+			return err
+		}
+	}
+	if err := patchPowerState(ctx, c, &server, baremetalcontrollerv1.PowerStateReboot); err != nil {
+		return err
+	}
+	generation := server.Generation
+	fmt.Printf("server/%s powerState set to %s\n", name, baremetalcontrollerv1.PowerStateReboot)
+
+	if !waitForStatus {
+		return nil
+	}
+	fmt.Printf("waiting for server/%s to reboot and be %s...\n", name, baremetalcontrollerv1.StatusActive)
+
+	rebooting := false
+	err := waitForServer(ctx, c, key, timeout, func(server *baremetalcontrollerv1.Server) bool {
+		if server.Status.Status == baremetalcontrollerv1.StatusRebooting && !rebooting {
+			rebooting = true
+			fmt.Printf("server/%s is %s\n", name, baremetalcontrollerv1.StatusRebooting)
+		}
+		// A server that was off is powered on instead, which also records
+		// the reboot
+		rebooted := server.Status.Reboot != nil && server.Status.Reboot.Generation >= generation
+		return rebooted && server.Status.Status == baremetalcontrollerv1.StatusActive
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("server/%s is %s\n", name, baremetalcontrollerv1.StatusActive)
+	return nil
+}
+
+// patchPowerState sets spec.powerState of the server
+func patchPowerState(ctx context.Context, c client.Client, server *baremetalcontrollerv1.Server, state baremetalcontrollerv1.PowerState) error {
+	patch := client.MergeFrom(server.DeepCopy())
+	server.Spec.PowerState = state
+	if err := c.Patch(ctx, server, patch); err != nil {
+		return fmt.Errorf("unable to power %s server %s: %w", state, index.ServerPath(client.ObjectKeyFromObject(server)), err)
+	}
+	return nil
+}
+
+// waitForServer polls the server until done returns true, and fails if the
+// server fails
+func waitForServer(ctx context.Context, c client.Client, key client.ObjectKey, timeout time.Duration, done func(*baremetalcontrollerv1.Server) bool) error {
+	name := index.ServerPath(key)
+	err := wait.PollUntilContextTimeout(ctx, 2*time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		var server baremetalcontrollerv1.Server
+		if err := c.Get(ctx, key, &server); err != nil {
+			return false, err
+		}
+		if server.Status.Status == baremetalcontrollerv1.StatusFailed {
+			return false, fmt.Errorf("server %s failed: %s", name, server.Status.Message)
+		}
+		return done(&server), nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for server %s: %w", name, err)
+	}
+	return nil
+}
+
+func statusCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: kubectl baremetal status [server...]")
+	}
+	_ = fs.Parse(args)
+
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tPOWER\tSTATUS\tFAILURES\tMESSAGE")
+	printServer := func(server *baremetalcontrollerv1.Server) error {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n",
+			index.ServerPath(client.ObjectKeyFromObject(server)), server.Spec.Type, server.Spec.PowerState,
+			server.Status.Status, server.Status.FailureCount, server.Status.Message)
+		return nil
+	}
+
+	if fs.NArg() == 0 {
+		// Page through large fleets instead of reading every server at once
+		if err := listing.Servers(ctx, c, listing.DefaultPageSize, printServer, client.InNamespace(namespace)); err != nil {
+			return err
+		}
+	} else {
+		for _, name := range fs.Args() {
+			var server baremetalcontrollerv1.Server
+			if err := c.Get(ctx, serverKey(name), &server); err != nil {
+				return err
+			}
+			_ = printServer(&server)
+		}
+	}
+	return w.Flush()
+}
+
+func drainCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("drain", flag.ExitOnError)
+	timeout := fs.Duration("timeout", 10*time.Minute, "How long to wait for pods to be evicted")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: kubectl baremetal drain [flags] <server>")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	name := fs.Arg(0)
+
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	if name, err = nodeName(ctx, c, name); err != nil {
+		return err
+	}
+
+	fmt.Printf("draining node/%s...\n", name)
+	err = wait.PollUntilContextTimeout(ctx, 5*time.Second, *timeout, true, func(ctx context.Context) (bool, error) {
+		return drain.Node(ctx, c, c, name)
+	})
+	if err != nil {
+		return fmt.Errorf("draining node %s: %w", name, err)
+	}
+	fmt.Printf("node/%s drained\n", name)
+	return nil
+}
+
+// nodeName returns the name of the server's node, or the name itself if
+// there is no such server
+func nodeName(ctx context.Context, c client.Client, name string) (string, error) {
+	var server baremetalcontrollerv1.Server
+	if err := c.Get(ctx, serverKey(name), &server); err != nil {
+		if apierrors.IsNotFound(err) {
+			return name, nil
+		}
+		return "", err
+	}
+	return server.NodeName(), nil
+}
+
+func uncordonCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("uncordon", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: kubectl baremetal uncordon <server>")
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	name := fs.Arg(0)
+
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	if name, err = nodeName(ctx, c, name); err != nil {
+		return err
+	}
+
+	if err := drain.Uncordon(ctx, c, name); err != nil {
+		return err
+	}
+	fmt.Printf("node/%s uncordoned\n", name)
+	return nil
+}
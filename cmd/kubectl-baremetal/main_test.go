@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+func TestSetPower(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  baremetalcontrollerv1.CurrentStatus
+		message string
+		state   baremetalcontrollerv1.PowerState
+		wait    bool
+		wantErr string
+	}{
+		{name: "no wait", status: baremetalcontrollerv1.StatusOffline, state: baremetalcontrollerv1.PowerStateOn},
+		{name: "already active", status: baremetalcontrollerv1.StatusActive, state: baremetalcontrollerv1.PowerStateOn, wait: true},
+		{name: "already offline", status: baremetalcontrollerv1.StatusOffline, state: baremetalcontrollerv1.PowerStateOff, wait: true},
+		{
+			name:    "failed",
+			status:  baremetalcontrollerv1.StatusFailed,
+			message: "Power action failed: BMC unreachable",
+			state:   baremetalcontrollerv1.PowerStateOn,
+			wait:    true,
+			wantErr: "BMC unreachable",
+		},
+		{
+			name:    "timeout",
+			status:  baremetalcontrollerv1.StatusPending,
+			state:   baremetalcontrollerv1.PowerStateOn,
+			wait:    true,
+			wantErr: "waiting for server worker-01",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &baremetalcontrollerv1.Server{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker-01"},
+				Spec:       baremetalcontrollerv1.ServerSpec{PowerState: baremetalcontrollerv1.PowerStateOff, Type: baremetalcontrollerv1.ControlTypeWOL},
+				Status:     baremetalcontrollerv1.ServerStatus{Status: tt.status, Message: tt.message},
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(server).
+				WithStatusSubresource(&baremetalcontrollerv1.Server{}).Build()
+			ctx := context.Background()
+
+			err := setPower(ctx, c, client.ObjectKey{Name: "worker-01"}, tt.state, tt.wait, 100*time.Millisecond)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("setPower() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("setPower() error = %v", err)
+			}
+
+			var got baremetalcontrollerv1.Server
+			if err := c.Get(ctx, client.ObjectKey{Name: "worker-01"}, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Spec.PowerState != tt.state {
+				t.Errorf("powerState = %s, want %s", got.Spec.PowerState, tt.state)
+			}
+		})
+	}
+}
+
+func TestSetPowerMissingServer(t *testing.T) {
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	if err := setPower(context.Background(), c, client.ObjectKey{Name: "worker-99"}, baremetalcontrollerv1.PowerStateOn, false, time.Second); err == nil {
+		t.Errorf("setPower() succeeded for a missing server")
+	}
+}
+
+func TestCycle(t *testing.T) {
+	tests := []struct {
+		name        string
+		powerState  baremetalcontrollerv1.PowerState
+		status      baremetalcontrollerv1.CurrentStatus
+		rebootedFor int64
+		wait        bool
+		wantPatches []baremetalcontrollerv1.PowerState
+		wantErr     string
+	}{
+		{
+			name:        "no wait",
+			powerState:  baremetalcontrollerv1.PowerStateOn,
+			status:      baremetalcontrollerv1.StatusActive,
+			wantPatches: []baremetalcontrollerv1.PowerState{baremetalcontrollerv1.PowerStateReboot},
+		},
+		{
+			// A reboot is only sent once per generation of the spec
+			name:        "already set to reboot",
+			powerState:  baremetalcontrollerv1.PowerStateReboot,
+			status:      baremetalcontrollerv1.StatusActive,
+			wantPatches: []baremetalcontrollerv1.PowerState{baremetalcontrollerv1.PowerStateOn, baremetalcontrollerv1.PowerStateReboot},
+		},
+		{
+			name:        "rebooted and active",
+			powerState:  baremetalcontrollerv1.PowerStateOn,
+			status:      baremetalcontrollerv1.StatusActive,
+			rebootedFor: 3,
+			wait:        true,
+			wantPatches: []baremetalcontrollerv1.PowerState{baremetalcontrollerv1.PowerStateReboot},
+		},
+		{
+			name:        "rebooting",
+			powerState:  baremetalcontrollerv1.PowerStateOn,
+			status:      baremetalcontrollerv1.StatusRebooting,
+			rebootedFor: 3,
+			wait:        true,
+			wantPatches: []baremetalcontrollerv1.PowerState{baremetalcontrollerv1.PowerStateReboot},
+			wantErr:     "waiting for server worker-01",
+		},
+		{
+			// Still active from before the reboot was asked for
+			name:        "not rebooted yet",
+			powerState:  baremetalcontrollerv1.PowerStateOn,
+			status:      baremetalcontrollerv1.StatusActive,
+			rebootedFor: 2,
+			wait:        true,
+			wantPatches: []baremetalcontrollerv1.PowerState{baremetalcontrollerv1.PowerStateReboot},
+			wantErr:     "waiting for server worker-01",
+		},
+		{
+			name:        "failed",
+			powerState:  baremetalcontrollerv1.PowerStateOn,
+			status:      baremetalcontrollerv1.StatusFailed,
+			wait:        true,
+			wantPatches: []baremetalcontrollerv1.PowerState{baremetalcontrollerv1.PowerStateReboot},
+			wantErr:     "RebootTimeout",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &baremetalcontrollerv1.Server{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker-01", Generation: 3},
+				Spec:       baremetalcontrollerv1.ServerSpec{PowerState: tt.powerState, Type: baremetalcontrollerv1.ControlTypeIPMI},
+				Status:     baremetalcontrollerv1.ServerStatus{Status: tt.status, Message: "Server failed: RebootTimeout"},
+			}
+			if tt.rebootedFor != 0 {
+				server.Status.Reboot = &baremetalcontrollerv1.RebootStatus{Generation: tt.rebootedFor}
+			}
+			var patches []baremetalcontrollerv1.PowerState
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(server).
+				WithStatusSubresource(&baremetalcontrollerv1.Server{}).
+				WithInterceptorFuncs(interceptor.Funcs{
+					Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+						patches = append(patches, obj.(*baremetalcontrollerv1.Server).Spec.PowerState)
+						return c.Patch(ctx, obj, patch, opts...)
+					},
+				}).Build()
+
+			err := cycle(context.Background(), c, client.ObjectKey{Name: "worker-01"}, tt.wait, 100*time.Millisecond)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("cycle() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("cycle() error = %v", err)
+			}
+			if len(patches) != len(tt.wantPatches) {
+				t.Fatalf("patched powerState to %v, want %v", patches, tt.wantPatches)
+			}
+			for i := range patches {
+				if patches[i] != tt.wantPatches[i] {
+					t.Errorf("patched powerState to %v, want %v", patches, tt.wantPatches)
+				}
+			}
+		})
+	}
+}
@@ -17,9 +17,11 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -37,7 +39,33 @@ import (
 
 	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
 	grpcserver "github.com/Unbounder1/bare-metal-controller/external"
+	"github.com/Unbounder1/bare-metal-controller/internal/bootlog"
+	"github.com/Unbounder1/bare-metal-controller/internal/breaker"
+	"github.com/Unbounder1/bare-metal-controller/internal/cluster"
+	"github.com/Unbounder1/bare-metal-controller/internal/console"
 	"github.com/Unbounder1/bare-metal-controller/internal/controller"
+	"github.com/Unbounder1/bare-metal-controller/internal/dashboard"
+	"github.com/Unbounder1/bare-metal-controller/internal/dhcp"
+	"github.com/Unbounder1/bare-metal-controller/internal/dns"
+	"github.com/Unbounder1/bare-metal-controller/internal/energy"
+	"github.com/Unbounder1/bare-metal-controller/internal/fleet"
+	"github.com/Unbounder1/bare-metal-controller/internal/idle"
+	"github.com/Unbounder1/bare-metal-controller/internal/index"
+	"github.com/Unbounder1/bare-metal-controller/internal/metal3"
+	"github.com/Unbounder1/bare-metal-controller/internal/neighbor"
+	"github.com/Unbounder1/bare-metal-controller/internal/nodefeatures"
+	"github.com/Unbounder1/bare-metal-controller/internal/pet"
+	"github.com/Unbounder1/bare-metal-controller/internal/power"
+	"github.com/Unbounder1/bare-metal-controller/internal/preflight"
+	"github.com/Unbounder1/bare-metal-controller/internal/pricing"
+	"github.com/Unbounder1/bare-metal-controller/internal/relay"
+	"github.com/Unbounder1/bare-metal-controller/internal/resolve"
+	"github.com/Unbounder1/bare-metal-controller/internal/scope"
+	"github.com/Unbounder1/bare-metal-controller/internal/shard"
+	"github.com/Unbounder1/bare-metal-controller/internal/telemetry"
+	"github.com/Unbounder1/bare-metal-controller/internal/ups"
+	"github.com/Unbounder1/bare-metal-controller/internal/wake"
+	webhookv1 "github.com/Unbounder1/bare-metal-controller/internal/webhook/v1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -56,13 +84,50 @@ func init() {
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
+	var leaderElectionNamespace string
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var enableTinkerbell bool
+	var enableWebhooks bool
+	var wolBroadcastAddress string
+	var bmcSessionIdleTimeout time.Duration
+	var powerWorkers int
+	var tenantPowerWorkers int
+	var fairQueue bool
+	var powerShutdownGracePeriod time.Duration
+	var nodeCleanup bool
+	var bmcColdResetBackoff time.Duration
+	var approveKubeletCSRs bool
+	var csrBootWindow time.Duration
 	var tlsOpts []func(*tls.Config)
 
 	// Use default grpc options
 	grpcOpts := grpcserver.DefaultOptions()
+	var metal3Opts metal3.Options
+	consoleOpts := console.DefaultOptions()
+	dashboardOpts := dashboard.DefaultOptions()
+	preflightOpts := preflight.DefaultOptions()
+	var shardOpts shard.Options
+	var scopeOpts scope.Options
+	var fleetOpts fleet.Options
+	upsOpts := ups.DefaultOptions()
+	idleOpts := idle.DefaultOptions()
+	wakeOpts := wake.DefaultOptions()
+	pricingOpts := pricing.DefaultOptions()
+	carbonOpts := pricing.DefaultCarbonOptions()
+	energyOpts := energy.DefaultOptions()
+	relayOpts := relay.DefaultOptions()
+	dnsOpts := dns.DefaultOptions()
+	dhcpOpts := dhcp.DefaultOptions()
+	neighborOpts := neighbor.DefaultOptions()
+	resolverOpts := resolve.DefaultOptions()
+	breakerOpts := breaker.DefaultOptions()
+	retryPolicy := power.DefaultRetryPolicy()
+	nodeFeatureOpts := nodefeatures.DefaultOptions()
+	telemetryOpts := telemetry.DefaultOptions()
+	petOpts := pet.DefaultOptions()
+	bootLogOpts := bootlog.DefaultOptions()
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -70,10 +135,63 @@ func main() {
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&leaderElectionNamespace, "leader-election-namespace", "",
+		"Namespace of the leader election lease. Required with --leader-elect when running outside a cluster.")
 	flag.BoolVar(&secureMetrics, "metrics-secure", true,
 		"If set, the metrics endpoint is served securely via HTTPS. Use --metrics-secure=false to use HTTP instead.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.BoolVar(&enableTinkerbell, "enable-tinkerbell", false,
+		"If set, servers with spec.provisioning.tinkerbell are provisioned through an existing Tinkerbell stack.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"If set, the defaulting and validating webhooks for Servers are served. "+
+			"They need a serving certificate in the webhook server's cert directory.")
+	flag.StringVar(&wolBroadcastAddress, "wol-broadcast-address", "255.255.255.255",
+		"Address Wake-on-LAN packets are broadcast to for servers without spec.control.wol.broadcastAddress.")
+	flag.DurationVar(&bmcSessionIdleTimeout, "bmc-session-idle-timeout", 5*time.Minute,
+		"How long an idle Redfish session to a BMC is kept open before logging out. 0 authenticates every request.")
+	flag.IntVar(&powerWorkers, "power-workers", 10,
+		"Number of power actions run concurrently outside of reconciles. 0 runs them inside the reconcile.")
+	flag.IntVar(&tenantPowerWorkers, "tenant-power-workers", 0,
+		"Number of power workers the servers of one tenant (baremetal.io/tenant label) may occupy at once. 0 doesn't limit tenants.")
+	flag.DurationVar(&powerShutdownGracePeriod, "power-shutdown-grace-period", 20*time.Second,
+		"How long running power actions may take to finish when the controller shuts down. "+
+			"Actions still running are then cancelled and recorded as interrupted, to be retried after the restart.")
+	flag.BoolVar(&fairQueue, "fair-reconcile-queue", true,
+		"If set, servers whose spec changed are reconciled before periodic probes, and ServerClasses take turns with their probes. "+
+			"Otherwise servers are reconciled in FIFO order.")
+	flag.DurationVar(&bmcColdResetBackoff, "bmc-cold-reset-backoff", time.Hour,
+		"Least time between cold resets of a BMC that answers pings but not IPMI sessions, sent while its server is failed. 0 disables cold resets.")
+	flag.BoolVar(&nodeCleanup, "node-cleanup", false,
+		"If set, deleting a Server drains and deletes its Node, and Nodes whose Server was removed are deleted.")
+	flag.BoolVar(&approveKubeletCSRs, "approve-kubelet-csrs", false,
+		"If set, kubelet client and serving CSRs are approved for servers the controller just booted.")
+	flag.DurationVar(&csrBootWindow, "csr-boot-window", 30*time.Minute,
+		"How long after a server turned active its kubelet CSRs are approved, with --approve-kubelet-csrs.")
+	metal3Opts.BindFlags(flag.CommandLine, "metal3-")
+	consoleOpts.BindFlags(flag.CommandLine, "console-")
+	dashboardOpts.BindFlags(flag.CommandLine, "dashboard-")
+	preflightOpts.BindFlags(flag.CommandLine, "preflight-")
+	shardOpts.BindFlags(flag.CommandLine, "shard-")
+	scopeOpts.BindFlags(flag.CommandLine, "")
+	fleetOpts.BindFlags(flag.CommandLine, "simulate-")
+	upsOpts.BindFlags(flag.CommandLine, "ups-")
+	idleOpts.BindFlags(flag.CommandLine, "idle-")
+	wakeOpts.BindFlags(flag.CommandLine, "wake-")
+	pricingOpts.BindFlags(flag.CommandLine, "pricing-")
+	carbonOpts.BindFlags(flag.CommandLine, "carbon-")
+	energyOpts.BindFlags(flag.CommandLine, "energy-")
+	relayOpts.BindFlags(flag.CommandLine, "wol-relay-")
+	dnsOpts.BindFlags(flag.CommandLine, "dns-")
+	dhcpOpts.BindFlags(flag.CommandLine, "dhcp-")
+	neighborOpts.BindFlags(flag.CommandLine, "neighbor-")
+	resolverOpts.BindFlags(flag.CommandLine, "resolver-")
+	breakerOpts.BindFlags(flag.CommandLine, "breaker-")
+	retryPolicy.BindFlags(flag.CommandLine, "power-retry-")
+	nodeFeatureOpts.BindFlags(flag.CommandLine, "node-features-")
+	telemetryOpts.BindFlags(flag.CommandLine, "telemetry-")
+	petOpts.BindFlags(flag.CommandLine, "pet-")
+	bootLogOpts.BindFlags(flag.CommandLine, "bootlog-")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -123,13 +241,52 @@ func main() {
 		// this setup is not recommended for production.
 	}
 
+	if err := fleetOpts.Validate(); err != nil {
+		setupLog.Error(err, "invalid simulation options")
+		os.Exit(1)
+	}
+	if err := retryPolicy.Validate(); err != nil {
+		setupLog.Error(err, "invalid power retry options")
+		os.Exit(1)
+	}
+	if err := shardOpts.Validate(); err != nil {
+		setupLog.Error(err, "invalid shard options")
+		os.Exit(1)
+	}
+	// Shards and scoped instances only cache their own objects, so every
+	// controller and the gRPC provider see just their part of the fleet
+	cacheOpts, err := shardOpts.CacheOptions()
+	if err != nil {
+		setupLog.Error(err, "unable to configure shard cache")
+		os.Exit(1)
+	}
+	if err := scopeOpts.Validate(); err != nil {
+		setupLog.Error(err, "invalid scope options")
+		os.Exit(1)
+	}
+	if err := scopeOpts.Apply(&cacheOpts); err != nil {
+		setupLog.Error(err, "unable to configure cache scope")
+		os.Exit(1)
+	}
+	if shardOpts.Enabled() {
+		setupLog.Info("Running as shard", "shard", shardOpts.Name, "selector", shardOpts.Selector)
+	}
+	if scopeOpts.WatchNamespace != "" || scopeOpts.ServerSelector != "" {
+		setupLog.Info("Scoped to a subset of objects",
+			"namespace", scopeOpts.WatchNamespace,
+			"serverSelector", scopeOpts.ServerSelector)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		Metrics:                metricsServerOptions,
 		WebhookServer:          webhookServer,
 		HealthProbeBindAddress: probeAddr,
+		Cache:                  cacheOpts,
 		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "1ff77049.bare-metal.io",
+		LeaderElectionID:       shardOpts.LeaderElectionID("1ff77049.bare-metal.io"),
+		// Only needed outside a cluster, in-cluster defaults to the pod's namespace
+		LeaderElectionNamespace: leaderElectionNamespace,
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -147,20 +304,303 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Index servers by MAC, address and provider ID for the gRPC provider
+	if err := index.Setup(context.Background(), mgr.GetFieldIndexer()); err != nil {
+		setupLog.Error(err, "unable to set up server indexes")
+		os.Exit(1)
+	}
+
+	// Servers behind routers are woken and pinged by relay agents on their
+	// subnets
+	var wolRelay power.WolRelay
+	var relayClient *relay.Client
+	if relayOpts.Enabled() {
+		if err := relayOpts.Validate(); err != nil {
+			setupLog.Error(err, "invalid WoL relay options")
+			os.Exit(1)
+		}
+		relayClient, err = relay.NewClient(relayOpts)
+		if err != nil {
+			setupLog.Error(err, "unable to create WoL relay client")
+			os.Exit(1)
+		}
+		defer relayClient.Close()
+		wolRelay = relayClient
+		setupLog.Info("WoL relays configured", "relays", relayOpts.Addresses)
+	}
+
+	// Servers with spec.clusterRef join workload clusters other than this one
+	clusters := cluster.NewClients(mgr.GetClient(), mgr.GetScheme())
+
+	// Hostnames in server addresses are resolved again as their records expire
+	resolver, err := resolve.New(resolverOpts)
+	if err != nil {
+		setupLog.Error(err, "invalid resolver options")
+		os.Exit(1)
+	}
+
+	// An open circuit breaker halts all power actions
+	powerBreaker, err := breaker.New(mgr.GetAPIReader(), breakerOpts)
+	if err != nil {
+		setupLog.Error(err, "invalid circuit breaker options")
+		os.Exit(1)
+	}
+
+	// Redfish BMCs push their power draw and temperatures instead of being
+	// polled for them
+	var telemetryReceiver *telemetry.Receiver
+	if telemetryOpts.Enabled() {
+		if err := telemetryOpts.Validate(); err != nil {
+			setupLog.Error(err, "invalid telemetry options")
+			os.Exit(1)
+		}
+		telemetryReceiver = telemetry.NewReceiver(telemetryOpts)
+		if err := mgr.Add(telemetryReceiver); err != nil {
+			setupLog.Error(err, "unable to add telemetry receiver to manager")
+			os.Exit(1)
+		}
+		setupLog.Info("Redfish telemetry configured", "address", telemetryOpts.Address, "url", telemetryOpts.URL)
+	}
+
+	// BMCs report hardware failures with Platform Event Traps within
+	// seconds, matched to servers by the address they come from
+	var petReceiver *pet.Receiver
+	if petOpts.Enabled() {
+		if err := petOpts.Validate(); err != nil {
+			setupLog.Error(err, "invalid platform event trap options")
+			os.Exit(1)
+		}
+		petReceiver = pet.NewReceiver(petOpts, mgr.GetClient())
+		if err := mgr.Add(petReceiver); err != nil {
+			setupLog.Error(err, "unable to add platform event trap receiver to manager")
+			os.Exit(1)
+		}
+		setupLog.Info("Platform event traps configured", "address", petOpts.Address)
+	}
+
+	// Servers send their syslog while they boot, served by the console
+	// proxy to the kubectl plugin
+	var bootLogReceiver *bootlog.Receiver
+	if bootLogOpts.Enabled() {
+		if err := bootLogOpts.Validate(); err != nil {
+			setupLog.Error(err, "invalid boot log options")
+			os.Exit(1)
+		}
+		bootLogReceiver = bootlog.NewReceiver(bootLogOpts, mgr.GetClient())
+		if err := mgr.Add(bootLogReceiver); err != nil {
+			setupLog.Error(err, "unable to add boot log receiver to manager")
+			os.Exit(1)
+		}
+		setupLog.Info("Boot log capture configured", "address", bootLogOpts.Address, "lines", bootLogOpts.Lines)
+		if !consoleOpts.Enabled() {
+			setupLog.Info("Boot logs are captured but not served, as the console proxy is disabled")
+		}
+	}
+
 	if err = (&controller.ServerReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
+		WolSender: &power.RealWolSender{
+			DefaultPort:             9,
+			DefaultBroadcastAddress: wolBroadcastAddress,
+			Retry:                   &retryPolicy,
+		},
+		SSHClient:                &power.RealSSHClient{Retry: &retryPolicy},
+		IPMIClient:               &power.RealIPMIClient{Retry: &retryPolicy},
+		MAASClient:               &power.RealMAASClient{Retry: &retryPolicy},
+		RedfishClient:            &power.RealRedfishClient{SessionIdleTimeout: bmcSessionIdleTimeout, Retry: &retryPolicy},
+		EquinixClient:            &power.RealEquinixClient{Retry: &retryPolicy},
+		HetznerClient:            &power.RealHetznerClient{Retry: &retryPolicy},
+		HypervisorClient:         &power.RealESXiClient{Retry: &retryPolicy},
+		Attestor:                 &power.RealAttestor{},
+		Pinger:                   &power.RealPinger{Retry: &retryPolicy},
+		Probers:                  &power.RealProberFactory{Retry: &retryPolicy},
+		WolRelay:                 wolRelay,
+		Recorder:                 mgr.GetEventRecorderFor("server-controller"),
+		APIReader:                mgr.GetAPIReader(),
+		PowerWorkers:             powerWorkers,
+		TenantPowerWorkers:       tenantPowerWorkers,
+		FairQueue:                fairQueue,
+		PowerShutdownGracePeriod: powerShutdownGracePeriod,
+		NodeCleanup:              nodeCleanup,
+		Clusters:                 clusters,
+		Resolver:                 resolver,
+		Breaker:                  powerBreaker,
+		Telemetry:                telemetryReceiver,
+		PlatformEvents:           petReceiver,
+		BootLogs:                 bootLogReceiver,
+		Simulation: controller.SimulationProfile{
+			CommandLatency:  fleetOpts.CommandLatency,
+			BootLatency:     fleetOpts.BootLatency,
+			ShutdownLatency: fleetOpts.ShutdownLatency,
+			FailureRate:     fleetOpts.FailureRate,
+			AuthFailureRate: fleetOpts.AuthFailureRate,
+			BootJitter:      fleetOpts.BootJitter,
+			FlapRate:        fleetOpts.FlapRate,
+		},
+		BMCColdResetBackoff: bmcColdResetBackoff,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Server")
 		os.Exit(1)
 	}
+	if err = (&controller.RebootCampaignReconciler{
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		APIReader: mgr.GetAPIReader(),
+		Clusters:  clusters,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "RebootCampaign")
+		os.Exit(1)
+	}
+	if err = (&controller.PowerActionReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+
+		NamespacedServers: scopeOpts.NamespacedServers,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "PowerAction")
+		os.Exit(1)
+	}
+	if err = (&controller.PowerBudgetReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "PowerBudget")
+		os.Exit(1)
+	}
+	if err = (&controller.StandbyReconciler{
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		APIReader: mgr.GetAPIReader(),
+		Recorder:  mgr.GetEventRecorderFor("standby-controller"),
+		Clusters:  clusters,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Standby")
+		os.Exit(1)
+	}
+	if err = (&controller.HibernationPolicyReconciler{
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		APIReader: mgr.GetAPIReader(),
+		Clusters:  clusters,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "HibernationPolicy")
+		os.Exit(1)
+	}
+	if err = (&controller.CalendarReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Calendar")
+		os.Exit(1)
+	}
+	if nodeCleanup {
+		if err = (&controller.NodeReconciler{
+			Client:    mgr.GetClient(),
+			Scheme:    mgr.GetScheme(),
+			APIReader: mgr.GetAPIReader(),
+
+			NamespacedServers: scopeOpts.NamespacedServers,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "Node")
+			os.Exit(1)
+		}
+	}
+	if approveKubeletCSRs {
+		if err = (&controller.CSRReconciler{
+			Client:     mgr.GetClient(),
+			Scheme:     mgr.GetScheme(),
+			BootWindow: csrBootWindow,
+			Clusters:   clusters,
+
+			NamespacedServers: scopeOpts.NamespacedServers,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "CSR")
+			os.Exit(1)
+		}
+	}
+	if dnsOpts.Enabled() {
+		publisher, err := dns.NewPublisher(dnsOpts, mgr.GetClient())
+		if err != nil {
+			setupLog.Error(err, "unable to create DNS publisher")
+			os.Exit(1)
+		}
+		if err = (&controller.DNSReconciler{
+			Client:    mgr.GetClient(),
+			Scheme:    mgr.GetScheme(),
+			Publisher: publisher,
+			Zone:      dnsOpts.Zone,
+			Clusters:  clusters,
+
+			NamespacedServers: scopeOpts.NamespacedServers,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "DNS")
+			os.Exit(1)
+		}
+		setupLog.Info("DNS registration configured", "provider", dnsOpts.Provider, "zone", dnsOpts.Zone)
+	}
+	if nodeFeatureOpts.Enabled {
+		if err := nodeFeatureOpts.Validate(); err != nil {
+			setupLog.Error(err, "invalid node feature options")
+			os.Exit(1)
+		}
+		if err = (&controller.NodeFeatureReconciler{
+			Client:   mgr.GetClient(),
+			Scheme:   mgr.GetScheme(),
+			Prefixes: nodeFeatureOpts.PrefixList(),
+			Clusters: clusters,
+
+			NamespacedServers: scopeOpts.NamespacedServers,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "NodeFeature")
+			os.Exit(1)
+		}
+	}
+	if enableTinkerbell {
+		if err = (&controller.TinkerbellReconciler{
+			Client: mgr.GetClient(),
+			Scheme: mgr.GetScheme(),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "Tinkerbell")
+			os.Exit(1)
+		}
+	}
+	if enableWebhooks {
+		if err = webhookv1.SetupServerWebhookWithManager(mgr, wolBroadcastAddress); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Server")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
+	if err := grpcOpts.Validate(); err != nil {
+		setupLog.Error(err, "invalid gRPC server options")
+		os.Exit(1)
+	}
 	grpcServer, err := grpcserver.NewServer(grpcOpts, mgr)
 	if err != nil {
 		setupLog.Error(err, "unable to create gRPC server")
 		os.Exit(1)
 	}
+	grpcServer.SetNamespacedServers(scopeOpts.NamespacedServers)
+	if pricingOpts.Enabled() {
+		policy, err := pricing.NewPolicy(pricingOpts)
+		if err != nil {
+			setupLog.Error(err, "unable to create pricing policy")
+			os.Exit(1)
+		}
+		grpcServer.SetPricing(policy)
+		setupLog.Info("Price-aware scale-down configured", "source", pricingOpts.Source)
+	}
+	if carbonOpts.Enabled() {
+		policy, err := pricing.NewCarbonPolicy(carbonOpts)
+		if err != nil {
+			setupLog.Error(err, "unable to create carbon policy")
+			os.Exit(1)
+		}
+		grpcServer.SetCarbon(policy)
+		setupLog.Info("Carbon-aware scaling configured", "source", carbonOpts.Source, "highAbove", carbonOpts.HighAbove)
+	}
 
 	if err := mgr.Add(grpcServer); err != nil {
 		setupLog.Error(err, "unable to add gRPC server to manager")
@@ -171,6 +611,161 @@ func main() {
 		"address", grpcOpts.Address,
 		"tls", grpcOpts.IsTLSEnabled())
 
+	if fleetOpts.Enabled() {
+		generator, err := fleet.NewGenerator(fleetOpts, mgr)
+		if err != nil {
+			setupLog.Error(err, "unable to create synthetic fleet")
+			os.Exit(1)
+		}
+		if err := mgr.Add(generator); err != nil {
+			setupLog.Error(err, "unable to add synthetic fleet to manager")
+			os.Exit(1)
+		}
+		setupLog.Info("Synthetic fleet configured", "servers", fleetOpts.Servers)
+	}
+
+	if upsOpts.Enabled() {
+		monitor, err := ups.NewMonitor(upsOpts, mgr)
+		if err != nil {
+			setupLog.Error(err, "unable to create UPS monitor")
+			os.Exit(1)
+		}
+		if err := mgr.Add(monitor); err != nil {
+			setupLog.Error(err, "unable to add UPS monitor to manager")
+			os.Exit(1)
+		}
+		setupLog.Info("UPS monitor configured", "address", upsOpts.Address, "ups", upsOpts.UPS)
+	}
+
+	if idleOpts.Enabled() {
+		detector, err := idle.NewDetector(idleOpts, mgr)
+		if err != nil {
+			setupLog.Error(err, "unable to create idle detector")
+			os.Exit(1)
+		}
+		if err := mgr.Add(detector); err != nil {
+			setupLog.Error(err, "unable to add idle detector to manager")
+			os.Exit(1)
+		}
+		setupLog.Info("Idle power-off configured", "after", idleOpts.After, "minActive", idleOpts.MinActive)
+	}
+
+	// DHCP leases and neighbor scans both end up in status.addresses
+	if dhcpOpts.Enabled() || neighborOpts.Enabled() {
+		if err := neighborOpts.Validate(); err != nil {
+			setupLog.Error(err, "invalid neighbor discovery options")
+			os.Exit(1)
+		}
+		var sources []dhcp.Source
+		if dhcpOpts.KeaURL != "" {
+			sources = append(sources, &dhcp.Kea{URL: dhcpOpts.KeaURL})
+		}
+		if dhcpOpts.RelayLeases {
+			if relayClient == nil {
+				setupLog.Error(nil, "--dhcp-relay-leases requires --wol-relay-addresses")
+				os.Exit(1)
+			}
+			sources = append(sources, relayClient.LeaseSources()...)
+		}
+		if neighborOpts.Subnets != "" {
+			subnets, _ := neighbor.ParseSubnets(neighborOpts.Subnets)
+			scanner := &neighbor.Scanner{Subnets: subnets, Wait: neighborOpts.Wait}
+			sources = append(sources, scanner.Source())
+		}
+		if neighborOpts.Relays {
+			if relayClient == nil {
+				setupLog.Error(nil, "--neighbor-relays requires --wol-relay-addresses")
+				os.Exit(1)
+			}
+			sources = append(sources, relayClient.NeighborSources()...)
+		}
+		tracker, err := dhcp.NewTracker(dhcpOpts, sources, mgr)
+		if err != nil {
+			setupLog.Error(err, "unable to create DHCP lease tracker")
+			os.Exit(1)
+		}
+		if err := mgr.Add(tracker); err != nil {
+			setupLog.Error(err, "unable to add DHCP lease tracker to manager")
+			os.Exit(1)
+		}
+		setupLog.Info("Address discovery configured", "sources", len(sources), "interval", dhcpOpts.Interval)
+	}
+
+	if wakeOpts.Enabled {
+		waker, err := wake.NewWaker(wakeOpts, mgr)
+		if err != nil {
+			setupLog.Error(err, "unable to create pod waker")
+			os.Exit(1)
+		}
+		if err := mgr.Add(waker); err != nil {
+			setupLog.Error(err, "unable to add pod waker to manager")
+			os.Exit(1)
+		}
+		setupLog.Info("Wake on pending pods configured", "interval", wakeOpts.Interval)
+	}
+
+	if energyOpts.Enabled() {
+		meter, err := energy.NewMeter(energyOpts, mgr)
+		if err != nil {
+			setupLog.Error(err, "unable to create energy meter")
+			os.Exit(1)
+		}
+		if err := mgr.Add(meter); err != nil {
+			setupLog.Error(err, "unable to add energy meter to manager")
+			os.Exit(1)
+		}
+		setupLog.Info("Energy reporting configured", "pricePerKWh", energyOpts.PricePerKWh, "reportPeriod", energyOpts.ReportPeriod)
+	}
+
+	if metal3Opts.Enabled() {
+		migrator, err := metal3.NewMigrator(metal3Opts, mgr)
+		if err != nil {
+			setupLog.Error(err, "unable to create Metal3 migrator")
+			os.Exit(1)
+		}
+		if err := mgr.Add(migrator); err != nil {
+			setupLog.Error(err, "unable to add Metal3 migrator to manager")
+			os.Exit(1)
+		}
+		setupLog.Info("Metal3 migration configured",
+			"mode", metal3Opts.Mode,
+			"namespace", metal3Opts.Namespace)
+	}
+
+	if consoleOpts.Enabled() {
+		if err := consoleOpts.Validate(); err != nil {
+			setupLog.Error(err, "invalid serial console options")
+			os.Exit(1)
+		}
+		consoleServer, err := console.NewServer(consoleOpts, mgr, &power.RealSerialConsole{}, &power.RealRedfishClient{}, resolver, bootLogReceiver)
+		if err != nil {
+			setupLog.Error(err, "unable to create serial console proxy")
+			os.Exit(1)
+		}
+		if err := mgr.Add(consoleServer); err != nil {
+			setupLog.Error(err, "unable to add serial console proxy to manager")
+			os.Exit(1)
+		}
+		setupLog.Info("Serial console proxy configured", "address", consoleOpts.Address)
+	}
+
+	if dashboardOpts.Enabled() {
+		if err := dashboardOpts.Validate(); err != nil {
+			setupLog.Error(err, "invalid dashboard options")
+			os.Exit(1)
+		}
+		dashboardServer, err := dashboard.NewServer(dashboardOpts, mgr)
+		if err != nil {
+			setupLog.Error(err, "unable to create dashboard")
+			os.Exit(1)
+		}
+		if err := mgr.Add(dashboardServer); err != nil {
+			setupLog.Error(err, "unable to add dashboard to manager")
+			os.Exit(1)
+		}
+		setupLog.Info("Dashboard configured", "address", dashboardOpts.Address)
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -179,6 +774,27 @@ func main() {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	if preflightOpts.Enabled {
+		if err := preflightOpts.Validate(); err != nil {
+			setupLog.Error(err, "invalid preflight options")
+			os.Exit(1)
+		}
+		checker, err := preflight.NewChecker(preflightOpts)
+		if err != nil {
+			setupLog.Error(err, "unable to create preflight checker")
+			os.Exit(1)
+		}
+		// Keep running so the failure is visible on the health probe
+		if err := checker.Check(nil); err != nil {
+			setupLog.Error(err, "preflight checks failed")
+		} else {
+			setupLog.Info("preflight checks passed")
+		}
+		if err := mgr.AddHealthzCheck("preflight", checker.Check); err != nil {
+			setupLog.Error(err, "unable to set up preflight check")
+			os.Exit(1)
+		}
+	}
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
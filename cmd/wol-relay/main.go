@@ -0,0 +1,110 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// wol-relay runs on a remote site's subnet and sends Wake-on-LAN packets and
+// pings for the controller, which can't broadcast across routers. It can also
+// serve the leases of a dnsmasq DHCP server on the subnet and resolve MAC
+// addresses on it. It serves the WakeRelay gRPC service and doesn't talk to
+// Kubernetes.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/Unbounder1/bare-metal-controller/internal/neighbor"
+	"github.com/Unbounder1/bare-metal-controller/internal/power"
+	"github.com/Unbounder1/bare-metal-controller/internal/relay"
+)
+
+func main() {
+	address := flag.String("address", ":9444", "Address to serve the relay on.")
+	certFile := flag.String("cert", "", "Path to the TLS certificate. Empty for insecure.")
+	keyFile := flag.String("key", "", "Path to the TLS key. Empty for insecure.")
+	caFile := flag.String("ca", "", "Path to the CA certificate that signed the controller's client certificate. Empty for insecure.")
+	broadcastAddress := flag.String("broadcast-address", "255.255.255.255",
+		"Broadcast address for servers without spec.control.wol.broadcastAddress.")
+	port := flag.Int("port", 9, "UDP port for servers without spec.control.wol.port.")
+	leaseFile := flag.String("dnsmasq-leases", "",
+		"Path to the dnsmasq lease file of the subnet's DHCP server, served to the controller. Empty to disable.")
+	scanSubnets := flag.String("scan-subnets", "",
+		"Comma-separated CIDRs on the agent's segment to resolve MAC addresses in with ARP and NDP for the controller. Empty to disable.")
+	flag.Parse()
+
+	if err := run(*address, *certFile, *keyFile, *caFile, *broadcastAddress, *port, *leaseFile, *scanSubnets); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(address, certFile, keyFile, caFile, broadcastAddress string, port int, leaseFile, scanSubnets string) error {
+	var serverOpts []grpc.ServerOption
+	switch {
+	case certFile != "" && keyFile != "" && caFile != "":
+		config, err := relay.ServerTLSConfig(certFile, keyFile, caFile)
+		if err != nil {
+			return err
+		}
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(config)))
+	case certFile != "" || keyFile != "" || caFile != "":
+		return fmt.Errorf("all TLS options (cert, key, ca) must be set together, or none")
+	default:
+		log.Printf("Serving without TLS, anyone who can reach %s can wake servers", address)
+	}
+
+	var scanner *neighbor.Scanner
+	if scanSubnets != "" {
+		subnets, err := neighbor.ParseSubnets(scanSubnets)
+		if err != nil {
+			return err
+		}
+		scanner = &neighbor.Scanner{Subnets: subnets, Wait: neighbor.DefaultOptions().Wait}
+	}
+
+	server := grpc.NewServer(serverOpts...)
+	relay.RegisterWakeRelayServer(server, &relay.Agent{
+		WolSender: &power.RealWolSender{
+			DefaultPort:             port,
+			DefaultBroadcastAddress: broadcastAddress,
+		},
+		Pinger:    &power.RealPinger{},
+		LeaseFile: leaseFile,
+		Scanner:   scanner,
+	})
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		server.GracefulStop()
+	}()
+
+	log.Printf("Relaying Wake-on-LAN on %s", address)
+	return server.Serve(listener)
+}
@@ -0,0 +1,183 @@
+package external
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/Unbounder1/bare-metal-controller/external/protos"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// MethodsRead matches the RPCs that don't change any server
+	MethodsRead = "read"
+	// MethodsWrite matches the RPCs that power servers on or off
+	MethodsWrite = "write"
+	// Wildcard matches any identity or RPC
+	Wildcard = "*"
+)
+
+// writeMethods are the RPCs that change the power state of servers. Every
+// other RPC of the CloudProvider service is read-only.
+var writeMethods = map[string]bool{
+	protos.CloudProvider_NodeGroupIncreaseSize_FullMethodName:       true,
+	protos.CloudProvider_NodeGroupDeleteNodes_FullMethodName:        true,
+	protos.CloudProvider_NodeGroupDecreaseTargetSize_FullMethodName: true,
+}
+
+var allMethods = map[string]bool{
+	protos.CloudProvider_NodeGroups_FullMethodName:                  true,
+	protos.CloudProvider_NodeGroupForNode_FullMethodName:            true,
+	protos.CloudProvider_PricingNodePrice_FullMethodName:            true,
+	protos.CloudProvider_PricingPodPrice_FullMethodName:             true,
+	protos.CloudProvider_GPULabel_FullMethodName:                    true,
+	protos.CloudProvider_GetAvailableGPUTypes_FullMethodName:        true,
+	protos.CloudProvider_Cleanup_FullMethodName:                     true,
+	protos.CloudProvider_Refresh_FullMethodName:                     true,
+	protos.CloudProvider_NodeGroupTargetSize_FullMethodName:         true,
+	protos.CloudProvider_NodeGroupIncreaseSize_FullMethodName:       true,
+	protos.CloudProvider_NodeGroupDeleteNodes_FullMethodName:        true,
+	protos.CloudProvider_NodeGroupDecreaseTargetSize_FullMethodName: true,
+	protos.CloudProvider_NodeGroupNodes_FullMethodName:              true,
+	protos.CloudProvider_NodeGroupTemplateNodeInfo_FullMethodName:   true,
+	protos.CloudProvider_NodeGroupGetOptions_FullMethodName:         true,
+}
+
+// Policy decides which clients may call which RPCs. Clients are identified
+// by the common name of their TLS client certificate. A call is allowed if
+// any rule matches both, and denied otherwise.
+type Policy struct {
+	Rules []PolicyRule `json:"rules"`
+}
+
+// PolicyRule allows a set of identities to call a set of RPCs.
+type PolicyRule struct {
+	// Identities are client certificate common names, or "*" for any client
+	Identities []string `json:"identities"`
+
+	// Methods are RPC names such as NodeGroupIncreaseSize, "read" for the
+	// RPCs that don't change servers, "write" for the ones that do, or "*"
+	// for all of them
+	Methods []string `json:"methods"`
+}
+
+// LoadPolicy reads a YAML or JSON policy file.
+func LoadPolicy(file string) (*Policy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read authorization policy: %w", err)
+	}
+
+	var policy Policy
+	if err := yaml.UnmarshalStrict(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse authorization policy %s: %w", file, err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid authorization policy %s: %w", file, err)
+	}
+	return &policy, nil
+}
+
+// Validate rejects rules without identities or with unknown methods, which
+// would otherwise silently deny every call.
+func (p *Policy) Validate() error {
+	for i, rule := range p.Rules {
+		if len(rule.Identities) == 0 {
+			return fmt.Errorf("rule %d has no identities", i)
+		}
+		if len(rule.Methods) == 0 {
+			return fmt.Errorf("rule %d has no methods", i)
+		}
+		for _, method := range rule.Methods {
+			switch method {
+			case MethodsRead, MethodsWrite, Wildcard:
+				continue
+			}
+			if !allMethods[fullMethodName(method)] {
+				return fmt.Errorf("rule %d: unknown method %s", i, method)
+			}
+		}
+	}
+	return nil
+}
+
+// Allowed reports whether identity may call the RPC with the given full
+// method name.
+func (p *Policy) Allowed(identity string, fullMethod string) bool {
+	for _, rule := range p.Rules {
+		if matchesIdentity(rule.Identities, identity) && matchesMethod(rule.Methods, fullMethod) {
+			return true
+		}
+	}
+	return false
+}
+
+// UnaryInterceptor rejects calls the policy doesn't allow with
+// PermissionDenied.
+func (p *Policy) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		identity := peerIdentity(ctx)
+		if !p.Allowed(identity, info.FullMethod) {
+			return nil, status.Errorf(codes.PermissionDenied, "%q may not call %s", identity, path.Base(info.FullMethod))
+		}
+		return handler(ctx, req)
+	}
+}
+
+// peerIdentity returns the common name of the verified client certificate,
+// or an empty string for connections without one.
+func peerIdentity(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return tlsInfo.State.VerifiedChains[0][0].Subject.CommonName
+}
+
+func matchesIdentity(identities []string, identity string) bool {
+	for _, id := range identities {
+		if id == Wildcard || (identity != "" && id == identity) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesMethod(methods []string, fullMethod string) bool {
+	for _, method := range methods {
+		switch method {
+		case Wildcard:
+			return true
+		case MethodsRead:
+			if allMethods[fullMethod] && !writeMethods[fullMethod] {
+				return true
+			}
+		case MethodsWrite:
+			if writeMethods[fullMethod] {
+				return true
+			}
+		default:
+			if fullMethodName(method) == fullMethod {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fullMethodName expands an RPC name such as NodeGroups to the full gRPC
+// method name
+func fullMethodName(method string) string {
+	return path.Join(path.Dir(protos.CloudProvider_NodeGroups_FullMethodName), method)
+}
@@ -0,0 +1,158 @@
+package external
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/Unbounder1/bare-metal-controller/external/protos"
+)
+
+func TestPolicyAllowed(t *testing.T) {
+	policy := &Policy{Rules: []PolicyRule{
+		{Identities: []string{"cluster-autoscaler"}, Methods: []string{MethodsRead, "NodeGroupIncreaseSize"}},
+		{Identities: []string{"admin"}, Methods: []string{Wildcard}},
+		{Identities: []string{Wildcard}, Methods: []string{"Refresh"}},
+		{Identities: []string{"scaler-down"}, Methods: []string{MethodsWrite}},
+	}}
+
+	tests := []struct {
+		name     string
+		identity string
+		method   string
+		want     bool
+	}{
+		{name: "read method", identity: "cluster-autoscaler", method: protos.CloudProvider_NodeGroups_FullMethodName, want: true},
+		{name: "named write method", identity: "cluster-autoscaler", method: protos.CloudProvider_NodeGroupIncreaseSize_FullMethodName, want: true},
+		{name: "write method not granted", identity: "cluster-autoscaler", method: protos.CloudProvider_NodeGroupDeleteNodes_FullMethodName},
+		{name: "wildcard method", identity: "admin", method: protos.CloudProvider_NodeGroupDeleteNodes_FullMethodName, want: true},
+		{name: "wildcard identity", identity: "anyone", method: protos.CloudProvider_Refresh_FullMethodName, want: true},
+		{name: "wildcard identity without certificate", identity: "", method: protos.CloudProvider_Refresh_FullMethodName, want: true},
+		{name: "write group", identity: "scaler-down", method: protos.CloudProvider_NodeGroupDecreaseTargetSize_FullMethodName, want: true},
+		{name: "write group excludes reads", identity: "scaler-down", method: protos.CloudProvider_NodeGroups_FullMethodName},
+		{name: "unknown identity", identity: "intruder", method: protos.CloudProvider_NodeGroups_FullMethodName},
+		{name: "no certificate", identity: "", method: protos.CloudProvider_NodeGroups_FullMethodName},
+		{name: "identity case matters", identity: "Admin", method: protos.CloudProvider_NodeGroups_FullMethodName},
+		{name: "read group excludes unknown methods", identity: "cluster-autoscaler", method: "/other.Service/NodeGroups"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Allowed(tt.identity, tt.method); got != tt.want {
+				t.Errorf("Allowed(%q, %s) = %v, want %v", tt.identity, tt.method, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmptyPolicyDeniesEverything(t *testing.T) {
+	policy := &Policy{}
+	for method := range allMethods {
+		if policy.Allowed("admin", method) {
+			t.Errorf("empty policy allowed %s", method)
+		}
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    PolicyRule
+		wantErr bool
+	}{
+		{name: "valid", rule: PolicyRule{Identities: []string{"a"}, Methods: []string{MethodsRead, "NodeGroupDeleteNodes"}}},
+		{name: "no identities", rule: PolicyRule{Methods: []string{MethodsRead}}, wantErr: true},
+		{name: "no methods", rule: PolicyRule{Identities: []string{"a"}}, wantErr: true},
+		{name: "unknown method", rule: PolicyRule{Identities: []string{"a"}, Methods: []string{"DeleteEverything"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&Policy{Rules: []PolicyRule{tt.rule}}).Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.yaml")
+	if err := os.WriteFile(valid, []byte("rules:\n- identities: [cluster-autoscaler]\n  methods: [read]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := LoadPolicy(valid)
+	if err != nil {
+		t.Fatalf("LoadPolicy() error = %v", err)
+	}
+	if !policy.Allowed("cluster-autoscaler", protos.CloudProvider_NodeGroups_FullMethodName) {
+		t.Errorf("loaded policy denies a granted call")
+	}
+
+	// Misspelled fields would otherwise load as an empty rule
+	typo := filepath.Join(dir, "typo.yaml")
+	if err := os.WriteFile(typo, []byte("rules:\n- identity: [cluster-autoscaler]\n  methods: [read]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPolicy(typo); err == nil {
+		t.Errorf("LoadPolicy() accepted an unknown field")
+	}
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	policy := &Policy{Rules: []PolicyRule{{Identities: []string{"cluster-autoscaler"}, Methods: []string{MethodsRead}}}}
+	interceptor := policy.UnaryInterceptor()
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	info := &grpc.UnaryServerInfo{FullMethod: protos.CloudProvider_NodeGroups_FullMethodName}
+
+	withCertificate := func(commonName string) context.Context {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+		return peer.NewContext(context.Background(), &peer.Peer{
+			Addr:     &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1)},
+			AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}},
+		})
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want codes.Code
+	}{
+		{name: "verified client", ctx: withCertificate("cluster-autoscaler"), want: codes.OK},
+		{name: "other client", ctx: withCertificate("intruder"), want: codes.PermissionDenied},
+		{name: "no peer", ctx: context.Background(), want: codes.PermissionDenied},
+		{
+			name: "unverified certificate",
+			ctx: peer.NewContext(context.Background(), &peer.Peer{
+				Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1)},
+				AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{
+					{Subject: pkix.Name{CommonName: "cluster-autoscaler"}},
+				}}},
+			}),
+			want: codes.PermissionDenied,
+		},
+		{
+			name: "plaintext connection",
+			ctx:  peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1)}}),
+			want: codes.PermissionDenied,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := interceptor(tt.ctx, nil, info, handler)
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("interceptor code = %s, want %s (error %v)", got, tt.want, err)
+			}
+		})
+	}
+}
@@ -2,10 +2,22 @@ package protos
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/budget"
+	"github.com/Unbounder1/bare-metal-controller/internal/drain"
+	"github.com/Unbounder1/bare-metal-controller/internal/index"
+	"github.com/Unbounder1/bare-metal-controller/internal/listing"
+	"github.com/Unbounder1/bare-metal-controller/internal/nodefeatures"
+	"github.com/Unbounder1/bare-metal-controller/internal/pricing"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -13,25 +25,64 @@ import (
 type BareMetalProviderServer struct {
 	UnimplementedCloudProviderServer
 	Client client.Client
+
+	// Reader, if set, is used for reads instead of Client, e.g. to read
+	// from the API server instead of the cache. Cache indexes are not
+	// available through it.
+	Reader client.Reader
+
+	// Pricing, if set, tunes scale-down to the electricity price through
+	// NodeGroupGetOptions.
+	Pricing *pricing.Policy
+
+	// Carbon, if set, holds back scale-ups of ServerClasses with a carbon
+	// policy while the carbon intensity is high, and tunes scale-down
+	// through NodeGroupGetOptions.
+	Carbon *pricing.CarbonPolicy
+
+	// SpreadLabels are Server labels, e.g. zone and rack, whose values
+	// scale-ups are spread across, the most significant first
+	SpreadLabels []string
+
+	// PreferLowWear powers on the servers with the fewest power cycles and
+	// the least runtime first, among those the spread labels can't tell
+	// apart
+	PreferLowWear bool
+
+	// NamespacedServers is set when Servers are namespaced, so the server
+	// of a node is looked up by name in every namespace
+	NamespacedServers bool
 }
 
 const defaultNodeGroupID = "bare-metal-pool"
 
-// NodeGroups returns all node groups configured for this cloud provider.
-func (s *BareMetalProviderServer) NodeGroups(ctx context.Context, req *NodeGroupsRequest) (*NodeGroupsResponse, error) {
-	var servers baremetalcontrollerv1.ServerList
+// instanceErrorClassOther matches cloudprovider.OtherErrorClass in the
+// cluster autoscaler
+const instanceErrorClassOther = 99
 
-	if err := s.Client.List(ctx, &servers); err != nil {
-		return nil, fmt.Errorf("failed to list servers: %w", err)
+// NodeGroups returns all node groups configured for this cloud provider:
+// the default one, and the spot one if any ServerClass is in the spot tier.
+func (s *BareMetalProviderServer) NodeGroups(ctx context.Context, req *NodeGroupsRequest) (*NodeGroupsResponse, error) {
+	groups, err := s.nodeGroupIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{defaultNodeGroupID}
+	if groups.hasSpot() {
+		ids = append(ids, spotNodeGroupID)
 	}
 
-	// Current functionality: only support a single node group
-	nodeGroups := []*NodeGroup{
-		{
-			Id:      defaultNodeGroupID,
+	var nodeGroups []*NodeGroup
+	for _, id := range ids {
+		count, err := s.countServers(ctx, id, nil)
+		if err != nil {
+			return nil, err
+		}
+		nodeGroups = append(nodeGroups, &NodeGroup{
+			Id:      id,
 			MinSize: 0,
-			MaxSize: int32(len(servers.Items)),
-		},
+			MaxSize: int32(count),
+		})
 	}
 
 	return &NodeGroupsResponse{
@@ -39,13 +90,15 @@ func (s *BareMetalProviderServer) NodeGroups(ctx context.Context, req *NodeGroup
 	}, nil
 }
 
-// NodeGroupIncreaseSize increases the size of a node group by provisioning
-// offline servers.
+// NodeGroupIncreaseSize increases the size of a node group by promoting
+// standby servers, then provisioning offline servers, spread across the
+// values of the spread labels. Servers of the default node group blocked by
+// a power budget get room by reclaiming spot servers.
 func (s *BareMetalProviderServer) NodeGroupIncreaseSize(ctx context.Context, req *NodeGroupIncreaseSizeRequest) (*NodeGroupIncreaseSizeResponse, error) {
 	nodeGroupID := req.GetId()
 
-	if nodeGroupID != defaultNodeGroupID {
-		return nil, fmt.Errorf("unknown node group: %s", nodeGroupID)
+	if err := checkNodeGroup(nodeGroupID); err != nil {
+		return nil, err
 	}
 
 	delta := int(req.GetDelta())
@@ -53,29 +106,105 @@ func (s *BareMetalProviderServer) NodeGroupIncreaseSize(ctx context.Context, req
 		return &NodeGroupIncreaseSizeResponse{}, nil
 	}
 
-	var servers baremetalcontrollerv1.ServerList
-	if err := s.Client.List(ctx, &servers); err != nil {
-		return nil, fmt.Errorf("failed to list servers: %w", err)
+	deferred, err := s.deferredClasses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := s.nodeGroupIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Servers shut down for power loss stay off until power returns,
+	// hibernated ones until the off-hours end, and overheated ones until
+	// they are powered on by hand. Non-urgent classes wait for a
+	// low-carbon window. Spread counts the servers of every node group.
+	spread := newSpread(s.SpreadLabels, s.PreferLowWear)
+	var candidates []*baremetalcontrollerv1.Server
+	heldBack := 0
+	err = s.eachServer(ctx, "", func(server *baremetalcontrollerv1.Server) error {
+		switch {
+		case server.Spec.PowerState.Settled() == baremetalcontrollerv1.PowerStateOn:
+			spread.add(server)
+		case groups.of(server) != nodeGroupID:
+		case server.Spec.PowerState == baremetalcontrollerv1.PowerStateOff &&
+			server.Annotations[baremetalcontrollerv1.UPSShutdownAnnotation] != "true" &&
+			server.Annotations[baremetalcontrollerv1.HibernatedAnnotation] == "" &&
+			server.Annotations[baremetalcontrollerv1.ThermalShutdownAnnotation] != "true":
+			if deferred[server.Spec.ServerClassName] {
+				heldBack++
+				return nil
+			}
+			candidates = append(candidates, server.DeepCopy())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Warm spares are ready right away, cold servers have to boot
+	provisioned, err := s.promoteStandby(ctx, nodeGroupID, groups, delta, spread)
+	if err != nil {
+		return nil, err
 	}
+	var exceeded error
+	var blocked []*baremetalcontrollerv1.Server
+	blockedBy := map[client.ObjectKey]string{}
+	for len(provisioned) < delta && len(candidates) > 0 {
+		i := spread.pick(candidates)
+		server := candidates[i]
+		candidates = append(candidates[:i], candidates[i+1:]...)
+
+		// Servers on a full rack or circuit are skipped for others
+		if err := budget.Check(ctx, s.reader(), server, provisioned); err != nil {
+			var budgetErr *budget.ExceededError
+			if !errors.As(err, &budgetErr) {
+				return nil, err
+			}
+			exceeded = err
+			blocked = append(blocked, server)
+			blockedBy[client.ObjectKeyFromObject(server)] = budgetErr.Budget
+			continue
+		}
 
-	provisioned := 0
-	for i := range servers.Items {
-		if provisioned >= delta {
-			break
+		server.Spec.PowerState = baremetalcontrollerv1.PowerStateOn
+		if err := s.Client.Update(ctx, server); err != nil {
+			return nil, fmt.Errorf("failed to power on server %s: %w", server.Name, err)
 		}
+		spread.add(server)
+		provisioned[client.ObjectKeyFromObject(server)] = true
+	}
 
-		server := &servers.Items[i]
-		if server.Spec.PowerState == baremetalcontrollerv1.PowerStateOff {
+	// Reclaimed spot servers are drained and powered off by the server
+	// controller, and the blocked servers wait for their budget meanwhile
+	if len(provisioned) < delta && nodeGroupID == defaultNodeGroupID {
+		if len(blocked) > delta-len(provisioned) {
+			blocked = blocked[:delta-len(provisioned)]
+		}
+		admitted, err := s.reclaim(ctx, groups, blocked, blockedBy)
+		for _, server := range admitted {
 			server.Spec.PowerState = baremetalcontrollerv1.PowerStateOn
 			if err := s.Client.Update(ctx, server); err != nil {
 				return nil, fmt.Errorf("failed to power on server %s: %w", server.Name, err)
 			}
-			provisioned++
+			provisioned[client.ObjectKeyFromObject(server)] = true
+		}
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	if provisioned < delta {
-		return nil, fmt.Errorf("could not provision enough servers: requested %d, provisioned %d", delta, provisioned)
+	if len(provisioned) < delta {
+		if exceeded != nil {
+			return nil, status.Errorf(codes.ResourceExhausted, "could not provision enough servers: requested %d, provisioned %d: %v",
+				delta, len(provisioned), exceeded)
+		}
+		if heldBack > 0 {
+			return nil, status.Errorf(codes.ResourceExhausted, "could not provision enough servers: requested %d, provisioned %d: "+
+				"%d servers deferred to a low-carbon window", delta, len(provisioned), heldBack)
+		}
+		return nil, fmt.Errorf("could not provision enough servers: requested %d, provisioned %d", delta, len(provisioned))
 	}
 
 	return &NodeGroupIncreaseSizeResponse{}, nil
@@ -86,20 +215,36 @@ func (s *BareMetalProviderServer) NodeGroupIncreaseSize(ctx context.Context, req
 func (s *BareMetalProviderServer) NodeGroupDeleteNodes(ctx context.Context, req *NodeGroupDeleteNodesRequest) (*NodeGroupDeleteNodesResponse, error) {
 	nodeGroupID := req.GetId()
 
-	if nodeGroupID != defaultNodeGroupID {
-		return nil, fmt.Errorf("unknown node group: %s", nodeGroupID)
+	if err := checkNodeGroup(nodeGroupID); err != nil {
+		return nil, err
+	}
+	groups, err := s.nodeGroupIndex(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	nodes := req.GetNodes()
 
 	for _, node := range nodes {
-		var server baremetalcontrollerv1.Server
-		if err := s.Client.Get(ctx, client.ObjectKey{Name: node.Name}, &server); err != nil {
-			return nil, fmt.Errorf("failed to get server %s: %w", node.Name, err)
+		server, err := s.serverForNode(ctx, node)
+		if err != nil {
+			return nil, err
+		}
+		if server == nil {
+			return nil, fmt.Errorf("no server found for node %s", node.Name)
+		}
+		if server.Spec.Role.Protected() {
+			return nil, status.Errorf(codes.FailedPrecondition, "server %s is protected as a %s server", server.Name, server.Spec.Role)
+		}
+		if !autoscaled(server) {
+			return nil, fmt.Errorf("server %s is excluded from autoscaling", server.Name)
+		}
+		if groups.of(server) != nodeGroupID {
+			return nil, fmt.Errorf("server %s is not in node group %s", server.Name, nodeGroupID)
 		}
 
 		server.Spec.PowerState = baremetalcontrollerv1.PowerStateOff
-		if err := s.Client.Update(ctx, &server); err != nil {
+		if err := s.Client.Update(ctx, server); err != nil {
 			return nil, fmt.Errorf("failed to power off server %s: %w", server.Name, err)
 		}
 	}
@@ -114,19 +259,26 @@ func (s *BareMetalProviderServer) NodeGroupForNode(ctx context.Context, req *Nod
 		return nil, fmt.Errorf("node is required")
 	}
 
-	// Check if a server with this name exists
-	var server baremetalcontrollerv1.Server
-	if err := s.Client.Get(ctx, client.ObjectKey{Name: node.Name}, &server); err != nil {
+	server, err := s.serverForNode(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+	if server == nil || !autoscaled(server) {
 		// Node not found in our inventory, return empty response
 		return &NodeGroupForNodeResponse{}, nil
 	}
 
-	// All servers belong to the default node group
+	// Servers belong to the node group of their ServerClass's tier
+	groups, err := s.nodeGroupIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nodeGroupID := groups.of(server)
 	return &NodeGroupForNodeResponse{
 		NodeGroup: &NodeGroup{
-			Id:      defaultNodeGroupID,
+			Id:      nodeGroupID,
 			MinSize: 0,
-			MaxSize: s.getMaxSize(ctx),
+			MaxSize: s.getMaxSize(ctx, nodeGroupID),
 		},
 	}, nil
 }
@@ -136,25 +288,20 @@ func (s *BareMetalProviderServer) NodeGroupForNode(ctx context.Context, req *Nod
 func (s *BareMetalProviderServer) NodeGroupTargetSize(ctx context.Context, req *NodeGroupTargetSizeRequest) (*NodeGroupTargetSizeResponse, error) {
 	nodeGroupID := req.GetId()
 
-	if nodeGroupID != defaultNodeGroupID {
-		return nil, fmt.Errorf("unknown node group: %s", nodeGroupID)
-	}
-
-	var servers baremetalcontrollerv1.ServerList
-	if err := s.Client.List(ctx, &servers); err != nil {
-		return nil, fmt.Errorf("failed to list servers: %w", err)
+	if err := checkNodeGroup(nodeGroupID); err != nil {
+		return nil, err
 	}
 
 	// Count servers that are powered on (target state)
-	targetSize := int32(0)
-	for _, server := range servers.Items {
-		if server.Spec.PowerState == baremetalcontrollerv1.PowerStateOn {
-			targetSize++
-		}
+	targetSize, err := s.countServers(ctx, nodeGroupID, func(server *baremetalcontrollerv1.Server) bool {
+		return server.Spec.PowerState.Settled() == baremetalcontrollerv1.PowerStateOn
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &NodeGroupTargetSizeResponse{
-		TargetSize: targetSize,
+		TargetSize: int32(targetSize),
 	}, nil
 }
 
@@ -163,8 +310,8 @@ func (s *BareMetalProviderServer) NodeGroupTargetSize(ctx context.Context, req *
 func (s *BareMetalProviderServer) NodeGroupDecreaseTargetSize(ctx context.Context, req *NodeGroupDecreaseTargetSizeRequest) (*NodeGroupDecreaseTargetSizeResponse, error) {
 	nodeGroupID := req.GetId()
 
-	if nodeGroupID != defaultNodeGroupID {
-		return nil, fmt.Errorf("unknown node group: %s", nodeGroupID)
+	if err := checkNodeGroup(nodeGroupID); err != nil {
+		return nil, err
 	}
 
 	delta := int(req.GetDelta())
@@ -172,26 +319,25 @@ func (s *BareMetalProviderServer) NodeGroupDecreaseTargetSize(ctx context.Contex
 		return &NodeGroupDecreaseTargetSizeResponse{}, nil
 	}
 
-	var servers baremetalcontrollerv1.ServerList
-	if err := s.Client.List(ctx, &servers); err != nil {
-		return nil, fmt.Errorf("failed to list servers: %w", err)
-	}
-
 	// Power off 'delta' number of servers that are currently on
 	powered_off := 0
-	for i := range servers.Items {
+	err := s.eachServer(ctx, nodeGroupID, func(server *baremetalcontrollerv1.Server) error {
 		if powered_off >= delta {
-			break
+			return listing.ErrStop
 		}
 
-		server := &servers.Items[i]
-		if server.Spec.PowerState == baremetalcontrollerv1.PowerStateOn {
+		if server.Spec.PowerState.Settled() == baremetalcontrollerv1.PowerStateOn {
+			server = server.DeepCopy()
 			server.Spec.PowerState = baremetalcontrollerv1.PowerStateOff
 			if err := s.Client.Update(ctx, server); err != nil {
-				return nil, fmt.Errorf("failed to power off server %s: %w", server.Name, err)
+				return fmt.Errorf("failed to power off server %s: %w", server.Name, err)
 			}
 			powered_off++
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &NodeGroupDecreaseTargetSizeResponse{}, nil
@@ -201,25 +347,37 @@ func (s *BareMetalProviderServer) NodeGroupDecreaseTargetSize(ctx context.Contex
 func (s *BareMetalProviderServer) NodeGroupNodes(ctx context.Context, req *NodeGroupNodesRequest) (*NodeGroupNodesResponse, error) {
 	nodeGroupID := req.GetId()
 
-	if nodeGroupID != defaultNodeGroupID {
-		return nil, fmt.Errorf("unknown node group: %s", nodeGroupID)
+	if err := checkNodeGroup(nodeGroupID); err != nil {
+		return nil, err
 	}
 
-	var servers baremetalcontrollerv1.ServerList
-	if err := s.Client.List(ctx, &servers); err != nil {
-		return nil, fmt.Errorf("failed to list servers: %w", err)
-	}
-
-	instances := make([]*Instance, 0, len(servers.Items))
-	for _, server := range servers.Items {
+	var instances []*Instance
+	err := s.eachServer(ctx, nodeGroupID, func(server *baremetalcontrollerv1.Server) error {
 		status := &InstanceStatus{
 			InstanceState: s.mapPowerStateToInstanceState(server.Spec.PowerState),
 		}
+		// A server powered on by a scale-up is still being created until the
+		// controller acted on its spec
+		if status.InstanceState == InstanceStatus_instanceRunning &&
+			server.Status.ObservedGeneration != server.Generation {
+			status.InstanceState = InstanceStatus_instanceCreating
+		}
+		if server.Status.Status == baremetalcontrollerv1.StatusFailed && server.Status.Reason != "" {
+			status.ErrorInfo = &InstanceErrorInfo{
+				ErrorCode:          string(server.Status.Reason),
+				ErrorMessage:       server.Status.Message,
+				InstanceErrorClass: instanceErrorClassOther,
+			}
+		}
 
 		instances = append(instances, &Instance{
-			Id:     server.Name,
+			Id:     instanceID(server),
 			Status: status,
 		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &NodeGroupNodesResponse{
@@ -227,28 +385,57 @@ func (s *BareMetalProviderServer) NodeGroupNodes(ctx context.Context, req *NodeG
 	}, nil
 }
 
-// GPULabel returns the label key used to identify GPU nodes.
+// NodeGroupTemplateNodeInfo returns the Node a server of the node group
+// registers as, for scale-from-zero simulations. It offers what every
+// server of the group offers at least: the CPUs and memory their BMCs
+// report, their node features, and the node template of their ServerClass.
+// Node groups without a server of known size leave the autoscaler to guess.
+func (s *BareMetalProviderServer) NodeGroupTemplateNodeInfo(ctx context.Context, req *NodeGroupTemplateNodeInfoRequest) (*NodeGroupTemplateNodeInfoResponse, error) {
+	nodeGroupID := req.GetId()
+
+	if err := checkNodeGroup(nodeGroupID); err != nil {
+		return nil, err
+	}
+
+	shape, ok, err := s.templateShape(ctx, nodeGroupID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "no server of node group %s reports its CPUs and memory", nodeGroupID)
+	}
+	nodeBytes, err := templateNode(nodeGroupID, shape).Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal template node: %w", err)
+	}
+
+	return &NodeGroupTemplateNodeInfoResponse{
+		NodeBytes: nodeBytes,
+	}, nil
+}
+
+// GPULabel returns the label key used to identify GPU nodes. Its value is
+// the GPU type, as set by GPU feature discovery.
 func (s *BareMetalProviderServer) GPULabel(ctx context.Context, req *GPULabelRequest) (*GPULabelResponse, error) {
-	// Standard Kubernetes GPU label
 	return &GPULabelResponse{
-		Label: "nvidia.com/gpu",
+		Label: nodefeatures.GPUProductLabel,
 	}, nil
 }
 
 // GetAvailableGPUTypes returns a map of available GPU types and their counts.
+// Servers are counted by their gpu-type label, or the GPU model found on
+// their Node by GPU feature discovery.
 func (s *BareMetalProviderServer) GetAvailableGPUTypes(ctx context.Context, req *GetAvailableGPUTypesRequest) (*GetAvailableGPUTypesResponse, error) {
-	var servers baremetalcontrollerv1.ServerList
-	if err := s.Client.List(ctx, &servers); err != nil {
-		return nil, fmt.Errorf("failed to list servers: %w", err)
-	}
-
 	gpuCounts := make(map[string]int64)
 
-	for _, server := range servers.Items {
-		// Check if server has GPU labels/annotations
-		if gpuType, ok := server.Labels["gpu-type"]; ok {
+	err := s.eachServer(ctx, "", func(server *baremetalcontrollerv1.Server) error {
+		if gpuType := nodefeatures.GPUType(server); gpuType != "" {
 			gpuCounts[gpuType]++
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Convert to map[string]*anypb.Any
@@ -266,10 +453,43 @@ func (s *BareMetalProviderServer) GetAvailableGPUTypes(ctx context.Context, req
 	}, nil
 }
 
+// NodeGroupGetOptions returns the autoscaling options of the node group.
+// While power is cheap, servers are kept longer so capacity is added then
+// rather than later. While it's expensive or the carbon intensity is high,
+// unneeded servers are removed sooner, the carbon intensity taking
+// precedence. Without either policy the autoscaler uses its defaults.
+func (s *BareMetalProviderServer) NodeGroupGetOptions(ctx context.Context, req *NodeGroupAutoscalingOptionsRequest) (*NodeGroupAutoscalingOptionsResponse, error) {
+	if err := checkNodeGroup(req.GetId()); err != nil {
+		return nil, err
+	}
+	if (s.Pricing == nil && s.Carbon == nil) || req.GetDefaults() == nil {
+		return s.UnimplementedCloudProviderServer.NodeGroupGetOptions(ctx, req)
+	}
+
+	options := proto.Clone(req.GetDefaults()).(*NodeGroupAutoscalingOptions)
+	var scaleDown pricing.ScaleDown
+	ok := false
+	if s.Pricing != nil {
+		scaleDown, ok = s.Pricing.ScaleDown(s.Pricing.Level(ctx, time.Now()))
+	}
+	if s.Carbon != nil {
+		if high, _ := s.Carbon.High(ctx, time.Now()); high {
+			scaleDown, ok = s.Carbon.ScaleDown(), true
+		}
+	}
+	if ok {
+		options.ScaleDownUtilizationThreshold = scaleDown.UtilizationThreshold
+		options.ScaleDownUnneededDuration = durationpb.New(scaleDown.UnneededTime)
+	}
+	return &NodeGroupAutoscalingOptionsResponse{
+		NodeGroupAutoscalingOptions: options,
+	}, nil
+}
+
 // Refresh triggers a refresh of the cached cloud provider state.
 func (s *BareMetalProviderServer) Refresh(ctx context.Context, req *RefreshRequest) (*RefreshResponse, error) {
-	// For bare metal, we don't maintain a cache - we always query the
-	// Kubernetes API directly. This is a no-op but returns success.
+	// Servers are read from the informer cache, which watches keep up to
+	// date, or from the API server. This is a no-op but returns success.
 	return &RefreshResponse{}, nil
 }
 
@@ -282,18 +502,202 @@ func (s *BareMetalProviderServer) Cleanup(ctx context.Context, req *CleanupReque
 
 // Helper methods
 
-// getMaxSize returns the maximum size of the node group (total number of servers).
-func (s *BareMetalProviderServer) getMaxSize(ctx context.Context) int32 {
-	var servers baremetalcontrollerv1.ServerList
-	if err := s.Client.List(ctx, &servers); err != nil {
+// deferredClasses returns the ServerClasses whose scale-ups are held back
+// because the carbon intensity is high, until their maxDelay runs out
+func (s *BareMetalProviderServer) deferredClasses(ctx context.Context) (map[string]bool, error) {
+	if s.Carbon == nil {
+		return nil, nil
+	}
+	high, since := s.Carbon.High(ctx, time.Now())
+	if !high {
+		return nil, nil
+	}
+
+	var classes baremetalcontrollerv1.ServerClassList
+	if err := s.reader().List(ctx, &classes); err != nil {
+		return nil, fmt.Errorf("failed to list server classes: %w", err)
+	}
+	deferred := map[string]bool{}
+	for _, class := range classes.Items {
+		policy := class.Spec.Carbon
+		if policy == nil {
+			continue
+		}
+		if policy.MaxDelay == nil || time.Since(since) < policy.MaxDelay.Duration {
+			deferred[class.Name] = true
+		}
+	}
+	return deferred, nil
+}
+
+// getMaxSize returns the maximum size of a node group (total number of its
+// autoscaled servers).
+func (s *BareMetalProviderServer) getMaxSize(ctx context.Context, nodeGroupID string) int32 {
+	count, err := s.countServers(ctx, nodeGroupID, nil)
+	if err != nil {
 		return 0
 	}
-	return int32(len(servers.Items))
+	return int32(count)
+}
+
+// serverForNode finds the server of a node by provider ID, falling back to
+// its node name. It returns nil if there is none.
+func (s *BareMetalProviderServer) serverForNode(ctx context.Context, node *ExternalGrpcNode) (*baremetalcontrollerv1.Server, error) {
+	server, err := s.serverByProviderID(ctx, node.GetProviderID())
+	if err != nil || server != nil {
+		return server, err
+	}
+	if s.Reader == nil {
+		return index.ServerByNodeName(ctx, s.Client, node.GetName(), s.NamespacedServers)
+	}
+
+	server, err = index.ServerByName(ctx, s.Reader, node.GetName(), s.NamespacedServers)
+	if err != nil || server != nil && server.NodeName() == node.GetName() {
+		return server, err
+	}
+	return s.findServer(ctx, func(server *baremetalcontrollerv1.Server) bool {
+		return server.Spec.NodeName == node.GetName()
+	})
+}
+
+// serverByProviderID looks up a server with the provider ID index, or by
+// listing all servers when reading without the cache
+func (s *BareMetalProviderServer) serverByProviderID(ctx context.Context, providerID string) (*baremetalcontrollerv1.Server, error) {
+	if s.Reader == nil {
+		return index.ServerByProviderID(ctx, s.Client, providerID)
+	}
+	if providerID == "" {
+		return nil, nil
+	}
+
+	return s.findServer(ctx, func(server *baremetalcontrollerv1.Server) bool {
+		return server.Spec.ProviderID == providerID
+	})
+}
+
+// findServer lists servers without the cache until one matches, returning
+// nil if none does
+func (s *BareMetalProviderServer) findServer(ctx context.Context, match func(*baremetalcontrollerv1.Server) bool) (*baremetalcontrollerv1.Server, error) {
+	var found *baremetalcontrollerv1.Server
+	err := listing.Servers(ctx, s.Reader, listing.DefaultPageSize, func(server *baremetalcontrollerv1.Server) error {
+		if !match(server) {
+			return nil
+		}
+		found = server.DeepCopy()
+		return listing.ErrStop
+	})
+	return found, err
+}
+
+// reader returns the client used for reads
+func (s *BareMetalProviderServer) reader() client.Reader {
+	if s.Reader != nil {
+		return s.Reader
+	}
+	return s.Client
+}
+
+// instanceID is the ID the autoscaler matches against Node provider IDs
+func instanceID(server *baremetalcontrollerv1.Server) string {
+	if server.Spec.ProviderID != "" {
+		return server.Spec.ProviderID
+	}
+	return server.Name
+}
+
+// eachServer calls fn for every server managed by the autoscaler in a node
+// group, or in any node group if nodeGroupID is empty. fn must copy a
+// server before modifying it.
+func (s *BareMetalProviderServer) eachServer(ctx context.Context, nodeGroupID string, fn func(*baremetalcontrollerv1.Server) error) error {
+	var groups nodeGroupIndex
+	if nodeGroupID != "" {
+		var err error
+		if groups, err = s.nodeGroupIndex(ctx); err != nil {
+			return err
+		}
+	}
+	return listing.Servers(ctx, s.reader(), s.pageSize(), func(server *baremetalcontrollerv1.Server) error {
+		if !autoscaled(server) || (nodeGroupID != "" && groups.of(server) != nodeGroupID) {
+			return nil
+		}
+		return fn(server)
+	})
+}
+
+// countServers returns the number of servers managed by the autoscaler in
+// a node group that match, or all of them if match is nil
+func (s *BareMetalProviderServer) countServers(ctx context.Context, nodeGroupID string, match func(*baremetalcontrollerv1.Server) bool) (int, error) {
+	count := 0
+	err := s.eachServer(ctx, nodeGroupID, func(server *baremetalcontrollerv1.Server) error {
+		if match == nil || match(server) {
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// pageSize pages reads from the API server. The cache can't be paged.
+func (s *BareMetalProviderServer) pageSize() int64 {
+	if s.Reader != nil {
+		return listing.DefaultPageSize
+	}
+	return 0
+}
+
+// autoscaled returns false for servers excluded from autoscaling or
+// protected by their role, which only change power state when edited
+// manually, and for standby servers, which join the node group once
+// promoted.
+func autoscaled(server *baremetalcontrollerv1.Server) bool {
+	return server.Annotations[baremetalcontrollerv1.AutoscalerExcludeAnnotation] != "true" &&
+		server.Annotations[baremetalcontrollerv1.StandbyAnnotation] != "true" &&
+		!server.Spec.Role.Protected()
+}
+
+// promoteStandby moves up to delta active standby servers of a node group
+// into it by uncordoning their nodes, spread like cold servers. It returns
+// the keys of the promoted servers.
+func (s *BareMetalProviderServer) promoteStandby(ctx context.Context, nodeGroupID string, groups nodeGroupIndex, delta int, spread *spread) (map[client.ObjectKey]bool, error) {
+	var standby []*baremetalcontrollerv1.Server
+	err := listing.Servers(ctx, s.reader(), s.pageSize(), func(server *baremetalcontrollerv1.Server) error {
+		if server.Annotations[baremetalcontrollerv1.StandbyAnnotation] == "true" &&
+			groups.of(server) == nodeGroupID &&
+			server.Annotations[baremetalcontrollerv1.AutoscalerExcludeAnnotation] != "true" &&
+			!server.Spec.Role.Protected() &&
+			server.Spec.PowerState.Settled() == baremetalcontrollerv1.PowerStateOn &&
+			server.Status.Status == baremetalcontrollerv1.StatusActive {
+			standby = append(standby, server.DeepCopy())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	promoted := map[client.ObjectKey]bool{}
+	for len(promoted) < delta && len(standby) > 0 {
+		i := spread.pick(standby)
+		server := standby[i]
+		standby = append(standby[:i], standby[i+1:]...)
+
+		if err := drain.Uncordon(ctx, s.Client, server.NodeName()); err != nil {
+			return promoted, fmt.Errorf("failed to uncordon standby server %s: %w", server.Name, err)
+		}
+		patch := client.MergeFrom(server.DeepCopy())
+		delete(server.Annotations, baremetalcontrollerv1.StandbyAnnotation)
+		if err := s.Client.Patch(ctx, server, patch); err != nil {
+			return promoted, fmt.Errorf("failed to promote standby server %s: %w", server.Name, err)
+		}
+		spread.add(server)
+		promoted[client.ObjectKeyFromObject(server)] = true
+	}
+	return promoted, nil
 }
 
 // mapPowerStateToInstanceState converts a server power state to an instance state.
 func (s *BareMetalProviderServer) mapPowerStateToInstanceState(powerState baremetalcontrollerv1.PowerState) InstanceStatus_InstanceState {
-	switch powerState {
+	switch powerState.Settled() {
 	case baremetalcontrollerv1.PowerStateOn:
 		return InstanceStatus_instanceRunning
 	case baremetalcontrollerv1.PowerStateOff:
@@ -0,0 +1,499 @@
+package protos
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	corev1 "k8s.io/api/core/v1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/index"
+	"github.com/Unbounder1/bare-metal-controller/internal/listing"
+	"github.com/Unbounder1/bare-metal-controller/internal/pricing"
+)
+
+// newProviderClient returns a fake client with the provider ID and node name
+// indexes the manager's cache has
+func newProviderClient(t *testing.T, objs ...client.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := baremetalcontrollerv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).
+		WithIndex(&baremetalcontrollerv1.Server{}, index.ServerProviderIDField, providerIDs).
+		WithIndex(&baremetalcontrollerv1.Server{}, index.ServerNodeNameField, func(obj client.Object) []string {
+			if name := obj.(*baremetalcontrollerv1.Server).Spec.NodeName; name != "" {
+				return []string{name}
+			}
+			return nil
+		}).
+		Build()
+}
+
+func providerIDs(obj client.Object) []string {
+	if id := obj.(*baremetalcontrollerv1.Server).Spec.ProviderID; id != "" {
+		return []string{id}
+	}
+	return nil
+}
+
+func poweredServer(name string, power baremetalcontrollerv1.PowerState, excluded bool) *baremetalcontrollerv1.Server {
+	server := &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: baremetalcontrollerv1.ServerSpec{
+			PowerState: power,
+			ProviderID: "baremetal://" + name,
+		},
+	}
+	if excluded {
+		server.Annotations = map[string]string{baremetalcontrollerv1.AutoscalerExcludeAnnotation: "true"}
+	}
+	return server
+}
+
+func powerStateOf(t *testing.T, c client.Client, name string) baremetalcontrollerv1.PowerState {
+	t.Helper()
+	var server baremetalcontrollerv1.Server
+	if err := c.Get(context.Background(), client.ObjectKey{Name: name}, &server); err != nil {
+		t.Fatal(err)
+	}
+	return server.Spec.PowerState
+}
+
+func TestAutoscalerExcludeAnnotation(t *testing.T) {
+	on, off := baremetalcontrollerv1.PowerStateOn, baremetalcontrollerv1.PowerStateOff
+	c := newProviderClient(t,
+		poweredServer("worker-01", on, false),
+		poweredServer("worker-02", off, false),
+		poweredServer("pinned-01", on, true),
+		poweredServer("pinned-02", off, true),
+	)
+	s := &BareMetalProviderServer{Client: c}
+	ctx := context.Background()
+
+	groups, err := s.NodeGroups(ctx, &NodeGroupsRequest{})
+	if err != nil {
+		t.Fatalf("NodeGroups() error = %v", err)
+	}
+	if len(groups.NodeGroups) != 1 || groups.NodeGroups[0].MaxSize != 2 {
+		t.Errorf("NodeGroups() = %v, want one group of the 2 autoscaled servers", groups.NodeGroups)
+	}
+	target, err := s.NodeGroupTargetSize(ctx, &NodeGroupTargetSizeRequest{Id: defaultNodeGroupID})
+	if err != nil || target.TargetSize != 1 {
+		t.Errorf("NodeGroupTargetSize() = %v, %v, want 1", target, err)
+	}
+	nodes, err := s.NodeGroupNodes(ctx, &NodeGroupNodesRequest{Id: defaultNodeGroupID})
+	if err != nil {
+		t.Fatalf("NodeGroupNodes() error = %v", err)
+	}
+	for _, instance := range nodes.Instances {
+		if instance.Id == "baremetal://pinned-01" || instance.Id == "baremetal://pinned-02" {
+			t.Errorf("NodeGroupNodes() lists excluded server %s", instance.Id)
+		}
+	}
+	if len(nodes.Instances) != 2 {
+		t.Errorf("NodeGroupNodes() = %d instances, want 2", len(nodes.Instances))
+	}
+
+	// The autoscaler leaves nodes without a node group alone
+	group, err := s.NodeGroupForNode(ctx, &NodeGroupForNodeRequest{Node: &ExternalGrpcNode{Name: "pinned-01", ProviderID: "baremetal://pinned-01"}})
+	if err != nil || group.NodeGroup != nil {
+		t.Errorf("NodeGroupForNode(pinned-01) = %v, %v, want no node group", group, err)
+	}
+	group, err = s.NodeGroupForNode(ctx, &NodeGroupForNodeRequest{Node: &ExternalGrpcNode{Name: "worker-01", ProviderID: "baremetal://worker-01"}})
+	if err != nil || group.NodeGroup.GetId() != defaultNodeGroupID || group.NodeGroup.MaxSize != 2 {
+		t.Errorf("NodeGroupForNode(worker-01) = %v, %v", group, err)
+	}
+
+	_, err = s.NodeGroupDeleteNodes(ctx, &NodeGroupDeleteNodesRequest{
+		Id:    defaultNodeGroupID,
+		Nodes: []*ExternalGrpcNode{{Name: "pinned-01", ProviderID: "baremetal://pinned-01"}},
+	})
+	if err == nil {
+		t.Errorf("NodeGroupDeleteNodes() deleted an excluded server")
+	}
+	if got := powerStateOf(t, c, "pinned-01"); got != on {
+		t.Errorf("excluded server powerState = %s, want on", got)
+	}
+
+	// Only worker-02 can be powered on, and only worker-01 powered off
+	if _, err := s.NodeGroupIncreaseSize(ctx, &NodeGroupIncreaseSizeRequest{Id: defaultNodeGroupID, Delta: 2}); err == nil {
+		t.Errorf("NodeGroupIncreaseSize() powered on an excluded server")
+	}
+	if got := powerStateOf(t, c, "worker-02"); got != on {
+		t.Errorf("worker-02 powerState = %s, want on", got)
+	}
+	if _, err := s.NodeGroupDecreaseTargetSize(ctx, &NodeGroupDecreaseTargetSizeRequest{Id: defaultNodeGroupID, Delta: 5}); err != nil {
+		t.Fatalf("NodeGroupDecreaseTargetSize() error = %v", err)
+	}
+	for name, want := range map[string]baremetalcontrollerv1.PowerState{"worker-01": off, "worker-02": off, "pinned-01": on, "pinned-02": off} {
+		if got := powerStateOf(t, c, name); got != want {
+			t.Errorf("%s powerState = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestNodeGroupNodesCreatingUntilObserved(t *testing.T) {
+	on := baremetalcontrollerv1.PowerStateOn
+	observed := poweredServer("worker-01", on, false)
+	observed.Generation = 2
+	observed.Status.ObservedGeneration = 2
+	scaledUp := poweredServer("worker-02", on, false)
+	scaledUp.Generation = 3
+	scaledUp.Status.ObservedGeneration = 2
+	s := &BareMetalProviderServer{Client: newProviderClient(t, observed, scaledUp)}
+
+	nodes, err := s.NodeGroupNodes(context.Background(), &NodeGroupNodesRequest{Id: defaultNodeGroupID})
+	if err != nil {
+		t.Fatalf("NodeGroupNodes() error = %v", err)
+	}
+	want := map[string]InstanceStatus_InstanceState{
+		"baremetal://worker-01": InstanceStatus_instanceRunning,
+		"baremetal://worker-02": InstanceStatus_instanceCreating,
+	}
+	for _, instance := range nodes.Instances {
+		if got := instance.Status.InstanceState; got != want[instance.Id] {
+			t.Errorf("instance %s state = %v, want %v", instance.Id, got, want[instance.Id])
+		}
+	}
+	if len(nodes.Instances) != len(want) {
+		t.Errorf("NodeGroupNodes() = %d instances, want %d", len(nodes.Instances), len(want))
+	}
+}
+
+func TestAutoscaled(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		role        baremetalcontrollerv1.ServerRole
+		want        bool
+	}{
+		{name: "worker", want: true},
+		{name: "excluded", annotations: map[string]string{baremetalcontrollerv1.AutoscalerExcludeAnnotation: "true"}},
+		{name: "exclusion off", annotations: map[string]string{baremetalcontrollerv1.AutoscalerExcludeAnnotation: "false"}, want: true},
+		{name: "standby", annotations: map[string]string{baremetalcontrollerv1.StandbyAnnotation: "true"}},
+		{name: "control plane", role: baremetalcontrollerv1.ServerRoleControlPlane},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &baremetalcontrollerv1.Server{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker-01", Annotations: tt.annotations},
+				Spec:       baremetalcontrollerv1.ServerSpec{Role: tt.role},
+			}
+			if got := autoscaled(server); got != tt.want {
+				t.Errorf("autoscaled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReaderServesReads(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := baremetalcontrollerv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	// The API reader has no cache indexes
+	apiServer := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		poweredServer("worker-01", baremetalcontrollerv1.PowerStateOn, false),
+		poweredServer("worker-02", baremetalcontrollerv1.PowerStateOff, false),
+	).Build()
+	errCacheRead := errors.New("read from the cache")
+	cached := interceptor.NewClient(apiServer, interceptor.Funcs{
+		Get: func(context.Context, client.WithWatch, client.ObjectKey, client.Object, ...client.GetOption) error {
+			return errCacheRead
+		},
+		List: func(context.Context, client.WithWatch, client.ObjectList, ...client.ListOption) error {
+			return errCacheRead
+		},
+	})
+	s := &BareMetalProviderServer{Client: cached, Reader: apiServer}
+	ctx := context.Background()
+
+	if s.pageSize() != listing.DefaultPageSize {
+		t.Errorf("pageSize() = %d, want reads from the API server paged", s.pageSize())
+	}
+	target, err := s.NodeGroupTargetSize(ctx, &NodeGroupTargetSizeRequest{Id: defaultNodeGroupID})
+	if err != nil || target.TargetSize != 1 {
+		t.Errorf("NodeGroupTargetSize() = %v, %v, want 1", target, err)
+	}
+	// Provider IDs are looked up without the index
+	group, err := s.NodeGroupForNode(ctx, &NodeGroupForNodeRequest{Node: &ExternalGrpcNode{Name: "node-a", ProviderID: "baremetal://worker-02"}})
+	if err != nil || group.NodeGroup.GetId() != defaultNodeGroupID {
+		t.Errorf("NodeGroupForNode() = %v, %v", group, err)
+	}
+
+	// Writes still go through the client
+	if _, err := s.NodeGroupIncreaseSize(ctx, &NodeGroupIncreaseSizeRequest{Id: defaultNodeGroupID, Delta: 1}); err != nil {
+		t.Fatalf("NodeGroupIncreaseSize() error = %v", err)
+	}
+	if got := powerStateOf(t, apiServer, "worker-02"); got != baremetalcontrollerv1.PowerStateOn {
+		t.Errorf("worker-02 powerState = %s, want on", got)
+	}
+
+	// Without a Reader everything is read from the cache
+	s.Reader = nil
+	if _, err := s.NodeGroupTargetSize(ctx, &NodeGroupTargetSizeRequest{Id: defaultNodeGroupID}); !errors.Is(err, errCacheRead) {
+		t.Errorf("NodeGroupTargetSize() error = %v without a Reader, want a cache read", err)
+	}
+}
+
+func TestListedServersNotModified(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := baremetalcontrollerv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	standby := poweredServer("standby-01", baremetalcontrollerv1.PowerStateOn, false)
+	standby.Annotations = map[string]string{baremetalcontrollerv1.StandbyAnnotation: "true"}
+	standby.Status.Status = baremetalcontrollerv1.StatusActive
+	worker := poweredServer("worker-01", baremetalcontrollerv1.PowerStateOn, false)
+	worker.Labels = map[string]string{"rack": "r1"}
+
+	// Cached lists skip the deep copy, so listed servers share their maps
+	// with the cache. Hand out the same maps for every list of a version.
+	type cached struct{ annotations, labels, wantAnnotations, wantLabels map[string]string }
+	cache := map[string]*cached{}
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(worker, standby, poweredServer("worker-02", baremetalcontrollerv1.PowerStateOff, false),
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "standby-01"}}).
+		WithIndex(&baremetalcontrollerv1.Server{}, index.ServerProviderIDField, providerIDs).
+		WithInterceptorFuncs(interceptor.Funcs{
+			List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+				if err := c.List(ctx, list, opts...); err != nil {
+					return err
+				}
+				servers, ok := list.(*baremetalcontrollerv1.ServerList)
+				if !ok {
+					return nil
+				}
+				for i := range servers.Items {
+					server := &servers.Items[i]
+					key := server.Name + "/" + server.ResourceVersion
+					if cache[key] == nil {
+						cache[key] = &cached{
+							annotations: server.Annotations, labels: server.Labels,
+							wantAnnotations: maps.Clone(server.Annotations), wantLabels: maps.Clone(server.Labels),
+						}
+					}
+					server.Annotations, server.Labels = cache[key].annotations, cache[key].labels
+				}
+				return nil
+			},
+		}).Build()
+	s := &BareMetalProviderServer{Client: c, SpreadLabels: []string{"rack"}}
+	ctx := context.Background()
+
+	if _, err := s.NodeGroupIncreaseSize(ctx, &NodeGroupIncreaseSizeRequest{Id: defaultNodeGroupID, Delta: 2}); err != nil {
+		t.Fatalf("NodeGroupIncreaseSize() error = %v", err)
+	}
+	// The changes were made to copies and written
+	var promoted baremetalcontrollerv1.Server
+	if err := c.Get(ctx, client.ObjectKey{Name: "standby-01"}, &promoted); err != nil {
+		t.Fatal(err)
+	}
+	if promoted.Annotations[baremetalcontrollerv1.StandbyAnnotation] != "" {
+		t.Errorf("standby-01 not promoted")
+	}
+	if got := powerStateOf(t, c, "worker-02"); got != baremetalcontrollerv1.PowerStateOn {
+		t.Errorf("worker-02 powerState = %s, want on", got)
+	}
+
+	if _, err := s.NodeGroupDeleteNodes(ctx, &NodeGroupDeleteNodesRequest{
+		Id:    defaultNodeGroupID,
+		Nodes: []*ExternalGrpcNode{{Name: "worker-01", ProviderID: "baremetal://worker-01"}},
+	}); err != nil {
+		t.Fatalf("NodeGroupDeleteNodes() error = %v", err)
+	}
+	if _, err := s.NodeGroupDecreaseTargetSize(ctx, &NodeGroupDecreaseTargetSizeRequest{Id: defaultNodeGroupID, Delta: 1}); err != nil {
+		t.Fatalf("NodeGroupDecreaseTargetSize() error = %v", err)
+	}
+
+	for key, server := range cache {
+		if !maps.Equal(server.annotations, server.wantAnnotations) || !maps.Equal(server.labels, server.wantLabels) {
+			t.Errorf("listed server %s modified: annotations %v, labels %v", key, server.annotations, server.labels)
+		}
+	}
+}
+
+func TestNodeGroupGetOptions(t *testing.T) {
+	defaults := &NodeGroupAutoscalingOptions{
+		ScaleDownUtilizationThreshold: 0.5,
+		ScaleDownUnneededDuration:     durationpb.New(10 * time.Minute),
+	}
+	policy := func(schedule string) *pricing.Policy {
+		opts := pricing.DefaultOptions()
+		opts.Source, opts.Schedule = pricing.SourceStatic, schedule
+		opts.CheapBelow, opts.ExpensiveAbove = 0.15, 0.25
+		p, err := pricing.NewPolicy(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	tests := []struct {
+		name          string
+		pricing       *pricing.Policy
+		carbon        *pricing.CarbonPolicy
+		wantThreshold float64
+		wantUnneeded  time.Duration
+	}{
+		{name: "cheap", pricing: policy("00:00=0.1"), wantThreshold: 0.3, wantUnneeded: 30 * time.Minute},
+		{name: "normal", pricing: policy("00:00=0.2"), wantThreshold: 0.5, wantUnneeded: 10 * time.Minute},
+		{name: "expensive", pricing: policy("00:00=0.4"), wantThreshold: 0.7, wantUnneeded: 2 * time.Minute},
+		// High carbon intensity takes precedence over cheap power
+		{name: "cheap but high carbon", pricing: policy("00:00=0.1"), carbon: carbonPolicy(t, "00:00=500"), wantThreshold: 0.7, wantUnneeded: 2 * time.Minute},
+		{name: "cheap and low carbon", pricing: policy("00:00=0.1"), carbon: carbonPolicy(t, "00:00=100"), wantThreshold: 0.3, wantUnneeded: 30 * time.Minute},
+		{name: "high carbon only", carbon: carbonPolicy(t, "00:00=500"), wantThreshold: 0.7, wantUnneeded: 2 * time.Minute},
+		{name: "low carbon only", carbon: carbonPolicy(t, "00:00=100"), wantThreshold: 0.5, wantUnneeded: 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &BareMetalProviderServer{Client: newProviderClient(t), Pricing: tt.pricing, Carbon: tt.carbon}
+			resp, err := s.NodeGroupGetOptions(context.Background(), &NodeGroupAutoscalingOptionsRequest{Id: defaultNodeGroupID, Defaults: defaults})
+			if err != nil {
+				t.Fatalf("NodeGroupGetOptions() error = %v", err)
+			}
+			options := resp.NodeGroupAutoscalingOptions
+			if options.ScaleDownUtilizationThreshold != tt.wantThreshold || options.ScaleDownUnneededDuration.AsDuration() != tt.wantUnneeded {
+				t.Errorf("options = %v, want threshold %v and unneeded time %s", options, tt.wantThreshold, tt.wantUnneeded)
+			}
+		})
+	}
+	if defaults.ScaleDownUtilizationThreshold != 0.5 {
+		t.Errorf("defaults of the request modified")
+	}
+
+	// Without a policy the autoscaler keeps its defaults
+	s := &BareMetalProviderServer{Client: newProviderClient(t)}
+	if _, err := s.NodeGroupGetOptions(context.Background(), &NodeGroupAutoscalingOptionsRequest{Id: defaultNodeGroupID, Defaults: defaults}); status.Code(err) != codes.Unimplemented {
+		t.Errorf("NodeGroupGetOptions() error = %v without a policy, want unimplemented", err)
+	}
+}
+
+func carbonPolicy(t *testing.T, schedule string) *pricing.CarbonPolicy {
+	t.Helper()
+	opts := pricing.DefaultCarbonOptions()
+	opts.Source, opts.Schedule = pricing.CarbonSourceStatic, schedule
+	p, err := pricing.NewCarbonPolicy(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestIncreaseSizeDefersToLowCarbon(t *testing.T) {
+	classServer := func(name string, class string) *baremetalcontrollerv1.Server {
+		server := poweredServer(name, baremetalcontrollerv1.PowerStateOff, false)
+		server.Spec.ServerClassName = class
+		return server
+	}
+	serverClass := func(name string, policy *baremetalcontrollerv1.CarbonPolicy) *baremetalcontrollerv1.ServerClass {
+		return &baremetalcontrollerv1.ServerClass{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       baremetalcontrollerv1.ServerClassSpec{Carbon: policy},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		schedule string
+		policy   *baremetalcontrollerv1.CarbonPolicy
+		wantOn   bool
+	}{
+		{name: "low carbon", schedule: "00:00=100", policy: &baremetalcontrollerv1.CarbonPolicy{}, wantOn: true},
+		{name: "high carbon", schedule: "00:00=500", policy: &baremetalcontrollerv1.CarbonPolicy{}},
+		{name: "within max delay", schedule: "00:00=500", policy: &baremetalcontrollerv1.CarbonPolicy{MaxDelay: &metav1.Duration{Duration: time.Hour}}},
+		{name: "max delay over", schedule: "00:00=500", policy: &baremetalcontrollerv1.CarbonPolicy{MaxDelay: &metav1.Duration{Duration: time.Nanosecond}}, wantOn: true},
+		{name: "not deferrable", schedule: "00:00=500", wantOn: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newProviderClient(t, serverClass("batch", tt.policy), classServer("batch-01", "batch"))
+			s := &BareMetalProviderServer{Client: c, Carbon: carbonPolicy(t, tt.schedule)}
+
+			_, err := s.NodeGroupIncreaseSize(context.Background(), &NodeGroupIncreaseSizeRequest{Id: defaultNodeGroupID, Delta: 1})
+			on := powerStateOf(t, c, "batch-01") == baremetalcontrollerv1.PowerStateOn
+			if on != tt.wantOn {
+				t.Errorf("batch-01 powered on = %v, want %v", on, tt.wantOn)
+			}
+			if tt.wantOn && err != nil {
+				t.Errorf("NodeGroupIncreaseSize() error = %v", err)
+			}
+			// The autoscaler backs off the node group instead of failing
+			if !tt.wantOn && status.Code(err) != codes.ResourceExhausted {
+				t.Errorf("NodeGroupIncreaseSize() error = %v, want resource exhausted", err)
+			}
+		})
+	}
+
+	// Servers of other classes are still powered on
+	c := newProviderClient(t, serverClass("batch", &baremetalcontrollerv1.CarbonPolicy{}),
+		classServer("batch-01", "batch"), classServer("web-01", "web"))
+	s := &BareMetalProviderServer{Client: c, Carbon: carbonPolicy(t, "00:00=500")}
+	if _, err := s.NodeGroupIncreaseSize(context.Background(), &NodeGroupIncreaseSizeRequest{Id: defaultNodeGroupID, Delta: 1}); err != nil {
+		t.Errorf("NodeGroupIncreaseSize() error = %v", err)
+	}
+	if powerStateOf(t, c, "web-01") != baremetalcontrollerv1.PowerStateOn || powerStateOf(t, c, "batch-01") != baremetalcontrollerv1.PowerStateOff {
+		t.Errorf("want web-01 powered on and batch-01 deferred")
+	}
+}
+
+func TestNodeGroupForNodeNamespaced(t *testing.T) {
+	server := poweredServer("worker-01", baremetalcontrollerv1.PowerStateOn, false)
+	server.Namespace = "team-a"
+	server.Spec.ProviderID = ""
+	scheme := runtime.NewScheme()
+	if err := baremetalcontrollerv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(server).
+		WithIndex(&baremetalcontrollerv1.Server{}, index.ServerProviderIDField, providerIDs).
+		WithIndex(&baremetalcontrollerv1.Server{}, index.ServerNameField, func(obj client.Object) []string {
+			return []string{obj.GetName()}
+		}).
+		Build()
+	s := &BareMetalProviderServer{Client: c, NamespacedServers: true}
+
+	// Nodes are named after their server, without its namespace
+	group, err := s.NodeGroupForNode(context.Background(), &NodeGroupForNodeRequest{Node: &ExternalGrpcNode{Name: "worker-01"}})
+	if err != nil || group.NodeGroup.GetId() != defaultNodeGroupID {
+		t.Errorf("NodeGroupForNode() = %v, %v, want %s", group, err, defaultNodeGroupID)
+	}
+}
+
+func TestNodeGroupForNodeByNodeName(t *testing.T) {
+	server := poweredServer("worker-01", baremetalcontrollerv1.PowerStateOn, false)
+	server.Spec.ProviderID = ""
+	server.Spec.NodeName = "rack3-u12"
+	c := newProviderClient(t, server)
+	ctx := context.Background()
+
+	for _, s := range []*BareMetalProviderServer{{Client: c}, {Client: c, Reader: c}} {
+		group, err := s.NodeGroupForNode(ctx, &NodeGroupForNodeRequest{Node: &ExternalGrpcNode{Name: "rack3-u12"}})
+		if err != nil || group.NodeGroup.GetId() != defaultNodeGroupID {
+			t.Errorf("NodeGroupForNode(rack3-u12) = %v, %v, want %s", group, err, defaultNodeGroupID)
+		}
+		// The server's own name is no node's anymore
+		group, err = s.NodeGroupForNode(ctx, &NodeGroupForNodeRequest{Node: &ExternalGrpcNode{Name: "worker-01"}})
+		if err != nil || group.NodeGroup.GetId() != "" {
+			t.Errorf("NodeGroupForNode(worker-01) = %v, %v, want no node group", group, err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package protos
+
+import (
+	"time"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+// spread counts the powered on servers per value of each spread label, so
+// scale-ups power on servers in the least used zones and racks first. With
+// preferLowWear, servers in equally used zones and racks are picked by their
+// power cycles, then their runtime, spreading wear across the fleet.
+type spread struct {
+	labels        []string
+	counts        []map[string]int
+	preferLowWear bool
+	now           time.Time
+}
+
+func newSpread(labels []string, preferLowWear bool) *spread {
+	s := &spread{
+		labels:        labels,
+		counts:        make([]map[string]int, len(labels)),
+		preferLowWear: preferLowWear,
+		now:           time.Now(),
+	}
+	for i := range s.counts {
+		s.counts[i] = map[string]int{}
+	}
+	return s
+}
+
+// add counts a powered on server. Servers without a label share the empty
+// value.
+func (s *spread) add(server *baremetalcontrollerv1.Server) {
+	for i, label := range s.labels {
+		s.counts[i][server.Labels[label]]++
+	}
+}
+
+// pick returns the index of the candidate with the fewest powered on
+// servers sharing its first label, then its second one and so on, then the
+// least worn one if wear is preferred. Ties are broken by name.
+func (s *spread) pick(candidates []*baremetalcontrollerv1.Server) int {
+	best := 0
+	for i := 1; i < len(candidates); i++ {
+		if s.less(candidates[i], candidates[best]) {
+			best = i
+		}
+	}
+	return best
+}
+
+func (s *spread) less(a, b *baremetalcontrollerv1.Server) bool {
+	for i, label := range s.labels {
+		countA, countB := s.counts[i][a.Labels[label]], s.counts[i][b.Labels[label]]
+		if countA != countB {
+			return countA < countB
+		}
+	}
+	if s.preferLowWear {
+		cyclesA, runtimeA := wearOf(a, s.now)
+		cyclesB, runtimeB := wearOf(b, s.now)
+		if cyclesA != cyclesB {
+			return cyclesA < cyclesB
+		}
+		if runtimeA != runtimeB {
+			return runtimeA < runtimeB
+		}
+	}
+	return a.Name < b.Name
+}
+
+// wearOf returns the power cycles and runtime of a server, zero for servers
+// that were never seen online
+func wearOf(server *baremetalcontrollerv1.Server, now time.Time) (int64, time.Duration) {
+	wear := server.Status.Wear
+	if wear == nil {
+		return 0, 0
+	}
+	return wear.PowerCycles, wear.TotalRuntime(now)
+}
@@ -0,0 +1,143 @@
+package protos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+const zoneLabel = "topology.kubernetes.io/zone"
+
+func placedServer(name string, zone string, rack string) *baremetalcontrollerv1.Server {
+	server := poweredServer(name, baremetalcontrollerv1.PowerStateOff, false)
+	server.Labels = map[string]string{}
+	if zone != "" {
+		server.Labels[zoneLabel] = zone
+	}
+	if rack != "" {
+		server.Labels["rack"] = rack
+	}
+	return server
+}
+
+func wornServer(name string, cycles int64, runtime time.Duration) *baremetalcontrollerv1.Server {
+	server := placedServer(name, "a", "r1")
+	server.Status.Wear = &baremetalcontrollerv1.WearStatus{PowerCycles: cycles, Runtime: metav1.Duration{Duration: runtime}}
+	return server
+}
+
+func TestSpreadPick(t *testing.T) {
+	wornZoneB := wornServer("worker-03", 900, 0)
+	wornZoneB.Labels = map[string]string{zoneLabel: "b", "rack": "r2"}
+
+	tests := []struct {
+		name          string
+		preferLowWear bool
+		on            []*baremetalcontrollerv1.Server
+		candidates    []*baremetalcontrollerv1.Server
+		want          string
+	}{
+		{
+			name:       "by name without servers on",
+			candidates: []*baremetalcontrollerv1.Server{placedServer("worker-02", "a", "r1"), placedServer("worker-01", "b", "r2")},
+			want:       "worker-01",
+		},
+		{
+			name:       "least used zone",
+			on:         []*baremetalcontrollerv1.Server{placedServer("worker-01", "a", "r1")},
+			candidates: []*baremetalcontrollerv1.Server{placedServer("worker-02", "a", "r2"), placedServer("worker-03", "b", "r3")},
+			want:       "worker-03",
+		},
+		{
+			name:       "least used rack within equal zones",
+			on:         []*baremetalcontrollerv1.Server{placedServer("worker-01", "a", "r1"), placedServer("worker-02", "b", "r3")},
+			candidates: []*baremetalcontrollerv1.Server{placedServer("worker-03", "a", "r1"), placedServer("worker-04", "b", "r4")},
+			want:       "worker-04",
+		},
+		{
+			name:       "zone before rack",
+			on:         []*baremetalcontrollerv1.Server{placedServer("worker-01", "a", "r1"), placedServer("worker-02", "a", "r2")},
+			candidates: []*baremetalcontrollerv1.Server{placedServer("worker-03", "a", "r3"), placedServer("worker-04", "b", "r1")},
+			want:       "worker-04",
+		},
+		{
+			name:       "unlabeled servers share a zone",
+			on:         []*baremetalcontrollerv1.Server{placedServer("worker-01", "", "")},
+			candidates: []*baremetalcontrollerv1.Server{placedServer("worker-02", "", ""), placedServer("worker-03", "a", "")},
+			want:       "worker-03",
+		},
+		{
+			name:       "wear ignored by default",
+			candidates: []*baremetalcontrollerv1.Server{wornServer("worker-01", 500, 0), wornServer("worker-02", 10, 0)},
+			want:       "worker-01",
+		},
+		{
+			name:          "fewest power cycles",
+			preferLowWear: true,
+			candidates:    []*baremetalcontrollerv1.Server{wornServer("worker-01", 500, 0), wornServer("worker-02", 10, time.Hour)},
+			want:          "worker-02",
+		},
+		{
+			name:          "least runtime",
+			preferLowWear: true,
+			candidates:    []*baremetalcontrollerv1.Server{wornServer("worker-01", 10, 2*time.Hour), wornServer("worker-02", 10, time.Hour)},
+			want:          "worker-02",
+		},
+		{
+			name:          "never online",
+			preferLowWear: true,
+			candidates:    []*baremetalcontrollerv1.Server{wornServer("worker-01", 1, 0), placedServer("worker-02", "a", "r1")},
+			want:          "worker-02",
+		},
+		{
+			name:          "spread before wear",
+			preferLowWear: true,
+			on:            []*baremetalcontrollerv1.Server{placedServer("worker-01", "b", "r2")},
+			candidates:    []*baremetalcontrollerv1.Server{wornServer("worker-02", 1, 0), wornZoneB},
+			want:          "worker-02",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSpread([]string{zoneLabel, "rack"}, tt.preferLowWear)
+			for _, server := range tt.on {
+				s.add(server)
+			}
+			if got := tt.candidates[s.pick(tt.candidates)].Name; got != tt.want {
+				t.Errorf("pick() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIncreaseSizeSpreads(t *testing.T) {
+	c := newProviderClient(t,
+		placedServer("worker-01", "a", "r1"),
+		placedServer("worker-02", "a", "r1"),
+		placedServer("worker-03", "a", "r2"),
+		placedServer("worker-04", "b", "r3"),
+		placedServer("worker-05", "b", "r3"),
+	)
+	s := &BareMetalProviderServer{Client: c, SpreadLabels: []string{zoneLabel, "rack"}}
+
+	if _, err := s.NodeGroupIncreaseSize(context.Background(), &NodeGroupIncreaseSizeRequest{Id: defaultNodeGroupID, Delta: 3}); err != nil {
+		t.Fatalf("NodeGroupIncreaseSize() error = %v", err)
+	}
+	// One per zone, then the unused rack of zone a
+	want := map[string]baremetalcontrollerv1.PowerState{
+		"worker-01": baremetalcontrollerv1.PowerStateOn,
+		"worker-02": baremetalcontrollerv1.PowerStateOff,
+		"worker-03": baremetalcontrollerv1.PowerStateOn,
+		"worker-04": baremetalcontrollerv1.PowerStateOn,
+		"worker-05": baremetalcontrollerv1.PowerStateOff,
+	}
+	for name, state := range want {
+		if got := powerStateOf(t, c, name); got != state {
+			t.Errorf("%s powerState = %s, want %s", name, got, state)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package protos
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/nodefeatures"
+)
+
+// defaultMaxPods is the kubelet's default pod limit, used unless a
+// ServerClass template sets pods
+const defaultMaxPods = 110
+
+// nodeShape is what the Node of a server looks like when it registers
+type nodeShape struct {
+	allocatable corev1.ResourceList
+	labels      map[string]string
+	taints      []corev1.Taint
+}
+
+// shapeOf returns the Node shape of a server: the CPUs and memory its BMC
+// reports and the features of its last Node, overridden by the template of
+// its ServerClass
+func shapeOf(server *baremetalcontrollerv1.Server, class *baremetalcontrollerv1.ServerClass) nodeShape {
+	shape := nodeShape{
+		allocatable: corev1.ResourceList{},
+		labels:      maps.Clone(server.Status.NodeFeatures),
+	}
+	if shape.labels == nil {
+		shape.labels = map[string]string{}
+	}
+	if gpuType := nodefeatures.GPUType(server); gpuType != "" {
+		shape.labels[nodefeatures.GPUProductLabel] = gpuType
+	}
+	if hardware := server.Status.Hardware; hardware != nil {
+		if hardware.CPUs > 0 {
+			shape.allocatable[corev1.ResourceCPU] = *resource.NewQuantity(int64(hardware.CPUs), resource.DecimalSI)
+		}
+		if hardware.Memory != nil {
+			shape.allocatable[corev1.ResourceMemory] = hardware.Memory.DeepCopy()
+		}
+	}
+	// Servers without a BMC inventory, e.g. WOL servers, are sized by what
+	// their OS reported
+	if inventory := server.Status.Inventory; inventory != nil {
+		if _, ok := shape.allocatable[corev1.ResourceCPU]; !ok && inventory.CPUThreads > 0 {
+			shape.allocatable[corev1.ResourceCPU] = *resource.NewQuantity(int64(inventory.CPUThreads), resource.DecimalSI)
+		}
+		if _, ok := shape.allocatable[corev1.ResourceMemory]; !ok && inventory.Memory != nil {
+			shape.allocatable[corev1.ResourceMemory] = inventory.Memory.DeepCopy()
+		}
+	}
+
+	if class != nil && class.Spec.NodeTemplate != nil {
+		template := class.Spec.NodeTemplate
+		for name, quantity := range template.Allocatable {
+			shape.allocatable[name] = quantity.DeepCopy()
+		}
+		maps.Copy(shape.labels, template.Labels)
+		shape.taints = append(shape.taints, template.Taints...)
+	}
+	if _, ok := shape.allocatable[corev1.ResourcePods]; !ok {
+		shape.allocatable[corev1.ResourcePods] = *resource.NewQuantity(defaultMaxPods, resource.DecimalSI)
+	}
+	return shape
+}
+
+// known returns false for shapes without CPUs or memory, which would make
+// the autoscaler think no pod fits
+func (s nodeShape) known() bool {
+	_, cpu := s.allocatable[corev1.ResourceCPU]
+	_, memory := s.allocatable[corev1.ResourceMemory]
+	return cpu && memory
+}
+
+// intersect narrows the shape down to what the other shape offers too: the
+// smaller of each resource, the labels both have and the taints of either.
+// A pod that fits the result fits both.
+func (s nodeShape) intersect(other nodeShape) nodeShape {
+	result := nodeShape{allocatable: corev1.ResourceList{}, labels: map[string]string{}}
+	for name, quantity := range s.allocatable {
+		otherQuantity, ok := other.allocatable[name]
+		if !ok {
+			continue
+		}
+		if otherQuantity.Cmp(quantity) < 0 {
+			quantity = otherQuantity
+		}
+		result.allocatable[name] = quantity.DeepCopy()
+	}
+	for key, value := range s.labels {
+		if otherValue, ok := other.labels[key]; ok && otherValue == value {
+			result.labels[key] = value
+		}
+	}
+	result.taints = append(result.taints, s.taints...)
+	for _, taint := range other.taints {
+		if !hasTaint(result.taints, taint) {
+			result.taints = append(result.taints, taint)
+		}
+	}
+	return result
+}
+
+func hasTaint(taints []corev1.Taint, taint corev1.Taint) bool {
+	for _, t := range taints {
+		if t.MatchTaint(&taint) {
+			return true
+		}
+	}
+	return false
+}
+
+// templateShape returns the shape every server of a node group has at least,
+// or false if no server's shape is known
+func (s *BareMetalProviderServer) templateShape(ctx context.Context, nodeGroupID string) (nodeShape, bool, error) {
+	var classes baremetalcontrollerv1.ServerClassList
+	if err := s.reader().List(ctx, &classes); err != nil {
+		return nodeShape{}, false, fmt.Errorf("failed to list server classes: %w", err)
+	}
+	byName := map[string]*baremetalcontrollerv1.ServerClass{}
+	for i := range classes.Items {
+		byName[classes.Items[i].Name] = &classes.Items[i]
+	}
+
+	var template nodeShape
+	found := false
+	err := s.eachServer(ctx, nodeGroupID, func(server *baremetalcontrollerv1.Server) error {
+		shape := shapeOf(server, byName[server.Spec.ServerClassName])
+		if !shape.known() {
+			return nil
+		}
+		if found {
+			template = template.intersect(shape)
+		} else {
+			template, found = shape, true
+		}
+		return nil
+	})
+	return template, found, err
+}
+
+// templateNode returns the Node a server of the shape registers as, ready
+// and empty
+func templateNode(nodeGroupID string, shape nodeShape) *corev1.Node {
+	name := "template-node-" + nodeGroupID
+	labels := maps.Clone(shape.labels)
+	labels[corev1.LabelHostname] = name
+	labels[corev1.LabelOSStable] = "linux"
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+		Spec: corev1.NodeSpec{
+			Taints: shape.taints,
+		},
+		Status: corev1.NodeStatus{
+			Capacity:    shape.allocatable.DeepCopy(),
+			Allocatable: shape.allocatable.DeepCopy(),
+			Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+			},
+		},
+	}
+}
@@ -0,0 +1,265 @@
+package protos
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/nodefeatures"
+)
+
+const gpuResource corev1.ResourceName = "nvidia.com/gpu"
+
+// sizedServer returns a server of the class whose BMC reports the CPUs and
+// memory, if any
+func sizedServer(name string, class string, cpus int32, memory string) *baremetalcontrollerv1.Server {
+	server := &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       baremetalcontrollerv1.ServerSpec{ServerClassName: class},
+	}
+	if cpus > 0 || memory != "" {
+		server.Status.Hardware = &baremetalcontrollerv1.HardwareStatus{CPUs: cpus}
+		if memory != "" {
+			quantity := resource.MustParse(memory)
+			server.Status.Hardware.Memory = &quantity
+		}
+	}
+	return server
+}
+
+func gpuClass() *baremetalcontrollerv1.ServerClass {
+	return &baremetalcontrollerv1.ServerClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "gpu"},
+		Spec: baremetalcontrollerv1.ServerClassSpec{
+			NodeTemplate: &baremetalcontrollerv1.NodeTemplate{
+				Allocatable: corev1.ResourceList{
+					gpuResource:           resource.MustParse("4"),
+					corev1.ResourceMemory: resource.MustParse("250Gi"),
+				},
+				Labels: map[string]string{"accelerator": "a100"},
+				Taints: []corev1.Taint{{Key: "nvidia.com/gpu", Value: "present", Effect: corev1.TaintEffectNoSchedule}},
+			},
+		},
+	}
+}
+
+func TestShapeOf(t *testing.T) {
+	withGPU := sizedServer("worker-01", "gpu", 64, "256Gi")
+	withGPU.Labels = map[string]string{nodefeatures.GPUTypeLabel: "NVIDIA-A100"}
+	withFeatures := sizedServer("worker-02", "", 16, "64Gi")
+	withFeatures.Status.NodeFeatures = map[string]string{"feature.node.kubernetes.io/cpu-model.vendor_id": "AMD"}
+	withHostInventory := sizedServer("worker-03", "", 0, "")
+	withHostInventory.Status.Inventory = &baremetalcontrollerv1.InventoryStatus{CPUCores: 8, CPUThreads: 16, Memory: resource.NewQuantity(32<<30, resource.BinarySI)}
+
+	tests := []struct {
+		name        string
+		server      *baremetalcontrollerv1.Server
+		class       *baremetalcontrollerv1.ServerClass
+		known       bool
+		allocatable map[corev1.ResourceName]string
+		labels      map[string]string
+		taints      int
+	}{
+		{
+			name:        "hardware inventory",
+			server:      sizedServer("worker-01", "", 32, "128Gi"),
+			known:       true,
+			allocatable: map[corev1.ResourceName]string{corev1.ResourceCPU: "32", corev1.ResourceMemory: "128Gi", corev1.ResourcePods: "110"},
+		},
+		{
+			name:        "host inventory",
+			server:      withHostInventory,
+			known:       true,
+			allocatable: map[corev1.ResourceName]string{corev1.ResourceCPU: "16", corev1.ResourceMemory: "32Gi"},
+		},
+		{
+			name:        "node features",
+			server:      withFeatures,
+			known:       true,
+			allocatable: map[corev1.ResourceName]string{corev1.ResourceCPU: "16", corev1.ResourceMemory: "64Gi"},
+			labels:      map[string]string{"feature.node.kubernetes.io/cpu-model.vendor_id": "AMD"},
+		},
+		{
+			name:   "class template overrides inventory",
+			server: withGPU,
+			class:  gpuClass(),
+			known:  true,
+			allocatable: map[corev1.ResourceName]string{
+				corev1.ResourceCPU: "64", corev1.ResourceMemory: "250Gi", gpuResource: "4", corev1.ResourcePods: "110",
+			},
+			labels: map[string]string{"accelerator": "a100", nodefeatures.GPUProductLabel: "NVIDIA-A100"},
+			taints: 1,
+		},
+		{
+			name:   "class template sets pods",
+			server: sizedServer("worker-01", "small", 4, "8Gi"),
+			class: &baremetalcontrollerv1.ServerClass{Spec: baremetalcontrollerv1.ServerClassSpec{
+				NodeTemplate: &baremetalcontrollerv1.NodeTemplate{Allocatable: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("30")}},
+			}},
+			known:       true,
+			allocatable: map[corev1.ResourceName]string{corev1.ResourcePods: "30"},
+		},
+		{
+			name:   "no inventory",
+			server: sizedServer("worker-01", "", 0, ""),
+			known:  false,
+		},
+		{
+			name:        "memory not reported",
+			server:      sizedServer("worker-01", "", 32, ""),
+			known:       false,
+			allocatable: map[corev1.ResourceName]string{corev1.ResourceCPU: "32"},
+		},
+		{
+			name:   "class without matching hardware",
+			server: sizedServer("worker-01", "gpu", 0, ""),
+			class:  gpuClass(),
+			// The template has memory but no CPUs
+			known:       false,
+			allocatable: map[corev1.ResourceName]string{gpuResource: "4", corev1.ResourceMemory: "250Gi"},
+			taints:      1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shape := shapeOf(tt.server, tt.class)
+			if shape.known() != tt.known {
+				t.Errorf("known() = %v, want %v", shape.known(), tt.known)
+			}
+			for name, want := range tt.allocatable {
+				got, ok := shape.allocatable[name]
+				if !ok || got.Cmp(resource.MustParse(want)) != 0 {
+					t.Errorf("allocatable %s = %s, want %s", name, got.String(), want)
+				}
+			}
+			for key, want := range tt.labels {
+				if got := shape.labels[key]; got != want {
+					t.Errorf("label %s = %q, want %q", key, got, want)
+				}
+			}
+			if len(shape.taints) != tt.taints {
+				t.Errorf("taints = %v, want %d", shape.taints, tt.taints)
+			}
+		})
+	}
+}
+
+func TestShapeOfDoesNotModifyInputs(t *testing.T) {
+	server := sizedServer("worker-01", "gpu", 64, "256Gi")
+	server.Status.NodeFeatures = map[string]string{"feature": "true"}
+	class := gpuClass()
+
+	shape := shapeOf(server, class)
+	shape.labels["extra"] = "true"
+	memory := shape.allocatable[corev1.ResourceMemory]
+	memory.Add(resource.MustParse("1Gi"))
+	shape.allocatable[corev1.ResourceMemory] = memory
+
+	if _, ok := server.Status.NodeFeatures["extra"]; ok {
+		t.Errorf("shape shares the server's node features")
+	}
+	if _, ok := class.Spec.NodeTemplate.Labels["extra"]; ok {
+		t.Errorf("shape shares the class's labels")
+	}
+	if got := class.Spec.NodeTemplate.Allocatable[corev1.ResourceMemory]; got.Cmp(resource.MustParse("250Gi")) != 0 {
+		t.Errorf("class memory = %s, want 250Gi", got.String())
+	}
+}
+
+func TestShapeIntersect(t *testing.T) {
+	noSchedule := corev1.Taint{Key: "dedicated", Value: "ml", Effect: corev1.TaintEffectNoSchedule}
+	preferNoSchedule := corev1.Taint{Key: "wear", Effect: corev1.TaintEffectPreferNoSchedule}
+	big := nodeShape{
+		allocatable: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("64"),
+			corev1.ResourceMemory: resource.MustParse("128Gi"),
+			gpuResource:           resource.MustParse("4"),
+		},
+		labels: map[string]string{"zone": "a", "accelerator": "a100"},
+		taints: []corev1.Taint{noSchedule},
+	}
+	small := nodeShape{
+		allocatable: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("32"),
+			corev1.ResourceMemory: resource.MustParse("256Gi"),
+		},
+		labels: map[string]string{"zone": "b", "accelerator": "a100"},
+		taints: []corev1.Taint{noSchedule, preferNoSchedule},
+	}
+
+	result := big.intersect(small)
+	want := map[corev1.ResourceName]string{corev1.ResourceCPU: "32", corev1.ResourceMemory: "128Gi"}
+	if len(result.allocatable) != len(want) {
+		t.Errorf("allocatable = %v, want only the resources both offer", result.allocatable)
+	}
+	for name, quantity := range want {
+		if got := result.allocatable[name]; got.Cmp(resource.MustParse(quantity)) != 0 {
+			t.Errorf("allocatable %s = %s, want %s", name, got.String(), quantity)
+		}
+	}
+	if len(result.labels) != 1 || result.labels["accelerator"] != "a100" {
+		t.Errorf("labels = %v, want the labels both have", result.labels)
+	}
+	if len(result.taints) != 2 {
+		t.Errorf("taints = %v, want the taints of either once", result.taints)
+	}
+}
+
+func TestNodeGroupTemplateNodeInfo(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := baremetalcontrollerv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	spotClass := &baremetalcontrollerv1.ServerClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "spot"},
+		Spec:       baremetalcontrollerv1.ServerClassSpec{Tier: baremetalcontrollerv1.ServerTierSpot},
+	}
+	excluded := sizedServer("worker-03", "", 2, "4Gi")
+	excluded.Annotations = map[string]string{baremetalcontrollerv1.AutoscalerExcludeAnnotation: "true"}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		spotClass,
+		sizedServer("worker-01", "", 64, "256Gi"),
+		sizedServer("worker-02", "", 32, "512Gi"),
+		// Servers of unknown size and excluded ones don't narrow the template
+		sizedServer("worker-04", "", 0, ""),
+		excluded,
+		// The spot node group has no server of known size
+		sizedServer("spot-01", "spot", 0, ""),
+	).WithStatusSubresource(&baremetalcontrollerv1.Server{}).Build()
+	s := &BareMetalProviderServer{Client: c}
+	ctx := context.Background()
+
+	resp, err := s.NodeGroupTemplateNodeInfo(ctx, &NodeGroupTemplateNodeInfoRequest{Id: defaultNodeGroupID})
+	if err != nil {
+		t.Fatalf("NodeGroupTemplateNodeInfo() error = %v", err)
+	}
+	var node corev1.Node
+	if err := node.Unmarshal(resp.NodeBytes); err != nil {
+		t.Fatal(err)
+	}
+	if cpu := node.Status.Allocatable[corev1.ResourceCPU]; cpu.Cmp(resource.MustParse("32")) != 0 {
+		t.Errorf("cpu = %s, want 32", cpu.String())
+	}
+	if memory := node.Status.Capacity[corev1.ResourceMemory]; memory.Cmp(resource.MustParse("256Gi")) != 0 {
+		t.Errorf("memory = %s, want 256Gi", memory.String())
+	}
+	if node.Labels[corev1.LabelHostname] != "template-node-"+defaultNodeGroupID {
+		t.Errorf("hostname label = %q", node.Labels[corev1.LabelHostname])
+	}
+
+	_, err = s.NodeGroupTemplateNodeInfo(ctx, &NodeGroupTemplateNodeInfoRequest{Id: spotNodeGroupID})
+	if status.Code(err) != codes.Unimplemented {
+		t.Errorf("spot template error = %v, want Unimplemented", err)
+	}
+	if _, err := s.NodeGroupTemplateNodeInfo(ctx, &NodeGroupTemplateNodeInfoRequest{Id: "other"}); err == nil {
+		t.Errorf("unknown node group returned a template")
+	}
+}
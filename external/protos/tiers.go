@@ -0,0 +1,118 @@
+package protos
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/budget"
+	"github.com/Unbounder1/bare-metal-controller/internal/listing"
+)
+
+// spotNodeGroupID holds the servers of spot ServerClasses, which are
+// reclaimed when the default node group needs their power budget
+const spotNodeGroupID = defaultNodeGroupID + "-spot"
+
+// nodeGroupIndex maps ServerClasses to the node groups of their tier.
+// Servers of other classes, or without one, are in the default node group.
+type nodeGroupIndex map[string]string
+
+func (s *BareMetalProviderServer) nodeGroupIndex(ctx context.Context) (nodeGroupIndex, error) {
+	var classes baremetalcontrollerv1.ServerClassList
+	if err := s.reader().List(ctx, &classes); err != nil {
+		return nil, fmt.Errorf("failed to list server classes: %w", err)
+	}
+	index := nodeGroupIndex{}
+	for _, class := range classes.Items {
+		if class.Spec.Tier == baremetalcontrollerv1.ServerTierSpot {
+			index[class.Name] = spotNodeGroupID
+		}
+	}
+	return index, nil
+}
+
+// of returns the node group of a server
+func (i nodeGroupIndex) of(server *baremetalcontrollerv1.Server) string {
+	if id, ok := i[server.Spec.ServerClassName]; ok {
+		return id
+	}
+	return defaultNodeGroupID
+}
+
+// hasSpot returns true if any ServerClass is in the spot tier
+func (i nodeGroupIndex) hasSpot() bool {
+	return len(i) > 0
+}
+
+// checkNodeGroup returns an error for IDs that are not a node group
+func checkNodeGroup(id string) error {
+	if id != defaultNodeGroupID && id != spotNodeGroupID {
+		return fmt.Errorf("unknown node group: %s", id)
+	}
+	return nil
+}
+
+// reclaim marks powered on spot servers to be drained and powered off, one
+// for each server of the default node group blocked by a power budget they
+// share. It returns the blocked servers that will get room, which can be
+// powered on and wait for the budget.
+func (s *BareMetalProviderServer) reclaim(ctx context.Context, groups nodeGroupIndex, blocked []*baremetalcontrollerv1.Server, budgets map[client.ObjectKey]string) ([]*baremetalcontrollerv1.Server, error) {
+	if len(blocked) == 0 || !groups.hasSpot() {
+		return nil, nil
+	}
+
+	var spot []*baremetalcontrollerv1.Server
+	err := listing.Servers(ctx, s.reader(), s.pageSize(), func(server *baremetalcontrollerv1.Server) error {
+		if groups.of(server) == spotNodeGroupID && autoscaled(server) &&
+			server.Spec.PowerState.Settled() == baremetalcontrollerv1.PowerStateOn && budget.Powered(server) &&
+			server.Annotations[baremetalcontrollerv1.ReclaimAnnotation] == "" {
+			spot = append(spot, server.DeepCopy())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(spot, func(i, j int) bool { return spot[i].Name < spot[j].Name })
+
+	selectors := map[string]labels.Selector{}
+	var admitted []*baremetalcontrollerv1.Server
+	for _, server := range blocked {
+		budgetName := budgets[client.ObjectKeyFromObject(server)]
+		selector, ok := selectors[budgetName]
+		if !ok {
+			var powerBudget baremetalcontrollerv1.PowerBudget
+			if err := s.reader().Get(ctx, client.ObjectKey{Name: budgetName}, &powerBudget); err != nil {
+				return admitted, fmt.Errorf("failed to get power budget %s: %w", budgetName, err)
+			}
+			selector, err = metav1.LabelSelectorAsSelector(&powerBudget.Spec.Selector)
+			if err != nil {
+				return admitted, fmt.Errorf("invalid selector in power budget %s: %w", powerBudget.Name, err)
+			}
+			selectors[budgetName] = selector
+		}
+
+		for i, victim := range spot {
+			if !selector.Matches(labels.Set(victim.Labels)) {
+				continue
+			}
+			patch := client.MergeFrom(victim.DeepCopy())
+			if victim.Annotations == nil {
+				victim.Annotations = map[string]string{}
+			}
+			victim.Annotations[baremetalcontrollerv1.ReclaimAnnotation] = server.Name
+			if err := s.Client.Patch(ctx, victim, patch); err != nil {
+				return admitted, fmt.Errorf("failed to reclaim spot server %s: %w", victim.Name, err)
+			}
+			spot = append(spot[:i], spot[i+1:]...)
+			admitted = append(admitted, server)
+			break
+		}
+	}
+	return admitted, nil
+}
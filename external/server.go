@@ -8,10 +8,13 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"strings"
 
 	"github.com/Unbounder1/bare-metal-controller/external/protos"
+	"github.com/Unbounder1/bare-metal-controller/internal/pricing"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
@@ -29,15 +32,39 @@ type Options struct {
 
 	// CAFile is the path to the CA certificate file
 	CAFile string
+
+	// TLSSecret is the namespace/name of a kubernetes.io/tls Secret, e.g.
+	// issued by cert-manager, to load the certificate from instead of
+	// CertFile and KeyFile. Renewals are picked up without a restart.
+	TLSSecret string
+
+	// CachedReads serves RPCs from the manager's informer cache. When false,
+	// every RPC reads servers from the API server.
+	CachedReads bool
+
+	// AuthzPolicyFile is the path to a policy of which client certificates
+	// may call which RPCs. Empty allows every client to call every RPC.
+	AuthzPolicyFile string
+
+	// SpreadLabels is a comma-separated list of Server labels whose values
+	// scale-ups are spread across, the most significant first. Empty powers
+	// servers on by name.
+	SpreadLabels string
+
+	// PreferLowWear powers on the servers with the fewest power cycles and
+	// the least runtime first among those the spread labels can't tell apart
+	PreferLowWear bool
 }
 
 // DefaultOptions returns the default server options.
 func DefaultOptions() Options {
 	return Options{
-		Address:  ":8086",
-		CertFile: "",
-		KeyFile:  "",
-		CAFile:   "",
+		Address:      ":8086",
+		CertFile:     "",
+		KeyFile:      "",
+		CAFile:       "",
+		CachedReads:  true,
+		SpreadLabels: "topology.kubernetes.io/zone,rack",
 	}
 }
 
@@ -52,6 +79,17 @@ func (o *Options) BindFlags(fs *flag.FlagSet, prefix string) {
 		"Path to TLS key file for gRPC server. Empty for insecure.")
 	fs.StringVar(&o.CAFile, prefix+"ca", o.CAFile,
 		"Path to CA certificate file for gRPC client verification. Empty for insecure.")
+	fs.StringVar(&o.TLSSecret, prefix+"tls-secret", o.TLSSecret,
+		"namespace/name of a kubernetes.io/tls Secret, e.g. from cert-manager, to serve TLS with. "+
+			"Client certificates are verified against its ca.crt unless a CA file is set. Reloaded when the Secret changes.")
+	fs.BoolVar(&o.CachedReads, prefix+"cached-reads", o.CachedReads,
+		"Serve RPCs from the controller's cache. If false, every RPC reads servers from the API server.")
+	fs.StringVar(&o.AuthzPolicyFile, prefix+"authz-policy", o.AuthzPolicyFile,
+		"Path to a policy of which client certificate common names may call which RPCs. Empty to allow all clients. Requires TLS.")
+	fs.StringVar(&o.SpreadLabels, prefix+"spread-labels", o.SpreadLabels,
+		"Comma-separated Server labels, e.g. zone and rack, to spread scale-ups across, the most significant first. Empty to power on servers by name.")
+	fs.BoolVar(&o.PreferLowWear, prefix+"prefer-low-wear", o.PreferLowWear,
+		"Power on the servers with the fewest power cycles, then the least runtime, first among equally used zones and racks.")
 }
 
 // Validate validates the options.
@@ -69,22 +107,42 @@ func (o *Options) Validate() error {
 		}
 	}
 
-	if setCount > 0 && setCount < 3 {
+	if o.TLSSecret != "" {
+		if _, _, err := parseSecretRef(o.TLSSecret); err != nil {
+			return err
+		}
+		if o.CertFile != "" || o.KeyFile != "" {
+			return fmt.Errorf("the TLS secret can't be combined with cert and key files")
+		}
+	} else if setCount > 0 && setCount < 3 {
 		return fmt.Errorf("all TLS options (cert, key, ca) must be set together, or none")
 	}
 
+	// Clients can only be identified by their certificates
+	if o.AuthzPolicyFile != "" && !o.IsTLSEnabled() {
+		return fmt.Errorf("an authorization policy requires TLS")
+	}
+
 	return nil
 }
 
 // IsTLSEnabled returns true if TLS is configured.
 func (o *Options) IsTLSEnabled() bool {
-	return o.CertFile != "" && o.KeyFile != "" && o.CAFile != ""
+	return o.TLSSecret != "" || (o.CertFile != "" && o.KeyFile != "" && o.CAFile != "")
 }
 
 // Server implements manager.Runnable for the gRPC cloud provider server.
 type Server struct {
 	options    Options
+	config     *rest.Config
 	client     client.Client
+	reader     client.Reader
+	policy     *Policy
+	pricing    *pricing.Policy
+	carbon     *pricing.CarbonPolicy
+	spread     []string
+	namespaced bool
+	secretCert *secretCertificate
 	grpcServer *grpc.Server
 	listener   net.Listener
 }
@@ -94,15 +152,64 @@ var _ manager.Runnable = &Server{}
 
 // NewServer creates a new gRPC server runnable.
 func NewServer(opts Options, mgr manager.Manager) (*Server, error) {
-	return &Server{
+	s := &Server{
 		options: opts,
+		config:  mgr.GetConfig(),
 		client:  mgr.GetClient(),
-	}, nil
+	}
+	if opts.TLSSecret != "" {
+		namespace, name, err := parseSecretRef(opts.TLSSecret)
+		if err != nil {
+			return nil, err
+		}
+		s.secretCert = &secretCertificate{namespace: namespace, name: name, caFile: opts.CAFile}
+	}
+	if !opts.CachedReads {
+		s.reader = mgr.GetAPIReader()
+	}
+	for _, label := range strings.Split(opts.SpreadLabels, ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			s.spread = append(s.spread, label)
+		}
+	}
+	if opts.AuthzPolicyFile != "" {
+		policy, err := LoadPolicy(opts.AuthzPolicyFile)
+		if err != nil {
+			return nil, err
+		}
+		s.policy = policy
+	}
+	return s, nil
+}
+
+// SetPricing tunes the scale-down reported to the autoscaler to the
+// electricity price. It must be called before the server is started.
+func (s *Server) SetPricing(policy *pricing.Policy) {
+	s.pricing = policy
+}
+
+// SetCarbon holds back scale-ups of non-urgent ServerClasses and tunes the
+// scale-down reported to the autoscaler while the carbon intensity is high.
+// It must be called before the server is started.
+func (s *Server) SetCarbon(policy *pricing.CarbonPolicy) {
+	s.carbon = policy
+}
+
+// SetNamespacedServers makes the provider look up the server of a node by
+// name in every namespace. It must be called before the server is started.
+func (s *Server) SetNamespacedServers(namespaced bool) {
+	s.namespaced = namespaced
 }
 
 // Start implements manager.Runnable and starts the gRPC server.
 // It blocks until the context is cancelled.
 func (s *Server) Start(ctx context.Context) error {
+	if s.secretCert != nil {
+		if err := s.secretCert.watch(ctx, s.config); err != nil {
+			return err
+		}
+	}
+
 	// Create gRPC server
 	grpcServer, err := s.createGRPCServer()
 	if err != nil {
@@ -112,7 +219,13 @@ func (s *Server) Start(ctx context.Context) error {
 
 	// Register the bare metal provider
 	bareMetalProvider := &protos.BareMetalProviderServer{
-		Client: s.client,
+		Client:            s.client,
+		Reader:            s.reader,
+		Pricing:           s.pricing,
+		Carbon:            s.carbon,
+		SpreadLabels:      s.spread,
+		PreferLowWear:     s.options.PreferLowWear,
+		NamespacedServers: s.namespaced,
 	}
 	protos.RegisterCloudProviderServer(s.grpcServer, bareMetalProvider)
 
@@ -143,6 +256,14 @@ func (s *Server) Start(ctx context.Context) error {
 
 // createGRPCServer creates the gRPC server with optional TLS.
 func (s *Server) createGRPCServer() (*grpc.Server, error) {
+	if s.secretCert != nil {
+		tlsConfig := &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			GetConfigForClient: s.secretCert.getConfigForClient,
+		}
+		return s.newGRPCServer(tlsConfig), nil
+	}
+
 	// Check if TLS is configured
 	if s.options.CertFile == "" || s.options.KeyFile == "" || s.options.CAFile == "" {
 		return grpc.NewServer(), nil
@@ -173,7 +294,17 @@ func (s *Server) createGRPCServer() (*grpc.Server, error) {
 		MinVersion:   tls.VersionTLS12,
 	}
 
-	return grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig))), nil
+	return s.newGRPCServer(tlsConfig), nil
+}
+
+// newGRPCServer creates a TLS gRPC server, enforcing the authorization
+// policy if there is one
+func (s *Server) newGRPCServer(tlsConfig *tls.Config) *grpc.Server {
+	serverOpts := []grpc.ServerOption{grpc.Creds(credentials.NewTLS(tlsConfig))}
+	if s.policy != nil {
+		serverOpts = append(serverOpts, grpc.UnaryInterceptor(s.policy.UnaryInterceptor()))
+	}
+	return grpc.NewServer(serverOpts...)
 }
 
 // NeedLeaderElection implements manager.LeaderElectionRunnable.
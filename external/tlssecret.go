@@ -0,0 +1,115 @@
+package external
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// secretCertificate serves the certificate from a kubernetes.io/tls Secret,
+// such as one managed by cert-manager, and picks up renewals without a
+// restart. Client certificates are verified against the Secret's ca.crt,
+// unless a CA file is given.
+type secretCertificate struct {
+	namespace string
+	name      string
+	caFile    string
+
+	config atomic.Pointer[tls.Config]
+}
+
+// parseSecretRef splits a namespace/name reference
+func parseSecretRef(ref string) (namespace string, name string, err error) {
+	namespace, name, ok := strings.Cut(ref, "/")
+	if !ok || namespace == "" || name == "" {
+		return "", "", fmt.Errorf("TLS secret must be namespace/name, got %q", ref)
+	}
+	return namespace, name, nil
+}
+
+// watch keeps the certificate up to date until ctx is done. Only the one
+// Secret is watched, not every Secret in the namespace.
+func (c *secretCertificate) watch(ctx context.Context, cfg *rest.Config) error {
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create client for TLS secret: %w", err)
+	}
+
+	lw := toolscache.NewListWatchFromClient(clientset.CoreV1().RESTClient(), "secrets", c.namespace,
+		fields.OneTermEqualSelector("metadata.name", c.name))
+	_, informer := toolscache.NewInformerWithOptions(toolscache.InformerOptions{
+		ListerWatcher: lw,
+		ObjectType:    &corev1.Secret{},
+		Handler: toolscache.ResourceEventHandlerFuncs{
+			AddFunc:    func(obj interface{}) { c.update(ctx, obj) },
+			UpdateFunc: func(_, obj interface{}) { c.update(ctx, obj) },
+		},
+	})
+	go informer.Run(ctx.Done())
+	return nil
+}
+
+func (c *secretCertificate) update(ctx context.Context, obj interface{}) {
+	logger := log.FromContext(ctx).WithName("grpc")
+	secret, ok := obj.(*corev1.Secret)
+	if !ok {
+		return
+	}
+
+	config, err := c.tlsConfig(secret)
+	if err != nil {
+		// Keep serving the previous certificate
+		logger.Error(err, "Failed to load gRPC certificate from secret", "secret", c.namespace+"/"+c.name)
+		return
+	}
+	c.config.Store(config)
+	logger.Info("Loaded gRPC certificate from secret", "secret", c.namespace+"/"+c.name)
+}
+
+func (c *secretCertificate) tlsConfig(secret *corev1.Secret) (*tls.Config, error) {
+	certificate, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+
+	caBytes := secret.Data["ca.crt"]
+	if c.caFile != "" {
+		if caBytes, err = os.ReadFile(c.caFile); err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+	}
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(caBytes) {
+		return nil, fmt.Errorf("failed to append CA certificate")
+	}
+
+	return &tls.Config{
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		Certificates: []tls.Certificate{certificate},
+		ClientCAs:    certPool,
+		MinVersion:   tls.VersionTLS12,
+		// gRPC only adds its ALPN protocol to the base config, not to the
+		// ones returned by GetConfigForClient
+		NextProtos: []string{"h2"},
+	}, nil
+}
+
+// getConfigForClient returns the current TLS config for each handshake
+func (c *secretCertificate) getConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	config := c.config.Load()
+	if config == nil {
+		return nil, fmt.Errorf("certificate from secret %s/%s is not loaded yet", c.namespace, c.name)
+	}
+	return config, nil
+}
@@ -0,0 +1,199 @@
+package external
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// certificatePEM returns a certificate and key signed by the parent, or
+// self-signed if parent is nil
+func certificatePEM(t *testing.T, commonName string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, []byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  parent == nil,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// tlsSecret returns a kubernetes.io/tls Secret with a new certificate
+// signed by a new CA
+func tlsSecret(t *testing.T, commonName string) *corev1.Secret {
+	t.Helper()
+	ca, caKey, caPEM, _ := certificatePEM(t, "test-ca", nil, nil)
+	_, _, certPEM, keyPEM := certificatePEM(t, commonName, ca, caKey)
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "grpc-tls", Namespace: "bare-metal"},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       certPEM,
+			corev1.TLSPrivateKeyKey: keyPEM,
+			"ca.crt":                caPEM,
+		},
+	}
+}
+
+// servedName returns the common name of the certificate served next
+func servedName(t *testing.T, c *secretCertificate) string {
+	t.Helper()
+	config, err := c.getConfigForClient(nil)
+	if err != nil {
+		t.Fatalf("getConfigForClient() error = %v", err)
+	}
+	cert, err := x509.ParseCertificate(config.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert.Subject.CommonName
+}
+
+func TestParseSecretRef(t *testing.T) {
+	tests := []struct {
+		ref       string
+		namespace string
+		name      string
+		wantErr   bool
+	}{
+		{ref: "bare-metal/grpc-tls", namespace: "bare-metal", name: "grpc-tls"},
+		{ref: "grpc-tls", wantErr: true},
+		{ref: "/grpc-tls", wantErr: true},
+		{ref: "bare-metal/", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			namespace, name, err := parseSecretRef(tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSecretRef(%q) error = %v, wantErr %v", tt.ref, err, tt.wantErr)
+			}
+			if namespace != tt.namespace || name != tt.name {
+				t.Errorf("parseSecretRef(%q) = %s, %s, want %s, %s", tt.ref, namespace, name, tt.namespace, tt.name)
+			}
+		})
+	}
+}
+
+func TestSecretCertificateRotation(t *testing.T) {
+	ctx := context.Background()
+	c := &secretCertificate{namespace: "bare-metal", name: "grpc-tls"}
+
+	if _, err := c.getConfigForClient(nil); err == nil {
+		t.Fatalf("getConfigForClient() served a certificate before the Secret was loaded")
+	}
+
+	c.update(ctx, tlsSecret(t, "first"))
+	if got := servedName(t, c); got != "first" {
+		t.Fatalf("first load serves %q, want first", got)
+	}
+	config, _ := c.getConfigForClient(nil)
+	if config.ClientAuth != tls.RequireAndVerifyClientCert || config.ClientCAs == nil {
+		t.Errorf("client certificates are not verified against the Secret's ca.crt")
+	}
+
+	c.update(ctx, tlsSecret(t, "renewed"))
+	if got := servedName(t, c); got != "renewed" {
+		t.Fatalf("after rotation serves %q, want renewed", got)
+	}
+
+	malformed := tlsSecret(t, "malformed")
+	malformed.Data[corev1.TLSPrivateKeyKey] = []byte("not a key")
+	c.update(ctx, malformed)
+	if got := servedName(t, c); got != "renewed" {
+		t.Errorf("after a malformed Secret serves %q, want the last good certificate", got)
+	}
+
+	noCA := tlsSecret(t, "no-ca")
+	delete(noCA.Data, "ca.crt")
+	c.update(ctx, noCA)
+	if got := servedName(t, c); got != "renewed" {
+		t.Errorf("after a Secret without ca.crt serves %q, want the last good certificate", got)
+	}
+
+	// Objects other than Secrets are ignored
+	c.update(ctx, &corev1.ConfigMap{})
+	if got := servedName(t, c); got != "renewed" {
+		t.Errorf("after a non-Secret serves %q, want renewed", got)
+	}
+}
+
+func TestSecretCertificateCA(t *testing.T) {
+	noCA := tlsSecret(t, "server")
+	delete(noCA.Data, "ca.crt")
+
+	_, _, caPEM, _ := certificatePEM(t, "file-ca", nil, nil)
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	if err := os.WriteFile(caFile, caPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		caFile  string
+		secret  *corev1.Secret
+		wantErr string
+	}{
+		{name: "CA in Secret", secret: tlsSecret(t, "server")},
+		{name: "CA file", caFile: caFile, secret: noCA},
+		{name: "missing CA", secret: noCA, wantErr: "failed to append CA certificate"},
+		{name: "missing CA file", caFile: filepath.Join(t.TempDir(), "missing.crt"), secret: tlsSecret(t, "server"), wantErr: "failed to read CA certificate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &secretCertificate{namespace: "bare-metal", name: "grpc-tls", caFile: tt.caFile}
+			_, err := c.tlsConfig(tt.secret)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("tlsConfig() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("tlsConfig() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
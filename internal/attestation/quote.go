@@ -0,0 +1,268 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package attestation verifies TPM 2.0 quotes against expected measured boot
+// values, so a server can prove how it booted before it is trusted.
+package attestation
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Unbounder1/bare-metal-controller/internal/power"
+)
+
+const (
+	// TPM_GENERATED_VALUE, prefixed to every structure signed by a TPM
+	tpmGeneratedValue uint32 = 0xff544347
+	// TPM_ST_ATTEST_QUOTE
+	tpmSTAttestQuote uint16 = 0x8018
+	// TPM_ALG_SHA256
+	tpmAlgSHA256 uint16 = 0x000b
+)
+
+// ExpectedPCRs maps a SHA-256 PCR index to its expected digest
+type ExpectedPCRs map[int][]byte
+
+// ParseExpectedPCRs converts PCR values from the Server spec, keyed by the
+// PCR index as a string and given as hex digests.
+func ParseExpectedPCRs(values map[string]string) (ExpectedPCRs, error) {
+	if len(values) == 0 {
+		return nil, fmt.Errorf("at least one expected PCR value is required")
+	}
+
+	pcrs := ExpectedPCRs{}
+	for key, value := range values {
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index > 23 {
+			return nil, fmt.Errorf("invalid PCR index %q", key)
+		}
+		digest, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(value), "0x"))
+		if err != nil || len(digest) != sha256.Size {
+			return nil, fmt.Errorf("PCR %d must be a hex SHA-256 digest", index)
+		}
+		pcrs[index] = digest
+	}
+	return pcrs, nil
+}
+
+// Indices returns the PCR indices in ascending order
+func (e ExpectedPCRs) Indices() []int {
+	indices := make([]int, 0, len(e))
+	for index := range e {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+	return indices
+}
+
+// Digest is the SHA-256 of the concatenated PCR values in index order, which
+// is what the TPM reports as the pcrDigest of a quote.
+func (e ExpectedPCRs) Digest() []byte {
+	h := sha256.New()
+	for _, index := range e.Indices() {
+		h.Write(e[index])
+	}
+	return h.Sum(nil)
+}
+
+// ParsePublicKey parses a PEM encoded attestation key
+func ParsePublicKey(data string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, fmt.Errorf("attestation key is not PEM encoded")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse attestation key: %w", err)
+	}
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported attestation key type %T", key)
+	}
+}
+
+// Verify checks that the quote was signed by the attestation key, is fresh
+// for nonce, and covers exactly the expected PCR values.
+func Verify(quote power.TPMQuote, key crypto.PublicKey, nonce []byte, expected ExpectedPCRs) error {
+	if err := verifySignature(quote, key); err != nil {
+		return err
+	}
+
+	info, err := parseQuote(quote.Message)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(info.extraData, nonce) {
+		return fmt.Errorf("quote nonce does not match")
+	}
+
+	indices := expected.Indices()
+	if !equalInts(info.pcrs, indices) {
+		return fmt.Errorf("quote covers PCRs %v, expected %v", info.pcrs, indices)
+	}
+
+	if !bytes.Equal(info.pcrDigest, expected.Digest()) {
+		return fmt.Errorf("PCR values do not match the expected measurements")
+	}
+	return nil
+}
+
+func verifySignature(quote power.TPMQuote, key crypto.PublicKey) error {
+	digest := sha256.Sum256(quote.Message)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], quote.Signature); err != nil {
+			if rsa.VerifyPSS(k, crypto.SHA256, digest[:], quote.Signature, nil) != nil {
+				return fmt.Errorf("quote signature is invalid")
+			}
+		}
+		return nil
+
+	case *ecdsa.PublicKey:
+		if ecdsa.VerifyASN1(k, digest[:], quote.Signature) {
+			return nil
+		}
+		// Older tpm2-tools write r and s concatenated
+		half := len(quote.Signature) / 2
+		r := new(big.Int).SetBytes(quote.Signature[:half])
+		s := new(big.Int).SetBytes(quote.Signature[half:])
+		if half > 0 && ecdsa.Verify(k, digest[:], r, s) {
+			return nil
+		}
+		return fmt.Errorf("quote signature is invalid")
+
+	default:
+		return fmt.Errorf("unsupported attestation key type %T", key)
+	}
+}
+
+type quoteInfo struct {
+	extraData []byte
+	pcrs      []int
+	pcrDigest []byte
+}
+
+// parseQuote decodes the parts of a TPMS_ATTEST quote needed for verification
+func parseQuote(message []byte) (*quoteInfo, error) {
+	r := bytes.NewReader(message)
+
+	var header struct {
+		Magic uint32
+		Type  uint16
+	}
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return nil, fmt.Errorf("quote is truncated")
+	}
+	if header.Magic != tpmGeneratedValue {
+		return nil, fmt.Errorf("quote was not generated by a TPM")
+	}
+	if header.Type != tpmSTAttestQuote {
+		return nil, fmt.Errorf("attestation is not a quote")
+	}
+
+	// qualifiedSigner
+	if _, err := readSized(r); err != nil {
+		return nil, err
+	}
+	extraData, err := readSized(r)
+	if err != nil {
+		return nil, err
+	}
+	// clockInfo (17 bytes) and firmwareVersion (8 bytes)
+	if _, err := r.Seek(17+8, io.SeekCurrent); err != nil {
+		return nil, fmt.Errorf("quote is truncated")
+	}
+
+	var count uint32
+	if err := binary.Read(r, binary.BigEndian, &count); err != nil {
+		return nil, fmt.Errorf("quote is truncated")
+	}
+	var pcrs []int
+	for i := uint32(0); i < count; i++ {
+		var selection struct {
+			Hash uint16
+			Size uint8
+		}
+		if err := binary.Read(r, binary.BigEndian, &selection); err != nil {
+			return nil, fmt.Errorf("quote is truncated")
+		}
+		bitmap := make([]byte, selection.Size)
+		if _, err := io.ReadFull(r, bitmap); err != nil {
+			return nil, fmt.Errorf("quote is truncated")
+		}
+		for byteIndex, b := range bitmap {
+			for bit := 0; bit < 8; bit++ {
+				if b&(1<<bit) == 0 {
+					continue
+				}
+				if selection.Hash != tpmAlgSHA256 {
+					return nil, fmt.Errorf("quote selects PCRs outside the SHA-256 bank")
+				}
+				pcrs = append(pcrs, byteIndex*8+bit)
+			}
+		}
+	}
+
+	pcrDigest, err := readSized(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &quoteInfo{extraData: extraData, pcrs: pcrs, pcrDigest: pcrDigest}, nil
+}
+
+// readSized reads a TPM2B structure, a 16 bit size followed by the data
+func readSized(r *bytes.Reader) ([]byte, error) {
+	var size uint16
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return nil, fmt.Errorf("quote is truncated")
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("quote is truncated")
+	}
+	return data, nil
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
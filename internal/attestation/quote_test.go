@@ -0,0 +1,233 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package attestation
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/Unbounder1/bare-metal-controller/internal/power"
+)
+
+// quote describes a TPMS_ATTEST structure to marshal
+type quote struct {
+	magic     uint32
+	kind      uint16
+	hash      uint16
+	nonce     []byte
+	pcrs      []int
+	pcrDigest []byte
+}
+
+// validQuote returns a quote over the expected PCRs for nonce
+func validQuote(nonce []byte, expected ExpectedPCRs) quote {
+	return quote{
+		magic:     tpmGeneratedValue,
+		kind:      tpmSTAttestQuote,
+		hash:      tpmAlgSHA256,
+		nonce:     nonce,
+		pcrs:      expected.Indices(),
+		pcrDigest: expected.Digest(),
+	}
+}
+
+// marshal encodes the quote the way a TPM does
+func (q quote) marshal() []byte {
+	var msg bytes.Buffer
+	write := func(v interface{}) { _ = binary.Write(&msg, binary.BigEndian, v) }
+
+	write(q.magic)
+	write(q.kind)
+	write(uint16(0)) // qualifiedSigner
+	write(uint16(len(q.nonce)))
+	msg.Write(q.nonce)
+	msg.Write(make([]byte, 17+8)) // clockInfo and firmwareVersion
+
+	bitmap := make([]byte, 3)
+	for _, pcr := range q.pcrs {
+		bitmap[pcr/8] |= 1 << (pcr % 8)
+	}
+	write(uint32(1))
+	write(q.hash)
+	write(uint8(len(bitmap)))
+	msg.Write(bitmap)
+	write(uint16(len(q.pcrDigest)))
+	msg.Write(q.pcrDigest)
+	return msg.Bytes()
+}
+
+func testExpectedPCRs(t *testing.T) ExpectedPCRs {
+	t.Helper()
+	expected, err := ParseExpectedPCRs(map[string]string{
+		"0": strings.Repeat("ab", sha256.Size),
+		"7": "0x" + strings.Repeat("CD", sha256.Size),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return expected
+}
+
+func TestParseQuote(t *testing.T) {
+	nonce := []byte("0123456789abcdef")
+	expected := testExpectedPCRs(t)
+	valid := validQuote(nonce, expected)
+
+	info, err := parseQuote(valid.marshal())
+	if err != nil {
+		t.Fatalf("parseQuote() error = %v", err)
+	}
+	if !bytes.Equal(info.extraData, nonce) || !equalInts(info.pcrs, []int{0, 7}) || !bytes.Equal(info.pcrDigest, expected.Digest()) {
+		t.Errorf("parseQuote() = %+v", info)
+	}
+
+	wrongMagic := valid
+	wrongMagic.magic = 0xdeadbeef
+	wrongType := valid
+	wrongType.kind = 0x8017 // TPM_ST_ATTEST_CERTIFY
+	sha1Bank := valid
+	sha1Bank.hash = 0x0004 // TPM_ALG_SHA1
+
+	tests := []struct {
+		name    string
+		message []byte
+		wantErr string
+	}{
+		{name: "empty", message: nil, wantErr: "truncated"},
+		{name: "header only", message: valid.marshal()[:6], wantErr: "truncated"},
+		{name: "truncated nonce", message: valid.marshal()[:16], wantErr: "truncated"},
+		{name: "truncated selection", message: valid.marshal()[:59], wantErr: "truncated"},
+		{name: "truncated digest", message: valid.marshal()[:len(valid.marshal())-1], wantErr: "truncated"},
+		{name: "wrong magic", message: wrongMagic.marshal(), wantErr: "not generated by a TPM"},
+		{name: "wrong type", message: wrongType.marshal(), wantErr: "not a quote"},
+		{name: "SHA-1 bank", message: sha1Bank.marshal(), wantErr: "outside the SHA-256 bank"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseQuote(tt.message)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseQuote() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	nonce := []byte("0123456789abcdef")
+	expected := testExpectedPCRs(t)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signASN1 := func(message []byte) []byte {
+		hash := sha256.Sum256(message)
+		signature, err := ecdsa.SignASN1(rand.Reader, ecKey, hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signature
+	}
+	// Older tpm2-tools write r and s concatenated
+	signPlainECDSA := func(message []byte) []byte {
+		hash := sha256.Sum256(message)
+		r, s, err := ecdsa.Sign(rand.Reader, ecKey, hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	signPKCS1 := func(message []byte) []byte {
+		hash := sha256.Sum256(message)
+		signature, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signature
+	}
+	signPSS := func(message []byte) []byte {
+		hash := sha256.Sum256(message)
+		signature, err := rsa.SignPSS(rand.Reader, rsaKey, crypto.SHA256, hash[:], nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signature
+	}
+
+	valid := validQuote(nonce, expected)
+	otherNonce := valid
+	otherNonce.nonce = []byte("fedcba9876543210")
+	otherDigest := valid
+	otherDigest.pcrDigest = bytes.Repeat([]byte{0xee}, sha256.Size)
+	otherPCRs := valid
+	otherPCRs.pcrs = []int{0}
+
+	tests := []struct {
+		name    string
+		quote   quote
+		sign    func([]byte) []byte
+		key     crypto.PublicKey
+		tamper  bool
+		wantErr string
+	}{
+		{name: "ECDSA", quote: valid, sign: signASN1, key: &ecKey.PublicKey},
+		{name: "ECDSA r and s", quote: valid, sign: signPlainECDSA, key: &ecKey.PublicKey},
+		{name: "RSA PKCS#1 v1.5", quote: valid, sign: signPKCS1, key: &rsaKey.PublicKey},
+		{name: "RSA PSS", quote: valid, sign: signPSS, key: &rsaKey.PublicKey},
+		{name: "ECDSA wrong key", quote: valid, sign: signASN1, key: &rsaKey.PublicKey, wantErr: "signature is invalid"},
+		{name: "RSA wrong key", quote: valid, sign: signPKCS1, key: &ecKey.PublicKey, wantErr: "signature is invalid"},
+		{name: "ECDSA tampered", quote: valid, sign: signASN1, key: &ecKey.PublicKey, tamper: true, wantErr: "signature is invalid"},
+		{name: "RSA tampered", quote: valid, sign: signPKCS1, key: &rsaKey.PublicKey, tamper: true, wantErr: "signature is invalid"},
+		{name: "nonce mismatch", quote: otherNonce, sign: signASN1, key: &ecKey.PublicKey, wantErr: "nonce does not match"},
+		{name: "PCR digest mismatch", quote: otherDigest, sign: signASN1, key: &ecKey.PublicKey, wantErr: "do not match the expected measurements"},
+		{name: "other PCRs", quote: otherPCRs, sign: signASN1, key: &ecKey.PublicKey, wantErr: "covers PCRs [0]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := tt.quote.marshal()
+			signature := tt.sign(message)
+			if tt.tamper {
+				// Change the PCR digest after it was signed
+				message = append([]byte(nil), message...)
+				message[len(message)-1] ^= 0xff
+			}
+			err := Verify(power.TPMQuote{Message: message, Signature: signature}, tt.key, nonce, expected)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Verify() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
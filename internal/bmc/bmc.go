@@ -0,0 +1,182 @@
+// Package bmc loads how a server's BMC is reached: its address with the
+// hostname resolved, the credentials from the Secrets the spec references,
+// and the TLS settings. The controller and the console proxy both use it, so
+// they reach a BMC the same way and apply the same tenant checks.
+package bmc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/power"
+	"github.com/Unbounder1/bare-metal-controller/internal/resolve"
+)
+
+// Endpoints loads BMC endpoints and the Secrets server specs reference
+type Endpoints struct {
+	// Reader reads the referenced Secrets
+	Reader client.Reader
+
+	// Resolver resolves hostnames in server addresses, nil to pass them on
+	Resolver *resolve.Resolver
+}
+
+// IPMITarget is the BMC of an IPMI server with its credentials
+type IPMITarget struct {
+	Address  string
+	Username string
+	Password string
+}
+
+// SecretValue reads a single key from the referenced Secret, which must be
+// in the namespace of the server's tenant
+func (e *Endpoints) SecretValue(ctx context.Context, server *baremetalcontrollerv1.Server, ref *baremetalcontrollerv1.SecretReference, key string) (string, error) {
+	if err := server.CheckSecretRef(ref); err != nil {
+		return "", InvalidSpec("%v", err)
+	}
+	secret := &corev1.Secret{}
+	err := e.Reader.Get(ctx, client.ObjectKey{
+		Name:      ref.Name,
+		Namespace: ref.Namespace,
+	}, secret)
+	if err != nil {
+		return "", WithReason(baremetalcontrollerv1.ReasonSecretMissing,
+			fmt.Errorf("failed to get secret %s/%s: %v", ref.Namespace, ref.Name, err))
+	}
+
+	value, ok := secret.Data[key]
+	if !ok {
+		return "", WithReason(baremetalcontrollerv1.ReasonSecretMissing,
+			fmt.Errorf("%s not found in secret %s/%s", key, ref.Namespace, ref.Name))
+	}
+	return string(value), nil
+}
+
+// IPMI validates the IPMI config, resolves the BMC address and loads the
+// credentials, from the credentials Secret if there is one
+func (e *Endpoints) IPMI(ctx context.Context, server *baremetalcontrollerv1.Server) (IPMITarget, error) {
+	ipmi := server.Spec.Control.IPMI
+	if ipmi == nil {
+		return IPMITarget{}, InvalidSpec("IPMI config is required")
+	}
+	if ipmi.Address == "" {
+		return IPMITarget{}, InvalidSpec("IPMI address is required")
+	}
+
+	target := IPMITarget{
+		Address:  e.ResolveAddress(ipmi.Address),
+		Username: ipmi.Username,
+		Password: ipmi.Password,
+	}
+	if ref := ipmi.CredentialsSecretRef; ref != nil {
+		usernameKey, passwordKey := ref.Keys()
+		var err error
+		if target.Username, err = e.SecretValue(ctx, server, &ref.SecretReference, usernameKey); err != nil {
+			return IPMITarget{}, err
+		}
+		if target.Password, err = e.SecretValue(ctx, server, &ref.SecretReference, passwordKey); err != nil {
+			return IPMITarget{}, err
+		}
+	}
+	return target, nil
+}
+
+// Redfish validates the Redfish config and loads its credentials
+func (e *Endpoints) Redfish(ctx context.Context, server *baremetalcontrollerv1.Server) (power.RedfishTarget, error) {
+	redfish := server.Spec.Control.Redfish
+	if redfish == nil {
+		return power.RedfishTarget{}, InvalidSpec("Redfish config is required")
+	}
+	if redfish.Address == "" {
+		return power.RedfishTarget{}, InvalidSpec("Redfish address is required")
+	}
+	if redfish.CredentialsSecretRef == nil {
+		return power.RedfishTarget{}, InvalidSpec("Redfish credentials secret reference is required")
+	}
+
+	username, err := e.SecretValue(ctx, server, redfish.CredentialsSecretRef, "username")
+	if err != nil {
+		return power.RedfishTarget{}, err
+	}
+	password, err := e.SecretValue(ctx, server, redfish.CredentialsSecretRef, "password")
+	if err != nil {
+		return power.RedfishTarget{}, err
+	}
+
+	tlsOptions, err := e.RedfishTLS(ctx, server, redfish.TLS)
+	if err != nil {
+		return power.RedfishTarget{}, err
+	}
+
+	return power.RedfishTarget{
+		Address:  redfish.Address,
+		Username: username,
+		Password: password,
+		SystemID: redfish.SystemID,
+		TLS:      tlsOptions,
+	}, nil
+}
+
+// RedfishTLS loads the CA bundle of the TLS config. Without a TLS config
+// the certificate isn't verified, as before the option existed.
+func (e *Endpoints) RedfishTLS(ctx context.Context, server *baremetalcontrollerv1.Server, spec *baremetalcontrollerv1.TLSSpecs) (power.RedfishTLS, error) {
+	if spec == nil {
+		return power.RedfishTLS{InsecureSkipVerify: true}, nil
+	}
+
+	tlsOptions := power.RedfishTLS{
+		ServerName:         spec.ServerName,
+		InsecureSkipVerify: spec.InsecureSkipVerify,
+	}
+	if spec.CASecretRef != nil && !spec.InsecureSkipVerify {
+		caBundle, err := e.SecretValue(ctx, server, spec.CASecretRef, "ca.crt")
+		if err != nil {
+			return power.RedfishTLS{}, err
+		}
+		tlsOptions.CABundle = []byte(caBundle)
+	}
+	return tlsOptions, nil
+}
+
+// ResolveAddress returns the address with its hostname replaced by the IP
+// address it currently resolves to. Addresses that are IPs or URLs, and
+// hostnames that can't be resolved, are returned as they are.
+func (e *Endpoints) ResolveAddress(address string) string {
+	if e.Resolver == nil || address == "" {
+		return address
+	}
+	if u, err := url.Parse(address); err == nil && u.Host != "" {
+		return address
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		host, port = address, ""
+	}
+	ip, err := e.Resolver.Resolve(context.Background(), host)
+	if err != nil {
+		log.Log.Error(err, "Failed to resolve server address", "address", address)
+		return address
+	}
+	if port != "" {
+		return net.JoinHostPort(ip, port)
+	}
+	return ip
+}
+
+// Host strips the scheme, path and port from a BMC address
+func Host(address string) string {
+	if u, err := url.Parse(address); err == nil && u.Host != "" {
+		return u.Hostname()
+	}
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		return host
+	}
+	return address
+}
@@ -0,0 +1,190 @@
+package bmc
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/resolve"
+)
+
+func testScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := baremetalcontrollerv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return scheme
+}
+
+func ipmiServer(namespace string, ipmi *baremetalcontrollerv1.IPMISpecs) *baremetalcontrollerv1.Server {
+	return &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-01", Namespace: namespace},
+		Spec: baremetalcontrollerv1.ServerSpec{
+			Type:    baremetalcontrollerv1.ControlTypeIPMI,
+			Control: baremetalcontrollerv1.ControlSpecs{IPMI: ipmi},
+		},
+	}
+}
+
+func TestIPMI(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "bmc", Namespace: "infra"},
+		Data: map[string][]byte{
+			"username": []byte("admin"),
+			"password": []byte("secret"),
+			"user":     []byte("operator"),
+		},
+	}
+	ref := func(usernameKey string) *baremetalcontrollerv1.CredentialsSecretReference {
+		return &baremetalcontrollerv1.CredentialsSecretReference{
+			SecretReference: baremetalcontrollerv1.SecretReference{Name: "bmc", Namespace: "infra"},
+			UsernameKey:     usernameKey,
+		}
+	}
+	resolver, err := resolve.New(resolve.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		namespace  string
+		ipmi       *baremetalcontrollerv1.IPMISpecs
+		want       IPMITarget
+		wantReason baremetalcontrollerv1.FailureReason
+	}{
+		{
+			name: "inline credentials",
+			ipmi: &baremetalcontrollerv1.IPMISpecs{Address: "10.0.1.11", Username: "root", Password: "calvin"},
+			want: IPMITarget{Address: "10.0.1.11", Username: "root", Password: "calvin"},
+		},
+		{
+			name: "secret with default keys",
+			ipmi: &baremetalcontrollerv1.IPMISpecs{Address: "10.0.1.11", CredentialsSecretRef: ref("")},
+			want: IPMITarget{Address: "10.0.1.11", Username: "admin", Password: "secret"},
+		},
+		{
+			// The secret wins over stale inline credentials
+			name: "secret with custom key",
+			ipmi: &baremetalcontrollerv1.IPMISpecs{Address: "10.0.1.11", Username: "root", CredentialsSecretRef: ref("user")},
+			want: IPMITarget{Address: "10.0.1.11", Username: "operator", Password: "secret"},
+		},
+		{
+			name: "hostname",
+			ipmi: &baremetalcontrollerv1.IPMISpecs{Address: "localhost:623", Username: "root", Password: "calvin"},
+			want: IPMITarget{Address: "127.0.0.1:623", Username: "root", Password: "calvin"},
+		},
+		{
+			name:       "missing key",
+			ipmi:       &baremetalcontrollerv1.IPMISpecs{Address: "10.0.1.11", CredentialsSecretRef: ref("login")},
+			wantReason: baremetalcontrollerv1.ReasonSecretMissing,
+		},
+		{
+			name:       "secret of another tenant",
+			namespace:  "team-a",
+			ipmi:       &baremetalcontrollerv1.IPMISpecs{Address: "10.0.1.11", CredentialsSecretRef: ref("")},
+			wantReason: baremetalcontrollerv1.ReasonSpecInvalid,
+		},
+		{
+			name:       "no address",
+			ipmi:       &baremetalcontrollerv1.IPMISpecs{CredentialsSecretRef: ref("")},
+			wantReason: baremetalcontrollerv1.ReasonSpecInvalid,
+		},
+		{
+			name:       "no IPMI config",
+			wantReason: baremetalcontrollerv1.ReasonSpecInvalid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Endpoints{
+				Reader:   fake.NewClientBuilder().WithScheme(testScheme(t)).WithObjects(secret).Build(),
+				Resolver: resolver,
+			}
+
+			got, err := e.IPMI(context.Background(), ipmiServer(tt.namespace, tt.ipmi))
+			if tt.wantReason != "" {
+				if err == nil {
+					t.Fatalf("IPMI() = %+v, want an error", got)
+				}
+				if reason, ok := Reason(err); !ok || reason != tt.wantReason {
+					t.Errorf("reason = %q, want %q", reason, tt.wantReason)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("IPMI() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IPMI() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedfishTLS(t *testing.T) {
+	ca := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "bmc-ca", Namespace: "infra"},
+		Data:       map[string][]byte{"ca.crt": []byte("-----BEGIN CERTIFICATE-----")},
+	}
+	caRef := &baremetalcontrollerv1.SecretReference{Name: "bmc-ca", Namespace: "infra"}
+	e := &Endpoints{Reader: fake.NewClientBuilder().WithScheme(testScheme(t)).WithObjects(ca).Build()}
+	server := ipmiServer("", nil)
+
+	tests := []struct {
+		name     string
+		spec     *baremetalcontrollerv1.TLSSpecs
+		insecure bool
+		wantCA   bool
+		wantErr  string
+	}{
+		{name: "no TLS config", insecure: true},
+		{name: "CA bundle", spec: &baremetalcontrollerv1.TLSSpecs{CASecretRef: caRef}, wantCA: true},
+		{name: "insecure ignores the CA", spec: &baremetalcontrollerv1.TLSSpecs{CASecretRef: caRef, InsecureSkipVerify: true}, insecure: true},
+		{name: "missing CA", spec: &baremetalcontrollerv1.TLSSpecs{CASecretRef: &baremetalcontrollerv1.SecretReference{Name: "other", Namespace: "infra"}}, wantErr: "infra/other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.RedfishTLS(context.Background(), server, tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("RedfishTLS() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RedfishTLS() error = %v", err)
+			}
+			if got.InsecureSkipVerify != tt.insecure || (len(got.CABundle) > 0) != tt.wantCA {
+				t.Errorf("RedfishTLS() = %+v", got)
+			}
+		})
+	}
+}
+
+func TestHost(t *testing.T) {
+	tests := map[string]string{
+		"https://192.168.1.20":         "192.168.1.20",
+		"https://192.168.1.20:8443/":   "192.168.1.20",
+		"https://bmc-01.example.com/x": "bmc-01.example.com",
+		"192.168.1.20:443":             "192.168.1.20",
+		"bmc-01":                       "bmc-01",
+		"https://[fd00::20]:443":       "fd00::20",
+	}
+	for address, want := range tests {
+		if got := Host(address); got != want {
+			t.Errorf("Host(%q) = %q, want %q", address, got, want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package bmc
+
+import (
+	"errors"
+	"fmt"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+// Error tags an error with the failure reason reported in the Server status
+type Error struct {
+	Reason baremetalcontrollerv1.FailureReason
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WithReason tags err with reason unless it already carries one, so the
+// most specific reason wins.
+func WithReason(reason baremetalcontrollerv1.FailureReason, err error) error {
+	if err == nil {
+		return nil
+	}
+	var tagged *Error
+	if errors.As(err, &tagged) {
+		return err
+	}
+	return &Error{Reason: reason, Err: err}
+}
+
+// InvalidSpec reports a configuration error in the Server spec
+func InvalidSpec(format string, args ...interface{}) error {
+	return &Error{Reason: baremetalcontrollerv1.ReasonSpecInvalid, Err: fmt.Errorf(format, args...)}
+}
+
+// Reason returns the failure reason err is tagged with, if any
+func Reason(err error) (baremetalcontrollerv1.FailureReason, bool) {
+	var tagged *Error
+	if errors.As(err, &tagged) {
+		return tagged.Reason, true
+	}
+	return "", false
+}
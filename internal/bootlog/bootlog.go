@@ -0,0 +1,122 @@
+// Package bootlog captures what servers log while they boot. Servers send
+// their kernel and early userspace messages to a syslog receiver, which
+// matches them to the Server by their source address and keeps the tail of
+// each server's current boot in memory. The console proxy serves the tail to
+// "kubectl baremetal bootlog", so a server that never joins the cluster can
+// be diagnosed without attaching to its console while it boots.
+package bootlog
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PathPrefix is the URL path boot logs are served under, followed by the
+// server name, or its namespace and name for namespaced Servers. Access is authorized as the non-resource URL with verb get.
+const PathPrefix = "/bootlog/"
+
+// Options contains configuration for the boot log receiver.
+type Options struct {
+	// Address is the UDP address syslog messages are received on (e.g.,
+	// ":514"), "0" to disable
+	Address string
+
+	// Lines is the number of lines kept of each server's boot
+	Lines int
+}
+
+// DefaultOptions returns the default boot log options.
+func DefaultOptions() Options {
+	return Options{
+		Address: "0",
+		Lines:   500,
+	}
+}
+
+// BindFlags binds the boot log options to command line flags.
+// The prefix can be used to namespace the flags (e.g., "bootlog-").
+func (o *Options) BindFlags(fs *flag.FlagSet, prefix string) {
+	fs.StringVar(&o.Address, prefix+"bind-address", o.Address,
+		"The UDP address the syslog receiver of boot logs binds to, e.g. :514. Use 0 to disable it.")
+	fs.IntVar(&o.Lines, prefix+"lines", o.Lines,
+		"Number of lines kept of the last boot of each server.")
+}
+
+// Validate validates the options.
+func (o *Options) Validate() error {
+	if !o.Enabled() {
+		return nil
+	}
+	if _, err := net.ResolveUDPAddr("udp", o.Address); err != nil {
+		return fmt.Errorf("invalid boot log receiver address %q: %w", o.Address, err)
+	}
+	if o.Lines <= 0 {
+		return fmt.Errorf("boot log lines must be positive")
+	}
+	return nil
+}
+
+// Enabled returns true if the receiver should be started.
+func (o *Options) Enabled() bool {
+	return o.Address != "" && o.Address != "0"
+}
+
+// facilityKernel is the syslog facility of kernel messages
+const facilityKernel = 0
+
+// Line is a message a server logged
+type Line struct {
+	// Received is when the controller received the message
+	Received time.Time
+	// Text is the message without its priority, e.g.
+	// "Oct 16 12:00:00 worker-01 kernel: Linux version 6.8.0"
+	Text string
+}
+
+// String formats the line for the plugin
+func (l Line) String() string {
+	return l.Received.UTC().Format(time.RFC3339) + " " + l.Text
+}
+
+// message is a syslog message of RFC 3164 or RFC 5424
+type message struct {
+	facility int
+	text     string
+}
+
+// parseMessage splits a syslog message into its facility and text. The
+// header after the priority is kept in the text, as servers format it
+// differently.
+func parseMessage(packet []byte) (message, error) {
+	s := string(packet)
+	if !strings.HasPrefix(s, "<") {
+		return message{}, errors.New("no priority")
+	}
+	end := strings.IndexByte(s, '>')
+	if end < 2 || end > 4 {
+		return message{}, errors.New("invalid priority")
+	}
+	priority, err := strconv.Atoi(s[1:end])
+	if err != nil || priority > 191 {
+		return message{}, fmt.Errorf("invalid priority %q", s[1:end])
+	}
+	text := s[end+1:]
+	// The version of RFC 5424 messages
+	text = strings.TrimPrefix(text, "1 ")
+	text = strings.TrimRight(text, "\r\n\x00")
+	if text == "" {
+		return message{}, errors.New("empty message")
+	}
+	return message{facility: priority / 8, text: text}, nil
+}
+
+// bootStarted reports whether the kernel logged the first message of a
+// boot, for servers that rebooted without the controller powering them on
+func (m message) bootStarted() bool {
+	return m.facility == facilityKernel && strings.Contains(m.text, "Linux version ")
+}
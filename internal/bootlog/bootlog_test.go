@@ -0,0 +1,77 @@
+package bootlog
+
+import (
+	"testing"
+)
+
+func TestParseMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		packet   string
+		facility int
+		text     string
+		boot     bool
+		wantErr  bool
+	}{
+		{
+			name:   "RFC 3164 kernel start",
+			packet: "<6>Oct 16 12:00:00 worker-01 kernel: Linux version 6.8.0-45-generic",
+			text:   "Oct 16 12:00:00 worker-01 kernel: Linux version 6.8.0-45-generic",
+			boot:   true,
+		},
+		{
+			name:     "RFC 5424",
+			packet:   "<30>1 2026-10-16T12:00:05Z worker-01 systemd 1 - - Started kubelet.service\n",
+			facility: 3,
+			text:     "2026-10-16T12:00:05Z worker-01 systemd 1 - - Started kubelet.service",
+		},
+		{
+			name:     "kernel version logged by a daemon",
+			packet:   "<30>Oct 16 12:00:05 worker-01 uname: Linux version 6.8.0",
+			facility: 3,
+			text:     "Oct 16 12:00:05 worker-01 uname: Linux version 6.8.0",
+		},
+		{name: "no priority", packet: "kernel: Linux version 6.8.0", wantErr: true},
+		{name: "unterminated priority", packet: "<6 kernel", wantErr: true},
+		{name: "priority out of range", packet: "<192>kernel", wantErr: true},
+		{name: "empty", packet: "<6>\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := parseMessage([]byte(tt.packet))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMessage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if m.facility != tt.facility || m.text != tt.text {
+				t.Errorf("parseMessage() = %+v, want facility %d and text %q", m, tt.facility, tt.text)
+			}
+			if m.bootStarted() != tt.boot {
+				t.Errorf("bootStarted() = %v, want %v", m.bootStarted(), tt.boot)
+			}
+		})
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		opts    Options
+		enabled bool
+		wantErr bool
+	}{
+		{opts: DefaultOptions()},
+		{opts: Options{Address: ":514", Lines: 500}, enabled: true},
+		{opts: Options{Address: ":514"}, enabled: true, wantErr: true},
+		{opts: Options{Address: "10.0.0.1:99999", Lines: 500}, enabled: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.Enabled(); got != tt.enabled {
+			t.Errorf("%+v Enabled() = %v, want %v", tt.opts, got, tt.enabled)
+		}
+		if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%+v Validate() error = %v, wantErr %v", tt.opts, err, tt.wantErr)
+		}
+	}
+}
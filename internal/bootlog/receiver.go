@@ -0,0 +1,199 @@
+package bootlog
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/Unbounder1/bare-metal-controller/internal/index"
+)
+
+// maxMessageSize is the largest datagram read, RFC 5424 receivers must take
+// messages of 2048 bytes
+const maxMessageSize = 8192
+
+// bootLog is the tail of a server's current boot
+type bootLog struct {
+	// kernelStarted is set once the kernel logged the start of the boot,
+	// so its next start is another boot
+	kernelStarted bool
+	lines         []Line
+}
+
+// Receiver implements manager.Runnable. It receives syslog messages, looks
+// up the server that sent each one by its source address, and keeps the
+// last lines of each server's current boot. It serves them over HTTP under
+// PathPrefix.
+type Receiver struct {
+	options Options
+	reader  client.Reader
+	log     logr.Logger
+	now     func() time.Time
+
+	mu   sync.Mutex
+	logs map[client.ObjectKey]*bootLog
+}
+
+// Ensure Receiver implements manager.Runnable
+var _ manager.Runnable = &Receiver{}
+
+// NewReceiver creates a new boot log receiver. Servers are looked up with
+// reader, which needs the index of server addresses.
+func NewReceiver(opts Options, reader client.Reader) *Receiver {
+	return &Receiver{
+		options: opts,
+		reader:  reader,
+		log:     ctrl.Log.WithName("bootlog"),
+		now:     time.Now,
+		logs:    map[client.ObjectKey]*bootLog{},
+	}
+}
+
+// NewBoot drops the lines of a server's previous boot, the controller calls
+// it when it powers the server on
+func (r *Receiver) NewBoot(server client.ObjectKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logs[server] = &bootLog{}
+}
+
+// Lines returns the last lines of a server's current boot, false if the
+// server logged nothing since the controller started
+func (r *Receiver) Lines(server client.ObjectKey) ([]Line, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	log, ok := r.logs[server]
+	if !ok {
+		return nil, false
+	}
+	return append([]Line(nil), log.lines...), true
+}
+
+// Forget drops the boot log of a server that no longer exists
+func (r *Receiver) Forget(server client.ObjectKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.logs, server)
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Every
+// replica keeps the logs sent to it.
+func (r *Receiver) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable and receives syslog messages until the
+// context is cancelled.
+func (r *Receiver) Start(ctx context.Context) error {
+	conn, err := net.ListenPacket("udp", r.options.Address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", r.options.Address, err)
+	}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("boot log receiver error: %w", err)
+		}
+		udp, ok := from.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+		r.handle(ctx, buf[:n], udp.IP)
+	}
+}
+
+// handle appends a message to the boot log of the server that sent it.
+// Invalid messages and messages from unknown addresses are dropped.
+func (r *Receiver) handle(ctx context.Context, packet []byte, from net.IP) {
+	m, err := parseMessage(packet)
+	if err != nil {
+		r.log.V(1).Info("Dropped invalid syslog message", "from", from.String(), "error", err.Error())
+		return
+	}
+
+	server, err := index.ServerByAddress(ctx, r.reader, from.String())
+	if err != nil {
+		r.log.Error(err, "Failed to look up server of syslog message", "from", from.String())
+		return
+	}
+	if server == nil {
+		r.log.V(1).Info("Dropped syslog message from an address of no server", "from", from.String())
+		return
+	}
+
+	r.Record(client.ObjectKeyFromObject(server), m.text, m.bootStarted())
+}
+
+// Record appends a line to the boot log of a server. kernelStart marks the
+// first line the kernel logs, which starts another boot if the kernel
+// already started since the last NewBoot.
+func (r *Receiver) Record(server client.ObjectKey, text string, kernelStart bool) {
+	now := r.now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	log := r.logs[server]
+	if log == nil || kernelStart && log.kernelStarted {
+		log = &bootLog{}
+		r.logs[server] = log
+	}
+	if kernelStart {
+		log.kernelStarted = true
+	}
+	line := Line{Received: now, Text: text}
+	if len(log.lines) < r.options.Lines {
+		log.lines = append(log.lines, line)
+	} else {
+		copy(log.lines, log.lines[1:])
+		log.lines[len(log.lines)-1] = line
+	}
+}
+
+// ServeHTTP serves the boot log of the server named in the path, see
+// index.ServerPath, as text. The tail query parameter limits it to the last
+// lines.
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	server, ok := index.ParseServerPath(strings.TrimPrefix(req.URL.Path, PathPrefix))
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	lines, ok := r.Lines(server)
+	if !ok {
+		http.Error(w, fmt.Sprintf("no boot log of server %s", index.ServerPath(server)), http.StatusNotFound)
+		return
+	}
+	if tail := req.URL.Query().Get("tail"); tail != "" {
+		n, err := strconv.Atoi(tail)
+		if err != nil || n < 0 {
+			http.Error(w, fmt.Sprintf("invalid tail %q", tail), http.StatusBadRequest)
+			return
+		}
+		if n < len(lines) {
+			lines = lines[len(lines)-n:]
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, line := range lines {
+		fmt.Fprintln(w, line.String())
+	}
+}
@@ -0,0 +1,137 @@
+package bootlog
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/index"
+)
+
+// worker01 is the server of newTestReceiver
+var worker01 = client.ObjectKey{Name: "worker-01"}
+
+// newTestReceiver returns a receiver for worker-01, which is at address
+func newTestReceiver(t *testing.T, opts Options, address string) *Receiver {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := baremetalcontrollerv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	builder := index.NewFakeClientBuilder(scheme).WithObjects(&baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-01"},
+		Spec: baremetalcontrollerv1.ServerSpec{
+			Type:    baremetalcontrollerv1.ControlTypeIPMI,
+			Control: baremetalcontrollerv1.ControlSpecs{IPMI: &baremetalcontrollerv1.IPMISpecs{Address: address}},
+		},
+	})
+	r := NewReceiver(opts, builder.Build())
+	r.now = func() time.Time { return time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC) }
+	return r
+}
+
+func texts(lines []Line) []string {
+	var texts []string
+	for _, l := range lines {
+		texts = append(texts, l.Text)
+	}
+	return texts
+}
+
+func TestReceiverHandle(t *testing.T) {
+	r := newTestReceiver(t, Options{Address: ":514", Lines: 3}, "10.0.1.11")
+	ctx := context.Background()
+	server := net.ParseIP("10.0.1.11")
+
+	if _, ok := r.Lines(worker01); ok {
+		t.Fatalf("Lines() found a log before the server logged")
+	}
+	r.handle(ctx, []byte("<6>kernel: Linux version 6.8.0"), server)
+	// Dropped: an unknown address and garbage
+	r.handle(ctx, []byte("<6>kernel: Linux version 6.8.0"), net.ParseIP("10.0.1.99"))
+	r.handle(ctx, []byte("garbage"), server)
+	for i := 1; i <= 3; i++ {
+		r.handle(ctx, []byte(fmt.Sprintf("<30>systemd: step %d", i)), server)
+	}
+
+	lines, ok := r.Lines(worker01)
+	if got := strings.Join(texts(lines), "|"); !ok || got != "systemd: step 1|systemd: step 2|systemd: step 3" {
+		t.Errorf("Lines() = %q, want the last 3 lines", got)
+	}
+
+	// A reboot the controller didn't start is detected by the kernel
+	r.handle(ctx, []byte("<6>kernel: Linux version 6.8.0"), server)
+	lines, _ = r.Lines(worker01)
+	if got := strings.Join(texts(lines), "|"); got != "kernel: Linux version 6.8.0" {
+		t.Errorf("Lines() after reboot = %q, want only the new boot", got)
+	}
+
+	// Powering on starts a new boot, whose firmware lines the kernel keeps
+	r.NewBoot(worker01)
+	r.handle(ctx, []byte("<14>ipxe: DHCP ok"), server)
+	r.handle(ctx, []byte("<6>kernel: Linux version 6.8.0"), server)
+	lines, _ = r.Lines(worker01)
+	if got := strings.Join(texts(lines), "|"); got != "ipxe: DHCP ok|kernel: Linux version 6.8.0" {
+		t.Errorf("Lines() after power on = %q, want the firmware and kernel lines", got)
+	}
+
+	r.Forget(worker01)
+	if _, ok := r.Lines(worker01); ok {
+		t.Errorf("Lines() found the log of a forgotten server")
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	r := newTestReceiver(t, Options{Address: ":514", Lines: 10}, "10.0.1.11")
+	server := net.ParseIP("10.0.1.11")
+	r.handle(context.Background(), []byte("<6>kernel: Linux version 6.8.0"), server)
+	r.handle(context.Background(), []byte("<30>systemd: Started kubelet.service"), server)
+
+	tests := []struct {
+		name string
+		path string
+		want int
+		body string
+	}{
+		{
+			name: "log",
+			path: PathPrefix + "worker-01",
+			want: http.StatusOK,
+			body: "2026-10-16T12:00:00Z kernel: Linux version 6.8.0\n2026-10-16T12:00:00Z systemd: Started kubelet.service\n",
+		},
+		{
+			name: "tail",
+			path: PathPrefix + "worker-01?tail=1",
+			want: http.StatusOK,
+			body: "2026-10-16T12:00:00Z systemd: Started kubelet.service\n",
+		},
+		{name: "invalid tail", path: PathPrefix + "worker-01?tail=-1", want: http.StatusBadRequest},
+		{name: "no server", path: PathPrefix, want: http.StatusNotFound},
+		{name: "nested path", path: PathPrefix + "default/worker-01/extra", want: http.StatusNotFound},
+		// worker-01 is cluster-scoped
+		{name: "other namespace", path: PathPrefix + "default/worker-01", want: http.StatusNotFound},
+		{name: "no log", path: PathPrefix + "worker-02", want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if recorder.Code != tt.want {
+				t.Errorf("ServeHTTP() status = %d, want %d", recorder.Code, tt.want)
+			}
+			if tt.body != "" && recorder.Body.String() != tt.body {
+				t.Errorf("ServeHTTP() body = %q, want %q", recorder.Body.String(), tt.body)
+			}
+		})
+	}
+}
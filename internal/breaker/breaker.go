@@ -0,0 +1,142 @@
+// Package breaker implements a cluster-wide circuit breaker for power
+// actions. While its ConfigMap marks power actions as halted, controllers
+// keep observing servers but leave their hardware alone, e.g. while an
+// incident is investigated.
+package breaker
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Keys of the breaker ConfigMap
+const (
+	// HaltedKey halts power actions while "true"
+	HaltedKey = "halted"
+	// ReasonKey says why power actions were halted
+	ReasonKey = "reason"
+)
+
+// Options contains configuration for the circuit breaker.
+type Options struct {
+	// ConfigMap is the namespace/name of the breaker ConfigMap. Empty
+	// disables the breaker.
+	ConfigMap string
+}
+
+// DefaultOptions returns the default breaker options, which use a ConfigMap
+// in the controller's namespace.
+func DefaultOptions() Options {
+	return Options{
+		ConfigMap: "bare-metal-controller-system/power-circuit-breaker",
+	}
+}
+
+// BindFlags binds the breaker options to command line flags.
+// The prefix can be used to namespace the flags (e.g., "breaker-").
+func (o *Options) BindFlags(fs *flag.FlagSet, prefix string) {
+	fs.StringVar(&o.ConfigMap, prefix+"configmap", o.ConfigMap,
+		`Namespace/name of the ConfigMap whose "halted" key halts all power actions while "true". Empty disables the circuit breaker.`)
+}
+
+// Validate validates the options.
+func (o *Options) Validate() error {
+	if !o.Enabled() {
+		return nil
+	}
+	_, err := ParseKey(o.ConfigMap)
+	return err
+}
+
+// Enabled returns true if the breaker is configured.
+func (o *Options) Enabled() bool {
+	return o.ConfigMap != ""
+}
+
+// ParseKey parses the namespace/name of a breaker ConfigMap
+func ParseKey(value string) (types.NamespacedName, error) {
+	namespace, name, ok := strings.Cut(value, "/")
+	if !ok || namespace == "" || name == "" {
+		return types.NamespacedName{}, fmt.Errorf("invalid breaker ConfigMap %q, expected namespace/name", value)
+	}
+	return types.NamespacedName{Namespace: namespace, Name: name}, nil
+}
+
+// Breaker reads the breaker ConfigMap. It reads it from the API server for
+// every check, so halting takes effect for the next power action.
+type Breaker struct {
+	reader client.Reader
+	key    types.NamespacedName
+}
+
+// New returns a breaker reading its ConfigMap with the reader, or nil if the
+// breaker is disabled.
+func New(reader client.Reader, opts Options) (*Breaker, error) {
+	if !opts.Enabled() {
+		return nil, nil
+	}
+	key, err := ParseKey(opts.ConfigMap)
+	if err != nil {
+		return nil, err
+	}
+	return &Breaker{reader: reader, key: key}, nil
+}
+
+// Halted reports whether power actions are halted, and why. A nil breaker or
+// a missing ConfigMap never halts them. If the ConfigMap can't be read, they
+// are halted along with the error, since the breaker may be open.
+func (b *Breaker) Halted(ctx context.Context) (bool, string, error) {
+	if b == nil {
+		return false, "", nil
+	}
+	var cm corev1.ConfigMap
+	if err := b.reader.Get(ctx, b.key, &cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, "", nil
+		}
+		return true, "", fmt.Errorf("unable to read circuit breaker %s: %w", b.key, err)
+	}
+	halted, _ := strconv.ParseBool(cm.Data[HaltedKey])
+	if !halted {
+		return false, "", nil
+	}
+	reason := cm.Data[ReasonKey]
+	if reason == "" {
+		reason = fmt.Sprintf("circuit breaker %s is open", b.key)
+	}
+	return true, reason, nil
+}
+
+// Set halts or resumes power actions by writing the ConfigMap, creating it if
+// needed.
+func Set(ctx context.Context, c client.Client, key types.NamespacedName, halted bool, reason string) error {
+	var cm corev1.ConfigMap
+	err := c.Get(ctx, key, &cm)
+	if apierrors.IsNotFound(err) {
+		cm = corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+			Data:       map[string]string{HaltedKey: strconv.FormatBool(halted), ReasonKey: reason},
+		}
+		return c.Create(ctx, &cm)
+	}
+	if err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(cm.DeepCopy())
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[HaltedKey] = strconv.FormatBool(halted)
+	cm.Data[ReasonKey] = reason
+	return c.Patch(ctx, &cm, patch)
+}
@@ -0,0 +1,144 @@
+// Package budget enforces PowerBudgets, which limit how many servers on the
+// same rack or circuit are powered on at once, or how much they draw.
+//
+// Servers that are on, booting or shutting down count against a budget.
+// Servers requested on but held off wait in name order, so the budget is
+// handed to the first of them once a server powers off.
+package budget
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/listing"
+)
+
+// ExceededError is returned when powering on a server would exceed a
+// PowerBudget
+type ExceededError struct {
+	Budget  string
+	Message string
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("power budget %s exceeded: %s", e.Budget, e.Message)
+}
+
+// Check returns an *ExceededError if powering on server would exceed a
+// PowerBudget that selects it. Servers in pending are being powered on by
+// the caller and count as powered on.
+func Check(ctx context.Context, reader client.Reader, server *baremetalcontrollerv1.Server, pending map[client.ObjectKey]bool) error {
+	var budgets baremetalcontrollerv1.PowerBudgetList
+	if err := reader.List(ctx, &budgets); err != nil {
+		return fmt.Errorf("failed to list power budgets: %w", err)
+	}
+
+	for i := range budgets.Items {
+		budget := &budgets.Items[i]
+		selector, err := metav1.LabelSelectorAsSelector(&budget.Spec.Selector)
+		if err != nil {
+			return fmt.Errorf("invalid selector in power budget %s: %w", budget.Name, err)
+		}
+		if !selector.Matches(labels.Set(server.Labels)) {
+			continue
+		}
+
+		poweredOn, watts := int32(1), Watts(budget, server)
+		err = listing.Servers(ctx, reader, 0, func(other *baremetalcontrollerv1.Server) error {
+			if client.ObjectKeyFromObject(other) != client.ObjectKeyFromObject(server) && counts(other, server, pending) {
+				poweredOn++
+				watts += Watts(budget, other)
+			}
+			return nil
+		}, client.MatchingLabelsSelector{Selector: selector})
+		if err != nil {
+			return err
+		}
+
+		if max := budget.Spec.MaxPoweredOn; max != nil && poweredOn > *max {
+			return &ExceededError{
+				Budget:  budget.Name,
+				Message: fmt.Sprintf("%d servers would be powered on, the limit is %d", poweredOn, *max),
+			}
+		}
+		if max := budget.Spec.MaxWatts; max != nil && watts > *max {
+			return &ExceededError{
+				Budget:  budget.Name,
+				Message: fmt.Sprintf("servers would draw %dW, the limit is %dW", watts, *max),
+			}
+		}
+	}
+	return nil
+}
+
+// Usage returns the servers a budget selects that are powered on, their
+// estimated draw and the servers waiting to be powered on
+func Usage(ctx context.Context, reader client.Reader, budget *baremetalcontrollerv1.PowerBudget) (baremetalcontrollerv1.PowerBudgetStatus, error) {
+	var status baremetalcontrollerv1.PowerBudgetStatus
+	selector, err := metav1.LabelSelectorAsSelector(&budget.Spec.Selector)
+	if err != nil {
+		return status, fmt.Errorf("invalid selector: %w", err)
+	}
+
+	err = listing.Servers(ctx, reader, 0, func(server *baremetalcontrollerv1.Server) error {
+		switch {
+		case Powered(server):
+			status.PoweredOn++
+			status.Watts += Watts(budget, server)
+		case requested(server):
+			status.Waiting = append(status.Waiting, server.Name)
+		}
+		return nil
+	}, client.MatchingLabelsSelector{Selector: selector})
+	sort.Strings(status.Waiting)
+	return status, err
+}
+
+// Powered returns true for servers that draw power: on, booting, rebooting,
+// shutting down, or with a power action in flight
+func Powered(server *baremetalcontrollerv1.Server) bool {
+	switch server.Status.Status {
+	case baremetalcontrollerv1.StatusActive, baremetalcontrollerv1.StatusPending, baremetalcontrollerv1.StatusDraining,
+		baremetalcontrollerv1.StatusRebooting:
+		return true
+	}
+	return meta.IsStatusConditionTrue(server.Status.Conditions, baremetalcontrollerv1.ConditionOperationInProgress)
+}
+
+// Watts returns the draw assumed for a server while it's on: its power cap,
+// or the budget's serverWatts without one
+func Watts(budget *baremetalcontrollerv1.PowerBudget, server *baremetalcontrollerv1.Server) int32 {
+	if server.Spec.PowerCapWatts != nil {
+		return *server.Spec.PowerCapWatts
+	}
+	if budget.Spec.ServerWatts != nil {
+		return *budget.Spec.ServerWatts
+	}
+	return 0
+}
+
+// requested returns true for servers that should be on but aren't yet
+func requested(server *baremetalcontrollerv1.Server) bool {
+	return server.Spec.PowerState.Settled() == baremetalcontrollerv1.PowerStateOn &&
+		server.Status.Status != baremetalcontrollerv1.StatusFailed && !Powered(server)
+}
+
+// counts returns true if other counts against a budget when powering on
+// server. Waiting servers only count ahead of servers that wait themselves
+// and come later by name.
+func counts(other *baremetalcontrollerv1.Server, server *baremetalcontrollerv1.Server, pending map[client.ObjectKey]bool) bool {
+	if Powered(other) || pending[client.ObjectKeyFromObject(other)] {
+		return true
+	}
+	if !requested(other) {
+		return false
+	}
+	return !requested(server) || other.Name < server.Name
+}
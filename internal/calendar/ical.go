@@ -0,0 +1,337 @@
+// Package calendar reads iCalendar (RFC 5545) feeds into the events that
+// open maintenance windows, hibernate pools or freeze changes. Only what
+// change-freeze and holiday calendars use is supported: single events,
+// all-day events, time zones and simple recurrence rules.
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event is a single occurrence of a calendar event
+type Event struct {
+	Summary string
+	Start   time.Time
+	End     time.Time
+}
+
+// vevent is an event of the feed before its recurrences are expanded
+type vevent struct {
+	summary   string
+	start     time.Time
+	end       time.Time
+	duration  time.Duration
+	allDay    bool
+	rrule     string
+	exdates   map[int64]bool
+	cancelled bool
+}
+
+// Parse reads the events of a feed and returns the occurrences that overlap
+// [from, to), sorted by start. Times without a time zone and all-day events
+// are in loc. Events whose recurrence rules can't be expanded are skipped
+// and counted.
+func Parse(r io.Reader, loc *time.Location, from, to time.Time) ([]Event, int, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var events []Event
+	skipped := 0
+	var current *vevent
+	// nested counts the components inside the event, such as alarms,
+	// whose properties don't belong to it
+	nested := 0
+	for _, line := range lines {
+		name, params, value := splitLine(line)
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			current = &vevent{exdates: map[int64]bool{}}
+		case current == nil:
+			continue
+		case name == "BEGIN":
+			nested++
+		case name == "END" && nested > 0:
+			nested--
+		case nested > 0:
+			continue
+		case name == "END" && value == "VEVENT":
+			occurrences, err := current.expand(from, to)
+			if err != nil {
+				skipped++
+			} else if !current.cancelled {
+				events = append(events, occurrences...)
+			}
+			current = nil
+		case name == "SUMMARY":
+			current.summary = unescape(value)
+		case name == "STATUS":
+			current.cancelled = strings.EqualFold(value, "CANCELLED")
+		case name == "RRULE":
+			current.rrule = value
+		case name == "DTSTART":
+			current.start, current.allDay, err = parseTime(value, params, loc)
+			if err != nil {
+				return nil, 0, fmt.Errorf("invalid DTSTART %q: %w", value, err)
+			}
+		case name == "DTEND":
+			current.end, _, err = parseTime(value, params, loc)
+			if err != nil {
+				return nil, 0, fmt.Errorf("invalid DTEND %q: %w", value, err)
+			}
+		case name == "DURATION":
+			current.duration, err = parseDuration(value)
+			if err != nil {
+				return nil, 0, fmt.Errorf("invalid DURATION %q: %w", value, err)
+			}
+		case name == "EXDATE":
+			for _, v := range strings.Split(value, ",") {
+				at, _, err := parseTime(v, params, loc)
+				if err != nil {
+					return nil, 0, fmt.Errorf("invalid EXDATE %q: %w", v, err)
+				}
+				current.exdates[at.Unix()] = true
+			}
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+	return events, skipped, nil
+}
+
+// Dates returns an all-day event for each YYYY-MM-DD date in loc that
+// overlaps [from, to)
+func Dates(dates []string, summary string, loc *time.Location, from, to time.Time) ([]Event, error) {
+	var events []Event
+	for _, date := range dates {
+		day, err := time.ParseInLocation("2006-01-02", date, loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q: %w", date, err)
+		}
+		event := Event{Summary: summary, Start: day, End: day.AddDate(0, 0, 1)}
+		if event.End.After(from) && event.Start.Before(to) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// expand returns the occurrences of the event that overlap [from, to)
+func (e *vevent) expand(from, to time.Time) ([]Event, error) {
+	if e.start.IsZero() {
+		return nil, fmt.Errorf("event without DTSTART")
+	}
+	length := e.duration
+	switch {
+	case length > 0:
+	case !e.end.IsZero():
+		length = e.end.Sub(e.start)
+	case e.allDay:
+		length = 24 * time.Hour
+	}
+	if length <= 0 {
+		return nil, nil
+	}
+
+	occurrence := func(start time.Time) Event {
+		return Event{Summary: e.summary, Start: start, End: start.Add(length)}
+	}
+	overlaps := func(start time.Time) bool {
+		return start.Add(length).After(from) && start.Before(to) && !e.exdates[start.Unix()]
+	}
+
+	if e.rrule == "" {
+		if !overlaps(e.start) {
+			return nil, nil
+		}
+		return []Event{occurrence(e.start)}, nil
+	}
+
+	rule, err := parseRule(e.rrule, e.start.Location())
+	if err != nil {
+		return nil, err
+	}
+	var events []Event
+	for n := 0; rule.count == 0 || n < rule.count; n++ {
+		start := rule.nth(e.start, n)
+		if !start.Before(to) || (!rule.until.IsZero() && start.After(rule.until)) {
+			break
+		}
+		if overlaps(start) {
+			events = append(events, occurrence(start))
+		}
+	}
+	return events, nil
+}
+
+// rule is a recurrence rule with a frequency, an interval and optionally a
+// count or an end. Rules with BYDAY, BYMONTH and other parts are rejected
+// rather than expanded wrongly.
+type rule struct {
+	freq     string
+	interval int
+	count    int
+	until    time.Time
+}
+
+func parseRule(value string, loc *time.Location) (*rule, error) {
+	r := &rule{interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		key, v, _ := strings.Cut(part, "=")
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.freq = strings.ToUpper(v)
+		case "INTERVAL":
+			r.interval, err = strconv.Atoi(v)
+		case "COUNT":
+			r.count, err = strconv.Atoi(v)
+		case "UNTIL":
+			r.until, _, err = parseTime(v, nil, loc)
+		case "WKST":
+			// Only matters with BYDAY
+		default:
+			return nil, fmt.Errorf("unsupported recurrence rule part %s", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid recurrence rule part %s: %w", part, err)
+		}
+	}
+	switch r.freq {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	default:
+		return nil, fmt.Errorf("unsupported recurrence frequency %q", r.freq)
+	}
+	if r.interval < 1 {
+		return nil, fmt.Errorf("invalid recurrence interval %d", r.interval)
+	}
+	return r, nil
+}
+
+// nth returns the start of the nth occurrence, keeping the wall clock time
+// across daylight saving changes
+func (r *rule) nth(start time.Time, n int) time.Time {
+	step := n * r.interval
+	switch r.freq {
+	case "DAILY":
+		return start.AddDate(0, 0, step)
+	case "WEEKLY":
+		return start.AddDate(0, 0, 7*step)
+	case "MONTHLY":
+		return start.AddDate(0, step, 0)
+	default:
+		return start.AddDate(step, 0, 0)
+	}
+}
+
+// unfold joins continuation lines, which start with a space or tab
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %w", err)
+	}
+	if len(lines) == 0 || lines[0] != "BEGIN:VCALENDAR" {
+		return nil, fmt.Errorf("not an iCalendar feed")
+	}
+	return lines, nil
+}
+
+// splitLine splits a content line into its name, parameters and value
+func splitLine(line string) (string, map[string]string, string) {
+	head, value, _ := strings.Cut(line, ":")
+	parts := strings.Split(head, ";")
+	params := map[string]string{}
+	for _, param := range parts[1:] {
+		key, v, _ := strings.Cut(param, "=")
+		params[strings.ToUpper(key)] = strings.Trim(v, `"`)
+	}
+	return strings.ToUpper(parts[0]), params, value
+}
+
+// parseTime parses a DATE or DATE-TIME value. UTC times end in Z, others
+// are in the TZID parameter's zone, or loc if it has none or is unknown.
+func parseTime(value string, params map[string]string, loc *time.Location) (time.Time, bool, error) {
+	if params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+	if tzid := params["TZID"]; tzid != "" {
+		if zone, err := time.LoadLocation(tzid); err == nil {
+			loc = zone
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+// parseDuration parses an RFC 5545 duration such as P1D, PT4H30M or P2W
+func parseDuration(value string) (time.Duration, error) {
+	value = strings.TrimPrefix(value, "+")
+	if !strings.HasPrefix(value, "P") {
+		return 0, fmt.Errorf("missing P")
+	}
+	var total time.Duration
+	inTime := false
+	number := ""
+	for _, c := range value[1:] {
+		switch {
+		case c == 'T':
+			inTime = true
+			continue
+		case c >= '0' && c <= '9':
+			number += string(c)
+			continue
+		}
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return 0, fmt.Errorf("missing number before %c", c)
+		}
+		number = ""
+		switch {
+		case c == 'W' && !inTime:
+			total += time.Duration(n) * 7 * 24 * time.Hour
+		case c == 'D' && !inTime:
+			total += time.Duration(n) * 24 * time.Hour
+		case c == 'H' && inTime:
+			total += time.Duration(n) * time.Hour
+		case c == 'M' && inTime:
+			total += time.Duration(n) * time.Minute
+		case c == 'S' && inTime:
+			total += time.Duration(n) * time.Second
+		default:
+			return 0, fmt.Errorf("unexpected %c", c)
+		}
+	}
+	if number != "" {
+		return 0, fmt.Errorf("missing unit after %s", number)
+	}
+	return total, nil
+}
+
+// unescape reverses the escaping of TEXT values
+func unescape(value string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
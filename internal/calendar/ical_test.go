@@ -0,0 +1,302 @@
+package calendar
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+// feed wraps events into a calendar with CRLF line endings
+func feed(lines ...string) string {
+	return strings.Join(append(append([]string{"BEGIN:VCALENDAR", "VERSION:2.0"}, lines...), "END:VCALENDAR"), "\r\n") + "\r\n"
+}
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestUnfold(t *testing.T) {
+	lines, err := unfold(strings.NewReader("BEGIN:VCALENDAR\r\nSUMMARY:Change\r\n  freeze\r\n\tfor Q4\r\n\r\nEND:VCALENDAR\r\n"))
+	if err != nil {
+		t.Fatalf("unfold() error = %v", err)
+	}
+	// Only the first space or tab of a continuation is dropped
+	want := []string{"BEGIN:VCALENDAR", "SUMMARY:Change freezefor Q4", "END:VCALENDAR"}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("unfold() = %q, want %q", lines, want)
+	}
+
+	for _, input := range []string{"", "<html></html>\n", " BEGIN:VCALENDAR\n"} {
+		if _, err := unfold(strings.NewReader(input)); err == nil {
+			t.Errorf("unfold(%q) succeeded", input)
+		}
+	}
+}
+
+func TestSplitLine(t *testing.T) {
+	name, params, value := splitLine(`dtstart;TZID="Europe/Berlin";value=DATE-TIME:20240101T090000`)
+	if name != "DTSTART" || value != "20240101T090000" {
+		t.Errorf("splitLine() = %s, %s", name, value)
+	}
+	if want := map[string]string{"TZID": "Europe/Berlin", "VALUE": "DATE-TIME"}; !reflect.DeepEqual(params, want) {
+		t.Errorf("params = %v, want %v", params, want)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	newYork := mustLoad(t, "America/New_York")
+	tests := []struct {
+		name       string
+		value      string
+		params     map[string]string
+		want       time.Time
+		wantAllDay bool
+		wantErr    bool
+	}{
+		{name: "UTC", value: "20240101T090000Z", want: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)},
+		{name: "floating", value: "20240101T090000", want: time.Date(2024, 1, 1, 9, 0, 0, 0, berlin)},
+		{name: "TZID", value: "20240101T090000", params: map[string]string{"TZID": "America/New_York"}, want: time.Date(2024, 1, 1, 9, 0, 0, 0, newYork)},
+		// Unknown zones, such as Outlook's Windows names, fall back to loc
+		{name: "unknown TZID", value: "20240101T090000", params: map[string]string{"TZID": "W. Europe Standard Time"}, want: time.Date(2024, 1, 1, 9, 0, 0, 0, berlin)},
+		{name: "VALUE=DATE", value: "20241224", params: map[string]string{"VALUE": "DATE"}, want: time.Date(2024, 12, 24, 0, 0, 0, 0, berlin), wantAllDay: true},
+		{name: "date without VALUE", value: "20241224", want: time.Date(2024, 12, 24, 0, 0, 0, 0, berlin), wantAllDay: true},
+		// All-day events are in loc, whatever their TZID
+		{name: "date with TZID", value: "20241224", params: map[string]string{"VALUE": "DATE", "TZID": "America/New_York"}, want: time.Date(2024, 12, 24, 0, 0, 0, 0, berlin), wantAllDay: true},
+		{name: "invalid", value: "2024-01-01T09:00:00", wantErr: true},
+		{name: "invalid date", value: "20241324", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, allDay, err := parseTime(tt.value, tt.params, berlin)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !got.Equal(tt.want) || got.Location().String() != tt.want.Location().String() || allDay != tt.wantAllDay {
+				t.Errorf("parseTime() = %v (%s), %t, want %v (%s), %t", got, got.Location(), allDay, tt.want, tt.want.Location(), tt.wantAllDay)
+			}
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "P1D", want: 24 * time.Hour},
+		{value: "P2W", want: 14 * 24 * time.Hour},
+		{value: "PT4H30M", want: 4*time.Hour + 30*time.Minute},
+		{value: "P1DT12H", want: 36 * time.Hour},
+		{value: "PT90S", want: 90 * time.Second},
+		{value: "+PT15M", want: 15 * time.Minute},
+		{value: "P0D", want: 0},
+		{value: "P", want: 0},
+		{value: "1D", wantErr: true},
+		{value: "-P1D", wantErr: true},
+		{value: "P1H", wantErr: true},
+		{value: "PT1D", wantErr: true},
+		{value: "P1M", wantErr: true},
+		{value: "PT1X", wantErr: true},
+		{value: "PTH", wantErr: true},
+		{value: "PT10", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseDuration(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseDuration(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseDuration(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	tests := []struct {
+		value   string
+		want    *rule
+		wantErr bool
+	}{
+		{value: "FREQ=WEEKLY", want: &rule{freq: "WEEKLY", interval: 1}},
+		{value: "freq=daily;interval=2;count=5;wkst=MO", want: &rule{freq: "DAILY", interval: 2, count: 5}},
+		{value: "FREQ=MONTHLY;UNTIL=20241231T230000Z", want: &rule{freq: "MONTHLY", interval: 1, until: time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC)}},
+		{value: "FREQ=YEARLY;UNTIL=20301224", want: &rule{freq: "YEARLY", interval: 1, until: time.Date(2030, 12, 24, 0, 0, 0, 0, berlin)}},
+		// Expanding these as plain weekly rules would open wrong windows
+		{value: "FREQ=WEEKLY;BYDAY=MO,WE", wantErr: true},
+		{value: "FREQ=MONTHLY;BYMONTHDAY=1", wantErr: true},
+		{value: "FREQ=HOURLY", wantErr: true},
+		{value: "INTERVAL=2", wantErr: true},
+		{value: "FREQ=DAILY;INTERVAL=0", wantErr: true},
+		{value: "FREQ=DAILY;COUNT=many", wantErr: true},
+		{value: "FREQ=DAILY;UNTIL=tomorrow", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseRule(tt.value, berlin)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseRule(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && (got.freq != tt.want.freq || got.interval != tt.want.interval || got.count != tt.want.count || !got.until.Equal(tt.want.until)) {
+			t.Errorf("parseRule(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRuleNth(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+	// Daylight saving time starts on 2024-03-10 in New York
+	start := time.Date(2024, 3, 8, 22, 0, 0, 0, newYork)
+	tests := []struct {
+		rule rule
+		n    int
+		want time.Time
+	}{
+		{rule: rule{freq: "DAILY", interval: 1}, n: 0, want: start},
+		{rule: rule{freq: "DAILY", interval: 1}, n: 3, want: time.Date(2024, 3, 11, 22, 0, 0, 0, newYork)},
+		{rule: rule{freq: "WEEKLY", interval: 2}, n: 1, want: time.Date(2024, 3, 22, 22, 0, 0, 0, newYork)},
+		{rule: rule{freq: "MONTHLY", interval: 1}, n: 8, want: time.Date(2024, 11, 8, 22, 0, 0, 0, newYork)},
+		{rule: rule{freq: "YEARLY", interval: 1}, n: 1, want: time.Date(2025, 3, 8, 22, 0, 0, 0, newYork)},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.nth(start, tt.n); !got.Equal(tt.want) {
+			t.Errorf("%s nth(%d) = %v, want %v", tt.rule.freq, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	newYork := mustLoad(t, "America/New_York")
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		events      []string
+		want        []Event
+		wantSkipped int
+		wantErr     bool
+	}{
+		{
+			name: "single event",
+			events: []string{
+				"BEGIN:VEVENT", "SUMMARY:Rack move\\, row 3", "DTSTART:20240305T080000Z", "DTEND:20240305T120000Z",
+				"BEGIN:VALARM", "SUMMARY:Reminder", "TRIGGER:-PT15M", "END:VALARM", "END:VEVENT",
+			},
+			want: []Event{{Summary: "Rack move, row 3", Start: time.Date(2024, 3, 5, 8, 0, 0, 0, time.UTC), End: time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC)}},
+		},
+		{
+			name:   "all-day event",
+			events: []string{"BEGIN:VEVENT", "SUMMARY:Holiday", "DTSTART;VALUE=DATE:20240329", "END:VEVENT"},
+			want:   []Event{{Summary: "Holiday", Start: time.Date(2024, 3, 29, 0, 0, 0, 0, berlin), End: time.Date(2024, 3, 30, 0, 0, 0, 0, berlin)}},
+		},
+		{
+			name:   "duration",
+			events: []string{"BEGIN:VEVENT", "SUMMARY:Patching", "DTSTART;TZID=Europe/Berlin:20240312T220000", "DURATION:PT4H", "END:VEVENT"},
+			want:   []Event{{Summary: "Patching", Start: time.Date(2024, 3, 12, 22, 0, 0, 0, berlin), End: time.Date(2024, 3, 13, 2, 0, 0, 0, berlin)}},
+		},
+		{
+			name:   "outside the range",
+			events: []string{"BEGIN:VEVENT", "SUMMARY:Patching", "DTSTART:20240401T000000Z", "DURATION:PT4H", "END:VEVENT"},
+		},
+		{
+			name:   "cancelled",
+			events: []string{"BEGIN:VEVENT", "SUMMARY:Patching", "STATUS:CANCELLED", "DTSTART:20240305T080000Z", "DURATION:PT4H", "END:VEVENT"},
+		},
+		{
+			// The wall clock time is kept when New York switches to daylight
+			// saving time on March 10
+			name: "COUNT across DST",
+			events: []string{
+				"BEGIN:VEVENT", "SUMMARY:Backup", "DTSTART;TZID=America/New_York:20240308T220000", "DURATION:PT2H",
+				"RRULE:FREQ=DAILY;COUNT=4", "EXDATE;TZID=America/New_York:20240309T220000", "END:VEVENT",
+			},
+			want: []Event{
+				{Summary: "Backup", Start: time.Date(2024, 3, 8, 22, 0, 0, 0, newYork), End: time.Date(2024, 3, 9, 0, 0, 0, 0, newYork)},
+				{Summary: "Backup", Start: time.Date(2024, 3, 10, 22, 0, 0, 0, newYork), End: time.Date(2024, 3, 11, 0, 0, 0, 0, newYork)},
+				{Summary: "Backup", Start: time.Date(2024, 3, 11, 22, 0, 0, 0, newYork), End: time.Date(2024, 3, 12, 0, 0, 0, 0, newYork)},
+			},
+		},
+		{
+			// Europe switches on March 31, UNTIL is inclusive
+			name: "UNTIL across DST",
+			events: []string{
+				"BEGIN:VEVENT", "SUMMARY:Freeze", "DTSTART;TZID=Europe/Berlin:20240317T090000", "DTEND;TZID=Europe/Berlin:20240317T100000",
+				"RRULE:FREQ=WEEKLY;UNTIL=20240331T070000Z", "END:VEVENT",
+			},
+			want: []Event{
+				{Summary: "Freeze", Start: time.Date(2024, 3, 17, 9, 0, 0, 0, berlin), End: time.Date(2024, 3, 17, 10, 0, 0, 0, berlin)},
+				{Summary: "Freeze", Start: time.Date(2024, 3, 24, 9, 0, 0, 0, berlin), End: time.Date(2024, 3, 24, 10, 0, 0, 0, berlin)},
+				{Summary: "Freeze", Start: time.Date(2024, 3, 31, 9, 0, 0, 0, berlin), End: time.Date(2024, 3, 31, 10, 0, 0, 0, berlin)},
+			},
+		},
+		{
+			name: "recurrence started before the range",
+			events: []string{
+				"BEGIN:VEVENT", "SUMMARY:Patch day", "DTSTART:20231212T200000Z", "DURATION:PT6H", "RRULE:FREQ=MONTHLY", "END:VEVENT",
+			},
+			want: []Event{{Summary: "Patch day", Start: time.Date(2024, 3, 12, 20, 0, 0, 0, time.UTC), End: time.Date(2024, 3, 13, 2, 0, 0, 0, time.UTC)}},
+		},
+		{
+			name: "BYDAY is skipped",
+			events: []string{
+				"BEGIN:VEVENT", "SUMMARY:Standup", "DTSTART:20240304T090000Z", "DURATION:PT15M", "RRULE:FREQ=WEEKLY;BYDAY=MO,WE", "END:VEVENT",
+				"BEGIN:VEVENT", "SUMMARY:No start", "DURATION:PT15M", "END:VEVENT",
+			},
+			wantSkipped: 2,
+		},
+		{name: "invalid DTSTART", events: []string{"BEGIN:VEVENT", "DTSTART:tomorrow", "END:VEVENT"}, wantErr: true},
+		{name: "invalid DURATION", events: []string{"BEGIN:VEVENT", "DTSTART:20240305T080000Z", "DURATION:4h", "END:VEVENT"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, skipped, err := Parse(strings.NewReader(feed(tt.events...)), berlin, from, to)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if skipped != tt.wantSkipped {
+				t.Errorf("Parse() skipped %d, want %d", skipped, tt.wantSkipped)
+			}
+			if len(events) != len(tt.want) {
+				t.Fatalf("Parse() = %v, want %v", events, tt.want)
+			}
+			for i := range events {
+				if events[i].Summary != tt.want[i].Summary || !events[i].Start.Equal(tt.want[i].Start) || !events[i].End.Equal(tt.want[i].End) {
+					t.Errorf("event %d = %v, want %v", i, events[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDates(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	from := time.Date(2024, 12, 24, 12, 0, 0, 0, berlin)
+	events, err := Dates([]string{"2024-12-23", "2024-12-24", "2025-01-01"}, "Holidays", berlin, from, from.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatalf("Dates() error = %v", err)
+	}
+	want := []Event{
+		{Summary: "Holidays", Start: time.Date(2024, 12, 24, 0, 0, 0, 0, berlin), End: time.Date(2024, 12, 25, 0, 0, 0, 0, berlin)},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("Dates() = %v, want %v", events, want)
+	}
+	if _, err := Dates([]string{"24.12.2024"}, "Holidays", berlin, from, from.AddDate(0, 0, 7)); err == nil {
+		t.Errorf("Dates() succeeded for an invalid date")
+	}
+}
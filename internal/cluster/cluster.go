@@ -0,0 +1,110 @@
+// Package cluster connects to the workload clusters the nodes of servers
+// join, so one fleet can back several clusters. Each cluster is reached with
+// the kubeconfig in the Secret its servers' spec.clusterRef references.
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+// KubeconfigKey is the key of the kubeconfig in a cluster's Secret
+const KubeconfigKey = "kubeconfig"
+
+// Clients hands out clients for workload clusters. A client is created the
+// first time a cluster is used and again whenever its Secret changes. The
+// clients don't cache, since the controller only touches a few nodes of
+// each cluster at a time.
+type Clients struct {
+	reader client.Reader
+	scheme *runtime.Scheme
+
+	mu      sync.Mutex
+	clients map[types.NamespacedName]*remote
+}
+
+// remote is the client of a cluster and the Secret version it was made from
+type remote struct {
+	resourceVersion string
+	client          client.Client
+}
+
+// NewClients returns clients that read kubeconfig Secrets with the reader
+// and decode objects with the scheme
+func NewClients(reader client.Reader, scheme *runtime.Scheme) *Clients {
+	return &Clients{
+		reader:  reader,
+		scheme:  scheme,
+		clients: map[types.NamespacedName]*remote{},
+	}
+}
+
+// Get returns the client of the cluster the server's spec.clusterRef
+// references. The kubeconfig Secret must be in the namespace of the server's
+// tenant, if it has one, so tenants can't act on each other's clusters.
+func (c *Clients) Get(ctx context.Context, server *baremetalcontrollerv1.Server) (client.Client, error) {
+	ref := server.Spec.ClusterRef
+	if ref == nil {
+		return nil, fmt.Errorf("server %s joins no cluster", server.Name)
+	}
+	if err := server.CheckSecretRef(&ref.KubeconfigSecretRef); err != nil {
+		return nil, fmt.Errorf("kubeconfig of cluster %s: %w", ref.Name, err)
+	}
+	key := types.NamespacedName{Namespace: ref.KubeconfigSecretRef.Namespace, Name: ref.KubeconfigSecretRef.Name}
+	var secret corev1.Secret
+	if err := c.reader.Get(ctx, key, &secret); err != nil {
+		return nil, fmt.Errorf("failed to get kubeconfig of cluster %s: %w", ref.Name, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if r, ok := c.clients[key]; ok && r.resourceVersion == secret.ResourceVersion {
+		return r.client, nil
+	}
+
+	kubeconfig, ok := secret.Data[KubeconfigKey]
+	if !ok {
+		return nil, fmt.Errorf("secret %s has no %q key for cluster %s", key, KubeconfigKey, ref.Name)
+	}
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig for cluster %s: %w", ref.Name, err)
+	}
+	cl, err := client.New(config, client.Options{Scheme: c.scheme})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for cluster %s: %w", ref.Name, err)
+	}
+	c.clients[key] = &remote{resourceVersion: secret.ResourceVersion, client: cl}
+	return cl, nil
+}
+
+// Referenced returns a server for each cluster the servers reference, to
+// get its client with, sorted by cluster name. Servers whose tenant may not
+// read the kubeconfig Secret they reference are left out.
+func Referenced(servers []baremetalcontrollerv1.Server) []*baremetalcontrollerv1.Server {
+	seen := map[baremetalcontrollerv1.ClusterReference]bool{}
+	var referencing []*baremetalcontrollerv1.Server
+	for i := range servers {
+		server := &servers[i]
+		ref := server.Spec.ClusterRef
+		if ref == nil || seen[*ref] || server.CheckSecretRef(&ref.KubeconfigSecretRef) != nil {
+			continue
+		}
+		seen[*ref] = true
+		referencing = append(referencing, server)
+	}
+	sort.Slice(referencing, func(i, j int) bool {
+		return referencing[i].Spec.ClusterRef.Name < referencing[j].Spec.ClusterRef.Name
+	})
+	return referencing
+}
@@ -0,0 +1,123 @@
+package cluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+// joining returns a server of the tenant that joins the cluster with the
+// kubeconfig in namespace/name
+func joining(serverName, tenant, clusterName, namespace, name string) baremetalcontrollerv1.Server {
+	server := baremetalcontrollerv1.Server{ObjectMeta: metav1.ObjectMeta{Name: serverName}}
+	if tenant != "" {
+		server.Labels = map[string]string{baremetalcontrollerv1.TenantLabel: tenant}
+	}
+	server.Spec.ClusterRef = &baremetalcontrollerv1.ClusterReference{
+		Name:                clusterName,
+		KubeconfigSecretRef: baremetalcontrollerv1.SecretReference{Name: name, Namespace: namespace},
+	}
+	return server
+}
+
+func kubeconfigSecret(t *testing.T, namespace, name string) *corev1.Secret {
+	t.Helper()
+	kubeconfig, err := clientcmd.Write(clientcmdapi.Config{
+		Clusters:       map[string]*clientcmdapi.Cluster{"workload": {Server: "https://workload.example:6443"}},
+		AuthInfos:      map[string]*clientcmdapi.AuthInfo{"admin": {Token: "token"}},
+		Contexts:       map[string]*clientcmdapi.Context{"workload": {Cluster: "workload", AuthInfo: "admin"}},
+		CurrentContext: "workload",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, ResourceVersion: "1"},
+		Data:       map[string][]byte{KubeconfigKey: kubeconfig},
+	}
+}
+
+func TestClientsGet(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	// Count the Secrets read, to see that another tenant's isn't
+	reads := 0
+	reader := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(kubeconfigSecret(t, "tenant-a", "workload"), kubeconfigSecret(t, "tenant-b", "workload")).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				reads++
+				return c.Get(ctx, key, obj, opts...)
+			},
+		}).Build()
+	clients := NewClients(reader, scheme)
+	ctx := context.Background()
+
+	own := joining("worker-01", "tenant-a", "workload", "tenant-a", "workload")
+	first, err := clients.Get(ctx, &own)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	second, err := clients.Get(ctx, &own)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if first != second {
+		t.Errorf("Get() created another client for an unchanged Secret")
+	}
+
+	reads = 0
+	crossTenant := joining("worker-02", "tenant-a", "workload", "tenant-b", "workload")
+	if _, err := clients.Get(ctx, &crossTenant); err == nil || !strings.Contains(err.Error(), "outside namespace tenant-a") {
+		t.Errorf("Get() error = %v for another tenant's kubeconfig", err)
+	}
+	// Even a client made for the other tenant isn't handed out
+	other := joining("worker-03", "tenant-b", "workload", "tenant-b", "workload")
+	if _, err := clients.Get(ctx, &other); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if _, err := clients.Get(ctx, &crossTenant); err == nil {
+		t.Errorf("Get() handed out the client of another tenant's cluster")
+	}
+	if reads != 1 {
+		t.Errorf("read %d Secrets, want only tenant-b's own", reads)
+	}
+
+	// Servers of no tenant may use any Secret
+	shared := joining("worker-04", "", "workload", "tenant-b", "workload")
+	if _, err := clients.Get(ctx, &shared); err != nil {
+		t.Errorf("Get() error = %v for a server without a tenant", err)
+	}
+}
+
+func TestReferenced(t *testing.T) {
+	servers := []baremetalcontrollerv1.Server{
+		joining("worker-01", "tenant-a", "workload", "tenant-a", "workload"),
+		joining("worker-02", "tenant-a", "workload", "tenant-a", "workload"),
+		// Only tenant-b may use its Secret
+		joining("worker-03", "tenant-a", "batch", "tenant-b", "batch"),
+		joining("worker-04", "tenant-b", "batch", "tenant-b", "batch"),
+		{ObjectMeta: metav1.ObjectMeta{Name: "worker-05"}},
+	}
+	var got []string
+	for _, server := range Referenced(servers) {
+		got = append(got, server.Name+"/"+server.Spec.ClusterRef.Name)
+	}
+	if want := "worker-04/batch worker-01/workload"; strings.Join(got, " ") != want {
+		t.Errorf("Referenced() = %v, want %s", got, want)
+	}
+}
@@ -0,0 +1,211 @@
+package console
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/net/websocket"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/bmc"
+	"github.com/Unbounder1/bare-metal-controller/internal/bootlog"
+	"github.com/Unbounder1/bare-metal-controller/internal/index"
+	"github.com/Unbounder1/bare-metal-controller/internal/power"
+	"github.com/Unbounder1/bare-metal-controller/internal/resolve"
+	"github.com/Unbounder1/bare-metal-controller/internal/serving"
+)
+
+// PathPrefix is the URL path consoles are served under, followed by the
+// server name, or <namespace>/<name> for namespaced Servers. Access is authorized as the non-resource URL with verb get.
+const PathPrefix = "/console/"
+
+// Options contains configuration for the serial console proxy.
+type Options struct {
+	// Address is the address to listen on (e.g., ":8087"), "0" to disable
+	Address string
+
+	// CertFile is the path to the TLS certificate file
+	CertFile string
+
+	// KeyFile is the path to the TLS key file
+	KeyFile string
+}
+
+// DefaultOptions returns the default console options.
+func DefaultOptions() Options {
+	return Options{
+		Address: "0",
+	}
+}
+
+// BindFlags binds the console options to command line flags.
+// The prefix can be used to namespace the flags (e.g., "console-").
+func (o *Options) BindFlags(fs *flag.FlagSet, prefix string) {
+	fs.StringVar(&o.Address, prefix+"bind-address", o.Address,
+		"The address the serial console proxy binds to. Use 0 to disable it.")
+	fs.StringVar(&o.CertFile, prefix+"cert", o.CertFile,
+		"Path to TLS certificate file for the console proxy. Empty for a self-signed certificate.")
+	fs.StringVar(&o.KeyFile, prefix+"key", o.KeyFile,
+		"Path to TLS key file for the console proxy. Empty for a self-signed certificate.")
+}
+
+// Validate validates the options.
+func (o *Options) Validate() error {
+	if (o.CertFile == "") != (o.KeyFile == "") {
+		return fmt.Errorf("console cert and key must be set together, or neither")
+	}
+	return nil
+}
+
+// Enabled returns true if the console proxy should be started.
+func (o *Options) Enabled() bool {
+	return o.Address != "" && o.Address != "0"
+}
+
+// Server implements manager.Runnable and proxies serial consoles of Servers
+// over websockets. Clients authenticate with a bearer token, which is checked
+// with a TokenReview and authorized with a SubjectAccessReview against
+// PathPrefix+[<namespace>/]<server>, so access can be granted per server with
+// RBAC nonResourceURLs. Boot logs are authorized the same way against
+// bootlog.PathPrefix+[<namespace>/]<server>.
+type Server struct {
+	options Options
+	client  client.Client
+	// endpoints loads the BMCs the consoles are opened through
+	endpoints *bmc.Endpoints
+	console   power.SerialConsole
+	redfish   power.RedfishClient
+	// bootLogs serves boot logs under bootlog.PathPrefix, nil to not serve
+	// them
+	bootLogs *bootlog.Receiver
+	mgr      manager.Manager
+	log      logr.Logger
+}
+
+// Ensure Server implements manager.Runnable
+var _ manager.Runnable = &Server{}
+
+// NewServer creates a new console proxy runnable. BMC hostnames are resolved
+// with resolver like the controller does, nil to pass them on. The boot logs
+// of bootLogs are served next to the consoles, nil to not serve them.
+func NewServer(opts Options, mgr manager.Manager, console power.SerialConsole, redfish power.RedfishClient, resolver *resolve.Resolver, bootLogs *bootlog.Receiver) (*Server, error) {
+	return &Server{
+		options:   opts,
+		client:    mgr.GetClient(),
+		endpoints: &bmc.Endpoints{Reader: mgr.GetClient(), Resolver: resolver},
+		console:   console,
+		redfish:   redfish,
+		bootLogs:  bootLogs,
+		mgr:       mgr,
+		log:       ctrl.Log.WithName("console"),
+	}, nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Consoles are
+// served by every replica.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable and serves consoles until the context is
+// cancelled.
+func (s *Server) Start(ctx context.Context) error {
+	filter, err := filters.WithAuthenticationAndAuthorization(s.mgr.GetConfig(), s.mgr.GetHTTPClient())
+	if err != nil {
+		return fmt.Errorf("failed to create console authorization filter: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(PathPrefix, s.serveConsole)
+	if s.bootLogs != nil {
+		mux.Handle(bootlog.PathPrefix, s.bootLogs)
+	}
+	handler, err := filter(s.log, mux)
+	if err != nil {
+		return fmt.Errorf("failed to wrap console handler: %w", err)
+	}
+
+	if err := serving.ServeTLS(ctx, s.options.Address, s.options.CertFile, s.options.KeyFile, "bare-metal-controller-console", handler); err != nil {
+		return fmt.Errorf("console server error: %w", err)
+	}
+	return nil
+}
+
+// serveConsole opens the server's console before upgrading to a websocket so
+// errors can still be reported with an HTTP status.
+func (s *Server) serveConsole(w http.ResponseWriter, req *http.Request) {
+	name := strings.TrimPrefix(req.URL.Path, PathPrefix)
+	key, ok := index.ParseServerPath(name)
+	if !ok {
+		http.Error(w, "expected "+PathPrefix+"[<namespace>/]<server>", http.StatusNotFound)
+		return
+	}
+
+	var server baremetalcontrollerv1.Server
+	if err := s.client.Get(req.Context(), key, &server); err != nil {
+		http.Error(w, fmt.Sprintf("failed to get server %s: %v", name, err), http.StatusNotFound)
+		return
+	}
+
+	conn, err := s.open(req.Context(), &server)
+	if err != nil {
+		s.log.Error(err, "Failed to open serial console", "server", name)
+		http.Error(w, fmt.Sprintf("failed to open serial console: %v", err), http.StatusBadGateway)
+		return
+	}
+	defer conn.Close()
+
+	s.log.Info("Serial console opened", "server", name)
+	websocket.Server{
+		Handler: func(ws *websocket.Conn) {
+			ws.PayloadType = websocket.BinaryFrame
+			done := make(chan struct{}, 2)
+			go func() {
+				_, _ = io.Copy(ws, conn)
+				done <- struct{}{}
+			}()
+			go func() {
+				_, _ = io.Copy(conn, ws)
+				done <- struct{}{}
+			}()
+			<-done
+		},
+	}.ServeHTTP(w, req)
+	s.log.Info("Serial console closed", "server", name)
+}
+
+// open connects to the console of the server based on its control type
+func (s *Server) open(ctx context.Context, server *baremetalcontrollerv1.Server) (io.ReadWriteCloser, error) {
+	switch server.Spec.Type {
+	case baremetalcontrollerv1.ControlTypeIPMI:
+		target, err := s.endpoints.IPMI(ctx, server)
+		if err != nil {
+			return nil, err
+		}
+		return s.console.OpenIPMI(target.Address, target.Username, target.Password)
+
+	case baremetalcontrollerv1.ControlTypeRedfish:
+		target, err := s.endpoints.Redfish(ctx, server)
+		if err != nil {
+			return nil, err
+		}
+		info, err := s.redfish.GetSerialConsole(target)
+		if err != nil {
+			return nil, err
+		}
+		address := s.endpoints.ResolveAddress(bmc.Host(target.Address))
+		return s.console.OpenSSH(address, info.Port, target.Username, target.Password, info.Command)
+
+	default:
+		return nil, fmt.Errorf("serial console is not supported for control type %q", server.Spec.Type)
+	}
+}
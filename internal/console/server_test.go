@@ -0,0 +1,210 @@
+package console
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/net/websocket"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/bmc"
+	"github.com/Unbounder1/bare-metal-controller/internal/power"
+	"github.com/Unbounder1/bare-metal-controller/internal/resolve"
+)
+
+func newTestServer(t *testing.T, console *power.MockSerialConsole, redfish *power.MockRedfishClient, objs ...client.Object) *Server {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := baremetalcontrollerv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	return &Server{
+		client:    c,
+		endpoints: &bmc.Endpoints{Reader: c},
+		console:   console,
+		redfish:   redfish,
+		log:       logr.Discard(),
+	}
+}
+
+func ipmiServer(name string) *baremetalcontrollerv1.Server {
+	return &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: baremetalcontrollerv1.ServerSpec{
+			Type: baremetalcontrollerv1.ControlTypeIPMI,
+			Control: baremetalcontrollerv1.ControlSpecs{
+				IPMI: &baremetalcontrollerv1.IPMISpecs{Address: "192.168.1.10", Username: "admin", Password: "secret"},
+			},
+		},
+	}
+}
+
+func redfishServer(name string, namespace string) *baremetalcontrollerv1.Server {
+	return &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: baremetalcontrollerv1.ServerSpec{
+			Type: baremetalcontrollerv1.ControlTypeRedfish,
+			Control: baremetalcontrollerv1.ControlSpecs{
+				Redfish: &baremetalcontrollerv1.RedfishSpecs{
+					Address:              "https://192.168.1.20:8443/",
+					CredentialsSecretRef: &baremetalcontrollerv1.SecretReference{Name: "bmc", Namespace: "default"},
+				},
+			},
+		},
+	}
+}
+
+func TestOpen(t *testing.T) {
+	credentials := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "bmc", Namespace: "default"},
+		Data:       map[string][]byte{"username": []byte("root"), "password": []byte("calvin")},
+	}
+	hostname := ipmiServer("worker-07")
+	hostname.Spec.Control.IPMI.Address = "localhost"
+	noIPMI := ipmiServer("worker-02")
+	noIPMI.Spec.Control.IPMI = nil
+	noSecretRef := redfishServer("worker-03", "")
+	noSecretRef.Spec.Control.Redfish.CredentialsSecretRef = nil
+	wol := &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-04"},
+		Spec:       baremetalcontrollerv1.ServerSpec{Type: baremetalcontrollerv1.ControlTypeWOL},
+	}
+
+	tests := []struct {
+		name        string
+		server      *baremetalcontrollerv1.Server
+		wantAddress string
+		wantUser    string
+		wantErr     string
+	}{
+		{name: "IPMI Serial over LAN", server: ipmiServer("worker-01"), wantAddress: "192.168.1.10", wantUser: "admin"},
+		{name: "IPMI by hostname", server: hostname, wantAddress: "127.0.0.1", wantUser: "admin"},
+		{name: "Redfish SSH console", server: redfishServer("worker-05", ""), wantAddress: "192.168.1.20", wantUser: "root"},
+		{name: "IPMI without config", server: noIPMI, wantErr: "IPMI config is required"},
+		{name: "Redfish without credentials", server: noSecretRef, wantErr: "credentials secret reference is required"},
+		{name: "Secret of another tenant", server: redfishServer("worker-06", "team-a"), wantErr: "team-a"},
+		{name: "unsupported control type", server: wol, wantErr: "not supported"},
+	}
+	resolver, err := resolve.New(resolve.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			console := &power.MockSerialConsole{}
+			redfish := &power.MockRedfishClient{SerialConsole: power.SerialConsoleInfo{Port: 22, Command: "console com2"}}
+			s := newTestServer(t, console, redfish, credentials)
+			s.endpoints.Resolver = resolver
+
+			_, err := s.open(context.Background(), tt.server)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("open() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("open() error = %v", err)
+			}
+			if console.LastAddress != tt.wantAddress || console.LastUser != tt.wantUser {
+				t.Errorf("opened %s as %s, want %s as %s", console.LastAddress, console.LastUser, tt.wantAddress, tt.wantUser)
+			}
+			if tt.server.Spec.Type == baremetalcontrollerv1.ControlTypeRedfish &&
+				(console.LastPort != 22 || console.LastCommand != "console com2") {
+				t.Errorf("SSH console on port %d with %q, want the BMC's", console.LastPort, console.LastCommand)
+			}
+		})
+	}
+}
+
+func TestServeConsole(t *testing.T) {
+	bmc, proxied := net.Pipe()
+	console := &power.MockSerialConsole{Conn: proxied}
+	s := newTestServer(t, console, &power.MockRedfishClient{}, ipmiServer("worker-01"))
+	httpServer := httptest.NewServer(http.HandlerFunc(s.serveConsole))
+	defer httpServer.Close()
+
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http")+PathPrefix+"worker-01", "", httpServer.URL)
+	if err != nil {
+		t.Fatalf("websocket.Dial() error = %v", err)
+	}
+	defer ws.Close()
+
+	// Keystrokes reach the BMC and console output reaches the client
+	go func() { _, _ = ws.Write([]byte("root\r")) }()
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(bmc, buf); err != nil || string(buf) != "root\r" {
+		t.Fatalf("BMC read %q, %v", buf, err)
+	}
+	go func() { _, _ = bmc.Write([]byte("login:")) }()
+	buf = make([]byte, 6)
+	if _, err := io.ReadFull(ws, buf); err != nil || string(buf) != "login:" {
+		t.Fatalf("client read %q, %v", buf, err)
+	}
+}
+
+func TestServeConsoleErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		openErr error
+		want    int
+	}{
+		{name: "no server", path: PathPrefix, want: http.StatusNotFound},
+		{name: "nested path", path: PathPrefix + "default/worker-01/extra", want: http.StatusNotFound},
+		{name: "unknown server", path: PathPrefix + "worker-99", want: http.StatusNotFound},
+		{name: "other namespace", path: PathPrefix + "team-b/worker-03", want: http.StatusNotFound},
+		{name: "namespaced BMC refuses", path: PathPrefix + "team-a/worker-03", openErr: io.ErrUnexpectedEOF, want: http.StatusBadGateway},
+		{name: "BMC refuses", path: PathPrefix + "worker-01", openErr: io.ErrUnexpectedEOF, want: http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			console := &power.MockSerialConsole{ReturnError: tt.openErr}
+			namespaced := ipmiServer("worker-03")
+			namespaced.Namespace = "team-a"
+			s := newTestServer(t, console, &power.MockRedfishClient{}, ipmiServer("worker-01"), namespaced)
+			recorder := httptest.NewRecorder()
+			s.serveConsole(recorder, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if recorder.Code != tt.want {
+				t.Errorf("serveConsole() status = %d, want %d", recorder.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		opts    Options
+		enabled bool
+		wantErr bool
+	}{
+		{opts: DefaultOptions()},
+		{opts: Options{Address: ":8087"}, enabled: true},
+		{opts: Options{Address: ":8087", CertFile: "tls.crt", KeyFile: "tls.key"}, enabled: true},
+		{opts: Options{Address: ":8087", CertFile: "tls.crt"}, enabled: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) error = %v, wantErr %v", tt.opts, err, tt.wantErr)
+		}
+		if tt.opts.Enabled() != tt.enabled {
+			t.Errorf("Enabled(%+v) = %v, want %v", tt.opts, !tt.enabled, tt.enabled)
+		}
+	}
+}
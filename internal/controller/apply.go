@@ -0,0 +1,84 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/csaupgrade"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+const (
+	// serverFieldManager owns the Server status fields written by
+	// ServerReconciler
+	serverFieldManager = "bare-metal-controller"
+	// tinkerbellFieldManager owns status.provisioning
+	tinkerbellFieldManager = "bare-metal-controller-tinkerbell"
+	// nodeFeatureFieldManager owns status.nodeFeatures
+	nodeFeatureFieldManager = "bare-metal-controller-node-features"
+)
+
+// legacyFieldManager is the manager the API server recorded for status
+// written with Update before server-side apply, derived from the user agent.
+var legacyFieldManager = strings.SplitN(rest.DefaultKubernetesUserAgent(), "/", 2)[0]
+
+// applyServerStatus writes status with server-side apply as fieldManager.
+// Only fields set in status are claimed, and fields the manager owned
+// before but no longer sets are removed. Fields owned by other managers are
+// left alone, and setting them to a different value fails with a conflict
+// instead of silently overwriting them.
+func applyServerStatus(ctx context.Context, c client.Client, server *baremetalcontrollerv1.Server, status baremetalcontrollerv1.ServerStatus, fieldManager string) error {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&status)
+	if err != nil {
+		return fmt.Errorf("failed to convert server status: %w", err)
+	}
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"status": content}}
+	obj.SetGroupVersionKind(baremetalcontrollerv1.GroupVersion.WithKind("Server"))
+	obj.SetName(server.Name)
+	obj.SetNamespace(server.Namespace)
+	return c.Status().Patch(ctx, obj, client.Apply, client.FieldOwner(fieldManager))
+}
+
+// upgradeStatusManagedFields hands the status fields written with Update
+// before server-side apply over to fieldManager, so its first apply doesn't
+// conflict with the controller's own earlier writes.
+func upgradeStatusManagedFields(ctx context.Context, c client.Client, server *baremetalcontrollerv1.Server, fieldManager string) error {
+	managers := sets.New(legacyFieldManager)
+	patch, err := csaupgrade.UpgradeManagedFieldsPatch(server, managers, fieldManager, csaupgrade.Subresource("status"))
+	if err != nil {
+		return fmt.Errorf("failed to upgrade managed fields: %w", err)
+	}
+	if patch == nil {
+		return nil
+	}
+	// Patch a copy so pending status changes aren't replaced by the response
+	if err := c.Patch(ctx, server.DeepCopy(), client.RawPatch(types.JSONPatchType, patch)); err != nil {
+		return fmt.Errorf("failed to upgrade managed fields: %w", err)
+	}
+	return csaupgrade.UpgradeManagedFields(server, managers, fieldManager, csaupgrade.Subresource("status"))
+}
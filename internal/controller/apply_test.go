@@ -0,0 +1,141 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+func newApplyScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := baremetalcontrollerv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return scheme
+}
+
+func TestApplyServerStatus(t *testing.T) {
+	server := &baremetalcontrollerv1.Server{ObjectMeta: metav1.ObjectMeta{Name: "worker-01"}}
+
+	// The fake client can't apply, so capture the patch instead
+	var applied *unstructured.Unstructured
+	var owner string
+	c := fake.NewClientBuilder().WithScheme(newApplyScheme(t)).WithObjects(server).
+		WithInterceptorFuncs(interceptor.Funcs{
+			SubResourcePatch: func(_ context.Context, _ client.Client, subResource string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+				if subResource != "status" || patch.Type() != client.Apply.Type() {
+					t.Errorf("patched %s with %s, want an apply of status", subResource, patch.Type())
+				}
+				patchOpts := &client.SubResourcePatchOptions{}
+				patchOpts.ApplyOptions(opts)
+				owner = patchOpts.FieldManager
+				applied = obj.(*unstructured.Unstructured)
+				return nil
+			},
+		}).Build()
+
+	err := applyServerStatus(context.Background(), c, server, baremetalcontrollerv1.ServerStatus{
+		Provisioning: &baremetalcontrollerv1.ProvisioningStatus{Workflow: "worker-01-provision", State: "STATE_RUNNING"},
+	}, tinkerbellFieldManager)
+	if err != nil {
+		t.Fatalf("applyServerStatus() error = %v", err)
+	}
+	if owner != tinkerbellFieldManager {
+		t.Errorf("field manager = %q, want %q", owner, tinkerbellFieldManager)
+	}
+	if applied.GetName() != "worker-01" || applied.GetKind() != "Server" || applied.GetAPIVersion() != baremetalcontrollerv1.GroupVersion.String() {
+		t.Errorf("applied %s %s %s", applied.GetAPIVersion(), applied.GetKind(), applied.GetName())
+	}
+
+	// Fields left unset are not claimed, or applying would clear them for
+	// the other managers
+	status, _, _ := unstructured.NestedMap(applied.Object, "status")
+	if len(status) != 1 {
+		t.Errorf("applied status %v, want only provisioning", status)
+	}
+	if state, _, _ := unstructured.NestedString(status, "provisioning", "state"); state != "STATE_RUNNING" {
+		t.Errorf("provisioning state = %q", state)
+	}
+	if _, found := applied.Object["spec"]; found {
+		t.Errorf("applied the spec too")
+	}
+}
+
+func TestUpgradeStatusManagedFields(t *testing.T) {
+	legacyEntry := metav1.ManagedFieldsEntry{
+		Manager:     legacyFieldManager,
+		Operation:   metav1.ManagedFieldsOperationUpdate,
+		APIVersion:  baremetalcontrollerv1.GroupVersion.String(),
+		FieldsType:  "FieldsV1",
+		FieldsV1:    &metav1.FieldsV1{Raw: []byte(`{"f:status":{"f:status":{},"f:message":{}}}`)},
+		Subresource: "status",
+	}
+
+	tests := []struct {
+		name        string
+		entries     []metav1.ManagedFieldsEntry
+		wantPatched bool
+	}{
+		{name: "written with Update", entries: []metav1.ManagedFieldsEntry{legacyEntry}, wantPatched: true},
+		{name: "already applied"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &baremetalcontrollerv1.Server{ObjectMeta: metav1.ObjectMeta{Name: "worker-01", ManagedFields: tt.entries}}
+			patched := false
+			c := fake.NewClientBuilder().WithScheme(newApplyScheme(t)).WithObjects(server.DeepCopy()).
+				WithInterceptorFuncs(interceptor.Funcs{
+					Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+						patched = true
+						return nil
+					},
+				}).Build()
+
+			// Pending status changes survive the upgrade
+			server.Status.Status = baremetalcontrollerv1.StatusActive
+			if err := upgradeStatusManagedFields(context.Background(), c, server, serverFieldManager); err != nil {
+				t.Fatalf("upgradeStatusManagedFields() error = %v", err)
+			}
+			if patched != tt.wantPatched {
+				t.Errorf("patched = %v, want %v", patched, tt.wantPatched)
+			}
+			if server.Status.Status != baremetalcontrollerv1.StatusActive {
+				t.Errorf("status = %q, pending change lost", server.Status.Status)
+			}
+			for _, entry := range server.ManagedFields {
+				if entry.Manager == legacyFieldManager {
+					t.Errorf("status fields still owned by %s", legacyFieldManager)
+				}
+			}
+			if tt.wantPatched && (len(server.ManagedFields) != 1 || server.ManagedFields[0].Manager != serverFieldManager ||
+				server.ManagedFields[0].Operation != metav1.ManagedFieldsOperationApply) {
+				t.Errorf("managed fields = %+v, want them applied by %s", server.ManagedFields, serverFieldManager)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/attestation"
+	"github.com/Unbounder1/bare-metal-controller/internal/power"
+)
+
+const defaultAKHandle = "0x81010002"
+
+// attestServer checks that a reachable server booted into the expected state
+// before it is trusted. It returns true once the server is trusted. Errors
+// reaching the server are returned so the caller can retry; a quote that
+// doesn't verify marks the server failed and powers it off.
+func (r *ServerReconciler) attestServer(ctx context.Context, server *baremetalcontrollerv1.Server) (bool, error) {
+	spec := server.Spec.Attestation
+	if spec == nil {
+		return true, nil
+	}
+
+	// Configuration errors can't be fixed by retrying
+	expected, err := attestation.ParseExpectedPCRs(spec.PCRs)
+	if err != nil {
+		r.rejectAttestation(ctx, server, err.Error())
+		return false, nil
+	}
+	akPublicKey, err := attestation.ParsePublicKey(spec.AKPublicKey)
+	if err != nil {
+		r.rejectAttestation(ctx, server, err.Error())
+		return false, nil
+	}
+	if spec.SSHSecretRef == nil {
+		r.rejectAttestation(ctx, server, "SSH secret reference is required")
+		return false, nil
+	}
+
+	key, err := r.endpoints().SecretValue(ctx, server, spec.SSHSecretRef, "ssh-privatekey")
+	if err != nil {
+		return false, err
+	}
+
+	address := r.endpoints().ResolveAddress(spec.Address)
+	if address == "" {
+		address = r.sshAddress(server)
+	}
+	akHandle := spec.AKHandle
+	if akHandle == "" {
+		akHandle = defaultAKHandle
+	}
+	if err := power.ValidAKHandle(akHandle); err != nil {
+		r.rejectAttestation(ctx, server, err.Error())
+		return false, nil
+	}
+
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return false, fmt.Errorf("unable to generate nonce: %w", err)
+	}
+
+	quote, err := r.Attestor.Quote(ctx, address, spec.User, key, akHandle, expected.Indices(), nonce)
+	if err != nil {
+		return false, err
+	}
+
+	if err := attestation.Verify(quote, akPublicKey, nonce, expected); err != nil {
+		r.rejectAttestation(ctx, server, err.Error())
+		return false, nil
+	}
+
+	setAttestationStatus(server, baremetalcontrollerv1.AttestationPhaseVerified, "")
+	return true, nil
+}
+
+// rejectAttestation marks a server that failed attestation as failed and
+// powers it off so it can't be used.
+func (r *ServerReconciler) rejectAttestation(ctx context.Context, server *baremetalcontrollerv1.Server, reason string) {
+	logger := log.FromContext(ctx)
+	logger.Info("Attestation failed, powering off server", "server", server.Name, "reason", reason)
+
+	setAttestationStatus(server, baremetalcontrollerv1.AttestationPhaseFailed, reason)
+	server.Status.Status = baremetalcontrollerv1.StatusFailed
+	server.Status.Message = fmt.Sprintf("Attestation failed: %s", reason)
+	server.Status.Reason = baremetalcontrollerv1.ReasonAttestationFailed
+
+	if r.powerActionsHalted(ctx, server, "power off after failed attestation") {
+		return
+	}
+	if err := r.powerOff(ctx, server); err != nil {
+		logger.Error(err, "Failed to power off server after failed attestation", "server", server.Name)
+	}
+}
+
+func setAttestationStatus(server *baremetalcontrollerv1.Server, phase baremetalcontrollerv1.AttestationPhase, message string) {
+	now := metav1.Now()
+	server.Status.Attestation = &baremetalcontrollerv1.AttestationStatus{
+		Phase:               phase,
+		Message:             message,
+		LastAttestationTime: &now,
+	}
+}
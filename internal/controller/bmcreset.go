@@ -0,0 +1,93 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/power"
+)
+
+// bmcResetSettleTime is how long a BMC is given to restart after a cold
+// reset before it is asked again
+const bmcResetSettleTime = 2 * time.Minute
+
+// recoverBMC cold resets the BMC of an IPMI server that failed because its
+// sessions timed out while the BMC still answers pings, which is what a
+// wedged BMC looks like. A reset is sent at most once per
+// BMCColdResetBackoff. Once the BMC answers IPMI again the failure is
+// cleared and the server is reconciled from scratch.
+func (r *ServerReconciler) recoverBMC(ctx context.Context, server *baremetalcontrollerv1.Server) ctrl.Result {
+	if r.BMCColdResetBackoff <= 0 || server.Spec.Type != baremetalcontrollerv1.ControlTypeIPMI ||
+		server.Status.Reason != baremetalcontrollerv1.ReasonBMCUnreachable {
+		return ctrl.Result{}
+	}
+	target, err := r.endpoints().IPMI(ctx, server)
+	if err != nil {
+		return ctrl.Result{}
+	}
+	address := target.Address
+
+	if _, err := r.IPMIClient.GetPowerStatus(ctx, address, target.Username, target.Password); err == nil {
+		r.event(server, corev1.EventTypeNormal, "BMCRecovered", "BMC at %s answers IPMI again", address)
+		r.clearFailure(server, "")
+		r.updateStatus(ctx, server)
+		return ctrl.Result{Requeue: true}
+	} else if !errors.Is(err, power.ErrUnreachable) {
+		return ctrl.Result{}
+	}
+
+	// A BMC that doesn't answer pings either is down or cut off, and can't
+	// be reset over the network
+	reachable, _, ok := r.isReachable(ctx, server, address, healthCheck{})
+	if !ok {
+		return ctrl.Result{RequeueAfter: reachabilityRetryInterval}
+	}
+	if !reachable {
+		return ctrl.Result{RequeueAfter: r.BMCColdResetBackoff}
+	}
+
+	if last := server.Status.BMCReset; last != nil && last.LastReset != nil {
+		if wait := r.BMCColdResetBackoff - time.Since(last.LastReset.Time); wait > 0 {
+			return ctrl.Result{RequeueAfter: min(wait, bmcResetSettleTime)}
+		}
+	}
+
+	if r.powerActionsHalted(ctx, server, "BMC cold reset") {
+		return ctrl.Result{RequeueAfter: breakerRetryInterval}
+	}
+	if err := r.IPMIClient.ColdReset(ctx, address, target.Username, target.Password); err != nil {
+		r.event(server, corev1.EventTypeWarning, "BMCColdResetFailed", "Cold reset of the BMC at %s failed: %v", address, err)
+	} else {
+		r.event(server, corev1.EventTypeWarning, "BMCColdReset", "Sent a cold reset to the BMC at %s after IPMI sessions timed out", address)
+	}
+	now := metav1.Now()
+	if server.Status.BMCReset == nil {
+		server.Status.BMCReset = &baremetalcontrollerv1.BMCResetStatus{}
+	}
+	server.Status.BMCReset.Count++
+	server.Status.BMCReset.LastReset = &now
+	r.updateStatus(ctx, server)
+	return ctrl.Result{RequeueAfter: bmcResetSettleTime}
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/power"
+)
+
+// applyBootDevice forces spec.bootDevice persistently through the BMC
+func (r *ServerReconciler) applyBootDevice(ctx context.Context, server *baremetalcontrollerv1.Server) error {
+	if server.Spec.BootPolicy != nil {
+		return invalidSpec("bootDevice can't be combined with bootPolicy")
+	}
+	device := server.Spec.BootDevice
+	if err := r.setBootDevice(ctx, server, device, true); err != nil {
+		return err
+	}
+	server.Status.Boot = &baremetalcontrollerv1.BootStatus{LastSource: device}
+	return nil
+}
+
+// applyBootPolicy forces the next boot source through the BMC before the
+// server is powered on. Only the last source is set persistently, so a
+// server that reboots on its own doesn't loop on an earlier source.
+func (r *ServerReconciler) applyBootPolicy(ctx context.Context, server *baremetalcontrollerv1.Server) error {
+	policy := server.Spec.BootPolicy
+	if len(policy.Sources) == 0 {
+		return nil
+	}
+
+	status := server.Status.Boot
+	if status == nil || !sameBootSources(status.Sources, policy.Sources) {
+		status = &baremetalcontrollerv1.BootStatus{
+			Sources: append([]baremetalcontrollerv1.BootSource(nil), policy.Sources...),
+		}
+	}
+
+	index := status.Completed
+	if index >= len(policy.Sources) {
+		index = len(policy.Sources) - 1
+	}
+	source := policy.Sources[index]
+	persistent := index == len(policy.Sources)-1
+
+	if err := r.setBootDevice(ctx, server, source, persistent); err != nil {
+		return err
+	}
+
+	status.LastSource = source
+	server.Status.Boot = status
+	return nil
+}
+
+// setBootDevice forces the boot source through the BMC
+func (r *ServerReconciler) setBootDevice(ctx context.Context, server *baremetalcontrollerv1.Server, source baremetalcontrollerv1.BootSource, persistent bool) error {
+	switch server.Spec.Type {
+	case baremetalcontrollerv1.ControlTypeIPMI:
+		target, err := r.endpoints().IPMI(ctx, server)
+		if err != nil {
+			return err
+		}
+		if err := r.IPMIClient.SetBootDevice(ctx, target.Address, target.Username, target.Password, string(source), persistent); err != nil {
+			return fmt.Errorf("failed to set boot device: %w", err)
+		}
+
+	case baremetalcontrollerv1.ControlTypeRedfish:
+		target, err := r.endpoints().Redfish(ctx, server)
+		if err != nil {
+			return err
+		}
+		if err := r.RedfishClient.SetBootDevice(target, string(source), persistent); err != nil {
+			return fmt.Errorf("failed to set boot device: %w", err)
+		}
+
+	default:
+		return invalidSpec("boot policy and boot device require the ipmi or redfish control type")
+	}
+	return nil
+}
+
+// getBootDevice reads the boot source the BMC forces
+func (r *ServerReconciler) getBootDevice(ctx context.Context, server *baremetalcontrollerv1.Server) (power.BootOverride, error) {
+	switch server.Spec.Type {
+	case baremetalcontrollerv1.ControlTypeIPMI:
+		target, err := r.endpoints().IPMI(ctx, server)
+		if err != nil {
+			return power.BootOverride{}, err
+		}
+		return r.IPMIClient.GetBootDevice(ctx, target.Address, target.Username, target.Password)
+
+	case baremetalcontrollerv1.ControlTypeRedfish:
+		target, err := r.endpoints().Redfish(ctx, server)
+		if err != nil {
+			return power.BootOverride{}, err
+		}
+		return r.RedfishClient.GetBootDevice(target)
+
+	default:
+		return power.BootOverride{}, invalidSpec("boot policy and boot device require the ipmi or redfish control type")
+	}
+}
+
+// verifyBootOrder checks after a boot that the BMC still forces the boot
+// device or the last source of the boot policy persistently, and sets it
+// again if a firmware update or a change in the BIOS setup reset it. Servers
+// still working through the earlier, one-time sources are left alone.
+func (r *ServerReconciler) verifyBootOrder(ctx context.Context, server *baremetalcontrollerv1.Server) {
+	expected, ok := persistentBootSource(server)
+	if !ok {
+		return
+	}
+
+	setCondition := func(conditionStatus metav1.ConditionStatus, reason string, message string) {
+		meta.SetStatusCondition(&server.Status.Conditions, metav1.Condition{
+			Type:               baremetalcontrollerv1.ConditionBootOrderCorrected,
+			Status:             conditionStatus,
+			Reason:             reason,
+			Message:            message,
+			ObservedGeneration: server.Generation,
+		})
+	}
+
+	override, err := r.getBootDevice(ctx, server)
+	if err != nil {
+		setCondition(metav1.ConditionUnknown, "VerificationFailed", fmt.Sprintf("Reading the boot device: %v", err))
+		return
+	}
+	if override.Device == string(expected) && override.Persistent {
+		if meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionBootOrderCorrected) != nil {
+			setCondition(metav1.ConditionFalse, "Verified", fmt.Sprintf("Persistent boot device is %s", expected))
+		}
+		return
+	}
+
+	found := override.Device
+	if found == "" {
+		found = "none"
+	}
+	if !override.Persistent {
+		found += " (next boot only)"
+	}
+	// Left for the next boot while the circuit breaker is open
+	if r.powerActionsHalted(ctx, server, "boot device correction") {
+		setCondition(metav1.ConditionUnknown, "PowerActionsHalted",
+			fmt.Sprintf("Boot device is %s instead of %s, not corrected while power actions are halted", found, expected))
+		return
+	}
+	if err := r.setBootDevice(ctx, server, expected, true); err != nil {
+		setCondition(metav1.ConditionUnknown, "CorrectionFailed",
+			fmt.Sprintf("Boot device was %s instead of %s: %v", found, expected, err))
+		return
+	}
+	log.FromContext(ctx).Info("Corrected persistent boot device", "server", server.Name, "found", found, "expected", expected)
+	r.event(server, corev1.EventTypeWarning, "BootOrderCorrected",
+		"Persistent boot device was %s instead of %s, set it again", found, expected)
+	setCondition(metav1.ConditionTrue, "Corrected", fmt.Sprintf("Persistent boot device was %s, set to %s", found, expected))
+}
+
+// persistentBootSource returns the boot source the BMC should force
+// persistently after a boot, if any
+func persistentBootSource(server *baremetalcontrollerv1.Server) (baremetalcontrollerv1.BootSource, bool) {
+	if server.Spec.BootDevice != "" {
+		return server.Spec.BootDevice, true
+	}
+	policy := server.Spec.BootPolicy
+	status := server.Status.Boot
+	if policy == nil || len(policy.Sources) == 0 || status == nil ||
+		status.Completed < len(policy.Sources) || !sameBootSources(status.Sources, policy.Sources) {
+		return "", false
+	}
+	return policy.Sources[len(policy.Sources)-1], true
+}
+
+// completeBoot records a successful boot under the current boot policy
+func completeBoot(server *baremetalcontrollerv1.Server) {
+	status := server.Status.Boot
+	if server.Spec.BootPolicy == nil || status == nil || status.LastSource == "" ||
+		!sameBootSources(status.Sources, server.Spec.BootPolicy.Sources) {
+		return
+	}
+	if status.Completed < len(status.Sources) {
+		status.Completed++
+	}
+}
+
+func sameBootSources(a, b []baremetalcontrollerv1.BootSource) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
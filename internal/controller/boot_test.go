@@ -0,0 +1,135 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/breaker"
+	"github.com/Unbounder1/bare-metal-controller/internal/power"
+)
+
+func TestVerifyBootOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		halted     bool
+		device     string
+		persistent bool
+		wantDevice string
+		wantStatus metav1.ConditionStatus
+		wantReason string
+	}{
+		{name: "still set", device: "disk", persistent: true, wantDevice: "disk"},
+		{name: "reset by firmware", device: "bios", wantDevice: "disk", wantStatus: metav1.ConditionTrue, wantReason: "Corrected"},
+		// Setting the boot device is a BMC action the breaker halts too
+		{name: "circuit breaker open", halted: true, device: "bios", wantDevice: "bios", wantStatus: metav1.ConditionUnknown, wantReason: "PowerActionsHalted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := clientgoscheme.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			halted := "false"
+			if tt.halted {
+				halted = "true"
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "power-circuit-breaker"},
+				Data:       map[string]string{breaker.HaltedKey: halted, breaker.ReasonKey: "incident 42"},
+			}).Build()
+			b, err := breaker.New(c, breaker.Options{ConfigMap: "default/power-circuit-breaker"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ipmi := &power.MockIPMIClient{BootDevice: tt.device, BootPersistent: tt.persistent}
+			r := &ServerReconciler{Client: c, IPMIClient: ipmi, Breaker: b}
+
+			sources := []baremetalcontrollerv1.BootSource{baremetalcontrollerv1.BootSourcePXE, baremetalcontrollerv1.BootSourceDisk}
+			server := &baremetalcontrollerv1.Server{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker-01"},
+				Spec: baremetalcontrollerv1.ServerSpec{
+					Type:       baremetalcontrollerv1.ControlTypeIPMI,
+					Control:    baremetalcontrollerv1.ControlSpecs{IPMI: &baremetalcontrollerv1.IPMISpecs{Address: "10.0.1.11"}},
+					BootPolicy: &baremetalcontrollerv1.BootPolicySpec{Sources: sources},
+				},
+				Status: baremetalcontrollerv1.ServerStatus{
+					Boot: &baremetalcontrollerv1.BootStatus{Sources: sources, Completed: 2, LastSource: baremetalcontrollerv1.BootSourceDisk},
+				},
+			}
+			r.verifyBootOrder(context.Background(), server)
+
+			if ipmi.BootDevice != tt.wantDevice {
+				t.Errorf("boot device = %q, want %q", ipmi.BootDevice, tt.wantDevice)
+			}
+			condition := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionBootOrderCorrected)
+			if tt.wantReason == "" {
+				if condition != nil {
+					t.Errorf("condition = %+v, want none", condition)
+				}
+				return
+			}
+			if condition == nil || condition.Status != tt.wantStatus || condition.Reason != tt.wantReason {
+				t.Errorf("condition = %+v, want %s with reason %s", condition, tt.wantStatus, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestApplyBootDevice(t *testing.T) {
+	ipmi := &power.MockIPMIClient{}
+	r := &ServerReconciler{IPMIClient: ipmi}
+	server := &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-01"},
+		Spec: baremetalcontrollerv1.ServerSpec{
+			Type:       baremetalcontrollerv1.ControlTypeIPMI,
+			Control:    baremetalcontrollerv1.ControlSpecs{IPMI: &baremetalcontrollerv1.IPMISpecs{Address: "10.0.1.11", Username: "admin", Password: "secret"}},
+			BootDevice: baremetalcontrollerv1.BootSourcePXE,
+		},
+	}
+	if err := r.applyBootDevice(context.Background(), server); err != nil {
+		t.Fatalf("applyBootDevice() error = %v", err)
+	}
+	if ipmi.BootDevice != "pxe" || !ipmi.BootPersistent {
+		t.Errorf("boot device = %q, persistent %v, want pxe persistently", ipmi.BootDevice, ipmi.BootPersistent)
+	}
+	if server.Status.Boot == nil || server.Status.Boot.LastSource != baremetalcontrollerv1.BootSourcePXE {
+		t.Errorf("status.boot = %+v, want last source pxe", server.Status.Boot)
+	}
+
+	// Flipped back to disk once provisioned, and kept there after boots
+	server.Spec.BootDevice = baremetalcontrollerv1.BootSourceDisk
+	r.verifyBootOrder(context.Background(), server)
+	if ipmi.BootDevice != "disk" || !ipmi.BootPersistent {
+		t.Errorf("boot device = %q, persistent %v, want disk persistently", ipmi.BootDevice, ipmi.BootPersistent)
+	}
+
+	server.Spec.BootPolicy = &baremetalcontrollerv1.BootPolicySpec{Sources: []baremetalcontrollerv1.BootSource{baremetalcontrollerv1.BootSourceDisk}}
+	err := r.applyBootDevice(context.Background(), server)
+	if err == nil || powerFailureReason(server, baremetalcontrollerv1.PowerStateOn, err) != baremetalcontrollerv1.ReasonSpecInvalid {
+		t.Errorf("applyBootDevice() error = %v with a boot policy, want an invalid spec", err)
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+const (
+	// bootLogTailLines is how many boot log lines a boot timeout reports
+	bootLogTailLines = 20
+	// bootLogLineLength bounds each reported line, so the message stays
+	// well below the limit of condition messages
+	bootLogLineLength = 256
+)
+
+// appendBootLogTail adds the last lines the server logged while booting to
+// the message of a boot timeout, so why the boot hung shows on the Server
+// and its Ready condition
+func (r *ServerReconciler) appendBootLogTail(server *baremetalcontrollerv1.Server) {
+	if r.BootLogs == nil || server.Status.Reason != baremetalcontrollerv1.ReasonBootTimeout {
+		return
+	}
+	lines, _ := r.BootLogs.Lines(client.ObjectKeyFromObject(server))
+	if len(lines) == 0 {
+		return
+	}
+	if len(lines) > bootLogTailLines {
+		lines = lines[len(lines)-bootLogTailLines:]
+	}
+
+	var b strings.Builder
+	b.WriteString(server.Status.Message)
+	if b.Len() == 0 {
+		b.WriteString("Server did not come up")
+	}
+	b.WriteString("; last boot log lines:")
+	for _, line := range lines {
+		text := line.Text
+		if len(text) > bootLogLineLength {
+			text = text[:bootLogLineLength] + "..."
+		}
+		b.WriteString("\n")
+		b.WriteString(text)
+	}
+	server.Status.Message = b.String()
+}
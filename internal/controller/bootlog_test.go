@@ -0,0 +1,99 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/bootlog"
+)
+
+func TestAppendBootLogTail(t *testing.T) {
+	tests := []struct {
+		name    string
+		reason  baremetalcontrollerv1.FailureReason
+		message string
+		lines   int
+		long    bool
+		want    string
+	}{
+		{name: "no lines", reason: baremetalcontrollerv1.ReasonBootTimeout},
+		{name: "not a boot timeout", reason: baremetalcontrollerv1.ReasonBMCUnreachable, message: "BMC unreachable", lines: 2, want: "BMC unreachable"},
+		{
+			name:   "boot timeout",
+			reason: baremetalcontrollerv1.ReasonBootTimeout,
+			lines:  2,
+			want:   "Server did not come up; last boot log lines:\nline 1\nline 2",
+		},
+		{
+			name:    "after a message",
+			reason:  baremetalcontrollerv1.ReasonBootTimeout,
+			message: "Ping timed out",
+			lines:   1,
+			want:    "Ping timed out; last boot log lines:\nline 1",
+		},
+		{
+			name:   "long line",
+			reason: baremetalcontrollerv1.ReasonBootTimeout,
+			long:   true,
+			want:   "Server did not come up; last boot log lines:\n" + strings.Repeat("x", bootLogLineLength) + "...",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := bootlog.NewReceiver(bootlog.Options{Lines: 500}, nil)
+			for i := 1; i <= tt.lines; i++ {
+				logs.Record(client.ObjectKey{Name: "worker-01"}, fmt.Sprintf("line %d", i), false)
+			}
+			if tt.long {
+				logs.Record(client.ObjectKey{Name: "worker-01"}, strings.Repeat("x", 1000), false)
+			}
+			r := &ServerReconciler{BootLogs: logs}
+			server := &baremetalcontrollerv1.Server{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker-01"},
+				Status:     baremetalcontrollerv1.ServerStatus{Reason: tt.reason, Message: tt.message},
+			}
+			r.appendBootLogTail(server)
+			if server.Status.Message != tt.want {
+				t.Errorf("message = %q, want %q", server.Status.Message, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppendBootLogTailKeepsLastLines(t *testing.T) {
+	logs := bootlog.NewReceiver(bootlog.Options{Lines: 500}, nil)
+	for i := 1; i <= 50; i++ {
+		logs.Record(client.ObjectKey{Name: "worker-01"}, fmt.Sprintf("line %d", i), false)
+	}
+	r := &ServerReconciler{BootLogs: logs}
+	server := &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-01"},
+		Status:     baremetalcontrollerv1.ServerStatus{Reason: baremetalcontrollerv1.ReasonBootTimeout},
+	}
+	r.appendBootLogTail(server)
+	lines := strings.Split(server.Status.Message, "\n")
+	if len(lines) != bootLogTailLines+1 || lines[1] != "line 31" || lines[bootLogTailLines] != "line 50" {
+		t.Errorf("message = %q, want lines 31 to 50", server.Status.Message)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+// breakerRetryInterval is how often a server held by the circuit breaker
+// checks it again
+const breakerRetryInterval = 30 * time.Second
+
+// holdForBreaker keeps a server in its power state while the circuit breaker
+// halts power actions, and clears the condition once the server no longer
+// waits. It returns true while the server has to wait.
+func (r *ServerReconciler) holdForBreaker(ctx context.Context, server *baremetalcontrollerv1.Server, currentState baremetalcontrollerv1.PowerState) (bool, error) {
+	if server.Spec.PowerState.Settled() == currentState && !rebootDue(server) {
+		if meta.RemoveStatusCondition(&server.Status.Conditions, baremetalcontrollerv1.ConditionPowerActionsHalted) {
+			r.updateStatus(ctx, server)
+		}
+		return false, nil
+	}
+
+	halted, reason, err := r.Breaker.Halted(ctx)
+	if err != nil {
+		return false, err
+	}
+	if !halted {
+		// The power action that follows updates the status
+		meta.RemoveStatusCondition(&server.Status.Conditions, baremetalcontrollerv1.ConditionPowerActionsHalted)
+		return false, nil
+	}
+
+	if !meta.IsStatusConditionTrue(server.Status.Conditions, baremetalcontrollerv1.ConditionPowerActionsHalted) {
+		log.FromContext(ctx).Info("Holding power action", "server", server.Name, "powerState", server.Spec.PowerState, "reason", reason)
+		r.event(server, corev1.EventTypeWarning, "PowerActionsHalted", "Holding power %s: %s", server.Spec.PowerState, reason)
+	}
+	meta.SetStatusCondition(&server.Status.Conditions, metav1.Condition{
+		Type:               baremetalcontrollerv1.ConditionPowerActionsHalted,
+		Status:             metav1.ConditionTrue,
+		Reason:             "CircuitBreakerOpen",
+		Message:            reason,
+		ObservedGeneration: server.Generation,
+	})
+	r.updateStatus(ctx, server)
+	return true, nil
+}
+
+// powerActionsHalted reports whether the circuit breaker halts power actions
+// the controller takes on its own, logging why
+func (r *ServerReconciler) powerActionsHalted(ctx context.Context, server *baremetalcontrollerv1.Server, action string) bool {
+	halted, reason, err := r.Breaker.Halted(ctx)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to check circuit breaker", "server", server.Name)
+	}
+	if halted {
+		log.FromContext(ctx).Info("Skipping "+action+" while power actions are halted", "server", server.Name, "reason", reason)
+	}
+	return halted
+}
@@ -0,0 +1,266 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/calendar"
+)
+
+const (
+	defaultCalendarRefreshInterval = time.Hour
+	// calendarHorizon is how far ahead events are imported
+	calendarHorizon = 90 * 24 * time.Hour
+	// maxCalendarEvents keeps the status of busy calendars small
+	maxCalendarEvents = 500
+	// maxCalendarSize is the largest feed read
+	maxCalendarSize = 10 << 20
+)
+
+// CalendarReconciler imports the events of Calendars into their status,
+// where maintenance windows, hibernation and change freezes read them.
+type CalendarReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// HTTPClient fetches the feeds (defaults to one with a 30s timeout)
+	HTTPClient *http.Client
+
+	// Now returns the current time, for the import horizon
+	Now func() time.Time
+}
+
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=calendars,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=calendars/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=calendars/finalizers,verbs=update
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+
+// Reconcile fetches the feed of a Calendar once per refresh interval and
+// records its upcoming events. Events of the last successful import are kept
+// while the feed can't be fetched.
+func (r *CalendarReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var cal baremetalcontrollerv1.Calendar
+	if err := r.Get(ctx, req.NamespacedName, &cal); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	refresh := defaultCalendarRefreshInterval
+	if cal.Spec.RefreshInterval != nil {
+		refresh = cal.Spec.RefreshInterval.Duration
+	}
+	now := r.now()
+	ready := meta.FindStatusCondition(cal.Status.Conditions, baremetalcontrollerv1.ConditionReady)
+	if ready != nil && ready.ObservedGeneration == cal.Generation && cal.Status.LastSyncTime != nil {
+		if elapsed := now.Sub(cal.Status.LastSyncTime.Time); elapsed < refresh {
+			return ctrl.Result{RequeueAfter: refresh - elapsed}, nil
+		}
+	}
+
+	events, skipped, err := r.importEvents(ctx, &cal, now)
+	condition := metav1.Condition{
+		Type:               baremetalcontrollerv1.ConditionReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Synced",
+		Message:            fmt.Sprintf("Imported %d events", len(events)),
+		ObservedGeneration: cal.Generation,
+	}
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to import calendar", "calendar", cal.Name)
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "SyncFailed"
+		condition.Message = err.Error()
+	} else {
+		cal.Status.Events = events
+		cal.Status.Skipped = int32(skipped)
+	}
+	synced := metav1.NewTime(now)
+	cal.Status.LastSyncTime = &synced
+	meta.SetStatusCondition(&cal.Status.Conditions, condition)
+	if err := r.Status().Update(ctx, &cal); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: refresh}, nil
+}
+
+// importEvents reads the feed and dates of a Calendar and returns the events
+// that haven't ended yet and start within the horizon
+func (r *CalendarReconciler) importEvents(ctx context.Context, cal *baremetalcontrollerv1.Calendar, now time.Time) ([]baremetalcontrollerv1.CalendarEvent, int, error) {
+	loc := time.UTC
+	if cal.Spec.TimeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(cal.Spec.TimeZone); err != nil {
+			return nil, 0, fmt.Errorf("invalid time zone: %w", err)
+		}
+	}
+	until := now.Add(calendarHorizon)
+
+	dates := make([]string, 0, len(cal.Spec.Dates))
+	for _, date := range cal.Spec.Dates {
+		dates = append(dates, string(date))
+	}
+	events, err := calendar.Dates(dates, cal.Name, loc, now, until)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	skipped := 0
+	if cal.Spec.URL != "" {
+		body, err := r.fetch(ctx, cal)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer body.Close()
+		feed, n, err := calendar.Parse(io.LimitReader(body, maxCalendarSize), loc, now, until)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to parse %s: %w", cal.Spec.URL, err)
+		}
+		events = append(events, feed...)
+		skipped = n
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+	if len(events) > maxCalendarEvents {
+		events = events[:maxCalendarEvents]
+	}
+	imported := make([]baremetalcontrollerv1.CalendarEvent, 0, len(events))
+	for _, event := range events {
+		imported = append(imported, baremetalcontrollerv1.CalendarEvent{
+			Summary: event.Summary,
+			Start:   metav1.NewTime(event.Start.UTC()),
+			End:     metav1.NewTime(event.End.UTC()),
+		})
+	}
+	return imported, skipped, nil
+}
+
+// fetch requests the feed, with the credentials of the secret if any
+func (r *CalendarReconciler) fetch(ctx context.Context, cal *baremetalcontrollerv1.Calendar) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cal.Spec.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if ref := cal.Spec.SecretRef; ref != nil {
+		var secret corev1.Secret
+		if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: ref.Namespace}, &secret); err != nil {
+			return nil, fmt.Errorf("failed to get secret %s/%s: %w", ref.Namespace, ref.Name, err)
+		}
+		req.SetBasicAuth(string(secret.Data["username"]), string(secret.Data["password"]))
+	}
+
+	httpClient := r.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", req.URL.Host, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s returned status %d", req.URL.Host, resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+func (r *CalendarReconciler) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+// loadCalendars returns the events of the named Calendars. Empty names are
+// ignored.
+func loadCalendars(ctx context.Context, c client.Reader, names ...string) (map[string][]baremetalcontrollerv1.CalendarEvent, error) {
+	calendars := map[string][]baremetalcontrollerv1.CalendarEvent{}
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if _, ok := calendars[name]; ok {
+			continue
+		}
+		var cal baremetalcontrollerv1.Calendar
+		if err := c.Get(ctx, types.NamespacedName{Name: name}, &cal); err != nil {
+			return nil, fmt.Errorf("failed to get calendar %s: %w", name, err)
+		}
+		calendars[name] = cal.Status.Events
+	}
+	return calendars, nil
+}
+
+// inCalendar reports whether now is during one of the events, and how long
+// until that changes, or 0 if no event starts or ends later
+func inCalendar(events []baremetalcontrollerv1.CalendarEvent, now time.Time) (bool, time.Duration) {
+	inside := false
+	next := time.Duration(0)
+	earlier := func(d time.Duration) {
+		if d > 0 && (next == 0 || d < next) {
+			next = d
+		}
+	}
+	for _, event := range events {
+		if event.Start.After(now) {
+			earlier(event.Start.Sub(now))
+			continue
+		}
+		if event.End.After(now) {
+			inside = true
+			earlier(event.End.Sub(now))
+		}
+	}
+	if !inside {
+		return false, next
+	}
+
+	// Overlapping events keep it going until the last one ends
+	end := now.Add(next)
+	for extended := true; extended; {
+		extended = false
+		for _, event := range events {
+			if !event.Start.After(end) && event.End.After(end) {
+				end = event.End.Time
+				extended = true
+			}
+		}
+	}
+	return true, end.Sub(now)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *CalendarReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&baremetalcontrollerv1.Calendar{}).
+		Named("calendar").
+		Complete(r)
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+const changeFreezeFeed = `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Example//Change Freeze//EN
+BEGIN:VEVENT
+UID:freeze-1
+SUMMARY:Q2 close\, change freeze
+DTSTART;TZID=Europe/Berlin:20250627T180000
+DTEND;TZID=Europe/Berlin:20250630T080000
+BEGIN:VALARM
+TRIGGER:-PT1H
+DURATION:PT5M
+ACTION:DISPLAY
+END:VALARM
+END:VEVENT
+BEGIN:VEVENT
+UID:standup
+SUMMARY:Weekly release
+DTSTART:20250602T120000Z
+DURATION:PT1H
+RRULE:FREQ=WEEKLY;COUNT=3
+END:VEVENT
+BEGIN:VEVENT
+UID:weekdays
+SUMMARY:Office hours
+DTSTART:20250602T080000Z
+DURATION:PT8H
+RRULE:FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR
+END:VEVENT
+BEGIN:VEVENT
+UID:past
+SUMMARY:Last year
+DTSTART;VALUE=DATE:20240101
+END:VEVENT
+END:VCALENDAR
+`
+
+var _ = Describe("Calendar Controller", func() {
+	const calendarName = "test-calendar"
+
+	var (
+		ctx        context.Context
+		reconciler *CalendarReconciler
+		feed       *httptest.Server
+		now        time.Time
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		now = time.Date(2025, time.June, 7, 3, 0, 0, 0, time.UTC)
+		feed = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(changeFreezeFeed))
+		}))
+		reconciler = &CalendarReconciler{
+			Client:     k8sClient,
+			Scheme:     k8sClient.Scheme(),
+			HTTPClient: feed.Client(),
+			Now:        func() time.Time { return now },
+		}
+
+		cal := &baremetalcontrollerv1.Calendar{
+			ObjectMeta: metav1.ObjectMeta{Name: calendarName},
+			Spec: baremetalcontrollerv1.CalendarSpec{
+				URL:      feed.URL,
+				Dates:    []baremetalcontrollerv1.CalendarDate{"2025-06-09"},
+				TimeZone: "Europe/Berlin",
+			},
+		}
+		Expect(k8sClient.Create(ctx, cal)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		feed.Close()
+		cal := &baremetalcontrollerv1.Calendar{}
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: calendarName}, cal); err == nil {
+			Expect(k8sClient.Delete(ctx, cal)).To(Succeed())
+		}
+	})
+
+	It("should import upcoming events and expand simple recurrences", func() {
+		result, err := reconciler.Reconcile(ctx, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: calendarName},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(time.Hour))
+
+		cal := &baremetalcontrollerv1.Calendar{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: calendarName}, cal)).To(Succeed())
+		Expect(cal.Status.Skipped).To(Equal(int32(1)))
+		ready := meta.FindStatusCondition(cal.Status.Conditions, baremetalcontrollerv1.ConditionReady)
+		Expect(ready).NotTo(BeNil())
+		Expect(ready.Status).To(Equal(metav1.ConditionTrue))
+
+		var summaries []string
+		for _, event := range cal.Status.Events {
+			summaries = append(summaries, event.Summary)
+		}
+		// The first weekly release is over, and the holiday is a Berlin day
+		Expect(summaries).To(Equal([]string{calendarName, "Weekly release", "Weekly release", "Q2 close, change freeze"}))
+		Expect(cal.Status.Events[0].Start.UTC()).To(Equal(time.Date(2025, time.June, 8, 22, 0, 0, 0, time.UTC)))
+		Expect(cal.Status.Events[3].Start.UTC()).To(Equal(time.Date(2025, time.June, 27, 16, 0, 0, 0, time.UTC)))
+		Expect(cal.Status.Events[3].End.UTC()).To(Equal(time.Date(2025, time.June, 30, 6, 0, 0, 0, time.UTC)))
+	})
+
+	It("should keep the last events while the feed can't be fetched", func() {
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: calendarName},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		feed.Close()
+		now = now.Add(2 * time.Hour)
+		_, err = reconciler.Reconcile(ctx, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: calendarName},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		cal := &baremetalcontrollerv1.Calendar{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: calendarName}, cal)).To(Succeed())
+		Expect(cal.Status.Events).To(HaveLen(4))
+		ready := meta.FindStatusCondition(cal.Status.Conditions, baremetalcontrollerv1.ConditionReady)
+		Expect(ready).NotTo(BeNil())
+		Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+		Expect(ready.Reason).To(Equal("SyncFailed"))
+	})
+})
@@ -0,0 +1,49 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/cluster"
+)
+
+// nodeClients returns the client and reader for the node of a server: the
+// given ones of the controller's cluster, or the client of the cluster the
+// server's spec.clusterRef names, whose kubeconfig Secret must be in the
+// namespace of the server's tenant
+func nodeClients(ctx context.Context, clusters *cluster.Clients, server *baremetalcontrollerv1.Server, local client.Client, reader client.Reader) (client.Client, client.Reader, error) {
+	ref := server.Spec.ClusterRef
+	if ref == nil {
+		return local, reader, nil
+	}
+	if clusters == nil {
+		return nil, nil, fmt.Errorf("server %s joins cluster %s, but no cluster clients are configured", server.Name, ref.Name)
+	}
+	if err := server.CheckSecretRef(&ref.KubeconfigSecretRef); err != nil {
+		return nil, nil, invalidSpec("%v", err)
+	}
+	c, err := clusters.Get(ctx, server)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, c, nil
+}
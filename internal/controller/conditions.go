@@ -0,0 +1,161 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+// updateStatus applies the status after deriving the Ready and Degraded
+// conditions and the wear counters from it, so they never disagree with status.status. Failures are
+// logged, since callers carry on with the next reconcile either way.
+// status.provisioning is owned by TinkerbellReconciler, status.addresses by
+// the DHCP lease tracker and status.nodeFeatures by NodeFeatureReconciler, so
+// they are left out.
+func (r *ServerReconciler) updateStatus(ctx context.Context, server *baremetalcontrollerv1.Server) error {
+	setReadyCondition(server)
+	setDegradedCondition(server)
+	trackWear(server, time.Now())
+
+	err := upgradeStatusManagedFields(ctx, r.Client, server, serverFieldManager)
+	if err == nil {
+		status := *server.Status.DeepCopy()
+		status.Provisioning = nil
+		status.Addresses = nil
+		status.NodeFeatures = nil
+		err = applyServerStatus(ctx, r.Client, server, status, serverFieldManager)
+	}
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to apply server status", "server", server.Name)
+	}
+	return err
+}
+
+// setReadyCondition mirrors status.status into the Ready condition, which is
+// what kubectl wait --for=condition=Ready checks. The failure reason, if any,
+// is used as the condition reason.
+func setReadyCondition(server *baremetalcontrollerv1.Server) {
+	status := metav1.ConditionFalse
+	if server.Status.Status == baremetalcontrollerv1.StatusActive {
+		status = metav1.ConditionTrue
+	}
+
+	reason := "Unknown"
+	if server.Status.Reason != "" {
+		reason = string(server.Status.Reason)
+	} else if server.Status.Status != "" {
+		reason = strings.ToUpper(string(server.Status.Status[:1])) + string(server.Status.Status[1:])
+	}
+
+	meta.SetStatusCondition(&server.Status.Conditions, metav1.Condition{
+		Type:               baremetalcontrollerv1.ConditionReady,
+		Status:             status,
+		Reason:             reason,
+		Message:            server.Status.Message,
+		ObservedGeneration: server.Generation,
+	})
+}
+
+// degradingConditions are the conditions that mark a server degraded while
+// they are true, in the order their reason is picked
+var degradingConditions = []string{
+	baremetalcontrollerv1.ConditionHardwareFault,
+	baremetalcontrollerv1.ConditionThermalCritical,
+	baremetalcontrollerv1.ConditionPowerDrift,
+	baremetalcontrollerv1.ConditionFirmwareDrift,
+}
+
+// setDegradedCondition sums up status.status and the conditions reporting
+// problems into the Degraded condition, which health checks such as Argo
+// CD's can read without knowing every condition of a server
+func setDegradedCondition(server *baremetalcontrollerv1.Server) {
+	condition := metav1.Condition{
+		Type:               baremetalcontrollerv1.ConditionDegraded,
+		Status:             metav1.ConditionFalse,
+		Reason:             "AsExpected",
+		ObservedGeneration: server.Generation,
+	}
+	if server.Status.Status == baremetalcontrollerv1.StatusFailed {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "Failed"
+		if server.Status.Reason != "" {
+			condition.Reason = string(server.Status.Reason)
+		}
+		condition.Message = server.Status.Message
+	} else {
+		for _, conditionType := range degradingConditions {
+			if c := meta.FindStatusCondition(server.Status.Conditions, conditionType); c != nil && c.Status == metav1.ConditionTrue {
+				condition.Status = metav1.ConditionTrue
+				condition.Reason = conditionType
+				condition.Message = c.Message
+				break
+			}
+		}
+	}
+	meta.SetStatusCondition(&server.Status.Conditions, condition)
+}
+
+// setReachableCondition records the result of the reachability check in the
+// Reachable condition. It returns true if the condition changed.
+func setReachableCondition(server *baremetalcontrollerv1.Server, address string, reachable bool) bool {
+	condition := metav1.Condition{
+		Type:               baremetalcontrollerv1.ConditionReachable,
+		Status:             metav1.ConditionTrue,
+		Reason:             "PingSucceeded",
+		Message:            fmt.Sprintf("%s answers pings", address),
+		ObservedGeneration: server.Generation,
+	}
+	switch {
+	case address == "":
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "NoAddress"
+		condition.Message = "No address is known for the server yet"
+	case !reachable:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "PingFailed"
+		condition.Message = fmt.Sprintf("%s does not answer pings", address)
+	}
+	return meta.SetStatusCondition(&server.Status.Conditions, condition)
+}
+
+// setPowerActionCondition records the result of a power action in the
+// PowerActionSucceeded condition
+func setPowerActionCondition(server *baremetalcontrollerv1.Server, action baremetalcontrollerv1.PowerState, err error) {
+	_, done := describeAction(action)
+	condition := metav1.Condition{
+		Type:               baremetalcontrollerv1.ConditionPowerActionSucceeded,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Succeeded",
+		Message:            done,
+		ObservedGeneration: server.Generation,
+	}
+	if err != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = string(server.Status.Reason)
+		condition.Message = err.Error()
+	}
+	meta.SetStatusCondition(&server.Status.Conditions, condition)
+}
@@ -0,0 +1,223 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+func TestSetReadyCondition(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     baremetalcontrollerv1.ServerStatus
+		wantStatus metav1.ConditionStatus
+		wantReason string
+	}{
+		{
+			name:       "active",
+			status:     baremetalcontrollerv1.ServerStatus{Status: baremetalcontrollerv1.StatusActive},
+			wantStatus: metav1.ConditionTrue,
+			wantReason: "Active",
+		},
+		{
+			name:       "pending",
+			status:     baremetalcontrollerv1.ServerStatus{Status: baremetalcontrollerv1.StatusPending},
+			wantStatus: metav1.ConditionFalse,
+			wantReason: "Pending",
+		},
+		{
+			name:       "offline",
+			status:     baremetalcontrollerv1.ServerStatus{Status: baremetalcontrollerv1.StatusOffline},
+			wantStatus: metav1.ConditionFalse,
+			wantReason: "Offline",
+		},
+		{
+			name: "failed with a reason",
+			status: baremetalcontrollerv1.ServerStatus{
+				Status:  baremetalcontrollerv1.StatusFailed,
+				Reason:  baremetalcontrollerv1.ReasonWOLSendFailed,
+				Message: "network error",
+			},
+			wantStatus: metav1.ConditionFalse,
+			wantReason: string(baremetalcontrollerv1.ReasonWOLSendFailed),
+		},
+		{
+			name:       "not reconciled yet",
+			wantStatus: metav1.ConditionFalse,
+			wantReason: "Unknown",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &baremetalcontrollerv1.Server{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker-01", Generation: 3},
+				Status:     tt.status,
+			}
+			setReadyCondition(server)
+
+			ready := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionReady)
+			if ready == nil {
+				t.Fatalf("no Ready condition")
+			}
+			if ready.Status != tt.wantStatus || ready.Reason != tt.wantReason {
+				t.Errorf("Ready = %s/%s, want %s/%s", ready.Status, ready.Reason, tt.wantStatus, tt.wantReason)
+			}
+			if ready.Message != tt.status.Message {
+				t.Errorf("Ready message = %q, want %q", ready.Message, tt.status.Message)
+			}
+			if ready.ObservedGeneration != 3 {
+				t.Errorf("Ready observedGeneration = %d, want 3", ready.ObservedGeneration)
+			}
+		})
+	}
+}
+
+func TestSetReadyConditionKeepsTransitionTime(t *testing.T) {
+	server := &baremetalcontrollerv1.Server{Status: baremetalcontrollerv1.ServerStatus{Status: baremetalcontrollerv1.StatusActive}}
+	setReadyCondition(server)
+	transition := metav1.NewTime(server.Status.Conditions[0].LastTransitionTime.Add(-time.Hour))
+	server.Status.Conditions[0].LastTransitionTime = transition
+
+	// kubectl wait and alerts rely on the time the server became ready
+	setReadyCondition(server)
+	if got := server.Status.Conditions[0].LastTransitionTime; !got.Equal(&transition) {
+		t.Errorf("lastTransitionTime = %s, want %s unchanged", got, transition)
+	}
+
+	server.Status.Status = baremetalcontrollerv1.StatusOffline
+	setReadyCondition(server)
+	if got := server.Status.Conditions[0].LastTransitionTime; got.Equal(&transition) {
+		t.Errorf("lastTransitionTime unchanged after the server went offline")
+	}
+	if len(server.Status.Conditions) != 1 {
+		t.Errorf("conditions = %v, want a single Ready condition", server.Status.Conditions)
+	}
+}
+
+func TestSetDegradedCondition(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      baremetalcontrollerv1.ServerStatus
+		wantStatus  metav1.ConditionStatus
+		wantReason  string
+		wantMessage string
+	}{
+		{
+			name:       "active",
+			status:     baremetalcontrollerv1.ServerStatus{Status: baremetalcontrollerv1.StatusActive},
+			wantStatus: metav1.ConditionFalse,
+			wantReason: "AsExpected",
+		},
+		{
+			name: "failed with a reason",
+			status: baremetalcontrollerv1.ServerStatus{
+				Status:  baremetalcontrollerv1.StatusFailed,
+				Reason:  baremetalcontrollerv1.ReasonBMCAuthFailed,
+				Message: "password rejected",
+			},
+			wantStatus:  metav1.ConditionTrue,
+			wantReason:  string(baremetalcontrollerv1.ReasonBMCAuthFailed),
+			wantMessage: "password rejected",
+		},
+		{
+			name: "hardware fault ahead of firmware drift",
+			status: baremetalcontrollerv1.ServerStatus{
+				Status: baremetalcontrollerv1.StatusActive,
+				Conditions: []metav1.Condition{
+					{Type: baremetalcontrollerv1.ConditionFirmwareDrift, Status: metav1.ConditionTrue, Message: "BIOS 1.2, want 1.3"},
+					{Type: baremetalcontrollerv1.ConditionHardwareFault, Status: metav1.ConditionTrue, Message: "PowerSupply sensor 0x01: input lost"},
+				},
+			},
+			wantStatus:  metav1.ConditionTrue,
+			wantReason:  baremetalcontrollerv1.ConditionHardwareFault,
+			wantMessage: "PowerSupply sensor 0x01: input lost",
+		},
+		{
+			name: "resolved drift",
+			status: baremetalcontrollerv1.ServerStatus{
+				Status: baremetalcontrollerv1.StatusActive,
+				Conditions: []metav1.Condition{
+					{Type: baremetalcontrollerv1.ConditionPowerDrift, Status: metav1.ConditionFalse},
+				},
+			},
+			wantStatus: metav1.ConditionFalse,
+			wantReason: "AsExpected",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &baremetalcontrollerv1.Server{Status: tt.status}
+			setDegradedCondition(server)
+
+			degraded := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionDegraded)
+			if degraded == nil {
+				t.Fatalf("no Degraded condition")
+			}
+			if degraded.Status != tt.wantStatus || degraded.Reason != tt.wantReason || degraded.Message != tt.wantMessage {
+				t.Errorf("Degraded = %s/%s %q, want %s/%s %q", degraded.Status, degraded.Reason, degraded.Message,
+					tt.wantStatus, tt.wantReason, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestSetReachableCondition(t *testing.T) {
+	server := &baremetalcontrollerv1.Server{}
+	if !setReachableCondition(server, "", false) {
+		t.Error("setReachableCondition() = false for a new condition")
+	}
+	if c := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionReachable); c.Reason != "NoAddress" {
+		t.Errorf("Reachable reason = %s, want NoAddress", c.Reason)
+	}
+
+	setReachableCondition(server, "10.0.0.5", true)
+	if !meta.IsStatusConditionTrue(server.Status.Conditions, baremetalcontrollerv1.ConditionReachable) {
+		t.Error("Reachable is not true for a server that answers")
+	}
+	if setReachableCondition(server, "10.0.0.5", true) {
+		t.Error("setReachableCondition() = true for an unchanged result")
+	}
+
+	setReachableCondition(server, "10.0.0.5", false)
+	c := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionReachable)
+	if c.Status != metav1.ConditionFalse || c.Reason != "PingFailed" {
+		t.Errorf("Reachable = %s/%s, want False/PingFailed", c.Status, c.Reason)
+	}
+}
+
+func TestSetPowerActionCondition(t *testing.T) {
+	server := &baremetalcontrollerv1.Server{}
+	setPowerActionCondition(server, baremetalcontrollerv1.PowerStateReboot, nil)
+	c := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionPowerActionSucceeded)
+	if c.Status != metav1.ConditionTrue || c.Reason != "Succeeded" || c.Message != "Rebooted" {
+		t.Errorf("PowerActionSucceeded = %s/%s %q, want True/Succeeded \"Rebooted\"", c.Status, c.Reason, c.Message)
+	}
+
+	server.Status.Reason = baremetalcontrollerv1.ReasonBMCUnreachable
+	setPowerActionCondition(server, baremetalcontrollerv1.PowerStateOff, errors.New("no route to host"))
+	c = meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionPowerActionSucceeded)
+	if c.Status != metav1.ConditionFalse || c.Reason != string(baremetalcontrollerv1.ReasonBMCUnreachable) || c.Message != "no route to host" {
+		t.Errorf("PowerActionSucceeded = %s/%s %q, want False/BMCUnreachable", c.Status, c.Reason, c.Message)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/listing"
+)
+
+// serversForSecret maps a Secret to the IPMI servers that read their
+// credentials from it, so rotated credentials are picked up right away
+func (r *ServerReconciler) serversForSecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	var requests []reconcile.Request
+	_ = listing.Servers(ctx, r, 0, func(server *baremetalcontrollerv1.Server) error {
+		ipmi := server.Spec.Control.IPMI
+		if ipmi == nil || ipmi.CredentialsSecretRef == nil {
+			return nil
+		}
+		if ref := ipmi.CredentialsSecretRef; ref.Name == obj.GetName() && ref.Namespace == obj.GetNamespace() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: client.ObjectKeyFromObject(server),
+			})
+		}
+		return nil
+	})
+	return requests
+}
+
+// recoverCredentials retries an IPMI server that failed because its
+// credentials Secret was missing or the BMC rejected its credentials. It
+// runs when the server is reconciled, e.g. because its Secret changed, and
+// not on a timer, so a BMC isn't locked out by retries with bad
+// credentials. Once the BMC takes the credentials the failure is cleared
+// and true is returned.
+func (r *ServerReconciler) recoverCredentials(ctx context.Context, server *baremetalcontrollerv1.Server) bool {
+	if server.Spec.Type != baremetalcontrollerv1.ControlTypeIPMI ||
+		server.Status.Reason != baremetalcontrollerv1.ReasonSecretMissing &&
+			server.Status.Reason != baremetalcontrollerv1.ReasonBMCAuthFailed {
+		return false
+	}
+	target, err := r.endpoints().IPMI(ctx, server)
+	if err != nil {
+		return false
+	}
+	if _, err := r.IPMIClient.GetPowerStatus(ctx, target.Address, target.Username, target.Password); err != nil {
+		return false
+	}
+	r.event(server, corev1.EventTypeNormal, "CredentialsAccepted", "BMC at %s accepts the IPMI credentials", target.Address)
+	r.clearFailure(server, "")
+	r.updateStatus(ctx, server)
+	return true
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+func credentialsScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := baremetalcontrollerv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return scheme
+}
+
+func ipmiServer(name string, ipmi *baremetalcontrollerv1.IPMISpecs) *baremetalcontrollerv1.Server {
+	return &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: baremetalcontrollerv1.ServerSpec{
+			Type:    baremetalcontrollerv1.ControlTypeIPMI,
+			Control: baremetalcontrollerv1.ControlSpecs{IPMI: ipmi},
+		},
+	}
+}
+
+func TestServersForSecret(t *testing.T) {
+	ref := &baremetalcontrollerv1.CredentialsSecretReference{
+		SecretReference: baremetalcontrollerv1.SecretReference{Name: "bmc", Namespace: "infra"},
+	}
+	c := fake.NewClientBuilder().WithScheme(credentialsScheme(t)).WithObjects(
+		ipmiServer("worker-01", &baremetalcontrollerv1.IPMISpecs{Address: "10.0.1.11", CredentialsSecretRef: ref}),
+		ipmiServer("worker-02", &baremetalcontrollerv1.IPMISpecs{Address: "10.0.1.12", Username: "root", Password: "calvin"}),
+	).Build()
+	r := &ServerReconciler{Client: c}
+
+	requests := r.serversForSecret(context.Background(), &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "bmc", Namespace: "infra"}})
+	if len(requests) != 1 || requests[0].Name != "worker-01" {
+		t.Errorf("requests = %v, want worker-01", requests)
+	}
+	requests = r.serversForSecret(context.Background(), &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "bmc", Namespace: "default"}})
+	if len(requests) != 0 {
+		t.Errorf("requests = %v, want none for a secret of another namespace", requests)
+	}
+}
+
+func TestServersForSecretNamespaced(t *testing.T) {
+	ref := &baremetalcontrollerv1.CredentialsSecretReference{
+		SecretReference: baremetalcontrollerv1.SecretReference{Name: "bmc", Namespace: "team-a"},
+	}
+	server := ipmiServer("worker-01", &baremetalcontrollerv1.IPMISpecs{Address: "10.0.1.11", CredentialsSecretRef: ref})
+	server.Namespace = "team-a"
+	c := fake.NewClientBuilder().WithScheme(credentialsScheme(t)).WithObjects(server).Build()
+	r := &ServerReconciler{Client: c}
+
+	requests := r.serversForSecret(context.Background(), &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "bmc", Namespace: "team-a"}})
+	if len(requests) != 1 || requests[0].Namespace != "team-a" || requests[0].Name != "worker-01" {
+		t.Errorf("requests = %v, want team-a/worker-01", requests)
+	}
+}
+
+func TestGetSSHCredentials(t *testing.T) {
+	secret := func(name string, data map[string]string) *corev1.Secret {
+		s := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "infra"}, Data: map[string][]byte{}}
+		for k, v := range data {
+			s.Data[k] = []byte(v)
+		}
+		return s
+	}
+	c := fake.NewClientBuilder().WithScheme(credentialsScheme(t)).WithObjects(
+		secret("ssh", map[string]string{"username": "core", "ssh-privatekey": "KEY"}),
+		secret("key-only", map[string]string{"ssh-privatekey": "KEY"}),
+		secret("user-only", map[string]string{"username": "core"}),
+	).Build()
+	r := &ServerReconciler{Client: c}
+
+	tests := []struct {
+		name       string
+		user       string
+		secret     string
+		wantUser   string
+		wantReason baremetalcontrollerv1.FailureReason
+		wantErr    string
+	}{
+		{name: "user of the secret", secret: "ssh", wantUser: "core"},
+		{name: "user of the spec wins", user: "root", secret: "ssh", wantUser: "root"},
+		{name: "user of the spec only", user: "root", secret: "key-only", wantUser: "root"},
+		{name: "no user", secret: "key-only", wantReason: baremetalcontrollerv1.ReasonSpecInvalid, wantErr: "SSH user is required"},
+		{name: "no key", user: "root", secret: "user-only", wantReason: baremetalcontrollerv1.ReasonSecretMissing, wantErr: "ssh-privatekey not found in secret infra/user-only"},
+		{name: "no secret", user: "root", secret: "missing", wantReason: baremetalcontrollerv1.ReasonSecretMissing, wantErr: "failed to get secret infra/missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &baremetalcontrollerv1.Server{ObjectMeta: metav1.ObjectMeta{Name: "worker-01"}}
+			ref := &baremetalcontrollerv1.SecretReference{Name: tt.secret, Namespace: "infra"}
+			user, key, err := r.getSSHCredentials(context.Background(), server, tt.user, ref)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("getSSHCredentials() error = %v, want %q", err, tt.wantErr)
+				}
+				if reason := powerFailureReason(server, baremetalcontrollerv1.PowerStateOff, err); reason != tt.wantReason {
+					t.Errorf("reason = %q, want %q", reason, tt.wantReason)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("getSSHCredentials() error = %v", err)
+			}
+			if user != tt.wantUser || key != "KEY" {
+				t.Errorf("getSSHCredentials() = %q, %q, want %q, KEY", user, key, tt.wantUser)
+			}
+		})
+	}
+}
@@ -0,0 +1,329 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/cluster"
+	"github.com/Unbounder1/bare-metal-controller/internal/index"
+)
+
+const (
+	defaultCSRBootWindow = 30 * time.Minute
+	csrPollInterval      = 15 * time.Second
+	nodeUserPrefix       = "system:node:"
+	nodesGroup           = "system:nodes"
+	bootstrappersGroup   = "system:bootstrappers"
+)
+
+// CSRReconciler approves the kubelet client and serving certificate
+// requests of servers the controller just booted, instead of approving every
+// kubelet request. Requests it can't tie to such a server are left pending
+// for an administrator or another approver. The requests of the workload
+// clusters servers reference are polled, since they can't be watched.
+type CSRReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// BootWindow is how long after a server turned active its kubelet's
+	// requests are approved (default 30m)
+	BootWindow time.Duration
+
+	// Clusters reaches the workload clusters of servers with
+	// spec.clusterRef. Their requests aren't reviewed if nil.
+	Clusters *cluster.Clients
+
+	// NamespacedServers is set when Servers are namespaced, so the server
+	// of a node is looked up by name in every namespace
+	NamespacedServers bool
+}
+
+// +kubebuilder:rbac:groups=certificates.k8s.io,resources=certificatesigningrequests,verbs=get;list;watch
+// +kubebuilder:rbac:groups=certificates.k8s.io,resources=certificatesigningrequests/approval,verbs=update
+// +kubebuilder:rbac:groups=certificates.k8s.io,resources=signers,resourceNames=kubernetes.io/kube-apiserver-client-kubelet;kubernetes.io/kubelet-serving,verbs=approve
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=servers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+
+// Reconcile approves a pending kubelet CSR if it comes from a server that
+// just booted
+func (r *CSRReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var csr certificatesv1.CertificateSigningRequest
+	if err := r.Get(ctx, req.NamespacedName, &csr); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	return ctrl.Result{}, r.review(ctx, r.Client, nil, &csr)
+}
+
+// review approves a pending kubelet CSR of the referenced cluster, or nil
+// for the controller's own, if it comes from a server of that cluster that
+// just booted
+func (r *CSRReconciler) review(ctx context.Context, c client.Client, ref *baremetalcontrollerv1.ClusterReference, csr *certificatesv1.CertificateSigningRequest) error {
+	if csr.Spec.SignerName != certificatesv1.KubeAPIServerClientKubeletSignerName &&
+		csr.Spec.SignerName != certificatesv1.KubeletServingSignerName {
+		return nil
+	}
+	for _, condition := range csr.Status.Conditions {
+		if condition.Type == certificatesv1.CertificateApproved || condition.Type == certificatesv1.CertificateDenied {
+			return nil
+		}
+	}
+
+	logger := log.FromContext(ctx).WithValues("csr", csr.Name)
+	if ref != nil {
+		logger = logger.WithValues("cluster", ref.Name)
+	}
+	server, err := r.verify(ctx, c, ref, csr)
+	if err != nil {
+		logger.Info("Leaving kubelet CSR pending", "reason", err.Error())
+		return nil
+	}
+
+	csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+		Type:           certificatesv1.CertificateApproved,
+		Status:         corev1.ConditionTrue,
+		Reason:         "ServerBooted",
+		Message:        fmt.Sprintf("Approved for server %s booted by bare-metal-controller", server.Name),
+		LastUpdateTime: metav1.Now(),
+	})
+	if err := c.SubResource("approval").Update(ctx, csr); err != nil {
+		return fmt.Errorf("failed to approve CSR %s: %w", csr.Name, err)
+	}
+	logger.Info("Approved kubelet CSR", "server", server.Name, "signer", csr.Spec.SignerName)
+	return nil
+}
+
+// pollClusters reviews the requests of the workload clusters servers
+// reference until the context is cancelled
+func (r *CSRReconciler) pollClusters(ctx context.Context) error {
+	ticker := time.NewTicker(csrPollInterval)
+	defer ticker.Stop()
+	for {
+		r.reviewClusters(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// reviewClusters reviews the pending requests of every workload cluster
+// servers reference
+func (r *CSRReconciler) reviewClusters(ctx context.Context) {
+	logger := log.FromContext(ctx)
+	var servers baremetalcontrollerv1.ServerList
+	if err := r.List(ctx, &servers); err != nil {
+		logger.Error(err, "Failed to list servers")
+		return
+	}
+	for _, referencing := range cluster.Referenced(servers.Items) {
+		ref := *referencing.Spec.ClusterRef
+		c, err := r.Clusters.Get(ctx, referencing)
+		if err != nil {
+			logger.Error(err, "Failed to connect to cluster", "cluster", ref.Name)
+			continue
+		}
+		var csrs certificatesv1.CertificateSigningRequestList
+		if err := c.List(ctx, &csrs); err != nil {
+			logger.Error(err, "Failed to list kubelet CSRs", "cluster", ref.Name)
+			continue
+		}
+		for i := range csrs.Items {
+			if err := r.review(ctx, c, &ref, &csrs.Items[i]); err != nil {
+				logger.Error(err, "Failed to review kubelet CSR", "cluster", ref.Name)
+			}
+		}
+	}
+}
+
+// verify checks that the request is a kubelet's, that it names a server of
+// the cluster that just booted, and for serving certificates that its
+// addresses are the server's. It returns the server, or why the request isn't
+// approved.
+func (r *CSRReconciler) verify(ctx context.Context, c client.Client, ref *baremetalcontrollerv1.ClusterReference, csr *certificatesv1.CertificateSigningRequest) (*baremetalcontrollerv1.Server, error) {
+	request, err := parseCSR(csr.Spec.Request)
+	if err != nil {
+		return nil, err
+	}
+	nodeName, ok := strings.CutPrefix(request.Subject.CommonName, nodeUserPrefix)
+	if !ok || nodeName == "" {
+		return nil, fmt.Errorf("common name %q is not a node", request.Subject.CommonName)
+	}
+	if !slices.Equal(request.Subject.Organization, []string{nodesGroup}) {
+		return nil, fmt.Errorf("organization %v is not %s", request.Subject.Organization, nodesGroup)
+	}
+
+	serving := csr.Spec.SignerName == certificatesv1.KubeletServingSignerName
+	if err := checkUsages(csr.Spec.Usages, serving); err != nil {
+		return nil, err
+	}
+	renewal := csr.Spec.Username == request.Subject.CommonName
+	if !renewal && (serving || !slices.Contains(csr.Spec.Groups, bootstrappersGroup)) {
+		return nil, fmt.Errorf("requested by %s, not the node or a bootstrap token", csr.Spec.Username)
+	}
+
+	server, err := index.ServerByNodeName(ctx, r, nodeName, r.NamespacedServers)
+	if err != nil {
+		return nil, err
+	}
+	if server == nil {
+		return nil, fmt.Errorf("no server %s", nodeName)
+	}
+	if !sameCluster(server.Spec.ClusterRef, ref) {
+		return nil, fmt.Errorf("server %s doesn't join this cluster", server.Name)
+	}
+	if !r.justBooted(server) {
+		return nil, fmt.Errorf("server %s didn't just boot", server.Name)
+	}
+
+	if serving {
+		if err := checkSANs(request, server); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkProviderID(ctx, c, server); err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+// justBooted returns true while a server is booting, or for the boot window
+// after it turned active
+func (r *CSRReconciler) justBooted(server *baremetalcontrollerv1.Server) bool {
+	switch server.Status.Status {
+	case baremetalcontrollerv1.StatusPending:
+		return true
+	case baremetalcontrollerv1.StatusActive:
+		window := r.BootWindow
+		if window == 0 {
+			window = defaultCSRBootWindow
+		}
+		ready := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionReady)
+		return ready != nil && ready.Status == metav1.ConditionTrue && time.Since(ready.LastTransitionTime.Time) < window
+	}
+	return false
+}
+
+// checkProviderID rejects requests for a registered node whose provider ID
+// differs from the server's
+func checkProviderID(ctx context.Context, c client.Client, server *baremetalcontrollerv1.Server) error {
+	if server.Spec.ProviderID == "" {
+		return nil
+	}
+	var node corev1.Node
+	if err := c.Get(ctx, types.NamespacedName{Name: server.NodeName()}, &node); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if node.Spec.ProviderID != "" && node.Spec.ProviderID != server.Spec.ProviderID {
+		return fmt.Errorf("node provider ID %s is not the server's %s", node.Spec.ProviderID, server.Spec.ProviderID)
+	}
+	return nil
+}
+
+// sameCluster returns true if both reference the same cluster through the
+// same kubeconfig Secret, or both the controller's own cluster
+func sameCluster(a, b *baremetalcontrollerv1.ClusterReference) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// checkSANs requires a serving certificate to name only the node and the
+// server's addresses
+func checkSANs(request *x509.CertificateRequest, server *baremetalcontrollerv1.Server) error {
+	if len(request.EmailAddresses) > 0 || len(request.URIs) > 0 {
+		return fmt.Errorf("email and URI SANs are not allowed")
+	}
+	if len(request.DNSNames) == 0 && len(request.IPAddresses) == 0 {
+		return fmt.Errorf("no DNS or IP SANs")
+	}
+	for _, name := range request.DNSNames {
+		if name != server.NodeName() && !slices.Contains(index.Addresses(server), name) {
+			return fmt.Errorf("DNS SAN %s is not the server's", name)
+		}
+	}
+	for _, ip := range request.IPAddresses {
+		if !slices.Contains(index.Addresses(server), ip.String()) {
+			return fmt.Errorf("IP SAN %s is not an address of server %s", ip, server.Name)
+		}
+	}
+	return nil
+}
+
+// checkUsages allows the key usages kubelets request for client or serving
+// certificates
+func checkUsages(usages []certificatesv1.KeyUsage, serving bool) error {
+	required := certificatesv1.UsageClientAuth
+	if serving {
+		required = certificatesv1.UsageServerAuth
+	}
+	allowed := []certificatesv1.KeyUsage{required, certificatesv1.UsageDigitalSignature, certificatesv1.UsageKeyEncipherment}
+	for _, usage := range usages {
+		if !slices.Contains(allowed, usage) {
+			return fmt.Errorf("usage %s is not allowed", usage)
+		}
+	}
+	if !slices.Contains(usages, required) {
+		return fmt.Errorf("usage %s is missing", required)
+	}
+	return nil
+}
+
+func parseCSR(data []byte) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, fmt.Errorf("request is not a PEM certificate request")
+	}
+	request, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse request: %w", err)
+	}
+	return request, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *CSRReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Clusters != nil {
+		if err := mgr.Add(manager.RunnableFunc(r.pollClusters)); err != nil {
+			return err
+		}
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&certificatesv1.CertificateSigningRequest{}).
+		Named("csr").
+		Complete(r)
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"net"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/cluster"
+)
+
+var _ = Describe("CSR Controller", func() {
+	const serverName = "csr-test-server"
+
+	var (
+		ctx        context.Context
+		reconciler *CSRReconciler
+	)
+
+	// createServingCSR requests a serving certificate for the addresses as
+	// the server's kubelet
+	createServingCSR := func(name string, ips ...string) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		template := &x509.CertificateRequest{
+			Subject:  pkix.Name{CommonName: "system:node:" + serverName, Organization: []string{"system:nodes"}},
+			DNSNames: []string{serverName},
+		}
+		for _, ip := range ips {
+			template.IPAddresses = append(template.IPAddresses, net.ParseIP(ip))
+		}
+		der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+		Expect(err).NotTo(HaveOccurred())
+
+		kubeletConfig := rest.CopyConfig(cfg)
+		kubeletConfig.Impersonate = rest.ImpersonationConfig{
+			UserName: "system:node:" + serverName,
+			Groups:   []string{"system:nodes", "system:authenticated"},
+		}
+		kubelet, err := client.New(kubeletConfig, client.Options{Scheme: k8sClient.Scheme()})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kubelet.Create(ctx, &certificatesv1.CertificateSigningRequest{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: certificatesv1.CertificateSigningRequestSpec{
+				Request:    pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}),
+				SignerName: certificatesv1.KubeletServingSignerName,
+				Usages: []certificatesv1.KeyUsage{
+					certificatesv1.UsageDigitalSignature,
+					certificatesv1.UsageKeyEncipherment,
+					certificatesv1.UsageServerAuth,
+				},
+			},
+		})).To(Succeed())
+	}
+
+	approved := func(name string) bool {
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: name}})
+		Expect(err).NotTo(HaveOccurred())
+		var csr certificatesv1.CertificateSigningRequest
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: name}, &csr)).To(Succeed())
+		for _, condition := range csr.Status.Conditions {
+			if condition.Type == certificatesv1.CertificateApproved {
+				return true
+			}
+		}
+		return false
+	}
+
+	setStatus := func(status baremetalcontrollerv1.CurrentStatus) {
+		var server baremetalcontrollerv1.Server
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+		server.Status.Status = status
+		ready := metav1.ConditionFalse
+		if status == baremetalcontrollerv1.StatusActive {
+			ready = metav1.ConditionTrue
+		}
+		meta.SetStatusCondition(&server.Status.Conditions, metav1.Condition{
+			Type:   baremetalcontrollerv1.ConditionReady,
+			Status: ready,
+			Reason: string(status),
+		})
+		Expect(k8sClient.Status().Update(ctx, &server)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		reconciler = &CSRReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+
+		server := &baremetalcontrollerv1.Server{
+			ObjectMeta: metav1.ObjectMeta{Name: serverName},
+			Spec: baremetalcontrollerv1.ServerSpec{
+				PowerState: baremetalcontrollerv1.PowerStateOn,
+				Type:       baremetalcontrollerv1.ControlTypeWOL,
+				Control: baremetalcontrollerv1.ControlSpecs{
+					WOL: &baremetalcontrollerv1.WOLSpecs{
+						Address:    "192.168.1.170",
+						MACAddress: "00:11:22:33:44:70",
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, server)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(k8sClient.DeleteAllOf(ctx, &certificatesv1.CertificateSigningRequest{},
+			client.MatchingFields{"spec.signerName": certificatesv1.KubeletServingSignerName})).To(Succeed())
+		server := &baremetalcontrollerv1.Server{}
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, server); err == nil {
+			Expect(k8sClient.Delete(ctx, server)).To(Succeed())
+		}
+	})
+
+	It("should approve a serving CSR of a server that just booted", func() {
+		setStatus(baremetalcontrollerv1.StatusActive)
+		createServingCSR("csr-test-booted", "192.168.1.170")
+		Expect(approved("csr-test-booted")).To(BeTrue())
+	})
+
+	It("should leave CSRs for other addresses pending", func() {
+		setStatus(baremetalcontrollerv1.StatusActive)
+		createServingCSR("csr-test-foreign-ip", "10.0.0.1")
+		Expect(approved("csr-test-foreign-ip")).To(BeFalse())
+	})
+
+	It("should leave CSRs of servers it didn't boot pending", func() {
+		setStatus(baremetalcontrollerv1.StatusOffline)
+		createServingCSR("csr-test-offline", "192.168.1.170")
+		Expect(approved("csr-test-offline")).To(BeFalse())
+	})
+
+	It("should approve CSRs of a server in the cluster its clusterRef names", func() {
+		// The workload cluster is the test cluster, reached through a kubeconfig
+		kubeconfig, err := clientcmd.Write(clientcmdapi.Config{
+			Clusters:       map[string]*clientcmdapi.Cluster{"workload": {Server: cfg.Host, CertificateAuthorityData: cfg.CAData}},
+			AuthInfos:      map[string]*clientcmdapi.AuthInfo{"admin": {ClientCertificateData: cfg.CertData, ClientKeyData: cfg.KeyData}},
+			Contexts:       map[string]*clientcmdapi.Context{"workload": {Cluster: "workload", AuthInfo: "admin"}},
+			CurrentContext: "workload",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "csr-test-workload", Namespace: "default"},
+			Data:       map[string][]byte{cluster.KubeconfigKey: kubeconfig},
+		}
+		Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+		DeferCleanup(func() { Expect(k8sClient.Delete(ctx, secret)).To(Succeed()) })
+
+		var server baremetalcontrollerv1.Server
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+		server.Spec.ClusterRef = &baremetalcontrollerv1.ClusterReference{
+			Name:                "workload",
+			KubeconfigSecretRef: baremetalcontrollerv1.SecretReference{Name: secret.Name, Namespace: secret.Namespace},
+		}
+		Expect(k8sClient.Update(ctx, &server)).To(Succeed())
+		setStatus(baremetalcontrollerv1.StatusActive)
+		createServingCSR("csr-test-workload", "192.168.1.170")
+
+		// The controller's own cluster isn't the server's
+		Expect(approved("csr-test-workload")).To(BeFalse())
+
+		reconciler.Clusters = cluster.NewClients(k8sClient, k8sClient.Scheme())
+		reconciler.reviewClusters(ctx)
+		var csr certificatesv1.CertificateSigningRequest
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "csr-test-workload"}, &csr)).To(Succeed())
+		Expect(csr.Status.Conditions).To(ContainElement(HaveField("Type", certificatesv1.CertificateApproved)))
+	})
+})
+
+func TestCheckSANs(t *testing.T) {
+	server := &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: "rack1-slot4"},
+		Spec: baremetalcontrollerv1.ServerSpec{
+			NodeName: "worker-04",
+			Type:     baremetalcontrollerv1.ControlTypeWOL,
+			Control: baremetalcontrollerv1.ControlSpecs{
+				WOL: &baremetalcontrollerv1.WOLSpecs{Address: "192.168.1.174", MACAddress: "00:11:22:33:44:74"},
+			},
+		},
+	}
+	tests := []struct {
+		name    string
+		request x509.CertificateRequest
+		wantErr bool
+	}{
+		// The kubelet requests its serving certificate for the node name
+		{name: "node name", request: x509.CertificateRequest{DNSNames: []string{"worker-04"}, IPAddresses: []net.IP{net.ParseIP("192.168.1.174")}}},
+		{name: "address as DNS name", request: x509.CertificateRequest{DNSNames: []string{"192.168.1.174"}}},
+		{name: "server name", request: x509.CertificateRequest{DNSNames: []string{"rack1-slot4"}}, wantErr: true},
+		{name: "other IP", request: x509.CertificateRequest{DNSNames: []string{"worker-04"}, IPAddresses: []net.IP{net.ParseIP("10.0.0.1")}}, wantErr: true},
+		{name: "no SANs", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkSANs(&tt.request, server); (err != nil) != tt.wantErr {
+				t.Errorf("checkSANs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
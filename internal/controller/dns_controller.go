@@ -0,0 +1,259 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/bmc"
+	"github.com/Unbounder1/bare-metal-controller/internal/cluster"
+	"github.com/Unbounder1/bare-metal-controller/internal/dns"
+	"github.com/Unbounder1/bare-metal-controller/internal/index"
+)
+
+// dnsResyncInterval republishes records that may have been changed outside
+// the controller
+const dnsResyncInterval = 10 * time.Minute
+
+// DNSReconciler publishes DNS records for servers: <server>.<zone> for the
+// OS addresses while the server is active, and <server>-bmc.<zone> for the
+// BMC address while the server exists.
+type DNSReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Publisher writes the records to external-dns or a DNS server
+	Publisher dns.Publisher
+
+	// Zone is the domain records are published under
+	Zone string
+
+	// Clusters reaches the nodes of servers with spec.clusterRef
+	Clusters *cluster.Clients
+
+	// NamespacedServers is set when Servers are namespaced, so the server
+	// of a node is looked up in every namespace
+	NamespacedServers bool
+
+	mu        sync.Mutex
+	published map[client.ObjectKey]publishedRecords
+}
+
+// publishedRecords are the records last published for a server
+type publishedRecords struct {
+	records []dns.Record
+	at      time.Time
+}
+
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=servers,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=externaldns.k8s.io,resources=dnsendpoints,verbs=get;list;watch;create;update;delete
+
+// Reconcile publishes the records of a server, and removes them before the
+// server is deleted
+func (r *DNSReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var server baremetalcontrollerv1.Server
+	if err := r.Get(ctx, req.NamespacedName, &server); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !server.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(&server, baremetalcontrollerv1.DNSFinalizer) {
+			return ctrl.Result{}, nil
+		}
+		if err := r.publish(ctx, &server, r.records(server.Name, nil, nil), true); err != nil {
+			return ctrl.Result{}, err
+		}
+		r.forget(req.NamespacedName)
+		controllerutil.RemoveFinalizer(&server, baremetalcontrollerv1.DNSFinalizer)
+		if err := r.Update(ctx, &server); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to remove finalizer: %w", err)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if controllerutil.AddFinalizer(&server, baremetalcontrollerv1.DNSFinalizer) {
+		if err := r.Update(ctx, &server); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to add finalizer: %w", err)
+		}
+	}
+
+	osAddresses, err := r.osAddresses(ctx, &server)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	records := r.records(server.Name, osAddresses, ipAddresses(managementAddress(&server)))
+	if err := r.publish(ctx, &server, records, false); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: dnsResyncInterval}, nil
+}
+
+// publish publishes the records unless the same were published within the
+// resync interval, or always when forced. Records are named after the
+// server without its namespace, as server names are unique across
+// namespaces.
+func (r *DNSReconciler) publish(ctx context.Context, server *baremetalcontrollerv1.Server, records []dns.Record, force bool) error {
+	key := client.ObjectKeyFromObject(server)
+	r.mu.Lock()
+	previous, ok := r.published[key]
+	r.mu.Unlock()
+	if ok && !force && reflect.DeepEqual(previous.records, records) && time.Since(previous.at) < dnsResyncInterval {
+		return nil
+	}
+
+	if err := r.Publisher.Publish(ctx, server.Name, records); err != nil {
+		return fmt.Errorf("failed to publish DNS records of server %s: %w", server.Name, err)
+	}
+	log.FromContext(ctx).Info("Published DNS records", "server", server.Name, "records", records)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.published == nil {
+		r.published = map[client.ObjectKey]publishedRecords{}
+	}
+	r.published[key] = publishedRecords{records: records, at: time.Now()}
+	return nil
+}
+
+func (r *DNSReconciler) forget(server client.ObjectKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.published, server)
+}
+
+func (r *DNSReconciler) records(server string, osAddresses, managementAddresses []string) []dns.Record {
+	return []dns.Record{
+		{Name: dns.OSName(server, r.Zone), Addresses: osAddresses},
+		{Name: dns.ManagementName(server, r.Zone), Addresses: managementAddresses},
+	}
+}
+
+// osAddresses returns the internal IPs of the node of an active server, or
+// its DHCP leases or the host address of its spec until the node registers
+func (r *DNSReconciler) osAddresses(ctx context.Context, server *baremetalcontrollerv1.Server) ([]string, error) {
+	if server.Status.Status != baremetalcontrollerv1.StatusActive {
+		return nil, nil
+	}
+
+	c, _, err := nodeClients(ctx, r.Clusters, server, r.Client, r.Client)
+	if err != nil {
+		return nil, err
+	}
+	var node corev1.Node
+	err = c.Get(ctx, types.NamespacedName{Name: server.NodeName()}, &node)
+	if client.IgnoreNotFound(err) != nil {
+		return nil, err
+	}
+	var addresses []string
+	for _, address := range node.Status.Addresses {
+		if address.Type == corev1.NodeInternalIP {
+			addresses = append(addresses, ipAddresses(address.Address)...)
+		}
+	}
+	if len(addresses) > 0 {
+		return addresses, nil
+	}
+
+	for _, address := range server.Status.Addresses {
+		addresses = append(addresses, ipAddresses(address.Address)...)
+	}
+	if len(addresses) > 0 {
+		return addresses, nil
+	}
+
+	control := server.Spec.Control
+	switch {
+	case server.Spec.Provisioning != nil && server.Spec.Provisioning.Tinkerbell != nil &&
+		server.Spec.Provisioning.Tinkerbell.IPAddress != "":
+		return ipAddresses(server.Spec.Provisioning.Tinkerbell.IPAddress), nil
+	case server.Spec.Type == baremetalcontrollerv1.ControlTypeWOL && control.WOL != nil:
+		return ipAddresses(control.WOL.Address), nil
+	case server.Spec.Type == baremetalcontrollerv1.ControlTypeMAAS && control.MAAS != nil:
+		return ipAddresses(control.MAAS.Address), nil
+	case server.Spec.Type == baremetalcontrollerv1.ControlTypeEquinix && control.Equinix != nil:
+		return ipAddresses(control.Equinix.Address), nil
+	case server.Spec.Type == baremetalcontrollerv1.ControlTypeHetzner && control.Hetzner != nil:
+		return ipAddresses(control.Hetzner.Address), nil
+	case server.Spec.Type == baremetalcontrollerv1.ControlTypeESXi && control.ESXi != nil:
+		return ipAddresses(bmc.Host(control.ESXi.Address)), nil
+	}
+	return nil, nil
+}
+
+// managementAddress returns the BMC address of IPMI and Redfish servers
+func managementAddress(server *baremetalcontrollerv1.Server) string {
+	control := server.Spec.Control
+	switch {
+	case server.Spec.Type == baremetalcontrollerv1.ControlTypeIPMI && control.IPMI != nil:
+		return bmc.Host(control.IPMI.Address)
+	case server.Spec.Type == baremetalcontrollerv1.ControlTypeRedfish && control.Redfish != nil:
+		return bmc.Host(control.Redfish.Address)
+	case server.Spec.Type == baremetalcontrollerv1.ControlTypeESXi && control.Redfish != nil:
+		return bmc.Host(control.Redfish.Address)
+	case server.Spec.Type == baremetalcontrollerv1.ControlTypeESXi && control.IPMI != nil:
+		return bmc.Host(control.IPMI.Address)
+	}
+	return ""
+}
+
+// ipAddresses returns the address if it's an IP address. Host names aren't
+// published.
+func ipAddresses(address string) []string {
+	if net.ParseIP(address) == nil {
+		return nil
+	}
+	return []string{address}
+}
+
+// serverForNode maps a node to the server it runs on
+func (r *DNSReconciler) serverForNode(ctx context.Context, obj client.Object) []reconcile.Request {
+	server, err := index.ServerByNodeName(ctx, r.Client, obj.GetName(), r.NamespacedServers)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to look up server of node", "node", obj.GetName())
+		return nil
+	}
+	if server == nil {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: client.ObjectKeyFromObject(server)}}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *DNSReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&baremetalcontrollerv1.Server{}).
+		Watches(&corev1.Node{}, handler.EnqueueRequestsFromMapFunc(r.serverForNode)).
+		Named("dns").
+		Complete(r)
+}
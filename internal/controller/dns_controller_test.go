@@ -0,0 +1,113 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/dns"
+)
+
+// fakePublisher records the last records published for each server
+type fakePublisher struct {
+	records map[string][]dns.Record
+}
+
+func (p *fakePublisher) Publish(_ context.Context, server string, records []dns.Record) error {
+	p.records[server] = records
+	return nil
+}
+
+var _ = Describe("DNS Controller", func() {
+	const serverName = "dns-test-server"
+
+	var (
+		ctx        context.Context
+		reconciler *DNSReconciler
+		publisher  *fakePublisher
+	)
+
+	reconcileServer := func() {
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: serverName}})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		publisher = &fakePublisher{records: map[string][]dns.Record{}}
+		reconciler = &DNSReconciler{
+			Client:    k8sClient,
+			Scheme:    k8sClient.Scheme(),
+			Publisher: publisher,
+			Zone:      "metal.example.com",
+		}
+
+		server := &baremetalcontrollerv1.Server{
+			ObjectMeta: metav1.ObjectMeta{Name: serverName},
+			Spec: baremetalcontrollerv1.ServerSpec{
+				PowerState: baremetalcontrollerv1.PowerStateOn,
+				Type:       baremetalcontrollerv1.ControlTypeIPMI,
+				Control: baremetalcontrollerv1.ControlSpecs{
+					IPMI: &baremetalcontrollerv1.IPMISpecs{Address: "10.0.0.20"},
+				},
+				Provisioning: &baremetalcontrollerv1.ProvisioningSpec{
+					Tinkerbell: &baremetalcontrollerv1.TinkerbellSpec{
+						Namespace:   "tink-system",
+						TemplateRef: "ubuntu",
+						IPAddress:   "192.168.1.20",
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, server)).To(Succeed())
+	})
+
+	It("should publish the OS records while the server is active and remove all on delete", func() {
+		reconcileServer()
+		Expect(publisher.records[serverName]).To(ConsistOf(
+			dns.Record{Name: "dns-test-server.metal.example.com"},
+			dns.Record{Name: "dns-test-server-bmc.metal.example.com", Addresses: []string{"10.0.0.20"}},
+		))
+
+		var server baremetalcontrollerv1.Server
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+		Expect(server.Finalizers).To(ContainElement(baremetalcontrollerv1.DNSFinalizer))
+		server.Status.Status = baremetalcontrollerv1.StatusActive
+		Expect(k8sClient.Status().Update(ctx, &server)).To(Succeed())
+
+		reconcileServer()
+		Expect(publisher.records[serverName]).To(ContainElement(
+			dns.Record{Name: "dns-test-server.metal.example.com", Addresses: []string{"192.168.1.20"}},
+		))
+
+		Expect(k8sClient.Delete(ctx, &server)).To(Succeed())
+		reconcileServer()
+		for _, record := range publisher.records[serverName] {
+			Expect(record.Addresses).To(BeEmpty())
+		}
+		err := k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+})
@@ -0,0 +1,105 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+// checkPowerDrift applies the drift policy of a server found powered on or
+// off out of band, i.e. one that matched spec.powerState before this check
+// and no longer does. The condition turns false once the server matches it
+// again. It returns true while the power action has to be skipped.
+func (r *ServerReconciler) checkPowerDrift(ctx context.Context, server *baremetalcontrollerv1.Server, previous baremetalcontrollerv1.CurrentStatus, currentState baremetalcontrollerv1.PowerState) (bool, error) {
+	desiredState := server.Spec.PowerState.Settled()
+	if desiredState == currentState {
+		if meta.IsStatusConditionTrue(server.Status.Conditions, baremetalcontrollerv1.ConditionPowerDrift) {
+			meta.SetStatusCondition(&server.Status.Conditions, metav1.Condition{
+				Type:               baremetalcontrollerv1.ConditionPowerDrift,
+				Status:             metav1.ConditionFalse,
+				Reason:             "Resolved",
+				Message:            fmt.Sprintf("Powered %s as expected", currentState),
+				ObservedGeneration: server.Generation,
+			})
+			r.updateStatus(ctx, server)
+		}
+		return false, nil
+	}
+
+	previousState := baremetalcontrollerv1.PowerStateOff
+	if previous == baremetalcontrollerv1.StatusActive {
+		previousState = baremetalcontrollerv1.PowerStateOn
+	}
+	settled := previous == baremetalcontrollerv1.StatusActive || previous == baremetalcontrollerv1.StatusOffline
+	drifted := settled && previousState == desiredState
+	if !drifted && !meta.IsStatusConditionTrue(server.Status.Conditions, baremetalcontrollerv1.ConditionPowerDrift) {
+		// spec.powerState changed, not the server
+		return false, nil
+	}
+
+	policy := server.Spec.DriftPolicy
+	if policy == "" {
+		policy = baremetalcontrollerv1.DriftPolicyReconcile
+	}
+	if drifted {
+		log.FromContext(ctx).Info("Detected out-of-band power change", "server", server.Name,
+			"powerState", currentState, "policy", policy)
+		r.event(server, corev1.EventTypeWarning, "DriftDetected",
+			"Server was powered %s out of band, expected %s (policy %s)", currentState, server.Spec.PowerState, policy)
+	}
+
+	condition := metav1.Condition{
+		Type:               baremetalcontrollerv1.ConditionPowerDrift,
+		Status:             metav1.ConditionTrue,
+		Message:            fmt.Sprintf("Powered %s out of band, spec.powerState is %s", currentState, server.Spec.PowerState),
+		ObservedGeneration: server.Generation,
+	}
+	switch policy {
+	case baremetalcontrollerv1.DriftPolicyAdopt:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Adopted"
+		condition.Message = fmt.Sprintf("Adopted out-of-band power %s", currentState)
+	case baremetalcontrollerv1.DriftPolicyAlert:
+		condition.Reason = "AlertOnly"
+	default:
+		condition.Reason = "Reconciling"
+	}
+	meta.SetStatusCondition(&server.Status.Conditions, condition)
+	r.updateStatus(ctx, server)
+
+	switch policy {
+	case baremetalcontrollerv1.DriftPolicyAdopt:
+		patch := client.MergeFrom(server.DeepCopy())
+		server.Spec.PowerState = currentState
+		if err := r.Patch(ctx, server, patch); err != nil {
+			return false, fmt.Errorf("failed to adopt power state: %w", err)
+		}
+		return true, nil
+	case baremetalcontrollerv1.DriftPolicyAlert:
+		return true, nil
+	}
+	return false, nil
+}
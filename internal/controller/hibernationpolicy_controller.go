@@ -0,0 +1,338 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/cluster"
+	"github.com/Unbounder1/bare-metal-controller/internal/drain"
+	"github.com/Unbounder1/bare-metal-controller/internal/index"
+	"github.com/Unbounder1/bare-metal-controller/internal/listing"
+)
+
+// hibernationDrainInterval rechecks nodes drained before hibernating
+const hibernationDrainInterval = 10 * time.Second
+
+// HibernationPolicyReconciler powers pools down to their floor during
+// off-hours and back up afterwards. Like PowerActionReconciler it only
+// changes spec.powerState, so ServerReconciler does the power actions.
+type HibernationPolicyReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// APIReader lists pods by node without caching every pod in the cluster
+	APIReader client.Reader
+
+	// Now returns the current time, for off-hours
+	Now func() time.Time
+
+	// Clusters reaches the nodes of servers with spec.clusterRef
+	Clusters *cluster.Clients
+}
+
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=hibernationpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=hibernationpolicies/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=hibernationpolicies/finalizers,verbs=update
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=calendars,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list
+// +kubebuilder:rbac:groups=core,resources=pods/eviction,verbs=create
+
+// Reconcile records which servers to power off when the off-hours start,
+// powers them off, and powers exactly those on again when they end.
+func (r *HibernationPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var policy baremetalcontrollerv1.HibernationPolicy
+	if err := r.Get(ctx, req.NamespacedName, &policy); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	names := make([]string, 0, len(policy.Spec.OffHours))
+	for _, window := range policy.Spec.OffHours {
+		names = append(names, window.Calendar)
+	}
+	calendars, err := loadCalendars(ctx, r.Client, names...)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	offHours, nextChange := inOffHours(policy.Spec.OffHours, calendars, r.now())
+	hibernating := policy.Status.Phase == baremetalcontrollerv1.HibernationPhaseHibernating
+
+	switch {
+	case offHours && !hibernating:
+		// Record the servers first, so they are restored even if the
+		// controller restarts halfway
+		hibernated, err := r.selectHibernated(ctx, &policy)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		now := metav1.NewTime(r.now())
+		policy.Status.Phase = baremetalcontrollerv1.HibernationPhaseHibernating
+		policy.Status.Hibernated = hibernated
+		policy.Status.HibernatedAt = &now
+		if err := r.Status().Update(ctx, &policy); err != nil {
+			return ctrl.Result{}, err
+		}
+		log.FromContext(ctx).Info("Hibernating pool", "policy", policy.Name, "servers", len(hibernated))
+		return ctrl.Result{Requeue: true}, nil
+
+	case offHours:
+		done, err := r.hibernate(ctx, &policy)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !done {
+			return ctrl.Result{RequeueAfter: hibernationDrainInterval}, nil
+		}
+
+	case hibernating:
+		if err := r.restore(ctx, &policy); err != nil {
+			return ctrl.Result{}, err
+		}
+		now := metav1.NewTime(r.now())
+		log.FromContext(ctx).Info("Restoring pool", "policy", policy.Name, "servers", len(policy.Status.Hibernated))
+		policy.Status.Phase = baremetalcontrollerv1.HibernationPhaseAwake
+		policy.Status.Hibernated = nil
+		policy.Status.RestoredAt = &now
+		if err := r.Status().Update(ctx, &policy); err != nil {
+			return ctrl.Result{}, err
+		}
+
+	case policy.Status.Phase == "":
+		policy.Status.Phase = baremetalcontrollerv1.HibernationPhaseAwake
+		if err := r.Status().Update(ctx, &policy); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{RequeueAfter: nextChange}, nil
+}
+
+// selectHibernated returns the powered on servers of the pool beyond the
+// floor, keeping the first ones by name. Namespaced servers are returned as
+// <namespace>/<name>, see index.ServerPath.
+func (r *HibernationPolicyReconciler) selectHibernated(ctx context.Context, policy *baremetalcontrollerv1.HibernationPolicy) ([]string, error) {
+	selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %w", err)
+	}
+
+	var poweredOn []string
+	err = listing.Servers(ctx, r.Client, 0, func(server *baremetalcontrollerv1.Server) error {
+		if server.Spec.PowerState.Settled() == baremetalcontrollerv1.PowerStateOn &&
+			server.Annotations[baremetalcontrollerv1.AutoscalerExcludeAnnotation] != "true" &&
+			server.Annotations[baremetalcontrollerv1.StandbyAnnotation] != "true" &&
+			!server.Spec.Role.Protected() {
+			poweredOn = append(poweredOn, index.ServerPath(client.ObjectKeyFromObject(server)))
+		}
+		return nil
+	}, client.MatchingLabelsSelector{Selector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(poweredOn)
+	if int(policy.Spec.Floor) >= len(poweredOn) {
+		return nil, nil
+	}
+	return poweredOn[policy.Spec.Floor:], nil
+}
+
+// hibernate powers off the recorded servers, draining them first if the
+// policy asks for it. Servers are annotated as they are powered off, so ones
+// powered on by hand while hibernating are left on. It returns false while
+// nodes are being drained.
+func (r *HibernationPolicyReconciler) hibernate(ctx context.Context, policy *baremetalcontrollerv1.HibernationPolicy) (bool, error) {
+	done := true
+	for _, name := range policy.Status.Hibernated {
+		key, _ := index.ParseServerPath(name)
+		var server baremetalcontrollerv1.Server
+		if err := r.Get(ctx, key, &server); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return false, err
+		}
+		if server.Annotations[baremetalcontrollerv1.HibernatedAnnotation] != "" ||
+			server.Spec.PowerState.Settled() != baremetalcontrollerv1.PowerStateOn {
+			continue
+		}
+
+		if policy.Spec.Drain {
+			c, reader, err := nodeClients(ctx, r.Clusters, &server, r.Client, r.apiReader())
+			if err != nil {
+				return false, err
+			}
+			drained, err := drain.Node(ctx, c, reader, server.NodeName())
+			if err != nil {
+				return false, err
+			}
+			if !drained {
+				done = false
+				continue
+			}
+		}
+
+		patch := client.MergeFrom(server.DeepCopy())
+		if server.Annotations == nil {
+			server.Annotations = map[string]string{}
+		}
+		server.Annotations[baremetalcontrollerv1.HibernatedAnnotation] = policy.Name
+		server.Spec.PowerState = baremetalcontrollerv1.PowerStateOff
+		if err := r.Patch(ctx, &server, patch); err != nil {
+			return false, fmt.Errorf("failed to power off server %s: %w", name, err)
+		}
+	}
+	return done, nil
+}
+
+// restore powers on the servers the policy powered off and uncordons their
+// nodes
+func (r *HibernationPolicyReconciler) restore(ctx context.Context, policy *baremetalcontrollerv1.HibernationPolicy) error {
+	for _, name := range policy.Status.Hibernated {
+		key, _ := index.ParseServerPath(name)
+		var server baremetalcontrollerv1.Server
+		if err := r.Get(ctx, key, &server); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		if policy.Spec.Drain {
+			c, _, err := nodeClients(ctx, r.Clusters, &server, r.Client, r.apiReader())
+			if err != nil {
+				return err
+			}
+			if err := drain.Uncordon(ctx, c, server.NodeName()); err != nil {
+				return err
+			}
+		}
+		if server.Annotations[baremetalcontrollerv1.HibernatedAnnotation] != policy.Name {
+			continue
+		}
+
+		patch := client.MergeFrom(server.DeepCopy())
+		delete(server.Annotations, baremetalcontrollerv1.HibernatedAnnotation)
+		server.Spec.PowerState = baremetalcontrollerv1.PowerStateOn
+		if err := r.Patch(ctx, &server, patch); err != nil {
+			return fmt.Errorf("failed to power on server %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// inOffHours reports whether now is inside any of the windows, and how long
+// until that changes
+func inOffHours(windows []baremetalcontrollerv1.MaintenanceWindow, calendars map[string][]baremetalcontrollerv1.CalendarEvent, now time.Time) (bool, time.Duration) {
+	now = now.UTC()
+	inside := false
+	next := time.Duration(0)
+	earlier := func(d time.Duration) {
+		if d > 0 && (next == 0 || d < next) {
+			next = d
+		}
+	}
+
+	for i := range windows {
+		window := &windows[i]
+		if window.Calendar != "" {
+			during, change := inCalendar(calendars[window.Calendar], now)
+			inside = inside || during
+			earlier(change)
+			continue
+		}
+		start, err := time.Parse("15:04", window.Start)
+		if err != nil {
+			continue
+		}
+		// A window that opened yesterday may still be open
+		for day := -1; day <= 7; day++ {
+			opens := time.Date(now.Year(), now.Month(), now.Day()+day, start.Hour(), start.Minute(), 0, 0, time.UTC)
+			if !windowOpensOn(window, opens.Weekday()) {
+				continue
+			}
+			closes := opens.Add(window.Duration.Duration)
+			if !now.Before(opens) && now.Before(closes) {
+				inside = true
+				earlier(closes.Sub(now))
+			}
+			if opens.After(now) {
+				earlier(opens.Sub(now))
+				break
+			}
+		}
+	}
+	if next == 0 {
+		next = time.Hour
+	}
+	return inside, next
+}
+
+func (r *HibernationPolicyReconciler) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+// apiReader returns the reader pods are listed with when draining
+func (r *HibernationPolicyReconciler) apiReader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
+}
+
+// policiesForCalendar enqueues the policies with off-hours from the calendar
+func (r *HibernationPolicyReconciler) policiesForCalendar(ctx context.Context, obj client.Object) []reconcile.Request {
+	var policies baremetalcontrollerv1.HibernationPolicyList
+	if err := r.List(ctx, &policies); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list hibernation policies")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, policy := range policies.Items {
+		for _, window := range policy.Spec.OffHours {
+			if window.Calendar == obj.GetName() {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&policy)})
+				break
+			}
+		}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HibernationPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&baremetalcontrollerv1.HibernationPolicy{}).
+		Watches(&baremetalcontrollerv1.Calendar{}, handler.EnqueueRequestsFromMapFunc(r.policiesForCalendar)).
+		Named("hibernationpolicy").
+		Complete(r)
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+var _ = Describe("HibernationPolicy Controller", func() {
+	const policyName = "test-hibernation"
+
+	var (
+		ctx        context.Context
+		reconciler *HibernationPolicyReconciler
+		now        time.Time
+	)
+
+	serverNames := []string{"hibernate-server-a", "hibernate-server-b", "hibernate-server-c"}
+
+	reconcilePolicy := func() reconcile.Result {
+		result, err := reconciler.Reconcile(ctx, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: policyName},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return result
+	}
+
+	getPolicy := func() *baremetalcontrollerv1.HibernationPolicy {
+		policy := &baremetalcontrollerv1.HibernationPolicy{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: policyName}, policy)).To(Succeed())
+		return policy
+	}
+
+	getServer := func(name string) *baremetalcontrollerv1.Server {
+		server := &baremetalcontrollerv1.Server{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: name}, server)).To(Succeed())
+		return server
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		// A Saturday, inside the weekend off-hours
+		now = time.Date(2025, time.June, 7, 3, 0, 0, 0, time.UTC)
+		reconciler = &HibernationPolicyReconciler{
+			Client: k8sClient,
+			Scheme: k8sClient.Scheme(),
+			Now:    func() time.Time { return now },
+		}
+
+		for _, name := range serverNames {
+			server := &baremetalcontrollerv1.Server{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   name,
+					Labels: map[string]string{"pool": "hibernate-test"},
+				},
+				Spec: baremetalcontrollerv1.ServerSpec{
+					PowerState: baremetalcontrollerv1.PowerStateOn,
+					Type:       baremetalcontrollerv1.ControlTypeWOL,
+					Control: baremetalcontrollerv1.ControlSpecs{
+						WOL: &baremetalcontrollerv1.WOLSpecs{
+							Address:    "192.168.1.160",
+							MACAddress: "00:11:22:33:44:aa",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, server)).To(Succeed())
+		}
+
+		policy := &baremetalcontrollerv1.HibernationPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: policyName},
+			Spec: baremetalcontrollerv1.HibernationPolicySpec{
+				Selector: metav1.LabelSelector{
+					MatchLabels: map[string]string{"pool": "hibernate-test"},
+				},
+				OffHours: []baremetalcontrollerv1.MaintenanceWindow{{
+					Start:    "19:00",
+					Duration: metav1.Duration{Duration: 60 * time.Hour},
+					Days:     []baremetalcontrollerv1.Weekday{"Fri"},
+				}},
+				Floor: 1,
+			},
+		}
+		Expect(k8sClient.Create(ctx, policy)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		policy := &baremetalcontrollerv1.HibernationPolicy{}
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: policyName}, policy); err == nil {
+			Expect(k8sClient.Delete(ctx, policy)).To(Succeed())
+		}
+		for _, name := range serverNames {
+			server := &baremetalcontrollerv1.Server{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{Name: name}, server); err == nil {
+				Expect(k8sClient.Delete(ctx, server)).To(Succeed())
+			}
+		}
+	})
+
+	It("should power the pool down to the floor and restore exactly the same servers", func() {
+		reconcilePolicy()
+		policy := getPolicy()
+		Expect(policy.Status.Phase).To(Equal(baremetalcontrollerv1.HibernationPhaseHibernating))
+		Expect(policy.Status.Hibernated).To(Equal(serverNames[1:]))
+
+		result := reconcilePolicy()
+		Expect(result.RequeueAfter).To(Equal(40 * time.Hour))
+		Expect(getServer(serverNames[0]).Spec.PowerState).To(Equal(baremetalcontrollerv1.PowerStateOn))
+		for _, name := range serverNames[1:] {
+			server := getServer(name)
+			Expect(server.Spec.PowerState).To(Equal(baremetalcontrollerv1.PowerStateOff))
+			Expect(server.Annotations).To(HaveKeyWithValue(baremetalcontrollerv1.HibernatedAnnotation, policyName))
+		}
+
+		// Servers powered on by hand while hibernating are left on
+		server := getServer(serverNames[1])
+		server.Spec.PowerState = baremetalcontrollerv1.PowerStateOn
+		Expect(k8sClient.Update(ctx, server)).To(Succeed())
+		reconcilePolicy()
+		Expect(getServer(serverNames[1]).Spec.PowerState).To(Equal(baremetalcontrollerv1.PowerStateOn))
+
+		now = now.Add(40 * time.Hour)
+		reconcilePolicy()
+		policy = getPolicy()
+		Expect(policy.Status.Phase).To(Equal(baremetalcontrollerv1.HibernationPhaseAwake))
+		Expect(policy.Status.Hibernated).To(BeEmpty())
+		for _, name := range serverNames {
+			server := getServer(name)
+			Expect(server.Spec.PowerState).To(Equal(baremetalcontrollerv1.PowerStateOn))
+			Expect(server.Annotations).NotTo(HaveKey(baremetalcontrollerv1.HibernatedAnnotation))
+		}
+	})
+})
@@ -0,0 +1,294 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/bmc"
+	"github.com/Unbounder1/bare-metal-controller/internal/listing"
+	"github.com/Unbounder1/bare-metal-controller/internal/power"
+)
+
+// refreshHardwareStatus collects the hardware inventory of the BMC and the
+// host, and LLDP neighbors, and compares the inventory with the ServerClass
+// firmware baseline. They are collected once and again whenever the server
+// boots, since that's when firmware updates, hardware changes and recabling
+// take effect. It returns true if the status changed.
+func (r *ServerReconciler) refreshHardwareStatus(ctx context.Context, server *baremetalcontrollerv1.Server, reachable bool) bool {
+	before := server.Status.DeepCopy()
+
+	if server.Status.Hardware == nil || (reachable && server.Status.Status == baremetalcontrollerv1.StatusPending) {
+		r.collectInventory(ctx, server)
+	}
+	if reachable && (server.Status.Inventory == nil || server.Status.Status == baremetalcontrollerv1.StatusPending) {
+		r.collectHostInventory(ctx, server)
+	}
+	if reachable && (server.Status.LLDP == nil || server.Status.Status == baremetalcontrollerv1.StatusPending) {
+		r.collectLLDP(ctx, server)
+	}
+	r.checkFirmwareBaseline(ctx, server)
+
+	return !equality.Semantic.DeepEqual(before, &server.Status)
+}
+
+func (r *ServerReconciler) collectInventory(ctx context.Context, server *baremetalcontrollerv1.Server) {
+	var inventory power.Inventory
+	var err error
+
+	switch server.Spec.Type {
+	case baremetalcontrollerv1.ControlTypeIPMI:
+		if server.Spec.Control.IPMI == nil {
+			return
+		}
+		var target bmc.IPMITarget
+		target, err = r.endpoints().IPMI(ctx, server)
+		if err == nil {
+			inventory, err = r.IPMIClient.GetInventory(ctx, target.Address, target.Username, target.Password)
+		}
+
+	case baremetalcontrollerv1.ControlTypeRedfish:
+		var target power.RedfishTarget
+		target, err = r.endpoints().Redfish(ctx, server)
+		if err == nil {
+			inventory, err = r.RedfishClient.GetInventory(target)
+		}
+
+	default:
+		// No BMC to ask
+		return
+	}
+
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to collect hardware inventory", "server", server.Name)
+		return
+	}
+
+	now := metav1.Now()
+	server.Status.Hardware = &baremetalcontrollerv1.HardwareStatus{
+		Manufacturer:       inventory.Manufacturer,
+		Model:              inventory.Model,
+		SerialNumber:       inventory.SerialNumber,
+		BIOSVersion:        inventory.BIOSVersion,
+		BMCFirmwareVersion: inventory.BMCFirmwareVersion,
+		CPUs:               inventory.CPUs,
+		LastUpdated:        &now,
+	}
+	if inventory.MemoryGiB > 0 {
+		server.Status.Hardware.Memory = resource.NewQuantity(int64(inventory.MemoryGiB*(1<<30)), resource.BinarySI)
+	}
+}
+
+// hostSSHAccess returns where and as whom the host is reached over SSH, which
+// is taken from the WOL control spec or the attestation spec. It returns
+// false for servers without SSH access.
+func (r *ServerReconciler) hostSSHAccess(server *baremetalcontrollerv1.Server) (string, string, *baremetalcontrollerv1.SecretReference, bool) {
+	switch {
+	case server.Spec.Control.WOL != nil && server.Spec.Control.WOL.SSHSecretRef != nil:
+		wol := server.Spec.Control.WOL
+		return r.sshAddress(server), wol.User, wol.SSHSecretRef, true
+	case server.Spec.Attestation != nil && server.Spec.Attestation.SSHSecretRef != nil:
+		attest := server.Spec.Attestation
+		address := r.endpoints().ResolveAddress(attest.Address)
+		if address == "" {
+			address = r.sshAddress(server)
+		}
+		return address, attest.User, attest.SSHSecretRef, true
+	}
+	return "", "", nil, false
+}
+
+// collectHostInventory reads the hardware of the host from the system
+// resources of Redfish servers, or from the OS over SSH
+func (r *ServerReconciler) collectHostInventory(ctx context.Context, server *baremetalcontrollerv1.Server) {
+	var inventory power.HostInventory
+	var source baremetalcontrollerv1.InventorySource
+	var err error
+
+	if server.Spec.Type == baremetalcontrollerv1.ControlTypeRedfish {
+		var target power.RedfishTarget
+		target, err = r.endpoints().Redfish(ctx, server)
+		if err == nil {
+			inventory, err = r.RedfishClient.GetHostInventory(target)
+		}
+		source = baremetalcontrollerv1.InventorySourceRedfish
+	} else {
+		address, user, ref, ok := r.hostSSHAccess(server)
+		if !ok {
+			return
+		}
+		var key string
+		user, key, err = r.getSSHCredentials(ctx, server, user, ref)
+		if err == nil {
+			inventory, err = r.SSHClient.GetHostInventory(ctx, address, user, key)
+		}
+		source = baremetalcontrollerv1.InventorySourceSSH
+	}
+
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to collect host inventory", "server", server.Name)
+		return
+	}
+	setInventoryStatus(server, inventory, source)
+}
+
+func setInventoryStatus(server *baremetalcontrollerv1.Server, inventory power.HostInventory, source baremetalcontrollerv1.InventorySource) {
+	now := metav1.Now()
+	status := &baremetalcontrollerv1.InventoryStatus{
+		CPUModel:    inventory.CPUModel,
+		CPUCores:    inventory.CPUCores,
+		CPUThreads:  inventory.CPUThreads,
+		GPUs:        inventory.GPUs,
+		Source:      source,
+		LastUpdated: &now,
+	}
+	if inventory.MemoryBytes > 0 {
+		status.Memory = resource.NewQuantity(inventory.MemoryBytes, resource.BinarySI)
+	}
+	for _, d := range inventory.Disks {
+		disk := baremetalcontrollerv1.DiskInventory{Name: d.Name, Model: d.Model, Rotational: d.Rotational}
+		if d.SizeBytes > 0 {
+			disk.Size = resource.NewQuantity(d.SizeBytes, resource.BinarySI)
+		}
+		status.Disks = append(status.Disks, disk)
+	}
+	for _, n := range inventory.NICs {
+		status.NICs = append(status.NICs, baremetalcontrollerv1.NICInventory{Name: n.Name, MACAddress: n.MACAddress})
+	}
+	server.Status.Inventory = status
+}
+
+// collectLLDP reads LLDP neighbors from lldpd on the host. It needs SSH
+// access.
+func (r *ServerReconciler) collectLLDP(ctx context.Context, server *baremetalcontrollerv1.Server) {
+	address, user, ref, ok := r.hostSSHAccess(server)
+	if !ok {
+		return
+	}
+
+	logger := log.FromContext(ctx)
+
+	user, key, err := r.getSSHCredentials(ctx, server, user, ref)
+	if err != nil {
+		logger.Error(err, "Failed to collect LLDP neighbors", "server", server.Name)
+		return
+	}
+
+	neighbors, err := r.SSHClient.GetLLDPNeighbors(ctx, address, user, key)
+	if err != nil {
+		logger.Error(err, "Failed to collect LLDP neighbors", "server", server.Name)
+		return
+	}
+	setLLDPStatus(server, neighbors)
+}
+
+func setLLDPStatus(server *baremetalcontrollerv1.Server, neighbors []power.LLDPNeighbor) {
+	now := metav1.Now()
+	status := &baremetalcontrollerv1.LLDPStatus{LastUpdated: &now}
+	for _, n := range neighbors {
+		status.Neighbors = append(status.Neighbors, baremetalcontrollerv1.LLDPNeighbor{
+			Interface:       n.Interface,
+			ChassisID:       n.ChassisID,
+			SystemName:      n.SystemName,
+			PortID:          n.PortID,
+			PortDescription: n.PortDescription,
+			VLAN:            n.VLAN,
+		})
+	}
+	server.Status.LLDP = status
+}
+
+// checkFirmwareBaseline sets the FirmwareDrift condition from the ServerClass
+// baseline, or removes it if there is no baseline to compare against.
+func (r *ServerReconciler) checkFirmwareBaseline(ctx context.Context, server *baremetalcontrollerv1.Server) {
+	if server.Spec.ServerClassName == "" {
+		meta.RemoveStatusCondition(&server.Status.Conditions, baremetalcontrollerv1.ConditionFirmwareDrift)
+		return
+	}
+
+	setDrift := func(status metav1.ConditionStatus, reason string, message string) {
+		meta.SetStatusCondition(&server.Status.Conditions, metav1.Condition{
+			Type:               baremetalcontrollerv1.ConditionFirmwareDrift,
+			Status:             status,
+			Reason:             reason,
+			Message:            message,
+			ObservedGeneration: server.Generation,
+		})
+	}
+
+	var class baremetalcontrollerv1.ServerClass
+	if err := r.Get(ctx, types.NamespacedName{Name: server.Spec.ServerClassName}, &class); err != nil {
+		if apierrors.IsNotFound(err) {
+			setDrift(metav1.ConditionUnknown, "ServerClassNotFound",
+				fmt.Sprintf("ServerClass %s not found", server.Spec.ServerClassName))
+		}
+		return
+	}
+
+	baseline := class.Spec.Firmware
+	if baseline == nil {
+		meta.RemoveStatusCondition(&server.Status.Conditions, baremetalcontrollerv1.ConditionFirmwareDrift)
+		return
+	}
+
+	hardware := server.Status.Hardware
+	if hardware == nil {
+		setDrift(metav1.ConditionUnknown, "InventoryUnavailable", "Hardware inventory has not been collected")
+		return
+	}
+
+	var drift []string
+	if baseline.BIOSVersion != "" && hardware.BIOSVersion != baseline.BIOSVersion {
+		drift = append(drift, fmt.Sprintf("BIOS version %s, expected %s", hardware.BIOSVersion, baseline.BIOSVersion))
+	}
+	if baseline.BMCFirmwareVersion != "" && hardware.BMCFirmwareVersion != baseline.BMCFirmwareVersion {
+		drift = append(drift, fmt.Sprintf("BMC firmware version %s, expected %s", hardware.BMCFirmwareVersion, baseline.BMCFirmwareVersion))
+	}
+
+	if len(drift) > 0 {
+		setDrift(metav1.ConditionTrue, "VersionMismatch", strings.Join(drift, "; "))
+		return
+	}
+	setDrift(metav1.ConditionFalse, "MatchesBaseline", "Firmware matches the ServerClass baseline")
+}
+
+// serversForClass maps a ServerClass to the Servers that reference it
+func (r *ServerReconciler) serversForClass(ctx context.Context, obj client.Object) []reconcile.Request {
+	var requests []reconcile.Request
+	_ = listing.Servers(ctx, r, 0, func(server *baremetalcontrollerv1.Server) error {
+		if server.Spec.ServerClassName == obj.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: client.ObjectKeyFromObject(server),
+			})
+		}
+		return nil
+	})
+	return requests
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/lifecycle"
+)
+
+// lifecycleServer lets the lifecycle state machine check and change a server
+type lifecycleServer struct {
+	ctx    context.Context
+	r      *ServerReconciler
+	server *baremetalcontrollerv1.Server
+	// reusedProbe is set if the reachability check reused a probe result
+	// an earlier reconcile already counted
+	reusedProbe bool
+}
+
+func (s *lifecycleServer) ExitMaintenanceMode() *lifecycle.Failure {
+	err := s.r.exitMaintenanceMode(s.ctx, s.server)
+	if err == nil {
+		return nil
+	}
+	return &lifecycle.Failure{
+		Reason: powerFailureReason(s.server, baremetalcontrollerv1.PowerStateOn, err),
+		Err:    err,
+	}
+}
+
+func (s *lifecycleServer) Attest() (bool, error) {
+	return s.r.attestServer(s.ctx, s.server)
+}
+
+// RecordFailure counts a failed check, unless it was made with a reused
+// probe result, so a single failed probe can't fail a server through the
+// reconciles its own status updates trigger
+func (s *lifecycleServer) RecordFailure() {
+	if s.reusedProbe {
+		return
+	}
+	s.r.recordFailure(s.server)
+}
+
+func (s *lifecycleServer) SetFailure(message string, reason baremetalcontrollerv1.FailureReason) {
+	s.server.Status.Message = message
+	s.server.Status.Reason = reason
+}
+
+func (s *lifecycleServer) ClearFailure() {
+	s.r.clearFailure(s.server, s.server.Status.Status)
+}
+
+func (s *lifecycleServer) CompleteBoot() {
+	s.r.verifyStorageLayout(s.ctx, s.server)
+	completeBoot(s.server)
+	s.r.verifyBootOrder(s.ctx, s.server)
+}
+
+func (s *lifecycleServer) RebootTimedOut() bool {
+	return rebootTimedOut(s.server, time.Now())
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/power"
+)
+
+var (
+	pingRTT = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "baremetal_server_ping_rtt_seconds",
+		Help: "Average round trip time of the echo replies of the server's last reachability probe.",
+	}, []string{"namespace", "server"})
+
+	pingLoss = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "baremetal_server_ping_loss_ratio",
+		Help: "Fraction of the echo requests of the server's last reachability probe that went unanswered.",
+	}, []string{"namespace", "server"})
+
+	powerCycles = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "baremetal_server_power_cycles",
+		Help: "Number of times the server came online.",
+	}, []string{"namespace", "server"})
+
+	runtimeSeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "baremetal_server_runtime_seconds",
+		Help: "Time the server was online, as of its last status update.",
+	}, []string{"namespace", "server"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(pingRTT, pingLoss, powerCycles, runtimeSeconds)
+}
+
+// setPingMetrics exports a probe. The round trip time of a probe without
+// replies is unknown, so the last one is kept.
+func setPingMetrics(server client.ObjectKey, stats power.PingStats) {
+	if stats.Reachable() {
+		pingRTT.WithLabelValues(server.Namespace, server.Name).Set(stats.RTT.Seconds())
+	}
+	pingLoss.WithLabelValues(server.Namespace, server.Name).Set(stats.Loss())
+}
+
+// setWearMetrics exports the power cycles and runtime of a server
+func setWearMetrics(server client.ObjectKey, wear *baremetalcontrollerv1.WearStatus, now time.Time) {
+	powerCycles.WithLabelValues(server.Namespace, server.Name).Set(float64(wear.PowerCycles))
+	runtimeSeconds.WithLabelValues(server.Namespace, server.Name).Set(wear.TotalRuntime(now).Seconds())
+}
+
+// forgetServerMetrics removes the series of a server that no longer exists
+func forgetServerMetrics(server client.ObjectKey) {
+	pingRTT.DeleteLabelValues(server.Namespace, server.Name)
+	pingLoss.DeleteLabelValues(server.Namespace, server.Name)
+	powerCycles.DeleteLabelValues(server.Namespace, server.Name)
+	runtimeSeconds.DeleteLabelValues(server.Namespace, server.Name)
+}
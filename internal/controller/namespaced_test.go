@@ -0,0 +1,111 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/index"
+)
+
+// newNamespacedClient returns a fake client with namespaced servers and the
+// server indexes
+func newNamespacedClient(t *testing.T, servers ...*baremetalcontrollerv1.Server) client.Client {
+	t.Helper()
+	builder := index.NewFakeClientBuilder(newApplyScheme(t))
+	for _, server := range servers {
+		builder.WithObjects(server)
+	}
+	return builder.Build()
+}
+
+func namespacedServer(namespace, name string, pool string, power baremetalcontrollerv1.PowerState) *baremetalcontrollerv1.Server {
+	return &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{"pool": pool}},
+		Spec:       baremetalcontrollerv1.ServerSpec{PowerState: power},
+	}
+}
+
+func TestPowerActionTargetsNamespaced(t *testing.T) {
+	c := newNamespacedClient(t,
+		namespacedServer("team-a", "worker-01", "gpu", baremetalcontrollerv1.PowerStateOn),
+		namespacedServer("team-b", "worker-02", "gpu", baremetalcontrollerv1.PowerStateOn),
+		namespacedServer("team-b", "worker-03", "cpu", baremetalcontrollerv1.PowerStateOn),
+	)
+	r := &PowerActionReconciler{Client: c, NamespacedServers: true}
+	action := &baremetalcontrollerv1.PowerAction{Spec: baremetalcontrollerv1.PowerActionSpec{
+		Action:   baremetalcontrollerv1.PowerActionOff,
+		Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "gpu"}},
+		// By name alone, with the namespace, and one that doesn't exist
+		Servers: []string{"worker-03", "team-a/worker-01", "worker-99"},
+	}}
+	if err := r.selectTargets(context.Background(), action); err != nil {
+		t.Fatalf("selectTargets() error = %v", err)
+	}
+
+	var got []string
+	for _, target := range action.Status.Targets {
+		got = append(got, index.ServerPath(client.ObjectKey{Namespace: target.Namespace, Name: target.Name}))
+	}
+	want := []string{"worker-99", "team-a/worker-01", "team-b/worker-02", "team-b/worker-03"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("targets = %v, want %v", got, want)
+	}
+}
+
+func TestHibernationNamespaced(t *testing.T) {
+	c := newNamespacedClient(t,
+		namespacedServer("team-a", "worker-01", "gpu", baremetalcontrollerv1.PowerStateOn),
+		namespacedServer("team-b", "worker-02", "gpu", baremetalcontrollerv1.PowerStateOn),
+		namespacedServer("team-b", "worker-03", "gpu", baremetalcontrollerv1.PowerStateOff),
+	)
+	r := &HibernationPolicyReconciler{Client: c}
+	policy := &baremetalcontrollerv1.HibernationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "nights"},
+		Spec: baremetalcontrollerv1.HibernationPolicySpec{
+			Selector: metav1.LabelSelector{MatchLabels: map[string]string{"pool": "gpu"}},
+		},
+	}
+
+	hibernated, err := r.selectHibernated(context.Background(), policy)
+	if err != nil {
+		t.Fatalf("selectHibernated() error = %v", err)
+	}
+	if want := []string{"team-a/worker-01", "team-b/worker-02"}; !reflect.DeepEqual(hibernated, want) {
+		t.Fatalf("selectHibernated() = %v, want %v", hibernated, want)
+	}
+
+	policy.Status.Hibernated = hibernated
+	if done, err := r.hibernate(context.Background(), policy); err != nil || !done {
+		t.Fatalf("hibernate() = %v, %v", done, err)
+	}
+	for _, key := range []client.ObjectKey{{Namespace: "team-a", Name: "worker-01"}, {Namespace: "team-b", Name: "worker-02"}} {
+		var server baremetalcontrollerv1.Server
+		if err := c.Get(context.Background(), key, &server); err != nil {
+			t.Fatal(err)
+		}
+		if server.Spec.PowerState != baremetalcontrollerv1.PowerStateOff || server.Annotations[baremetalcontrollerv1.HibernatedAnnotation] != "nights" {
+			t.Errorf("%s has power state %q and annotations %v, want hibernated", key, server.Spec.PowerState, server.Annotations)
+		}
+	}
+}
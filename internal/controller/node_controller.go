@@ -0,0 +1,118 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/index"
+)
+
+// NodeReconciler deletes Nodes whose Server was removed, after draining
+// them. Only Nodes with the server label are considered; the server
+// controller sets it with --node-cleanup.
+type NodeReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// APIReader looks up servers outside the controller's shard or scope,
+	// which aren't cached, and lists pods when draining. Defaults to the
+	// client.
+	APIReader client.Reader
+
+	// NamespacedServers is set when Servers are namespaced, so the server
+	// of a node is looked up by name in every namespace
+	NamespacedServers bool
+}
+
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=servers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list
+// +kubebuilder:rbac:groups=core,resources=pods/eviction,verbs=create
+
+// Reconcile drains and deletes a labeled Node once its Server is gone
+func (r *NodeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var node corev1.Node
+	if err := r.Get(ctx, req.NamespacedName, &node); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	serverName := node.Labels[baremetalcontrollerv1.ServerLabel]
+	if serverName == "" || !node.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	server, err := index.ServerByName(ctx, r.reader(), serverName, r.NamespacedServers)
+	if err != nil || server != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.FromContext(ctx).Info("Cleaning up node of removed server", "node", node.Name, "server", serverName)
+	deleted, err := deleteNode(ctx, r.Client, r.reader(), node.Name)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !deleted {
+		return ctrl.Result{RequeueAfter: nodeCleanupDrainInterval}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// nodesForServer maps a server to the nodes labeled with its name, so they
+// are cleaned up as soon as it's deleted
+func (r *NodeReconciler) nodesForServer(ctx context.Context, obj client.Object) []reconcile.Request {
+	var nodes corev1.NodeList
+	if err := r.List(ctx, &nodes, client.MatchingLabels{baremetalcontrollerv1.ServerLabel: obj.GetName()}); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list nodes of server", "server", obj.GetName())
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(nodes.Items))
+	for _, node := range nodes.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: node.Name}})
+	}
+	return requests
+}
+
+func (r *NodeReconciler) reader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *NodeReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	labeled := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetLabels()[baremetalcontrollerv1.ServerLabel] != ""
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Node{}, builder.WithPredicates(labeled)).
+		Watches(&baremetalcontrollerv1.Server{}, handler.EnqueueRequestsFromMapFunc(r.nodesForServer)).
+		Named("node").
+		Complete(r)
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+var _ = Describe("Node Controller", func() {
+	const (
+		nodeName   = "node-cleanup-node"
+		serverName = "node-cleanup-server"
+	)
+
+	var (
+		ctx        context.Context
+		reconciler *NodeReconciler
+	)
+
+	reconcileNode := func() {
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: nodeName},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		reconciler = &NodeReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   nodeName,
+				Labels: map[string]string{baremetalcontrollerv1.ServerLabel: serverName},
+			},
+		}
+		Expect(k8sClient.Create(ctx, node)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		node := &corev1.Node{}
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: nodeName}, node); err == nil {
+			Expect(k8sClient.Delete(ctx, node)).To(Succeed())
+		}
+		server := &baremetalcontrollerv1.Server{}
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, server); err == nil {
+			Expect(k8sClient.Delete(ctx, server)).To(Succeed())
+		}
+	})
+
+	It("should keep the node while its server exists", func() {
+		server := &baremetalcontrollerv1.Server{
+			ObjectMeta: metav1.ObjectMeta{Name: serverName},
+			Spec: baremetalcontrollerv1.ServerSpec{
+				PowerState: baremetalcontrollerv1.PowerStateOn,
+				Type:       baremetalcontrollerv1.ControlTypeIPMI,
+				Control: baremetalcontrollerv1.ControlSpecs{
+					IPMI: &baremetalcontrollerv1.IPMISpecs{Address: "192.168.1.160"},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, server)).To(Succeed())
+
+		reconcileNode()
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: nodeName}, &corev1.Node{})).To(Succeed())
+	})
+
+	It("should delete the node once its server is gone", func() {
+		reconcileNode()
+		err := k8sClient.Get(ctx, types.NamespacedName{Name: nodeName}, &corev1.Node{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+})
@@ -0,0 +1,115 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/drain"
+)
+
+// nodeCleanupDrainInterval rechecks a node drained before it is deleted
+const nodeCleanupDrainInterval = 10 * time.Second
+
+// finalizeServer drains and deletes the node of a server being deleted,
+// then releases the server. It returns true while the server is being
+// deleted.
+func (r *ServerReconciler) finalizeServer(ctx context.Context, server *baremetalcontrollerv1.Server) (bool, ctrl.Result, error) {
+	if server.DeletionTimestamp.IsZero() {
+		if r.NodeCleanup && controllerutil.AddFinalizer(server, baremetalcontrollerv1.NodeCleanupFinalizer) {
+			if err := r.Update(ctx, server); err != nil {
+				return true, ctrl.Result{}, fmt.Errorf("failed to add finalizer: %w", err)
+			}
+		}
+		return false, ctrl.Result{}, nil
+	}
+	if !controllerutil.ContainsFinalizer(server, baremetalcontrollerv1.NodeCleanupFinalizer) {
+		return true, ctrl.Result{}, nil
+	}
+
+	c, reader, err := nodeClients(ctx, r.Clusters, server, r.Client, r.apiReader())
+	if err != nil {
+		return true, ctrl.Result{}, err
+	}
+	deleted, err := deleteNode(ctx, c, reader, server.NodeName())
+	if err != nil {
+		return true, ctrl.Result{}, err
+	}
+	if !deleted {
+		return true, ctrl.Result{RequeueAfter: nodeCleanupDrainInterval}, nil
+	}
+	controllerutil.RemoveFinalizer(server, baremetalcontrollerv1.NodeCleanupFinalizer)
+	if err := r.Update(ctx, server); err != nil {
+		return true, ctrl.Result{}, fmt.Errorf("failed to remove finalizer: %w", err)
+	}
+	return true, ctrl.Result{}, nil
+}
+
+// labelNode marks the node of an active server with the server's name, so
+// the node is deleted once the server is gone
+func (r *ServerReconciler) labelNode(ctx context.Context, server *baremetalcontrollerv1.Server) error {
+	c, _, err := nodeClients(ctx, r.Clusters, server, r.Client, r.apiReader())
+	if err != nil {
+		return err
+	}
+	var node corev1.Node
+	if err := c.Get(ctx, types.NamespacedName{Name: server.NodeName()}, &node); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if node.Labels[baremetalcontrollerv1.ServerLabel] == server.Name {
+		return nil
+	}
+	patch := client.MergeFrom(node.DeepCopy())
+	if node.Labels == nil {
+		node.Labels = map[string]string{}
+	}
+	node.Labels[baremetalcontrollerv1.ServerLabel] = server.Name
+	if err := c.Patch(ctx, &node, patch); err != nil {
+		return fmt.Errorf("failed to label node %s: %w", node.Name, err)
+	}
+	return nil
+}
+
+// deleteNode drains a node and deletes it once drained. It returns true once
+// the node is gone.
+func deleteNode(ctx context.Context, c client.Client, reader client.Reader, name string) (bool, error) {
+	drained, err := drain.Node(ctx, c, reader, name)
+	if err != nil || !drained {
+		return false, err
+	}
+	node := &corev1.Node{}
+	node.Name = name
+	if err := c.Delete(ctx, node); err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, fmt.Errorf("failed to delete node %s: %w", name, err)
+	}
+	log.FromContext(ctx).Info("Deleted node", "node", name)
+	return true, nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"maps"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/cluster"
+	"github.com/Unbounder1/bare-metal-controller/internal/index"
+	"github.com/Unbounder1/bare-metal-controller/internal/nodefeatures"
+)
+
+// NodeFeatureReconciler records the node-feature-discovery labels of the
+// Node of an active server in status.nodeFeatures. They are kept once the
+// Node is gone, so GPU types and wake on pending pods still know the
+// hardware of powered off servers.
+type NodeFeatureReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Prefixes are the prefixes of the recorded Node labels
+	Prefixes []string
+
+	// Clusters reaches the nodes of servers with spec.clusterRef
+	Clusters *cluster.Clients
+
+	// NamespacedServers is set when Servers are namespaced, so the server
+	// of a node is looked up in every namespace
+	NamespacedServers bool
+}
+
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=servers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=servers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+
+// Reconcile copies the feature labels of a server's Node into its status
+func (r *NodeFeatureReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var server baremetalcontrollerv1.Server
+	if err := r.Get(ctx, req.NamespacedName, &server); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	// A Node left from an earlier boot may not match the hardware anymore
+	if server.Status.Status != baremetalcontrollerv1.StatusActive || !server.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	c, _, err := nodeClients(ctx, r.Clusters, &server, r.Client, r.Client)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	var node corev1.Node
+	if err := c.Get(ctx, types.NamespacedName{Name: server.NodeName()}, &node); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	features := nodefeatures.Select(node.Labels, r.Prefixes)
+	if maps.Equal(features, server.Status.NodeFeatures) {
+		return ctrl.Result{}, nil
+	}
+	log.FromContext(ctx).Info("Recording node features", "server", server.Name, "features", len(features))
+
+	// Only status.nodeFeatures is applied, the rest belongs to ServerReconciler
+	return ctrl.Result{}, applyServerStatus(ctx, r.Client, &server, baremetalcontrollerv1.ServerStatus{
+		NodeFeatures: features,
+	}, nodeFeatureFieldManager)
+}
+
+// serverForNode maps a node to the server it runs on
+func (r *NodeFeatureReconciler) serverForNode(ctx context.Context, obj client.Object) []reconcile.Request {
+	server, err := index.ServerByNodeName(ctx, r.Client, obj.GetName(), r.NamespacedServers)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to look up server of node", "node", obj.GetName())
+		return nil
+	}
+	if server == nil {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: client.ObjectKeyFromObject(server)}}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *NodeFeatureReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&baremetalcontrollerv1.Server{}).
+		Watches(&corev1.Node{}, handler.EnqueueRequestsFromMapFunc(r.serverForNode)).
+		Named("nodefeature").
+		Complete(r)
+}
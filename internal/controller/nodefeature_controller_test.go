@@ -0,0 +1,129 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/nodefeatures"
+)
+
+var _ = Describe("NodeFeature Controller", func() {
+	const serverName = "nodefeature-test-server"
+
+	var (
+		ctx        context.Context
+		reconciler *NodeFeatureReconciler
+	)
+
+	reconcileServer := func() {
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: serverName}})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	getServer := func() *baremetalcontrollerv1.Server {
+		server := &baremetalcontrollerv1.Server{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, server)).To(Succeed())
+		return server
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		options := nodefeatures.DefaultOptions()
+		reconciler = &NodeFeatureReconciler{
+			Client:   k8sClient,
+			Scheme:   k8sClient.Scheme(),
+			Prefixes: options.PrefixList(),
+		}
+
+		server := &baremetalcontrollerv1.Server{
+			ObjectMeta: metav1.ObjectMeta{Name: serverName},
+			Spec: baremetalcontrollerv1.ServerSpec{
+				PowerState: baremetalcontrollerv1.PowerStateOn,
+				Type:       baremetalcontrollerv1.ControlTypeWOL,
+				Control: baremetalcontrollerv1.ControlSpecs{
+					WOL: &baremetalcontrollerv1.WOLSpecs{Address: "192.168.1.30", MACAddress: "00:11:22:33:44:30"},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, server)).To(Succeed())
+		server.Status.Status = baremetalcontrollerv1.StatusActive
+		Expect(k8sClient.Status().Update(ctx, server)).To(Succeed())
+
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name: serverName,
+			Labels: map[string]string{
+				"kubernetes.io/hostname":                       serverName,
+				"feature.node.kubernetes.io/cpu-cpuid.AVX512F": "true",
+				nodefeatures.GPUProductLabel:                   "NVIDIA-A100-SXM4-80GB",
+				"feature.node.kubernetes.io/pci-10de.present":  "true",
+				"node-role.kubernetes.io/worker":               "",
+			},
+		}}
+		Expect(k8sClient.Create(ctx, node)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(k8sClient.Delete(ctx, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: serverName}})).To(Succeed())
+		Expect(k8sClient.Delete(ctx, getServer())).To(Succeed())
+	})
+
+	It("should record the feature labels of the node and keep them once it's gone", func() {
+		reconcileServer()
+		server := getServer()
+		Expect(server.Status.NodeFeatures).To(Equal(map[string]string{
+			"feature.node.kubernetes.io/cpu-cpuid.AVX512F": "true",
+			"feature.node.kubernetes.io/pci-10de.present":  "true",
+			nodefeatures.GPUProductLabel:                   "NVIDIA-A100-SXM4-80GB",
+		}))
+		Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusActive))
+		Expect(nodefeatures.GPUType(server)).To(Equal("NVIDIA-A100-SXM4-80GB"))
+
+		By("dropping features the node lost")
+		node := &corev1.Node{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, node)).To(Succeed())
+		delete(node.Labels, "feature.node.kubernetes.io/cpu-cpuid.AVX512F")
+		Expect(k8sClient.Update(ctx, node)).To(Succeed())
+		reconcileServer()
+		Expect(getServer().Status.NodeFeatures).NotTo(HaveKey("feature.node.kubernetes.io/cpu-cpuid.AVX512F"))
+
+		By("keeping them while the server is off")
+		server = getServer()
+		server.Status.Status = baremetalcontrollerv1.StatusOffline
+		Expect(k8sClient.Status().Update(ctx, server)).To(Succeed())
+		node.Labels = nil
+		Expect(k8sClient.Update(ctx, node)).To(Succeed())
+		reconcileServer()
+		Expect(getServer().Status.NodeFeatures).To(HaveKeyWithValue(nodefeatures.GPUProductLabel, "NVIDIA-A100-SXM4-80GB"))
+	})
+
+	It("should prefer the gpu-type label of the server", func() {
+		server := getServer()
+		server.Labels = map[string]string{nodefeatures.GPUTypeLabel: "a100"}
+		Expect(k8sClient.Update(ctx, server)).To(Succeed())
+		reconcileServer()
+		Expect(nodefeatures.GPUType(getServer())).To(Equal("a100"))
+	})
+})
@@ -0,0 +1,76 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/pet"
+)
+
+// handlePlatformEvents records the Platform Event Traps the server's BMC
+// sent since the last reconcile. A critical event sets the HardwareFault
+// condition, which its deassertion for the same kind of sensor clears.
+func (r *ServerReconciler) handlePlatformEvents(ctx context.Context, server *baremetalcontrollerv1.Server) {
+	if r.PlatformEvents == nil {
+		return
+	}
+	changed := false
+	for _, e := range r.PlatformEvents.TakeEvents(client.ObjectKeyFromObject(server)) {
+		log.FromContext(ctx).Info("Received platform event", "server", server.Name, "event", e.String(), "severity", e.Severity)
+		eventType := corev1.EventTypeNormal
+		if e.Severity != pet.SeverityOK && !e.Deasserted {
+			eventType = corev1.EventTypeWarning
+		}
+		r.event(server, eventType, "PlatformEvent", "%s %s", e.Severity, e)
+
+		if setHardwareFault(server, e) {
+			changed = true
+		}
+	}
+	if changed {
+		r.updateStatus(ctx, server)
+	}
+}
+
+// setHardwareFault sets the HardwareFault condition for a critical event,
+// and clears it for the deassertion of an event of the kind of sensor that
+// set it. It returns true if the condition changed.
+func setHardwareFault(server *baremetalcontrollerv1.Server, e pet.Event) bool {
+	fault := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionHardwareFault)
+	condition := metav1.Condition{
+		Type:               baremetalcontrollerv1.ConditionHardwareFault,
+		Message:            e.String(),
+		ObservedGeneration: server.Generation,
+	}
+	switch {
+	case e.Fault():
+		condition.Status, condition.Reason = metav1.ConditionTrue, e.SensorType
+	case e.Deasserted && fault != nil && fault.Status == metav1.ConditionTrue && fault.Reason == e.SensorType:
+		condition.Status, condition.Reason = metav1.ConditionFalse, "Deasserted"
+	default:
+		return false
+	}
+	return meta.SetStatusCondition(&server.Status.Conditions, condition)
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/pet"
+)
+
+func TestSetHardwareFault(t *testing.T) {
+	inputLost := pet.Event{SensorType: "PowerSupply", Sensor: 0x31, Description: "input lost", Severity: pet.SeverityCritical}
+	inputBack := inputLost
+	inputBack.Deasserted = true
+	tripped := pet.Event{SensorType: "Temperature", Sensor: 0x02, Description: "upper critical going high", Severity: pet.SeverityCritical}
+	trippedBack := tripped
+	trippedBack.Deasserted = true
+	fanWarning := pet.Event{SensorType: "Fan", Sensor: 0x40, Description: "lower non-critical going low", Severity: pet.SeverityWarning}
+
+	tests := []struct {
+		name        string
+		events      []pet.Event
+		wantChanged bool
+		wantStatus  metav1.ConditionStatus
+		wantReason  string
+	}{
+		{name: "warning", events: []pet.Event{fanWarning}},
+		{name: "critical", events: []pet.Event{inputLost}, wantChanged: true, wantStatus: metav1.ConditionTrue, wantReason: "PowerSupply"},
+		{name: "deasserted", events: []pet.Event{inputLost, inputBack}, wantChanged: true, wantStatus: metav1.ConditionFalse, wantReason: "Deasserted"},
+		// Only the kind of sensor that set the fault clears it
+		{name: "other sensor deasserted", events: []pet.Event{inputLost, trippedBack}, wantStatus: metav1.ConditionTrue, wantReason: "PowerSupply"},
+		{name: "latest fault wins", events: []pet.Event{inputLost, tripped}, wantChanged: true, wantStatus: metav1.ConditionTrue, wantReason: "Temperature"},
+		{name: "deasserted without fault", events: []pet.Event{inputBack}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &baremetalcontrollerv1.Server{}
+			changed := false
+			for _, e := range tt.events {
+				changed = setHardwareFault(server, e)
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("setHardwareFault() = %v for the last event, want %v", changed, tt.wantChanged)
+			}
+			condition := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionHardwareFault)
+			if tt.wantReason == "" {
+				if condition != nil {
+					t.Errorf("condition = %+v, want none", condition)
+				}
+				return
+			}
+			if condition == nil || condition.Status != tt.wantStatus || condition.Reason != tt.wantReason {
+				t.Errorf("condition = %+v, want %s with reason %s", condition, tt.wantStatus, tt.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+// powerQueueRetryInterval is how long to wait before retrying a power action
+// that didn't fit in the queue because all workers are busy
+const powerQueueRetryInterval = 5 * time.Second
+
+// submitResult says whether a power action was queued
+type submitResult int
+
+const (
+	// submitted means the action is queued or running
+	submitted submitResult = iota
+	// queueFull means all workers are busy
+	queueFull
+	// tenantQuotaExceeded means the server's tenant has as many actions in
+	// flight as it may
+	tenantQuotaExceeded
+)
+
+// powerOperations runs power actions on a fixed number of workers, so a BMC
+// that takes tens of seconds to answer doesn't hold up the reconciles of
+// other servers. A finished action triggers a reconcile of its server, which
+// picks up the result.
+type powerOperations struct {
+	workers int
+	// tenantWorkers bounds the actions in flight for the servers of one
+	// tenant, so no tenant can occupy all workers. Zero means no bound.
+	tenantWorkers int
+	queue         chan *powerOperation
+	events        chan event.GenericEvent
+	// gracePeriod is how long running actions may take to finish once the
+	// controller shuts down, before they are cancelled
+	gracePeriod time.Duration
+	// checkpoint records the operations left when the workers stopped, so
+	// their result, or how far they got, is in the status after a restart.
+	// Nil leaves them to the next controller.
+	checkpoint func(context.Context, *powerOperation)
+
+	mu         sync.Mutex
+	operations map[client.ObjectKey]*powerOperation
+}
+
+// powerOperation is a power action for one server. server is the copy the
+// action runs on, so it carries the storage and boot status the action
+// recorded.
+type powerOperation struct {
+	server *baremetalcontrollerv1.Server
+	action baremetalcontrollerv1.PowerState
+	run    func(context.Context, *baremetalcontrollerv1.Server, baremetalcontrollerv1.PowerState) error
+	tenant string
+
+	started bool
+	done    bool
+	err     error
+}
+
+func newPowerOperations(workers int, tenantWorkers int) *powerOperations {
+	return &powerOperations{
+		workers:       workers,
+		tenantWorkers: tenantWorkers,
+		queue:         make(chan *powerOperation, workers),
+		events:        make(chan event.GenericEvent, workers),
+		operations:    map[client.ObjectKey]*powerOperation{},
+	}
+}
+
+// checkpointTimeout bounds recording the operations left at shutdown
+const checkpointTimeout = 5 * time.Second
+
+// Start runs the workers until ctx is done. Running actions then get the
+// grace period to finish, so a controller rollout doesn't cut off a BMC in
+// the middle of a power on, and the operations left are checkpointed.
+func (p *powerOperations) Start(ctx context.Context) error {
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case op := <-p.queue:
+					// Actions queued as the controller shuts down aren't
+					// started
+					if ctx.Err() != nil {
+						return
+					}
+					p.execute(ctx, runCtx, op)
+				}
+			}
+		}()
+	}
+
+	<-ctx.Done()
+	stopped := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(p.gracePeriod):
+		cancel()
+		<-stopped
+	}
+
+	if p.checkpoint != nil {
+		checkpointCtx, cancelCheckpoint := context.WithTimeout(context.WithoutCancel(ctx), checkpointTimeout)
+		defer cancelCheckpoint()
+		p.mu.Lock()
+		left := make([]*powerOperation, 0, len(p.operations))
+		for _, op := range p.operations {
+			left = append(left, op)
+		}
+		p.mu.Unlock()
+		for _, op := range left {
+			p.checkpoint(checkpointCtx, op)
+		}
+	}
+	return nil
+}
+
+// execute runs an action with runCtx, which outlives ctx by the grace
+// period, and triggers a reconcile of its server unless ctx is done
+func (p *powerOperations) execute(ctx context.Context, runCtx context.Context, op *powerOperation) {
+	p.mu.Lock()
+	op.started = true
+	p.mu.Unlock()
+
+	err := op.run(runCtx, op.server, op.action)
+
+	p.mu.Lock()
+	op.done = true
+	op.err = err
+	p.mu.Unlock()
+
+	select {
+	case p.events <- event.GenericEvent{Object: op.server}:
+	case <-ctx.Done():
+	}
+}
+
+// submit queues an action for the server unless one is already queued or
+// running, the queue is full, or the server's tenant has used up its share
+// of the workers.
+func (p *powerOperations) submit(server *baremetalcontrollerv1.Server, action baremetalcontrollerv1.PowerState,
+	run func(context.Context, *baremetalcontrollerv1.Server, baremetalcontrollerv1.PowerState) error) submitResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := client.ObjectKeyFromObject(server)
+	if _, ok := p.operations[key]; ok {
+		return submitted
+	}
+
+	tenant := server.Tenant()
+	if tenant != "" && p.tenantWorkers > 0 {
+		inFlight := 0
+		for _, op := range p.operations {
+			if op.tenant == tenant && !op.done {
+				inFlight++
+			}
+		}
+		if inFlight >= p.tenantWorkers {
+			return tenantQuotaExceeded
+		}
+	}
+
+	op := &powerOperation{server: server.DeepCopy(), action: action, run: run, tenant: tenant}
+	select {
+	case p.queue <- op:
+		p.operations[key] = op
+		return submitted
+	default:
+		return queueFull
+	}
+}
+
+// take returns the finished operation of a server and forgets it, or
+// reports whether one is still queued or running.
+func (p *powerOperations) take(key client.ObjectKey) (op *powerOperation, inFlight bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	op, ok := p.operations[key]
+	if !ok {
+		return nil, false
+	}
+	if !op.done {
+		return nil, true
+	}
+	delete(p.operations, key)
+	return op, false
+}
+
+// forget drops the operation of a deleted server. A running action can't be
+// cancelled and finishes on its own.
+func (p *powerOperations) forget(key client.ObjectKey) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.operations, key)
+}
+
+// startPowerOperation queues the power action and marks it in progress. The
+// reconcile triggered by its completion records the result.
+func (r *ServerReconciler) startPowerOperation(ctx context.Context, server *baremetalcontrollerv1.Server, action baremetalcontrollerv1.PowerState) (ctrl.Result, error) {
+	doing, _ := describeAction(action)
+	switch r.operations.submit(server, action, r.performPowerAction) {
+	case queueFull:
+		log.FromContext(ctx).Info("All power workers are busy, retrying", "server", server.Name)
+		return ctrl.Result{RequeueAfter: powerQueueRetryInterval}, nil
+	case tenantQuotaExceeded:
+		log.FromContext(ctx).Info("Tenant has no power workers left, retrying", "server", server.Name,
+			"tenant", server.Tenant())
+		meta.SetStatusCondition(&server.Status.Conditions, metav1.Condition{
+			Type:   baremetalcontrollerv1.ConditionOperationInProgress,
+			Status: metav1.ConditionFalse,
+			Reason: "TenantQuotaExceeded",
+			Message: fmt.Sprintf("Tenant %s has %d power actions in flight, waiting before %s",
+				server.Tenant(), r.operations.tenantWorkers, strings.ToLower(doing)),
+			ObservedGeneration: server.Generation,
+		})
+		r.updateStatus(ctx, server)
+		return ctrl.Result{RequeueAfter: powerQueueRetryInterval}, nil
+	}
+
+	meta.SetStatusCondition(&server.Status.Conditions, metav1.Condition{
+		Type:               baremetalcontrollerv1.ConditionOperationInProgress,
+		Status:             metav1.ConditionTrue,
+		Reason:             operationReason(action),
+		Message:            doing,
+		ObservedGeneration: server.Generation,
+	})
+	r.updateStatus(ctx, server)
+	return ctrl.Result{}, nil
+}
+
+// completePowerOperation records the result of a finished power action
+func (r *ServerReconciler) completePowerOperation(ctx context.Context, server *baremetalcontrollerv1.Server, op *powerOperation) (ctrl.Result, error) {
+	server.Status.Storage = op.server.Status.Storage
+	server.Status.Boot = op.server.Status.Boot
+	server.Status.Reboot = op.server.Status.Reboot
+	// The action was picked for the spec of its submission
+	server.Status.ObservedGeneration = op.server.Generation
+
+	_, done := describeAction(op.action)
+	condition := metav1.Condition{
+		Type:               baremetalcontrollerv1.ConditionOperationInProgress,
+		Status:             metav1.ConditionFalse,
+		Reason:             "Succeeded",
+		Message:            done,
+		ObservedGeneration: server.Generation,
+	}
+	if op.err != nil {
+		condition.Reason = "Failed"
+		condition.Message = op.err.Error()
+	}
+	meta.SetStatusCondition(&server.Status.Conditions, condition)
+
+	return r.finishPowerAction(ctx, server, op.action, op.err)
+}
+
+// checkpointPowerOperation records an operation left when the controller
+// shut down. A finished action is recorded as its reconcile would have. An
+// unfinished one keeps its in progress condition, saying how far it got, so
+// the next controller finds it interrupted and powers the server again.
+func (r *ServerReconciler) checkpointPowerOperation(ctx context.Context, op *powerOperation) {
+	var reader client.Reader = r.Client
+	if r.APIReader != nil {
+		reader = r.APIReader
+	}
+	var server baremetalcontrollerv1.Server
+	if err := reader.Get(ctx, client.ObjectKeyFromObject(op.server), &server); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to checkpoint power action", "server", op.server.Name)
+		return
+	}
+
+	if op.done && !errors.Is(op.err, context.Canceled) {
+		_, _ = r.completePowerOperation(ctx, &server, op)
+		return
+	}
+	doing, _ := describeAction(op.action)
+	progress := "before it started"
+	if op.started {
+		progress = "while waiting for the BMC, which may have applied it"
+	}
+	meta.SetStatusCondition(&server.Status.Conditions, metav1.Condition{
+		Type:               baremetalcontrollerv1.ConditionOperationInProgress,
+		Status:             metav1.ConditionTrue,
+		Reason:             operationReason(op.action),
+		Message:            fmt.Sprintf("%s was interrupted by a controller shutdown %s", doing, progress),
+		ObservedGeneration: server.Generation,
+	})
+	r.updateStatus(ctx, &server)
+}
+
+// interruptedPowerOperation clears an in progress condition left behind by
+// a controller restart, since the action's result is lost. The server is
+// then powered again if it isn't in the requested state. It returns true if
+// the condition was changed.
+func interruptedPowerOperation(server *baremetalcontrollerv1.Server) bool {
+	previous := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionOperationInProgress)
+	if previous == nil || previous.Status != metav1.ConditionTrue {
+		return false
+	}
+	meta.SetStatusCondition(&server.Status.Conditions, metav1.Condition{
+		Type:               baremetalcontrollerv1.ConditionOperationInProgress,
+		Status:             metav1.ConditionFalse,
+		Reason:             "Interrupted",
+		Message:            fmt.Sprintf("The controller restarted before the power action finished: %s", previous.Message),
+		ObservedGeneration: server.Generation,
+	})
+	return true
+}
+
+func operationReason(action baremetalcontrollerv1.PowerState) string {
+	switch action {
+	case baremetalcontrollerv1.PowerStateOn:
+		return "PoweringOn"
+	case baremetalcontrollerv1.PowerStateReboot:
+		return "Rebooting"
+	}
+	return "PoweringOff"
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+func TestPowerOperationsShutdown(t *testing.T) {
+	operations := newPowerOperations(2, 0)
+	operations.gracePeriod = 200 * time.Millisecond
+	var mu sync.Mutex
+	checkpointed := map[string]*powerOperation{}
+	operations.checkpoint = func(_ context.Context, op *powerOperation) {
+		mu.Lock()
+		defer mu.Unlock()
+		checkpointed[op.server.Name] = op
+	}
+
+	running := make(chan struct{}, 2)
+	// finishes within the grace period
+	quick := func(context.Context, *baremetalcontrollerv1.Server, baremetalcontrollerv1.PowerState) error {
+		running <- struct{}{}
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}
+	// only returns once cancelled
+	hung := func(ctx context.Context, _ *baremetalcontrollerv1.Server, _ baremetalcontrollerv1.PowerState) error {
+		running <- struct{}{}
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	server := func(name string) *baremetalcontrollerv1.Server {
+		return &baremetalcontrollerv1.Server{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error)
+	go func() { stopped <- operations.Start(ctx) }()
+
+	operations.submit(server("quick"), baremetalcontrollerv1.PowerStateOn, quick)
+	operations.submit(server("hung"), baremetalcontrollerv1.PowerStateOff, hung)
+	<-running
+	<-running
+	// Both workers are busy, so this one stays queued
+	operations.submit(server("queued"), baremetalcontrollerv1.PowerStateOn, quick)
+	cancel()
+
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Fatalf("Start() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start() didn't return after the grace period")
+	}
+
+	if op := checkpointed["quick"]; op == nil || !op.done || op.err != nil {
+		t.Errorf("quick = %+v, want finished during the grace period", op)
+	}
+	if op := checkpointed["hung"]; op == nil || !op.started || op.err != context.Canceled {
+		t.Errorf("hung = %+v, want cancelled after the grace period", op)
+	}
+	if op := checkpointed["queued"]; op == nil || op.started {
+		t.Errorf("queued = %+v, want not started", op)
+	}
+}
+
+func TestPowerOperationsPerNamespace(t *testing.T) {
+	operations := newPowerOperations(2, 0)
+	run := func(context.Context, *baremetalcontrollerv1.Server, baremetalcontrollerv1.PowerState) error {
+		return nil
+	}
+	teamA := &baremetalcontrollerv1.Server{ObjectMeta: metav1.ObjectMeta{Name: "worker-01", Namespace: "team-a"}}
+	teamB := &baremetalcontrollerv1.Server{ObjectMeta: metav1.ObjectMeta{Name: "worker-01", Namespace: "team-b"}}
+
+	// Servers of the same name in different namespaces are separate
+	// servers, so one's queued action doesn't stand in for the other's
+	operations.submit(teamA, baremetalcontrollerv1.PowerStateOn, run)
+	operations.submit(teamB, baremetalcontrollerv1.PowerStateOff, run)
+	if len(operations.queue) != 2 {
+		t.Fatalf("%d actions queued, want 2", len(operations.queue))
+	}
+
+	operations.forget(client.ObjectKeyFromObject(teamA))
+	if _, inFlight := operations.take(client.ObjectKeyFromObject(teamA)); inFlight {
+		t.Errorf("action of %s in flight after forget()", client.ObjectKeyFromObject(teamA))
+	}
+	if _, inFlight := operations.take(client.ObjectKeyFromObject(teamB)); !inFlight {
+		t.Errorf("action of %s not in flight after forgetting %s", client.ObjectKeyFromObject(teamB), client.ObjectKeyFromObject(teamA))
+	}
+}
+
+func TestInterruptedPowerOperation(t *testing.T) {
+	tests := []struct {
+		name        string
+		condition   *metav1.Condition
+		wantChanged bool
+		wantMessage string
+	}{
+		{name: "no operation"},
+		{
+			name:      "finished",
+			condition: &metav1.Condition{Status: metav1.ConditionFalse, Reason: "Succeeded", Message: "Powered on"},
+		},
+		{
+			name: "checkpointed",
+			condition: &metav1.Condition{Status: metav1.ConditionTrue, Reason: "PoweringOn",
+				Message: "Powering on was interrupted by a controller shutdown before it started"},
+			wantChanged: true,
+			wantMessage: "The controller restarted before the power action finished: Powering on was interrupted by a controller shutdown before it started",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &baremetalcontrollerv1.Server{}
+			if tt.condition != nil {
+				tt.condition.Type = baremetalcontrollerv1.ConditionOperationInProgress
+				meta.SetStatusCondition(&server.Status.Conditions, *tt.condition)
+			}
+			if changed := interruptedPowerOperation(server); changed != tt.wantChanged {
+				t.Fatalf("interruptedPowerOperation() = %v, want %v", changed, tt.wantChanged)
+			}
+			if !tt.wantChanged {
+				return
+			}
+			condition := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionOperationInProgress)
+			if condition.Status != metav1.ConditionFalse || condition.Reason != "Interrupted" || condition.Message != tt.wantMessage {
+				t.Errorf("condition = %+v, want Interrupted with %q", condition, tt.wantMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/index"
+	"github.com/Unbounder1/bare-metal-controller/internal/listing"
+)
+
+// defaultPowerActionTimeout applies when spec.timeout is not set
+const defaultPowerActionTimeout = 15 * time.Minute
+
+// PowerActionReconciler executes PowerActions. Like RebootCampaignReconciler
+// it only changes spec.powerState of the targets and waits for their status,
+// so ServerReconciler does the actual power actions.
+type PowerActionReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Now returns the current time, for start times and timeouts
+	Now func() time.Time
+
+	// NamespacedServers is set when Servers are namespaced, so servers
+	// listed by name alone are looked up in every namespace
+	NamespacedServers bool
+}
+
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=poweractions,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=poweractions/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=poweractions/finalizers,verbs=update
+
+// Reconcile starts the action once its start time has passed and moves every
+// target towards the requested state until all of them succeeded or failed.
+func (r *PowerActionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var action baremetalcontrollerv1.PowerAction
+	if err := r.Get(ctx, req.NamespacedName, &action); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if action.Status.Phase == baremetalcontrollerv1.PowerActionPhaseSucceeded ||
+		action.Status.Phase == baremetalcontrollerv1.PowerActionPhaseFailed {
+		return ctrl.Result{}, nil
+	}
+
+	now := r.now()
+	if action.Spec.StartTime != nil && now.Before(action.Spec.StartTime.Time) {
+		if action.Status.Phase != baremetalcontrollerv1.PowerActionPhaseScheduled {
+			action.Status.Phase = baremetalcontrollerv1.PowerActionPhaseScheduled
+			if err := r.Status().Update(ctx, &action); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{RequeueAfter: action.Spec.StartTime.Sub(now)}, nil
+	}
+
+	// Resolve the targets once, so servers labelled later aren't affected
+	if action.Status.StartTime == nil {
+		if err := r.selectTargets(ctx, &action); err != nil {
+			return ctrl.Result{}, err
+		}
+		started := metav1.NewTime(now)
+		action.Status.StartTime = &started
+		action.Status.Phase = baremetalcontrollerv1.PowerActionPhaseRunning
+	}
+
+	done := true
+	failed := false
+	for i := range action.Status.Targets {
+		target := &action.Status.Targets[i]
+		if err := r.advance(ctx, &action, target); err != nil {
+			logger.Error(err, "Failed to advance power action", "action", action.Name, "server", target.Name)
+			target.Message = err.Error()
+		}
+		switch target.Phase {
+		case baremetalcontrollerv1.PowerActionTargetSucceeded:
+		case baremetalcontrollerv1.PowerActionTargetFailed:
+			failed = true
+		default:
+			done = false
+		}
+	}
+
+	if done {
+		completed := metav1.NewTime(now)
+		action.Status.CompletionTime = &completed
+		action.Status.Phase = baremetalcontrollerv1.PowerActionPhaseSucceeded
+		if failed {
+			action.Status.Phase = baremetalcontrollerv1.PowerActionPhaseFailed
+		}
+	}
+
+	if err := r.Status().Update(ctx, &action); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if done {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: 15 * time.Second}, nil
+}
+
+func (r *PowerActionReconciler) selectTargets(ctx context.Context, action *baremetalcontrollerv1.PowerAction) error {
+	targets := map[client.ObjectKey]bool{}
+	for _, name := range action.Spec.Servers {
+		key, err := r.serverKey(ctx, name)
+		if err != nil {
+			return err
+		}
+		targets[key] = true
+	}
+
+	if action.Spec.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(action.Spec.Selector)
+		if err != nil {
+			return fmt.Errorf("invalid selector: %w", err)
+		}
+		err = listing.Servers(ctx, r, 0, func(server *baremetalcontrollerv1.Server) error {
+			targets[client.ObjectKeyFromObject(server)] = true
+			return nil
+		}, client.MatchingLabelsSelector{Selector: selector})
+		if err != nil {
+			return err
+		}
+	}
+
+	sorted := make([]client.ObjectKey, 0, len(targets))
+	for key := range targets {
+		sorted = append(sorted, key)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Namespace != sorted[j].Namespace {
+			return sorted[i].Namespace < sorted[j].Namespace
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	phase := baremetalcontrollerv1.PowerActionTargetPoweringOff
+	if action.Spec.Action == baremetalcontrollerv1.PowerActionOn {
+		phase = baremetalcontrollerv1.PowerActionTargetPoweringOn
+	}
+
+	action.Status.Targets = nil
+	for _, key := range sorted {
+		action.Status.Targets = append(action.Status.Targets, baremetalcontrollerv1.PowerActionTargetStatus{
+			Name:      key.Name,
+			Namespace: key.Namespace,
+			Phase:     phase,
+		})
+	}
+	return nil
+}
+
+// serverKey returns the key of a server of spec.servers, given as a name or
+// as <namespace>/<name>. Servers that don't exist keep their name, so
+// their target fails.
+func (r *PowerActionReconciler) serverKey(ctx context.Context, name string) (client.ObjectKey, error) {
+	key, ok := index.ParseServerPath(name)
+	if !ok || key.Namespace != "" || !r.NamespacedServers {
+		return key, nil
+	}
+	server, err := index.ServerByName(ctx, r, name, true)
+	if err != nil || server == nil {
+		return key, err
+	}
+	return client.ObjectKeyFromObject(server), nil
+}
+
+// advance moves a target to the next phase once the server reached the
+// state of the current one.
+func (r *PowerActionReconciler) advance(ctx context.Context, action *baremetalcontrollerv1.PowerAction, target *baremetalcontrollerv1.PowerActionTargetStatus) error {
+	if target.Phase == baremetalcontrollerv1.PowerActionTargetSucceeded ||
+		target.Phase == baremetalcontrollerv1.PowerActionTargetFailed {
+		return nil
+	}
+
+	var server baremetalcontrollerv1.Server
+	if err := r.Get(ctx, types.NamespacedName{Namespace: target.Namespace, Name: target.Name}, &server); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.finish(target, baremetalcontrollerv1.PowerActionTargetFailed, "Server not found")
+			return nil
+		}
+		return err
+	}
+
+	if server.Status.Status == baremetalcontrollerv1.StatusFailed {
+		r.finish(target, baremetalcontrollerv1.PowerActionTargetFailed, fmt.Sprintf("Server failed: %s", server.Status.Message))
+		return nil
+	}
+
+	timeout := defaultPowerActionTimeout
+	if action.Spec.Timeout != nil {
+		timeout = action.Spec.Timeout.Duration
+	}
+	timedOut := r.now().After(action.Status.StartTime.Add(timeout))
+
+	switch target.Phase {
+	case baremetalcontrollerv1.PowerActionTargetPoweringOff:
+		if err := r.setPowerState(ctx, &server, baremetalcontrollerv1.PowerStateOff); err != nil {
+			return err
+		}
+		if server.Status.Status != baremetalcontrollerv1.StatusOffline {
+			if timedOut {
+				r.finish(target, baremetalcontrollerv1.PowerActionTargetFailed, "Timed out waiting for server to power off")
+				return nil
+			}
+			target.Message = "Waiting for server to power off"
+			return nil
+		}
+		if action.Spec.Action != baremetalcontrollerv1.PowerActionCycle {
+			r.finish(target, baremetalcontrollerv1.PowerActionTargetSucceeded, "")
+			return nil
+		}
+		target.Phase = baremetalcontrollerv1.PowerActionTargetPoweringOn
+		fallthrough
+
+	case baremetalcontrollerv1.PowerActionTargetPoweringOn:
+		if err := r.setPowerState(ctx, &server, baremetalcontrollerv1.PowerStateOn); err != nil {
+			return err
+		}
+		if server.Status.Status != baremetalcontrollerv1.StatusActive {
+			if timedOut {
+				r.finish(target, baremetalcontrollerv1.PowerActionTargetFailed, "Timed out waiting for server to power on")
+				return nil
+			}
+			target.Message = "Waiting for server to power on"
+			return nil
+		}
+		r.finish(target, baremetalcontrollerv1.PowerActionTargetSucceeded, "")
+	}
+	return nil
+}
+
+func (r *PowerActionReconciler) setPowerState(ctx context.Context, server *baremetalcontrollerv1.Server, state baremetalcontrollerv1.PowerState) error {
+	if server.Spec.PowerState == state {
+		return nil
+	}
+	server.Spec.PowerState = state
+	if err := r.Update(ctx, server); err != nil {
+		return fmt.Errorf("failed to power %s server: %w", state, err)
+	}
+	return nil
+}
+
+func (r *PowerActionReconciler) finish(target *baremetalcontrollerv1.PowerActionTargetStatus, phase baremetalcontrollerv1.PowerActionTargetPhase, message string) {
+	now := metav1.NewTime(r.now())
+	target.Phase = phase
+	target.Message = message
+	target.CompletedAt = &now
+}
+
+func (r *PowerActionReconciler) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PowerActionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&baremetalcontrollerv1.PowerAction{}).
+		Named("poweraction").
+		Complete(r)
+}
@@ -0,0 +1,198 @@
+// internal/controller/server_controller_test.go
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+var _ = Describe("PowerAction Controller", func() {
+	const actionName = "test-action"
+
+	var (
+		ctx        context.Context
+		reconciler *PowerActionReconciler
+		now        time.Time
+	)
+
+	serverNames := []string{"action-server-a", "action-server-b"}
+
+	reconcileAction := func() reconcile.Result {
+		result, err := reconciler.Reconcile(ctx, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: actionName},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return result
+	}
+
+	getAction := func() *baremetalcontrollerv1.PowerAction {
+		action := &baremetalcontrollerv1.PowerAction{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: actionName}, action)).To(Succeed())
+		return action
+	}
+
+	getServer := func(name string) *baremetalcontrollerv1.Server {
+		server := &baremetalcontrollerv1.Server{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: name}, server)).To(Succeed())
+		return server
+	}
+
+	// Simulates ServerReconciler reporting a new status
+	setServerStatus := func(name string, status baremetalcontrollerv1.CurrentStatus) {
+		server := getServer(name)
+		server.Status.Status = status
+		Expect(k8sClient.Status().Update(ctx, server)).To(Succeed())
+	}
+
+	createAction := func(spec baremetalcontrollerv1.PowerActionSpec) {
+		action := &baremetalcontrollerv1.PowerAction{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: actionName,
+			},
+			Spec: spec,
+		}
+		Expect(k8sClient.Create(ctx, action)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		now = time.Date(2025, time.June, 7, 3, 0, 0, 0, time.UTC)
+		reconciler = &PowerActionReconciler{
+			Client: k8sClient,
+			Scheme: k8sClient.Scheme(),
+			Now:    func() time.Time { return now },
+		}
+
+		for _, name := range serverNames {
+			server := &baremetalcontrollerv1.Server{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   name,
+					Labels: map[string]string{"pool": "action-test"},
+				},
+				Spec: baremetalcontrollerv1.ServerSpec{
+					PowerState: baremetalcontrollerv1.PowerStateOn,
+					Type:       baremetalcontrollerv1.ControlTypeWOL,
+					Control: baremetalcontrollerv1.ControlSpecs{
+						WOL: &baremetalcontrollerv1.WOLSpecs{
+							Address:    "192.168.1.130",
+							MACAddress: "00:11:22:33:44:77",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, server)).To(Succeed())
+			setServerStatus(name, baremetalcontrollerv1.StatusActive)
+		}
+	})
+
+	AfterEach(func() {
+		action := &baremetalcontrollerv1.PowerAction{}
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: actionName}, action); err == nil {
+			Expect(k8sClient.Delete(ctx, action)).To(Succeed())
+		}
+		for _, name := range serverNames {
+			server := &baremetalcontrollerv1.Server{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{Name: name}, server); err == nil {
+				Expect(k8sClient.Delete(ctx, server)).To(Succeed())
+			}
+		}
+	})
+
+	It("should cycle all selected servers and report each result", func() {
+		createAction(baremetalcontrollerv1.PowerActionSpec{
+			Action: baremetalcontrollerv1.PowerActionCycle,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"pool": "action-test"},
+			},
+		})
+
+		reconcileAction()
+		action := getAction()
+		Expect(action.Status.Phase).To(Equal(baremetalcontrollerv1.PowerActionPhaseRunning))
+		Expect(action.Status.Targets).To(HaveLen(2))
+		for i, name := range serverNames {
+			Expect(action.Status.Targets[i].Phase).To(Equal(baremetalcontrollerv1.PowerActionTargetPoweringOff))
+			Expect(getServer(name).Spec.PowerState).To(Equal(baremetalcontrollerv1.PowerStateOff))
+			setServerStatus(name, baremetalcontrollerv1.StatusOffline)
+		}
+
+		reconcileAction()
+		for i, name := range serverNames {
+			Expect(getAction().Status.Targets[i].Phase).To(Equal(baremetalcontrollerv1.PowerActionTargetPoweringOn))
+			Expect(getServer(name).Spec.PowerState).To(Equal(baremetalcontrollerv1.PowerStateOn))
+		}
+
+		setServerStatus(serverNames[0], baremetalcontrollerv1.StatusActive)
+		setServerStatus(serverNames[1], baremetalcontrollerv1.StatusFailed)
+		reconcileAction()
+		action = getAction()
+		Expect(action.Status.Targets[0].Phase).To(Equal(baremetalcontrollerv1.PowerActionTargetSucceeded))
+		Expect(action.Status.Targets[1].Phase).To(Equal(baremetalcontrollerv1.PowerActionTargetFailed))
+		Expect(action.Status.Phase).To(Equal(baremetalcontrollerv1.PowerActionPhaseFailed))
+		Expect(action.Status.CompletionTime).NotTo(BeNil())
+	})
+
+	It("should wait for the start time", func() {
+		start := metav1.NewTime(now.Add(time.Hour))
+		createAction(baremetalcontrollerv1.PowerActionSpec{
+			Action:    baremetalcontrollerv1.PowerActionOff,
+			Servers:   []string{serverNames[0]},
+			StartTime: &start,
+		})
+
+		result := reconcileAction()
+		Expect(result.RequeueAfter).To(Equal(time.Hour))
+		Expect(getAction().Status.Phase).To(Equal(baremetalcontrollerv1.PowerActionPhaseScheduled))
+		Expect(getServer(serverNames[0]).Spec.PowerState).To(Equal(baremetalcontrollerv1.PowerStateOn))
+
+		now = now.Add(time.Hour)
+		reconcileAction()
+		action := getAction()
+		Expect(action.Status.Targets).To(HaveLen(1))
+		Expect(getServer(serverNames[0]).Spec.PowerState).To(Equal(baremetalcontrollerv1.PowerStateOff))
+	})
+
+	It("should fail targets that don't reach the state before the timeout", func() {
+		createAction(baremetalcontrollerv1.PowerActionSpec{
+			Action:  baremetalcontrollerv1.PowerActionOff,
+			Servers: []string{serverNames[0], "missing-server"},
+			Timeout: &metav1.Duration{Duration: 10 * time.Minute},
+		})
+
+		reconcileAction()
+		action := getAction()
+		Expect(action.Status.Targets[0].Name).To(Equal(serverNames[0]))
+		Expect(action.Status.Targets[0].Phase).To(Equal(baremetalcontrollerv1.PowerActionTargetPoweringOff))
+		Expect(action.Status.Targets[1].Message).To(Equal("Server not found"))
+
+		now = now.Add(11 * time.Minute)
+		reconcileAction()
+		action = getAction()
+		Expect(action.Status.Targets[0].Phase).To(Equal(baremetalcontrollerv1.PowerActionTargetFailed))
+		Expect(action.Status.Phase).To(Equal(baremetalcontrollerv1.PowerActionPhaseFailed))
+	})
+})
@@ -0,0 +1,69 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/budget"
+)
+
+// powerBudgetRetryInterval is how often a server held off by a power budget
+// checks the budget again
+const powerBudgetRetryInterval = 30 * time.Second
+
+// holdForPowerBudget keeps a server off while powering it on would exceed a
+// PowerBudget, and clears the condition once it no longer waits. It returns
+// true while the server has to wait.
+func (r *ServerReconciler) holdForPowerBudget(ctx context.Context, server *baremetalcontrollerv1.Server, currentState baremetalcontrollerv1.PowerState) (bool, error) {
+	if server.Spec.PowerState.Settled() != baremetalcontrollerv1.PowerStateOn || currentState == baremetalcontrollerv1.PowerStateOn {
+		if meta.RemoveStatusCondition(&server.Status.Conditions, baremetalcontrollerv1.ConditionPowerBudgetExceeded) {
+			r.updateStatus(ctx, server)
+		}
+		return false, nil
+	}
+
+	err := budget.Check(ctx, r.Client, server, nil)
+	var exceeded *budget.ExceededError
+	if !errors.As(err, &exceeded) {
+		// The power action that follows updates the status
+		meta.RemoveStatusCondition(&server.Status.Conditions, baremetalcontrollerv1.ConditionPowerBudgetExceeded)
+		return false, err
+	}
+
+	if !meta.IsStatusConditionTrue(server.Status.Conditions, baremetalcontrollerv1.ConditionPowerBudgetExceeded) {
+		log.FromContext(ctx).Info("Holding off power on", "server", server.Name, "budget", exceeded.Budget, "reason", exceeded.Message)
+		r.event(server, corev1.EventTypeWarning, "PowerBudgetExceeded", "Holding off power on: %s", exceeded.Error())
+	}
+	meta.SetStatusCondition(&server.Status.Conditions, metav1.Condition{
+		Type:               baremetalcontrollerv1.ConditionPowerBudgetExceeded,
+		Status:             metav1.ConditionTrue,
+		Reason:             "WaitingForBudget",
+		Message:            exceeded.Error(),
+		ObservedGeneration: server.Generation,
+	})
+	r.updateStatus(ctx, server)
+	return true, nil
+}
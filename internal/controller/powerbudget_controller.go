@@ -0,0 +1,86 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/budget"
+)
+
+// PowerBudgetReconciler reports how much of each PowerBudget is in use.
+// ServerReconciler and the autoscaler provider enforce the budgets.
+type PowerBudgetReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=powerbudgets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=powerbudgets/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=powerbudgets/finalizers,verbs=update
+
+// Reconcile counts the powered on and waiting servers of a budget.
+func (r *PowerBudgetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var powerBudget baremetalcontrollerv1.PowerBudget
+	if err := r.Get(ctx, req.NamespacedName, &powerBudget); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	status, err := budget.Usage(ctx, r.Client, &powerBudget)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to count servers", "powerBudget", powerBudget.Name)
+		return ctrl.Result{}, nil
+	}
+	if equality.Semantic.DeepEqual(status, powerBudget.Status) {
+		return ctrl.Result{}, nil
+	}
+	powerBudget.Status = status
+	return ctrl.Result{}, r.Status().Update(ctx, &powerBudget)
+}
+
+// budgetsForServer enqueues every budget, since a server may have left the
+// selector of a budget as well as joined one
+func (r *PowerBudgetReconciler) budgetsForServer(ctx context.Context, _ client.Object) []reconcile.Request {
+	var budgets baremetalcontrollerv1.PowerBudgetList
+	if err := r.List(ctx, &budgets); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list power budgets")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(budgets.Items))
+	for _, b := range budgets.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&b)})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PowerBudgetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&baremetalcontrollerv1.PowerBudget{}).
+		Watches(&baremetalcontrollerv1.Server{}, handler.EnqueueRequestsFromMapFunc(r.budgetsForServer)).
+		Named("powerbudget").
+		Complete(r)
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+var _ = Describe("PowerBudget Controller", func() {
+	const budgetName = "test-budget"
+
+	var ctx context.Context
+
+	// budget-server-a is on, the others are requested on in name order
+	serverNames := []string{"budget-server-a", "budget-server-b", "budget-server-c"}
+
+	getServer := func(name string) *baremetalcontrollerv1.Server {
+		server := &baremetalcontrollerv1.Server{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: name}, server)).To(Succeed())
+		return server
+	}
+
+	setServerStatus := func(name string, status baremetalcontrollerv1.CurrentStatus) {
+		server := getServer(name)
+		server.Status.Status = status
+		Expect(k8sClient.Status().Update(ctx, server)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		for _, name := range serverNames {
+			server := &baremetalcontrollerv1.Server{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   name,
+					Labels: map[string]string{"rack": "budget-test"},
+				},
+				Spec: baremetalcontrollerv1.ServerSpec{
+					PowerState: baremetalcontrollerv1.PowerStateOn,
+					Type:       baremetalcontrollerv1.ControlTypeWOL,
+					Control: baremetalcontrollerv1.ControlSpecs{
+						WOL: &baremetalcontrollerv1.WOLSpecs{
+							Address:    "192.168.1.140",
+							MACAddress: "00:11:22:33:44:88",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, server)).To(Succeed())
+			setServerStatus(name, baremetalcontrollerv1.StatusOffline)
+		}
+		setServerStatus(serverNames[0], baremetalcontrollerv1.StatusActive)
+
+		budget := &baremetalcontrollerv1.PowerBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: budgetName},
+			Spec: baremetalcontrollerv1.PowerBudgetSpec{
+				Selector: metav1.LabelSelector{
+					MatchLabels: map[string]string{"rack": "budget-test"},
+				},
+				MaxPoweredOn: ptr.To(int32(3)),
+				MaxWatts:     ptr.To(int32(1000)),
+				ServerWatts:  ptr.To(int32(400)),
+			},
+		}
+		Expect(k8sClient.Create(ctx, budget)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		budget := &baremetalcontrollerv1.PowerBudget{}
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: budgetName}, budget); err == nil {
+			Expect(k8sClient.Delete(ctx, budget)).To(Succeed())
+		}
+		for _, name := range serverNames {
+			server := &baremetalcontrollerv1.Server{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{Name: name}, server); err == nil {
+				Expect(k8sClient.Delete(ctx, server)).To(Succeed())
+			}
+		}
+	})
+
+	It("should report the powered on and waiting servers", func() {
+		reconciler := &PowerBudgetReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: budgetName},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		budget := &baremetalcontrollerv1.PowerBudget{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: budgetName}, budget)).To(Succeed())
+		Expect(budget.Status.PoweredOn).To(Equal(int32(1)))
+		Expect(budget.Status.Watts).To(Equal(int32(400)))
+		Expect(budget.Status.Waiting).To(Equal(serverNames[1:]))
+	})
+
+	It("should hold off servers that would exceed the budget in name order", func() {
+		reconciler := &ServerReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+
+		// Two servers fit the count, but not the 1000W at 400W each
+		held, err := reconciler.holdForPowerBudget(ctx, getServer(serverNames[1]), baremetalcontrollerv1.PowerStateOff)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(held).To(BeFalse())
+
+		server := getServer(serverNames[2])
+		held, err = reconciler.holdForPowerBudget(ctx, server, baremetalcontrollerv1.PowerStateOff)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(held).To(BeTrue())
+		condition := meta.FindStatusCondition(getServer(serverNames[2]).Status.Conditions, baremetalcontrollerv1.ConditionPowerBudgetExceeded)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Message).To(ContainSubstring("would draw 1200W"))
+
+		// Once the first server is off, the last one may be powered on
+		setServerStatus(serverNames[0], baremetalcontrollerv1.StatusOffline)
+		server = getServer(serverNames[0])
+		server.Spec.PowerState = baremetalcontrollerv1.PowerStateOff
+		Expect(k8sClient.Update(ctx, server)).To(Succeed())
+
+		held, err = reconciler.holdForPowerBudget(ctx, getServer(serverNames[2]), baremetalcontrollerv1.PowerStateOff)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(held).To(BeFalse())
+	})
+})
@@ -0,0 +1,142 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/power"
+)
+
+// powerCapRefreshInterval limits how often the power draw is read from the
+// BMC while the spec is unchanged
+const powerCapRefreshInterval = time.Minute
+
+// reconcilePowerCap applies spec.powerCapWatts through the BMC, which
+// enforces it whether or not the server is on, and reports the active limit
+// and power draw. It returns true if the status changed.
+func (r *ServerReconciler) reconcilePowerCap(ctx context.Context, server *baremetalcontrollerv1.Server) bool {
+	var desired int32
+	if server.Spec.PowerCapWatts != nil {
+		desired = *server.Spec.PowerCapWatts
+	}
+	condition := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionPowerCapCompliant)
+	if desired == 0 && condition == nil {
+		return false
+	}
+	if condition != nil && condition.ObservedGeneration == server.Generation && server.Status.PowerCap != nil &&
+		server.Status.PowerCap.LastUpdated != nil && time.Since(server.Status.PowerCap.LastUpdated.Time) < powerCapRefreshInterval {
+		return false
+	}
+
+	before := server.Status.DeepCopy()
+	setCompliant := func(status metav1.ConditionStatus, reason string, message string) {
+		meta.SetStatusCondition(&server.Status.Conditions, metav1.Condition{
+			Type:               baremetalcontrollerv1.ConditionPowerCapCompliant,
+			Status:             status,
+			Reason:             reason,
+			Message:            message,
+			ObservedGeneration: server.Generation,
+		})
+	}
+
+	// Once the limit is applied, the draw can come from the BMC's metric
+	// reports instead of another round trip
+	var limit power.PowerLimit
+	var err error
+	if watts, ok := r.reportedPowerDraw(server, powerCapRefreshInterval); ok && desired != 0 &&
+		condition != nil && condition.ObservedGeneration == server.Generation &&
+		server.Status.PowerCap != nil && server.Status.PowerCap.LimitWatts == desired {
+		limit = power.PowerLimit{LimitWatts: desired, ConsumedWatts: watts}
+	} else {
+		limit, err = r.applyPowerLimit(ctx, server, desired)
+	}
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to apply power cap", "server", server.Name)
+		setCompliant(metav1.ConditionUnknown, "PowerLimitUnavailable", err.Error())
+		return !equality.Semantic.DeepEqual(before, &server.Status)
+	}
+
+	if desired == 0 {
+		// The limit was lifted
+		server.Status.PowerCap = nil
+		meta.RemoveStatusCondition(&server.Status.Conditions, baremetalcontrollerv1.ConditionPowerCapCompliant)
+		return true
+	}
+
+	now := metav1.Now()
+	server.Status.PowerCap = &baremetalcontrollerv1.PowerCapStatus{
+		LimitWatts:    limit.LimitWatts,
+		ConsumedWatts: limit.ConsumedWatts,
+		LastUpdated:   &now,
+	}
+	switch {
+	case limit.LimitWatts != desired:
+		setCompliant(metav1.ConditionFalse, "LimitNotApplied",
+			fmt.Sprintf("BMC reports a power limit of %dW, expected %dW", limit.LimitWatts, desired))
+	case limit.ConsumedWatts > desired:
+		setCompliant(metav1.ConditionFalse, "OverLimit",
+			fmt.Sprintf("Drawing %dW, above the %dW cap", limit.ConsumedWatts, desired))
+	default:
+		setCompliant(metav1.ConditionTrue, "WithinLimit",
+			fmt.Sprintf("Drawing %dW of the %dW cap", limit.ConsumedWatts, desired))
+	}
+	return !equality.Semantic.DeepEqual(before, &server.Status)
+}
+
+// applyPowerLimit sets the BMC power limit if it differs from the desired
+// one, and returns the limit and draw read back afterwards
+func (r *ServerReconciler) applyPowerLimit(ctx context.Context, server *baremetalcontrollerv1.Server, watts int32) (power.PowerLimit, error) {
+	switch server.Spec.Type {
+	case baremetalcontrollerv1.ControlTypeIPMI:
+		target, err := r.endpoints().IPMI(ctx, server)
+		if err != nil {
+			return power.PowerLimit{}, err
+		}
+		limit, err := r.IPMIClient.GetPowerLimit(ctx, target.Address, target.Username, target.Password)
+		if err != nil || limit.LimitWatts == watts {
+			return limit, err
+		}
+		if err := r.IPMIClient.SetPowerLimit(ctx, target.Address, target.Username, target.Password, watts); err != nil {
+			return power.PowerLimit{}, err
+		}
+		return r.IPMIClient.GetPowerLimit(ctx, target.Address, target.Username, target.Password)
+
+	case baremetalcontrollerv1.ControlTypeRedfish:
+		target, err := r.endpoints().Redfish(ctx, server)
+		if err != nil {
+			return power.PowerLimit{}, err
+		}
+		limit, err := r.RedfishClient.GetPowerLimit(target)
+		if err != nil || limit.LimitWatts == watts {
+			return limit, err
+		}
+		if err := r.RedfishClient.SetPowerLimit(target, watts); err != nil {
+			return power.PowerLimit{}, err
+		}
+		return r.RedfishClient.GetPowerLimit(target)
+	}
+	return power.PowerLimit{}, fmt.Errorf("power capping requires the ipmi or redfish control type")
+}
@@ -0,0 +1,290 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/power"
+)
+
+const (
+	// reachabilityMaxAge is how long a probe result is used before the
+	// server is probed again
+	reachabilityMaxAge = 10 * time.Second
+	// reachabilityRetryInterval requeues a server waiting for a probe, in
+	// case the event for its completion is dropped
+	reachabilityRetryInterval = 10 * time.Second
+	// maxConcurrentProbes bounds the pings in flight at once
+	maxConcurrentProbes = 64
+	// pingSampleInterval is how often a probe is kept in status.ping
+	pingSampleInterval = time.Minute
+	// maxPingSamples is the number of probes kept in status.ping
+	maxPingSamples = 10
+)
+
+// reachabilityProbes checks servers in the background, so a reconcile never
+// waits seconds for an unreachable host. A finished probe triggers a
+// reconcile of its server, which uses the cached result.
+type reachabilityProbes struct {
+	prober func(power.HealthCheck) power.Prober
+	relay  power.WolRelay
+	slots  chan struct{}
+	events chan event.GenericEvent
+
+	// ctx is cancelled when the manager stops, aborting running probes
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	results map[client.ObjectKey]*probeResult
+}
+
+type probeResult struct {
+	address   string
+	relay     string
+	check     healthCheck
+	reachable bool
+	// successes is the number of successful probes in a row, carried over
+	// from the previous result of the same check
+	successes int
+	// stats are nil for probes through a relay, which only reports
+	// reachability
+	stats   *power.PingStats
+	checked time.Time
+	running bool
+	// counted is set once a reconcile counted the result as a failed check,
+	// so reconciles reusing it don't count it again
+	counted bool
+}
+
+func newReachabilityProbes(prober func(power.HealthCheck) power.Prober, relay power.WolRelay) *reachabilityProbes {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &reachabilityProbes{
+		prober:  prober,
+		relay:   relay,
+		slots:   make(chan struct{}, maxConcurrentProbes),
+		events:  make(chan event.GenericEvent, 1024),
+		ctx:     ctx,
+		cancel:  cancel,
+		results: map[client.ObjectKey]*probeResult{},
+	}
+}
+
+// Start waits for ctx to be done, then aborts the running probes
+func (p *reachabilityProbes) Start(ctx context.Context) error {
+	<-ctx.Done()
+	p.cancel()
+	return nil
+}
+
+// reachable returns the last result of the check for the server's address.
+// If there is none, or it is too old, a probe is started and ok is false.
+func (p *reachabilityProbes) reachable(server *baremetalcontrollerv1.Server, address string, check healthCheck) (reachable bool, stats *power.PingStats, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := client.ObjectKeyFromObject(server)
+	relay := wolRelay(server)
+	result, found := p.results[key]
+	same := found && result.address == address && result.relay == relay && result.check == check
+	if same && !result.running && time.Since(result.checked) <= reachabilityMaxAge {
+		return result.reachable, result.stats, true
+	}
+	if same && result.running {
+		return false, nil, false
+	}
+
+	next := &probeResult{address: address, relay: relay, check: check, running: true}
+	if same {
+		next.successes = result.successes
+	}
+	result = next
+	p.results[key] = result
+	go p.probe(key, result)
+	return false, nil, false
+}
+
+func (p *reachabilityProbes) probe(key client.ObjectKey, result *probeResult) {
+	p.slots <- struct{}{}
+	var up bool
+	var stats *power.PingStats
+	if result.relay != "" {
+		up = p.relay != nil && p.relay.IsReachable(p.ctx, result.relay, result.address)
+	} else {
+		probed := p.prober(result.check.HealthCheck).Probe(p.ctx, result.address)
+		up, stats = probed.Reachable(), &probed
+	}
+	<-p.slots
+
+	p.mu.Lock()
+	if up {
+		result.successes++
+	} else {
+		result.successes = 0
+	}
+	result.reachable = result.successes >= max(result.check.successThreshold, 1)
+	result.stats = stats
+	result.checked = time.Now()
+	result.running = false
+	p.mu.Unlock()
+
+	select {
+	case p.events <- event.GenericEvent{Object: &baremetalcontrollerv1.Server{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}}:
+	default:
+		// The server is requeued after reachabilityRetryInterval anyway
+	}
+}
+
+// expire makes the next reconcile of a server wait for a new probe, e.g.
+// because a power action was sent or its BMC reported a power change. A
+// running probe may have pinged before the change, so its result is
+// dropped as well.
+func (p *reachabilityProbes) expire(key client.ObjectKey) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.results, key)
+}
+
+// count reports whether the server's probe result may count as a failed
+// check, which it may only once. Servers without a result, e.g. ones
+// without an address, always count.
+func (p *reachabilityProbes) count(key client.ObjectKey) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	result, ok := p.results[key]
+	if !ok {
+		return true
+	}
+	if result.running || result.counted {
+		return false
+	}
+	result.counted = true
+	return true
+}
+
+// forget drops the result of a deleted server
+func (p *reachabilityProbes) forget(key client.ObjectKey) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.results, key)
+}
+
+// isReachable reports whether the server passes the check, and the round
+// trip times and losses of the probes unless they went through a relay.
+// With background probes, ok is false until a probe result is available.
+// The check's success threshold only applies to background probes.
+func (r *ServerReconciler) isReachable(ctx context.Context, server *baremetalcontrollerv1.Server, address string, check healthCheck) (reachable bool, stats *power.PingStats, ok bool) {
+	// WoL servers without a configured address can't be reached until their
+	// address is discovered
+	if address == "" {
+		return false, nil, true
+	}
+	if r.probes == nil {
+		if relay := wolRelay(server); relay != "" {
+			return r.WolRelay != nil && r.WolRelay.IsReachable(ctx, relay, address), nil, true
+		}
+		probed := r.prober(check.HealthCheck).Probe(ctx, address)
+		return probed.Reachable(), &probed, true
+	}
+	return r.probes.reachable(server, address, check)
+}
+
+// healthCheck is how an address is probed. The zero value pings it once.
+type healthCheck struct {
+	power.HealthCheck
+	// successThreshold is the number of successful probes in a row before
+	// the address counts as reachable
+	successThreshold int
+}
+
+// serverHealthCheck returns the check of spec.healthCheck
+func serverHealthCheck(server *baremetalcontrollerv1.Server) healthCheck {
+	spec := server.Spec.HealthCheck
+	if spec == nil {
+		return healthCheck{}
+	}
+	check := healthCheck{
+		HealthCheck: power.HealthCheck{
+			Method: string(spec.Method),
+			Port:   int(spec.Port),
+			Path:   spec.Path,
+		},
+		successThreshold: int(spec.SuccessThreshold),
+	}
+	if spec.Timeout != nil {
+		check.Timeout = spec.Timeout.Duration
+	}
+	return check
+}
+
+// prober returns the prober of the check, the pinger for servers without
+// one or without a prober factory
+func (r *ServerReconciler) prober(check power.HealthCheck) power.Prober {
+	if r.Probers == nil || check == (power.HealthCheck{}) {
+		return r.Pinger
+	}
+	return r.Probers.Prober(check)
+}
+
+// recordPing exports the round trip time and losses of the last probe, and
+// adds them to status.ping if the last sample is older than
+// pingSampleInterval. It returns true if the status changed.
+func (r *ServerReconciler) recordPing(server *baremetalcontrollerv1.Server, stats *power.PingStats) bool {
+	if stats == nil || stats.Sent == 0 {
+		return false
+	}
+	setPingMetrics(client.ObjectKeyFromObject(server), *stats)
+
+	status := server.Status.Ping
+	if status == nil {
+		status = &baremetalcontrollerv1.PingStatus{}
+	}
+	if n := len(status.Samples); n > 0 && time.Since(status.Samples[n-1].Time.Time) < pingSampleInterval {
+		return false
+	}
+	sample := baremetalcontrollerv1.PingSample{
+		Time:        metav1.Now(),
+		LossPercent: int32(math.Round(stats.Loss() * 100)),
+	}
+	if stats.Reachable() {
+		sample.RTT = &metav1.Duration{Duration: stats.RTT}
+	}
+	status.Samples = append(status.Samples, sample)
+	if len(status.Samples) > maxPingSamples {
+		status.Samples = status.Samples[len(status.Samples)-maxPingSamples:]
+	}
+	server.Status.Ping = status
+	return true
+}
+
+// wolRelay returns the relay that pings the server, or "" to ping it from
+// the controller
+func wolRelay(server *baremetalcontrollerv1.Server) string {
+	if server.Spec.Type != baremetalcontrollerv1.ControlTypeWOL || server.Spec.Control.WOL == nil {
+		return ""
+	}
+	return server.Spec.Control.WOL.Relay
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/power"
+)
+
+func TestReachabilitySuccessThreshold(t *testing.T) {
+	pinger := &power.MockPinger{}
+	var checks []power.HealthCheck
+	probes := newReachabilityProbes(func(check power.HealthCheck) power.Prober {
+		checks = append(checks, check)
+		return pinger
+	}, nil)
+	server := &baremetalcontrollerv1.Server{ObjectMeta: metav1.ObjectMeta{Name: "web-1"}}
+	check := healthCheck{HealthCheck: power.HealthCheck{Method: power.HealthCheckTCP, Port: 443}, successThreshold: 2}
+
+	// probe runs a probe of the server and returns its result
+	probe := func(check healthCheck) bool {
+		t.Helper()
+		if _, _, ok := probes.reachable(server, "10.0.0.5", check); ok {
+			t.Fatal("reachable() returned a result before probing")
+		}
+		select {
+		case <-probes.events:
+		case <-time.After(5 * time.Second):
+			t.Fatal("probe didn't finish")
+		}
+		reachable, _, ok := probes.reachable(server, "10.0.0.5", check)
+		if !ok {
+			t.Fatal("reachable() returned no result after probing")
+		}
+		probes.mu.Lock()
+		probes.results[client.ObjectKeyFromObject(server)].checked = time.Time{}
+		probes.mu.Unlock()
+		return reachable
+	}
+
+	pinger.Reachable = true
+	if probe(check) {
+		t.Error("reachable after one success, want two")
+	}
+	if !probe(check) {
+		t.Error("unreachable after two successes")
+	}
+	pinger.Reachable = false
+	if probe(check) {
+		t.Error("reachable after a failure")
+	}
+	pinger.Reachable = true
+	if probe(check) {
+		t.Error("reachable after one success following a failure")
+	}
+
+	// A different check starts counting again
+	other := check
+	other.Port = 8443
+	if probe(other) {
+		t.Error("reachable after one success of a changed check")
+	}
+	if checks[len(checks)-1] != other.HealthCheck {
+		t.Errorf("probed with %+v, want %+v", checks[len(checks)-1], other.HealthCheck)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/bmc"
+	"github.com/Unbounder1/bare-metal-controller/internal/power"
+)
+
+// withReason and invalidSpec tag errors like the BMC endpoints do, so
+// reasons from both are reported the same way
+var (
+	withReason  = bmc.WithReason
+	invalidSpec = bmc.InvalidSpec
+)
+
+// powerFailureReason picks the reason for a failed power action. Tagged
+// errors keep their reason, anything else is attributed to the backend used
+// for the action by the class of the error.
+func powerFailureReason(server *baremetalcontrollerv1.Server, action baremetalcontrollerv1.PowerState, err error) baremetalcontrollerv1.FailureReason {
+	if reason, ok := bmc.Reason(err); ok {
+		return reason
+	}
+
+	class := power.Classify(err)
+	if server.Spec.Type == baremetalcontrollerv1.ControlTypeWOL {
+		if action == baremetalcontrollerv1.PowerStateOn {
+			return baremetalcontrollerv1.ReasonWOLSendFailed
+		}
+		switch class {
+		case power.ClassAuthFailure:
+			return baremetalcontrollerv1.ReasonSSHAuthFailed
+		case power.ClassUnreachable:
+			return baremetalcontrollerv1.ReasonSSHUnreachable
+		}
+		return baremetalcontrollerv1.ReasonSSHCommandFailed
+	}
+
+	switch class {
+	case power.ClassAuthFailure:
+		if errors.Is(err, power.ErrCertificate) {
+			return baremetalcontrollerv1.ReasonBMCCertificateUntrusted
+		}
+		return baremetalcontrollerv1.ReasonBMCAuthFailed
+	case power.ClassUnreachable:
+		return baremetalcontrollerv1.ReasonBMCUnreachable
+	case power.ClassTransient:
+		return baremetalcontrollerv1.ReasonBMCBusy
+	case power.ClassUnsupported:
+		return baremetalcontrollerv1.ReasonBMCUnsupported
+	}
+	return baremetalcontrollerv1.ReasonBMCCommandFailed
+}
+
+// timeoutReason is reported when a server never reached the state it was
+// waiting for.
+func timeoutReason(status baremetalcontrollerv1.CurrentStatus) baremetalcontrollerv1.FailureReason {
+	switch status {
+	case baremetalcontrollerv1.StatusDraining:
+		return baremetalcontrollerv1.ReasonShutdownTimeout
+	case baremetalcontrollerv1.StatusRebooting:
+		return baremetalcontrollerv1.ReasonRebootTimeout
+	}
+	return baremetalcontrollerv1.ReasonBootTimeout
+}
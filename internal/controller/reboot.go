@@ -0,0 +1,130 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+const (
+	// rebootCheckInterval is how often a rebooting server is checked, often
+	// enough to see it go down before it is back up
+	rebootCheckInterval = 5 * time.Second
+	// rebootShutdownTimeout is how long a rebooted server may stay up
+	// before the reboot is taken to have failed, unless overridden by
+	// spec.timeouts.shutdownTimeout
+	rebootShutdownTimeout = 5 * time.Minute
+)
+
+// rebootDue reports whether the spec asks for a reboot the server didn't get
+// yet, one per generation of the spec
+func rebootDue(server *baremetalcontrollerv1.Server) bool {
+	if server.Spec.PowerState != baremetalcontrollerv1.PowerStateReboot {
+		return false
+	}
+	return server.Status.Reboot == nil || server.Status.Reboot.Generation != server.Generation
+}
+
+// recordReboot marks the reboot asked for by the spec as done, by a reboot or
+// by powering on a server that was off
+func recordReboot(server *baremetalcontrollerv1.Server) {
+	if server.Spec.PowerState != baremetalcontrollerv1.PowerStateReboot {
+		return
+	}
+	now := metav1.Now()
+	server.Status.Reboot = &baremetalcontrollerv1.RebootStatus{
+		Generation: server.Generation,
+		LastReboot: &now,
+	}
+}
+
+// rebootTimedOut reports whether a rebooting server stayed up for longer
+// than a reboot takes to shut it down
+func rebootTimedOut(server *baremetalcontrollerv1.Server, now time.Time) bool {
+	if server.Status.Reboot == nil || server.Status.Reboot.LastReboot == nil {
+		return true
+	}
+	timeout := rebootShutdownTimeout
+	if t := server.Spec.Timeouts; t != nil && t.ShutdownTimeout != nil && t.ShutdownTimeout.Duration > 0 {
+		timeout = t.ShutdownTimeout.Duration
+	}
+	return now.Sub(server.Status.Reboot.LastReboot.Time) > timeout
+}
+
+// waitInterval returns how long to wait before checking a server waiting for
+// a power change again
+func waitInterval(server *baremetalcontrollerv1.Server) time.Duration {
+	if server.Status.Status == baremetalcontrollerv1.StatusRebooting {
+		return rebootCheckInterval
+	}
+	return requeueInterval(server)
+}
+
+// reboot restarts a server that is on, by rebooting its OS over SSH or power
+// cycling it through its BMC
+func (r *ServerReconciler) reboot(ctx context.Context, server *baremetalcontrollerv1.Server) error {
+	if err := r.disarmWatchdog(ctx, server); err != nil {
+		return err
+	}
+
+	switch server.Spec.Type {
+	case baremetalcontrollerv1.ControlTypeWOL:
+		if server.Spec.Control.WOL == nil {
+			return invalidSpec("WOL config is required")
+		}
+		address := r.sshAddress(server)
+		if address == "" {
+			return fmt.Errorf("no address known for server %s to reboot", server.Name)
+		}
+		if server.Spec.Control.WOL.SSHSecretRef == nil {
+			return invalidSpec("SSH secret reference is required")
+		}
+		user, key, err := r.getSSHCredentials(ctx, server, server.Spec.Control.WOL.User, server.Spec.Control.WOL.SSHSecretRef)
+		if err != nil {
+			return err
+		}
+		return r.SSHClient.Reboot(ctx, address, user, key)
+
+	case baremetalcontrollerv1.ControlTypeIPMI:
+		target, err := r.endpoints().IPMI(ctx, server)
+		if err != nil {
+			return err
+		}
+		if target.Username == "" || target.Password == "" {
+			return invalidSpec("IPMI username and password are required")
+		}
+		return r.IPMIClient.PowerCycle(ctx, target.Address, target.Username, target.Password)
+
+	default:
+		return invalidSpec("powerState reboot is only supported for wol and ipmi servers, not %s", server.Spec.Type)
+	}
+}
+
+// describeAction returns how status messages say a power action is being
+// done and was done
+func describeAction(action baremetalcontrollerv1.PowerState) (string, string) {
+	if action == baremetalcontrollerv1.PowerStateReboot {
+		return "Rebooting", "Rebooted"
+	}
+	return fmt.Sprintf("Powering %s", action), fmt.Sprintf("Powered %s", action)
+}
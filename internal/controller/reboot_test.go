@@ -0,0 +1,109 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+func TestRebootDue(t *testing.T) {
+	tests := []struct {
+		name       string
+		powerState baremetalcontrollerv1.PowerState
+		reboot     *baremetalcontrollerv1.RebootStatus
+		want       bool
+	}{
+		{name: "never rebooted", powerState: baremetalcontrollerv1.PowerStateReboot, want: true},
+		{
+			name: "rebooted for an older generation", powerState: baremetalcontrollerv1.PowerStateReboot,
+			reboot: &baremetalcontrollerv1.RebootStatus{Generation: 2}, want: true,
+		},
+		{
+			name: "rebooted for this generation", powerState: baremetalcontrollerv1.PowerStateReboot,
+			reboot: &baremetalcontrollerv1.RebootStatus{Generation: 3},
+		},
+		{name: "not asked to reboot", powerState: baremetalcontrollerv1.PowerStateOn},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &baremetalcontrollerv1.Server{
+				ObjectMeta: metav1.ObjectMeta{Generation: 3},
+				Spec:       baremetalcontrollerv1.ServerSpec{PowerState: tt.powerState},
+				Status:     baremetalcontrollerv1.ServerStatus{Reboot: tt.reboot},
+			}
+			if got := rebootDue(server); got != tt.want {
+				t.Errorf("rebootDue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordReboot(t *testing.T) {
+	server := &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Generation: 4},
+		Spec:       baremetalcontrollerv1.ServerSpec{PowerState: baremetalcontrollerv1.PowerStateReboot},
+	}
+	recordReboot(server)
+	if rebootDue(server) {
+		t.Error("rebootDue() = true after recordReboot()")
+	}
+	if rebootTimedOut(server, time.Now()) {
+		t.Error("rebootTimedOut() = true right after the reboot")
+	}
+	if !rebootTimedOut(server, time.Now().Add(rebootShutdownTimeout+time.Second)) {
+		t.Error("rebootTimedOut() = false after the timeout")
+	}
+	server.Spec.Timeouts = &baremetalcontrollerv1.TimeoutsSpec{ShutdownTimeout: &metav1.Duration{Duration: 15 * time.Minute}}
+	if rebootTimedOut(server, time.Now().Add(rebootShutdownTimeout+time.Second)) {
+		t.Error("rebootTimedOut() = true before spec.timeouts.shutdownTimeout")
+	}
+	server.Spec.Timeouts = nil
+
+	server.Generation = 5
+	if !rebootDue(server) {
+		t.Error("rebootDue() = false for a new generation")
+	}
+
+	on := &baremetalcontrollerv1.Server{Spec: baremetalcontrollerv1.ServerSpec{PowerState: baremetalcontrollerv1.PowerStateOn}}
+	recordReboot(on)
+	if on.Status.Reboot != nil {
+		t.Errorf("recordReboot() recorded %+v for a server that wasn't asked to reboot", on.Status.Reboot)
+	}
+}
+
+func TestDescribeAction(t *testing.T) {
+	tests := []struct {
+		action      baremetalcontrollerv1.PowerState
+		doing, done string
+	}{
+		{baremetalcontrollerv1.PowerStateOn, "Powering on", "Powered on"},
+		{baremetalcontrollerv1.PowerStateOff, "Powering off", "Powered off"},
+		{baremetalcontrollerv1.PowerStateReboot, "Rebooting", "Rebooted"},
+	}
+	for _, tt := range tests {
+		doing, done := describeAction(tt.action)
+		if doing != tt.doing || done != tt.done {
+			t.Errorf("describeAction(%q) = %q, %q, want %q, %q", tt.action, doing, done, tt.doing, tt.done)
+		}
+	}
+}
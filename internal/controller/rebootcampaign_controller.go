@@ -0,0 +1,422 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/cluster"
+	"github.com/Unbounder1/bare-metal-controller/internal/drain"
+	"github.com/Unbounder1/bare-metal-controller/internal/listing"
+)
+
+// RebootCampaignReconciler rolls reboots across the servers selected by a
+// RebootCampaign. Servers are rebooted through their spec.powerState, so
+// ServerReconciler does the actual power actions.
+type RebootCampaignReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// APIReader lists pods by node without caching every pod in the cluster
+	APIReader client.Reader
+
+	// Now returns the current time, for maintenance windows
+	Now func() time.Time
+
+	// Clusters reaches the nodes of servers with spec.clusterRef
+	Clusters *cluster.Clients
+}
+
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=rebootcampaigns,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=rebootcampaigns/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=rebootcampaigns/finalizers,verbs=update
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=calendars,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list
+// +kubebuilder:rbac:groups=core,resources=pods/eviction,verbs=create
+
+// Reconcile advances each server of the campaign through drain, power off and
+// power on, starting new reboots while fewer than maxUnavailable are in
+// progress and the maintenance window is open.
+func (r *RebootCampaignReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var campaign baremetalcontrollerv1.RebootCampaign
+	if err := r.Get(ctx, req.NamespacedName, &campaign); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if campaign.Status.Phase == baremetalcontrollerv1.CampaignPhaseCompleted ||
+		campaign.Status.Phase == baremetalcontrollerv1.CampaignPhaseFailed {
+		return ctrl.Result{}, nil
+	}
+
+	// Select the servers once, so servers added later aren't rebooted
+	if campaign.Status.Phase == "" {
+		if err := r.selectServers(ctx, &campaign); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	unavailable := 0
+	for i := range campaign.Status.Servers {
+		entry := &campaign.Status.Servers[i]
+		if err := r.advance(ctx, &campaign, entry); err != nil {
+			logger.Error(err, "Failed to advance reboot", "campaign", campaign.Name, "server", entry.Name)
+			entry.Message = err.Error()
+		}
+		if isUnavailable(entry.Phase) {
+			unavailable++
+		}
+	}
+
+	maxUnavailable := campaign.Spec.MaxUnavailable
+	if maxUnavailable < 1 {
+		maxUnavailable = 1
+	}
+
+	open, nextOpen := r.canStartReboots(ctx, &campaign)
+	if open {
+		for i := range campaign.Status.Servers {
+			if unavailable >= maxUnavailable {
+				break
+			}
+			entry := &campaign.Status.Servers[i]
+			if entry.Phase != baremetalcontrollerv1.ServerRebootPending {
+				continue
+			}
+
+			now := metav1.Now()
+			entry.StartedAt = &now
+			entry.Phase = baremetalcontrollerv1.ServerRebootDraining
+			if !campaign.Spec.Drain {
+				entry.Phase = baremetalcontrollerv1.ServerRebootPoweringOff
+			}
+			if err := r.advance(ctx, &campaign, entry); err != nil {
+				entry.Message = err.Error()
+			}
+			unavailable++
+		}
+	}
+
+	done := true
+	failed := false
+	for _, entry := range campaign.Status.Servers {
+		switch entry.Phase {
+		case baremetalcontrollerv1.ServerRebootFailed:
+			failed = true
+		case baremetalcontrollerv1.ServerRebootCompleted, baremetalcontrollerv1.ServerRebootSkipped:
+		default:
+			done = false
+		}
+	}
+
+	if done {
+		now := metav1.Now()
+		campaign.Status.CompletionTime = &now
+		campaign.Status.Phase = baremetalcontrollerv1.CampaignPhaseCompleted
+		if failed {
+			campaign.Status.Phase = baremetalcontrollerv1.CampaignPhaseFailed
+		}
+	}
+
+	if err := r.Status().Update(ctx, &campaign); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if done {
+		return ctrl.Result{}, nil
+	}
+	if unavailable == 0 && !open {
+		return ctrl.Result{RequeueAfter: nextOpen}, nil
+	}
+	return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+}
+
+func (r *RebootCampaignReconciler) selectServers(ctx context.Context, campaign *baremetalcontrollerv1.RebootCampaign) error {
+	selector, err := metav1.LabelSelectorAsSelector(&campaign.Spec.Selector)
+	if err != nil {
+		return fmt.Errorf("invalid selector: %w", err)
+	}
+
+	campaign.Status.Servers = nil
+	err = listing.Servers(ctx, r, 0, func(server *baremetalcontrollerv1.Server) error {
+		entry := baremetalcontrollerv1.ServerRebootStatus{
+			Name:      server.Name,
+			Namespace: server.Namespace,
+			Phase:     baremetalcontrollerv1.ServerRebootPending,
+		}
+		if server.Spec.PowerState.Settled() != baremetalcontrollerv1.PowerStateOn {
+			entry.Phase = baremetalcontrollerv1.ServerRebootSkipped
+			entry.Message = "Server is powered off"
+		}
+		campaign.Status.Servers = append(campaign.Status.Servers, entry)
+		return nil
+	}, client.MatchingLabelsSelector{Selector: selector})
+	if err != nil {
+		return err
+	}
+	campaign.Status.Phase = baremetalcontrollerv1.CampaignPhaseRunning
+	return nil
+}
+
+// advance moves a server to the next phase of its reboot once the current
+// phase is done.
+func (r *RebootCampaignReconciler) advance(ctx context.Context, campaign *baremetalcontrollerv1.RebootCampaign, entry *baremetalcontrollerv1.ServerRebootStatus) error {
+	switch entry.Phase {
+	case baremetalcontrollerv1.ServerRebootDraining,
+		baremetalcontrollerv1.ServerRebootPoweringOff,
+		baremetalcontrollerv1.ServerRebootPoweringOn,
+		baremetalcontrollerv1.ServerRebootFailed:
+	default:
+		return nil
+	}
+
+	var server baremetalcontrollerv1.Server
+	if err := r.Get(ctx, types.NamespacedName{Namespace: entry.Namespace, Name: entry.Name}, &server); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.finish(entry, baremetalcontrollerv1.ServerRebootFailed, "Server was deleted")
+			return nil
+		}
+		return err
+	}
+
+	if server.Status.Status == baremetalcontrollerv1.StatusFailed {
+		if entry.Phase != baremetalcontrollerv1.ServerRebootFailed {
+			r.finish(entry, baremetalcontrollerv1.ServerRebootFailed, fmt.Sprintf("Server failed: %s", server.Status.Message))
+		}
+		return nil
+	}
+
+	switch entry.Phase {
+	case baremetalcontrollerv1.ServerRebootFailed:
+		// Resume the campaign once the server has been fixed
+		if server.Spec.PowerState.Settled() == baremetalcontrollerv1.PowerStateOn &&
+			server.Status.Status == baremetalcontrollerv1.StatusActive {
+			r.finish(entry, baremetalcontrollerv1.ServerRebootCompleted, "Recovered after failure")
+		}
+
+	case baremetalcontrollerv1.ServerRebootDraining:
+		c, reader, err := nodeClients(ctx, r.Clusters, &server, r.Client, r.APIReader)
+		if err != nil {
+			return err
+		}
+		drained, err := drain.Node(ctx, c, reader, server.NodeName())
+		if err != nil {
+			return err
+		}
+		if !drained {
+			entry.Message = "Waiting for pods to be evicted"
+			return nil
+		}
+		entry.Phase = baremetalcontrollerv1.ServerRebootPoweringOff
+		fallthrough
+
+	case baremetalcontrollerv1.ServerRebootPoweringOff:
+		if server.Spec.PowerState != baremetalcontrollerv1.PowerStateOff {
+			server.Spec.PowerState = baremetalcontrollerv1.PowerStateOff
+			if err := r.Update(ctx, &server); err != nil {
+				return fmt.Errorf("failed to power off server: %w", err)
+			}
+		}
+		if server.Status.Status != baremetalcontrollerv1.StatusOffline {
+			entry.Message = "Waiting for server to power off"
+			return nil
+		}
+		entry.Phase = baremetalcontrollerv1.ServerRebootPoweringOn
+		fallthrough
+
+	case baremetalcontrollerv1.ServerRebootPoweringOn:
+		if server.Spec.PowerState.Settled() != baremetalcontrollerv1.PowerStateOn {
+			server.Spec.PowerState = baremetalcontrollerv1.PowerStateOn
+			if err := r.Update(ctx, &server); err != nil {
+				return fmt.Errorf("failed to power on server: %w", err)
+			}
+		}
+		if server.Status.Status != baremetalcontrollerv1.StatusActive {
+			entry.Message = "Waiting for server to come back"
+			return nil
+		}
+		if campaign.Spec.Drain {
+			c, _, err := nodeClients(ctx, r.Clusters, &server, r.Client, r.APIReader)
+			if err != nil {
+				return err
+			}
+			if err := drain.Uncordon(ctx, c, server.NodeName()); err != nil {
+				return fmt.Errorf("failed to uncordon node: %w", err)
+			}
+		}
+		r.finish(entry, baremetalcontrollerv1.ServerRebootCompleted, "")
+	}
+	return nil
+}
+
+func (r *RebootCampaignReconciler) finish(entry *baremetalcontrollerv1.ServerRebootStatus, phase baremetalcontrollerv1.ServerRebootPhase, message string) {
+	now := metav1.Now()
+	entry.Phase = phase
+	entry.Message = message
+	entry.CompletedAt = &now
+}
+
+func (r *RebootCampaignReconciler) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+// isUnavailable reports whether a server counts towards maxUnavailable
+func isUnavailable(phase baremetalcontrollerv1.ServerRebootPhase) bool {
+	switch phase {
+	case baremetalcontrollerv1.ServerRebootDraining,
+		baremetalcontrollerv1.ServerRebootPoweringOff,
+		baremetalcontrollerv1.ServerRebootPoweringOn,
+		baremetalcontrollerv1.ServerRebootFailed:
+		return true
+	}
+	return false
+}
+
+var weekdays = map[baremetalcontrollerv1.Weekday]time.Weekday{
+	"Sun": time.Sunday,
+	"Mon": time.Monday,
+	"Tue": time.Tuesday,
+	"Wed": time.Wednesday,
+	"Thu": time.Thursday,
+	"Fri": time.Friday,
+	"Sat": time.Saturday,
+}
+
+// canStartReboots reports whether the maintenance window is open and no
+// change freeze is on, and if not, when to check again. Reboots are held
+// back while a calendar can't be read.
+func (r *RebootCampaignReconciler) canStartReboots(ctx context.Context, campaign *baremetalcontrollerv1.RebootCampaign) (bool, time.Duration) {
+	window := campaign.Spec.MaintenanceWindow
+	windowCalendar := ""
+	if window != nil {
+		windowCalendar = window.Calendar
+	}
+	calendars, err := loadCalendars(ctx, r.Client, windowCalendar, campaign.Spec.FreezeCalendar)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Holding back reboots", "campaign", campaign.Name)
+		return false, time.Minute
+	}
+
+	now := r.now()
+	if campaign.Spec.FreezeCalendar != "" {
+		if frozen, thaws := inCalendar(calendars[campaign.Spec.FreezeCalendar], now); frozen {
+			return false, thaws
+		}
+	}
+	return inMaintenanceWindow(window, calendars, now)
+}
+
+// inMaintenanceWindow reports whether now is inside the window, and if not,
+// how long until it opens.
+func inMaintenanceWindow(window *baremetalcontrollerv1.MaintenanceWindow, calendars map[string][]baremetalcontrollerv1.CalendarEvent, now time.Time) (bool, time.Duration) {
+	if window == nil {
+		return true, 0
+	}
+	if window.Calendar != "" {
+		open, next := inCalendar(calendars[window.Calendar], now)
+		if open {
+			return true, 0
+		}
+		if next == 0 {
+			next = time.Hour
+		}
+		return false, next
+	}
+
+	start, err := time.Parse("15:04", window.Start)
+	if err != nil {
+		return false, time.Hour
+	}
+	now = now.UTC()
+
+	// A window that opened yesterday may still be open
+	var next time.Duration
+	for day := -1; day <= 7; day++ {
+		opens := time.Date(now.Year(), now.Month(), now.Day()+day, start.Hour(), start.Minute(), 0, 0, time.UTC)
+		if !windowOpensOn(window, opens.Weekday()) {
+			continue
+		}
+		if !now.Before(opens) && now.Before(opens.Add(window.Duration.Duration)) {
+			return true, 0
+		}
+		if opens.After(now) {
+			next = opens.Sub(now)
+			break
+		}
+	}
+	if next == 0 {
+		next = time.Hour
+	}
+	return false, next
+}
+
+func windowOpensOn(window *baremetalcontrollerv1.MaintenanceWindow, day time.Weekday) bool {
+	if len(window.Days) == 0 {
+		return true
+	}
+	for _, d := range window.Days {
+		if weekdays[d] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// campaignsForCalendar enqueues the running campaigns whose maintenance
+// window or change freeze comes from the calendar
+func (r *RebootCampaignReconciler) campaignsForCalendar(ctx context.Context, obj client.Object) []reconcile.Request {
+	var campaigns baremetalcontrollerv1.RebootCampaignList
+	if err := r.List(ctx, &campaigns); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list reboot campaigns")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, campaign := range campaigns.Items {
+		window := campaign.Spec.MaintenanceWindow
+		if campaign.Spec.FreezeCalendar == obj.GetName() || (window != nil && window.Calendar == obj.GetName()) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&campaign)})
+		}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *RebootCampaignReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&baremetalcontrollerv1.RebootCampaign{}).
+		Watches(&baremetalcontrollerv1.Calendar{}, handler.EnqueueRequestsFromMapFunc(r.campaignsForCalendar)).
+		Named("rebootcampaign").
+		Complete(r)
+}
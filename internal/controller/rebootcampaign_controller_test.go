@@ -0,0 +1,224 @@
+// internal/controller/server_controller_test.go
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+var _ = Describe("RebootCampaign Controller", func() {
+	const campaignName = "test-campaign"
+
+	var (
+		ctx        context.Context
+		reconciler *RebootCampaignReconciler
+		now        time.Time
+	)
+
+	serverNames := []string{"campaign-server-a", "campaign-server-b", "campaign-server-c"}
+
+	reconcileCampaign := func() reconcile.Result {
+		result, err := reconciler.Reconcile(ctx, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: campaignName},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return result
+	}
+
+	getCampaign := func() *baremetalcontrollerv1.RebootCampaign {
+		campaign := &baremetalcontrollerv1.RebootCampaign{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: campaignName}, campaign)).To(Succeed())
+		return campaign
+	}
+
+	getServer := func(name string) *baremetalcontrollerv1.Server {
+		server := &baremetalcontrollerv1.Server{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: name}, server)).To(Succeed())
+		return server
+	}
+
+	// Simulates ServerReconciler reporting a new status
+	setServerStatus := func(name string, status baremetalcontrollerv1.CurrentStatus) {
+		server := getServer(name)
+		server.Status.Status = status
+		Expect(k8sClient.Status().Update(ctx, server)).To(Succeed())
+	}
+
+	createCampaign := func(window *baremetalcontrollerv1.MaintenanceWindow) {
+		campaign := &baremetalcontrollerv1.RebootCampaign{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: campaignName,
+			},
+			Spec: baremetalcontrollerv1.RebootCampaignSpec{
+				Selector: metav1.LabelSelector{
+					MatchLabels: map[string]string{"pool": "campaign-test"},
+				},
+				MaxUnavailable:    1,
+				MaintenanceWindow: window,
+			},
+		}
+		Expect(k8sClient.Create(ctx, campaign)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		now = time.Date(2025, time.June, 7, 3, 0, 0, 0, time.UTC) // Saturday
+		reconciler = &RebootCampaignReconciler{
+			Client:    k8sClient,
+			Scheme:    k8sClient.Scheme(),
+			APIReader: k8sClient,
+			Now:       func() time.Time { return now },
+		}
+
+		for i, name := range serverNames {
+			powerState := baremetalcontrollerv1.PowerStateOn
+			if i == len(serverNames)-1 {
+				powerState = baremetalcontrollerv1.PowerStateOff
+			}
+			server := &baremetalcontrollerv1.Server{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   name,
+					Labels: map[string]string{"pool": "campaign-test"},
+				},
+				Spec: baremetalcontrollerv1.ServerSpec{
+					PowerState: powerState,
+					Type:       baremetalcontrollerv1.ControlTypeWOL,
+					Control: baremetalcontrollerv1.ControlSpecs{
+						WOL: &baremetalcontrollerv1.WOLSpecs{
+							Address:    "192.168.1.120",
+							MACAddress: "00:11:22:33:44:66",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, server)).To(Succeed())
+			if powerState == baremetalcontrollerv1.PowerStateOn {
+				setServerStatus(name, baremetalcontrollerv1.StatusActive)
+			}
+		}
+	})
+
+	AfterEach(func() {
+		campaign := &baremetalcontrollerv1.RebootCampaign{}
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: campaignName}, campaign); err == nil {
+			Expect(k8sClient.Delete(ctx, campaign)).To(Succeed())
+		}
+		for _, name := range serverNames {
+			server := &baremetalcontrollerv1.Server{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{Name: name}, server); err == nil {
+				Expect(k8sClient.Delete(ctx, server)).To(Succeed())
+			}
+		}
+	})
+
+	It("should reboot one server at a time and skip powered off servers", func() {
+		createCampaign(nil)
+
+		reconcileCampaign()
+		campaign := getCampaign()
+		Expect(campaign.Status.Phase).To(Equal(baremetalcontrollerv1.CampaignPhaseRunning))
+		Expect(campaign.Status.Servers).To(HaveLen(3))
+		Expect(campaign.Status.Servers[0].Phase).To(Equal(baremetalcontrollerv1.ServerRebootPoweringOff))
+		Expect(campaign.Status.Servers[1].Phase).To(Equal(baremetalcontrollerv1.ServerRebootPending))
+		Expect(campaign.Status.Servers[2].Phase).To(Equal(baremetalcontrollerv1.ServerRebootSkipped))
+		Expect(getServer(serverNames[0]).Spec.PowerState).To(Equal(baremetalcontrollerv1.PowerStateOff))
+
+		setServerStatus(serverNames[0], baremetalcontrollerv1.StatusOffline)
+		reconcileCampaign()
+		Expect(getCampaign().Status.Servers[0].Phase).To(Equal(baremetalcontrollerv1.ServerRebootPoweringOn))
+		Expect(getServer(serverNames[0]).Spec.PowerState).To(Equal(baremetalcontrollerv1.PowerStateOn))
+
+		setServerStatus(serverNames[0], baremetalcontrollerv1.StatusActive)
+		reconcileCampaign()
+		campaign = getCampaign()
+		Expect(campaign.Status.Servers[0].Phase).To(Equal(baremetalcontrollerv1.ServerRebootCompleted))
+		Expect(campaign.Status.Servers[1].Phase).To(Equal(baremetalcontrollerv1.ServerRebootPoweringOff))
+
+		setServerStatus(serverNames[1], baremetalcontrollerv1.StatusOffline)
+		reconcileCampaign()
+		setServerStatus(serverNames[1], baremetalcontrollerv1.StatusActive)
+		reconcileCampaign()
+		campaign = getCampaign()
+		Expect(campaign.Status.Phase).To(Equal(baremetalcontrollerv1.CampaignPhaseCompleted))
+		Expect(campaign.Status.CompletionTime).NotTo(BeNil())
+	})
+
+	It("should wait for the maintenance window before starting reboots", func() {
+		createCampaign(&baremetalcontrollerv1.MaintenanceWindow{
+			Start:    "22:00",
+			Duration: metav1.Duration{Duration: 2 * time.Hour},
+		})
+
+		result := reconcileCampaign()
+		Expect(result.RequeueAfter).To(Equal(19 * time.Hour))
+		Expect(getCampaign().Status.Servers[0].Phase).To(Equal(baremetalcontrollerv1.ServerRebootPending))
+		Expect(getServer(serverNames[0]).Spec.PowerState).To(Equal(baremetalcontrollerv1.PowerStateOn))
+	})
+
+	It("should treat a window that opened the previous day as open", func() {
+		createCampaign(&baremetalcontrollerv1.MaintenanceWindow{
+			Start:    "23:00",
+			Duration: metav1.Duration{Duration: 6 * time.Hour},
+			Days:     []baremetalcontrollerv1.Weekday{"Fri"},
+		})
+
+		reconcileCampaign()
+		Expect(getCampaign().Status.Servers[0].Phase).To(Equal(baremetalcontrollerv1.ServerRebootPoweringOff))
+	})
+
+	It("should hold back reboots during a change freeze", func() {
+		freeze := &baremetalcontrollerv1.Calendar{
+			ObjectMeta: metav1.ObjectMeta{Name: "campaign-freeze"},
+			Spec: baremetalcontrollerv1.CalendarSpec{
+				Dates: []baremetalcontrollerv1.CalendarDate{"2025-06-07"},
+			},
+		}
+		Expect(k8sClient.Create(ctx, freeze)).To(Succeed())
+		defer func() {
+			Expect(k8sClient.Delete(ctx, freeze)).To(Succeed())
+		}()
+		freeze.Status.Events = []baremetalcontrollerv1.CalendarEvent{{
+			Summary: "Release freeze",
+			Start:   metav1.NewTime(time.Date(2025, time.June, 7, 0, 0, 0, 0, time.UTC)),
+			End:     metav1.NewTime(time.Date(2025, time.June, 8, 0, 0, 0, 0, time.UTC)),
+		}}
+		Expect(k8sClient.Status().Update(ctx, freeze)).To(Succeed())
+
+		createCampaign(nil)
+		campaign := getCampaign()
+		campaign.Spec.FreezeCalendar = freeze.Name
+		Expect(k8sClient.Update(ctx, campaign)).To(Succeed())
+
+		result := reconcileCampaign()
+		Expect(result.RequeueAfter).To(Equal(21 * time.Hour))
+		Expect(getCampaign().Status.Servers[0].Phase).To(Equal(baremetalcontrollerv1.ServerRebootPending))
+
+		now = now.Add(21 * time.Hour)
+		reconcileCampaign()
+		Expect(getCampaign().Status.Servers[0].Phase).To(Equal(baremetalcontrollerv1.ServerRebootPoweringOff))
+	})
+})
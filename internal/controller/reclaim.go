@@ -0,0 +1,80 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/drain"
+)
+
+// reclaimDrainInterval rechecks a node drained to reclaim a spot server
+const reclaimDrainInterval = 10 * time.Second
+
+// reclaimSpot drains and powers off a spot server the autoscaler reclaimed
+// for a reserved scale-up, then uncordons its node and removes the
+// annotation once the server is off. It returns when to check the drain
+// again, or 0 if there is nothing to wait for.
+func (r *ServerReconciler) reclaimSpot(ctx context.Context, server *baremetalcontrollerv1.Server) (time.Duration, error) {
+	reservedFor := server.Annotations[baremetalcontrollerv1.ReclaimAnnotation]
+	if reservedFor == "" {
+		return 0, nil
+	}
+
+	c, reader, err := nodeClients(ctx, r.Clusters, server, r.Client, r.apiReader())
+	if err != nil {
+		return 0, err
+	}
+	if server.Spec.PowerState.Settled() == baremetalcontrollerv1.PowerStateOn {
+		drained, err := drain.Node(ctx, c, reader, server.NodeName())
+		if err != nil {
+			return 0, err
+		}
+		if !drained {
+			return reclaimDrainInterval, nil
+		}
+		patch := client.MergeFrom(server.DeepCopy())
+		server.Spec.PowerState = baremetalcontrollerv1.PowerStateOff
+		if err := r.Patch(ctx, server, patch); err != nil {
+			return 0, fmt.Errorf("failed to power off server: %w", err)
+		}
+		log.FromContext(ctx).Info("Reclaiming spot server", "server", server.Name, "reservedFor", reservedFor)
+		r.event(server, corev1.EventTypeNormal, "Reclaimed", "Powering off for reserved server %s", reservedFor)
+		return 0, nil
+	}
+
+	if server.Status.Status == baremetalcontrollerv1.StatusActive {
+		// Still powering off
+		return 0, nil
+	}
+	if err := drain.Uncordon(ctx, c, server.NodeName()); err != nil {
+		return 0, err
+	}
+	patch := client.MergeFrom(server.DeepCopy())
+	delete(server.Annotations, baremetalcontrollerv1.ReclaimAnnotation)
+	if err := r.Patch(ctx, server, patch); err != nil {
+		return 0, fmt.Errorf("failed to remove reclaim annotation: %w", err)
+	}
+	return 0, nil
+}
@@ -0,0 +1,320 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+// fairQueue is the work queue of the server controller with FairQueue set.
+// Servers whose spec changed, i.e. a user or the autoscaler asked for a
+// power action, are reconciled before servers that are only due for their
+// periodic probe. Probes take turns between ServerClasses, so the resync of
+// a large class can't hold up the servers of the others. Like the default
+// queue, a server is never reconciled by two workers at once, and a server
+// added while being reconciled is reconciled again once it's done.
+type fairQueue struct {
+	rateLimiter workqueue.TypedRateLimiter[reconcile.Request]
+	// pool returns the ServerClass of a server, whose probes take turns
+	// with those of other classes
+	pool func(reconcile.Request) string
+
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	// queued maps the servers waiting in urgent or pools to whether they
+	// are urgent. The lists may hold stale entries of servers that were
+	// taken or made urgent since, which are skipped.
+	queued map[reconcile.Request]bool
+	urgent []reconcile.Request
+	pools  map[string][]reconcile.Request
+	// poolOrder are the pools with queued servers, in the order they take
+	// turns
+	poolOrder []string
+
+	processing map[reconcile.Request]struct{}
+	// again maps the servers added while being reconciled to whether they
+	// are urgent
+	again map[reconcile.Request]bool
+	// waiting holds when the servers added with a delay are due
+	waiting map[reconcile.Request]time.Time
+
+	shuttingDown bool
+}
+
+// Ensure fairQueue implements the rate limiting work queue of controllers
+var _ workqueue.TypedRateLimitingInterface[reconcile.Request] = &fairQueue{}
+
+func newFairQueue(rateLimiter workqueue.TypedRateLimiter[reconcile.Request], pool func(reconcile.Request) string) *fairQueue {
+	q := &fairQueue{
+		rateLimiter: rateLimiter,
+		pool:        pool,
+		queued:      map[reconcile.Request]bool{},
+		pools:       map[string][]reconcile.Request{},
+		processing:  map[reconcile.Request]struct{}{},
+		again:       map[reconcile.Request]bool{},
+		waiting:     map[reconcile.Request]time.Time{},
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// Add queues a server for its routine reconcile
+func (q *fairQueue) Add(item reconcile.Request) {
+	q.add(item, false)
+}
+
+// AddUrgent queues a server ahead of the servers due for routine
+// reconciles, or moves it ahead if it's already queued
+func (q *fairQueue) AddUrgent(item reconcile.Request) {
+	q.add(item, true)
+}
+
+func (q *fairQueue) add(item reconcile.Request, urgent bool) {
+	// Looked up outside the lock, as it reads the cache
+	pool := ""
+	if !urgent {
+		pool = q.pool(item)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.shuttingDown {
+		return
+	}
+	if _, ok := q.processing[item]; ok {
+		q.again[item] = q.again[item] || urgent
+		return
+	}
+	if wasUrgent, ok := q.queued[item]; ok {
+		if urgent && !wasUrgent {
+			// The entry in its pool goes stale
+			q.queued[item] = true
+			q.urgent = append(q.urgent, item)
+			q.cond.Signal()
+		}
+		return
+	}
+
+	q.queued[item] = urgent
+	if urgent {
+		q.urgent = append(q.urgent, item)
+	} else {
+		if len(q.pools[pool]) == 0 {
+			q.poolOrder = append(q.poolOrder, pool)
+		}
+		q.pools[pool] = append(q.pools[pool], item)
+	}
+	q.cond.Signal()
+}
+
+// Len returns the number of queued servers
+func (q *fairQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.queued)
+}
+
+// Get blocks until a server is queued, and returns the urgent servers first
+// and then those of each pool in turn
+func (q *fairQueue) Get() (reconcile.Request, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.queued) == 0 && !q.shuttingDown {
+		q.cond.Wait()
+	}
+	if len(q.queued) == 0 {
+		return reconcile.Request{}, true
+	}
+
+	item, ok := q.takeUrgent()
+	if !ok {
+		item = q.takeRoutine()
+	}
+	delete(q.queued, item)
+	q.processing[item] = struct{}{}
+	return item, false
+}
+
+// takeUrgent pops the first urgent server, false if there is none
+func (q *fairQueue) takeUrgent() (reconcile.Request, bool) {
+	for len(q.urgent) > 0 {
+		item := q.urgent[0]
+		q.urgent = q.urgent[1:]
+		if q.queued[item] {
+			return item, true
+		}
+	}
+	return reconcile.Request{}, false
+}
+
+// takeRoutine pops the first server of the pool whose turn it is. It must
+// only be called with a routine server queued.
+func (q *fairQueue) takeRoutine() reconcile.Request {
+	for {
+		pool := q.poolOrder[0]
+		q.poolOrder = q.poolOrder[1:]
+		items := q.pools[pool]
+		item := items[0]
+		if items = items[1:]; len(items) > 0 {
+			q.pools[pool] = items
+			q.poolOrder = append(q.poolOrder, pool)
+		} else {
+			delete(q.pools, pool)
+		}
+		if urgent, ok := q.queued[item]; ok && !urgent {
+			return item
+		}
+	}
+}
+
+// Done marks a server as reconciled, and queues it again if it was added
+// in the meantime
+func (q *fairQueue) Done(item reconcile.Request) {
+	q.mu.Lock()
+	delete(q.processing, item)
+	urgent, again := q.again[item]
+	delete(q.again, item)
+	q.cond.Broadcast()
+	q.mu.Unlock()
+
+	if again {
+		q.add(item, urgent)
+	}
+}
+
+// ShutDown makes Get return once the queue is empty and drops servers added
+// from now on
+func (q *fairQueue) ShutDown() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.shuttingDown = true
+	q.cond.Broadcast()
+}
+
+// ShutDownWithDrain shuts down the queue and waits for the reconciles in
+// progress to finish
+func (q *fairQueue) ShutDownWithDrain() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.shuttingDown = true
+	q.cond.Broadcast()
+	for len(q.processing) > 0 {
+		q.cond.Wait()
+	}
+}
+
+// ShuttingDown reports whether the queue is shutting down
+func (q *fairQueue) ShuttingDown() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.shuttingDown
+}
+
+// AddAfter queues a server for its routine reconcile once the duration has
+// passed. A server already due earlier keeps its time.
+func (q *fairQueue) AddAfter(item reconcile.Request, duration time.Duration) {
+	if duration <= 0 {
+		q.Add(item)
+		return
+	}
+	due := time.Now().Add(duration)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.shuttingDown {
+		return
+	}
+	if waiting, ok := q.waiting[item]; ok && !waiting.After(due) {
+		return
+	}
+	q.waiting[item] = due
+	time.AfterFunc(duration, func() {
+		q.mu.Lock()
+		current, ok := q.waiting[item]
+		if ok && current.Equal(due) {
+			delete(q.waiting, item)
+		}
+		q.mu.Unlock()
+		if ok && current.Equal(due) {
+			q.Add(item)
+		}
+	})
+}
+
+// AddRateLimited queues a server once the rate limiter allows it
+func (q *fairQueue) AddRateLimited(item reconcile.Request) {
+	q.AddAfter(item, q.rateLimiter.When(item))
+}
+
+// Forget resets the rate limiter of a server
+func (q *fairQueue) Forget(item reconcile.Request) {
+	q.rateLimiter.Forget(item)
+}
+
+// NumRequeues returns how often a server was rate limited
+func (q *fairQueue) NumRequeues(item reconcile.Request) int {
+	return q.rateLimiter.NumRequeues(item)
+}
+
+// urgentQueue is implemented by queues that reconcile some servers first
+type urgentQueue interface {
+	AddUrgent(reconcile.Request)
+}
+
+// urgentServerChanges queues servers whose spec changed, or that are being
+// deleted, as urgent. The controller's own watch queues them as routine
+// reconciles as well, which the queue merges.
+var urgentServerChanges = handler.Funcs{
+	UpdateFunc: func(_ context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+		if e.ObjectOld.GetGeneration() == e.ObjectNew.GetGeneration() &&
+			(e.ObjectNew.GetDeletionTimestamp() == nil || e.ObjectOld.GetDeletionTimestamp() != nil) {
+			return
+		}
+		item := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(e.ObjectNew)}
+		if urgent, ok := q.(urgentQueue); ok {
+			urgent.AddUrgent(item)
+			return
+		}
+		q.Add(item)
+	},
+}
+
+// serverPool returns the ServerClass of a server for fairQueue. Servers
+// without a class, and servers not in the cache, share a pool.
+func (r *ServerReconciler) serverPool(req reconcile.Request) string {
+	var server baremetalcontrollerv1.Server
+	if err := r.Get(context.Background(), req.NamespacedName, &server); err != nil {
+		return ""
+	}
+	return server.Spec.ServerClassName
+}
+
+// newQueue returns the work queue of the controller
+func (r *ServerReconciler) newQueue(_ string, rateLimiter workqueue.TypedRateLimiter[reconcile.Request]) workqueue.TypedRateLimitingInterface[reconcile.Request] {
+	return newFairQueue(rateLimiter, r.serverPool)
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+func request(name string) reconcile.Request {
+	return reconcile.Request{NamespacedName: types.NamespacedName{Name: name}}
+}
+
+// newTestQueue returns a queue whose servers are in the pool named by their
+// first letter
+func newTestQueue() *fairQueue {
+	return newFairQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request](), func(req reconcile.Request) string {
+		return req.Name[:1]
+	})
+}
+
+// takeAll takes all queued servers in order
+func takeAll(q *fairQueue) []string {
+	var names []string
+	for q.Len() > 0 {
+		item, _ := q.Get()
+		names = append(names, item.Name)
+		q.Done(item)
+	}
+	return names
+}
+
+func TestFairQueueOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		add  func(q *fairQueue)
+		want []string
+	}{
+		{
+			name: "urgent first",
+			add: func(q *fairQueue) {
+				q.Add(request("a1"))
+				q.Add(request("a2"))
+				q.AddUrgent(request("b1"))
+			},
+			want: []string{"b1", "a1", "a2"},
+		},
+		{
+			name: "pools take turns",
+			add: func(q *fairQueue) {
+				q.Add(request("a1"))
+				q.Add(request("a2"))
+				q.Add(request("a3"))
+				q.Add(request("b1"))
+				q.Add(request("c1"))
+				q.Add(request("c2"))
+			},
+			want: []string{"a1", "b1", "c1", "a2", "c2", "a3"},
+		},
+		{
+			name: "added twice",
+			add: func(q *fairQueue) {
+				q.Add(request("a1"))
+				q.Add(request("b1"))
+				q.Add(request("a1"))
+				q.AddUrgent(request("c1"))
+				q.AddUrgent(request("c1"))
+			},
+			want: []string{"c1", "a1", "b1"},
+		},
+		{
+			name: "made urgent",
+			add: func(q *fairQueue) {
+				q.Add(request("a1"))
+				q.Add(request("a2"))
+				q.AddUrgent(request("a2"))
+				q.Add(request("a2"))
+			},
+			want: []string{"a2", "a1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newTestQueue()
+			tt.add(q)
+			got := takeAll(q)
+			if len(got) != len(tt.want) {
+				t.Fatalf("order = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("order = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFairQueueAddWhileProcessing(t *testing.T) {
+	q := newTestQueue()
+	q.Add(request("a1"))
+	q.Add(request("a2"))
+	item, _ := q.Get()
+
+	// Not handed to another worker while a1 is reconciled
+	q.AddUrgent(item)
+	if next, _ := q.Get(); next.Name != "a2" {
+		t.Fatalf("Get() = %s, want a2", next.Name)
+	}
+	if q.Len() != 0 {
+		t.Fatalf("Len() = %d while a1 is reconciled, want 0", q.Len())
+	}
+	q.Done(item)
+	if got := takeAll(q); len(got) != 1 || got[0] != "a1" {
+		t.Errorf("after Done = %v, want a1 again", got)
+	}
+}
+
+func TestFairQueueAddAfter(t *testing.T) {
+	q := newTestQueue()
+	q.AddAfter(request("a1"), time.Hour)
+	q.AddAfter(request("a1"), 10*time.Millisecond)
+	// Later than the time a1 is already due
+	q.AddAfter(request("a1"), time.Hour)
+	if q.Len() != 0 {
+		t.Fatalf("Len() = %d before a1 is due, want 0", q.Len())
+	}
+
+	done := make(chan reconcile.Request)
+	go func() {
+		item, _ := q.Get()
+		done <- item
+	}()
+	select {
+	case item := <-done:
+		if item.Name != "a1" {
+			t.Errorf("Get() = %s, want a1", item.Name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("a1 was not queued once due")
+	}
+}
+
+func TestFairQueueShutDown(t *testing.T) {
+	q := newTestQueue()
+	q.Add(request("a1"))
+	item, _ := q.Get()
+
+	drained := make(chan struct{})
+	go func() {
+		q.ShutDownWithDrain()
+		close(drained)
+	}()
+	for !q.ShuttingDown() {
+		time.Sleep(time.Millisecond)
+	}
+	q.Add(request("a2"))
+	select {
+	case <-drained:
+		t.Fatal("ShutDownWithDrain() returned while a1 was reconciled")
+	case <-time.After(10 * time.Millisecond):
+	}
+	q.Done(item)
+	<-drained
+
+	if _, shutdown := q.Get(); !shutdown {
+		t.Errorf("Get() after shut down returned a server")
+	}
+}
+
+func TestUrgentServerChanges(t *testing.T) {
+	now := metav1.Now()
+	server := func(generation int64, deleting bool) *baremetalcontrollerv1.Server {
+		s := &baremetalcontrollerv1.Server{ObjectMeta: metav1.ObjectMeta{Name: "b1", Generation: generation}}
+		if deleting {
+			s.DeletionTimestamp = &now
+		}
+		return s
+	}
+	tests := []struct {
+		name     string
+		old, new *baremetalcontrollerv1.Server
+		urgent   bool
+	}{
+		{name: "status changed", old: server(1, false), new: server(1, false)},
+		{name: "spec changed", old: server(1, false), new: server(2, false), urgent: true},
+		{name: "deleted", old: server(1, false), new: server(1, true), urgent: true},
+		{name: "still deleting", old: server(1, true), new: server(1, true)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newTestQueue()
+			q.Add(request("a1"))
+			urgentServerChanges.Update(context.Background(), event.UpdateEvent{ObjectOld: tt.old, ObjectNew: tt.new}, q)
+			got := takeAll(q)
+			if tt.urgent != (len(got) == 2 && got[0] == "b1") || !tt.urgent && len(got) != 1 {
+				t.Errorf("order = %v, urgent %v", got, tt.urgent)
+			}
+		})
+	}
+}
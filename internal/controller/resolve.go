@@ -0,0 +1,112 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"net"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/bmc"
+)
+
+// controlHostnames returns the hostnames in the addresses the server is
+// controlled and reached at, once each and sorted
+func controlHostnames(server *baremetalcontrollerv1.Server) []string {
+	var addresses []string
+	if ipmi := server.Spec.Control.IPMI; ipmi != nil {
+		addresses = append(addresses, ipmi.Address)
+	}
+	if wol := server.Spec.Control.WOL; wol != nil {
+		addresses = append(addresses, wol.Address, wol.SSHAddress)
+	}
+	if maas := server.Spec.Control.MAAS; maas != nil {
+		addresses = append(addresses, maas.Address)
+	}
+	if equinix := server.Spec.Control.Equinix; equinix != nil {
+		addresses = append(addresses, equinix.Address)
+	}
+	if hetzner := server.Spec.Control.Hetzner; hetzner != nil {
+		addresses = append(addresses, hetzner.Address)
+	}
+	if esxi := server.Spec.Control.ESXi; esxi != nil {
+		addresses = append(addresses, esxi.Address)
+	}
+	if redfish := server.Spec.Control.Redfish; redfish != nil {
+		addresses = append(addresses, redfish.Address)
+	}
+	if attest := server.Spec.Attestation; attest != nil {
+		addresses = append(addresses, attest.Address)
+	}
+
+	seen := map[string]bool{}
+	var hostnames []string
+	for _, address := range addresses {
+		host := bmc.Host(address)
+		if host == "" || net.ParseIP(host) != nil || seen[host] {
+			continue
+		}
+		seen[host] = true
+		hostnames = append(hostnames, host)
+	}
+	sort.Strings(hostnames)
+	return hostnames
+}
+
+// refreshResolvedAddresses records the addresses the server's hostnames
+// resolve to in the status, with an event whenever one moves. It returns true
+// if the status changed.
+func (r *ServerReconciler) refreshResolvedAddresses(server *baremetalcontrollerv1.Server) bool {
+	if r.Resolver == nil {
+		return false
+	}
+	previous := map[string]string{}
+	for _, resolved := range server.Status.ResolvedAddresses {
+		previous[resolved.Hostname] = resolved.Address
+	}
+
+	var resolved []baremetalcontrollerv1.ResolvedAddress
+	changed := false
+	for _, hostname := range controlHostnames(server) {
+		address := r.endpoints().ResolveAddress(hostname)
+		if address == hostname {
+			// Unresolved, keep the last address if there is one
+			if last, ok := previous[hostname]; ok {
+				resolved = append(resolved, baremetalcontrollerv1.ResolvedAddress{Hostname: hostname, Address: last})
+			}
+			continue
+		}
+		if last, ok := previous[hostname]; !ok || last != address {
+			changed = true
+			if ok {
+				r.event(server, corev1.EventTypeNormal, "AddressResolved", "%s moved from %s to %s", hostname, last, address)
+			} else {
+				r.event(server, corev1.EventTypeNormal, "AddressResolved", "%s resolved to %s", hostname, address)
+			}
+		}
+		resolved = append(resolved, baremetalcontrollerv1.ResolvedAddress{Hostname: hostname, Address: address})
+	}
+	if len(resolved) != len(server.Status.ResolvedAddresses) {
+		changed = true
+	}
+	if changed {
+		server.Status.ResolvedAddresses = resolved
+	}
+	return changed
+}
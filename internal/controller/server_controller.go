@@ -19,134 +19,558 @@ package controller
 import (
 	"context"
 	"fmt"
+	"net"
+	"strconv"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/bmc"
+	"github.com/Unbounder1/bare-metal-controller/internal/bootlog"
+	"github.com/Unbounder1/bare-metal-controller/internal/breaker"
+	"github.com/Unbounder1/bare-metal-controller/internal/cluster"
+	"github.com/Unbounder1/bare-metal-controller/internal/lifecycle"
+	"github.com/Unbounder1/bare-metal-controller/internal/pet"
 	"github.com/Unbounder1/bare-metal-controller/internal/power"
+	"github.com/Unbounder1/bare-metal-controller/internal/resolve"
+	"github.com/Unbounder1/bare-metal-controller/internal/telemetry"
 )
 
+// defaultRequeueInterval is how often a server is checked while waiting for
+// a power change, unless overridden by spec.timeouts or spec.reconcileInterval
+const defaultRequeueInterval = 60 * time.Second
+
+// powerRetryInterval is the wait before retrying a power action that failed
+// with a temporary error, doubled for each failure in a row
+const powerRetryInterval = 15 * time.Second
+
+// maxPowerRetryInterval bounds the wait between retries of a power action
+const maxPowerRetryInterval = 10 * time.Minute
+
 // ServerReconciler reconciles a Server object
 type ServerReconciler struct {
 	client.Client
-	Scheme     *runtime.Scheme
-	WolSender  power.WolSender
-	SSHClient  power.SSHClient
-	IPMIClient power.IPMIClient
-	Pinger     power.Pinger
+	Scheme        *runtime.Scheme
+	WolSender     power.WolSender
+	SSHClient     power.SSHClient
+	IPMIClient    power.IPMIClient
+	MAASClient    power.MAASClient
+	RedfishClient power.RedfishClient
+	EquinixClient power.EquinixClient
+	HetznerClient power.HetznerClient
+
+	// HypervisorClient shuts down standalone hypervisor hosts
+	HypervisorClient power.HypervisorClient
+	Attestor         power.Attestor
+	Pinger           power.Pinger
+
+	// Probers builds the probes of servers with spec.healthCheck. Servers
+	// are pinged with Pinger if nil.
+	Probers power.ProberFactory
+
+	// WolRelay wakes and probes WoL servers with a relay, nil if no relays
+	// are configured
+	WolRelay power.WolRelay
+
+	// Recorder emits the actions taken for simulated servers and thermal
+	// shutdowns
+	Recorder record.EventRecorder
+
+	// APIReader lists pods when draining a node for a thermal shutdown,
+	// since the cache doesn't hold them. Defaults to the client.
+	APIReader client.Reader
+
+	// NodeCleanup deletes the node of a server when the server is deleted,
+	// after draining it, and labels nodes with their server
+	NodeCleanup bool
+
+	// Clusters reaches the nodes of servers with spec.clusterRef
+	Clusters *cluster.Clients
+
+	// Resolver resolves hostnames in server addresses, nil to pass them on
+	// as they are
+	Resolver *resolve.Resolver
+
+	// Breaker halts all power actions while it is open, nil to never halt
+	// them
+	Breaker *breaker.Breaker
+
+	// Telemetry receives the metric reports and events of Redfish BMCs,
+	// which are subscribed to it, nil to poll them for power draw,
+	// temperatures and power changes
+	Telemetry *telemetry.Receiver
+
+	// PlatformEvents receives the IPMI Platform Event Traps of BMCs, nil to
+	// not take traps
+	PlatformEvents *pet.Receiver
+
+	// BootLogs keeps what servers log over syslog while they boot, nil if
+	// boot logs aren't captured
+	BootLogs *bootlog.Receiver
+
+	// Simulation shapes how servers with the simulate annotation behave
+	Simulation SimulationProfile
+
+	// BMCColdResetBackoff is the least time between cold resets of a BMC
+	// whose IPMI sessions time out. Zero never resets BMCs.
+	BMCColdResetBackoff time.Duration
+
+	// PowerWorkers runs power actions on this many workers outside of the
+	// reconcile, so slow BMCs don't block other servers. Zero runs them
+	// inline.
+	PowerWorkers int
+
+	// TenantPowerWorkers bounds the power workers the servers of one tenant
+	// occupy at once, see TenantLabel. Zero doesn't bound them.
+	TenantPowerWorkers int
+
+	// PowerShutdownGracePeriod is how long running power actions may take
+	// to finish when the controller shuts down, before they are cancelled
+	// and checkpointed as interrupted
+	PowerShutdownGracePeriod time.Duration
+
+	// FairQueue reconciles servers whose spec changed before servers due
+	// for their periodic probe, and lets the ServerClasses take turns with
+	// their probes, instead of one FIFO queue
+	FairQueue bool
+
+	operations  *powerOperations
+	probes      *reachabilityProbes
+	simulations simulationStore
+	simulating  bool
 }
 
 func (r *ServerReconciler) powerOn(ctx context.Context, server *baremetalcontrollerv1.Server) error {
 	switch server.Spec.Type {
 	case baremetalcontrollerv1.ControlTypeWOL:
 		if server.Spec.Control.WOL == nil {
-			return fmt.Errorf("WOL config is required")
+			return invalidSpec("WOL config is required")
 		}
 		if server.Spec.Control.WOL.MACAddress == "" {
-			return fmt.Errorf("WOL MAC address is required")
+			return invalidSpec("WOL MAC address is required")
 		}
 
-		return r.WolSender.Wake(server.Spec.Control.WOL.MACAddress, server.Spec.Control.WOL.Port, server.Spec.Control.WOL.BroadcastAddress)
+		wol := server.Spec.Control.WOL
+		if wol.Relay != "" {
+			if r.WolRelay == nil {
+				return fmt.Errorf("no WoL relays are configured for relay %s", wol.Relay)
+			}
+			return r.WolRelay.Wake(ctx, wol.Relay, wol.MACAddress, wol.Port, wol.BroadcastAddress)
+		}
+		return r.WolSender.Wake(ctx, wol.MACAddress, wol.Port, wol.BroadcastAddress)
 
 	case baremetalcontrollerv1.ControlTypeIPMI:
-		if server.Spec.Control.IPMI == nil {
-			return fmt.Errorf("IPMI config is required")
+		target, err := r.endpoints().IPMI(ctx, server)
+		if err != nil {
+			return err
+		}
+		if target.Username == "" || target.Password == "" {
+			return invalidSpec("IPMI username and password are required")
 		}
-		if server.Spec.Control.IPMI.Address == "" {
-			return fmt.Errorf("IPMI address is required")
+		return r.IPMIClient.PowerOn(ctx, target.Address, target.Username, target.Password)
+
+	case baremetalcontrollerv1.ControlTypeMAAS:
+		maas := server.Spec.Control.MAAS
+		apiKey, err := r.getMAASAPIKey(ctx, server)
+		if err != nil {
+			return err
 		}
-		if server.Spec.Control.IPMI.Username == "" || server.Spec.Control.IPMI.Password == "" {
-			return fmt.Errorf("IPMI username and password are required")
+		if maas.Deploy {
+			return r.MAASClient.Deploy(maas.Endpoint, apiKey, maas.SystemID, maas.DistroSeries)
 		}
-		return r.IPMIClient.PowerOn(server.Spec.Control.IPMI.Address, server.Spec.Control.IPMI.Username, server.Spec.Control.IPMI.Password)
+		return r.MAASClient.PowerOn(maas.Endpoint, apiKey, maas.SystemID)
+
+	case baremetalcontrollerv1.ControlTypeRedfish:
+		target, err := r.endpoints().Redfish(ctx, server)
+		if err != nil {
+			return err
+		}
+		return r.RedfishClient.PowerOn(target)
+
+	case baremetalcontrollerv1.ControlTypeEquinix:
+		token, deviceID, err := r.getEquinixDevice(ctx, server)
+		if err != nil {
+			return err
+		}
+		return r.EquinixClient.PowerOn(token, deviceID)
+
+	case baremetalcontrollerv1.ControlTypeHetzner:
+		hetzner := server.Spec.Control.Hetzner
+		username, password, err := r.getHetznerCredentials(ctx, server)
+		if err != nil {
+			return err
+		}
+		// The rescue system is only active for one boot, so it is enabled
+		// again before every power on
+		if rescue := hetzner.Rescue; rescue != nil {
+			rescueOS := rescue.OS
+			if rescueOS == "" {
+				rescueOS = "linux"
+			}
+			if err := r.HetznerClient.EnableRescue(username, password, hetzner.ServerNumber, rescueOS, rescue.AuthorizedKeys); err != nil {
+				return fmt.Errorf("failed to enable rescue system: %w", err)
+			}
+		}
+		return r.HetznerClient.Wake(username, password, hetzner.ServerNumber)
+
+	case baremetalcontrollerv1.ControlTypeESXi:
+		// A host that is off has no API, so it is woken by its other backend
+		powerOnType, err := esxiPowerOnType(server)
+		if err != nil {
+			return err
+		}
+		host := server.DeepCopy()
+		host.Spec.Type = powerOnType
+		return r.powerOn(ctx, host)
 
 	default:
-		return fmt.Errorf("unknown control type: %s", server.Spec.Type)
+		return invalidSpec("unknown control type: %s", server.Spec.Type)
 	}
 }
 
+// getServerAddress returns the IP address the server is reached at
 func (r *ServerReconciler) getServerAddress(server *baremetalcontrollerv1.Server) string {
+	return r.endpoints().ResolveAddress(serverAddress(server))
+}
+
+func serverAddress(server *baremetalcontrollerv1.Server) string {
 	switch server.Spec.Type {
 	case baremetalcontrollerv1.ControlTypeWOL:
-		if server.Spec.Control.WOL != nil {
-			return server.Spec.Control.WOL.Address
+		if wol := server.Spec.Control.WOL; wol != nil {
+			return leasedAddress(server, wol.MACAddress, wol.Address)
 		}
 	case baremetalcontrollerv1.ControlTypeIPMI:
 		if server.Spec.Control.IPMI != nil {
 			return server.Spec.Control.IPMI.Address
 		}
+	case baremetalcontrollerv1.ControlTypeMAAS:
+		if server.Spec.Control.MAAS != nil {
+			return server.Spec.Control.MAAS.Address
+		}
+	case baremetalcontrollerv1.ControlTypeRedfish:
+		if server.Spec.Control.Redfish != nil {
+			return bmc.Host(server.Spec.Control.Redfish.Address)
+		}
+	case baremetalcontrollerv1.ControlTypeEquinix:
+		if server.Spec.Control.Equinix != nil {
+			return server.Spec.Control.Equinix.Address
+		}
+	case baremetalcontrollerv1.ControlTypeHetzner:
+		if server.Spec.Control.Hetzner != nil {
+			return server.Spec.Control.Hetzner.Address
+		}
+	case baremetalcontrollerv1.ControlTypeESXi:
+		if server.Spec.Control.ESXi != nil {
+			return bmc.Host(server.Spec.Control.ESXi.Address)
+		}
 	}
 	return ""
 }
 
+// sshAddress returns the host:port SSH connects to, which for WoL servers
+// behind NAT is a port forward rather than the address they are pinged at
+func (r *ServerReconciler) sshAddress(server *baremetalcontrollerv1.Server) string {
+	wol := server.Spec.Control.WOL
+	if wol == nil {
+		return r.getServerAddress(server)
+	}
+	address := r.endpoints().ResolveAddress(leasedAddress(server, wol.MACAddress, wol.Address))
+	if wol.SSHAddress != "" {
+		address = r.endpoints().ResolveAddress(wol.SSHAddress)
+	}
+	if address == "" || wol.SSHPort == 0 {
+		return address
+	}
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		address = host
+	}
+	return net.JoinHostPort(address, strconv.Itoa(int(wol.SSHPort)))
+}
+
+// leasedAddress returns the address DHCP last leased to the interface with
+// the MAC address, or the configured address if there is none
+func leasedAddress(server *baremetalcontrollerv1.Server, macAddress string, configured string) string {
+	mac, err := net.ParseMAC(macAddress)
+	if err != nil {
+		return configured
+	}
+	for _, address := range server.Status.Addresses {
+		if leased, err := net.ParseMAC(address.MACAddress); err == nil && leased.String() == mac.String() {
+			return address.Address
+		}
+	}
+	return configured
+}
+
+// endpoints loads BMC endpoints and Secrets through the reconciler's client
+func (r *ServerReconciler) endpoints() *bmc.Endpoints {
+	return &bmc.Endpoints{Reader: r.Client, Resolver: r.Resolver}
+}
+
+// getSSHCredentials loads the private key of an SSH Secret, and the user
+// from its username key unless the spec names one
+func (r *ServerReconciler) getSSHCredentials(ctx context.Context, server *baremetalcontrollerv1.Server, user string, ref *baremetalcontrollerv1.SecretReference) (string, string, error) {
+	key, err := r.endpoints().SecretValue(ctx, server, ref, "ssh-privatekey")
+	if err != nil {
+		return "", "", err
+	}
+	if user == "" {
+		if user, err = r.endpoints().SecretValue(ctx, server, ref, "username"); err != nil || user == "" {
+			return "", "", invalidSpec("SSH user is required, in the spec or in username of secret %s/%s", ref.Namespace, ref.Name)
+		}
+	}
+	return user, key, nil
+}
+
+// getMAASAPIKey validates the MAAS config and loads its API key
+func (r *ServerReconciler) getMAASAPIKey(ctx context.Context, server *baremetalcontrollerv1.Server) (string, error) {
+	maas := server.Spec.Control.MAAS
+	if maas == nil {
+		return "", invalidSpec("MAAS config is required")
+	}
+	if maas.Endpoint == "" {
+		return "", invalidSpec("MAAS endpoint is required")
+	}
+	if maas.SystemID == "" {
+		return "", invalidSpec("MAAS system ID is required")
+	}
+	if maas.APIKeySecretRef == nil {
+		return "", invalidSpec("MAAS API key secret reference is required")
+	}
+	return r.endpoints().SecretValue(ctx, server, maas.APIKeySecretRef, "api-key")
+}
+
+// getEquinixDevice validates the Equinix Metal config and loads its API
+// token. Servers without a device ID are matched to a device of the project
+// by hostname.
+func (r *ServerReconciler) getEquinixDevice(ctx context.Context, server *baremetalcontrollerv1.Server) (string, string, error) {
+	equinix := server.Spec.Control.Equinix
+	if equinix == nil {
+		return "", "", invalidSpec("Equinix Metal config is required")
+	}
+	if equinix.ProjectID == "" {
+		return "", "", invalidSpec("Equinix Metal project ID is required")
+	}
+	if equinix.APITokenSecretRef == nil {
+		return "", "", invalidSpec("Equinix Metal API token secret reference is required")
+	}
+	token, err := r.endpoints().SecretValue(ctx, server, equinix.APITokenSecretRef, "api-token")
+	if err != nil {
+		return "", "", err
+	}
+	if equinix.DeviceID != "" {
+		return token, equinix.DeviceID, nil
+	}
+	hostname := equinix.Hostname
+	if hostname == "" {
+		hostname = server.Name
+	}
+	deviceID, err := r.EquinixClient.FindDevice(token, equinix.ProjectID, hostname)
+	if err != nil {
+		return "", "", err
+	}
+	return token, deviceID, nil
+}
+
+// getHetznerCredentials validates the Hetzner config and loads its Robot
+// webservice credentials
+func (r *ServerReconciler) getHetznerCredentials(ctx context.Context, server *baremetalcontrollerv1.Server) (string, string, error) {
+	hetzner := server.Spec.Control.Hetzner
+	if hetzner == nil {
+		return "", "", invalidSpec("Hetzner config is required")
+	}
+	if hetzner.ServerNumber <= 0 {
+		return "", "", invalidSpec("Hetzner server number is required")
+	}
+	if hetzner.CredentialsSecretRef == nil {
+		return "", "", invalidSpec("Hetzner credentials secret reference is required")
+	}
+	username, err := r.endpoints().SecretValue(ctx, server, hetzner.CredentialsSecretRef, "username")
+	if err != nil {
+		return "", "", err
+	}
+	password, err := r.endpoints().SecretValue(ctx, server, hetzner.CredentialsSecretRef, "password")
+	if err != nil {
+		return "", "", err
+	}
+	return username, password, nil
+}
+
+// getHypervisorTarget validates the ESXi config and loads its credentials
+func (r *ServerReconciler) getHypervisorTarget(ctx context.Context, server *baremetalcontrollerv1.Server) (power.HypervisorTarget, error) {
+	esxi := server.Spec.Control.ESXi
+	if esxi == nil {
+		return power.HypervisorTarget{}, invalidSpec("ESXi config is required")
+	}
+	if esxi.Address == "" {
+		return power.HypervisorTarget{}, invalidSpec("ESXi address is required")
+	}
+	if esxi.CredentialsSecretRef == nil {
+		return power.HypervisorTarget{}, invalidSpec("ESXi credentials secret reference is required")
+	}
+	username, err := r.endpoints().SecretValue(ctx, server, esxi.CredentialsSecretRef, "username")
+	if err != nil {
+		return power.HypervisorTarget{}, err
+	}
+	password, err := r.endpoints().SecretValue(ctx, server, esxi.CredentialsSecretRef, "password")
+	if err != nil {
+		return power.HypervisorTarget{}, err
+	}
+	tlsOptions, err := r.endpoints().RedfishTLS(ctx, server, esxi.TLS)
+	if err != nil {
+		return power.HypervisorTarget{}, err
+	}
+	return power.HypervisorTarget{
+		Address:  esxi.Address,
+		Username: username,
+		Password: password,
+		TLS:      tlsOptions,
+	}, nil
+}
+
+// esxiPowerOnType returns the backend an ESXi host is powered on with
+func esxiPowerOnType(server *baremetalcontrollerv1.Server) (baremetalcontrollerv1.ControlType, error) {
+	control := server.Spec.Control
+	switch {
+	case control.Redfish != nil:
+		return baremetalcontrollerv1.ControlTypeRedfish, nil
+	case control.IPMI != nil:
+		return baremetalcontrollerv1.ControlTypeIPMI, nil
+	case control.WOL != nil:
+		return baremetalcontrollerv1.ControlTypeWOL, nil
+	}
+	return "", invalidSpec("ESXi hosts need a Redfish, IPMI or WoL config to be powered on")
+}
+
+// exitMaintenanceMode takes an ESXi host that booted back up out of the
+// maintenance mode it was shut down in, so it runs VMs again
+func (r *ServerReconciler) exitMaintenanceMode(ctx context.Context, server *baremetalcontrollerv1.Server) error {
+	if server.Spec.Type != baremetalcontrollerv1.ControlTypeESXi || r.HypervisorClient == nil {
+		return nil
+	}
+	target, err := r.getHypervisorTarget(ctx, server)
+	if err != nil {
+		return err
+	}
+	return r.HypervisorClient.ExitMaintenanceMode(target)
+}
+
 // powerOff powers off the server based on its control type
 func (r *ServerReconciler) powerOff(ctx context.Context, server *baremetalcontrollerv1.Server) error {
-	// TODO: Implement pod draining before shutdown
+	if err := r.disarmWatchdog(ctx, server); err != nil {
+		return err
+	}
 
 	// Shutdown server based on specified control type
 	switch server.Spec.Type {
 	case baremetalcontrollerv1.ControlTypeWOL:
 		if server.Spec.Control.WOL == nil {
-			return fmt.Errorf("WOL config is required")
+			return invalidSpec("WOL config is required")
 		}
-		if server.Spec.Control.WOL.Address == "" {
-			return fmt.Errorf("WOL address is required")
-		}
-		if server.Spec.Control.WOL.User == "" {
-			return fmt.Errorf("WOL user is required")
+		address := r.sshAddress(server)
+		if address == "" {
+			return fmt.Errorf("no address known for server %s to shut down", server.Name)
 		}
 		if server.Spec.Control.WOL.SSHSecretRef == nil {
-			return fmt.Errorf("SSH secret reference is required")
+			return invalidSpec("SSH secret reference is required")
 		}
 
-		// Getting key from secret
-		secret := &corev1.Secret{}
-		secret.Name = server.Spec.Control.WOL.SSHSecretRef.Name
-		secret.Namespace = server.Spec.Control.WOL.SSHSecretRef.Namespace
-		err := r.Get(ctx, types.NamespacedName{
-			Name:      server.Spec.Control.WOL.SSHSecretRef.Name,
-			Namespace: server.Spec.Control.WOL.SSHSecretRef.Namespace,
-		}, secret)
-
+		user, key, err := r.getSSHCredentials(ctx, server, server.Spec.Control.WOL.User, server.Spec.Control.WOL.SSHSecretRef)
 		if err != nil {
-			return fmt.Errorf("failed to get SSH secret: %v", err)
+			return err
 		}
-		keyBytes, ok := secret.Data["ssh-privatekey"]
-		if !ok {
-			return fmt.Errorf("ssh-privatekey not found in secret %s/%s", secret.Namespace, secret.Name)
-		}
-		key := string(keyBytes)
 
 		// Shutdown via SSH
-		return r.SSHClient.Shutdown(server.Spec.Control.WOL.Address, server.Spec.Control.WOL.User, key)
+		return r.SSHClient.Shutdown(ctx, address, user, key)
 
 	case baremetalcontrollerv1.ControlTypeIPMI:
-		if server.Spec.Control.IPMI == nil {
-			return fmt.Errorf("IPMI config is required")
+		target, err := r.endpoints().IPMI(ctx, server)
+		if err != nil {
+			return err
+		}
+		if target.Username == "" || target.Password == "" {
+			return invalidSpec("IPMI username and password are required")
 		}
-		if server.Spec.Control.IPMI.Address == "" {
-			return fmt.Errorf("IPMI address is required")
+		return r.IPMIClient.PowerOff(ctx, target.Address, target.Username, target.Password)
+
+	case baremetalcontrollerv1.ControlTypeMAAS:
+		maas := server.Spec.Control.MAAS
+		apiKey, err := r.getMAASAPIKey(ctx, server)
+		if err != nil {
+			return err
+		}
+		// Releasing a machine also powers it off
+		if maas.Deploy {
+			return r.MAASClient.Release(maas.Endpoint, apiKey, maas.SystemID)
+		}
+		return r.MAASClient.PowerOff(maas.Endpoint, apiKey, maas.SystemID)
+
+	case baremetalcontrollerv1.ControlTypeRedfish:
+		target, err := r.endpoints().Redfish(ctx, server)
+		if err != nil {
+			return err
+		}
+		return r.RedfishClient.PowerOff(target)
+
+	case baremetalcontrollerv1.ControlTypeEquinix:
+		token, deviceID, err := r.getEquinixDevice(ctx, server)
+		if err != nil {
+			return err
 		}
-		if server.Spec.Control.IPMI.Username == "" || server.Spec.Control.IPMI.Password == "" {
-			return fmt.Errorf("IPMI username and password are required")
+		return r.EquinixClient.PowerOff(token, deviceID)
+
+	case baremetalcontrollerv1.ControlTypeHetzner:
+		hetzner := server.Spec.Control.Hetzner
+		username, password, err := r.getHetznerCredentials(ctx, server)
+		if err != nil {
+			return err
 		}
-		return r.IPMIClient.PowerOff(server.Spec.Control.IPMI.Address, server.Spec.Control.IPMI.Username, server.Spec.Control.IPMI.Password)
+		resetType := "power"
+		if hetzner.ForceOff {
+			resetType = "power_long"
+		}
+		return r.HetznerClient.Reset(username, password, hetzner.ServerNumber, resetType)
+
+	case baremetalcontrollerv1.ControlTypeESXi:
+		target, err := r.getHypervisorTarget(ctx, server)
+		if err != nil {
+			return err
+		}
+		timeout := 5 * time.Minute
+		if esxi := server.Spec.Control.ESXi; esxi.MaintenanceTimeout != nil {
+			timeout = esxi.MaintenanceTimeout.Duration
+		}
+		if err := r.HypervisorClient.EnterMaintenanceMode(target, timeout); err != nil {
+			return fmt.Errorf("failed to enter maintenance mode: %w", err)
+		}
+		return r.HypervisorClient.Shutdown(target)
 
 	default:
-		return fmt.Errorf("unknown control type: %s", server.Spec.Type)
+		return invalidSpec("unknown control type: %s", server.Spec.Type)
 	}
 }
 
 // +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=servers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=servers/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=servers/finalizers,verbs=update
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=serverclasses,verbs=get;list;watch
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=powerbudgets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list
+// +kubebuilder:rbac:groups=core,resources=pods/eviction,verbs=create
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -162,79 +586,174 @@ func (r *ServerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 
 	var server baremetalcontrollerv1.Server
 	if err := r.Get(ctx, req.NamespacedName, &server); err != nil {
+		r.simulations.forget(req.NamespacedName)
+		if r.operations != nil {
+			r.operations.forget(req.NamespacedName)
+		}
+		if r.probes != nil {
+			r.probes.forget(req.NamespacedName)
+		}
+		forgetServerMetrics(req.NamespacedName)
+		if r.Telemetry != nil {
+			r.Telemetry.Forget(req.NamespacedName)
+		}
+		if r.PlatformEvents != nil {
+			r.PlatformEvents.Forget(req.NamespacedName)
+		}
+		if r.BootLogs != nil {
+			r.BootLogs.Forget(req.NamespacedName)
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	// Set default PowerState to "off" if not specified
+	// Delete the node along with the server
+	if deleting, result, err := r.finalizeServer(ctx, &server); deleting || err != nil {
+		return result, err
+	}
+
+	if isSimulated(&server) && !r.simulating {
+		return r.simulator(&server).Reconcile(ctx, req)
+	}
+
+	// Hardware faults are reported whatever state the server is in
+	r.handlePlatformEvents(ctx, &server)
+
+	// Record a finished power action, or wait for a pending one
+	if r.operations != nil {
+		op, inFlight := r.operations.take(req.NamespacedName)
+		if inFlight {
+			return ctrl.Result{}, nil
+		}
+		if op != nil {
+			return r.completePowerOperation(ctx, &server, op)
+		}
+		if interruptedPowerOperation(&server) {
+			r.updateStatus(ctx, &server)
+		}
+	}
+
+	// An unset PowerState means off. The defaulting webhook writes it to
+	// the spec, this covers Servers admitted without it.
 	if server.Spec.PowerState == "" {
 		server.Spec.PowerState = baremetalcontrollerv1.PowerStateOff
 	}
 
-	// Ignore if failed status
+	// Ignore if failed status, unless the credentials were fixed or a cold
+	// reset may bring back the BMC
 	if server.Status.Status == baremetalcontrollerv1.StatusFailed {
-		return ctrl.Result{}, nil
+		if r.recoverCredentials(ctx, &server) {
+			return ctrl.Result{Requeue: true}, nil
+		}
+		return r.recoverBMC(ctx, &server), nil
 	}
 
-	// Set to failed if failure count exceeds threshold
-	if server.Status.FailureCount >= 3 {
+	// Set to failed if failure count exceeds threshold, and the server has
+	// been failing for longer than it may take to boot or shut down
+	if failedTooOften(&server, time.Now()) {
+		if server.Status.Reason == "" {
+			server.Status.Reason = timeoutReason(server.Status.Status)
+		}
+		r.appendBootLogTail(&server)
 		server.Status.Status = baremetalcontrollerv1.StatusFailed
-		r.Status().Update(ctx, &server)
+		r.updateStatus(ctx, &server)
 		return ctrl.Result{}, nil
 	}
 
+	// Probe again right away if the BMC pushed a power change
+	r.handleBMCEvents(ctx, &server)
+
 	// Check reachability
 	address := r.getServerAddress(&server)
-	if address == "" {
+	if address == "" && server.Spec.Type != baremetalcontrollerv1.ControlTypeWOL {
 		server.Status.Status = baremetalcontrollerv1.StatusFailed
 		server.Status.Message = "No address configured for server"
-		r.Status().Update(ctx, &server)
+		server.Status.Reason = baremetalcontrollerv1.ReasonSpecInvalid
+		r.updateStatus(ctx, &server)
 		return ctrl.Result{}, fmt.Errorf("no address configured for server %s", server.Name)
 	}
-	reachable := r.Pinger.IsReachable(address)
+	reachable, pingStats, ok := r.isReachable(ctx, &server, address, serverHealthCheck(&server))
+	if !ok {
+		return ctrl.Result{RequeueAfter: reachabilityRetryInterval}, nil
+	}
 
-	// Update status based on reachability
-	switch server.Status.Status {
-	case baremetalcontrollerv1.StatusPending:
-		// Waiting for server to come online
-		if reachable {
-			r.clearFailure(&server, baremetalcontrollerv1.StatusActive)
-		} else {
-			r.recordFailure(&server)
-		}
-		r.Status().Update(ctx, &server)
-		if reachable {
-			return ctrl.Result{}, nil
-		}
-		return ctrl.Result{RequeueAfter: 60 * time.Second}, nil
+	// Keep ping statistics, resolved addresses, hardware inventory, firmware
+	// drift and the power cap up to date
+	statusChanged := r.recordPing(&server, pingStats)
+	if server.Status.Address != address {
+		server.Status.Address = address
+		statusChanged = true
+	}
+	if setReachableCondition(&server, address, reachable) {
+		statusChanged = true
+	}
+	if r.refreshResolvedAddresses(&server) {
+		statusChanged = true
+	}
+	if r.refreshHardwareStatus(ctx, &server, reachable) {
+		statusChanged = true
+	}
+	if r.reconcileTelemetry(ctx, &server) {
+		statusChanged = true
+	}
+	if r.reconcilePowerCap(ctx, &server) {
+		statusChanged = true
+	}
+	// Watch temperatures, powering the server off if they stay critical
+	thermalInterval, thermalChanged := r.checkThermal(ctx, &server)
+	if statusChanged || thermalChanged {
+		r.updateStatus(ctx, &server)
+	}
+	// Give up spot servers reclaimed for a reserved scale-up
+	reclaimInterval, err := r.reclaimSpot(ctx, &server)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if reclaimInterval > 0 {
+		return ctrl.Result{RequeueAfter: reclaimInterval}, nil
+	}
 
-	case baremetalcontrollerv1.StatusDraining:
-		// Waiting for server to go offline
-		if !reachable {
-			r.clearFailure(&server, baremetalcontrollerv1.StatusOffline)
-		} else {
-			r.recordFailure(&server)
-		}
-		r.Status().Update(ctx, &server)
-		if !reachable {
+	// Update status based on reachability
+	previousStatus := server.Status.Status
+	event := lifecycle.Unreachable
+	if reachable {
+		event = lifecycle.Reachable
+	}
+	check := &lifecycleServer{ctx: ctx, r: r, server: &server}
+	if r.probes != nil && address != "" {
+		check.reusedProbe = !r.probes.count(req.NamespacedName)
+	}
+	next, changed, err := lifecycle.ServerLifecycle.Fire(check, previousStatus, event)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	server.Status.Status = next
+	if changed {
+		r.updateStatus(ctx, &server)
+	}
+	// Servers waiting to boot or shut down are checked again until they get
+	// there, and power actions only start once they are settled
+	switch {
+	case lifecycle.ServerLifecycle.Waiting(previousStatus):
+		if next != previousStatus {
 			return ctrl.Result{}, nil
 		}
-		return ctrl.Result{RequeueAfter: 60 * time.Second}, nil
+		return ctrl.Result{RequeueAfter: waitInterval(&server)}, nil
+	case lifecycle.ServerLifecycle.Waiting(next):
+		return ctrl.Result{RequeueAfter: waitInterval(&server)}, nil
+	case lifecycle.ServerLifecycle.Terminal(next):
+		return ctrl.Result{}, nil
+	}
 
-	case baremetalcontrollerv1.StatusActive:
-		// Detect unexpected offline
-		if !reachable {
-			server.Status.Status = baremetalcontrollerv1.StatusOffline
-			r.Status().Update(ctx, &server)
-		}
+	// Arm the BMC watchdog for the booted OS
+	if r.reconcileWatchdog(ctx, &server) {
+		r.updateStatus(ctx, &server)
+	}
 
-	case baremetalcontrollerv1.StatusOffline, "":
-		// Detect unexpected online, or initialize status
-		if reachable {
-			server.Status.Status = baremetalcontrollerv1.StatusActive
-		} else {
-			server.Status.Status = baremetalcontrollerv1.StatusOffline
+	// Remember the server of its node, to delete the node with the server
+	if r.NodeCleanup && server.Status.Status == baremetalcontrollerv1.StatusActive {
+		if err := r.labelNode(ctx, &server); err != nil {
+			log.FromContext(ctx).Error(err, "Failed to label node", "server", server.Name)
 		}
-		r.Status().Update(ctx, &server)
 	}
 
 	// Determine current power state from status
@@ -242,38 +761,171 @@ func (r *ServerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 	if server.Status.Status == baremetalcontrollerv1.StatusActive {
 		currentState = baremetalcontrollerv1.PowerStateOn
 	}
+	// A server asked to reboot stays on between reboots
+	desiredState := server.Spec.PowerState.Settled()
+	reboot := currentState == baremetalcontrollerv1.PowerStateOn && rebootDue(&server)
 
-	// If desired state matches current state, nothing to do
-	if server.Spec.PowerState == currentState {
-		return ctrl.Result{}, nil
+	// Apply the drift policy if the server was powered on or off out of band
+	skip, err := r.checkPowerDrift(ctx, &server, previousStatus, currentState)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if skip {
+		return ctrl.Result{RequeueAfter: requeueInterval(&server)}, nil
+	}
+
+	// Leave the hardware alone while the circuit breaker is open
+	halted, err := r.holdForBreaker(ctx, &server, currentState)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if halted {
+		return ctrl.Result{RequeueAfter: breakerRetryInterval}, nil
+	}
+
+	// Keep the server off while powering it on would exceed a power budget
+	held, err := r.holdForPowerBudget(ctx, &server, currentState)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if held {
+		return ctrl.Result{RequeueAfter: powerBudgetRetryInterval}, nil
+	}
+
+	// If desired state matches current state, nothing to do until the next
+	// check, sooner if the thermal policy polls more often
+	if desiredState == currentState && !reboot {
+		if server.Status.ObservedGeneration != server.Generation {
+			server.Status.ObservedGeneration = server.Generation
+			r.updateStatus(ctx, &server)
+		}
+		requeueAfter := requeueInterval(&server)
+		if thermalInterval > 0 && thermalInterval < requeueAfter {
+			requeueAfter = thermalInterval
+		}
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
 	}
 
 	// Perform power action
-	var err error
-	var newStatus baremetalcontrollerv1.CurrentStatus
+	action := desiredState
+	if reboot {
+		action = baremetalcontrollerv1.PowerStateReboot
+	}
+	if action != baremetalcontrollerv1.PowerStateOn && action != baremetalcontrollerv1.PowerStateOff &&
+		action != baremetalcontrollerv1.PowerStateReboot {
+		return ctrl.Result{}, nil
+	}
+	if r.operations != nil {
+		return r.startPowerOperation(ctx, &server, action)
+	}
+	err = r.performPowerAction(ctx, &server, action)
+	server.Status.ObservedGeneration = server.Generation
+	return r.finishPowerAction(ctx, &server, action, err)
+}
 
-	switch server.Spec.PowerState {
-	case baremetalcontrollerv1.PowerStateOn:
-		err = r.powerOn(ctx, &server)
-		newStatus = baremetalcontrollerv1.StatusPending
+// performPowerAction applies the storage layout and boot policy before
+// powering on, reboots the server, or powers it off
+func (r *ServerReconciler) performPowerAction(ctx context.Context, server *baremetalcontrollerv1.Server, action baremetalcontrollerv1.PowerState) error {
+	switch action {
 	case baremetalcontrollerv1.PowerStateOff:
-		err = r.powerOff(ctx, &server)
-		newStatus = baremetalcontrollerv1.StatusDraining
-	default:
-		return ctrl.Result{}, nil
+		return r.powerOff(ctx, server)
+	case baremetalcontrollerv1.PowerStateReboot:
+		if server.Spec.BootDevice != "" {
+			if err := r.applyBootDevice(ctx, server); err != nil {
+				return err
+			}
+		}
+		if r.BootLogs != nil {
+			r.BootLogs.NewBoot(client.ObjectKeyFromObject(server))
+		}
+		if err := r.reboot(ctx, server); err != nil {
+			return err
+		}
+		recordReboot(server)
+		return nil
+	}
+
+	if server.Spec.Storage != nil {
+		if err := withReason(baremetalcontrollerv1.ReasonStorageFailed, r.applyStorageLayout(ctx, server)); err != nil {
+			return err
+		}
+	}
+	if server.Spec.BootDevice != "" {
+		if err := r.applyBootDevice(ctx, server); err != nil {
+			return err
+		}
+	} else if server.Spec.BootPolicy != nil {
+		if err := r.applyBootPolicy(ctx, server); err != nil {
+			return err
+		}
 	}
+	if r.BootLogs != nil {
+		r.BootLogs.NewBoot(client.ObjectKeyFromObject(server))
+	}
+	if err := r.powerOn(ctx, server); err != nil {
+		return err
+	}
+	// Powering on a server that was off is its reboot
+	recordReboot(server)
+	return nil
+}
 
+// finishPowerAction records the result of a power action and waits for the
+// server to boot or shut down
+func (r *ServerReconciler) finishPowerAction(ctx context.Context, server *baremetalcontrollerv1.Server, action baremetalcontrollerv1.PowerState, err error) (ctrl.Result, error) {
 	if err != nil {
-		server.Status.Status = baremetalcontrollerv1.StatusFailed
 		server.Status.Message = fmt.Sprintf("Power action failed: %v", err)
-		r.Status().Update(ctx, &server)
+		server.Status.Reason = powerFailureReason(server, action, err)
+		setPowerActionCondition(server, action, err)
+		// Temporary errors and unreachable backends are retried until the
+		// failure threshold marks the server failed
+		if power.Retryable(err) {
+			r.recordFailure(server)
+			r.updateStatus(ctx, server)
+			return ctrl.Result{RequeueAfter: powerRetryDelay(server.Status.FailureCount)}, nil
+		}
+		server.Status.Status = baremetalcontrollerv1.StatusFailed
+		r.updateStatus(ctx, server)
 		return ctrl.Result{}, err
 	}
 
-	server.Status.Status = newStatus
-	server.Status.Message = ""
-	r.Status().Update(ctx, &server)
-	return ctrl.Result{RequeueAfter: 60 * time.Second}, nil
+	status := baremetalcontrollerv1.StatusPending
+	switch action {
+	case baremetalcontrollerv1.PowerStateOff:
+		status = baremetalcontrollerv1.StatusDraining
+	case baremetalcontrollerv1.PowerStateReboot:
+		status = baremetalcontrollerv1.StatusRebooting
+	}
+	r.clearFailure(server, status)
+	setPowerActionCondition(server, action, nil)
+	r.updateStatus(ctx, server)
+	// A probe from before the action would count as a failed boot or
+	// shutdown
+	if r.probes != nil {
+		r.probes.expire(client.ObjectKeyFromObject(server))
+	}
+	return ctrl.Result{RequeueAfter: waitInterval(server)}, nil
+}
+
+// powerRetryDelay returns the wait before retrying a power action after the
+// given number of failures in a row
+func powerRetryDelay(failures int) time.Duration {
+	delay := powerRetryInterval
+	for i := 1; i < failures && delay < maxPowerRetryInterval; i++ {
+		delay *= 2
+	}
+	return min(delay, maxPowerRetryInterval)
+}
+
+// requeueInterval returns how long to wait before checking a server again
+func requeueInterval(server *baremetalcontrollerv1.Server) time.Duration {
+	if t := server.Spec.Timeouts; t != nil && t.RequeueInterval != nil {
+		return t.RequeueInterval.Duration
+	}
+	if server.Spec.ReconcileInterval != nil {
+		return server.Spec.ReconcileInterval.Duration
+	}
+	return defaultRequeueInterval
 }
 
 func (r *ServerReconciler) clearFailure(server *baremetalcontrollerv1.Server, newStatus baremetalcontrollerv1.CurrentStatus) {
@@ -281,6 +933,7 @@ func (r *ServerReconciler) clearFailure(server *baremetalcontrollerv1.Server, ne
 	server.Status.FailingSince = nil
 	server.Status.FailureCount = 0
 	server.Status.Message = ""
+	server.Status.Reason = ""
 }
 
 func (r *ServerReconciler) recordFailure(server *baremetalcontrollerv1.Server) {
@@ -293,8 +946,38 @@ func (r *ServerReconciler) recordFailure(server *baremetalcontrollerv1.Server) {
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *ServerReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&baremetalcontrollerv1.Server{}).
-		Named("server").
-		Complete(r)
+		Watches(&baremetalcontrollerv1.ServerClass{}, handler.EnqueueRequestsFromMapFunc(r.serversForClass)).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.serversForSecret))
+
+	r.probes = newReachabilityProbes(r.prober, r.WolRelay)
+	if err := mgr.Add(r.probes); err != nil {
+		return err
+	}
+	b = b.WatchesRawSource(source.Channel(r.probes.events, &handler.EnqueueRequestForObject{}))
+
+	if r.PowerWorkers > 0 {
+		r.operations = newPowerOperations(r.PowerWorkers, r.TenantPowerWorkers)
+		r.operations.gracePeriod = r.PowerShutdownGracePeriod
+		r.operations.checkpoint = r.checkpointPowerOperation
+		if err := mgr.Add(r.operations); err != nil {
+			return err
+		}
+		b = b.WatchesRawSource(source.Channel(r.operations.events, &handler.EnqueueRequestForObject{}))
+	}
+
+	if r.Telemetry != nil {
+		b = b.WatchesRawSource(source.Channel(r.Telemetry.Notifications(), &handler.EnqueueRequestForObject{}))
+	}
+	if r.PlatformEvents != nil {
+		b = b.WatchesRawSource(source.Channel(r.PlatformEvents.Notifications(), &handler.EnqueueRequestForObject{}))
+	}
+
+	if r.FairQueue {
+		b = b.WithOptions(controller.Options{NewQueue: r.newQueue}).
+			Watches(&baremetalcontrollerv1.Server{}, urgentServerChanges)
+	}
+
+	return b.Named("server").Complete(r)
 }
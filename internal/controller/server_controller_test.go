@@ -18,20 +18,38 @@ limitations under the License.
 package controller
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/breaker"
 	"github.com/Unbounder1/bare-metal-controller/internal/power"
+	"github.com/Unbounder1/bare-metal-controller/internal/resolve"
+	"github.com/Unbounder1/bare-metal-controller/internal/telemetry"
 )
 
 var _ = Describe("Server Controller", func() {
@@ -187,6 +205,86 @@ var _ = Describe("Server Controller", func() {
 				Expect(mockWol.LastPort).To(Equal(9))
 			})
 
+			It("should wake and probe the server through its relay", func() {
+				var server baremetalcontrollerv1.Server
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				server.Spec.Control.WOL.Relay = "site-b"
+				Expect(k8sClient.Update(ctx, &server)).To(Succeed())
+
+				mockRelay := &power.MockWolRelay{}
+				reconciler.WolRelay = mockRelay
+				mockPinger.Reachable = true // Only the relay can see the server
+
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mockWol.WakeCalled).To(BeFalse())
+				Expect(mockRelay.WakeCalled).To(BeTrue())
+				Expect(mockRelay.LastRelay).To(Equal("site-b"))
+				Expect(mockRelay.LastMAC).To(Equal("00:11:22:33:44:55"))
+
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusPending))
+			})
+
+			It("should wait for the address of a server without one to be discovered", func() {
+				var server baremetalcontrollerv1.Server
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				server.Spec.Control.WOL.Address = ""
+				Expect(k8sClient.Update(ctx, &server)).To(Succeed())
+				mockPinger.Reachable = true
+
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mockWol.WakeCalled).To(BeTrue())
+				Expect(mockPinger.PingCallCount).To(Equal(0))
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusPending))
+
+				server.Status.Addresses = []baremetalcontrollerv1.ServerAddress{{
+					Address:    "192.168.1.77",
+					MACAddress: "00:11:22:33:44:55",
+					Source:     "neighbor",
+				}}
+				Expect(k8sClient.Status().Update(ctx, &server)).To(Succeed())
+
+				_, err = reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mockPinger.LastAddress).To(Equal("192.168.1.77"))
+			})
+
+			It("should reach a server by the address its hostname resolves to", func() {
+				resolver, err := resolve.New(resolve.DefaultOptions())
+				Expect(err).NotTo(HaveOccurred())
+				reconciler.Resolver = resolver
+
+				var server baremetalcontrollerv1.Server
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				server.Spec.Control.WOL.Address = "localhost"
+				Expect(k8sClient.Update(ctx, &server)).To(Succeed())
+				mockPinger.Reachable = true
+
+				_, err = reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mockPinger.LastAddress).To(Equal("127.0.0.1"))
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				Expect(server.Status.ResolvedAddresses).To(Equal([]baremetalcontrollerv1.ResolvedAddress{{
+					Hostname: "localhost",
+					Address:  "127.0.0.1",
+				}}))
+			})
+
 			It("should set status to booting after sending WoL packet", func() {
 				mockPinger.Reachable = false // Server is off, not yet reachable
 
@@ -199,6 +297,8 @@ var _ = Describe("Server Controller", func() {
 				var server baremetalcontrollerv1.Server
 				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
 				Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusPending))
+				Expect(meta.IsStatusConditionFalse(server.Status.Conditions, baremetalcontrollerv1.ConditionReady)).To(BeTrue())
+				Expect(meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionReady).Reason).To(Equal("Pending"))
 			})
 
 			It("should set status to active when server becomes reachable", func() {
@@ -214,6 +314,56 @@ var _ = Describe("Server Controller", func() {
 				var server baremetalcontrollerv1.Server
 				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
 				Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusActive))
+				Expect(meta.IsStatusConditionTrue(server.Status.Conditions, baremetalcontrollerv1.ConditionReady)).To(BeTrue())
+			})
+
+			It("should record the round trip time of the server's pings once a minute", func() {
+				mockPinger.Reachable = true
+				mockPinger.RTT = 3 * time.Millisecond
+
+				for i := 0; i < 3; i++ {
+					_, err := reconciler.Reconcile(ctx, reconcile.Request{
+						NamespacedName: types.NamespacedName{Name: serverName},
+					})
+					Expect(err).NotTo(HaveOccurred())
+				}
+
+				var server baremetalcontrollerv1.Server
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				Expect(server.Status.Ping).NotTo(BeNil())
+				Expect(server.Status.Ping.Samples).To(HaveLen(1))
+				Expect(server.Status.Ping.Samples[0].RTT.Duration).To(Equal(3 * time.Millisecond))
+				Expect(server.Status.Ping.Samples[0].LossPercent).To(BeZero())
+			})
+
+			It("should count a power cycle when the server comes online and its runtime when it goes down", func() {
+				mockPinger.Reachable = true
+
+				for i := 0; i < 3; i++ {
+					_, err := reconciler.Reconcile(ctx, reconcile.Request{
+						NamespacedName: types.NamespacedName{Name: serverName},
+					})
+					Expect(err).NotTo(HaveOccurred())
+				}
+
+				var server baremetalcontrollerv1.Server
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusActive))
+				Expect(server.Status.Wear).NotTo(BeNil())
+				Expect(server.Status.Wear.PowerCycles).To(Equal(int64(1)))
+				Expect(server.Status.Wear.ActiveSince).NotTo(BeNil())
+
+				mockPinger.Reachable = false
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				Expect(server.Status.Status).NotTo(Equal(baremetalcontrollerv1.StatusActive))
+				Expect(server.Status.Wear.PowerCycles).To(Equal(int64(1)))
+				Expect(server.Status.Wear.ActiveSince).To(BeNil())
+				Expect(server.Status.Wear.Runtime.Duration).To(BeNumerically(">=", 0))
 			})
 
 			It("should set status to failed when WoL packet fails to send", func() {
@@ -229,6 +379,14 @@ var _ = Describe("Server Controller", func() {
 				var server baremetalcontrollerv1.Server
 				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
 				Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusFailed))
+				Expect(server.Status.Reason).To(Equal(baremetalcontrollerv1.ReasonWOLSendFailed))
+
+				// kubectl wait --for=condition=Ready sees why the server isn't ready
+				ready := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionReady)
+				Expect(ready).NotTo(BeNil())
+				Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+				Expect(ready.Reason).To(Equal(string(baremetalcontrollerv1.ReasonWOLSendFailed)))
+				Expect(ready.Message).To(Equal(server.Status.Message))
 			})
 		})
 
@@ -260,6 +418,47 @@ var _ = Describe("Server Controller", func() {
 				Expect(mockSSH.LastUser).To(Equal("admin"))
 			})
 
+			It("should shut down the server at the address DHCP last leased it", func() {
+				var server baremetalcontrollerv1.Server
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				server.Status.Addresses = []baremetalcontrollerv1.ServerAddress{{
+					Address:    "192.168.1.142",
+					MACAddress: "00:11:22:33:44:55",
+					Source:     "kea",
+				}}
+				Expect(k8sClient.Status().Update(ctx, &server)).To(Succeed())
+				mockPinger.Reachable = true
+
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mockSSH.ShutdownCalled).To(BeTrue())
+				Expect(mockSSH.LastHost).To(Equal("192.168.1.142"))
+
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				Expect(server.Status.Addresses).To(HaveLen(1))
+			})
+
+			It("should shut down a server behind NAT through its SSH port forward", func() {
+				var server baremetalcontrollerv1.Server
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				server.Spec.Control.WOL.SSHAddress = "203.0.113.10"
+				server.Spec.Control.WOL.SSHPort = 2222
+				Expect(k8sClient.Update(ctx, &server)).To(Succeed())
+				mockPinger.Reachable = true
+
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mockSSH.ShutdownCalled).To(BeTrue())
+				Expect(mockSSH.LastHost).To(Equal("203.0.113.10:2222"))
+				Expect(mockPinger.LastAddress).To(Equal("192.168.1.100"))
+			})
+
 			It("should set status to draining after sending shutdown", func() {
 				mockPinger.Reachable = true // Still reachable during shutdown
 
@@ -303,6 +502,7 @@ var _ = Describe("Server Controller", func() {
 				var server baremetalcontrollerv1.Server
 				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
 				Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusFailed))
+				Expect(server.Status.Reason).To(Equal(baremetalcontrollerv1.ReasonSSHCommandFailed))
 			})
 		})
 
@@ -385,6 +585,10 @@ var _ = Describe("Server Controller", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(mockIPMI.PowerOnCalled).To(BeTrue())
 				Expect(mockIPMI.LastAddress).To(Equal("192.168.1.101"))
+
+				var server baremetalcontrollerv1.Server
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				Expect(server.Status.ObservedGeneration).To(Equal(server.Generation))
 			})
 
 			It("should set status to active when server is reachable", func() {
@@ -401,11 +605,53 @@ var _ = Describe("Server Controller", func() {
 				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
 				Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusActive))
 			})
+
+			It("should retry a power on that fails with a temporary error", func() {
+				mockPinger.Reachable = false
+				mockIPMI.ReturnError = fmt.Errorf("ipmitool failed: %w", power.ErrTransient)
+
+				result, err := reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.RequeueAfter).To(Equal(15 * time.Second))
+
+				var server baremetalcontrollerv1.Server
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				Expect(server.Status.Status).NotTo(Equal(baremetalcontrollerv1.StatusFailed))
+				Expect(server.Status.Reason).To(Equal(baremetalcontrollerv1.ReasonBMCBusy))
+				Expect(server.Status.FailureCount).To(Equal(1))
+
+				mockIPMI.ReturnError = nil
+				_, err = reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusPending))
+				Expect(server.Status.Reason).To(BeEmpty())
+				Expect(server.Status.FailureCount).To(BeZero())
+			})
+
+			It("should fail a power on the BMC doesn't support straight away", func() {
+				mockPinger.Reachable = false
+				mockIPMI.ReturnError = fmt.Errorf("ipmitool failed: %w", power.ErrUnsupported)
+
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
+				Expect(err).To(HaveOccurred())
+
+				var server baremetalcontrollerv1.Server
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusFailed))
+				Expect(server.Status.Reason).To(Equal(baremetalcontrollerv1.ReasonBMCUnsupported))
+			})
 		})
 
-		Context("when turning off the server", func() {
+		Context("when rebooting the server", func() {
 			BeforeEach(func() {
-				server := createIPMIServer(serverName, baremetalcontrollerv1.PowerStateOff)
+				server := createIPMIServer(serverName, baremetalcontrollerv1.PowerStateReboot)
 				Expect(k8sClient.Create(ctx, server)).To(Succeed())
 
 				var created baremetalcontrollerv1.Server
@@ -414,74 +660,1582 @@ var _ = Describe("Server Controller", func() {
 				Expect(k8sClient.Status().Update(ctx, &created)).To(Succeed())
 			})
 
-			It("should send IPMI power off command", func() {
-				mockPinger.Reachable = true // Server is active and reachable
+			It("should power cycle once and wait for the server to come back", func() {
+				reconcileServer := func() {
+					_, err := reconciler.Reconcile(ctx, reconcile.Request{
+						NamespacedName: types.NamespacedName{Name: serverName},
+					})
+					Expect(err).NotTo(HaveOccurred())
+				}
+				var server baremetalcontrollerv1.Server
+				getServer := func() {
+					Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				}
+
+				mockPinger.Reachable = true
+				reconcileServer()
+				Expect(mockIPMI.PowerCycled).To(BeTrue())
+				Expect(mockIPMI.PowerOnCalled).To(BeFalse())
+				getServer()
+				Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusRebooting))
+				Expect(server.Status.Reboot).NotTo(BeNil())
+				Expect(server.Status.Reboot.Generation).To(Equal(server.Generation))
+
+				// Still up, the reboot hasn't taken it down yet
+				reconcileServer()
+				getServer()
+				Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusRebooting))
+
+				mockPinger.Reachable = false
+				reconcileServer()
+				getServer()
+				Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusPending))
+
+				mockPinger.Reachable = true
+				reconcileServer()
+				getServer()
+				Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusActive))
 
+				// The reboot of this generation is done
+				mockIPMI.PowerCycled = false
+				reconcileServer()
+				Expect(mockIPMI.PowerCycled).To(BeFalse())
+			})
+
+			It("should fail a server that never goes down", func() {
+				mockPinger.Reachable = true
 				_, err := reconciler.Reconcile(ctx, reconcile.Request{
 					NamespacedName: types.NamespacedName{Name: serverName},
 				})
+				Expect(err).NotTo(HaveOccurred())
+
+				var server baremetalcontrollerv1.Server
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				longAgo := metav1.NewTime(time.Now().Add(-rebootShutdownTimeout - time.Minute))
+				server.Status.Reboot.LastReboot = &longAgo
+				Expect(k8sClient.Status().Update(ctx, &server)).To(Succeed())
 
+				_, err = reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
 				Expect(err).NotTo(HaveOccurred())
-				Expect(mockIPMI.PowerOffCalled).To(BeTrue())
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusFailed))
+				Expect(server.Status.Reason).To(Equal(baremetalcontrollerv1.ReasonRebootTimeout))
 			})
+		})
 
-			It("should set status to offline when server is unreachable", func() {
-				mockPinger.Reachable = false // Server has shut down
+		Context("with a PXE-once boot policy", func() {
+			BeforeEach(func() {
+				server := createIPMIServer(serverName, baremetalcontrollerv1.PowerStateOn)
+				server.Spec.BootPolicy = &baremetalcontrollerv1.BootPolicySpec{
+					Sources: []baremetalcontrollerv1.BootSource{
+						baremetalcontrollerv1.BootSourcePXE,
+						baremetalcontrollerv1.BootSourceDisk,
+					},
+				}
+				Expect(k8sClient.Create(ctx, server)).To(Succeed())
+				mockPinger.Reachable = false
+			})
 
-				for i := 0; i < 5; i++ {
+			It("should netboot once and then boot from disk", func() {
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mockIPMI.BootDevice).To(Equal("pxe"))
+				Expect(mockIPMI.BootPersistent).To(BeFalse())
+				Expect(mockIPMI.PowerOnCalled).To(BeTrue())
+
+				// Server finishes booting
+				mockPinger.Reachable = true
+				_, err = reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				var server baremetalcontrollerv1.Server
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusActive))
+				Expect(server.Status.Boot.Completed).To(Equal(1))
+
+				// Server goes down and is powered on again
+				mockPinger.Reachable = false
+				_, err = reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mockIPMI.BootDevice).To(Equal("disk"))
+				Expect(mockIPMI.BootPersistent).To(BeTrue())
+			})
+
+			It("should set the boot device again when the firmware reset it", func() {
+				for _, reachable := range []bool{false, true, false} {
+					mockPinger.Reachable = reachable
 					_, err := reconciler.Reconcile(ctx, reconcile.Request{
 						NamespacedName: types.NamespacedName{Name: serverName},
 					})
-
 					Expect(err).NotTo(HaveOccurred())
 				}
+				Expect(mockIPMI.BootDevice).To(Equal("disk"))
+
+				// A firmware update resets the boot order while the server boots
+				mockIPMI.BootDevice = "bios"
+				mockIPMI.BootPersistent = false
+				mockPinger.Reachable = true
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mockIPMI.BootDevice).To(Equal("disk"))
+				Expect(mockIPMI.BootPersistent).To(BeTrue())
+
 				var server baremetalcontrollerv1.Server
 				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
-				Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusOffline))
+				Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusActive))
+				corrected := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionBootOrderCorrected)
+				Expect(corrected).NotTo(BeNil())
+				Expect(corrected.Status).To(Equal(metav1.ConditionTrue))
+				Expect(corrected.Message).To(Equal("Persistent boot device was bios (next boot only), set to disk"))
 			})
 		})
-	})
 
-	Context("When handling pending states", func() {
-		const serverName = "pending-test-server"
-		secretName := "ssh-secret-" + serverName
+		Context("with a power cap", func() {
+			BeforeEach(func() {
+				server := createIPMIServer(serverName, baremetalcontrollerv1.PowerStateOff)
+				powerCap := int32(300)
+				server.Spec.PowerCapWatts = &powerCap
+				Expect(k8sClient.Create(ctx, server)).To(Succeed())
+				mockIPMI.PowerLimit = power.PowerLimit{ConsumedWatts: 280}
+				mockPinger.Reachable = false
+			})
 
-		AfterEach(func() {
-			deleteServer(serverName)
-			deleteSecret(secretName, testNamespace)
-		})
+			It("should apply the limit and report compliance until it's removed", func() {
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mockIPMI.PowerLimit.LimitWatts).To(Equal(int32(300)))
 
-		It("should requeue when status is booting", func() {
-			secret := createSSHSecret(secretName, testNamespace)
-			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+				var server baremetalcontrollerv1.Server
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				Expect(server.Status.PowerCap).NotTo(BeNil())
+				Expect(server.Status.PowerCap.LimitWatts).To(Equal(int32(300)))
+				Expect(server.Status.PowerCap.ConsumedWatts).To(Equal(int32(280)))
+				compliant := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionPowerCapCompliant)
+				Expect(compliant).NotTo(BeNil())
+				Expect(compliant.Status).To(Equal(metav1.ConditionTrue))
+
+				server.Spec.PowerCapWatts = nil
+				Expect(k8sClient.Update(ctx, &server)).To(Succeed())
+				_, err = reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mockIPMI.PowerLimit.LimitWatts).To(BeZero())
 
-			server := createWolServer(serverName, baremetalcontrollerv1.PowerStateOn)
-			Expect(k8sClient.Create(ctx, server)).To(Succeed())
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				Expect(server.Status.PowerCap).To(BeNil())
+				Expect(meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionPowerCapCompliant)).To(BeNil())
+			})
+		})
 
-			var created baremetalcontrollerv1.Server
-			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &created)).To(Succeed())
-			created.Status.Status = baremetalcontrollerv1.StatusPending
-			Expect(k8sClient.Status().Update(ctx, &created)).To(Succeed())
+		Context("with a watchdog", func() {
+			BeforeEach(func() {
+				server := createIPMIServer(serverName, baremetalcontrollerv1.PowerStateOn)
+				server.Spec.Watchdog = &baremetalcontrollerv1.WatchdogSpec{
+					Timeout: &metav1.Duration{Duration: 3 * time.Minute},
+					Action:  baremetalcontrollerv1.WatchdogActionPowerCycle,
+				}
+				Expect(k8sClient.Create(ctx, server)).To(Succeed())
+				mockPinger.Reachable = true
+			})
 
-			mockPinger.Reachable = false // Not yet reachable, should requeue
+			It("should arm the watchdog once booted and disarm it before powering off", func() {
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mockIPMI.WatchdogTimeout).To(Equal(3 * time.Minute))
+				Expect(mockIPMI.WatchdogAction).To(Equal(power.WatchdogActionPowerCycle))
 
-			result, err := reconciler.Reconcile(ctx, reconcile.Request{
-				NamespacedName: types.NamespacedName{Name: serverName},
-			})
+				var server baremetalcontrollerv1.Server
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				armed := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionWatchdogArmed)
+				Expect(armed).NotTo(BeNil())
+				Expect(armed.Status).To(Equal(metav1.ConditionTrue))
 
-			Expect(err).NotTo(HaveOccurred())
-			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+				server.Spec.PowerState = baremetalcontrollerv1.PowerStateOff
+				Expect(k8sClient.Update(ctx, &server)).To(Succeed())
+				_, err = reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mockIPMI.PowerOffCalled).To(BeTrue())
+				Expect(mockIPMI.WatchdogTimeout).To(BeZero())
+			})
 		})
 
-		It("should requeue when status is draining", func() {
-			secret := createSSHSecret(secretName, testNamespace)
-			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+		Context("while the circuit breaker is open", func() {
+			breakerKey := types.NamespacedName{Namespace: "default", Name: "power-circuit-breaker"}
 
-			server := createWolServer(serverName, baremetalcontrollerv1.PowerStateOff)
-			Expect(k8sClient.Create(ctx, server)).To(Succeed())
+			BeforeEach(func() {
+				Expect(k8sClient.Create(ctx, createIPMIServer(serverName, baremetalcontrollerv1.PowerStateOn))).To(Succeed())
+				Expect(breaker.Set(ctx, k8sClient, breakerKey, true, "incident 42")).To(Succeed())
 
-			var created baremetalcontrollerv1.Server
-			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &created)).To(Succeed())
+				var err error
+				reconciler.Breaker, err = breaker.New(k8sClient, breaker.Options{ConfigMap: breakerKey.String()})
+				Expect(err).NotTo(HaveOccurred())
+				mockPinger.Reachable = false
+			})
+
+			AfterEach(func() {
+				cm := &corev1.ConfigMap{}
+				Expect(k8sClient.Get(ctx, breakerKey, cm)).To(Succeed())
+				Expect(k8sClient.Delete(ctx, cm)).To(Succeed())
+			})
+
+			It("should hold power actions until it is closed", func() {
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mockIPMI.PowerOnCalled).To(BeFalse())
+
+				var server baremetalcontrollerv1.Server
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				halted := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionPowerActionsHalted)
+				Expect(halted).NotTo(BeNil())
+				Expect(halted.Message).To(Equal("incident 42"))
+
+				Expect(breaker.Set(ctx, k8sClient, breakerKey, false, "")).To(Succeed())
+				_, err = reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mockIPMI.PowerOnCalled).To(BeTrue())
+			})
+		})
+
+		Context("with a wedged BMC", func() {
+			BeforeEach(func() {
+				server := createIPMIServer(serverName, baremetalcontrollerv1.PowerStateOn)
+				Expect(k8sClient.Create(ctx, server)).To(Succeed())
+
+				var created baremetalcontrollerv1.Server
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &created)).To(Succeed())
+				created.Status.Status = baremetalcontrollerv1.StatusFailed
+				created.Status.Reason = baremetalcontrollerv1.ReasonBMCUnreachable
+				Expect(k8sClient.Status().Update(ctx, &created)).To(Succeed())
+
+				reconciler.BMCColdResetBackoff = time.Hour
+				mockIPMI.ReturnError = fmt.Errorf("ipmitool failed: %w", power.ErrUnreachable)
+				mockPinger.Reachable = true
+			})
+
+			It("should cold reset the BMC once and retry the server once it answers", func() {
+				for i := 0; i < 2; i++ {
+					_, err := reconciler.Reconcile(ctx, reconcile.Request{
+						NamespacedName: types.NamespacedName{Name: serverName},
+					})
+					Expect(err).NotTo(HaveOccurred())
+				}
+				Expect(mockIPMI.ColdResetCalled).To(BeTrue())
+
+				var server baremetalcontrollerv1.Server
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				Expect(server.Status.BMCReset).NotTo(BeNil())
+				Expect(server.Status.BMCReset.Count).To(Equal(int32(1)))
+
+				mockIPMI.ReturnError = nil
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				Expect(server.Status.Status).NotTo(Equal(baremetalcontrollerv1.StatusFailed))
+				Expect(server.Status.Reason).To(BeEmpty())
+			})
+		})
+
+		Context("when turning off the server", func() {
+			BeforeEach(func() {
+				server := createIPMIServer(serverName, baremetalcontrollerv1.PowerStateOff)
+				Expect(k8sClient.Create(ctx, server)).To(Succeed())
+
+				var created baremetalcontrollerv1.Server
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &created)).To(Succeed())
+				created.Status.Status = baremetalcontrollerv1.StatusActive
+				Expect(k8sClient.Status().Update(ctx, &created)).To(Succeed())
+			})
+
+			It("should send IPMI power off command", func() {
+				mockPinger.Reachable = true // Server is active and reachable
+
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mockIPMI.PowerOffCalled).To(BeTrue())
+			})
+
+			It("should set status to offline when server is unreachable", func() {
+				mockPinger.Reachable = false // Server has shut down
+
+				for i := 0; i < 5; i++ {
+					_, err := reconciler.Reconcile(ctx, reconcile.Request{
+						NamespacedName: types.NamespacedName{Name: serverName},
+					})
+
+					Expect(err).NotTo(HaveOccurred())
+				}
+				var server baremetalcontrollerv1.Server
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusOffline))
+			})
+		})
+	})
+
+	Context("When reconciling a MAAS server", func() {
+		const serverName = "maas-test-server"
+		secretName := "maas-secret-" + serverName
+
+		var mockMAAS *power.MockMAASClient
+
+		createMAASServer := func(desiredPower baremetalcontrollerv1.PowerState, deploy bool) *baremetalcontrollerv1.Server {
+			return &baremetalcontrollerv1.Server{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: serverName,
+				},
+				Spec: baremetalcontrollerv1.ServerSpec{
+					PowerState: desiredPower,
+					Type:       baremetalcontrollerv1.ControlTypeMAAS,
+					Control: baremetalcontrollerv1.ControlSpecs{
+						MAAS: &baremetalcontrollerv1.MAASSpecs{
+							Address:  "192.168.1.102",
+							Endpoint: "http://maas.example.com:5240/MAAS",
+							SystemID: "abc123",
+							Deploy:   deploy,
+							APIKeySecretRef: &baremetalcontrollerv1.SecretReference{
+								Name:      secretName,
+								Namespace: testNamespace,
+							},
+						},
+					},
+				},
+			}
+		}
+
+		BeforeEach(func() {
+			mockMAAS = &power.MockMAASClient{}
+			reconciler.MAASClient = mockMAAS
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      secretName,
+					Namespace: testNamespace,
+				},
+				Data: map[string][]byte{
+					"api-key": []byte("consumer:token:secret"),
+				},
+			}
+			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteServer(serverName)
+			deleteSecret(secretName, testNamespace)
+		})
+
+		It("should power on the machine with the API key from the secret", func() {
+			Expect(k8sClient.Create(ctx, createMAASServer(baremetalcontrollerv1.PowerStateOn, false))).To(Succeed())
+			mockPinger.Reachable = false
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockMAAS.PowerOnCalled).To(BeTrue())
+			Expect(mockMAAS.DeployCalled).To(BeFalse())
+			Expect(mockMAAS.LastSystemID).To(Equal("abc123"))
+			Expect(mockMAAS.LastAPIKey).To(Equal("consumer:token:secret"))
+		})
+
+		It("should deploy the machine when deploy is set", func() {
+			Expect(k8sClient.Create(ctx, createMAASServer(baremetalcontrollerv1.PowerStateOn, true))).To(Succeed())
+			mockPinger.Reachable = false
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockMAAS.DeployCalled).To(BeTrue())
+			Expect(mockMAAS.PowerOnCalled).To(BeFalse())
+		})
+
+		It("should release the machine on power off when deploy is set", func() {
+			Expect(k8sClient.Create(ctx, createMAASServer(baremetalcontrollerv1.PowerStateOff, true))).To(Succeed())
+
+			var created baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &created)).To(Succeed())
+			created.Status.Status = baremetalcontrollerv1.StatusActive
+			Expect(k8sClient.Status().Update(ctx, &created)).To(Succeed())
+
+			mockPinger.Reachable = true
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockMAAS.ReleaseCalled).To(BeTrue())
+		})
+	})
+
+	Context("When reconciling an Equinix Metal server", func() {
+		const serverName = "equinix-test-server"
+		secretName := "equinix-secret-" + serverName
+
+		var mockEquinix *power.MockEquinixClient
+
+		createEquinixServer := func(desiredPower baremetalcontrollerv1.PowerState, deviceID string) *baremetalcontrollerv1.Server {
+			return &baremetalcontrollerv1.Server{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: serverName,
+				},
+				Spec: baremetalcontrollerv1.ServerSpec{
+					PowerState: desiredPower,
+					Type:       baremetalcontrollerv1.ControlTypeEquinix,
+					Control: baremetalcontrollerv1.ControlSpecs{
+						Equinix: &baremetalcontrollerv1.EquinixSpecs{
+							Address:   "147.75.1.20",
+							ProjectID: "project-1",
+							DeviceID:  deviceID,
+							APITokenSecretRef: &baremetalcontrollerv1.SecretReference{
+								Name:      secretName,
+								Namespace: testNamespace,
+							},
+						},
+					},
+				},
+			}
+		}
+
+		BeforeEach(func() {
+			mockEquinix = &power.MockEquinixClient{
+				Devices: map[string]string{serverName: "device-by-hostname"},
+			}
+			reconciler.EquinixClient = mockEquinix
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      secretName,
+					Namespace: testNamespace,
+				},
+				Data: map[string][]byte{
+					"api-token": []byte("metal-token"),
+				},
+			}
+			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteServer(serverName)
+			deleteSecret(secretName, testNamespace)
+		})
+
+		It("should power on the device with the API token from the secret", func() {
+			Expect(k8sClient.Create(ctx, createEquinixServer(baremetalcontrollerv1.PowerStateOn, "device-1"))).To(Succeed())
+			mockPinger.Reachable = false
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockEquinix.PowerOnCalled).To(BeTrue())
+			Expect(mockEquinix.LastDeviceID).To(Equal("device-1"))
+			Expect(mockEquinix.LastToken).To(Equal("metal-token"))
+		})
+
+		It("should find the device by the server name without a device ID", func() {
+			Expect(k8sClient.Create(ctx, createEquinixServer(baremetalcontrollerv1.PowerStateOn, ""))).To(Succeed())
+			mockPinger.Reachable = false
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockEquinix.LastHostname).To(Equal(serverName))
+			Expect(mockEquinix.PowerOnCalled).To(BeTrue())
+			Expect(mockEquinix.LastDeviceID).To(Equal("device-by-hostname"))
+		})
+
+		It("should power off the device", func() {
+			Expect(k8sClient.Create(ctx, createEquinixServer(baremetalcontrollerv1.PowerStateOff, "device-1"))).To(Succeed())
+
+			var created baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &created)).To(Succeed())
+			created.Status.Status = baremetalcontrollerv1.StatusActive
+			Expect(k8sClient.Status().Update(ctx, &created)).To(Succeed())
+
+			mockPinger.Reachable = true
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockEquinix.PowerOffCalled).To(BeTrue())
+		})
+	})
+
+	Context("When reconciling a Hetzner server", func() {
+		const serverName = "hetzner-test-server"
+		secretName := "robot-secret-" + serverName
+
+		var mockHetzner *power.MockHetznerClient
+
+		createHetznerServer := func(desiredPower baremetalcontrollerv1.PowerState) *baremetalcontrollerv1.Server {
+			return &baremetalcontrollerv1.Server{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: serverName,
+				},
+				Spec: baremetalcontrollerv1.ServerSpec{
+					PowerState: desiredPower,
+					Type:       baremetalcontrollerv1.ControlTypeHetzner,
+					Control: baremetalcontrollerv1.ControlSpecs{
+						Hetzner: &baremetalcontrollerv1.HetznerSpecs{
+							Address:      "203.0.113.30",
+							ServerNumber: 321,
+							CredentialsSecretRef: &baremetalcontrollerv1.SecretReference{
+								Name:      secretName,
+								Namespace: testNamespace,
+							},
+						},
+					},
+				},
+			}
+		}
+
+		BeforeEach(func() {
+			mockHetzner = &power.MockHetznerClient{}
+			reconciler.HetznerClient = mockHetzner
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      secretName,
+					Namespace: testNamespace,
+				},
+				Data: map[string][]byte{
+					"username": []byte("#ws+robot"),
+					"password": []byte("robot-password"),
+				},
+			}
+			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteServer(serverName)
+			deleteSecret(secretName, testNamespace)
+		})
+
+		It("should wake the server through Robot", func() {
+			Expect(k8sClient.Create(ctx, createHetznerServer(baremetalcontrollerv1.PowerStateOn))).To(Succeed())
+			mockPinger.Reachable = false
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockHetzner.WakeCalled).To(BeTrue())
+			Expect(mockHetzner.RescueCalled).To(BeFalse())
+			Expect(mockHetzner.LastServerNumber).To(Equal(int32(321)))
+			Expect(mockHetzner.LastUsername).To(Equal("#ws+robot"))
+		})
+
+		It("should enable the rescue system before waking the server", func() {
+			server := createHetznerServer(baremetalcontrollerv1.PowerStateOn)
+			server.Spec.Control.Hetzner.Rescue = &baremetalcontrollerv1.HetznerRescue{
+				AuthorizedKeys: []string{"15:28:b0:03:95:f0:77:b3:10:56:15:6b:77:22:a5:bb"},
+			}
+			Expect(k8sClient.Create(ctx, server)).To(Succeed())
+			mockPinger.Reachable = false
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockHetzner.RescueCalled).To(BeTrue())
+			Expect(mockHetzner.LastRescueOS).To(Equal("linux"))
+			Expect(mockHetzner.LastAuthorizedKeys).To(HaveLen(1))
+			Expect(mockHetzner.WakeCalled).To(BeTrue())
+		})
+
+		It("should press the power button on power off", func() {
+			Expect(k8sClient.Create(ctx, createHetznerServer(baremetalcontrollerv1.PowerStateOff))).To(Succeed())
+
+			var created baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &created)).To(Succeed())
+			created.Status.Status = baremetalcontrollerv1.StatusActive
+			Expect(k8sClient.Status().Update(ctx, &created)).To(Succeed())
+
+			mockPinger.Reachable = true
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockHetzner.ResetCalled).To(BeTrue())
+			Expect(mockHetzner.LastResetType).To(Equal("power"))
+		})
+	})
+
+	Context("When reconciling an ESXi host", func() {
+		const serverName = "esxi-test-server"
+		secretName := "esxi-secret-" + serverName
+
+		var mockHypervisor *power.MockHypervisorClient
+		var mockIPMI *power.MockIPMIClient
+
+		createESXiServer := func(desiredPower baremetalcontrollerv1.PowerState) *baremetalcontrollerv1.Server {
+			return &baremetalcontrollerv1.Server{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: serverName,
+				},
+				Spec: baremetalcontrollerv1.ServerSpec{
+					PowerState: desiredPower,
+					Type:       baremetalcontrollerv1.ControlTypeESXi,
+					Control: baremetalcontrollerv1.ControlSpecs{
+						ESXi: &baremetalcontrollerv1.ESXiSpecs{
+							Address: "192.168.1.140",
+							CredentialsSecretRef: &baremetalcontrollerv1.SecretReference{
+								Name:      secretName,
+								Namespace: testNamespace,
+							},
+						},
+						IPMI: &baremetalcontrollerv1.IPMISpecs{
+							Address:  "192.168.1.141",
+							Username: "admin",
+							Password: "password",
+						},
+					},
+				},
+			}
+		}
+
+		BeforeEach(func() {
+			mockHypervisor = &power.MockHypervisorClient{}
+			reconciler.HypervisorClient = mockHypervisor
+			mockIPMI = &power.MockIPMIClient{}
+			reconciler.IPMIClient = mockIPMI
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      secretName,
+					Namespace: testNamespace,
+				},
+				Data: map[string][]byte{
+					"username": []byte("root"),
+					"password": []byte("esxi-password"),
+				},
+			}
+			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteServer(serverName)
+			deleteSecret(secretName, testNamespace)
+		})
+
+		It("should power on the host through its BMC", func() {
+			Expect(k8sClient.Create(ctx, createESXiServer(baremetalcontrollerv1.PowerStateOn))).To(Succeed())
+			mockPinger.Reachable = false
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockIPMI.PowerOnCalled).To(BeTrue())
+			Expect(mockIPMI.LastAddress).To(Equal("192.168.1.141"))
+			Expect(mockPinger.LastAddress).To(Equal("192.168.1.140"))
+		})
+
+		It("should take the host out of maintenance mode once it is up", func() {
+			Expect(k8sClient.Create(ctx, createESXiServer(baremetalcontrollerv1.PowerStateOn))).To(Succeed())
+
+			var created baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &created)).To(Succeed())
+			created.Status.Status = baremetalcontrollerv1.StatusPending
+			Expect(k8sClient.Status().Update(ctx, &created)).To(Succeed())
+			mockPinger.Reachable = true
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockHypervisor.ExitMaintenanceCalled).To(BeTrue())
+			Expect(mockHypervisor.LastTarget.Password).To(Equal("esxi-password"))
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &created)).To(Succeed())
+			Expect(created.Status.Status).To(Equal(baremetalcontrollerv1.StatusActive))
+		})
+
+		It("should enter maintenance mode before shutting the host down", func() {
+			Expect(k8sClient.Create(ctx, createESXiServer(baremetalcontrollerv1.PowerStateOff))).To(Succeed())
+
+			var created baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &created)).To(Succeed())
+			created.Status.Status = baremetalcontrollerv1.StatusActive
+			Expect(k8sClient.Status().Update(ctx, &created)).To(Succeed())
+			mockPinger.Reachable = true
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockHypervisor.EnterMaintenanceCalled).To(BeTrue())
+			Expect(mockHypervisor.LastTimeout).To(Equal(5 * time.Minute))
+			Expect(mockHypervisor.ShutdownCalled).To(BeTrue())
+			Expect(mockIPMI.PowerOffCalled).To(BeFalse())
+		})
+	})
+
+	Context("When reconciling a Redfish server with a RAID layout", func() {
+		const serverName = "redfish-raid-test-server"
+		secretName := "bmc-secret-" + serverName
+
+		var mockRedfish *power.MockRedfishClient
+
+		BeforeEach(func() {
+			mockRedfish = &power.MockRedfishClient{}
+			reconciler.RedfishClient = mockRedfish
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      secretName,
+					Namespace: testNamespace,
+				},
+				Data: map[string][]byte{
+					"username": []byte("admin"),
+					"password": []byte("password"),
+					"ca.crt":   []byte("pinned-certificate"),
+				},
+			}
+			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+
+			server := &baremetalcontrollerv1.Server{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: serverName,
+				},
+				Spec: baremetalcontrollerv1.ServerSpec{
+					PowerState: baremetalcontrollerv1.PowerStateOn,
+					Type:       baremetalcontrollerv1.ControlTypeRedfish,
+					Control: baremetalcontrollerv1.ControlSpecs{
+						Redfish: &baremetalcontrollerv1.RedfishSpecs{
+							Address: "https://192.168.1.110",
+							CredentialsSecretRef: &baremetalcontrollerv1.SecretReference{
+								Name:      secretName,
+								Namespace: testNamespace,
+							},
+						},
+					},
+					Storage: &baremetalcontrollerv1.StorageSpec{
+						Volumes: []baremetalcontrollerv1.VolumeSpec{
+							{Name: "os", RAIDType: "RAID1", Drives: []string{"0", "1"}},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, server)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteServer(serverName)
+			deleteSecret(secretName, testNamespace)
+		})
+
+		It("should create missing volumes before powering on", func() {
+			mockPinger.Reachable = false
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockRedfish.CreatedVolumes).To(HaveLen(1))
+			Expect(mockRedfish.CreatedVolumes[0].RAIDType).To(Equal("RAID1"))
+			Expect(mockRedfish.PowerOnCalled).To(BeTrue())
+			Expect(mockRedfish.LastTarget.Username).To(Equal("admin"))
+			Expect(mockRedfish.LastTarget.TLS.InsecureSkipVerify).To(BeTrue())
+
+			var server baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+			Expect(server.Status.Storage).NotTo(BeNil())
+			Expect(server.Status.Storage.Phase).To(Equal(baremetalcontrollerv1.StoragePhaseApplied))
+		})
+
+		It("should verify the BMC certificate against the pinned CA", func() {
+			mockPinger.Reachable = false
+
+			var server baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+			server.Spec.Control.Redfish.TLS = &baremetalcontrollerv1.TLSSpecs{
+				CASecretRef: &baremetalcontrollerv1.SecretReference{
+					Name:      secretName,
+					Namespace: testNamespace,
+				},
+				ServerName: "bmc-110.example.com",
+			}
+			Expect(k8sClient.Update(ctx, &server)).To(Succeed())
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockRedfish.PowerOnCalled).To(BeTrue())
+			Expect(mockRedfish.LastTarget.TLS.InsecureSkipVerify).To(BeFalse())
+			Expect(mockRedfish.LastTarget.TLS.ServerName).To(Equal("bmc-110.example.com"))
+			Expect(string(mockRedfish.LastTarget.TLS.CABundle)).To(Equal("pinned-certificate"))
+		})
+
+		It("should verify the layout once the server is up", func() {
+			mockPinger.Reachable = false
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			mockPinger.Reachable = true
+			mockRedfish.Volumes = []power.Volume{
+				{ID: "1", Name: "os", RAIDType: "RAID1", Drives: []string{"1", "0"}},
+			}
+			_, err = reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			var server baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+			Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusActive))
+			Expect(server.Status.Storage.Phase).To(Equal(baremetalcontrollerv1.StoragePhaseVerified))
+		})
+	})
+
+	Context("When a Redfish BMC pushes metric reports", func() {
+		const serverName = "telemetry-test-server"
+		secretName := "bmc-secret-" + serverName
+
+		var mockRedfish *power.MockRedfishClient
+		var receiver *telemetry.Receiver
+
+		// post sends a body to a receiver endpoint as the server's BMC
+		post := func(serve http.HandlerFunc, path string, body string) int {
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+			req.RemoteAddr = "192.168.1.111:40000"
+			recorder := httptest.NewRecorder()
+			serve(recorder, req)
+			return recorder.Code
+		}
+
+		BeforeEach(func() {
+			mockRedfish = &power.MockRedfishClient{PowerLimit: power.PowerLimit{ConsumedWatts: 250}}
+			reconciler.RedfishClient = mockRedfish
+			receiver = telemetry.NewReceiver(telemetry.Options{Address: ":0", URL: "http://telemetry.test:8089/"})
+			reconciler.Telemetry = receiver
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      secretName,
+					Namespace: testNamespace,
+				},
+				Data: map[string][]byte{
+					"username": []byte("admin"),
+					"password": []byte("password"),
+				},
+			}
+			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+
+			powerCap := int32(300)
+			server := &baremetalcontrollerv1.Server{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: serverName,
+				},
+				Spec: baremetalcontrollerv1.ServerSpec{
+					PowerState:    baremetalcontrollerv1.PowerStateOn,
+					Type:          baremetalcontrollerv1.ControlTypeRedfish,
+					PowerCapWatts: &powerCap,
+					Control: baremetalcontrollerv1.ControlSpecs{
+						Redfish: &baremetalcontrollerv1.RedfishSpecs{
+							Address: "https://192.168.1.111",
+							CredentialsSecretRef: &baremetalcontrollerv1.SecretReference{
+								Name:      secretName,
+								Namespace: testNamespace,
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, server)).To(Succeed())
+			mockPinger.Reachable = true
+		})
+
+		AfterEach(func() {
+			reconciler.Telemetry = nil
+			deleteServer(serverName)
+			deleteSecret(secretName, testNamespace)
+		})
+
+		It("should subscribe the BMC and take the power draw from its reports", func() {
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockRedfish.Subscription).To(Equal("http://telemetry.test:8089/telemetry/" + serverName))
+
+			var server baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+			Expect(server.Status.Telemetry).NotTo(BeNil())
+			Expect(server.Status.Telemetry.Subscription).To(Equal("/redfish/v1/EventService/Subscriptions/1"))
+			Expect(server.Status.PowerCap.ConsumedWatts).To(Equal(int32(250)))
+
+			Expect(mockRedfish.SubscriptionContext).NotTo(BeEmpty())
+			report := fmt.Sprintf(`{"Context": %q, "MetricValues": [{"MetricId": "PowerConsumedWatts", "MetricValue": "280",
+				"MetricProperty": "/redfish/v1/Chassis/1/Power#/PowerControl/0/PowerConsumedWatts"}]}`, mockRedfish.SubscriptionContext)
+			Expect(post(receiver.ServeHTTP, telemetry.PathPrefix+serverName, report)).To(Equal(http.StatusNoContent))
+
+			// Let the power draw be due for a refresh
+			stale := metav1.NewTime(time.Now().Add(-2 * time.Minute))
+			server.Status.PowerCap.LastUpdated = &stale
+			Expect(k8sClient.Status().Update(ctx, &server)).To(Succeed())
+
+			_, err = reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+			Expect(server.Status.PowerCap.ConsumedWatts).To(Equal(int32(280)))
+		})
+
+		It("should subscribe the BMC to events and record the events it posts", func() {
+			recorder := record.NewFakeRecorder(10)
+			reconciler.Recorder = recorder
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockRedfish.EventSubscription).To(Equal("http://telemetry.test:8089/events/" + serverName))
+
+			var server baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+			Expect(server.Status.Telemetry.EventSubscription).To(Equal("/redfish/v1/EventService/Subscriptions/2"))
+
+			payload := fmt.Sprintf(`{"Context": %q, "Events": [{"EventType": "Alert", "MessageId": "iLOEvents.2.1.ServerPoweredOff",
+				"Message": "Server power removed.", "MessageSeverity": "Warning"}]}`, mockRedfish.SubscriptionContext)
+			Expect(post(receiver.ServeEvents, telemetry.EventPathPrefix+serverName, payload)).To(Equal(http.StatusNoContent))
+			Expect(receiver.Notifications()).To(Receive())
+
+			_, err = reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorder.Events).To(Receive(ContainSubstring("Warning iLOEvents.2.1.ServerPoweredOff: Server power removed.")))
+			Expect(receiver.TakeEvents(types.NamespacedName{Name: serverName})).To(BeEmpty())
+		})
+
+		It("should reject reports for servers that weren't subscribed", func() {
+			Expect(post(receiver.ServeHTTP, telemetry.PathPrefix+"unknown", `{}`)).To(Equal(http.StatusNotFound))
+			Expect(post(receiver.ServeEvents, telemetry.EventPathPrefix+"unknown", `{}`)).To(Equal(http.StatusNotFound))
+		})
+
+		It("should reject events without the subscription's context", func() {
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			forged := `{"Context": "guessed", "Events": [{"MessageId": "ResourceEvent.1.3.PowerStateChanged"}]}`
+			Expect(post(receiver.ServeEvents, telemetry.EventPathPrefix+serverName, forged)).To(Equal(http.StatusForbidden))
+			Expect(receiver.TakeEvents(types.NamespacedName{Name: serverName})).To(BeEmpty())
+		})
+
+		It("should subscribe again with a new context after a restart", func() {
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			first := mockRedfish.SubscriptionContext
+
+			receiver = telemetry.NewReceiver(telemetry.Options{Address: ":0", URL: "http://telemetry.test:8089/"})
+			reconciler.Telemetry = receiver
+			_, err = reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockRedfish.SubscriptionContext).NotTo(Equal(first))
+			Expect(receiver.Expecting(types.NamespacedName{Name: serverName})).To(BeTrue())
+		})
+	})
+
+	Context("When comparing firmware with a ServerClass baseline", func() {
+		const (
+			serverName = "firmware-test-server"
+			className  = "firmware-test-class"
+		)
+		secretName := "bmc-secret-" + serverName
+
+		var mockRedfish *power.MockRedfishClient
+
+		BeforeEach(func() {
+			mockRedfish = &power.MockRedfishClient{
+				Inventory: power.Inventory{
+					Manufacturer:       "Dell Inc.",
+					Model:              "PowerEdge R650",
+					BIOSVersion:        "1.8.2",
+					BMCFirmwareVersion: "7.00.00.171",
+					CPUs:               64,
+					MemoryGiB:          256,
+				},
+			}
+			reconciler.RedfishClient = mockRedfish
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      secretName,
+					Namespace: testNamespace,
+				},
+				Data: map[string][]byte{
+					"username": []byte("admin"),
+					"password": []byte("password"),
+				},
+			}
+			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+
+			class := &baremetalcontrollerv1.ServerClass{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: className,
+				},
+				Spec: baremetalcontrollerv1.ServerClassSpec{
+					Firmware: &baremetalcontrollerv1.FirmwareBaseline{
+						BIOSVersion:        "1.9.0",
+						BMCFirmwareVersion: "7.00.00.171",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, class)).To(Succeed())
+
+			server := &baremetalcontrollerv1.Server{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: serverName,
+				},
+				Spec: baremetalcontrollerv1.ServerSpec{
+					PowerState:      baremetalcontrollerv1.PowerStateOff,
+					Type:            baremetalcontrollerv1.ControlTypeRedfish,
+					ServerClassName: className,
+					Control: baremetalcontrollerv1.ControlSpecs{
+						Redfish: &baremetalcontrollerv1.RedfishSpecs{
+							Address: "https://192.168.1.111",
+							CredentialsSecretRef: &baremetalcontrollerv1.SecretReference{
+								Name:      secretName,
+								Namespace: testNamespace,
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, server)).To(Succeed())
+			mockPinger.Reachable = false
+		})
+
+		AfterEach(func() {
+			deleteServer(serverName)
+			deleteSecret(secretName, testNamespace)
+			class := &baremetalcontrollerv1.ServerClass{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{Name: className}, class); err == nil {
+				Expect(k8sClient.Delete(ctx, class)).To(Succeed())
+			}
+		})
+
+		It("should report the inventory and flag drift from the baseline", func() {
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			var server baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+			Expect(server.Status.Hardware).NotTo(BeNil())
+			Expect(server.Status.Hardware.Model).To(Equal("PowerEdge R650"))
+			Expect(server.Status.Hardware.BIOSVersion).To(Equal("1.8.2"))
+			Expect(server.Status.Hardware.CPUs).To(Equal(int32(64)))
+			Expect(server.Status.Hardware.Memory.String()).To(Equal("256Gi"))
+
+			drift := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionFirmwareDrift)
+			Expect(drift).NotTo(BeNil())
+			Expect(drift.Status).To(Equal(metav1.ConditionTrue))
+			Expect(drift.Message).To(ContainSubstring("BIOS version 1.8.2, expected 1.9.0"))
+		})
+
+		It("should clear drift once the firmware matches", func() {
+			mockRedfish.Inventory.BIOSVersion = "1.9.0"
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			var server baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+			drift := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionFirmwareDrift)
+			Expect(drift).NotTo(BeNil())
+			Expect(drift.Status).To(Equal(metav1.ConditionFalse))
+		})
+	})
+
+	Context("When a server overheats", func() {
+		const serverName = "thermal-test-server"
+		const className = "thermal-test-class"
+
+		var mockIPMI *power.MockIPMIClient
+
+		BeforeEach(func() {
+			mockIPMI = &power.MockIPMIClient{
+				Temperatures: []power.Temperature{
+					{Name: "Inlet Temp", Celsius: 24, CriticalCelsius: 47},
+					{Name: "CPU Temp", Celsius: 101.4, CriticalCelsius: 95},
+				},
+			}
+			reconciler.IPMIClient = mockIPMI
+
+			class := &baremetalcontrollerv1.ServerClass{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: className,
+				},
+				Spec: baremetalcontrollerv1.ServerClassSpec{
+					Thermal: &baremetalcontrollerv1.ThermalPolicy{
+						SustainedFor: &metav1.Duration{Duration: 0},
+						PowerOff:     true,
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, class)).To(Succeed())
+
+			server := createIPMIServer(serverName, baremetalcontrollerv1.PowerStateOn)
+			server.Spec.ServerClassName = className
+			Expect(k8sClient.Create(ctx, server)).To(Succeed())
+
+			var created baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &created)).To(Succeed())
+			created.Status.Status = baremetalcontrollerv1.StatusActive
+			Expect(k8sClient.Status().Update(ctx, &created)).To(Succeed())
+			mockPinger.Reachable = true
+		})
+
+		AfterEach(func() {
+			deleteServer(serverName)
+			class := &baremetalcontrollerv1.ServerClass{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{Name: className}, class); err == nil {
+				Expect(k8sClient.Delete(ctx, class)).To(Succeed())
+			}
+		})
+
+		It("should power off the server once a sensor stays critical", func() {
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockIPMI.PowerOffCalled).To(BeTrue())
+
+			var server baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+			Expect(server.Spec.PowerState).To(Equal(baremetalcontrollerv1.PowerStateOff))
+			Expect(server.Annotations).To(HaveKeyWithValue(baremetalcontrollerv1.ThermalShutdownAnnotation, "true"))
+			Expect(server.Status.Thermal).NotTo(BeNil())
+			Expect(server.Status.Thermal.Sensors).To(ContainElement(baremetalcontrollerv1.TemperatureSensor{
+				Name: "CPU Temp", Celsius: 101, CriticalCelsius: 95,
+			}))
+
+			critical := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionThermalCritical)
+			Expect(critical).NotTo(BeNil())
+			Expect(critical.Status).To(Equal(metav1.ConditionTrue))
+			Expect(critical.Reason).To(Equal("SustainedCritical"))
+			Expect(critical.Message).To(ContainSubstring("CPU Temp at 101°C"))
+		})
+
+		It("should leave protected servers on", func() {
+			var server baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+			server.Spec.Role = baremetalcontrollerv1.ServerRoleControlPlane
+			Expect(k8sClient.Update(ctx, &server)).To(Succeed())
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockIPMI.PowerOffCalled).To(BeFalse())
+
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+			Expect(server.Spec.PowerState).To(Equal(baremetalcontrollerv1.PowerStateOn))
+			Expect(server.Annotations).NotTo(HaveKey(baremetalcontrollerv1.ThermalShutdownAnnotation))
+			critical := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionThermalCritical)
+			Expect(critical).NotTo(BeNil())
+			Expect(critical.Status).To(Equal(metav1.ConditionTrue))
+		})
+
+		It("should only record readings while below critical", func() {
+			mockIPMI.Temperatures[1].Celsius = 70
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockIPMI.PowerOffCalled).To(BeFalse())
+
+			var server baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+			Expect(server.Spec.PowerState).To(Equal(baremetalcontrollerv1.PowerStateOn))
+			Expect(server.Status.Thermal.CriticalSince).To(BeNil())
+			critical := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionThermalCritical)
+			Expect(critical).NotTo(BeNil())
+			Expect(critical.Status).To(Equal(metav1.ConditionFalse))
+		})
+	})
+
+	Context("When a spot server is reclaimed", func() {
+		const serverName = "reclaim-test-server"
+
+		var mockIPMI *power.MockIPMIClient
+
+		BeforeEach(func() {
+			mockIPMI = &power.MockIPMIClient{}
+			reconciler.IPMIClient = mockIPMI
+
+			server := createIPMIServer(serverName, baremetalcontrollerv1.PowerStateOn)
+			server.Annotations = map[string]string{
+				baremetalcontrollerv1.ReclaimAnnotation: "reserved-server",
+			}
+			Expect(k8sClient.Create(ctx, server)).To(Succeed())
+
+			var created baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &created)).To(Succeed())
+			created.Status.Status = baremetalcontrollerv1.StatusActive
+			Expect(k8sClient.Status().Update(ctx, &created)).To(Succeed())
+			mockPinger.Reachable = true
+		})
+
+		AfterEach(func() {
+			deleteServer(serverName)
+		})
+
+		It("should power the server off and remove the annotation once it is off", func() {
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockIPMI.PowerOffCalled).To(BeTrue())
+
+			var server baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+			Expect(server.Spec.PowerState).To(Equal(baremetalcontrollerv1.PowerStateOff))
+			Expect(server.Annotations).To(HaveKeyWithValue(baremetalcontrollerv1.ReclaimAnnotation, "reserved-server"))
+
+			server.Status.Status = baremetalcontrollerv1.StatusOffline
+			Expect(k8sClient.Status().Update(ctx, &server)).To(Succeed())
+			mockPinger.Reachable = false
+
+			_, err = reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+			Expect(server.Annotations).NotTo(HaveKey(baremetalcontrollerv1.ReclaimAnnotation))
+		})
+	})
+
+	Context("When a server is powered off out of band", func() {
+		const serverName = "drift-test-server"
+
+		var mockIPMI *power.MockIPMIClient
+
+		BeforeEach(func() {
+			mockIPMI = &power.MockIPMIClient{}
+			reconciler.IPMIClient = mockIPMI
+		})
+
+		AfterEach(func() {
+			deleteServer(serverName)
+		})
+
+		createActiveServer := func(policy baremetalcontrollerv1.DriftPolicy) {
+			server := createIPMIServer(serverName, baremetalcontrollerv1.PowerStateOn)
+			server.Spec.DriftPolicy = policy
+			Expect(k8sClient.Create(ctx, server)).To(Succeed())
+
+			var created baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &created)).To(Succeed())
+			created.Status.Status = baremetalcontrollerv1.StatusActive
+			Expect(k8sClient.Status().Update(ctx, &created)).To(Succeed())
+			mockPinger.Reachable = false // Someone pressed the power button
+		}
+
+		reconcileServer := func() *baremetalcontrollerv1.Server {
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			var server baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+			return &server
+		}
+
+		It("should flag the drift and power the server back on by default", func() {
+			createActiveServer("")
+
+			server := reconcileServer()
+			Expect(mockIPMI.PowerOnCalled).To(BeTrue())
+			condition := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionPowerDrift)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal("Reconciling"))
+
+			server.Status.Status = baremetalcontrollerv1.StatusActive
+			Expect(k8sClient.Status().Update(ctx, server)).To(Succeed())
+			mockPinger.Reachable = true
+
+			server = reconcileServer()
+			Expect(meta.IsStatusConditionFalse(server.Status.Conditions, baremetalcontrollerv1.ConditionPowerDrift)).To(BeTrue())
+		})
+
+		It("should adopt the observed power state", func() {
+			createActiveServer(baremetalcontrollerv1.DriftPolicyAdopt)
+
+			server := reconcileServer()
+			Expect(mockIPMI.PowerOnCalled).To(BeFalse())
+			Expect(server.Spec.PowerState).To(Equal(baremetalcontrollerv1.PowerStateOff))
+			condition := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionPowerDrift)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal("Adopted"))
+		})
+
+		It("should only alert until the policy changes", func() {
+			createActiveServer(baremetalcontrollerv1.DriftPolicyAlert)
+
+			for i := 0; i < 2; i++ {
+				server := reconcileServer()
+				Expect(mockIPMI.PowerOnCalled).To(BeFalse())
+				Expect(server.Spec.PowerState).To(Equal(baremetalcontrollerv1.PowerStateOn))
+				Expect(meta.IsStatusConditionTrue(server.Status.Conditions, baremetalcontrollerv1.ConditionPowerDrift)).To(BeTrue())
+			}
+
+			var server baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+			server.Spec.DriftPolicy = baremetalcontrollerv1.DriftPolicyReconcile
+			Expect(k8sClient.Update(ctx, &server)).To(Succeed())
+
+			reconcileServer()
+			Expect(mockIPMI.PowerOnCalled).To(BeTrue())
+		})
+	})
+
+	Context("When a server is deleted with node cleanup", func() {
+		const serverName = "node-cleanup-test-server"
+
+		BeforeEach(func() {
+			reconciler.IPMIClient = &power.MockIPMIClient{}
+			reconciler.NodeCleanup = true
+
+			Expect(k8sClient.Create(ctx, createIPMIServer(serverName, baremetalcontrollerv1.PowerStateOn))).To(Succeed())
+			Expect(k8sClient.Create(ctx, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: serverName}})).To(Succeed())
+			mockPinger.Reachable = true
+		})
+
+		AfterEach(func() {
+			node := &corev1.Node{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, node); err == nil {
+				Expect(k8sClient.Delete(ctx, node)).To(Succeed())
+			}
+		})
+
+		It("should label the node and delete it with the server", func() {
+			for i := 0; i < 2; i++ {
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			var server baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+			Expect(server.Finalizers).To(ContainElement(baremetalcontrollerv1.NodeCleanupFinalizer))
+			var node corev1.Node
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &node)).To(Succeed())
+			Expect(node.Labels).To(HaveKeyWithValue(baremetalcontrollerv1.ServerLabel, serverName))
+
+			Expect(k8sClient.Delete(ctx, &server)).To(Succeed())
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &node)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			err = k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Context("When attesting a server before marking it active", func() {
+		const serverName = "attestation-test-server"
+		secretName := "ssh-secret-" + serverName
+
+		var (
+			mockAttestor *power.MockAttestor
+			akKey        *ecdsa.PrivateKey
+			bootPCR      []byte
+		)
+
+		BeforeEach(func() {
+			var err error
+			akKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			akDER, err := x509.MarshalPKIXPublicKey(&akKey.PublicKey)
+			Expect(err).NotTo(HaveOccurred())
+
+			bootPCR = bytes.Repeat([]byte{0xab}, sha256.Size)
+			mockAttestor = &power.MockAttestor{}
+			reconciler.Attestor = mockAttestor
+
+			Expect(k8sClient.Create(ctx, createSSHSecret(secretName, testNamespace))).To(Succeed())
+
+			server := createWolServer(serverName, baremetalcontrollerv1.PowerStateOn)
+			server.Spec.Attestation = &baremetalcontrollerv1.AttestationSpec{
+				User: "admin",
+				SSHSecretRef: &baremetalcontrollerv1.SecretReference{
+					Name:      secretName,
+					Namespace: testNamespace,
+				},
+				AKPublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: akDER})),
+				PCRs:        map[string]string{"7": hex.EncodeToString(bootPCR)},
+			}
+			Expect(k8sClient.Create(ctx, server)).To(Succeed())
+
+			var created baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &created)).To(Succeed())
+			created.Status.Status = baremetalcontrollerv1.StatusPending
+			Expect(k8sClient.Status().Update(ctx, &created)).To(Succeed())
+
+			mockPinger.Reachable = true
+		})
+
+		AfterEach(func() {
+			deleteServer(serverName)
+			deleteSecret(secretName, testNamespace)
+		})
+
+		It("should mark the server active when the quote matches", func() {
+			mockAttestor.QuoteFunc = func(pcrs []int, nonce []byte) (power.TPMQuote, error) {
+				return signQuote(akKey, nonce, pcrs, bootPCR), nil
+			}
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockAttestor.LastPCRs).To(Equal([]int{7}))
+			Expect(mockAttestor.LastAKHandle).To(Equal("0x81010002"))
+
+			var server baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+			Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusActive))
+			Expect(server.Status.Attestation.Phase).To(Equal(baremetalcontrollerv1.AttestationPhaseVerified))
+		})
+
+		It("should fail and power off the server when PCR values differ", func() {
+			mockAttestor.QuoteFunc = func(pcrs []int, nonce []byte) (power.TPMQuote, error) {
+				return signQuote(akKey, nonce, pcrs, bytes.Repeat([]byte{0xcd}, sha256.Size)), nil
+			}
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockSSH.ShutdownCalled).To(BeTrue())
+
+			var server baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+			Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusFailed))
+			Expect(server.Status.Attestation.Phase).To(Equal(baremetalcontrollerv1.AttestationPhaseFailed))
+			Expect(server.Status.Reason).To(Equal(baremetalcontrollerv1.ReasonAttestationFailed))
+		})
+
+		It("should stay pending when the quote can't be fetched yet", func() {
+			mockAttestor.ReturnError = errors.NewServiceUnavailable("ssh not ready")
+
+			result, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+
+			var server baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+			Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusPending))
+			Expect(server.Status.FailureCount).To(Equal(1))
+		})
+	})
+
+	Context("When handling pending states", func() {
+		const serverName = "pending-test-server"
+		secretName := "ssh-secret-" + serverName
+
+		AfterEach(func() {
+			deleteServer(serverName)
+			deleteSecret(secretName, testNamespace)
+		})
+
+		It("should requeue when status is booting", func() {
+			secret := createSSHSecret(secretName, testNamespace)
+			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+
+			server := createWolServer(serverName, baremetalcontrollerv1.PowerStateOn)
+			Expect(k8sClient.Create(ctx, server)).To(Succeed())
+
+			var created baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &created)).To(Succeed())
+			created.Status.Status = baremetalcontrollerv1.StatusPending
+			Expect(k8sClient.Status().Update(ctx, &created)).To(Succeed())
+
+			mockPinger.Reachable = false // Not yet reachable, should requeue
+
+			result, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+		})
+
+		It("should requeue when status is draining", func() {
+			secret := createSSHSecret(secretName, testNamespace)
+			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+
+			server := createWolServer(serverName, baremetalcontrollerv1.PowerStateOff)
+			Expect(k8sClient.Create(ctx, server)).To(Succeed())
+
+			var created baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &created)).To(Succeed())
 			created.Status.Status = baremetalcontrollerv1.StatusDraining
 			Expect(k8sClient.Status().Update(ctx, &created)).To(Succeed())
 
@@ -495,6 +2249,50 @@ var _ = Describe("Server Controller", func() {
 			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
 		})
 
+		It("should requeue at the server's reconcile interval", func() {
+			secret := createSSHSecret(secretName, testNamespace)
+			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+
+			server := createWolServer(serverName, baremetalcontrollerv1.PowerStateOn)
+			server.Spec.ReconcileInterval = &metav1.Duration{Duration: 10 * time.Minute}
+			Expect(k8sClient.Create(ctx, server)).To(Succeed())
+
+			mockPinger.Reachable = false
+
+			result, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(10 * time.Minute))
+
+			// Settled servers are rechecked at the same interval
+			mockPinger.Reachable = true
+			for i := 0; i < 2; i++ {
+				result, err = reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(result.RequeueAfter).To(Equal(10 * time.Minute))
+		})
+
+		It("should recheck settled servers at the default interval", func() {
+			secret := createSSHSecret(secretName, testNamespace)
+			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+			Expect(k8sClient.Create(ctx, createWolServer(serverName, baremetalcontrollerv1.PowerStateOn))).To(Succeed())
+
+			mockPinger.Reachable = true
+			var result reconcile.Result
+			var err error
+			for i := 0; i < 2; i++ {
+				result, err = reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(result.RequeueAfter).To(Equal(defaultRequeueInterval))
+		})
+
 		It("should transition from booting to active when reachable", func() {
 			secret := createSSHSecret(secretName, testNamespace)
 			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
@@ -518,6 +2316,76 @@ var _ = Describe("Server Controller", func() {
 			var updated baremetalcontrollerv1.Server
 			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &updated)).To(Succeed())
 			Expect(updated.Status.Status).To(Equal(baremetalcontrollerv1.StatusActive))
+			Expect(updated.Status.Address).To(Equal("192.168.1.100"))
+		})
+
+		It("should record LLDP neighbors when the server boots", func() {
+			secret := createSSHSecret(secretName, testNamespace)
+			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+
+			server := createWolServer(serverName, baremetalcontrollerv1.PowerStateOn)
+			Expect(k8sClient.Create(ctx, server)).To(Succeed())
+
+			var created baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &created)).To(Succeed())
+			created.Status.Status = baremetalcontrollerv1.StatusPending
+			Expect(k8sClient.Status().Update(ctx, &created)).To(Succeed())
+
+			mockPinger.Reachable = true
+			mockSSH.LLDPNeighbors = []power.LLDPNeighbor{
+				{Interface: "eno1", SystemName: "leaf-01", PortID: "Ethernet12"},
+			}
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			var updated baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &updated)).To(Succeed())
+			Expect(updated.Status.LLDP).NotTo(BeNil())
+			Expect(updated.Status.LLDP.Neighbors).To(HaveLen(1))
+			Expect(updated.Status.LLDP.Neighbors[0].SystemName).To(Equal("leaf-01"))
+			Expect(updated.Status.LLDP.Neighbors[0].PortID).To(Equal("Ethernet12"))
+		})
+
+		It("should record the host inventory when the server boots", func() {
+			secret := createSSHSecret(secretName, testNamespace)
+			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+
+			server := createWolServer(serverName, baremetalcontrollerv1.PowerStateOn)
+			Expect(k8sClient.Create(ctx, server)).To(Succeed())
+
+			var created baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &created)).To(Succeed())
+			created.Status.Status = baremetalcontrollerv1.StatusPending
+			Expect(k8sClient.Status().Update(ctx, &created)).To(Succeed())
+
+			mockPinger.Reachable = true
+			mockSSH.HostInventory = power.HostInventory{
+				CPUModel:    "AMD EPYC 7543 32-Core Processor",
+				CPUCores:    32,
+				CPUThreads:  64,
+				MemoryBytes: 256 << 30,
+				Disks:       []power.Disk{{Name: "nvme0n1", SizeBytes: 960197124096}},
+				NICs:        []power.NIC{{Name: "eno1", MACAddress: "3c:ec:ef:01:02:03"}},
+				GPUs:        2,
+			}
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: serverName},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			var updated baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &updated)).To(Succeed())
+			Expect(updated.Status.Inventory).NotTo(BeNil())
+			Expect(updated.Status.Inventory.Source).To(Equal(baremetalcontrollerv1.InventorySourceSSH))
+			Expect(updated.Status.Inventory.CPUThreads).To(Equal(int32(64)))
+			Expect(updated.Status.Inventory.Memory.String()).To(Equal("256Gi"))
+			Expect(updated.Status.Inventory.Disks).To(HaveLen(1))
+			Expect(updated.Status.Inventory.NICs[0].MACAddress).To(Equal("3c:ec:ef:01:02:03"))
+			Expect(updated.Status.Inventory.GPUs).To(Equal(int32(2)))
 		})
 
 		It("should transition from draining to offline when unreachable", func() {
@@ -598,4 +2466,326 @@ var _ = Describe("Server Controller", func() {
 			Expect(err).To(HaveOccurred())
 		})
 	})
+
+	Context("When probing reachability in the background", func() {
+		const serverName = "probe-test-server"
+		secretName := "ssh-secret-" + serverName
+
+		BeforeEach(func() {
+			reconciler.probes = newReachabilityProbes(func(power.HealthCheck) power.Prober { return mockPinger }, nil)
+
+			Expect(k8sClient.Create(ctx, createSSHSecret(secretName, testNamespace))).To(Succeed())
+			Expect(k8sClient.Create(ctx, createWolServer(serverName, baremetalcontrollerv1.PowerStateOn))).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteServer(serverName)
+			deleteSecret(secretName, testNamespace)
+		})
+
+		It("should wait for the probe instead of pinging inline", func() {
+			mockPinger.Reachable = false
+			request := reconcile.Request{NamespacedName: types.NamespacedName{Name: serverName}}
+
+			result, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(reachabilityRetryInterval))
+			Expect(mockWol.WakeCalled).To(BeFalse())
+
+			Eventually(reconciler.probes.events, timeout, interval).Should(Receive())
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockWol.WakeCalled).To(BeTrue())
+		})
+
+		It("should count a failed probe only once across reconciles", func() {
+			mockPinger.Reachable = false
+			request := reconcile.Request{NamespacedName: types.NamespacedName{Name: serverName}}
+			getServer := func() baremetalcontrollerv1.Server {
+				var server baremetalcontrollerv1.Server
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				return server
+			}
+
+			By("waking the server on the first probe")
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(reconciler.probes.events, timeout, interval).Should(Receive())
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockWol.WakeCalled).To(BeTrue())
+			Expect(getServer().Status.Status).To(Equal(baremetalcontrollerv1.StatusPending))
+
+			By("waiting for a probe taken after the power action")
+			result, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(reachabilityRetryInterval))
+			Expect(getServer().Status.FailureCount).To(BeZero())
+			Eventually(reconciler.probes.events, timeout, interval).Should(Receive())
+
+			By("reconciling several times with the same failed probe")
+			for i := 0; i < 4; i++ {
+				_, err = reconciler.Reconcile(ctx, request)
+				Expect(err).NotTo(HaveOccurred())
+			}
+			server := getServer()
+			Expect(server.Status.FailureCount).To(Equal(1))
+			Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusPending))
+		})
+	})
+
+	Context("When power actions run on workers", func() {
+		const serverName = "worker-test-server"
+		secretName := "ssh-secret-" + serverName
+
+		var cancel context.CancelFunc
+
+		BeforeEach(func() {
+			var workerCtx context.Context
+			workerCtx, cancel = context.WithCancel(ctx)
+			reconciler.operations = newPowerOperations(1, 0)
+			go func() { _ = reconciler.operations.Start(workerCtx) }()
+
+			Expect(k8sClient.Create(ctx, createSSHSecret(secretName, testNamespace))).To(Succeed())
+			Expect(k8sClient.Create(ctx, createWolServer(serverName, baremetalcontrollerv1.PowerStateOn))).To(Succeed())
+		})
+
+		AfterEach(func() {
+			cancel()
+			deleteServer(serverName)
+			deleteSecret(secretName, testNamespace)
+		})
+
+		It("should track the action in a condition until it finishes", func() {
+			mockPinger.Reachable = false
+			request := reconcile.Request{NamespacedName: types.NamespacedName{Name: serverName}}
+
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			var server baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(server.Status.Conditions, baremetalcontrollerv1.ConditionOperationInProgress)).To(BeTrue())
+			Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusOffline))
+
+			Eventually(reconciler.operations.events, timeout, interval).Should(Receive())
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(mockWol.WakeCalled).To(BeTrue())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+			Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusPending))
+			condition := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionOperationInProgress)
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("Succeeded"))
+		})
+
+		It("should bound the actions in flight per tenant", func() {
+			operations := newPowerOperations(3, 1)
+			run := func(context.Context, *baremetalcontrollerv1.Server, baremetalcontrollerv1.PowerState) error {
+				return nil
+			}
+			tenantServer := func(name string, tenant string) *baremetalcontrollerv1.Server {
+				server := createWolServer(name, baremetalcontrollerv1.PowerStateOn)
+				if tenant != "" {
+					server.Labels = map[string]string{baremetalcontrollerv1.TenantLabel: tenant}
+				}
+				return server
+			}
+
+			Expect(operations.submit(tenantServer("tenant-a-1", "tenant-a"), baremetalcontrollerv1.PowerStateOn, run)).To(Equal(submitted))
+			Expect(operations.submit(tenantServer("tenant-a-1", "tenant-a"), baremetalcontrollerv1.PowerStateOn, run)).To(Equal(submitted))
+			Expect(operations.submit(tenantServer("tenant-a-2", "tenant-a"), baremetalcontrollerv1.PowerStateOn, run)).To(Equal(tenantQuotaExceeded))
+			Expect(operations.submit(tenantServer("tenant-b-1", "tenant-b"), baremetalcontrollerv1.PowerStateOn, run)).To(Equal(submitted))
+			Expect(operations.submit(tenantServer("untenanted", ""), baremetalcontrollerv1.PowerStateOn, run)).To(Equal(submitted))
+		})
+
+		It("should report a tenant out of workers in the condition", func() {
+			mockPinger.Reachable = false
+			// The workers aren't started, so the queued action stays in flight
+			reconciler.operations = newPowerOperations(2, 1)
+			busy := createWolServer("tenant-busy", baremetalcontrollerv1.PowerStateOn)
+			busy.Labels = map[string]string{baremetalcontrollerv1.TenantLabel: testNamespace}
+			Expect(reconciler.operations.submit(busy, baremetalcontrollerv1.PowerStateOn, reconciler.performPowerAction)).To(Equal(submitted))
+
+			var server baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+			server.Labels = map[string]string{baremetalcontrollerv1.TenantLabel: testNamespace}
+			Expect(k8sClient.Update(ctx, &server)).To(Succeed())
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: serverName}})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+			condition := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionOperationInProgress)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("TenantQuotaExceeded"))
+			Expect(mockWol.WakeCalled).To(BeFalse())
+		})
+	})
+
+	Context("When a server belongs to a tenant", func() {
+		const serverName = "tenant-test-server"
+		secretName := "ssh-secret-" + serverName
+
+		BeforeEach(func() {
+			Expect(k8sClient.Create(ctx, createSSHSecret(secretName, testNamespace))).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteServer(serverName)
+			deleteSecret(secretName, testNamespace)
+		})
+
+		It("should refuse Secrets outside the tenant's namespace", func() {
+			server := createWolServer(serverName, baremetalcontrollerv1.PowerStateOff)
+			server.Labels = map[string]string{baremetalcontrollerv1.TenantLabel: "tenant-a"}
+			Expect(k8sClient.Create(ctx, server)).To(Succeed())
+			mockPinger.Reachable = true
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: serverName}})
+			Expect(err).To(HaveOccurred())
+			Expect(mockSSH.ShutdownCalled).To(BeFalse())
+
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, server)).To(Succeed())
+			Expect(server.Status.Status).To(Equal(baremetalcontrollerv1.StatusFailed))
+			Expect(server.Status.Reason).To(Equal(baremetalcontrollerv1.ReasonSpecInvalid))
+			Expect(server.Status.Message).To(ContainSubstring("outside namespace tenant-a"))
+		})
+
+		It("should use Secrets in the tenant's namespace", func() {
+			server := createWolServer(serverName, baremetalcontrollerv1.PowerStateOff)
+			server.Labels = map[string]string{baremetalcontrollerv1.TenantLabel: testNamespace}
+			Expect(k8sClient.Create(ctx, server)).To(Succeed())
+			mockPinger.Reachable = true
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: serverName}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockSSH.ShutdownCalled).To(BeTrue())
+		})
+	})
+
+	Context("When simulating a server", func() {
+		const serverName = "simulated-server"
+		secretName := "ssh-secret-" + serverName
+
+		var recorder *record.FakeRecorder
+
+		BeforeEach(func() {
+			recorder = record.NewFakeRecorder(10)
+			reconciler.Recorder = recorder
+
+			Expect(k8sClient.Create(ctx, createSSHSecret(secretName, testNamespace))).To(Succeed())
+			server := createWolServer(serverName, baremetalcontrollerv1.PowerStateOn)
+			server.Annotations = map[string]string{baremetalcontrollerv1.SimulateAnnotation: "true"}
+			Expect(k8sClient.Create(ctx, server)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteServer(serverName)
+			deleteSecret(secretName, testNamespace)
+		})
+
+		It("should power the server on and off without touching the real backends", func() {
+			reconcileServer := func() {
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			getStatus := func() baremetalcontrollerv1.CurrentStatus {
+				var server baremetalcontrollerv1.Server
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				return server.Status.Status
+			}
+
+			reconcileServer()
+			Expect(getStatus()).To(Equal(baremetalcontrollerv1.StatusPending))
+			Expect(recorder.Events).To(Receive(ContainSubstring("Would send Wake-on-LAN to 00:11:22:33:44:55")))
+
+			reconcileServer()
+			Expect(getStatus()).To(Equal(baremetalcontrollerv1.StatusActive))
+
+			var server baremetalcontrollerv1.Server
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+			server.Spec.PowerState = baremetalcontrollerv1.PowerStateOff
+			Expect(k8sClient.Update(ctx, &server)).To(Succeed())
+
+			reconcileServer()
+			Expect(recorder.Events).To(Receive(ContainSubstring("Would run shutdown on admin@192.168.1.100")))
+			reconcileServer()
+			Expect(getStatus()).To(Equal(baremetalcontrollerv1.StatusOffline))
+
+			Expect(mockWol.WakeCalled).To(BeFalse())
+			Expect(mockSSH.ShutdownCalled).To(BeFalse())
+		})
+
+		It("should inject faults from the simulation profile", func() {
+			reconcileServer := func() error {
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: serverName},
+				})
+				return err
+			}
+			getServer := func() baremetalcontrollerv1.Server {
+				var server baremetalcontrollerv1.Server
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: serverName}, &server)).To(Succeed())
+				return server
+			}
+
+			Expect(reconcileServer()).To(Succeed())
+			Expect(reconcileServer()).To(Succeed())
+			Expect(getServer().Status.Status).To(Equal(baremetalcontrollerv1.StatusActive))
+
+			By("dropping reachability checks of a running server")
+			reconciler.Simulation = SimulationProfile{FlapRate: 1}
+			Expect(reconcileServer()).To(Succeed())
+			Expect(getServer().Status.Status).To(Equal(baremetalcontrollerv1.StatusPending))
+
+			By("rejecting power commands")
+			reconciler.Simulation = SimulationProfile{AuthFailureRate: 1}
+			Expect(reconcileServer()).To(Succeed())
+			Expect(getServer().Status.Status).To(Equal(baremetalcontrollerv1.StatusActive))
+
+			server := getServer()
+			server.Spec.PowerState = baremetalcontrollerv1.PowerStateOff
+			Expect(k8sClient.Update(ctx, &server)).To(Succeed())
+			Expect(reconcileServer()).To(MatchError(ContainSubstring("simulated BMC rejected the credentials")))
+			Expect(getServer().Status.Status).To(Equal(baremetalcontrollerv1.StatusFailed))
+			Expect(getServer().Status.Reason).To(Equal(baremetalcontrollerv1.ReasonSSHAuthFailed))
+		})
+	})
 })
+
+// signQuote builds a TPMS_ATTEST quote over a single SHA-256 PCR bank
+// selection and signs it the way tpm2_quote does.
+func signQuote(key *ecdsa.PrivateKey, nonce []byte, pcrs []int, value []byte) power.TPMQuote {
+	var msg bytes.Buffer
+	write := func(v interface{}) { _ = binary.Write(&msg, binary.BigEndian, v) }
+
+	write(uint32(0xff544347)) // TPM_GENERATED_VALUE
+	write(uint16(0x8018))     // TPM_ST_ATTEST_QUOTE
+	write(uint16(0))          // qualifiedSigner
+	write(uint16(len(nonce)))
+	msg.Write(nonce)
+	msg.Write(make([]byte, 17+8)) // clockInfo and firmwareVersion
+
+	bitmap := make([]byte, 3)
+	digest := sha256.New()
+	for _, pcr := range pcrs {
+		bitmap[pcr/8] |= 1 << (pcr % 8)
+		digest.Write(value)
+	}
+	write(uint32(1))
+	write(uint16(0x000b)) // TPM_ALG_SHA256
+	write(uint8(len(bitmap)))
+	msg.Write(bitmap)
+	pcrDigest := digest.Sum(nil)
+	write(uint16(len(pcrDigest)))
+	msg.Write(pcrDigest)
+
+	hash := sha256.Sum256(msg.Bytes())
+	signature, _ := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	return power.TPMQuote{Message: msg.Bytes(), Signature: signature}
+}
@@ -0,0 +1,573 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/power"
+)
+
+// isSimulated reports whether the server should be driven by the in-memory
+// backend
+func isSimulated(server *baremetalcontrollerv1.Server) bool {
+	return server.Annotations[baremetalcontrollerv1.SimulateAnnotation] == "true"
+}
+
+// SimulationProfile shapes how simulated servers behave, e.g. to load test
+// the controller with realistic BMC latencies, or to exercise its failure
+// handling with injected faults. The zero value answers instantly and never
+// fails.
+type SimulationProfile struct {
+	// CommandLatency delays every simulated power command
+	CommandLatency time.Duration
+	// BootLatency is how long a server takes to become reachable after
+	// powering on
+	BootLatency time.Duration
+	// ShutdownLatency is how long a server stays reachable after powering
+	// off
+	ShutdownLatency time.Duration
+	// FailureRate is the probability, from 0 to 1, that a power command
+	// fails with a temporary error
+	FailureRate float64
+	// AuthFailureRate is the probability, from 0 to 1, that a power command
+	// is rejected as if the BMC credentials were wrong
+	AuthFailureRate float64
+	// BootJitter adds a random delay of up to this long to each boot, so
+	// some servers come up late or miss the boot timeout
+	BootJitter time.Duration
+	// FlapRate is the probability, from 0 to 1, that a running server fails
+	// a reachability check
+	FlapRate float64
+}
+
+// simulationStore keeps the state of simulated machines across reconciles
+type simulationStore struct {
+	mu       sync.Mutex
+	machines map[client.ObjectKey]*simulatedMachine
+}
+
+func (s *simulationStore) machine(server *baremetalcontrollerv1.Server) *simulatedMachine {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.machines == nil {
+		s.machines = map[client.ObjectKey]*simulatedMachine{}
+	}
+	key := client.ObjectKeyFromObject(server)
+	m, ok := s.machines[key]
+	if !ok {
+		// Start from whatever the status claims, so adding the annotation
+		// to an existing server doesn't trigger power actions
+		m = &simulatedMachine{on: server.Status.Status == baremetalcontrollerv1.StatusActive}
+		s.machines[key] = m
+	}
+	return m
+}
+
+func (s *simulationStore) forget(key client.ObjectKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.machines, key)
+}
+
+// simulator returns a reconciler for a single simulated server. It shares the
+// client and recorder with r, but all backends act on the in-memory machine.
+func (r *ServerReconciler) simulator(server *baremetalcontrollerv1.Server) *ServerReconciler {
+	m := r.simulations.machine(server)
+	m.mu.Lock()
+	m.recorder = r.Recorder
+	m.server = server.DeepCopy()
+	m.profile = r.Simulation
+	m.mu.Unlock()
+
+	return &ServerReconciler{
+		Client:           r.Client,
+		Scheme:           r.Scheme,
+		Recorder:         r.Recorder,
+		APIReader:        r.APIReader,
+		WolSender:        &simulatedWol{m},
+		SSHClient:        &simulatedSSH{m},
+		IPMIClient:       &simulatedIPMI{m},
+		MAASClient:       &simulatedMAAS{m},
+		RedfishClient:    &simulatedRedfish{m},
+		EquinixClient:    &simulatedEquinix{m},
+		HetznerClient:    &simulatedHetzner{m},
+		HypervisorClient: &simulatedHypervisor{m},
+		Attestor:         &simulatedAttestor{m},
+		Pinger:           &simulatedPinger{m},
+		WolRelay:         &simulatedRelay{m},
+		operations:       r.operations,
+		simulating:       true,
+	}
+}
+
+// simulatedMachine is the in-memory state of a simulated server
+type simulatedMachine struct {
+	mu        sync.Mutex
+	on        bool
+	changedAt time.Time
+	bootDelay time.Duration
+	volumes   []power.Volume
+	// powerLimit is the power cap set through the simulated BMC
+	powerLimit int32
+	// bootOverride is the boot device set through the simulated BMC
+	bootOverride power.BootOverride
+
+	recorder record.EventRecorder
+	server   *baremetalcontrollerv1.Server
+	profile  SimulationProfile
+}
+
+// record emits the action that would have been taken as an event
+func (m *simulatedMachine) record(format string, args ...interface{}) {
+	m.mu.Lock()
+	recorder, server := m.recorder, m.server
+	m.mu.Unlock()
+	if recorder != nil {
+		recorder.Eventf(server, corev1.EventTypeNormal, "Simulated", format, args...)
+	}
+}
+
+func (m *simulatedMachine) setPower(on bool, format string, args ...interface{}) error {
+	m.mu.Lock()
+	profile := m.profile
+	m.mu.Unlock()
+
+	time.Sleep(profile.CommandLatency)
+	if chance(profile.AuthFailureRate) {
+		return fmt.Errorf("simulated BMC rejected the credentials: %w", power.ErrAuth)
+	}
+	if chance(profile.FailureRate) {
+		return fmt.Errorf("simulated power command failure: %w", power.ErrTransient)
+	}
+
+	m.mu.Lock()
+	if m.on != on {
+		m.on = on
+		m.start(profile)
+	}
+	m.mu.Unlock()
+	m.record(format, args...)
+	return nil
+}
+
+// reboot boots a running machine again, which is unreachable until it is
+// back
+func (m *simulatedMachine) reboot(format string, args ...interface{}) error {
+	m.mu.Lock()
+	profile := m.profile
+	m.mu.Unlock()
+
+	time.Sleep(profile.CommandLatency)
+	if chance(profile.FailureRate) {
+		return fmt.Errorf("simulated power command failure: %w", power.ErrTransient)
+	}
+
+	m.mu.Lock()
+	if !m.on {
+		m.mu.Unlock()
+		return fmt.Errorf("simulated machine is off: %w", power.ErrPermanent)
+	}
+	m.start(profile)
+	m.mu.Unlock()
+	m.record(format, args...)
+	return nil
+}
+
+// start records a power change of the machine, m.mu must be held
+func (m *simulatedMachine) start(profile SimulationProfile) {
+	m.changedAt = time.Now()
+	m.bootDelay = profile.BootLatency
+	if m.on && profile.BootJitter > 0 {
+		m.bootDelay += time.Duration(rand.Int63n(int64(profile.BootJitter)))
+	}
+}
+
+// isReachable reports whether the machine answers pings, which lags the
+// power state by the boot and shutdown latencies. A running machine
+// randomly drops a check at the profile's flap rate.
+func (m *simulatedMachine) isReachable() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	elapsed := time.Since(m.changedAt)
+	if m.on {
+		return elapsed >= m.bootDelay && !chance(m.profile.FlapRate)
+	}
+	return elapsed < m.profile.ShutdownLatency
+}
+
+// chance returns true with the given probability
+func chance(probability float64) bool {
+	return probability > 0 && rand.Float64() < probability
+}
+
+func (m *simulatedMachine) isOn() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.on
+}
+
+var simulatedInventory = power.Inventory{
+	Manufacturer: "Simulated",
+	Model:        "Simulated",
+	SerialNumber: "SIMULATED",
+}
+
+var simulatedHostInventory = power.HostInventory{
+	CPUModel: "Simulated",
+}
+
+// simulatedDrawWatts is the power draw of a running simulated server
+// without a power cap
+const simulatedDrawWatts = 250
+
+// getPowerLimit reports the power cap and a draw held to it while the
+// machine is on
+func (m *simulatedMachine) getPowerLimit() power.PowerLimit {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := power.PowerLimit{LimitWatts: m.powerLimit}
+	if m.on {
+		result.ConsumedWatts = simulatedDrawWatts
+		if m.powerLimit > 0 && m.powerLimit < simulatedDrawWatts {
+			result.ConsumedWatts = m.powerLimit
+		}
+	}
+	return result
+}
+
+func (m *simulatedMachine) setPowerLimit(watts int32, format string, args ...interface{}) {
+	m.mu.Lock()
+	m.powerLimit = watts
+	m.mu.Unlock()
+	m.record(format, args...)
+}
+
+func (m *simulatedMachine) getBootOverride() power.BootOverride {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.bootOverride
+}
+
+func (m *simulatedMachine) setBootOverride(override power.BootOverride, format string, args ...interface{}) {
+	m.mu.Lock()
+	m.bootOverride = override
+	m.mu.Unlock()
+	m.record(format, args...)
+}
+
+// simulatedTemperatures are the sensors of a running simulated server
+var simulatedTemperatures = []power.Temperature{
+	{Name: "Inlet Temp", Celsius: 24, CriticalCelsius: 47},
+	{Name: "CPU Temp", Celsius: 55, CriticalCelsius: 95},
+}
+
+type simulatedWol struct{ m *simulatedMachine }
+
+func (s *simulatedWol) Wake(ctx context.Context, macAddress string, port int, broadcastAddress string) error {
+	return s.m.setPower(true, "Would send Wake-on-LAN to %s via %s:%d", macAddress, broadcastAddress, port)
+}
+
+type simulatedSSH struct{ m *simulatedMachine }
+
+func (s *simulatedSSH) Shutdown(ctx context.Context, host string, user string, key string) error {
+	return s.m.setPower(false, "Would run shutdown on %s@%s over SSH", user, host)
+}
+
+func (s *simulatedSSH) Reboot(ctx context.Context, host string, user string, key string) error {
+	return s.m.reboot("Would run reboot on %s@%s over SSH", user, host)
+}
+
+func (s *simulatedSSH) GetLLDPNeighbors(ctx context.Context, host string, user string, key string) ([]power.LLDPNeighbor, error) {
+	return nil, nil
+}
+
+func (s *simulatedSSH) GetHostInventory(ctx context.Context, host string, user string, key string) (power.HostInventory, error) {
+	return simulatedHostInventory, nil
+}
+
+type simulatedIPMI struct{ m *simulatedMachine }
+
+func (s *simulatedIPMI) PowerOn(ctx context.Context, address string, username string, password string) error {
+	return s.m.setPower(true, "Would send IPMI power on to %s", address)
+}
+
+func (s *simulatedIPMI) PowerOff(ctx context.Context, address string, username string, password string) error {
+	return s.m.setPower(false, "Would send IPMI soft power off to %s", address)
+}
+
+func (s *simulatedIPMI) PowerCycle(ctx context.Context, address string, username string, password string) error {
+	return s.m.reboot("Would send IPMI power cycle to %s", address)
+}
+
+func (s *simulatedIPMI) GetPowerStatus(ctx context.Context, address string, username string, password string) (bool, error) {
+	return s.m.isOn(), nil
+}
+
+func (s *simulatedIPMI) GetInventory(ctx context.Context, address string, username string, password string) (power.Inventory, error) {
+	return simulatedInventory, nil
+}
+
+func (s *simulatedIPMI) SetBootDevice(ctx context.Context, address string, username string, password string, device string, persistent bool) error {
+	s.m.setBootOverride(power.BootOverride{Device: device, Persistent: persistent},
+		"Would set IPMI boot device of %s to %s (persistent: %t)", address, device, persistent)
+	return nil
+}
+
+func (s *simulatedIPMI) GetBootDevice(ctx context.Context, address string, username string, password string) (power.BootOverride, error) {
+	return s.m.getBootOverride(), nil
+}
+
+func (s *simulatedIPMI) GetPowerLimit(ctx context.Context, address string, username string, password string) (power.PowerLimit, error) {
+	return s.m.getPowerLimit(), nil
+}
+
+func (s *simulatedIPMI) SetPowerLimit(ctx context.Context, address string, username string, password string, watts int32) error {
+	s.m.setPowerLimit(watts, "Would set DCMI power limit of %s to %dW", address, watts)
+	return nil
+}
+
+func (s *simulatedIPMI) GetTemperatures(ctx context.Context, address string, username string, password string) ([]power.Temperature, error) {
+	return simulatedTemperatures, nil
+}
+
+func (s *simulatedIPMI) ColdReset(ctx context.Context, address string, username string, password string) error {
+	s.m.record("Would cold reset the BMC at %s", address)
+	return nil
+}
+
+func (s *simulatedIPMI) SetWatchdog(ctx context.Context, address string, username string, password string, timeout time.Duration, action string) error {
+	if timeout == 0 {
+		s.m.record("Would disarm the IPMI watchdog of %s", address)
+	} else {
+		s.m.record("Would arm the IPMI watchdog of %s for %s with %s", address, timeout, action)
+	}
+	return nil
+}
+
+type simulatedMAAS struct{ m *simulatedMachine }
+
+func (s *simulatedMAAS) PowerOn(endpoint string, apiKey string, systemID string) error {
+	return s.m.setPower(true, "Would power on MAAS machine %s", systemID)
+}
+
+func (s *simulatedMAAS) PowerOff(endpoint string, apiKey string, systemID string) error {
+	return s.m.setPower(false, "Would power off MAAS machine %s", systemID)
+}
+
+func (s *simulatedMAAS) GetPowerStatus(endpoint string, apiKey string, systemID string) (bool, error) {
+	return s.m.isOn(), nil
+}
+
+func (s *simulatedMAAS) Deploy(endpoint string, apiKey string, systemID string, distroSeries string) error {
+	return s.m.setPower(true, "Would deploy MAAS machine %s with %q", systemID, distroSeries)
+}
+
+func (s *simulatedMAAS) Release(endpoint string, apiKey string, systemID string) error {
+	return s.m.setPower(false, "Would release MAAS machine %s", systemID)
+}
+
+type simulatedEquinix struct{ m *simulatedMachine }
+
+func (s *simulatedEquinix) PowerOn(token string, deviceID string) error {
+	return s.m.setPower(true, "Would power on Equinix Metal device %s", deviceID)
+}
+
+func (s *simulatedEquinix) PowerOff(token string, deviceID string) error {
+	return s.m.setPower(false, "Would power off Equinix Metal device %s", deviceID)
+}
+
+func (s *simulatedEquinix) GetPowerStatus(token string, deviceID string) (bool, error) {
+	return s.m.isOn(), nil
+}
+
+func (s *simulatedEquinix) FindDevice(token string, projectID string, hostname string) (string, error) {
+	return hostname, nil
+}
+
+type simulatedHetzner struct{ m *simulatedMachine }
+
+func (s *simulatedHetzner) Wake(username string, password string, serverNumber int32) error {
+	return s.m.setPower(true, "Would wake Hetzner server %d through Robot", serverNumber)
+}
+
+func (s *simulatedHetzner) Reset(username string, password string, serverNumber int32, resetType string) error {
+	return s.m.setPower(false, "Would send a %s reset to Hetzner server %d", resetType, serverNumber)
+}
+
+func (s *simulatedHetzner) EnableRescue(username string, password string, serverNumber int32, os string, authorizedKeys []string) error {
+	return nil
+}
+
+type simulatedHypervisor struct{ m *simulatedMachine }
+
+func (s *simulatedHypervisor) EnterMaintenanceMode(target power.HypervisorTarget, timeout time.Duration) error {
+	return nil
+}
+
+func (s *simulatedHypervisor) ExitMaintenanceMode(target power.HypervisorTarget) error {
+	return nil
+}
+
+func (s *simulatedHypervisor) Shutdown(target power.HypervisorTarget) error {
+	return s.m.setPower(false, "Would shut down hypervisor host %s", target.Address)
+}
+
+type simulatedRedfish struct{ m *simulatedMachine }
+
+func (s *simulatedRedfish) PowerOn(target power.RedfishTarget) error {
+	return s.m.setPower(true, "Would send Redfish power on to %s", target.Address)
+}
+
+func (s *simulatedRedfish) PowerOff(target power.RedfishTarget) error {
+	return s.m.setPower(false, "Would send Redfish graceful shutdown to %s", target.Address)
+}
+
+func (s *simulatedRedfish) GetPowerStatus(target power.RedfishTarget) (bool, error) {
+	return s.m.isOn(), nil
+}
+
+func (s *simulatedRedfish) GetInventory(target power.RedfishTarget) (power.Inventory, error) {
+	return simulatedInventory, nil
+}
+
+func (s *simulatedRedfish) GetHostInventory(target power.RedfishTarget) (power.HostInventory, error) {
+	return simulatedHostInventory, nil
+}
+
+func (s *simulatedRedfish) SetBootDevice(target power.RedfishTarget, device string, persistent bool) error {
+	s.m.setBootOverride(power.BootOverride{Device: device, Persistent: persistent},
+		"Would set Redfish boot override of %s to %s (persistent: %t)", target.Address, device, persistent)
+	return nil
+}
+
+func (s *simulatedRedfish) GetBootDevice(target power.RedfishTarget) (power.BootOverride, error) {
+	return s.m.getBootOverride(), nil
+}
+
+func (s *simulatedRedfish) GetPowerLimit(target power.RedfishTarget) (power.PowerLimit, error) {
+	return s.m.getPowerLimit(), nil
+}
+
+func (s *simulatedRedfish) SetPowerLimit(target power.RedfishTarget, watts int32) error {
+	s.m.setPowerLimit(watts, "Would set Redfish power limit of %s to %dW", target.Address, watts)
+	return nil
+}
+
+func (s *simulatedRedfish) GetTemperatures(target power.RedfishTarget) ([]power.Temperature, error) {
+	return simulatedTemperatures, nil
+}
+
+func (s *simulatedRedfish) SetWatchdog(target power.RedfishTarget, action string) error {
+	if action == "" {
+		s.m.record("Would disable the Redfish host watchdog of %s", target.Address)
+	} else {
+		s.m.record("Would enable the Redfish host watchdog of %s with %s", target.Address, action)
+	}
+	return nil
+}
+
+func (s *simulatedRedfish) GetSerialConsole(target power.RedfishTarget) (power.SerialConsoleInfo, error) {
+	return power.SerialConsoleInfo{}, fmt.Errorf("serial console is not available for simulated servers")
+}
+
+func (s *simulatedRedfish) ListVolumes(target power.RedfishTarget, storageID string) ([]power.Volume, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	return append([]power.Volume(nil), s.m.volumes...), nil
+}
+
+func (s *simulatedRedfish) CreateVolume(target power.RedfishTarget, storageID string, volume power.Volume) error {
+	s.m.mu.Lock()
+	volume.ID = volume.Name
+	s.m.volumes = append(s.m.volumes, volume)
+	s.m.mu.Unlock()
+	s.m.record("Would create %s volume %s on %s", volume.RAIDType, volume.Name, target.Address)
+	return nil
+}
+
+func (s *simulatedRedfish) DeleteVolume(target power.RedfishTarget, storageID string, volumeID string) error {
+	s.m.mu.Lock()
+	for i, v := range s.m.volumes {
+		if v.ID == volumeID {
+			s.m.volumes = append(s.m.volumes[:i], s.m.volumes[i+1:]...)
+			break
+		}
+	}
+	s.m.mu.Unlock()
+	s.m.record("Would delete volume %s on %s", volumeID, target.Address)
+	return nil
+}
+
+// SubscribeMetricReports fails, so simulated servers are polled
+func (s *simulatedRedfish) SubscribeMetricReports(target power.RedfishTarget, destination string, context string) (string, error) {
+	return "", fmt.Errorf("simulated BMCs send no metric reports: %w", power.ErrUnsupported)
+}
+
+// SubscribeEvents fails, so simulated servers are polled
+func (s *simulatedRedfish) SubscribeEvents(target power.RedfishTarget, destination string, context string) (string, error) {
+	return "", fmt.Errorf("simulated BMCs send no events: %w", power.ErrUnsupported)
+}
+
+func (s *simulatedRedfish) Unsubscribe(target power.RedfishTarget, subscriptionURI string) error {
+	return nil
+}
+
+type simulatedAttestor struct{ m *simulatedMachine }
+
+func (s *simulatedAttestor) Quote(ctx context.Context, host string, user string, key string, akHandle string, pcrs []int, nonce []byte) (power.TPMQuote, error) {
+	return power.TPMQuote{}, fmt.Errorf("attestation is not simulated, remove spec.attestation to simulate this server")
+}
+
+// simulatedRelay wakes and pings the machine as if from its subnet
+type simulatedRelay struct{ m *simulatedMachine }
+
+func (s *simulatedRelay) Wake(ctx context.Context, relay string, macAddress string, port int, broadcastAddress string) error {
+	return (&simulatedWol{s.m}).Wake(ctx, macAddress, port, broadcastAddress)
+}
+
+func (s *simulatedRelay) IsReachable(ctx context.Context, relay string, address string) bool {
+	return s.m.isReachable()
+}
+
+type simulatedPinger struct{ m *simulatedMachine }
+
+// simulatedRTT is the round trip time of a running simulated server
+const simulatedRTT = 300 * time.Microsecond
+
+// IsReachable reports the simulated power state, as if the host answered
+// pings while it's on, once it has booted
+func (s *simulatedPinger) IsReachable(ctx context.Context, address string) bool {
+	return s.m.isReachable()
+}
+
+func (s *simulatedPinger) Probe(ctx context.Context, address string) power.PingStats {
+	if !s.m.isReachable() {
+		return power.PingStats{Sent: 1}
+	}
+	return power.PingStats{Sent: 1, Received: 1, RTT: simulatedRTT}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/power"
+)
+
+func TestSimulatedMachineReachability(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile SimulationProfile
+		on      bool
+		elapsed time.Duration
+		want    bool
+	}{
+		{name: "on instantly", on: true, want: true},
+		{name: "off instantly"},
+		{name: "booting", profile: SimulationProfile{BootLatency: time.Minute}, on: true, elapsed: 30 * time.Second},
+		{name: "booted", profile: SimulationProfile{BootLatency: time.Minute}, on: true, elapsed: 2 * time.Minute, want: true},
+		{name: "shutting down", profile: SimulationProfile{ShutdownLatency: time.Minute}, elapsed: 30 * time.Second, want: true},
+		{name: "shut down", profile: SimulationProfile{ShutdownLatency: time.Minute}, elapsed: 2 * time.Minute},
+		{name: "flapping", profile: SimulationProfile{FlapRate: 1}, on: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &simulatedMachine{on: !tt.on, profile: tt.profile}
+			if err := m.setPower(tt.on, "power"); err != nil {
+				t.Fatalf("setPower() error = %v", err)
+			}
+			m.changedAt = m.changedAt.Add(-tt.elapsed)
+			if got := m.isReachable(); got != tt.want {
+				t.Errorf("isReachable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimulatedMachineFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile SimulationProfile
+		want    power.ErrorClass
+	}{
+		{name: "transient", profile: SimulationProfile{FailureRate: 1}, want: power.ClassTransient},
+		{name: "credentials", profile: SimulationProfile{AuthFailureRate: 1}, want: power.ClassAuthFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &simulatedMachine{profile: tt.profile}
+			err := m.setPower(true, "power on")
+			if got := power.Classify(err); got != tt.want {
+				t.Errorf("setPower() error = %v, classified %v, want %v", err, got, tt.want)
+			}
+			if m.isOn() {
+				t.Errorf("failed power command powered the machine on")
+			}
+		})
+	}
+}
+
+func TestSimulatedMachineBootJitter(t *testing.T) {
+	m := &simulatedMachine{profile: SimulationProfile{BootLatency: time.Minute, BootJitter: time.Minute}}
+	if err := m.setPower(true, "power on"); err != nil {
+		t.Fatal(err)
+	}
+	if m.bootDelay < time.Minute || m.bootDelay >= 2*time.Minute {
+		t.Errorf("bootDelay = %s, want between the boot latency and the latency plus jitter", m.bootDelay)
+	}
+
+	// Commands that don't change the power state don't restart the boot
+	changedAt := m.changedAt
+	if err := m.setPower(true, "power on"); err != nil {
+		t.Fatal(err)
+	}
+	if !m.changedAt.Equal(changedAt) {
+		t.Errorf("repeated power on restarted the boot")
+	}
+}
+
+func TestSimulatorUsesProfile(t *testing.T) {
+	profile := SimulationProfile{CommandLatency: time.Millisecond, FailureRate: 0.5}
+	r := &ServerReconciler{Simulation: profile}
+	server := &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: "sim-00001"},
+		Status:     baremetalcontrollerv1.ServerStatus{Status: baremetalcontrollerv1.StatusActive},
+	}
+
+	sim := r.simulator(server)
+	if !sim.simulating {
+		t.Errorf("simulator() not simulating")
+	}
+	m := r.simulations.machine(server)
+	if m.profile != profile {
+		t.Errorf("machine profile = %+v, want %+v", m.profile, profile)
+	}
+	// The machine starts in the state the status claims
+	if !m.isOn() {
+		t.Errorf("machine of an active server is off")
+	}
+}
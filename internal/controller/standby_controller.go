@@ -0,0 +1,282 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/budget"
+	"github.com/Unbounder1/bare-metal-controller/internal/cluster"
+	"github.com/Unbounder1/bare-metal-controller/internal/drain"
+	"github.com/Unbounder1/bare-metal-controller/internal/listing"
+)
+
+// standbyRetryInterval rechecks standby servers that are booting or whose
+// nodes are still being drained
+const standbyRetryInterval = 15 * time.Second
+
+// StandbyReconciler keeps the warm standby pool of each ServerClass filled.
+// Standby servers are powered on through their spec.powerState and their
+// nodes cordoned once they register. The autoscaler provider promotes them.
+type StandbyReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// APIReader lists pods by node without caching every pod in the cluster
+	APIReader client.Reader
+
+	// Recorder emits events when servers join or leave the pool
+	Recorder record.EventRecorder
+
+	// Clusters reaches the nodes of servers with spec.clusterRef
+	Clusters *cluster.Clients
+}
+
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=serverclasses,verbs=get;list;watch
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=servers,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=powerbudgets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list
+// +kubebuilder:rbac:groups=core,resources=pods/eviction,verbs=create
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// Reconcile powers on cold servers of the class until its standby policy is
+// met, cordons the nodes of standby servers and releases extra ones.
+func (r *StandbyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var class baremetalcontrollerv1.ServerClass
+	if err := r.Get(ctx, req.NamespacedName, &class); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	var desired int
+	if class.Spec.Standby != nil {
+		desired = int(class.Spec.Standby.Servers)
+	}
+
+	var standby, cold []*baremetalcontrollerv1.Server
+	err := listing.Servers(ctx, r.Client, 0, func(server *baremetalcontrollerv1.Server) error {
+		if server.Spec.ServerClassName != class.Name {
+			return nil
+		}
+		switch {
+		case server.Annotations[baremetalcontrollerv1.StandbyAnnotation] == "true":
+			standby = append(standby, server.DeepCopy())
+		case standbyCandidate(server):
+			cold = append(cold, server.DeepCopy())
+		}
+		return nil
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	sort.Slice(standby, func(i, j int) bool { return standby[i].Name < standby[j].Name })
+
+	// Servers powered off or failed since are no longer spares
+	kept := standby[:0]
+	for _, server := range standby {
+		if server.Spec.PowerState.Settled() != baremetalcontrollerv1.PowerStateOn ||
+			server.Status.Status == baremetalcontrollerv1.StatusFailed {
+			if err := r.release(ctx, server, false); err != nil {
+				return ctrl.Result{}, err
+			}
+			continue
+		}
+		kept = append(kept, server)
+	}
+	standby = kept
+
+	for len(standby) > desired {
+		if err := r.release(ctx, standby[len(standby)-1], true); err != nil {
+			return ctrl.Result{}, err
+		}
+		standby = standby[:len(standby)-1]
+	}
+
+	// Backfill from cold servers in name order, skipping full power budgets
+	sort.Slice(cold, func(i, j int) bool { return cold[i].Name < cold[j].Name })
+	pending := map[client.ObjectKey]bool{}
+	for _, server := range cold {
+		if len(standby) >= desired {
+			break
+		}
+		if err := budget.Check(ctx, r.Client, server, pending); err != nil {
+			var exceeded *budget.ExceededError
+			if !errors.As(err, &exceeded) {
+				return ctrl.Result{}, err
+			}
+			logger.V(1).Info("Not powering on standby server", "server", server.Name, "reason", err.Error())
+			continue
+		}
+		if err := r.powerOnStandby(ctx, server); err != nil {
+			return ctrl.Result{}, err
+		}
+		pending[client.ObjectKeyFromObject(server)] = true
+		standby = append(standby, server)
+	}
+
+	// Keep workloads off standby nodes until they are promoted
+	ready := true
+	for _, server := range standby {
+		if server.Status.Status != baremetalcontrollerv1.StatusActive {
+			ready = false
+			continue
+		}
+		c, reader, err := nodeClients(ctx, r.Clusters, server, r.Client, r.apiReader())
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		var node corev1.Node
+		if err := c.Get(ctx, types.NamespacedName{Name: server.NodeName()}, &node); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return ctrl.Result{}, err
+			}
+			ready = false
+			continue
+		}
+		if node.Spec.Unschedulable {
+			continue
+		}
+		// Evict pods scheduled before the node was cordoned
+		drained, err := drain.Node(ctx, c, reader, server.NodeName())
+		if err != nil {
+			logger.Error(err, "Failed to cordon standby node", "server", server.Name)
+			ready = false
+			continue
+		}
+		if !drained {
+			ready = false
+		}
+	}
+	if !ready {
+		return ctrl.Result{RequeueAfter: standbyRetryInterval}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// standbyCandidate returns true for cold servers the pool may be filled
+// from: off, autoscaled, not protected, and not kept off for power loss,
+// off-hours or overheating
+func standbyCandidate(server *baremetalcontrollerv1.Server) bool {
+	return server.Spec.PowerState == baremetalcontrollerv1.PowerStateOff &&
+		server.Status.Status != baremetalcontrollerv1.StatusFailed &&
+		server.Annotations[baremetalcontrollerv1.AutoscalerExcludeAnnotation] != "true" &&
+		!server.Spec.Role.Protected() &&
+		server.Annotations[baremetalcontrollerv1.UPSShutdownAnnotation] != "true" &&
+		server.Annotations[baremetalcontrollerv1.HibernatedAnnotation] == "" &&
+		server.Annotations[baremetalcontrollerv1.ThermalShutdownAnnotation] != "true"
+}
+
+// powerOnStandby annotates a cold server as a spare and powers it on. Its
+// node was cordoned if it was powered off while idle, so the idle power-off
+// annotation is removed to keep it that way.
+func (r *StandbyReconciler) powerOnStandby(ctx context.Context, server *baremetalcontrollerv1.Server) error {
+	patch := client.MergeFrom(server.DeepCopy())
+	if server.Annotations == nil {
+		server.Annotations = map[string]string{}
+	}
+	server.Annotations[baremetalcontrollerv1.StandbyAnnotation] = "true"
+	delete(server.Annotations, baremetalcontrollerv1.IdlePowerOffAnnotation)
+	server.Spec.PowerState = baremetalcontrollerv1.PowerStateOn
+	if err := r.Patch(ctx, server, patch); err != nil {
+		return fmt.Errorf("failed to power on standby server %s: %w", server.Name, err)
+	}
+	log.FromContext(ctx).Info("Powering on standby server", "server", server.Name, "serverClass", server.Spec.ServerClassName)
+	r.event(server, corev1.EventTypeNormal, "StandbyPoweredOn", "Powering on as a warm spare")
+	return nil
+}
+
+// release removes a server from the pool and uncordons its node. With
+// powerOff, a spare the pool no longer needs is powered off as well.
+func (r *StandbyReconciler) release(ctx context.Context, server *baremetalcontrollerv1.Server, powerOff bool) error {
+	c, _, err := nodeClients(ctx, r.Clusters, server, r.Client, r.apiReader())
+	if err != nil {
+		return err
+	}
+	if err := drain.Uncordon(ctx, c, server.NodeName()); err != nil {
+		return err
+	}
+	patch := client.MergeFrom(server.DeepCopy())
+	delete(server.Annotations, baremetalcontrollerv1.StandbyAnnotation)
+	if powerOff {
+		server.Spec.PowerState = baremetalcontrollerv1.PowerStateOff
+	}
+	if err := r.Patch(ctx, server, patch); err != nil {
+		return fmt.Errorf("failed to release standby server %s: %w", server.Name, err)
+	}
+	if powerOff {
+		log.FromContext(ctx).Info("Powering off extra standby server", "server", server.Name)
+		r.event(server, corev1.EventTypeNormal, "StandbyPoweredOff", "Powering off, the standby pool is full")
+	}
+	return nil
+}
+
+func (r *StandbyReconciler) event(server *baremetalcontrollerv1.Server, eventType string, reason string, message string) {
+	if r.Recorder != nil {
+		r.Recorder.Event(server, eventType, reason, message)
+	}
+}
+
+// apiReader returns the reader pods are listed with when draining
+func (r *StandbyReconciler) apiReader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
+}
+
+// classForServer maps a Server or its Node, which shares its name, to the
+// ServerClass of the server
+func (r *StandbyReconciler) classForServer(ctx context.Context, obj client.Object) []reconcile.Request {
+	server, ok := obj.(*baremetalcontrollerv1.Server)
+	if !ok {
+		server = &baremetalcontrollerv1.Server{}
+		if err := r.Get(ctx, types.NamespacedName{Name: obj.GetName()}, server); err != nil {
+			return nil
+		}
+	}
+	if server.Spec.ServerClassName == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: server.Spec.ServerClassName}}}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *StandbyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&baremetalcontrollerv1.ServerClass{}).
+		Watches(&baremetalcontrollerv1.Server{}, handler.EnqueueRequestsFromMapFunc(r.classForServer)).
+		Watches(&corev1.Node{}, handler.EnqueueRequestsFromMapFunc(r.classForServer)).
+		Named("standby").
+		Complete(r)
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+var _ = Describe("Standby Controller", func() {
+	const className = "standby-class"
+
+	var (
+		ctx        context.Context
+		reconciler *StandbyReconciler
+	)
+
+	serverNames := []string{"standby-server-a", "standby-server-b"}
+
+	reconcileClass := func() reconcile.Result {
+		result, err := reconciler.Reconcile(ctx, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: className},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return result
+	}
+
+	getServer := func(name string) *baremetalcontrollerv1.Server {
+		server := &baremetalcontrollerv1.Server{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: name}, server)).To(Succeed())
+		return server
+	}
+
+	setStandby := func(servers int32) {
+		class := &baremetalcontrollerv1.ServerClass{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: className}, class)).To(Succeed())
+		class.Spec.Standby = &baremetalcontrollerv1.StandbyPolicy{Servers: servers}
+		Expect(k8sClient.Update(ctx, class)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		reconciler = &StandbyReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+
+		class := &baremetalcontrollerv1.ServerClass{ObjectMeta: metav1.ObjectMeta{Name: className}}
+		Expect(k8sClient.Create(ctx, class)).To(Succeed())
+
+		for _, name := range serverNames {
+			server := &baremetalcontrollerv1.Server{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Spec: baremetalcontrollerv1.ServerSpec{
+					PowerState:      baremetalcontrollerv1.PowerStateOff,
+					ServerClassName: className,
+					Type:            baremetalcontrollerv1.ControlTypeWOL,
+					Control: baremetalcontrollerv1.ControlSpecs{
+						WOL: &baremetalcontrollerv1.WOLSpecs{
+							Address:    "192.168.1.150",
+							MACAddress: "00:11:22:33:44:99",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, server)).To(Succeed())
+		}
+	})
+
+	AfterEach(func() {
+		for _, name := range serverNames {
+			server := &baremetalcontrollerv1.Server{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{Name: name}, server); err == nil {
+				Expect(k8sClient.Delete(ctx, server)).To(Succeed())
+			}
+		}
+		class := &baremetalcontrollerv1.ServerClass{}
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: className}, class); err == nil {
+			Expect(k8sClient.Delete(ctx, class)).To(Succeed())
+		}
+	})
+
+	It("should fill the pool from cold servers and power off extra spares", func() {
+		setStandby(1)
+		result := reconcileClass()
+		Expect(result.RequeueAfter).To(Equal(15 * time.Second))
+
+		server := getServer(serverNames[0])
+		Expect(server.Annotations).To(HaveKeyWithValue(baremetalcontrollerv1.StandbyAnnotation, "true"))
+		Expect(server.Spec.PowerState).To(Equal(baremetalcontrollerv1.PowerStateOn))
+		server = getServer(serverNames[1])
+		Expect(server.Annotations).NotTo(HaveKey(baremetalcontrollerv1.StandbyAnnotation))
+		Expect(server.Spec.PowerState).To(Equal(baremetalcontrollerv1.PowerStateOff))
+
+		setStandby(0)
+		reconcileClass()
+		server = getServer(serverNames[0])
+		Expect(server.Annotations).NotTo(HaveKey(baremetalcontrollerv1.StandbyAnnotation))
+		Expect(server.Spec.PowerState).To(Equal(baremetalcontrollerv1.PowerStateOff))
+	})
+
+	It("should backfill a promoted spare", func() {
+		setStandby(1)
+		reconcileClass()
+
+		// Promotion by the autoscaler provider removes the annotation
+		server := getServer(serverNames[0])
+		delete(server.Annotations, baremetalcontrollerv1.StandbyAnnotation)
+		Expect(k8sClient.Update(ctx, server)).To(Succeed())
+
+		reconcileClass()
+		Expect(getServer(serverNames[0]).Spec.PowerState).To(Equal(baremetalcontrollerv1.PowerStateOn))
+		server = getServer(serverNames[1])
+		Expect(server.Annotations).To(HaveKeyWithValue(baremetalcontrollerv1.StandbyAnnotation, "true"))
+		Expect(server.Spec.PowerState).To(Equal(baremetalcontrollerv1.PowerStateOn))
+	})
+})
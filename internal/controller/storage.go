@@ -0,0 +1,210 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/power"
+)
+
+const bytesPerGiB = 1024 * 1024 * 1024
+
+// applyStorageLayout applies the declared RAID layout before the server is
+// powered on. Most controllers only apply volume changes on reset, so the
+// layout is verified once the server comes back up.
+func (r *ServerReconciler) applyStorageLayout(ctx context.Context, server *baremetalcontrollerv1.Server) error {
+	storage := server.Spec.Storage
+	if server.Spec.Type != baremetalcontrollerv1.ControlTypeRedfish {
+		return invalidSpec("storage layout requires the redfish control type")
+	}
+
+	if server.Status.Storage != nil {
+		switch server.Status.Storage.Phase {
+		case baremetalcontrollerv1.StoragePhaseApplied:
+			// Waiting for the next boot to apply the pending layout
+			return nil
+		case baremetalcontrollerv1.StoragePhaseFailed:
+			return fmt.Errorf("storage layout failed: %s", server.Status.Storage.Message)
+		}
+	}
+
+	target, err := r.endpoints().Redfish(ctx, server)
+	if err != nil {
+		return err
+	}
+
+	volumes, err := r.RedfishClient.ListVolumes(target, storage.Controller)
+	if err != nil {
+		return fmt.Errorf("failed to list volumes: %w", err)
+	}
+
+	if mismatch := storageMismatch(storage, volumes); mismatch == "" {
+		setStorageStatus(server, baremetalcontrollerv1.StoragePhaseVerified, "", volumes)
+		return nil
+	}
+
+	// Remove volumes that are in the way of the declared layout
+	existing := map[string]bool{}
+	for _, v := range volumes {
+		desired := findVolumeSpec(storage.Volumes, v.Name)
+		if desired != nil && volumeMatches(*desired, v) {
+			existing[v.Name] = true
+			continue
+		}
+		if !storage.WipeExisting {
+			if desired != nil {
+				return fmt.Errorf("volume %s exists with a different layout and wipeExisting is not set", v.Name)
+			}
+			continue
+		}
+		if err := r.RedfishClient.DeleteVolume(target, storage.Controller, v.ID); err != nil {
+			return fmt.Errorf("failed to delete volume %s: %w", v.Name, err)
+		}
+	}
+
+	for _, spec := range storage.Volumes {
+		if existing[spec.Name] {
+			continue
+		}
+		volume := power.Volume{
+			Name:          spec.Name,
+			RAIDType:      spec.RAIDType,
+			CapacityBytes: spec.CapacityGiB * bytesPerGiB,
+			Drives:        spec.Drives,
+		}
+		if err := r.RedfishClient.CreateVolume(target, storage.Controller, volume); err != nil {
+			return fmt.Errorf("failed to create volume %s: %w", spec.Name, err)
+		}
+	}
+
+	setStorageStatus(server, baremetalcontrollerv1.StoragePhaseApplied, "Layout will be applied on next boot", nil)
+	return nil
+}
+
+// verifyStorageLayout checks that a layout applied before boot took effect.
+func (r *ServerReconciler) verifyStorageLayout(ctx context.Context, server *baremetalcontrollerv1.Server) {
+	if server.Spec.Storage == nil || server.Status.Storage == nil ||
+		server.Status.Storage.Phase != baremetalcontrollerv1.StoragePhaseApplied {
+		return
+	}
+
+	target, err := r.endpoints().Redfish(ctx, server)
+	if err != nil {
+		setStorageStatus(server, baremetalcontrollerv1.StoragePhaseFailed, err.Error(), nil)
+		return
+	}
+
+	volumes, err := r.RedfishClient.ListVolumes(target, server.Spec.Storage.Controller)
+	if err != nil {
+		setStorageStatus(server, baremetalcontrollerv1.StoragePhaseFailed, fmt.Sprintf("failed to list volumes: %v", err), nil)
+		return
+	}
+
+	if mismatch := storageMismatch(server.Spec.Storage, volumes); mismatch != "" {
+		setStorageStatus(server, baremetalcontrollerv1.StoragePhaseFailed, mismatch, volumes)
+		return
+	}
+	setStorageStatus(server, baremetalcontrollerv1.StoragePhaseVerified, "", volumes)
+}
+
+// storageMismatch returns a description of the first difference between the
+// declared layout and the observed volumes, or "" if they match.
+func storageMismatch(storage *baremetalcontrollerv1.StorageSpec, volumes []power.Volume) string {
+	for _, spec := range storage.Volumes {
+		found := false
+		for _, v := range volumes {
+			if v.Name != spec.Name {
+				continue
+			}
+			if !volumeMatches(spec, v) {
+				return fmt.Sprintf("volume %s has RAID type %s on drives %v, expected %s on %v",
+					spec.Name, v.RAIDType, v.Drives, spec.RAIDType, spec.Drives)
+			}
+			found = true
+			break
+		}
+		if !found {
+			return fmt.Sprintf("volume %s not found", spec.Name)
+		}
+	}
+
+	if storage.WipeExisting {
+		for _, v := range volumes {
+			if findVolumeSpec(storage.Volumes, v.Name) == nil {
+				return fmt.Sprintf("undeclared volume %s present", v.Name)
+			}
+		}
+	}
+	return ""
+}
+
+func volumeMatches(spec baremetalcontrollerv1.VolumeSpec, v power.Volume) bool {
+	if !strings.EqualFold(spec.RAIDType, v.RAIDType) {
+		return false
+	}
+	// Some controllers don't report drive links
+	if len(v.Drives) == 0 {
+		return true
+	}
+	return sameStrings(spec.Drives, v.Drives)
+}
+
+func findVolumeSpec(specs []baremetalcontrollerv1.VolumeSpec, name string) *baremetalcontrollerv1.VolumeSpec {
+	for i := range specs {
+		if specs[i].Name == name {
+			return &specs[i]
+		}
+	}
+	return nil
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	x := append([]string(nil), a...)
+	y := append([]string(nil), b...)
+	sort.Strings(x)
+	sort.Strings(y)
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func setStorageStatus(server *baremetalcontrollerv1.Server, phase baremetalcontrollerv1.StoragePhase, message string, volumes []power.Volume) {
+	status := &baremetalcontrollerv1.StorageStatus{
+		Phase:   phase,
+		Message: message,
+	}
+	for _, v := range volumes {
+		status.Volumes = append(status.Volumes, baremetalcontrollerv1.VolumeStatus{
+			Name:          v.Name,
+			RAIDType:      v.RAIDType,
+			CapacityBytes: v.CapacityBytes,
+			Drives:        v.Drives,
+		})
+	}
+	server.Status.Storage = status
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/bmc"
+	"github.com/Unbounder1/bare-metal-controller/internal/power"
+)
+
+// telemetryCheckInterval is how often the subscription of a BMC is checked,
+// so one the BMC dropped, e.g. after failed deliveries, is made again
+const telemetryCheckInterval = time.Hour
+
+// reconcileTelemetry subscribes the BMC of a Redfish server to the metric
+// report and event receiver, and moves the subscriptions when the
+// receiver's URL changes. It returns true if the status changed.
+func (r *ServerReconciler) reconcileTelemetry(ctx context.Context, server *baremetalcontrollerv1.Server) bool {
+	if r.Telemetry == nil || server.Spec.Type != baremetalcontrollerv1.ControlTypeRedfish {
+		return false
+	}
+	key := client.ObjectKeyFromObject(server)
+	destination := r.Telemetry.Destination(key)
+	eventDestination := r.Telemetry.EventDestination(key)
+	status := server.Status.Telemetry
+	if status != nil && status.Destination == destination && status.EventDestination == eventDestination &&
+		status.LastUpdated != nil && time.Since(status.LastUpdated.Time) < telemetryCheckInterval &&
+		(r.Telemetry.Expecting(key) || status.Subscription == "" && status.EventSubscription == "") {
+		return false
+	}
+
+	logger := log.FromContext(ctx)
+	target, err := r.endpoints().Redfish(ctx, server)
+	if err != nil {
+		logger.Error(err, "Failed to subscribe to metric reports and events", "server", server.Name)
+		return false
+	}
+	if status != nil {
+		for _, moved := range []struct{ subscription, from, to string }{
+			{status.Subscription, status.Destination, destination},
+			{status.EventSubscription, status.EventDestination, eventDestination},
+		} {
+			if moved.subscription == "" || moved.from == moved.to {
+				continue
+			}
+			if err := r.RedfishClient.Unsubscribe(target, moved.subscription); err != nil {
+				logger.Error(err, "Failed to remove subscription", "server", server.Name,
+					"subscription", moved.subscription)
+			}
+		}
+	}
+
+	// A new token after a restart of the controller replaces the one the
+	// subscriptions had
+	token := r.Telemetry.Expect(key, bmc.Host(target.Address))
+	now := metav1.Now()
+	next := &baremetalcontrollerv1.TelemetryStatus{
+		Destination:      destination,
+		EventDestination: eventDestination,
+		LastUpdated:      &now,
+	}
+	subscription, err := r.RedfishClient.SubscribeMetricReports(target, destination, token)
+	if err != nil {
+		if !errors.Is(err, power.ErrUnsupported) {
+			logger.Error(err, "Failed to subscribe to metric reports", "server", server.Name)
+		}
+		next.Message = err.Error()
+	} else {
+		next.Subscription = subscription
+	}
+	subscription, err = r.RedfishClient.SubscribeEvents(target, eventDestination, token)
+	if err != nil {
+		if !errors.Is(err, power.ErrUnsupported) {
+			logger.Error(err, "Failed to subscribe to events", "server", server.Name)
+		}
+		next.EventMessage = err.Error()
+	} else {
+		next.EventSubscription = subscription
+	}
+	if next.Subscription == "" && next.EventSubscription == "" {
+		r.Telemetry.Forget(key)
+	}
+	server.Status.Telemetry = next
+	return true
+}
+
+// handleBMCEvents records the events the server's BMC posted since the last
+// reconcile. A power change makes the server be probed again right away
+// instead of when its last probe result expires, so drift is noticed as
+// soon as the BMC reports it.
+func (r *ServerReconciler) handleBMCEvents(ctx context.Context, server *baremetalcontrollerv1.Server) {
+	if r.Telemetry == nil {
+		return
+	}
+	for _, e := range r.Telemetry.TakeEvents(client.ObjectKeyFromObject(server)) {
+		log.FromContext(ctx).Info("Received BMC event", "server", server.Name, "type", e.Type,
+			"messageId", e.MessageID, "severity", e.Severity, "message", e.Message)
+		eventType := corev1.EventTypeNormal
+		if e.Severity == "Warning" || e.Severity == "Critical" {
+			eventType = corev1.EventTypeWarning
+		}
+		r.event(server, eventType, "BMCEvent", "%s %s: %s", e.Severity, e.MessageID, e.Message)
+
+		if e.PowerChange() && r.probes != nil {
+			r.probes.expire(client.ObjectKeyFromObject(server))
+		}
+	}
+}
+
+// reportedPowerDraw returns the power draw of the server's latest metric
+// report, if it is recent enough to stand in for reading it from the BMC
+func (r *ServerReconciler) reportedPowerDraw(server *baremetalcontrollerv1.Server, interval time.Duration) (int32, bool) {
+	if r.Telemetry == nil {
+		return 0, false
+	}
+	return r.Telemetry.ConsumedWatts(client.ObjectKeyFromObject(server), 2*interval)
+}
+
+// reportedTemperatures returns the temperatures of the server's latest
+// metric report, if it is recent enough to stand in for reading them from
+// the BMC. Reports carry no critical temperatures, so those of the sensors
+// last read from the BMC are kept.
+func (r *ServerReconciler) reportedTemperatures(server *baremetalcontrollerv1.Server, interval time.Duration) ([]power.Temperature, bool) {
+	if r.Telemetry == nil {
+		return nil, false
+	}
+	temperatures, ok := r.Telemetry.Temperatures(client.ObjectKeyFromObject(server), 2*interval)
+	if !ok || server.Status.Thermal == nil {
+		return temperatures, ok
+	}
+	for i := range temperatures {
+		for _, sensor := range server.Status.Thermal.Sensors {
+			if sensor.Name == temperatures[i].Name {
+				temperatures[i].CriticalCelsius = float64(sensor.CriticalCelsius)
+				break
+			}
+		}
+	}
+	return temperatures, true
+}
@@ -0,0 +1,282 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/drain"
+	"github.com/Unbounder1/bare-metal-controller/internal/power"
+)
+
+const (
+	defaultThermalInterval     = 30 * time.Second
+	defaultThermalSustainedFor = 2 * time.Minute
+	// thermalDrainInterval rechecks a node drained for a thermal shutdown
+	thermalDrainInterval = 10 * time.Second
+)
+
+// checkThermal reads the temperatures of an active server whose ServerClass
+// has a thermal policy, and emits an event and optionally drains and powers
+// off the server once a sensor has been critical for too long. It returns
+// when to check again, 0 if there is nothing to watch, and whether the
+// status changed.
+func (r *ServerReconciler) checkThermal(ctx context.Context, server *baremetalcontrollerv1.Server) (time.Duration, bool) {
+	logger := log.FromContext(ctx)
+	before := server.Status.DeepCopy()
+	changed := func() bool {
+		return !equality.Semantic.DeepEqual(before, &server.Status)
+	}
+
+	policy := r.thermalPolicy(ctx, server)
+	if policy == nil || server.Status.Status != baremetalcontrollerv1.StatusActive ||
+		server.Spec.PowerState.Settled() != baremetalcontrollerv1.PowerStateOn {
+		// The clock restarts once the server runs again
+		if server.Status.Thermal != nil {
+			server.Status.Thermal.CriticalSince = nil
+		}
+		return 0, changed()
+	}
+
+	interval := defaultThermalInterval
+	if policy.Interval != nil {
+		interval = policy.Interval.Duration
+	}
+	sustainedFor := defaultThermalSustainedFor
+	if policy.SustainedFor != nil {
+		sustainedFor = policy.SustainedFor.Duration
+	}
+	shuttingDown := server.Annotations[baremetalcontrollerv1.ThermalShutdownAnnotation] == "true"
+
+	thermal := server.Status.Thermal
+	if thermal != nil && thermal.LastUpdated != nil && !shuttingDown {
+		if elapsed := time.Since(thermal.LastUpdated.Time); elapsed < interval {
+			return interval - elapsed, changed()
+		}
+	}
+
+	temperatures, ok := r.reportedTemperatures(server, interval)
+	if !ok {
+		var err error
+		if temperatures, err = r.getTemperatures(ctx, server); err != nil {
+			logger.Error(err, "Failed to read temperatures", "server", server.Name)
+			return interval, changed()
+		}
+	}
+	critical := setThermalStatus(server, policy, temperatures)
+
+	thermal = server.Status.Thermal
+	sustained := thermal.CriticalSince != nil && time.Since(thermal.CriticalSince.Time) >= sustainedFor
+	if !sustained {
+		setThermalCondition(server, critical, false)
+		if shuttingDown {
+			// Cooled down while draining, or powered on again by hand
+			if err := r.cancelThermalShutdown(ctx, server); err != nil {
+				logger.Error(err, "Failed to cancel thermal shutdown", "server", server.Name)
+			}
+		}
+		return interval, changed()
+	}
+
+	if condition := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionThermalCritical); condition == nil ||
+		condition.Reason != "SustainedCritical" {
+		r.event(server, corev1.EventTypeWarning, "ThermalCritical", "%s for %s", critical, sustainedFor)
+	}
+	setThermalCondition(server, critical, true)
+	// Protected servers are left for an operator to power off
+	if !policy.PowerOff || server.Spec.Role.Protected() {
+		return interval, changed()
+	}
+
+	requeue, err := r.thermalShutdown(ctx, server, policy, thermal.CriticalSince.Add(sustainedFor), critical)
+	if err != nil {
+		logger.Error(err, "Failed to shut down overheating server", "server", server.Name)
+		return thermalDrainInterval, changed()
+	}
+	return requeue, changed()
+}
+
+// thermalShutdown annotates the server, drains its node if the policy asks
+// for it and then powers the server off. It returns when to check the drain
+// again, or 0 once the server is being powered off.
+func (r *ServerReconciler) thermalShutdown(ctx context.Context, server *baremetalcontrollerv1.Server, policy *baremetalcontrollerv1.ThermalPolicy, since time.Time, critical string) (time.Duration, error) {
+	if server.Annotations[baremetalcontrollerv1.ThermalShutdownAnnotation] != "true" {
+		patch := client.MergeFrom(server.DeepCopy())
+		if server.Annotations == nil {
+			server.Annotations = map[string]string{}
+		}
+		server.Annotations[baremetalcontrollerv1.ThermalShutdownAnnotation] = "true"
+		if err := r.Patch(ctx, server, patch); err != nil {
+			return 0, fmt.Errorf("failed to annotate server: %w", err)
+		}
+	}
+
+	if policy.DrainTimeout != nil && time.Now().Before(since.Add(policy.DrainTimeout.Duration)) {
+		c, reader, err := nodeClients(ctx, r.Clusters, server, r.Client, r.apiReader())
+		if err != nil {
+			return 0, err
+		}
+		drained, err := drain.Node(ctx, c, reader, server.NodeName())
+		if err != nil {
+			return 0, err
+		}
+		if !drained {
+			return thermalDrainInterval, nil
+		}
+	}
+
+	patch := client.MergeFrom(server.DeepCopy())
+	server.Spec.PowerState = baremetalcontrollerv1.PowerStateOff
+	if err := r.Patch(ctx, server, patch); err != nil {
+		return 0, fmt.Errorf("failed to power off server: %w", err)
+	}
+	log.FromContext(ctx).Info("Powering off overheating server", "server", server.Name, "reason", critical)
+	r.event(server, corev1.EventTypeWarning, "ThermalShutdown", "Powering off: %s", critical)
+	return 0, nil
+}
+
+// cancelThermalShutdown uncordons the node of a server that is no longer
+// shut down for heat and removes the annotation
+func (r *ServerReconciler) cancelThermalShutdown(ctx context.Context, server *baremetalcontrollerv1.Server) error {
+	c, _, err := nodeClients(ctx, r.Clusters, server, r.Client, r.apiReader())
+	if err != nil {
+		return err
+	}
+	if err := drain.Uncordon(ctx, c, server.NodeName()); err != nil {
+		return err
+	}
+	patch := client.MergeFrom(server.DeepCopy())
+	delete(server.Annotations, baremetalcontrollerv1.ThermalShutdownAnnotation)
+	if err := r.Patch(ctx, server, patch); err != nil {
+		return err
+	}
+	r.event(server, corev1.EventTypeNormal, "ThermalRecovered", "Temperatures are below critical")
+	return nil
+}
+
+// thermalPolicy returns the thermal policy of the server's class, if any
+func (r *ServerReconciler) thermalPolicy(ctx context.Context, server *baremetalcontrollerv1.Server) *baremetalcontrollerv1.ThermalPolicy {
+	if server.Spec.ServerClassName == "" {
+		return nil
+	}
+	var class baremetalcontrollerv1.ServerClass
+	if err := r.Get(ctx, types.NamespacedName{Name: server.Spec.ServerClassName}, &class); err != nil {
+		return nil
+	}
+	return class.Spec.Thermal
+}
+
+func (r *ServerReconciler) getTemperatures(ctx context.Context, server *baremetalcontrollerv1.Server) ([]power.Temperature, error) {
+	switch server.Spec.Type {
+	case baremetalcontrollerv1.ControlTypeIPMI:
+		target, err := r.endpoints().IPMI(ctx, server)
+		if err != nil {
+			return nil, err
+		}
+		return r.IPMIClient.GetTemperatures(ctx, target.Address, target.Username, target.Password)
+
+	case baremetalcontrollerv1.ControlTypeRedfish:
+		target, err := r.endpoints().Redfish(ctx, server)
+		if err != nil {
+			return nil, err
+		}
+		return r.RedfishClient.GetTemperatures(target)
+	}
+	return nil, fmt.Errorf("temperature sensors require the ipmi or redfish control type")
+}
+
+// setThermalStatus records the readings and when a sensor became critical.
+// It returns a description of the critical sensors, or "" if there are none.
+func setThermalStatus(server *baremetalcontrollerv1.Server, policy *baremetalcontrollerv1.ThermalPolicy, temperatures []power.Temperature) string {
+	now := metav1.Now()
+	status := &baremetalcontrollerv1.ThermalStatus{LastUpdated: &now}
+	if server.Status.Thermal != nil {
+		status.CriticalSince = server.Status.Thermal.CriticalSince
+	}
+
+	var critical []string
+	for _, t := range temperatures {
+		sensor := baremetalcontrollerv1.TemperatureSensor{
+			Name:            t.Name,
+			Celsius:         int32(math.Round(t.Celsius)),
+			CriticalCelsius: int32(math.Round(t.CriticalCelsius)),
+		}
+		if policy.CriticalCelsius != nil {
+			sensor.CriticalCelsius = *policy.CriticalCelsius
+		}
+		if sensor.CriticalCelsius > 0 && sensor.Celsius >= sensor.CriticalCelsius {
+			critical = append(critical, fmt.Sprintf("%s at %d°C (critical %d°C)", sensor.Name, sensor.Celsius, sensor.CriticalCelsius))
+		}
+		status.Sensors = append(status.Sensors, sensor)
+	}
+
+	switch {
+	case len(critical) == 0:
+		status.CriticalSince = nil
+	case status.CriticalSince == nil:
+		status.CriticalSince = &now
+	}
+	server.Status.Thermal = status
+	return strings.Join(critical, ", ")
+}
+
+func setThermalCondition(server *baremetalcontrollerv1.Server, critical string, sustained bool) {
+	condition := metav1.Condition{
+		Type:               baremetalcontrollerv1.ConditionThermalCritical,
+		Status:             metav1.ConditionFalse,
+		Reason:             "BelowCritical",
+		Message:            "All temperature sensors are below critical",
+		ObservedGeneration: server.Generation,
+	}
+	if critical != "" {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "Critical"
+		condition.Message = critical
+		if sustained {
+			condition.Reason = "SustainedCritical"
+		}
+	}
+	meta.SetStatusCondition(&server.Status.Conditions, condition)
+}
+
+// event emits an event on the server, if a recorder is configured
+func (r *ServerReconciler) event(server *baremetalcontrollerv1.Server, eventType string, reason string, format string, args ...interface{}) {
+	if r.Recorder != nil {
+		r.Recorder.Eventf(server, eventType, reason, format, args...)
+	}
+}
+
+// apiReader returns the reader pods are listed with when draining
+func (r *ServerReconciler) apiReader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+// defaultFailureThreshold is the number of failed checks in a row before a
+// server is marked failed, unless overridden by spec.timeouts
+const defaultFailureThreshold = 3
+
+// failureThreshold returns the number of failed checks in a row before the
+// server is marked failed
+func failureThreshold(server *baremetalcontrollerv1.Server) int {
+	if t := server.Spec.Timeouts; t != nil && t.FailureThreshold > 0 {
+		return int(t.FailureThreshold)
+	}
+	return defaultFailureThreshold
+}
+
+// waitTimeout returns how long a server may wait to boot or shut down in its
+// current status, 0 if only its failed checks count
+func waitTimeout(server *baremetalcontrollerv1.Server) time.Duration {
+	t := server.Spec.Timeouts
+	if t == nil {
+		return 0
+	}
+	var timeout *metav1.Duration
+	switch server.Status.Status {
+	case baremetalcontrollerv1.StatusPending:
+		timeout = t.BootTimeout
+	case baremetalcontrollerv1.StatusDraining:
+		timeout = t.ShutdownTimeout
+	}
+	if timeout == nil {
+		return 0
+	}
+	return timeout.Duration
+}
+
+// failedTooOften reports whether the server failed enough checks in a row,
+// and has been failing for longer than its boot or shutdown timeout, to be
+// marked failed
+func failedTooOften(server *baremetalcontrollerv1.Server, now time.Time) bool {
+	if server.Status.FailureCount < failureThreshold(server) {
+		return false
+	}
+	timeout := waitTimeout(server)
+	if timeout == 0 || server.Status.FailingSince == nil {
+		return true
+	}
+	return now.Sub(server.Status.FailingSince.Time) >= timeout
+}
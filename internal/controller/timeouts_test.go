@@ -0,0 +1,114 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+func TestFailedTooOften(t *testing.T) {
+	now := time.Now()
+	minutesAgo := func(m int) *metav1.Time {
+		since := metav1.NewTime(now.Add(-time.Duration(m) * time.Minute))
+		return &since
+	}
+	tests := []struct {
+		name     string
+		status   baremetalcontrollerv1.CurrentStatus
+		timeouts *baremetalcontrollerv1.TimeoutsSpec
+		failures int
+		since    *metav1.Time
+		want     bool
+	}{
+		{name: "below the default threshold", status: baremetalcontrollerv1.StatusPending, failures: 2, since: minutesAgo(2)},
+		{name: "default threshold", status: baremetalcontrollerv1.StatusPending, failures: 3, since: minutesAgo(3), want: true},
+		{
+			name: "below a raised threshold", status: baremetalcontrollerv1.StatusPending, failures: 4, since: minutesAgo(4),
+			timeouts: &baremetalcontrollerv1.TimeoutsSpec{FailureThreshold: 5},
+		},
+		{
+			name: "threshold reached within the boot timeout", status: baremetalcontrollerv1.StatusPending, failures: 3, since: minutesAgo(3),
+			timeouts: &baremetalcontrollerv1.TimeoutsSpec{BootTimeout: &metav1.Duration{Duration: 15 * time.Minute}},
+		},
+		{
+			name: "past the boot timeout", status: baremetalcontrollerv1.StatusPending, failures: 16, since: minutesAgo(16),
+			timeouts: &baremetalcontrollerv1.TimeoutsSpec{BootTimeout: &metav1.Duration{Duration: 15 * time.Minute}},
+			want:     true,
+		},
+		{
+			name: "past the boot timeout below the threshold", status: baremetalcontrollerv1.StatusPending, failures: 2, since: minutesAgo(20),
+			timeouts: &baremetalcontrollerv1.TimeoutsSpec{BootTimeout: &metav1.Duration{Duration: 15 * time.Minute}},
+		},
+		{
+			name: "boot timeout doesn't apply to draining servers", status: baremetalcontrollerv1.StatusDraining, failures: 3, since: minutesAgo(3),
+			timeouts: &baremetalcontrollerv1.TimeoutsSpec{BootTimeout: &metav1.Duration{Duration: 15 * time.Minute}},
+			want:     true,
+		},
+		{
+			name: "within the shutdown timeout", status: baremetalcontrollerv1.StatusDraining, failures: 3, since: minutesAgo(3),
+			timeouts: &baremetalcontrollerv1.TimeoutsSpec{ShutdownTimeout: &metav1.Duration{Duration: 10 * time.Minute}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &baremetalcontrollerv1.Server{
+				Spec: baremetalcontrollerv1.ServerSpec{Timeouts: tt.timeouts},
+				Status: baremetalcontrollerv1.ServerStatus{
+					Status:       tt.status,
+					FailureCount: tt.failures,
+					FailingSince: tt.since,
+				},
+			}
+			if got := failedTooOften(server, now); got != tt.want {
+				t.Errorf("failedTooOften() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequeueInterval(t *testing.T) {
+	server := &baremetalcontrollerv1.Server{}
+	if got := requeueInterval(server); got != defaultRequeueInterval {
+		t.Errorf("requeueInterval() = %v, want %v", got, defaultRequeueInterval)
+	}
+	server.Spec.ReconcileInterval = &metav1.Duration{Duration: 5 * time.Minute}
+	if got := requeueInterval(server); got != 5*time.Minute {
+		t.Errorf("requeueInterval() = %v, want reconcileInterval", got)
+	}
+	server.Spec.Timeouts = &baremetalcontrollerv1.TimeoutsSpec{RequeueInterval: &metav1.Duration{Duration: 20 * time.Second}}
+	if got := requeueInterval(server); got != 20*time.Second {
+		t.Errorf("requeueInterval() = %v, want timeouts.requeueInterval", got)
+	}
+}
+
+func TestPowerRetryDelay(t *testing.T) {
+	for failures, want := range map[int]time.Duration{
+		1:  powerRetryInterval,
+		2:  2 * powerRetryInterval,
+		3:  4 * powerRetryInterval,
+		50: maxPowerRetryInterval,
+	} {
+		if got := powerRetryDelay(failures); got != want {
+			t.Errorf("powerRetryDelay(%d) = %v, want %v", failures, got, want)
+		}
+	}
+}
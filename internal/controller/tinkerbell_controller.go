@@ -0,0 +1,146 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/tinkerbell"
+)
+
+// TinkerbellReconciler delegates provisioning of Servers to an existing
+// Tinkerbell stack. It only manages Hardware and Workflow objects and the
+// provisioning status; power state stays with ServerReconciler.
+type TinkerbellReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=tinkerbell.org,resources=hardware;workflows,verbs=get;list;watch;create;update;patch
+
+// Reconcile ensures the Hardware and Workflow for a Server exist and mirrors
+// the workflow state into the Server status.
+func (r *TinkerbellReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var server baremetalcontrollerv1.Server
+	if err := r.Get(ctx, req.NamespacedName, &server); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !hasTinkerbellProvisioning(&server) {
+		return ctrl.Result{}, nil
+	}
+
+	hardware, err := tinkerbell.HardwareForServer(&server)
+	if err != nil {
+		// Wait for the spec to be fixed, which triggers a new reconcile
+		logger.Error(err, "Invalid Tinkerbell provisioning spec", "server", server.Name)
+		return ctrl.Result{}, nil
+	}
+	if err := r.ensureObject(ctx, &server, hardware); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to ensure hardware: %w", err)
+	}
+
+	workflow := tinkerbell.WorkflowForServer(&server)
+	if err := r.ensureObject(ctx, &server, workflow); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to ensure workflow: %w", err)
+	}
+
+	state := tinkerbell.WorkflowState(workflow)
+
+	// Stop netbooting once the OS is installed
+	if state == tinkerbell.StateSuccess && tinkerbell.DisableNetboot(hardware) {
+		if err := r.Update(ctx, hardware); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to disable netboot: %w", err)
+		}
+		logger.Info("Provisioning complete, disabled netboot", "server", server.Name)
+	}
+
+	workflowKey := workflow.GetNamespace() + "/" + workflow.GetName()
+	if err := r.setProvisioningStatus(ctx, &server, workflowKey, state); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if tinkerbell.IsTerminal(state) {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: 60 * time.Second}, nil
+}
+
+// ensureObject creates obj if it doesn't exist, otherwise loads the existing
+// object into obj. Existing objects are not modified so users can tweak them.
+func (r *TinkerbellReconciler) ensureObject(ctx context.Context, server *baremetalcontrollerv1.Server, obj *unstructured.Unstructured) error {
+	key := types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}
+	err := r.Get(ctx, key, obj)
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	if err := controllerutil.SetControllerReference(server, obj, r.Scheme); err != nil {
+		return err
+	}
+	return r.Create(ctx, obj)
+}
+
+func (r *TinkerbellReconciler) setProvisioningStatus(ctx context.Context, server *baremetalcontrollerv1.Server, workflow string, state string) error {
+	current := server.Status.Provisioning
+	if current != nil && current.Workflow == workflow && current.State == state {
+		return nil
+	}
+
+	// Only status.provisioning is applied, the rest belongs to ServerReconciler
+	server.Status.Provisioning = &baremetalcontrollerv1.ProvisioningStatus{
+		Workflow: workflow,
+		State:    state,
+	}
+	return applyServerStatus(ctx, r.Client, server, baremetalcontrollerv1.ServerStatus{
+		Provisioning: server.Status.Provisioning,
+	}, tinkerbellFieldManager)
+}
+
+func hasTinkerbellProvisioning(server *baremetalcontrollerv1.Server) bool {
+	return server.Spec.Provisioning != nil && server.Spec.Provisioning.Tinkerbell != nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *TinkerbellReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&baremetalcontrollerv1.Server{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			server, ok := obj.(*baremetalcontrollerv1.Server)
+			return ok && hasTinkerbellProvisioning(server)
+		}))).
+		Named("tinkerbell").
+		Complete(r)
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/power"
+)
+
+// Watchdog timeouts, the longest being the countdown limit of IPMI
+const (
+	defaultWatchdogTimeout = 5 * time.Minute
+	maxWatchdogTimeout     = 6553 * time.Second
+)
+
+// watchdogActions maps spec actions to the actions of the power clients
+var watchdogActions = map[baremetalcontrollerv1.WatchdogAction]string{
+	baremetalcontrollerv1.WatchdogActionReset:      power.WatchdogActionReset,
+	baremetalcontrollerv1.WatchdogActionPowerCycle: power.WatchdogActionPowerCycle,
+	baremetalcontrollerv1.WatchdogActionPowerOff:   power.WatchdogActionPowerOff,
+}
+
+// reconcileWatchdog arms the BMC watchdog with spec.watchdog once per boot,
+// after the server went active. The BMC stops the watchdog when it expires,
+// so it is armed again at the next boot. Removing the spec disarms it. It
+// returns true if the status changed.
+func (r *ServerReconciler) reconcileWatchdog(ctx context.Context, server *baremetalcontrollerv1.Server) bool {
+	condition := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionWatchdogArmed)
+	if server.Spec.Watchdog == nil {
+		if condition == nil {
+			return false
+		}
+		if condition.Status == metav1.ConditionTrue {
+			if err := r.setWatchdog(ctx, server, nil); err != nil {
+				log.FromContext(ctx).Error(err, "Failed to disarm watchdog", "server", server.Name)
+				return false
+			}
+			r.event(server, corev1.EventTypeNormal, "WatchdogDisarmed", "Disarmed the BMC watchdog")
+		}
+		meta.RemoveStatusCondition(&server.Status.Conditions, baremetalcontrollerv1.ConditionWatchdogArmed)
+		return true
+	}
+
+	before := server.Status.DeepCopy()
+	setArmed := func(status metav1.ConditionStatus, reason string, message string) {
+		meta.SetStatusCondition(&server.Status.Conditions, metav1.Condition{
+			Type:               baremetalcontrollerv1.ConditionWatchdogArmed,
+			Status:             status,
+			Reason:             reason,
+			Message:            message,
+			ObservedGeneration: server.Generation,
+		})
+	}
+
+	if server.Status.Status != baremetalcontrollerv1.StatusActive {
+		// Armed again once the server is back
+		if condition != nil && condition.Status == metav1.ConditionTrue {
+			setArmed(metav1.ConditionFalse, "NotRunning", "Waiting for the server to boot")
+		}
+		return !equality.Semantic.DeepEqual(before, &server.Status)
+	}
+	// Failed attempts other than BMC errors are retried once the spec changes
+	if condition != nil && condition.ObservedGeneration == server.Generation &&
+		(condition.Status == metav1.ConditionTrue || condition.Status == metav1.ConditionFalse && condition.Reason != "NotRunning") {
+		return false
+	}
+
+	spec := server.Spec.Watchdog
+	timeout := watchdogTimeout(spec)
+	if timeout < time.Second || timeout > maxWatchdogTimeout {
+		setArmed(metav1.ConditionFalse, "InvalidTimeout",
+			fmt.Sprintf("Timeout must be between 1s and %s", maxWatchdogTimeout))
+		return !equality.Semantic.DeepEqual(before, &server.Status)
+	}
+	if server.Spec.Type != baremetalcontrollerv1.ControlTypeIPMI && server.Spec.Type != baremetalcontrollerv1.ControlTypeRedfish {
+		setArmed(metav1.ConditionFalse, "Unsupported", "The watchdog requires the ipmi or redfish control type")
+		return !equality.Semantic.DeepEqual(before, &server.Status)
+	}
+
+	if err := r.setWatchdog(ctx, server, spec); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to arm watchdog", "server", server.Name)
+		setArmed(metav1.ConditionUnknown, "WatchdogUnavailable", err.Error())
+		return !equality.Semantic.DeepEqual(before, &server.Status)
+	}
+	r.event(server, corev1.EventTypeNormal, "WatchdogArmed", "Armed the BMC watchdog for %s with %s", timeout, spec.Action)
+	setArmed(metav1.ConditionTrue, "Armed",
+		fmt.Sprintf("The BMC takes action %s if the OS doesn't reset the watchdog within %s", spec.Action, timeout))
+	return true
+}
+
+// disarmWatchdog stops an armed watchdog before a deliberate power off, so
+// the BMC doesn't power the server back on
+func (r *ServerReconciler) disarmWatchdog(ctx context.Context, server *baremetalcontrollerv1.Server) error {
+	condition := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionWatchdogArmed)
+	if condition == nil || condition.Status != metav1.ConditionTrue {
+		return nil
+	}
+	if err := r.setWatchdog(ctx, server, nil); err != nil {
+		return fmt.Errorf("failed to disarm watchdog: %w", err)
+	}
+	meta.SetStatusCondition(&server.Status.Conditions, metav1.Condition{
+		Type:               baremetalcontrollerv1.ConditionWatchdogArmed,
+		Status:             metav1.ConditionFalse,
+		Reason:             "NotRunning",
+		Message:            "Disarmed for power off",
+		ObservedGeneration: server.Generation,
+	})
+	return nil
+}
+
+// setWatchdog arms the watchdog of the BMC with the spec, or disarms it for
+// nil
+func (r *ServerReconciler) setWatchdog(ctx context.Context, server *baremetalcontrollerv1.Server, spec *baremetalcontrollerv1.WatchdogSpec) error {
+	var timeout time.Duration
+	var action string
+	if spec != nil {
+		timeout = watchdogTimeout(spec)
+		action = watchdogActions[spec.Action]
+		if action == "" {
+			action = power.WatchdogActionReset
+		}
+	}
+
+	switch server.Spec.Type {
+	case baremetalcontrollerv1.ControlTypeIPMI:
+		target, err := r.endpoints().IPMI(ctx, server)
+		if err != nil {
+			return err
+		}
+		return r.IPMIClient.SetWatchdog(ctx, target.Address, target.Username, target.Password, timeout, action)
+
+	case baremetalcontrollerv1.ControlTypeRedfish:
+		target, err := r.endpoints().Redfish(ctx, server)
+		if err != nil {
+			return err
+		}
+		return r.RedfishClient.SetWatchdog(target, action)
+	}
+	return fmt.Errorf("the watchdog requires the ipmi or redfish control type")
+}
+
+func watchdogTimeout(spec *baremetalcontrollerv1.WatchdogSpec) time.Duration {
+	if spec.Timeout != nil {
+		return spec.Timeout.Duration
+	}
+	return defaultWatchdogTimeout
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+// trackWear counts a power cycle when a server comes online and adds the
+// time it was online to its runtime when it leaves active. Servers that
+// are online when first seen count as one cycle from then on.
+func trackWear(server *baremetalcontrollerv1.Server, now time.Time) {
+	active := server.Status.Status == baremetalcontrollerv1.StatusActive
+	wear := server.Status.Wear
+	switch {
+	case active && (wear == nil || wear.ActiveSince == nil):
+		if wear == nil {
+			wear = &baremetalcontrollerv1.WearStatus{}
+			server.Status.Wear = wear
+		}
+		wear.PowerCycles++
+		wear.ActiveSince = &metav1.Time{Time: now}
+	case !active && wear != nil && wear.ActiveSince != nil:
+		wear.Runtime.Duration = wear.TotalRuntime(now)
+		wear.ActiveSince = nil
+	}
+	if wear != nil {
+		setWearMetrics(client.ObjectKeyFromObject(server), wear, now)
+	}
+}
@@ -0,0 +1,198 @@
+package dashboard
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/serving"
+)
+
+// PathPrefix is the URL path the dashboard is served under. Access is
+// authorized as the non-resource URL with verb get.
+const PathPrefix = "/dashboard/"
+
+// tokenCookie holds the bearer token entered on the login page, since
+// browsers can't send an Authorization header on their own.
+const tokenCookie = "baremetal-dashboard-token"
+
+// Options contains configuration for the web dashboard.
+type Options struct {
+	// Address is the address to listen on (e.g., ":8088"), "0" to disable
+	Address string
+
+	// CertFile is the path to the TLS certificate file
+	CertFile string
+
+	// KeyFile is the path to the TLS key file
+	KeyFile string
+}
+
+// DefaultOptions returns the default dashboard options.
+func DefaultOptions() Options {
+	return Options{
+		Address: "0",
+	}
+}
+
+// BindFlags binds the dashboard options to command line flags.
+// The prefix can be used to namespace the flags (e.g., "dashboard-").
+func (o *Options) BindFlags(fs *flag.FlagSet, prefix string) {
+	fs.StringVar(&o.Address, prefix+"bind-address", o.Address,
+		"The address the web dashboard binds to. Use 0 to disable it.")
+	fs.StringVar(&o.CertFile, prefix+"cert", o.CertFile,
+		"Path to TLS certificate file for the dashboard. Empty for a self-signed certificate.")
+	fs.StringVar(&o.KeyFile, prefix+"key", o.KeyFile,
+		"Path to TLS key file for the dashboard. Empty for a self-signed certificate.")
+}
+
+// Validate validates the options.
+func (o *Options) Validate() error {
+	if (o.CertFile == "") != (o.KeyFile == "") {
+		return fmt.Errorf("dashboard cert and key must be set together, or neither")
+	}
+	return nil
+}
+
+// Enabled returns true if the dashboard should be started.
+func (o *Options) Enabled() bool {
+	return o.Address != "" && o.Address != "0"
+}
+
+// Server implements manager.Runnable and serves a read-only overview of all
+// Servers. Like the console proxy, requests are authenticated with a bearer
+// token through a TokenReview and authorized with a SubjectAccessReview
+// against PathPrefix.
+type Server struct {
+	options Options
+	client  client.Client
+	mgr     manager.Manager
+	log     logr.Logger
+}
+
+// Ensure Server implements manager.Runnable
+var _ manager.Runnable = &Server{}
+
+// NewServer creates a new dashboard runnable.
+func NewServer(opts Options, mgr manager.Manager) (*Server, error) {
+	return &Server{
+		options: opts,
+		client:  mgr.GetClient(),
+		mgr:     mgr,
+		log:     ctrl.Log.WithName("dashboard"),
+	}, nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. The
+// dashboard is served by every replica.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable and serves the dashboard until the
+// context is cancelled.
+func (s *Server) Start(ctx context.Context) error {
+	filter, err := filters.WithAuthenticationAndAuthorization(s.mgr.GetConfig(), s.mgr.GetHTTPClient())
+	if err != nil {
+		return fmt.Errorf("failed to create dashboard authorization filter: %w", err)
+	}
+
+	protected := http.NewServeMux()
+	protected.HandleFunc(PathPrefix, s.serveDashboard)
+	authorized, err := filter(s.log, protected)
+	if err != nil {
+		return fmt.Errorf("failed to wrap dashboard handler: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", s.serveLogin)
+	mux.HandleFunc("/logout", serveLogout)
+	mux.Handle(PathPrefix, withTokenCookie(authorized))
+	mux.Handle("/", http.RedirectHandler(PathPrefix, http.StatusFound))
+
+	if err := serving.ServeTLS(ctx, s.options.Address, s.options.CertFile, s.options.KeyFile, "bare-metal-controller-dashboard", mux); err != nil {
+		return fmt.Errorf("dashboard server error: %w", err)
+	}
+	return nil
+}
+
+// withTokenCookie passes the token from the login cookie on as a bearer
+// token, and sends browsers without either to the login page.
+func withTokenCookie(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") == "" {
+			cookie, err := req.Cookie(tokenCookie)
+			if err != nil || cookie.Value == "" {
+				http.Redirect(w, req, "/login", http.StatusFound)
+				return
+			}
+			req.Header.Set("Authorization", "Bearer "+cookie.Value)
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+func (s *Server) serveLogin(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		render(w, loginTemplate, nil)
+	case http.MethodPost:
+		token := req.PostFormValue("token")
+		if token == "" {
+			http.Redirect(w, req, "/login", http.StatusFound)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     tokenCookie,
+			Value:    token,
+			Path:     "/",
+			Secure:   true,
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		})
+		http.Redirect(w, req, PathPrefix, http.StatusFound)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func serveLogout(w http.ResponseWriter, req *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     tokenCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   true,
+		HttpOnly: true,
+	})
+	http.Redirect(w, req, "/login", http.StatusFound)
+}
+
+func (s *Server) serveDashboard(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != PathPrefix {
+		http.NotFound(w, req)
+		return
+	}
+
+	var servers baremetalcontrollerv1.ServerList
+	// summarize only reads the servers, so skip copying them out of the cache
+	if err := s.client.List(req.Context(), &servers, client.UnsafeDisableDeepCopy); err != nil {
+		s.log.Error(err, "Failed to list servers")
+		http.Error(w, fmt.Sprintf("failed to list servers: %v", err), http.StatusInternalServerError)
+		return
+	}
+	// The energy figures are left out rather than failing the page
+	var reports baremetalcontrollerv1.EnergyReportList
+	if err := s.client.List(req.Context(), &reports, client.UnsafeDisableDeepCopy); err != nil {
+		s.log.Error(err, "Failed to list energy reports")
+	}
+	render(w, dashboardTemplate, summarize(servers.Items, latestReport(reports.Items), time.Now()))
+}
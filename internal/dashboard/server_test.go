@@ -0,0 +1,282 @@
+package dashboard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+func server(name string, power baremetalcontrollerv1.PowerState, status baremetalcontrollerv1.CurrentStatus, readySince time.Time) baremetalcontrollerv1.Server {
+	s := baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       baremetalcontrollerv1.ServerSpec{PowerState: power, Type: baremetalcontrollerv1.ControlTypeIPMI},
+		Status:     baremetalcontrollerv1.ServerStatus{Status: status},
+	}
+	if !readySince.IsZero() {
+		s.Status.Conditions = []metav1.Condition{{
+			Type:               baremetalcontrollerv1.ConditionReady,
+			Status:             metav1.ConditionTrue,
+			Reason:             "PowerStateReached",
+			LastTransitionTime: metav1.NewTime(readySince),
+		}}
+	}
+	return s
+}
+
+func TestSummarize(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	failed := server("worker-03", baremetalcontrollerv1.PowerStateOn, baremetalcontrollerv1.StatusFailed, now.Add(-time.Minute))
+	failed.Status.Reason = baremetalcontrollerv1.ReasonWOLSendFailed
+	failed.Status.FailingSince = &metav1.Time{Time: now.Add(-2 * time.Hour)}
+	failed.Status.Hardware = &baremetalcontrollerv1.HardwareStatus{Manufacturer: "Dell", Model: "R650"}
+	// The reading from before the failure is stale
+	failed.Status.PowerCap = &baremetalcontrollerv1.PowerCapStatus{ConsumedWatts: 150}
+	active := server("worker-02", baremetalcontrollerv1.PowerStateOn, baremetalcontrollerv1.StatusActive, now.Add(-time.Hour))
+	active.Status.PowerCap = &baremetalcontrollerv1.PowerCapStatus{ConsumedWatts: 312}
+	report := &baremetalcontrollerv1.EnergyReport{
+		ObjectMeta: metav1.ObjectMeta{Name: "energy-20250531-0000"},
+		Spec: baremetalcontrollerv1.EnergyReportSpec{
+			Start:    metav1.NewTime(time.Date(2025, 5, 31, 0, 0, 0, 0, time.UTC)),
+			End:      metav1.NewTime(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)),
+			Currency: "EUR",
+		},
+		Status: baremetalcontrollerv1.EnergyReportStatus{
+			Total:   baremetalcontrollerv1.EnergyUsage{EnergyWattHours: 9850, Cost: "2.46"},
+			Servers: []baremetalcontrollerv1.EnergyUsage{{Name: "worker-02", EnergyWattHours: 7488}},
+		},
+	}
+
+	summary := summarize([]baremetalcontrollerv1.Server{
+		active,
+		failed,
+		server("worker-01", baremetalcontrollerv1.PowerStateOff, "", time.Time{}),
+	}, report, now)
+
+	if summary.Total != 3 || summary.PoweredOn != 2 || summary.PoweredOff != 1 {
+		t.Errorf("totals = %d, %d on, %d off", summary.Total, summary.PoweredOn, summary.PoweredOff)
+	}
+	wantStatus := []StatusCount{{"active", 1}, {"failed", 1}, {"unknown", 1}}
+	if len(summary.ByStatus) != len(wantStatus) {
+		t.Fatalf("ByStatus = %v, want %v", summary.ByStatus, wantStatus)
+	}
+	for i := range wantStatus {
+		if summary.ByStatus[i] != wantStatus[i] {
+			t.Errorf("ByStatus = %v, want %v", summary.ByStatus, wantStatus)
+		}
+	}
+
+	if len(summary.Servers) != 3 || summary.Servers[0].Name != "worker-01" || summary.Servers[2].Name != "worker-03" {
+		t.Errorf("Servers = %v, want them sorted by name", summary.Servers)
+	}
+	if summary.Servers[0].Ready != "Unknown" || summary.Servers[1].Ready != "True" {
+		t.Errorf("Ready = %s, %s", summary.Servers[0].Ready, summary.Servers[1].Ready)
+	}
+	if summary.Servers[2].Model != "Dell R650" {
+		t.Errorf("Model = %q", summary.Servers[2].Model)
+	}
+
+	if summary.DrawWatts != 312 || summary.Metered != 1 || summary.Servers[1].Draw != "312 W" || summary.Servers[2].Draw != "" {
+		t.Errorf("draw = %d W of %d servers, rows %q, %q", summary.DrawWatts, summary.Metered, summary.Servers[1].Draw, summary.Servers[2].Draw)
+	}
+	wantEnergy := EnergyFigures{Report: "energy-20250531-0000", Period: "2025-05-31 00:00 to 2025-06-01 00:00", KWh: "9.8", Cost: "2.46", Currency: "EUR"}
+	if summary.Energy == nil || *summary.Energy != wantEnergy {
+		t.Errorf("Energy = %+v, want %+v", summary.Energy, wantEnergy)
+	}
+	if summary.Servers[1].Energy != "7.5 kWh" || summary.Servers[0].Energy != "" {
+		t.Errorf("server energy = %q, %q", summary.Servers[0].Energy, summary.Servers[1].Energy)
+	}
+
+	if len(summary.Failures) != 1 || summary.Failures[0].Since != "120m" || summary.Failures[0].Reason != "WOLSendFailed" {
+		t.Errorf("Failures = %+v", summary.Failures)
+	}
+	// Newest transition first, servers without a Ready condition left out
+	if len(summary.Transitions) != 2 || summary.Transitions[0].Name != "worker-03" || summary.Transitions[0].Ago != "60s" {
+		t.Errorf("Transitions = %+v", summary.Transitions)
+	}
+}
+
+func TestSummarizeLimitsTransitions(t *testing.T) {
+	now := time.Now()
+	var servers []baremetalcontrollerv1.Server
+	for i := 0; i < maxTransitions+5; i++ {
+		servers = append(servers, server("worker", baremetalcontrollerv1.PowerStateOn, baremetalcontrollerv1.StatusActive, now.Add(-time.Duration(i)*time.Minute)))
+	}
+	if got := len(summarize(servers, nil, now).Transitions); got != maxTransitions {
+		t.Errorf("%d transitions, want %d", got, maxTransitions)
+	}
+}
+
+func TestLatestReport(t *testing.T) {
+	report := func(name string, end time.Time) baremetalcontrollerv1.EnergyReport {
+		return baremetalcontrollerv1.EnergyReport{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       baremetalcontrollerv1.EnergyReportSpec{End: metav1.NewTime(end)},
+		}
+	}
+	now := time.Now()
+
+	if latest := latestReport(nil); latest != nil {
+		t.Errorf("latestReport() = %s, want none", latest.Name)
+	}
+	latest := latestReport([]baremetalcontrollerv1.EnergyReport{
+		report("yesterday", now.Add(-24*time.Hour)),
+		report("today", now),
+		report("last-week", now.Add(-7*24*time.Hour)),
+	})
+	if latest == nil || latest.Name != "today" {
+		t.Errorf("latestReport() = %v, want the one ending last", latest)
+	}
+}
+
+func TestWithTokenCookie(t *testing.T) {
+	var gotAuth string
+	handler := withTokenCookie(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		gotAuth = req.Header.Get("Authorization")
+	}))
+
+	tests := []struct {
+		name         string
+		header       string
+		cookie       string
+		wantAuth     string
+		wantRedirect bool
+	}{
+		{name: "bearer token", header: "Bearer api", wantAuth: "Bearer api"},
+		{name: "cookie", cookie: "browser", wantAuth: "Bearer browser"},
+		{name: "header wins", header: "Bearer api", cookie: "browser", wantAuth: "Bearer api"},
+		{name: "neither", wantRedirect: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotAuth = ""
+			req := httptest.NewRequest(http.MethodGet, PathPrefix, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: tokenCookie, Value: tt.cookie})
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			if tt.wantRedirect {
+				if recorder.Code != http.StatusFound || recorder.Header().Get("Location") != "/login" {
+					t.Errorf("response %d to %q, want a redirect to /login", recorder.Code, recorder.Header().Get("Location"))
+				}
+				return
+			}
+			if gotAuth != tt.wantAuth {
+				t.Errorf("Authorization = %q, want %q", gotAuth, tt.wantAuth)
+			}
+		})
+	}
+}
+
+func TestServeLogin(t *testing.T) {
+	s := &Server{log: logr.Discard()}
+
+	recorder := httptest.NewRecorder()
+	s.serveLogin(recorder, httptest.NewRequest(http.MethodGet, "/login", nil))
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `name="token"`) {
+		t.Errorf("GET /login = %d, want the login form", recorder.Code)
+	}
+
+	form := url.Values{"token": {"secret-token"}}
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder = httptest.NewRecorder()
+	s.serveLogin(recorder, req)
+	if recorder.Header().Get("Location") != PathPrefix {
+		t.Errorf("POST /login redirects to %q, want the dashboard", recorder.Header().Get("Location"))
+	}
+	cookies := recorder.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value != "secret-token" || !cookies[0].HttpOnly || !cookies[0].Secure {
+		t.Errorf("cookies = %v, want a secure HttpOnly token cookie", cookies)
+	}
+
+	recorder = httptest.NewRecorder()
+	s.serveLogin(recorder, httptest.NewRequest(http.MethodDelete, "/login", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE /login = %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	serveLogout(recorder, httptest.NewRequest(http.MethodGet, "/logout", nil))
+	if cookies := recorder.Result().Cookies(); len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("logout cookies = %v, want the token cookie expired", cookies)
+	}
+}
+
+func TestServeDashboard(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := baremetalcontrollerv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	failed := server("worker-03", baremetalcontrollerv1.PowerStateOn, baremetalcontrollerv1.StatusFailed, time.Now())
+	failed.Status.Message = "<script>alert(1)</script>"
+	active := server("worker-01", baremetalcontrollerv1.PowerStateOn, baremetalcontrollerv1.StatusActive, time.Now())
+	active.Status.PowerCap = &baremetalcontrollerv1.PowerCapStatus{ConsumedWatts: 312}
+	report := &baremetalcontrollerv1.EnergyReport{
+		ObjectMeta: metav1.ObjectMeta{Name: "energy-20250531-0000"},
+		Spec:       baremetalcontrollerv1.EnergyReportSpec{Currency: "EUR"},
+		Status:     baremetalcontrollerv1.EnergyReportStatus{Total: baremetalcontrollerv1.EnergyUsage{EnergyWattHours: 9850, Cost: "2.46"}},
+	}
+	s := &Server{
+		client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(&failed, &active, report).Build(),
+		log:    logr.Discard(),
+	}
+
+	recorder := httptest.NewRecorder()
+	s.serveDashboard(recorder, httptest.NewRequest(http.MethodGet, PathPrefix, nil))
+	body := recorder.Body.String()
+	if recorder.Code != http.StatusOK || !strings.Contains(body, "Failures") {
+		t.Fatalf("GET %s = %d:\n%s", PathPrefix, recorder.Code, body)
+	}
+	if !strings.Contains(body, "312 W") || !strings.Contains(body, "9.8 kWh") || !strings.Contains(body, "2.46 EUR") {
+		t.Errorf("power draw and energy missing:\n%s", body)
+	}
+	// Status messages come from BMCs and must not be rendered as HTML
+	if strings.Contains(body, "<script>") || !strings.Contains(body, "&lt;script&gt;") {
+		t.Errorf("message not escaped:\n%s", body)
+	}
+	if recorder.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("Cache-Control = %q", recorder.Header().Get("Cache-Control"))
+	}
+
+	recorder = httptest.NewRecorder()
+	s.serveDashboard(recorder, httptest.NewRequest(http.MethodGet, PathPrefix+"servers", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("GET %sservers = %d, want 404", PathPrefix, recorder.Code)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	tests := []struct {
+		opts    Options
+		enabled bool
+		wantErr bool
+	}{
+		{opts: DefaultOptions()},
+		{opts: Options{Address: ":8088"}, enabled: true},
+		{opts: Options{Address: ":8088", CertFile: "tls.crt", KeyFile: "tls.key"}, enabled: true},
+		{opts: Options{Address: ":8088", KeyFile: "tls.key"}, enabled: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) error = %v, wantErr %v", tt.opts, err, tt.wantErr)
+		}
+		if tt.opts.Enabled() != tt.enabled {
+			t.Errorf("Enabled(%+v) = %v, want %v", tt.opts, !tt.enabled, tt.enabled)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package dashboard
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/util/duration"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/budget"
+)
+
+// maxTransitions is the number of recent transitions shown
+const maxTransitions = 20
+
+// Summary is the data rendered by the dashboard.
+type Summary struct {
+	Total      int
+	PoweredOn  int
+	PoweredOff int
+	// DrawWatts adds up the last BMC readings of the Metered powered on
+	// servers that have one
+	DrawWatts   int64
+	Metered     int
+	Energy      *EnergyFigures
+	ByStatus    []StatusCount
+	Failures    []ServerRow
+	Transitions []Transition
+	Servers     []ServerRow
+	GeneratedAt string
+}
+
+// StatusCount is the number of servers in a status.
+type StatusCount struct {
+	Status string
+	Count  int
+}
+
+// ServerRow is a single server in the fleet table.
+type ServerRow struct {
+	Name    string
+	Type    string
+	Power   string
+	Status  string
+	Ready   string
+	Reason  string
+	Message string
+	Model   string
+	Since   string
+	Draw    string
+	Energy  string
+}
+
+// EnergyFigures is the energy use of the fleet in the latest EnergyReport.
+type EnergyFigures struct {
+	Report   string
+	Period   string
+	KWh      string
+	Cost     string
+	Currency string
+}
+
+// Transition is the last change of a server's Ready condition.
+type Transition struct {
+	Name   string
+	Status string
+	Reason string
+	Ago    string
+	at     time.Time
+}
+
+// summarize builds the dashboard from a list of servers and the latest
+// EnergyReport, nil without one
+func summarize(servers []baremetalcontrollerv1.Server, report *baremetalcontrollerv1.EnergyReport, now time.Time) Summary {
+	summary := Summary{
+		Total:       len(servers),
+		GeneratedAt: now.UTC().Format(time.RFC3339),
+	}
+
+	energy := map[string]int64{}
+	if report != nil {
+		summary.Energy = &EnergyFigures{
+			Report:   report.Name,
+			Period:   report.Spec.Start.UTC().Format("2006-01-02 15:04") + " to " + report.Spec.End.UTC().Format("2006-01-02 15:04"),
+			KWh:      kWh(report.Status.Total.EnergyWattHours),
+			Cost:     report.Status.Total.Cost,
+			Currency: report.Spec.Currency,
+		}
+		for _, usage := range report.Status.Servers {
+			energy[usage.Name] = usage.EnergyWattHours
+		}
+	}
+
+	counts := map[string]int{}
+	for _, server := range servers {
+		if server.Spec.PowerState.Settled() == baremetalcontrollerv1.PowerStateOn {
+			summary.PoweredOn++
+		} else {
+			summary.PoweredOff++
+		}
+
+		status := string(server.Status.Status)
+		if status == "" {
+			status = "unknown"
+		}
+		counts[status]++
+
+		row := ServerRow{
+			Name:    server.Name,
+			Type:    string(server.Spec.Type),
+			Power:   string(server.Spec.PowerState),
+			Status:  status,
+			Ready:   "Unknown",
+			Reason:  string(server.Status.Reason),
+			Message: server.Status.Message,
+		}
+		if server.Status.Hardware != nil {
+			row.Model = strings.TrimSpace(server.Status.Hardware.Manufacturer + " " + server.Status.Hardware.Model)
+		}
+		// Readings of servers that were since powered off are stale
+		if reading := server.Status.PowerCap; reading != nil && reading.ConsumedWatts > 0 && budget.Powered(&server) {
+			row.Draw = fmt.Sprintf("%d W", reading.ConsumedWatts)
+			summary.DrawWatts += int64(reading.ConsumedWatts)
+			summary.Metered++
+		}
+		if wattHours, ok := energy[server.Name]; ok {
+			row.Energy = kWh(wattHours) + " kWh"
+		}
+
+		if ready := meta.FindStatusCondition(server.Status.Conditions, baremetalcontrollerv1.ConditionReady); ready != nil {
+			row.Ready = string(ready.Status)
+			summary.Transitions = append(summary.Transitions, Transition{
+				Name:   server.Name,
+				Status: status,
+				Reason: ready.Reason,
+				Ago:    duration.HumanDuration(now.Sub(ready.LastTransitionTime.Time)),
+				at:     ready.LastTransitionTime.Time,
+			})
+		}
+
+		summary.Servers = append(summary.Servers, row)
+		if server.Status.Status == baremetalcontrollerv1.StatusFailed {
+			if server.Status.FailingSince != nil {
+				row.Since = duration.HumanDuration(now.Sub(server.Status.FailingSince.Time))
+			}
+			summary.Failures = append(summary.Failures, row)
+		}
+	}
+
+	for status, count := range counts {
+		summary.ByStatus = append(summary.ByStatus, StatusCount{Status: status, Count: count})
+	}
+	sort.Slice(summary.ByStatus, func(i, j int) bool {
+		return summary.ByStatus[i].Status < summary.ByStatus[j].Status
+	})
+	sort.Slice(summary.Servers, func(i, j int) bool {
+		return summary.Servers[i].Name < summary.Servers[j].Name
+	})
+	sort.Slice(summary.Transitions, func(i, j int) bool {
+		return summary.Transitions[i].at.After(summary.Transitions[j].at)
+	})
+	if len(summary.Transitions) > maxTransitions {
+		summary.Transitions = summary.Transitions[:maxTransitions]
+	}
+	return summary
+}
+
+// latestReport returns the EnergyReport covering the most recent period, nil
+// without any
+func latestReport(reports []baremetalcontrollerv1.EnergyReport) *baremetalcontrollerv1.EnergyReport {
+	var latest *baremetalcontrollerv1.EnergyReport
+	for i := range reports {
+		if latest == nil || reports[i].Spec.End.After(latest.Spec.End.Time) {
+			latest = &reports[i]
+		}
+	}
+	return latest
+}
+
+func kWh(wattHours int64) string {
+	return fmt.Sprintf("%.1f", float64(wattHours)/1000)
+}
+
+func render(w http.ResponseWriter, tmpl *template.Template, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := tmpl.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+const style = `<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { text-align: left; padding: 0.3em 1em 0.3em 0; border-bottom: 1px solid #ddd; }
+.cards { display: flex; gap: 1em; margin-bottom: 2em; }
+.card { border: 1px solid #ddd; padding: 0.5em 1em; }
+.card b { display: block; font-size: 1.5em; }
+.failed { color: #b00; }
+</style>`
+
+var loginTemplate = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html><head><title>Bare Metal Dashboard</title>` + style + `</head>
+<body>
+<h1>Bare Metal Dashboard</h1>
+<form method="post" action="/login">
+<p>Paste a Kubernetes bearer token allowed to get <code>/dashboard/</code>:</p>
+<p><input type="password" name="token" size="60" autofocus> <button type="submit">Sign in</button></p>
+</form>
+</body></html>`))
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html><head><title>Bare Metal Dashboard</title>
+<meta http-equiv="refresh" content="30">` + style + `</head>
+<body>
+<h1>Bare Metal Dashboard</h1>
+<p>Updated {{.GeneratedAt}} &middot; <a href="/logout">Sign out</a></p>
+
+<div class="cards">
+<div class="card"><b>{{.Total}}</b>servers</div>
+<div class="card"><b>{{.PoweredOn}}</b>powered on</div>
+<div class="card"><b>{{.PoweredOff}}</b>powered off</div>
+{{if .Metered}}<div class="card"><b>{{.DrawWatts}} W</b>drawn by {{.Metered}} metered servers</div>
+{{end}}{{with .Energy}}<div class="card" title="EnergyReport {{.Report}}, {{.Period}} UTC"><b>{{.KWh}} kWh</b>{{.Cost}} {{.Currency}} in the last report</div>
+{{end}}{{range .ByStatus}}<div class="card"><b>{{.Count}}</b>{{.Status}}</div>
+{{end}}</div>
+
+{{if .Failures}}
+<h2 class="failed">Failures</h2>
+<table>
+<tr><th>Server</th><th>Reason</th><th>Failing for</th><th>Message</th></tr>
+{{range .Failures}}<tr><td>{{.Name}}</td><td>{{.Reason}}</td><td>{{.Since}}</td><td>{{.Message}}</td></tr>
+{{end}}</table>
+{{end}}
+
+<h2>Recent Transitions</h2>
+<table>
+<tr><th>Server</th><th>Status</th><th>Reason</th><th>When</th></tr>
+{{range .Transitions}}<tr><td>{{.Name}}</td><td>{{.Status}}</td><td>{{.Reason}}</td><td>{{.Ago}} ago</td></tr>
+{{end}}</table>
+
+<h2>Servers</h2>
+<table>
+<tr><th>Name</th><th>Type</th><th>Power</th><th>Status</th><th>Ready</th><th>Reason</th><th>Hardware</th><th>Draw</th><th>Energy</th></tr>
+{{range .Servers}}<tr><td>{{.Name}}</td><td>{{.Type}}</td><td>{{.Power}}</td><td{{if eq .Status "failed"}} class="failed"{{end}}>{{.Status}}</td><td>{{.Ready}}</td><td>{{.Reason}}</td><td>{{.Model}}</td><td>{{.Draw}}</td><td>{{.Energy}}</td></tr>
+{{end}}</table>
+</body></html>`))
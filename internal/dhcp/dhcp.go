@@ -0,0 +1,246 @@
+// Package dhcp tracks the DHCP leases of servers and records their current
+// OS addresses in status.addresses, since addresses configured in the spec
+// drift when servers get their addresses from DHCP.
+package dhcp
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/index"
+)
+
+// fieldManager owns status.addresses
+const fieldManager = "bare-metal-controller-dhcp"
+
+// Lease is an address a DHCP server leased to an interface
+type Lease struct {
+	MACAddress string
+	IPAddress  string
+	Hostname   string
+	// Expires is zero for infinite leases
+	Expires time.Time
+}
+
+// Source reads the current leases of a DHCP server, or other MAC to IP
+// address mappings such as neighbor scans
+type Source interface {
+	// Name identifies the source in status.addresses
+	Name() string
+	Leases(ctx context.Context) ([]Lease, error)
+}
+
+// Options contains configuration for DHCP lease tracking.
+type Options struct {
+	// KeaURL is the Kea Control Agent the leases are read from, which needs
+	// the lease_cmds hook loaded. Empty to not read leases from Kea.
+	KeaURL string
+
+	// RelayLeases reads the dnsmasq leases of every WoL relay agent
+	RelayLeases bool
+
+	// Interval is how often leases are read
+	Interval time.Duration
+}
+
+// DefaultOptions returns the default lease tracking options, with tracking
+// disabled.
+func DefaultOptions() Options {
+	return Options{
+		Interval: time.Minute,
+	}
+}
+
+// BindFlags binds the lease tracking options to command line flags.
+// The prefix can be used to namespace the flags (e.g., "dhcp-").
+func (o *Options) BindFlags(fs *flag.FlagSet, prefix string) {
+	fs.StringVar(&o.KeaURL, prefix+"kea-url", o.KeaURL,
+		"URL of the Kea Control Agent to read DHCPv4 leases from, with the lease_cmds hook loaded. Empty to disable.")
+	fs.BoolVar(&o.RelayLeases, prefix+"relay-leases", o.RelayLeases,
+		"If set, the dnsmasq leases of every WoL relay agent are read.")
+	fs.DurationVar(&o.Interval, prefix+"interval", o.Interval,
+		"How often DHCP leases are read and neighbors are scanned.")
+}
+
+// Validate validates the options.
+func (o *Options) Validate() error {
+	if !o.Enabled() {
+		return nil
+	}
+	if o.KeaURL != "" {
+		u, err := url.Parse(o.KeaURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid Kea URL %q", o.KeaURL)
+		}
+	}
+	if o.Interval <= 0 {
+		return fmt.Errorf("DHCP interval must be positive")
+	}
+	return nil
+}
+
+// Enabled returns true if leases should be tracked.
+func (o *Options) Enabled() bool {
+	return o.KeaURL != "" || o.RelayLeases
+}
+
+// Tracker implements manager.Runnable. It reads the leases of all sources
+// periodically and records the unexpired ones in the status of the servers
+// whose WoL or provisioning MAC addresses they were leased to. Servers
+// without a current lease keep their last addresses.
+type Tracker struct {
+	options Options
+	sources []Source
+	client  client.Client
+}
+
+// Ensure Tracker implements manager.Runnable
+var _ manager.Runnable = &Tracker{}
+
+// NewTracker creates a new lease tracker for the sources.
+func NewTracker(opts Options, sources []Source, mgr manager.Manager) (*Tracker, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no DHCP lease sources")
+	}
+	return &Tracker{
+		options: opts,
+		sources: sources,
+		client:  mgr.GetClient(),
+	}, nil
+}
+
+// Start reads the leases until the context is cancelled.
+func (t *Tracker) Start(ctx context.Context) error {
+	ticker := time.NewTicker(t.options.Interval)
+	defer ticker.Stop()
+	for {
+		t.sync(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync reads the leases of every source and updates the addresses of the
+// servers they belong to
+func (t *Tracker) sync(ctx context.Context, now time.Time) {
+	logger := log.FromContext(ctx).WithName("dhcp")
+
+	addresses := map[client.ObjectKey][]baremetalcontrollerv1.ServerAddress{}
+	servers := map[client.ObjectKey]*baremetalcontrollerv1.Server{}
+	// An address found by several sources is recorded from the first
+	seen := map[string]bool{}
+	for _, source := range t.sources {
+		leases, err := source.Leases(ctx)
+		if err != nil {
+			logger.Error(err, "Failed to read server addresses", "source", source.Name())
+			continue
+		}
+		for _, lease := range leases {
+			if !lease.Expires.IsZero() && !lease.Expires.After(now) {
+				continue
+			}
+			server, err := index.ServerByMAC(ctx, t.client, lease.MACAddress)
+			if err != nil {
+				logger.Error(err, "Failed to look up server of lease", "mac", lease.MACAddress)
+				continue
+			}
+			if server == nil {
+				continue
+			}
+			address := serverAddress(source.Name(), lease)
+			if key := address.MACAddress + "/" + address.Address; !seen[key] {
+				seen[key] = true
+				key := client.ObjectKeyFromObject(server)
+				servers[key] = server
+				addresses[key] = append(addresses[key], address)
+			}
+		}
+	}
+
+	for key, leased := range addresses {
+		server := servers[key]
+		sort.Slice(leased, func(i, j int) bool {
+			if leased[i].MACAddress != leased[j].MACAddress {
+				return leased[i].MACAddress < leased[j].MACAddress
+			}
+			return leased[i].Address < leased[j].Address
+		})
+		if sameAddresses(server.Status.Addresses, leased) {
+			continue
+		}
+		if err := t.apply(ctx, server, leased); err != nil {
+			logger.Error(err, "Failed to update server addresses", "server", server.Name)
+			continue
+		}
+		logger.Info("Updated discovered server addresses", "server", server.Name, "addresses", leased)
+	}
+}
+
+// apply writes status.addresses with server-side apply, leaving the rest of
+// the status to the server controller
+func (t *Tracker) apply(ctx context.Context, server *baremetalcontrollerv1.Server, addresses []baremetalcontrollerv1.ServerAddress) error {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&baremetalcontrollerv1.ServerStatus{Addresses: addresses})
+	if err != nil {
+		return fmt.Errorf("failed to convert server status: %w", err)
+	}
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"status": content}}
+	obj.SetGroupVersionKind(baremetalcontrollerv1.GroupVersion.WithKind("Server"))
+	obj.SetName(server.Name)
+	obj.SetNamespace(server.Namespace)
+	return t.client.Status().Patch(ctx, obj, client.Apply, client.FieldOwner(fieldManager), client.ForceOwnership)
+}
+
+func serverAddress(source string, lease Lease) baremetalcontrollerv1.ServerAddress {
+	mac := lease.MACAddress
+	if hw, err := net.ParseMAC(mac); err == nil {
+		mac = hw.String()
+	}
+	address := baremetalcontrollerv1.ServerAddress{
+		Address:    lease.IPAddress,
+		MACAddress: mac,
+		Hostname:   lease.Hostname,
+		Source:     source,
+	}
+	if !lease.Expires.IsZero() {
+		expires := metav1.NewTime(lease.Expires.Truncate(time.Second))
+		address.Expires = &expires
+	}
+	return address
+}
+
+// sameAddresses compares addresses, with expiries to the second as stored
+func sameAddresses(a, b []baremetalcontrollerv1.ServerAddress) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		x, y := a[i], b[i]
+		if x.Expires != nil && y.Expires != nil && x.Expires.Unix() == y.Expires.Unix() {
+			x.Expires, y.Expires = nil, nil
+		}
+		if !reflect.DeepEqual(x, y) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,124 @@
+package dhcp
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/index"
+)
+
+// fakeSource returns fixed leases
+type fakeSource struct {
+	name   string
+	leases []Lease
+	err    error
+}
+
+func (s *fakeSource) Name() string {
+	return s.name
+}
+
+func (s *fakeSource) Leases(context.Context) ([]Lease, error) {
+	return s.leases, s.err
+}
+
+func wolServer(name, mac string, addresses ...baremetalcontrollerv1.ServerAddress) *baremetalcontrollerv1.Server {
+	return &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: baremetalcontrollerv1.ServerSpec{
+			Type:    baremetalcontrollerv1.ControlTypeWOL,
+			Control: baremetalcontrollerv1.ControlSpecs{WOL: &baremetalcontrollerv1.WOLSpecs{Address: name, MACAddress: mac}},
+		},
+		Status: baremetalcontrollerv1.ServerStatus{Addresses: addresses},
+	}
+}
+
+func TestTrackerSync(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := baremetalcontrollerv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	current := baremetalcontrollerv1.ServerAddress{Address: "10.0.0.13", MACAddress: "00:11:22:33:44:77", Source: "kea"}
+
+	// The fake client doesn't support server-side apply, so the status
+	// patches are recorded instead
+	patched := map[string][]baremetalcontrollerv1.ServerAddress{}
+	builder := index.NewFakeClientBuilder(scheme).
+		WithObjects(
+			wolServer("worker-01", "00:11:22:aa:bb:55"),
+			wolServer("worker-02", "00:11:22:33:44:66"),
+			wolServer("worker-03", "00:11:22:33:44:77", current),
+		).
+		WithStatusSubresource(&baremetalcontrollerv1.Server{}).
+		WithInterceptorFuncs(interceptor.Funcs{
+			SubResourcePatch: func(_ context.Context, _ client.Client, subResource string, obj client.Object, patch client.Patch, _ ...client.SubResourcePatchOption) error {
+				if subResource != "status" || patch != client.Apply {
+					t.Errorf("patched %s with %s, want an applied status", subResource, patch.Type())
+				}
+				var status baremetalcontrollerv1.ServerStatus
+				content := obj.(*unstructured.Unstructured).Object["status"].(map[string]interface{})
+				if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, &status); err != nil {
+					t.Errorf("invalid status: %v", err)
+				}
+				patched[obj.GetName()] = status.Addresses
+				return nil
+			},
+		})
+
+	expires := metav1.NewTime(now.Add(time.Hour))
+	tracker := &Tracker{
+		client: builder.Build(),
+		sources: []Source{
+			&fakeSource{name: "broken", err: errors.New("connection refused")},
+			&fakeSource{name: "kea", leases: []Lease{
+				// Matched by MAC address in any notation
+				{MACAddress: "00-11-22-AA-BB-55", IPAddress: "10.0.0.11", Hostname: "worker-01", Expires: now.Add(time.Hour)},
+				{MACAddress: "00:11:22:33:44:66", IPAddress: "10.0.0.2", Expires: now.Add(-time.Minute)},
+				{MACAddress: "00:11:22:33:44:88", IPAddress: "10.0.0.99"},
+				{MACAddress: current.MACAddress, IPAddress: current.Address},
+			}},
+			&fakeSource{name: "relay", leases: []Lease{
+				{MACAddress: "00:11:22:33:44:66", IPAddress: "10.0.0.12"},
+				// Already recorded from kea
+				{MACAddress: "00:11:22:aa:bb:55", IPAddress: "10.0.0.11"},
+			}},
+		},
+	}
+	tracker.sync(context.Background(), now)
+
+	want := map[string][]baremetalcontrollerv1.ServerAddress{
+		"worker-01": {{Address: "10.0.0.11", MACAddress: "00:11:22:aa:bb:55", Hostname: "worker-01", Source: "kea", Expires: &expires}},
+		// The expired lease is skipped
+		"worker-02": {{Address: "10.0.0.12", MACAddress: "00:11:22:33:44:66", Source: "relay"}},
+		// worker-03 already has its address, and the unknown MAC belongs
+		// to no server
+	}
+	if len(patched) != len(want) {
+		t.Errorf("patched %v, want %v", patched, want)
+	}
+	for name, addresses := range want {
+		if !sameAddresses(patched[name], addresses) {
+			t.Errorf("addresses of %s = %+v, want %+v", name, patched[name], addresses)
+		}
+	}
+}
+
+func TestServerAddress(t *testing.T) {
+	got := serverAddress("relay", Lease{MACAddress: "00-11-22-AA-BB-CC", IPAddress: "10.0.0.11", Expires: time.Unix(1700000000, 500)})
+	expires := metav1.NewTime(time.Unix(1700000000, 0))
+	want := baremetalcontrollerv1.ServerAddress{Address: "10.0.0.11", MACAddress: "00:11:22:aa:bb:cc", Source: "relay", Expires: &expires}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("serverAddress() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,48 @@
+package dhcp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseDnsmasq reads a dnsmasq lease file. Each line is
+// "<expiry> <mac> <ip> <hostname> <client-id>", with an expiry of 0 for
+// infinite leases and "*" for an unknown hostname. DHCPv6 leases, which
+// have no MAC address, are skipped.
+func ParseDnsmasq(r io.Reader) ([]Lease, error) {
+	var leases []Lease
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] == "duid" {
+			continue
+		}
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("invalid dnsmasq lease %q", scanner.Text())
+		}
+		if _, err := net.ParseMAC(fields[1]); err != nil {
+			continue
+		}
+		expiry, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dnsmasq lease expiry %q", fields[0])
+		}
+		lease := Lease{MACAddress: fields[1], IPAddress: fields[2]}
+		if fields[3] != "*" {
+			lease.Hostname = fields[3]
+		}
+		if expiry != 0 {
+			lease.Expires = time.Unix(expiry, 0)
+		}
+		leases = append(leases, lease)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dnsmasq leases: %w", err)
+	}
+	return leases, nil
+}
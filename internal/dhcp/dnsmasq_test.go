@@ -0,0 +1,66 @@
+package dhcp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDnsmasq(t *testing.T) {
+	tests := []struct {
+		name    string
+		leases  string
+		want    []Lease
+		wantErr bool
+	}{
+		{
+			name: "IPv4 leases",
+			leases: "1700000000 00:11:22:33:44:55 10.0.0.11 worker-01 01:00:11:22:33:44:55\n" +
+				"1700000600 00:11:22:33:44:66 10.0.0.12 * *\n",
+			want: []Lease{
+				{MACAddress: "00:11:22:33:44:55", IPAddress: "10.0.0.11", Hostname: "worker-01", Expires: time.Unix(1700000000, 0)},
+				{MACAddress: "00:11:22:33:44:66", IPAddress: "10.0.0.12", Expires: time.Unix(1700000600, 0)},
+			},
+		},
+		{
+			name:   "infinite lease",
+			leases: "0 00:11:22:33:44:55 10.0.0.11 worker-01 *\n",
+			want:   []Lease{{MACAddress: "00:11:22:33:44:55", IPAddress: "10.0.0.11", Hostname: "worker-01"}},
+		},
+		{
+			// Expired leases are kept, the tracker skips them
+			name:   "expired lease",
+			leases: "1 00:11:22:33:44:55 10.0.0.11 worker-01 *\n",
+			want:   []Lease{{MACAddress: "00:11:22:33:44:55", IPAddress: "10.0.0.11", Hostname: "worker-01", Expires: time.Unix(1, 0)}},
+		},
+		{
+			// DHCPv6 leases follow the server DUID and have an IAID where
+			// the MAC address would be
+			name: "IPv6 leases",
+			leases: "1700000000 00:11:22:33:44:55 10.0.0.11 worker-01 *\n" +
+				"duid 00:01:00:01:2c:5e:2a:1b:00:11:22:33:44:00\n" +
+				"1700000000 1122867 2001:db8::11 worker-01 00:01:00:01:2c:5e:2a:1b:00:11:22:33:44:55\n",
+			want: []Lease{{MACAddress: "00:11:22:33:44:55", IPAddress: "10.0.0.11", Hostname: "worker-01", Expires: time.Unix(1700000000, 0)}},
+		},
+		{
+			name:   "blank lines",
+			leases: "\n  \n1700000000 00:11:22:33:44:55 10.0.0.11 worker-01 *\n\n",
+			want:   []Lease{{MACAddress: "00:11:22:33:44:55", IPAddress: "10.0.0.11", Hostname: "worker-01", Expires: time.Unix(1700000000, 0)}},
+		},
+		{name: "empty", leases: ""},
+		{name: "too few fields", leases: "1700000000 00:11:22:33:44:55 10.0.0.11\n", wantErr: true},
+		{name: "invalid expiry", leases: "tomorrow 00:11:22:33:44:55 10.0.0.11 worker-01 *\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDnsmasq(strings.NewReader(tt.leases))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDnsmasq() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDnsmasq() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
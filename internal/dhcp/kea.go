@@ -0,0 +1,103 @@
+package dhcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// keaResultEmpty is the result of a command that found nothing
+const keaResultEmpty = 3
+
+// Kea reads DHCPv4 leases from a Kea Control Agent with the lease4-get-all
+// command of the lease_cmds hook
+type Kea struct {
+	URL        string
+	HTTPClient *http.Client
+}
+
+// Name returns "kea"
+func (k *Kea) Name() string {
+	return "kea"
+}
+
+type keaCommand struct {
+	Command string   `json:"command"`
+	Service []string `json:"service"`
+}
+
+type keaResponse struct {
+	Result    int    `json:"result"`
+	Text      string `json:"text"`
+	Arguments struct {
+		Leases []keaLease `json:"leases"`
+	} `json:"arguments"`
+}
+
+type keaLease struct {
+	HWAddress string `json:"hw-address"`
+	IPAddress string `json:"ip-address"`
+	Hostname  string `json:"hostname"`
+	// CLTT is when the client last renewed, in Unix seconds
+	CLTT int64 `json:"cltt"`
+	// ValidLifetime is the lease time in seconds, 0xffffffff for infinite
+	ValidLifetime int64 `json:"valid-lft"`
+	State         int   `json:"state"`
+}
+
+// Leases returns the leases of the DHCPv4 server
+func (k *Kea) Leases(ctx context.Context) ([]Lease, error) {
+	body, err := json.Marshal(keaCommand{Command: "lease4-get-all", Service: []string{"dhcp4"}})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, k.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	httpClient := k.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query Kea: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Kea returned status %d", resp.StatusCode)
+	}
+
+	// The Control Agent answers with one response per service
+	var responses []keaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&responses); err != nil {
+		return nil, fmt.Errorf("failed to decode Kea response: %w", err)
+	}
+	var leases []Lease
+	for _, response := range responses {
+		switch response.Result {
+		case 0:
+		case keaResultEmpty:
+			continue
+		default:
+			return nil, fmt.Errorf("Kea lease4-get-all failed: %s", response.Text)
+		}
+		for _, l := range response.Arguments.Leases {
+			// Only default (0) leases are assigned, not declined or expired ones
+			if l.State != 0 || l.HWAddress == "" {
+				continue
+			}
+			lease := Lease{MACAddress: l.HWAddress, IPAddress: l.IPAddress, Hostname: l.Hostname}
+			if l.ValidLifetime != 0xffffffff {
+				lease.Expires = time.Unix(l.CLTT+l.ValidLifetime, 0)
+			}
+			leases = append(leases, lease)
+		}
+	}
+	return leases, nil
+}
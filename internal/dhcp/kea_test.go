@@ -0,0 +1,75 @@
+package dhcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestKeaLeases(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response string
+		want     []Lease
+		wantErr  bool
+	}{
+		{
+			name:   "leases",
+			status: http.StatusOK,
+			response: `[{"result": 0, "text": "3 IPv4 lease(s) found.", "arguments": {"leases": [
+				{"hw-address": "00:11:22:33:44:55", "ip-address": "10.0.0.11", "hostname": "worker-01", "cltt": 1700000000, "valid-lft": 3600, "state": 0},
+				{"hw-address": "00:11:22:33:44:66", "ip-address": "10.0.0.12", "hostname": "", "cltt": 1700000000, "valid-lft": 4294967295, "state": 0},
+				{"hw-address": "00:11:22:33:44:77", "ip-address": "10.0.0.13", "hostname": "worker-03", "cltt": 1700000000, "valid-lft": 3600, "state": 1},
+				{"hw-address": "", "ip-address": "10.0.0.14", "hostname": "", "cltt": 1700000000, "valid-lft": 3600, "state": 0}
+			]}}]`,
+			// Declined leases and leases without a MAC address are skipped
+			want: []Lease{
+				{MACAddress: "00:11:22:33:44:55", IPAddress: "10.0.0.11", Hostname: "worker-01", Expires: time.Unix(1700003600, 0)},
+				{MACAddress: "00:11:22:33:44:66", IPAddress: "10.0.0.12"},
+			},
+		},
+		{
+			name:     "no leases",
+			status:   http.StatusOK,
+			response: `[{"result": 3, "text": "0 IPv4 lease(s) found.", "arguments": {"leases": []}}]`,
+		},
+		{
+			name:     "hook not loaded",
+			status:   http.StatusOK,
+			response: `[{"result": 2, "text": "'lease4-get-all' command not supported."}]`,
+			wantErr:  true,
+		},
+		{name: "server error", status: http.StatusInternalServerError, response: `[]`, wantErr: true},
+		{name: "invalid response", status: http.StatusOK, response: `{"result": 0}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var cmd keaCommand
+				if err := json.NewDecoder(r.Body).Decode(&cmd); err != nil {
+					t.Errorf("invalid command: %v", err)
+				}
+				if cmd.Command != "lease4-get-all" || !reflect.DeepEqual(cmd.Service, []string{"dhcp4"}) {
+					t.Errorf("command = %+v, want lease4-get-all for dhcp4", cmd)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer srv.Close()
+
+			k := &Kea{URL: srv.URL}
+			got, err := k.Leases(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Leases() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Leases() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+// Package dns publishes DNS records for the OS and management addresses of
+// servers, through external-dns DNSEndpoint resources or RFC 2136 dynamic
+// updates, so tooling can reach machines by stable names.
+package dns
+
+import (
+	"context"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ProviderExternalDNS writes DNSEndpoint resources for external-dns
+	ProviderExternalDNS = "external-dns"
+	// ProviderRFC2136 sends dynamic updates to an authoritative server
+	ProviderRFC2136 = "rfc2136"
+)
+
+// Options contains configuration for DNS registration.
+type Options struct {
+	// Provider is external-dns or rfc2136. Empty disables DNS registration.
+	Provider string
+
+	// Zone is the domain records are published under, as <server>.<zone>
+	// for OS addresses and <server>-bmc.<zone> for management addresses
+	Zone string
+
+	// TTL of the published records
+	TTL time.Duration
+
+	// Namespace DNSEndpoints are created in (external-dns)
+	Namespace string
+
+	// Nameserver is the host:port dynamic updates are sent to (rfc2136)
+	Nameserver string
+
+	// TSIGKeyName, TSIGSecretFile and TSIGAlgorithm sign dynamic updates.
+	// Unsigned updates are sent without a key name.
+	TSIGKeyName    string
+	TSIGSecretFile string
+	TSIGAlgorithm  string
+
+	// Timeout bounds each dynamic update
+	Timeout time.Duration
+}
+
+// DefaultOptions returns the default DNS options, with registration disabled.
+func DefaultOptions() Options {
+	return Options{
+		TTL:           5 * time.Minute,
+		Namespace:     "default",
+		TSIGAlgorithm: "hmac-sha256",
+		Timeout:       10 * time.Second,
+	}
+}
+
+// BindFlags binds the DNS options to command line flags.
+// The prefix can be used to namespace the flags (e.g., "dns-").
+func (o *Options) BindFlags(fs *flag.FlagSet, prefix string) {
+	fs.StringVar(&o.Provider, prefix+"provider", o.Provider,
+		"Publish server DNS records with external-dns or rfc2136. Empty to disable.")
+	fs.StringVar(&o.Zone, prefix+"zone", o.Zone,
+		"Domain server records are published under, e.g. metal.example.com.")
+	fs.DurationVar(&o.TTL, prefix+"ttl", o.TTL,
+		"TTL of published records.")
+	fs.StringVar(&o.Namespace, prefix+"namespace", o.Namespace,
+		"Namespace of the DNSEndpoints created for external-dns.")
+	fs.StringVar(&o.Nameserver, prefix+"nameserver", o.Nameserver,
+		"host:port of the authoritative server RFC 2136 updates are sent to.")
+	fs.StringVar(&o.TSIGKeyName, prefix+"tsig-key-name", o.TSIGKeyName,
+		"Name of the TSIG key signing RFC 2136 updates. Empty for unsigned updates.")
+	fs.StringVar(&o.TSIGSecretFile, prefix+"tsig-secret-file", o.TSIGSecretFile,
+		"Path to the base64 TSIG secret.")
+	fs.StringVar(&o.TSIGAlgorithm, prefix+"tsig-algorithm", o.TSIGAlgorithm,
+		"TSIG algorithm: hmac-sha256 or hmac-sha512.")
+	fs.DurationVar(&o.Timeout, prefix+"timeout", o.Timeout,
+		"Timeout of each RFC 2136 update.")
+}
+
+// Validate validates the options.
+func (o *Options) Validate() error {
+	if !o.Enabled() {
+		return nil
+	}
+	if o.Zone == "" {
+		return fmt.Errorf("DNS zone is required")
+	}
+	if o.TTL < time.Second {
+		return fmt.Errorf("DNS TTL must be at least 1s")
+	}
+	switch o.Provider {
+	case ProviderExternalDNS:
+		if o.Namespace == "" {
+			return fmt.Errorf("DNS namespace is required for external-dns")
+		}
+	case ProviderRFC2136:
+		if _, _, err := net.SplitHostPort(o.Nameserver); err != nil {
+			return fmt.Errorf("DNS nameserver must be host:port: %w", err)
+		}
+		if (o.TSIGKeyName == "") != (o.TSIGSecretFile == "") {
+			return fmt.Errorf("TSIG key name and secret file must be set together")
+		}
+		if _, ok := tsigAlgorithms[strings.ToLower(o.TSIGAlgorithm)]; !ok {
+			return fmt.Errorf("unsupported TSIG algorithm %q", o.TSIGAlgorithm)
+		}
+		if o.Timeout <= 0 {
+			return fmt.Errorf("DNS timeout must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown DNS provider %q", o.Provider)
+	}
+	return nil
+}
+
+// Enabled returns true if server records should be published.
+func (o *Options) Enabled() bool {
+	return o.Provider != ""
+}
+
+// Record is a name and the addresses it resolves to
+type Record struct {
+	Name      string
+	Addresses []string
+}
+
+// Publisher publishes the records of a server, replacing what was published
+// for the server before. Records without addresses are removed.
+type Publisher interface {
+	Publish(ctx context.Context, server string, records []Record) error
+}
+
+// NewPublisher returns the publisher of the configured provider
+func NewPublisher(opts Options, c client.Client) (Publisher, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.Provider == ProviderExternalDNS {
+		return &ExternalDNS{Client: c, Namespace: opts.Namespace, TTL: opts.TTL}, nil
+	}
+
+	updater := &RFC2136{
+		Nameserver: opts.Nameserver,
+		Zone:       opts.Zone,
+		TTL:        opts.TTL,
+		Timeout:    opts.Timeout,
+	}
+	if opts.TSIGKeyName != "" {
+		data, err := os.ReadFile(opts.TSIGSecretFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read TSIG secret: %w", err)
+		}
+		secret, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, fmt.Errorf("TSIG secret is not base64: %w", err)
+		}
+		updater.TSIG = &TSIGKey{Name: opts.TSIGKeyName, Algorithm: opts.TSIGAlgorithm, Secret: secret}
+	}
+	return updater, nil
+}
+
+// OSName returns the name of the OS addresses of a server
+func OSName(server, zone string) string {
+	return server + "." + strings.TrimSuffix(zone, ".")
+}
+
+// ManagementName returns the name of the BMC addresses of a server
+func ManagementName(server, zone string) string {
+	return server + "-bmc." + strings.TrimSuffix(zone, ".")
+}
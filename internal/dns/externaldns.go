@@ -0,0 +1,106 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DNSEndpointGVK is the GroupVersionKind of the external-dns DNSEndpoint
+// resource. It is handled as unstructured, so the controller doesn't depend
+// on the external-dns module.
+var DNSEndpointGVK = schema.GroupVersionKind{
+	Group:   "externaldns.k8s.io",
+	Version: "v1alpha1",
+	Kind:    "DNSEndpoint",
+}
+
+// serverLabel records the Server a DNSEndpoint belongs to
+const serverLabel = "baremetal.io/server"
+
+// ExternalDNS publishes the records of each server in a DNSEndpoint of the
+// same name, which external-dns picks up with --source=crd
+type ExternalDNS struct {
+	Client    client.Client
+	Namespace string
+	TTL       time.Duration
+}
+
+// Publish creates, updates or deletes the DNSEndpoint of the server
+func (e *ExternalDNS) Publish(ctx context.Context, server string, records []Record) error {
+	var endpoints []interface{}
+	for _, record := range records {
+		for _, recordType := range []string{"A", "AAAA"} {
+			var targets []interface{}
+			for _, address := range record.Addresses {
+				if addressType(address) == recordType {
+					targets = append(targets, address)
+				}
+			}
+			if len(targets) == 0 {
+				continue
+			}
+			endpoints = append(endpoints, map[string]interface{}{
+				"dnsName":    record.Name,
+				"recordType": recordType,
+				"recordTTL":  int64(e.TTL.Seconds()),
+				"targets":    targets,
+			})
+		}
+	}
+
+	endpoint := &unstructured.Unstructured{}
+	endpoint.SetGroupVersionKind(DNSEndpointGVK)
+	err := e.Client.Get(ctx, client.ObjectKey{Namespace: e.Namespace, Name: server}, endpoint)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to get DNSEndpoint %s: %w", server, err)
+	}
+	exists := err == nil
+
+	if len(endpoints) == 0 {
+		if !exists {
+			return nil
+		}
+		if err := e.Client.Delete(ctx, endpoint); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete DNSEndpoint %s: %w", server, err)
+		}
+		return nil
+	}
+
+	if err := unstructured.SetNestedSlice(endpoint.Object, endpoints, "spec", "endpoints"); err != nil {
+		return err
+	}
+	if exists {
+		if err := e.Client.Update(ctx, endpoint); err != nil {
+			return fmt.Errorf("failed to update DNSEndpoint %s: %w", server, err)
+		}
+		return nil
+	}
+	endpoint.SetNamespace(e.Namespace)
+	endpoint.SetName(server)
+	endpoint.SetLabels(map[string]string{serverLabel: server})
+	if err := e.Client.Create(ctx, endpoint); err != nil {
+		return fmt.Errorf("failed to create DNSEndpoint %s: %w", server, err)
+	}
+	return nil
+}
+
+// addressType returns the record type of an IP address, or "" if it isn't
+// one
+func addressType(address string) string {
+	ip := net.ParseIP(address)
+	switch {
+	case ip == nil:
+		return ""
+	case ip.To4() != nil:
+		return "A"
+	default:
+		return "AAAA"
+	}
+}
@@ -0,0 +1,235 @@
+package dns
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	typeA    = 1
+	typeSOA  = 6
+	typeAAAA = 28
+	typeTSIG = 250
+
+	classIN  = 1
+	classANY = 255
+
+	opcodeUpdate = 5
+	tsigFudge    = 300
+)
+
+// tsigAlgorithms maps the supported TSIG algorithms to their hashes
+var tsigAlgorithms = map[string]func() hash.Hash{
+	"hmac-sha256": sha256.New,
+	"hmac-sha512": sha512.New,
+}
+
+// rcodes names the response codes of failed updates
+var rcodes = map[int]string{
+	1: "FORMERR", 2: "SERVFAIL", 3: "NXDOMAIN", 4: "NOTIMP", 5: "REFUSED",
+	6: "YXDOMAIN", 7: "YXRRSET", 8: "NXRRSET", 9: "NOTAUTH", 10: "NOTZONE",
+}
+
+// TSIGKey signs updates (RFC 8945)
+type TSIGKey struct {
+	Name      string
+	Algorithm string
+	Secret    []byte
+}
+
+// RFC2136 publishes records with dynamic updates (RFC 2136) over TCP. Each
+// publish replaces the A and AAAA records of the names in one update. The
+// signatures of responses aren't verified.
+type RFC2136 struct {
+	Nameserver string
+	Zone       string
+	TTL        time.Duration
+	Timeout    time.Duration
+	TSIG       *TSIGKey
+}
+
+// Publish replaces the A and AAAA records of each name with its addresses
+func (u *RFC2136) Publish(ctx context.Context, server string, records []Record) error {
+	msg, id, err := u.updateMessage(records)
+	if err != nil {
+		return err
+	}
+	if u.TSIG != nil {
+		if msg, err = u.sign(msg, id, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, u.Timeout)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", u.Nameserver)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", u.Nameserver, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	framed := binary.BigEndian.AppendUint16(nil, uint16(len(msg)))
+	if _, err := conn.Write(append(framed, msg...)); err != nil {
+		return fmt.Errorf("failed to send update for %s: %w", server, err)
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return fmt.Errorf("failed to read update response for %s: %w", server, err)
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return fmt.Errorf("failed to read update response for %s: %w", server, err)
+	}
+	if len(resp) < 12 || binary.BigEndian.Uint16(resp) != id {
+		return fmt.Errorf("invalid update response for %s", server)
+	}
+	if rcode := int(resp[3] & 0x0f); rcode != 0 {
+		name, ok := rcodes[rcode]
+		if !ok {
+			name = fmt.Sprintf("rcode %d", rcode)
+		}
+		return fmt.Errorf("update for %s failed: %s", server, name)
+	}
+	return nil
+}
+
+// updateMessage builds an update that deletes the A and AAAA records of each
+// name and adds its addresses
+func (u *RFC2136) updateMessage(records []Record) ([]byte, uint16, error) {
+	var idBytes [2]byte
+	if _, err := rand.Read(idBytes[:]); err != nil {
+		return nil, 0, err
+	}
+	id := binary.BigEndian.Uint16(idBytes[:])
+
+	zone, err := wireName(u.Zone)
+	if err != nil {
+		return nil, 0, err
+	}
+	var updates []byte
+	count := 0
+	for _, record := range records {
+		name, err := wireName(record.Name)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, rrType := range []uint16{typeA, typeAAAA} {
+			updates = appendRR(updates, name, rrType, classANY, 0, nil)
+			count++
+		}
+		for _, address := range record.Addresses {
+			ip := net.ParseIP(address)
+			switch {
+			case ip == nil:
+				return nil, 0, fmt.Errorf("%s is not an IP address", address)
+			case ip.To4() != nil:
+				updates = appendRR(updates, name, typeA, classIN, uint32(u.TTL.Seconds()), ip.To4())
+			default:
+				updates = appendRR(updates, name, typeAAAA, classIN, uint32(u.TTL.Seconds()), ip.To16())
+			}
+			count++
+		}
+	}
+
+	msg := binary.BigEndian.AppendUint16(nil, id)
+	msg = binary.BigEndian.AppendUint16(msg, opcodeUpdate<<11)
+	msg = binary.BigEndian.AppendUint16(msg, 1) // zone
+	msg = binary.BigEndian.AppendUint16(msg, 0) // prerequisites
+	msg = binary.BigEndian.AppendUint16(msg, uint16(count))
+	msg = binary.BigEndian.AppendUint16(msg, 0) // additional
+	msg = append(msg, zone...)
+	msg = binary.BigEndian.AppendUint16(msg, typeSOA)
+	msg = binary.BigEndian.AppendUint16(msg, classIN)
+	return append(msg, updates...), id, nil
+}
+
+// sign appends a TSIG record to the message
+func (u *RFC2136) sign(msg []byte, id uint16, now time.Time) ([]byte, error) {
+	// Both names are hashed in canonical, lowercase form
+	algorithmName := strings.ToLower(u.TSIG.Algorithm)
+	newHash, ok := tsigAlgorithms[algorithmName]
+	if !ok {
+		return nil, fmt.Errorf("unsupported TSIG algorithm %q", u.TSIG.Algorithm)
+	}
+	keyName, err := wireName(strings.ToLower(u.TSIG.Name))
+	if err != nil {
+		return nil, err
+	}
+	algorithm, err := wireName(algorithmName)
+	if err != nil {
+		return nil, err
+	}
+	signed := uint64(now.Unix())
+	timeSigned := []byte{byte(signed >> 40), byte(signed >> 32), byte(signed >> 24), byte(signed >> 16), byte(signed >> 8), byte(signed)}
+
+	// The MAC covers the message and the TSIG variables
+	mac := hmac.New(newHash, u.TSIG.Secret)
+	mac.Write(msg)
+	mac.Write(keyName)
+	variables := binary.BigEndian.AppendUint16(nil, classANY)
+	variables = binary.BigEndian.AppendUint32(variables, 0) // TTL
+	variables = append(variables, algorithm...)
+	variables = append(variables, timeSigned...)
+	variables = binary.BigEndian.AppendUint16(variables, tsigFudge)
+	variables = binary.BigEndian.AppendUint16(variables, 0) // error
+	variables = binary.BigEndian.AppendUint16(variables, 0) // other length
+	mac.Write(variables)
+	sum := mac.Sum(nil)
+
+	rdata := append([]byte{}, algorithm...)
+	rdata = append(rdata, timeSigned...)
+	rdata = binary.BigEndian.AppendUint16(rdata, tsigFudge)
+	rdata = binary.BigEndian.AppendUint16(rdata, uint16(len(sum)))
+	rdata = append(rdata, sum...)
+	rdata = binary.BigEndian.AppendUint16(rdata, id)
+	rdata = binary.BigEndian.AppendUint16(rdata, 0) // error
+	rdata = binary.BigEndian.AppendUint16(rdata, 0) // other length
+
+	out := append([]byte{}, msg...)
+	binary.BigEndian.PutUint16(out[10:], binary.BigEndian.Uint16(out[10:])+1)
+	return appendRR(out, keyName, typeTSIG, classANY, 0, rdata), nil
+}
+
+// appendRR appends a resource record
+func appendRR(b []byte, name []byte, rrType, class uint16, ttl uint32, rdata []byte) []byte {
+	b = append(b, name...)
+	b = binary.BigEndian.AppendUint16(b, rrType)
+	b = binary.BigEndian.AppendUint16(b, class)
+	b = binary.BigEndian.AppendUint32(b, ttl)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(rdata)))
+	return append(b, rdata...)
+}
+
+// wireName encodes a domain name as length-prefixed labels, uncompressed
+func wireName(name string) ([]byte, error) {
+	name = strings.TrimSuffix(name, ".")
+	var b []byte
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			if label == "" || len(label) > 63 {
+				return nil, fmt.Errorf("invalid DNS name %q", name)
+			}
+			b = append(b, byte(len(label)))
+			b = append(b, label...)
+		}
+	}
+	b = append(b, 0)
+	if len(b) > 255 {
+		return nil, fmt.Errorf("DNS name %q is too long", name)
+	}
+	return b, nil
+}
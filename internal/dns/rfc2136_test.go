@@ -0,0 +1,134 @@
+package dns
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+)
+
+// unhex decodes hex split into commented parts
+func unhex(t *testing.T, parts ...string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.Join(parts, ""))
+	if err != nil {
+		t.Fatalf("invalid hex: %v", err)
+	}
+	return b
+}
+
+// testUpdate is the update for worker-01.example.com with id 0x1234
+func testUpdate(t *testing.T) []byte {
+	t.Helper()
+	return unhex(t,
+		// Header: id, UPDATE opcode, one zone, no prerequisites, four
+		// updates, no additional records
+		"1234", "2800", "0001", "0000", "0004", "0000",
+		// Zone example.com SOA IN
+		"076578616d706c6503636f6d00", "0006", "0001",
+		// Delete the A and AAAA RRsets: class ANY, TTL 0, no rdata
+		"09776f726b65722d3031076578616d706c6503636f6d00", "0001", "00ff", "00000000", "0000",
+		"09776f726b65722d3031076578616d706c6503636f6d00", "001c", "00ff", "00000000", "0000",
+		// Add 192.0.2.1 and 2001:db8::1 with a TTL of 300
+		"09776f726b65722d3031076578616d706c6503636f6d00", "0001", "0001", "0000012c", "0004", "c0000201",
+		"09776f726b65722d3031076578616d706c6503636f6d00", "001c", "0001", "0000012c", "0010", "20010db8000000000000000000000001",
+	)
+}
+
+func TestUpdateMessage(t *testing.T) {
+	u := &RFC2136{Zone: "example.com.", TTL: 5 * time.Minute}
+	msg, id, err := u.updateMessage([]Record{{Name: "worker-01.example.com", Addresses: []string{"192.0.2.1", "2001:db8::1"}}})
+	if err != nil {
+		t.Fatalf("updateMessage() error = %v", err)
+	}
+	if got := binary.BigEndian.Uint16(msg); got != id {
+		t.Errorf("message id = %#04x, want %#04x", got, id)
+	}
+
+	// The id is random, so compare the rest with the test id in its place
+	binary.BigEndian.PutUint16(msg, 0x1234)
+	if want := testUpdate(t); !bytes.Equal(msg, want) {
+		t.Errorf("updateMessage() =\n%x\nwant\n%x", msg, want)
+	}
+
+	if _, _, err := u.updateMessage([]Record{{Name: "worker-01.example.com", Addresses: []string{"worker-01"}}}); err == nil {
+		t.Errorf("updateMessage() succeeded for an address that isn't an IP")
+	}
+	if _, _, err := u.updateMessage([]Record{{Name: "worker..example.com"}}); err == nil {
+		t.Errorf("updateMessage() succeeded for an empty label")
+	}
+}
+
+func TestSign(t *testing.T) {
+	// The MAC is HMAC-SHA256 over the message followed by the TSIG
+	// variables of RFC 8945 section 4.3.3, computed outside this package
+	update := testUpdate(t)
+	wantRR := unhex(t,
+		// tsig-key ANY TSIG, TTL 0
+		"08747369672d6b657900", "00fa", "00ff", "00000000", "003d",
+		// Algorithm hmac-sha256, signed at 1700000000, fudge 300
+		"0b686d61632d73686132353600", "00006553f100", "012c",
+		// MAC
+		"0020", "41f0a1bc3a12e81f7cb4b49bec725379c053dc4da39a805666dfa4cef4e3fade",
+		// Original id, no error, no other data
+		"1234", "0000", "0000",
+	)
+	want := append(append([]byte{}, update...), wantRR...)
+	// One more additional record
+	want[11] = 1
+
+	tests := []struct {
+		name string
+		key  TSIGKey
+	}{
+		{name: "canonical", key: TSIGKey{Name: "tsig-key.", Algorithm: "hmac-sha256"}},
+		// Names are hashed lowercase, whatever case they are configured in
+		{name: "mixed case", key: TSIGKey{Name: "TSIG-Key", Algorithm: "HMAC-SHA256"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := tt.key
+			key.Secret = []byte("0123456789abcdef0123456789abcdef")
+			u := &RFC2136{TSIG: &key}
+			got, err := u.sign(update, 0x1234, time.Unix(1700000000, 0))
+			if err != nil {
+				t.Fatalf("sign() error = %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("sign() =\n%x\nwant\n%x", got, want)
+			}
+		})
+	}
+
+	u := &RFC2136{TSIG: &TSIGKey{Name: "tsig-key", Algorithm: "hmac-md5", Secret: []byte("secret")}}
+	if _, err := u.sign(update, 0x1234, time.Unix(1700000000, 0)); err == nil {
+		t.Errorf("sign() succeeded with an unsupported algorithm")
+	}
+}
+
+func TestWireName(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "example.com", want: "076578616d706c6503636f6d00"},
+		{name: "example.com.", want: "076578616d706c6503636f6d00"},
+		{name: ".", want: "00"},
+		{name: "a..example.com", wantErr: true},
+		{name: strings.Repeat("a", 64) + ".example.com", wantErr: true},
+		{name: strings.Repeat(strings.Repeat("a", 63)+".", 4) + "com", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := wireName(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("wireName(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && hex.EncodeToString(got) != tt.want {
+			t.Errorf("wireName(%q) = %x, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package drain cordons nodes and evicts their pods, like kubectl drain.
+package drain
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Node cordons a node and requests eviction of its pods. It returns true once
+// no evictable pods are left. Evictions blocked by a PodDisruptionBudget are
+// retried on the next call. A node that doesn't exist is considered drained.
+func Node(ctx context.Context, c client.Client, reader client.Reader, name string) (bool, error) {
+	if err := setUnschedulable(ctx, c, name, true); err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	// The cache doesn't index pods by node, so ask the API server directly
+	var pods corev1.PodList
+	if err := reader.List(ctx, &pods, client.MatchingFields{"spec.nodeName": name}); err != nil {
+		return false, fmt.Errorf("failed to list pods on node %s: %w", name, err)
+	}
+
+	remaining := 0
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !isEvictable(pod) {
+			continue
+		}
+		remaining++
+
+		eviction := &policyv1.Eviction{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      pod.Name,
+				Namespace: pod.Namespace,
+			},
+		}
+		err := c.SubResource("eviction").Create(ctx, pod, eviction)
+		if err != nil && !apierrors.IsNotFound(err) && !apierrors.IsTooManyRequests(err) {
+			return false, fmt.Errorf("failed to evict pod %s/%s: %w", pod.Namespace, pod.Name, err)
+		}
+	}
+	return remaining == 0, nil
+}
+
+// Empty returns true if no evictable pods run on the node, i.e. only
+// DaemonSet, mirror and finished pods are left
+func Empty(ctx context.Context, reader client.Reader, name string) (bool, error) {
+	var pods corev1.PodList
+	if err := reader.List(ctx, &pods, client.MatchingFields{"spec.nodeName": name}); err != nil {
+		return false, fmt.Errorf("failed to list pods on node %s: %w", name, err)
+	}
+	for i := range pods.Items {
+		if isEvictable(&pods.Items[i]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Uncordon makes a node schedulable again
+func Uncordon(ctx context.Context, c client.Client, name string) error {
+	return client.IgnoreNotFound(setUnschedulable(ctx, c, name, false))
+}
+
+func setUnschedulable(ctx context.Context, c client.Client, name string, unschedulable bool) error {
+	var node corev1.Node
+	if err := c.Get(ctx, types.NamespacedName{Name: name}, &node); err != nil {
+		return err
+	}
+	if node.Spec.Unschedulable == unschedulable {
+		return nil
+	}
+
+	patch := client.MergeFrom(node.DeepCopy())
+	node.Spec.Unschedulable = unschedulable
+	return c.Patch(ctx, &node, patch)
+}
+
+// isEvictable skips pods that would come straight back or are already done,
+// like kubectl drain --ignore-daemonsets
+func isEvictable(pod *corev1.Pod) bool {
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false
+	}
+	if _, ok := pod.Annotations[corev1.MirrorPodAnnotationKey]; ok {
+		return false
+	}
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "DaemonSet" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,146 @@
+package drain
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func pod(name string, node string, mutate func(*corev1.Pod)) *corev1.Pod {
+	p := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: node},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	if mutate != nil {
+		mutate(p)
+	}
+	return p
+}
+
+func newClient(objs ...client.Object) client.Client {
+	return fake.NewClientBuilder().
+		WithScheme(clientgoscheme.Scheme).
+		WithObjects(objs...).
+		WithIndex(&corev1.Pod{}, "spec.nodeName", func(obj client.Object) []string {
+			return []string{obj.(*corev1.Pod).Spec.NodeName}
+		}).
+		Build()
+}
+
+func TestIsEvictable(t *testing.T) {
+	tests := []struct {
+		name string
+		pod  *corev1.Pod
+		want bool
+	}{
+		{name: "running", pod: pod("web", "worker-01", nil), want: true},
+		{name: "pending", pod: pod("web", "worker-01", func(p *corev1.Pod) { p.Status.Phase = corev1.PodPending }), want: true},
+		{name: "succeeded", pod: pod("job", "worker-01", func(p *corev1.Pod) { p.Status.Phase = corev1.PodSucceeded })},
+		{name: "failed", pod: pod("job", "worker-01", func(p *corev1.Pod) { p.Status.Phase = corev1.PodFailed })},
+		{
+			name: "mirror",
+			pod: pod("etcd", "worker-01", func(p *corev1.Pod) {
+				p.Annotations = map[string]string{corev1.MirrorPodAnnotationKey: "hash"}
+			}),
+		},
+		{
+			name: "daemonset",
+			pod: pod("node-exporter", "worker-01", func(p *corev1.Pod) {
+				p.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "node-exporter"}}
+			}),
+		},
+		{
+			name: "replicaset",
+			pod: pod("web", "worker-01", func(p *corev1.Pod) {
+				p.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web"}}
+			}),
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isEvictable(tt.pod); got != tt.want {
+				t.Errorf("isEvictable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNode(t *testing.T) {
+	ctx := context.Background()
+	daemon := pod("node-exporter", "worker-01", func(p *corev1.Pod) {
+		p.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "node-exporter"}}
+	})
+	c := newClient(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-01"}},
+		pod("web", "worker-01", nil),
+		daemon,
+		pod("other", "worker-02", nil),
+	)
+
+	drained, err := Node(ctx, c, c, "worker-01")
+	if err != nil {
+		t.Fatalf("Node() error = %v", err)
+	}
+	if drained {
+		t.Errorf("Node() = true while a pod was still being evicted")
+	}
+
+	var node corev1.Node
+	if err := c.Get(ctx, types.NamespacedName{Name: "worker-01"}, &node); err != nil {
+		t.Fatal(err)
+	}
+	if !node.Spec.Unschedulable {
+		t.Errorf("node not cordoned")
+	}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(pod("web", "", nil)), &corev1.Pod{}); !apierrors.IsNotFound(err) {
+		t.Errorf("web pod not evicted: %v", err)
+	}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(daemon), &corev1.Pod{}); err != nil {
+		t.Errorf("DaemonSet pod was evicted: %v", err)
+	}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(pod("other", "", nil)), &corev1.Pod{}); err != nil {
+		t.Errorf("pod of another node was evicted: %v", err)
+	}
+
+	drained, err = Node(ctx, c, c, "worker-01")
+	if err != nil || !drained {
+		t.Errorf("Node() = %v, %v once only the DaemonSet pod is left, want true", drained, err)
+	}
+	if empty, err := Empty(ctx, c, "worker-01"); err != nil || !empty {
+		t.Errorf("Empty() = %v, %v, want true", empty, err)
+	}
+	if empty, err := Empty(ctx, c, "worker-02"); err != nil || empty {
+		t.Errorf("Empty() = %v, %v for a node with a pod, want false", empty, err)
+	}
+
+	if err := Uncordon(ctx, c, "worker-01"); err != nil {
+		t.Fatalf("Uncordon() error = %v", err)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: "worker-01"}, &node); err != nil {
+		t.Fatal(err)
+	}
+	if node.Spec.Unschedulable {
+		t.Errorf("node still cordoned")
+	}
+}
+
+func TestMissingNode(t *testing.T) {
+	ctx := context.Background()
+	c := newClient()
+
+	if drained, err := Node(ctx, c, c, "worker-99"); err != nil || !drained {
+		t.Errorf("Node() = %v, %v for a missing node, want drained", drained, err)
+	}
+	if err := Uncordon(ctx, c, "worker-99"); err != nil {
+		t.Errorf("Uncordon() error = %v for a missing node", err)
+	}
+}
@@ -0,0 +1,350 @@
+// Package energy estimates the energy used by powered on servers and what it
+// costs, per server and per pool, for metrics and periodic EnergyReports.
+package energy
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/budget"
+	"github.com/Unbounder1/bare-metal-controller/internal/listing"
+)
+
+// maxGap bounds the time a single check accounts for, so a stalled manager
+// doesn't bill a server that was since powered off for the whole gap
+const maxGap = 10 * time.Minute
+
+// Options contains configuration for energy reporting.
+type Options struct {
+	// Interval is how often power draw is sampled. Zero disables energy
+	// reporting.
+	Interval time.Duration
+
+	// PricePerKWh is the electricity price costs are computed with
+	PricePerKWh float64
+
+	// Currency is recorded in reports next to the costs
+	Currency string
+
+	// ServerWatts is the draw assumed for powered on servers without a
+	// power reading or cap
+	ServerWatts int
+
+	// PoolLabel is the Server label servers are grouped into pools by.
+	// Empty groups them by ServerClass.
+	PoolLabel string
+
+	// ReportPeriod is how much time each EnergyReport covers. Zero writes
+	// no reports.
+	ReportPeriod time.Duration
+}
+
+// DefaultOptions returns the default energy reporting options.
+func DefaultOptions() Options {
+	return Options{
+		Interval: time.Minute,
+		Currency: "USD",
+	}
+}
+
+// BindFlags binds the energy options to command line flags.
+// The prefix can be used to namespace the flags (e.g., "energy-").
+func (o *Options) BindFlags(fs *flag.FlagSet, prefix string) {
+	fs.DurationVar(&o.Interval, prefix+"interval", o.Interval,
+		"How often to sample the power draw of servers for energy metrics. 0 to disable.")
+	fs.Float64Var(&o.PricePerKWh, prefix+"price-per-kwh", o.PricePerKWh,
+		"Electricity price per kWh energy costs are computed with.")
+	fs.StringVar(&o.Currency, prefix+"currency", o.Currency,
+		"Currency of --energy-price-per-kwh, recorded in energy reports.")
+	fs.IntVar(&o.ServerWatts, prefix+"server-watts", o.ServerWatts,
+		"Power draw assumed for powered on servers without a BMC reading or power cap.")
+	fs.StringVar(&o.PoolLabel, prefix+"pool-label", o.PoolLabel,
+		"Server label to group servers into pools by. Empty to group them by ServerClass.")
+	fs.DurationVar(&o.ReportPeriod, prefix+"report-period", o.ReportPeriod,
+		"Time each EnergyReport covers, e.g. 24h. 0 to only export metrics.")
+}
+
+// Validate validates the options.
+func (o *Options) Validate() error {
+	if !o.Enabled() {
+		return nil
+	}
+	if o.PricePerKWh < 0 {
+		return fmt.Errorf("energy price must not be negative")
+	}
+	if o.ServerWatts < 0 {
+		return fmt.Errorf("energy server watts must not be negative")
+	}
+	if o.ReportPeriod < 0 {
+		return fmt.Errorf("energy report period must not be negative")
+	}
+	if o.ReportPeriod > 0 && o.ReportPeriod < o.Interval {
+		return fmt.Errorf("energy report period must not be shorter than the interval")
+	}
+	return nil
+}
+
+// Enabled returns true if energy use should be tracked.
+func (o *Options) Enabled() bool {
+	return o.Interval > 0
+}
+
+// usage accumulates the energy of a server over a report period
+type usage struct {
+	pool      string
+	wattHours float64
+	seconds   float64
+}
+
+// Meter implements manager.Runnable. It samples the estimated draw of every
+// powered on server, adds it up into energy metrics and writes an
+// EnergyReport at the end of each report period.
+type Meter struct {
+	options Options
+	client  client.Client
+
+	last        time.Time
+	periodStart time.Time
+	pools       map[string]string
+	period      map[string]*usage
+}
+
+// Ensure Meter implements manager.Runnable
+var _ manager.Runnable = &Meter{}
+
+// NewMeter creates a new energy reporting runnable.
+func NewMeter(opts Options, mgr manager.Manager) (*Meter, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return &Meter{
+		options: opts,
+		client:  mgr.GetClient(),
+		pools:   map[string]string{},
+		period:  map[string]*usage{},
+	}, nil
+}
+
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=energyreports,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=bare-metal-controller.bare-metal.io,resources=energyreports/status,verbs=get;update;patch
+
+// Start implements manager.Runnable. It samples servers until ctx is done.
+func (m *Meter) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("energy")
+	logger.Info("Tracking server energy use", "pricePerKWh", m.options.PricePerKWh, "reportPeriod", m.options.ReportPeriod)
+
+	ticker := time.NewTicker(m.options.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			if err := m.sample(ctx, now); err != nil {
+				logger.Error(err, "Failed to sample server power draw")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+// Returns true so only one replica writes reports.
+func (m *Meter) NeedLeaderElection() bool {
+	return true
+}
+
+// sample adds the energy used since the last sample, assuming each server
+// drew its current estimate throughout, and writes the report of a period
+// that ended
+func (m *Meter) sample(ctx context.Context, now time.Time) error {
+	if m.last.IsZero() {
+		m.last = now
+		m.periodStart = m.periodOf(now)
+		return nil
+	}
+
+	// Split the sample at the end of a report period, so each period gets
+	// its share
+	if m.options.ReportPeriod > 0 {
+		if end := m.periodStart.Add(m.options.ReportPeriod); !now.Before(end) {
+			if err := m.account(ctx, end); err != nil {
+				return err
+			}
+			if err := m.writeReport(ctx, end); err != nil {
+				log.FromContext(ctx).WithName("energy").Error(err, "Failed to write energy report", "start", m.periodStart)
+			}
+			m.periodStart = m.periodOf(now)
+			m.period = map[string]*usage{}
+		}
+	}
+	return m.account(ctx, now)
+}
+
+// account adds the energy used between the last sample and now
+func (m *Meter) account(ctx context.Context, now time.Time) error {
+	elapsed := now.Sub(m.last)
+	if elapsed > maxGap {
+		elapsed = maxGap
+	}
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	seen := map[string]bool{}
+	err := listing.Servers(ctx, m.client, 0, func(server *baremetalcontrollerv1.Server) error {
+		seen[server.Name] = true
+		pool := m.poolOf(server)
+		if previous, ok := m.pools[server.Name]; ok && previous != pool {
+			forget(server.Name)
+		}
+		m.pools[server.Name] = pool
+
+		if !budget.Powered(server) {
+			powerWatts.WithLabelValues(server.Name, pool).Set(0)
+			return nil
+		}
+		watts := m.watts(server)
+		powerWatts.WithLabelValues(server.Name, pool).Set(float64(watts))
+
+		wattHours := float64(watts) * elapsed.Hours()
+		energyKWh.WithLabelValues(server.Name, pool).Add(wattHours / 1000)
+		poweredOnSeconds.WithLabelValues(server.Name, pool).Add(elapsed.Seconds())
+		energyCost.WithLabelValues(server.Name, pool).Add(wattHours / 1000 * m.options.PricePerKWh)
+
+		if m.options.ReportPeriod > 0 {
+			u := m.period[server.Name]
+			if u == nil {
+				u = &usage{}
+				m.period[server.Name] = u
+			}
+			u.pool = pool
+			u.wattHours += wattHours
+			u.seconds += elapsed.Seconds()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	m.last = now
+
+	// Forget deleted servers. Their usage stays in the current period.
+	for name := range m.pools {
+		if !seen[name] {
+			forget(name)
+			delete(m.pools, name)
+		}
+	}
+	return nil
+}
+
+// watts returns the estimated draw of a powered on server: the last BMC
+// reading, its power cap, or the configured default
+func (m *Meter) watts(server *baremetalcontrollerv1.Server) int32 {
+	if server.Status.PowerCap != nil && server.Status.PowerCap.ConsumedWatts > 0 {
+		return server.Status.PowerCap.ConsumedWatts
+	}
+	if server.Spec.PowerCapWatts != nil {
+		return *server.Spec.PowerCapWatts
+	}
+	return int32(m.options.ServerWatts)
+}
+
+func (m *Meter) poolOf(server *baremetalcontrollerv1.Server) string {
+	if m.options.PoolLabel != "" {
+		return server.Labels[m.options.PoolLabel]
+	}
+	return server.Spec.ServerClassName
+}
+
+// periodOf returns the start of the report period containing t, aligned to
+// multiples of the period since the Unix epoch, e.g. midnight UTC for 24h
+func (m *Meter) periodOf(t time.Time) time.Time {
+	if m.options.ReportPeriod <= 0 {
+		return t
+	}
+	return t.UTC().Truncate(m.options.ReportPeriod)
+}
+
+// writeReport writes the EnergyReport of the period ending at end. A report
+// that already exists, e.g. written before a leader change, is kept.
+func (m *Meter) writeReport(ctx context.Context, end time.Time) error {
+	report := &baremetalcontrollerv1.EnergyReport{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "energy-" + m.periodStart.UTC().Format("20060102-1504"),
+		},
+		Spec: baremetalcontrollerv1.EnergyReportSpec{
+			Start:       metav1.NewTime(m.periodStart),
+			End:         metav1.NewTime(end.UTC()),
+			PricePerKWh: strconv.FormatFloat(m.options.PricePerKWh, 'f', -1, 64),
+			Currency:    m.options.Currency,
+			PoolLabel:   m.options.PoolLabel,
+		},
+	}
+	status := m.summarize()
+	if err := m.client.Create(ctx, report); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return nil
+		}
+		return err
+	}
+	report.Status = status
+	if err := m.client.Status().Update(ctx, report); err != nil {
+		return err
+	}
+	log.FromContext(ctx).WithName("energy").Info("Wrote energy report", "report", report.Name,
+		"wattHours", status.Total.EnergyWattHours, "cost", status.Total.Cost)
+	return nil
+}
+
+// summarize adds up the usage of the period per server, per pool and in
+// total
+func (m *Meter) summarize() baremetalcontrollerv1.EnergyReportStatus {
+	var total usage
+	pools := map[string]*usage{}
+	var servers []baremetalcontrollerv1.EnergyUsage
+	for name, u := range m.period {
+		servers = append(servers, m.energyUsage(name, u))
+		pool := pools[u.pool]
+		if pool == nil {
+			pool = &usage{}
+			pools[u.pool] = pool
+		}
+		pool.wattHours += u.wattHours
+		pool.seconds += u.seconds
+		total.wattHours += u.wattHours
+		total.seconds += u.seconds
+	}
+	var poolUsage []baremetalcontrollerv1.EnergyUsage
+	for name, u := range pools {
+		poolUsage = append(poolUsage, m.energyUsage(name, u))
+	}
+	sort.Slice(servers, func(i, j int) bool { return servers[i].Name < servers[j].Name })
+	sort.Slice(poolUsage, func(i, j int) bool { return poolUsage[i].Name < poolUsage[j].Name })
+
+	return baremetalcontrollerv1.EnergyReportStatus{
+		Total:   m.energyUsage("", &total),
+		Pools:   poolUsage,
+		Servers: servers,
+	}
+}
+
+func (m *Meter) energyUsage(name string, u *usage) baremetalcontrollerv1.EnergyUsage {
+	return baremetalcontrollerv1.EnergyUsage{
+		Name:             name,
+		EnergyWattHours:  int64(u.wattHours + 0.5),
+		PoweredOnSeconds: int64(u.seconds + 0.5),
+		Cost:             strconv.FormatFloat(u.wattHours/1000*m.options.PricePerKWh, 'f', 2, 64),
+	}
+}
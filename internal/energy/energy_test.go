@@ -0,0 +1,189 @@
+package energy
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+func meteredServer(name string, class string, status baremetalcontrollerv1.CurrentStatus) *baremetalcontrollerv1.Server {
+	return &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"team": "ml"}},
+		Spec:       baremetalcontrollerv1.ServerSpec{ServerClassName: class},
+		Status:     baremetalcontrollerv1.ServerStatus{Status: status},
+	}
+}
+
+func newTestMeter(t *testing.T, opts Options, objs ...client.Object) (*Meter, client.Client) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := baremetalcontrollerv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).
+		WithStatusSubresource(&baremetalcontrollerv1.EnergyReport{}).Build()
+	return &Meter{options: opts, client: c, pools: map[string]string{}, period: map[string]*usage{}}, c
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{name: "disabled", opts: Options{PricePerKWh: -1}},
+		{name: "metrics only", opts: Options{Interval: time.Minute, PricePerKWh: 0.3}},
+		{name: "daily reports", opts: Options{Interval: time.Minute, ReportPeriod: 24 * time.Hour}},
+		{name: "negative price", opts: Options{Interval: time.Minute, PricePerKWh: -0.1}, wantErr: true},
+		{name: "negative watts", opts: Options{Interval: time.Minute, ServerWatts: -1}, wantErr: true},
+		{name: "negative period", opts: Options{Interval: time.Minute, ReportPeriod: -time.Hour}, wantErr: true},
+		{name: "period shorter than interval", opts: Options{Interval: time.Hour, ReportPeriod: time.Minute}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWatts(t *testing.T) {
+	m := &Meter{options: Options{ServerWatts: 300}}
+	tests := []struct {
+		name     string
+		reading  int32
+		capWatts *int32
+		want     int32
+	}{
+		{name: "default", want: 300},
+		{name: "power cap", capWatts: ptr.To[int32](450), want: 450},
+		{name: "BMC reading", reading: 212, capWatts: ptr.To[int32](450), want: 212},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := meteredServer("worker-01", "", baremetalcontrollerv1.StatusActive)
+			server.Spec.PowerCapWatts = tt.capWatts
+			if tt.reading > 0 {
+				server.Status.PowerCap = &baremetalcontrollerv1.PowerCapStatus{ConsumedWatts: tt.reading}
+			}
+			if got := m.watts(server); got != tt.want {
+				t.Errorf("watts() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPeriodOf(t *testing.T) {
+	at := time.Date(2025, 3, 10, 13, 47, 0, 0, time.FixedZone("CET", 3600))
+	tests := []struct {
+		period time.Duration
+		want   time.Time
+	}{
+		{period: 24 * time.Hour, want: time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)},
+		{period: time.Hour, want: time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)},
+		{period: 0, want: at},
+	}
+	for _, tt := range tests {
+		m := &Meter{options: Options{ReportPeriod: tt.period}}
+		if got := m.periodOf(at); !got.Equal(tt.want) {
+			t.Errorf("periodOf() with period %s = %s, want %s", tt.period, got, tt.want)
+		}
+	}
+}
+
+func TestSample(t *testing.T) {
+	m, _ := newTestMeter(t, Options{Interval: time.Minute, PricePerKWh: 0.5, ServerWatts: 400},
+		meteredServer("energy-01", "gpu", baremetalcontrollerv1.StatusActive),
+		meteredServer("energy-02", "gpu", baremetalcontrollerv1.StatusOffline),
+	)
+	ctx := context.Background()
+	start := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	// The first sample only starts the clock
+	for _, at := range []time.Time{start, start.Add(5 * time.Minute), start.Add(3 * time.Hour)} {
+		if err := m.sample(ctx, at); err != nil {
+			t.Fatalf("sample() error = %v", err)
+		}
+	}
+	// 5 minutes, then a gap bounded to maxGap
+	wantKWh := 0.4 * (5*time.Minute + maxGap).Hours()
+	if got := testutil.ToFloat64(energyKWh.WithLabelValues("energy-01", "gpu")); math.Abs(got-wantKWh) > 1e-9 {
+		t.Errorf("energy = %v kWh, want %v", got, wantKWh)
+	}
+	if got := testutil.ToFloat64(energyCost.WithLabelValues("energy-01", "gpu")); math.Abs(got-wantKWh*0.5) > 1e-9 {
+		t.Errorf("cost = %v, want %v", got, wantKWh*0.5)
+	}
+	if got := testutil.ToFloat64(powerWatts.WithLabelValues("energy-02", "gpu")); got != 0 {
+		t.Errorf("powered off server draws %vW", got)
+	}
+	if got := testutil.ToFloat64(poweredOnSeconds.WithLabelValues("energy-02", "gpu")); got != 0 {
+		t.Errorf("powered off server on for %vs", got)
+	}
+}
+
+func TestSampleWritesReports(t *testing.T) {
+	capped := meteredServer("report-01", "gpu", baremetalcontrollerv1.StatusActive)
+	capped.Spec.PowerCapWatts = ptr.To[int32](1000)
+	m, c := newTestMeter(t, Options{Interval: time.Minute, PricePerKWh: 0.2, Currency: "EUR", ServerWatts: 500, PoolLabel: "team", ReportPeriod: time.Hour},
+		capped,
+		meteredServer("report-02", "cpu", baremetalcontrollerv1.StatusActive),
+	)
+	ctx := context.Background()
+	start := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	// The sample crossing 13:00 is split between the two periods
+	for at := start; at.Before(start.Add(time.Hour)); at = at.Add(10 * time.Minute) {
+		if err := m.sample(ctx, at); err != nil {
+			t.Fatalf("sample() error = %v", err)
+		}
+	}
+	if err := m.sample(ctx, start.Add(65*time.Minute)); err != nil {
+		t.Fatalf("sample() error = %v", err)
+	}
+
+	var report baremetalcontrollerv1.EnergyReport
+	if err := c.Get(ctx, client.ObjectKey{Name: "energy-20250310-1200"}, &report); err != nil {
+		t.Fatalf("report of the first hour: %v", err)
+	}
+	if !report.Spec.End.Time.Equal(start.Add(time.Hour)) || report.Spec.Currency != "EUR" || report.Spec.PricePerKWh != "0.2" {
+		t.Errorf("report spec = %+v", report.Spec)
+	}
+	total := report.Status.Total
+	if total.EnergyWattHours != 1500 || total.PoweredOnSeconds != 7200 || total.Cost != "0.30" {
+		t.Errorf("total = %+v, want 1500Wh over 7200s costing 0.30", total)
+	}
+	if len(report.Status.Pools) != 1 || report.Status.Pools[0].Name != "ml" {
+		t.Errorf("pools = %+v, want servers grouped by their team label", report.Status.Pools)
+	}
+	if len(report.Status.Servers) != 2 || report.Status.Servers[0].Name != "report-01" || report.Status.Servers[0].EnergyWattHours != 1000 {
+		t.Errorf("servers = %+v", report.Status.Servers)
+	}
+
+	// The next period starts with the rest of the split sample
+	if u := m.period["report-01"]; u == nil || u.seconds != 300 {
+		t.Errorf("usage of the next period = %+v, want 5 minutes", u)
+	}
+
+	// A report written before, e.g. by the previous leader, is kept
+	m.periodStart = start
+	if err := m.writeReport(ctx, start.Add(time.Hour)); err != nil {
+		t.Errorf("writeReport() error = %v for an existing report", err)
+	}
+	if err := c.Get(ctx, client.ObjectKey{Name: "energy-20250310-1200"}, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Status.Total.EnergyWattHours != 1500 {
+		t.Errorf("existing report overwritten: %+v", report.Status.Total)
+	}
+}
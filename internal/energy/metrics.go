@@ -0,0 +1,42 @@
+package energy
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	powerWatts = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "baremetal_server_power_watts",
+		Help: "Estimated power draw of the server, 0 while powered off.",
+	}, []string{"server", "pool"})
+
+	energyKWh = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "baremetal_server_energy_kwh_total",
+		Help: "Estimated energy drawn by the server while powered on, in kWh.",
+	}, []string{"server", "pool"})
+
+	poweredOnSeconds = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "baremetal_server_powered_on_seconds_total",
+		Help: "Time the server has been powered on.",
+	}, []string{"server", "pool"})
+
+	energyCost = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "baremetal_server_energy_cost_total",
+		Help: "Cost of the energy drawn by the server at the configured price per kWh.",
+	}, []string{"server", "pool"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(powerWatts, energyKWh, poweredOnSeconds, energyCost)
+}
+
+// forget removes the series of a server that no longer exists or moved to
+// another pool
+func forget(server string) {
+	labels := prometheus.Labels{"server": server}
+	powerWatts.DeletePartialMatch(labels)
+	energyKWh.DeletePartialMatch(labels)
+	poweredOnSeconds.DeletePartialMatch(labels)
+	energyCost.DeletePartialMatch(labels)
+}
@@ -0,0 +1,195 @@
+// Package fleet creates a synthetic fleet of simulated Servers, so the
+// reconciler, cache and autoscaler path can be load tested without hardware.
+package fleet
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+// SyntheticLabel marks the Servers created for a synthetic fleet, e.g. to
+// delete them with kubectl delete servers -l baremetal.io/synthetic=true
+const SyntheticLabel = "baremetal.io/synthetic"
+
+// createWorkers bounds the concurrent creates at startup
+const createWorkers = 16
+
+// Options contains configuration for the synthetic fleet and for how all
+// simulated servers behave.
+type Options struct {
+	// Servers is the number of simulated Servers to create at startup
+	Servers int
+
+	// CommandLatency delays every simulated power command
+	CommandLatency time.Duration
+
+	// BootLatency is how long a simulated server takes to become reachable
+	// after powering on
+	BootLatency time.Duration
+
+	// ShutdownLatency is how long a simulated server stays reachable after
+	// powering off
+	ShutdownLatency time.Duration
+
+	// FailureRate is the probability, from 0 to 1, that a simulated power
+	// command fails
+	FailureRate float64
+
+	// AuthFailureRate is the probability, from 0 to 1, that a simulated
+	// power command fails with a BMC authentication error
+	AuthFailureRate float64
+
+	// BootJitter adds a random delay of up to this long to each simulated
+	// boot
+	BootJitter time.Duration
+
+	// FlapRate is the probability, from 0 to 1, that a running simulated
+	// server fails a reachability check
+	FlapRate float64
+}
+
+// BindFlags binds the fleet options to command line flags.
+// The prefix can be used to namespace the flags (e.g., "simulate-").
+func (o *Options) BindFlags(fs *flag.FlagSet, prefix string) {
+	fs.IntVar(&o.Servers, prefix+"servers", o.Servers,
+		"Number of simulated Servers to create at startup for scale testing. 0 to disable.")
+	fs.DurationVar(&o.CommandLatency, prefix+"command-latency", o.CommandLatency,
+		"How long every simulated power command takes.")
+	fs.DurationVar(&o.BootLatency, prefix+"boot-latency", o.BootLatency,
+		"How long a simulated server takes to become reachable after powering on.")
+	fs.DurationVar(&o.ShutdownLatency, prefix+"shutdown-latency", o.ShutdownLatency,
+		"How long a simulated server stays reachable after powering off.")
+	fs.Float64Var(&o.FailureRate, prefix+"failure-rate", o.FailureRate,
+		"Probability from 0 to 1 that a simulated power command fails.")
+	fs.Float64Var(&o.AuthFailureRate, prefix+"auth-failure-rate", o.AuthFailureRate,
+		"Probability from 0 to 1 that a simulated power command fails as if the BMC rejected the credentials.")
+	fs.DurationVar(&o.BootJitter, prefix+"boot-jitter", o.BootJitter,
+		"Random extra delay of up to this long added to each simulated boot.")
+	fs.Float64Var(&o.FlapRate, prefix+"flap-rate", o.FlapRate,
+		"Probability from 0 to 1 that a running simulated server fails a reachability check.")
+}
+
+// Validate validates the options.
+func (o *Options) Validate() error {
+	if o.Servers < 0 || o.Servers > 65536 {
+		return fmt.Errorf("simulated servers must be between 0 and 65536")
+	}
+	if o.CommandLatency < 0 || o.BootLatency < 0 || o.ShutdownLatency < 0 || o.BootJitter < 0 {
+		return fmt.Errorf("simulated latencies must not be negative")
+	}
+	for name, rate := range map[string]float64{
+		"failure rate":      o.FailureRate,
+		"auth failure rate": o.AuthFailureRate,
+		"flap rate":         o.FlapRate,
+	} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("simulated %s must be between 0 and 1", name)
+		}
+	}
+	return nil
+}
+
+// Enabled returns true if a synthetic fleet should be created.
+func (o *Options) Enabled() bool {
+	return o.Servers > 0
+}
+
+// Generator implements manager.Runnable and creates the synthetic fleet
+// when the manager starts. Existing Servers are left alone, so it is safe to
+// leave enabled across restarts.
+type Generator struct {
+	options Options
+	client  client.Client
+}
+
+// Ensure Generator implements manager.Runnable
+var _ manager.Runnable = &Generator{}
+
+// NewGenerator creates a new synthetic fleet runnable.
+func NewGenerator(opts Options, mgr manager.Manager) (*Generator, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return &Generator{
+		options: opts,
+		client:  mgr.GetClient(),
+	}, nil
+}
+
+// Start implements manager.Runnable. It creates the fleet once and returns.
+func (g *Generator) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("fleet")
+
+	names := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	created := 0
+	for i := 0; i < createWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range names {
+				server := SyntheticServer(index)
+				if err := g.client.Create(ctx, server); err != nil {
+					if !apierrors.IsAlreadyExists(err) {
+						logger.Error(err, "Failed to create simulated server", "server", server.Name)
+					}
+					continue
+				}
+				mu.Lock()
+				created++
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for i := 0; i < g.options.Servers && ctx.Err() == nil; i++ {
+		names <- i
+	}
+	close(names)
+	wg.Wait()
+
+	logger.Info("Created simulated servers", "created", created, "total", g.options.Servers)
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+// Returns true so only one replica creates the fleet.
+func (g *Generator) NeedLeaderElection() bool {
+	return true
+}
+
+// SyntheticServer returns the simulated IPMI Server with the given index.
+// Credentials are inline, so no Secrets are needed, and its address is in
+// 10.200.0.0/16 but never contacted.
+func SyntheticServer(index int) *baremetalcontrollerv1.Server {
+	return &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("sim-%05d", index),
+			Labels:      map[string]string{SyntheticLabel: "true"},
+			Annotations: map[string]string{baremetalcontrollerv1.SimulateAnnotation: "true"},
+		},
+		Spec: baremetalcontrollerv1.ServerSpec{
+			PowerState: baremetalcontrollerv1.PowerStateOff,
+			Type:       baremetalcontrollerv1.ControlTypeIPMI,
+			Control: baremetalcontrollerv1.ControlSpecs{
+				IPMI: &baremetalcontrollerv1.IPMISpecs{
+					Address:  fmt.Sprintf("10.200.%d.%d", index/256, index%256),
+					Username: "simulated",
+					Password: "simulated",
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,138 @@
+package fleet
+
+import (
+	"context"
+	"flag"
+	"net"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{name: "disabled"},
+		{name: "realistic", opts: Options{Servers: 5000, CommandLatency: 2 * time.Second, BootLatency: time.Minute, FailureRate: 0.01, FlapRate: 0.001}},
+		{name: "largest fleet", opts: Options{Servers: 65536}},
+		{name: "too many servers", opts: Options{Servers: 65537}, wantErr: true},
+		{name: "negative servers", opts: Options{Servers: -1}, wantErr: true},
+		{name: "negative latency", opts: Options{ShutdownLatency: -time.Second}, wantErr: true},
+		{name: "negative jitter", opts: Options{BootJitter: -time.Second}, wantErr: true},
+		{name: "failure rate above 1", opts: Options{FailureRate: 1.5}, wantErr: true},
+		{name: "negative auth failure rate", opts: Options{AuthFailureRate: -0.1}, wantErr: true},
+		{name: "every check flaps", opts: Options{FlapRate: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBindFlags(t *testing.T) {
+	var opts Options
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	opts.BindFlags(fs, "simulate-")
+	if err := fs.Parse([]string{"--simulate-servers=100", "--simulate-boot-latency=90s", "--simulate-failure-rate=0.05"}); err != nil {
+		t.Fatal(err)
+	}
+	if opts.Servers != 100 || opts.BootLatency != 90*time.Second || opts.FailureRate != 0.05 || !opts.Enabled() {
+		t.Errorf("options = %+v", opts)
+	}
+}
+
+func TestSyntheticServer(t *testing.T) {
+	names := map[string]bool{}
+	addresses := map[string]bool{}
+	for _, index := range []int{0, 1, 255, 256, 65535} {
+		server := SyntheticServer(index)
+		if names[server.Name] || addresses[server.Spec.Control.IPMI.Address] {
+			t.Errorf("SyntheticServer(%d) = %s at %s, already used", index, server.Name, server.Spec.Control.IPMI.Address)
+		}
+		names[server.Name] = true
+		addresses[server.Spec.Control.IPMI.Address] = true
+
+		if ip := net.ParseIP(server.Spec.Control.IPMI.Address); ip == nil || !(&net.IPNet{IP: net.IPv4(10, 200, 0, 0), Mask: net.CIDRMask(16, 32)}).Contains(ip) {
+			t.Errorf("SyntheticServer(%d) address = %s, want one in 10.200.0.0/16", index, server.Spec.Control.IPMI.Address)
+		}
+		if server.Labels[SyntheticLabel] != "true" || server.Annotations[baremetalcontrollerv1.SimulateAnnotation] != "true" {
+			t.Errorf("SyntheticServer(%d) not marked synthetic and simulated", index)
+		}
+		if server.Spec.PowerState != baremetalcontrollerv1.PowerStateOff {
+			t.Errorf("SyntheticServer(%d) powerState = %s, want off", index, server.Spec.PowerState)
+		}
+	}
+	if name := SyntheticServer(42).Name; name != "sim-00042" {
+		t.Errorf("SyntheticServer(42) name = %s", name)
+	}
+}
+
+func TestGeneratorStart(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := baremetalcontrollerv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	// A server left from an earlier run, changed since
+	existing := SyntheticServer(3)
+	existing.Spec.PowerState = baremetalcontrollerv1.PowerStateOn
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(existing,
+		&baremetalcontrollerv1.Server{ObjectMeta: metav1.ObjectMeta{Name: "worker-01"}}).Build()
+	g := &Generator{options: Options{Servers: 50}, client: c}
+	ctx := context.Background()
+
+	// Restarts create nothing new
+	for i := 0; i < 2; i++ {
+		if err := g.Start(ctx); err != nil {
+			t.Fatalf("Start() error = %v", err)
+		}
+	}
+
+	var servers baremetalcontrollerv1.ServerList
+	if err := c.List(ctx, &servers, client.MatchingLabels{SyntheticLabel: "true"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(servers.Items) != 50 {
+		t.Errorf("%d synthetic servers, want 50", len(servers.Items))
+	}
+	var kept baremetalcontrollerv1.Server
+	if err := c.Get(ctx, client.ObjectKey{Name: existing.Name}, &kept); err != nil {
+		t.Fatal(err)
+	}
+	if kept.Spec.PowerState != baremetalcontrollerv1.PowerStateOn {
+		t.Errorf("existing synthetic server overwritten")
+	}
+}
+
+func TestGeneratorStopsWhenCanceled(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := baremetalcontrollerv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	g := &Generator{options: Options{Servers: 1000}, client: c}
+	if err := g.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	var servers baremetalcontrollerv1.ServerList
+	if err := c.List(context.Background(), &servers); err != nil {
+		t.Fatal(err)
+	}
+	if len(servers.Items) != 0 {
+		t.Errorf("%d servers created after the manager stopped", len(servers.Items))
+	}
+}
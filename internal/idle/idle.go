@@ -0,0 +1,359 @@
+// Package idle powers off servers whose nodes have been empty or nearly idle
+// for a while, for clusters that don't run the cluster autoscaler.
+package idle
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/drain"
+	"github.com/Unbounder1/bare-metal-controller/internal/listing"
+)
+
+// nodeMetricsKind is read as unstructured, so the metrics API client isn't
+// needed as a dependency
+var nodeMetricsKind = schema.GroupVersionKind{Group: "metrics.k8s.io", Version: "v1beta1", Kind: "NodeMetrics"}
+
+// Options contains configuration for idle power-off.
+type Options struct {
+	// After is how long a node must stay idle before its server is powered
+	// off. Zero disables idle power-off.
+	After time.Duration
+
+	// CPUThreshold is the fraction of allocatable CPU below which a node
+	// with pods counts as idle. Zero only powers off empty nodes.
+	CPUThreshold float64
+
+	// MinActive is the number of active servers that are never powered off
+	MinActive int
+
+	// Selector limits idle power-off to matching Servers
+	Selector string
+
+	// Interval is how often nodes are checked
+	Interval time.Duration
+
+	// DrainTimeout bounds how long an idle node is drained. A node that
+	// can't be drained in time is uncordoned and left running.
+	DrainTimeout time.Duration
+}
+
+// DefaultOptions returns the default idle power-off options.
+func DefaultOptions() Options {
+	return Options{
+		CPUThreshold: 0.05,
+		MinActive:    1,
+		Interval:     time.Minute,
+		DrainTimeout: 5 * time.Minute,
+	}
+}
+
+// BindFlags binds the idle options to command line flags.
+// The prefix can be used to namespace the flags (e.g., "idle-").
+func (o *Options) BindFlags(fs *flag.FlagSet, prefix string) {
+	fs.DurationVar(&o.After, prefix+"power-off-after", o.After,
+		"Power off servers whose nodes have been idle this long. 0 to disable.")
+	fs.Float64Var(&o.CPUThreshold, prefix+"cpu-threshold", o.CPUThreshold,
+		"Fraction of allocatable CPU, from the metrics API, below which a node counts as idle. 0 for empty nodes only.")
+	fs.IntVar(&o.MinActive, prefix+"min-active", o.MinActive,
+		"Number of active servers never powered off for being idle.")
+	fs.StringVar(&o.Selector, prefix+"selector", o.Selector,
+		"Label selector of the Servers that may be powered off for being idle. Empty for all.")
+	fs.DurationVar(&o.Interval, prefix+"interval", o.Interval,
+		"How often to check nodes for idleness.")
+	fs.DurationVar(&o.DrainTimeout, prefix+"drain-timeout", o.DrainTimeout,
+		"How long to drain an idle node before giving up and leaving it running.")
+}
+
+// Validate validates the options.
+func (o *Options) Validate() error {
+	if !o.Enabled() {
+		return nil
+	}
+	if o.CPUThreshold < 0 || o.CPUThreshold > 1 {
+		return fmt.Errorf("idle CPU threshold must be between 0 and 1")
+	}
+	if o.MinActive < 0 {
+		return fmt.Errorf("idle min active must not be negative")
+	}
+	if o.Interval <= 0 {
+		return fmt.Errorf("idle interval must be positive")
+	}
+	if o.DrainTimeout < 0 {
+		return fmt.Errorf("idle drain timeout must not be negative")
+	}
+	if _, err := labels.Parse(o.Selector); err != nil {
+		return fmt.Errorf("invalid idle selector: %w", err)
+	}
+	return nil
+}
+
+// Enabled returns true if idle servers should be powered off.
+func (o *Options) Enabled() bool {
+	return o.After > 0
+}
+
+// Detector implements manager.Runnable. It watches the nodes of active
+// servers and powers off the ones that stayed idle for long enough, draining
+// them first. Servers excluded from autoscaling are left alone.
+type Detector struct {
+	options  Options
+	selector labels.Selector
+	client   client.Client
+	reader   client.Reader
+	recorder record.EventRecorder
+
+	idleSince    map[client.ObjectKey]time.Time
+	drainStarted map[client.ObjectKey]time.Time
+}
+
+// Ensure Detector implements manager.Runnable
+var _ manager.Runnable = &Detector{}
+
+// NewDetector creates a new idle power-off runnable.
+func NewDetector(opts Options, mgr manager.Manager) (*Detector, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	selector, err := labels.Parse(opts.Selector)
+	if err != nil {
+		return nil, err
+	}
+	return &Detector{
+		options:      opts,
+		selector:     selector,
+		client:       mgr.GetClient(),
+		reader:       mgr.GetAPIReader(),
+		recorder:     mgr.GetEventRecorderFor("idle-detector"),
+		idleSince:    map[client.ObjectKey]time.Time{},
+		drainStarted: map[client.ObjectKey]time.Time{},
+	}, nil
+}
+
+// +kubebuilder:rbac:groups=metrics.k8s.io,resources=nodes,verbs=get
+
+// Start implements manager.Runnable. It checks nodes until ctx is done.
+func (d *Detector) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("idle")
+	logger.Info("Powering off idle servers", "after", d.options.After, "minActive", d.options.MinActive)
+
+	ticker := time.NewTicker(d.options.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := d.check(ctx); err != nil {
+				logger.Error(err, "Failed to check for idle servers")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+// Returns true so only one replica powers servers off.
+func (d *Detector) NeedLeaderElection() bool {
+	return true
+}
+
+// check updates how long each active server has been idle and powers off
+// the ones idle for long enough, as long as more than MinActive are left
+func (d *Detector) check(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("idle")
+
+	var active, poweredOn []*baremetalcontrollerv1.Server
+	err := listing.Servers(ctx, d.client, 0, func(server *baremetalcontrollerv1.Server) error {
+		if server.Spec.PowerState.Settled() != baremetalcontrollerv1.PowerStateOn {
+			return nil
+		}
+		if server.Annotations[baremetalcontrollerv1.IdlePowerOffAnnotation] == "true" {
+			poweredOn = append(poweredOn, server.DeepCopy())
+		}
+		if server.Status.Status == baremetalcontrollerv1.StatusActive {
+			active = append(active, server.DeepCopy())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Servers powered on again since, e.g. manually, need their nodes back
+	for _, server := range poweredOn {
+		if err := d.uncordon(ctx, server); err != nil {
+			logger.Error(err, "Failed to uncordon server powered off while idle", "server", server.Name)
+		}
+	}
+
+	seen := map[client.ObjectKey]bool{}
+	remaining := len(active)
+	for _, server := range active {
+		key := client.ObjectKeyFromObject(server)
+		seen[key] = true
+		if !d.eligible(server) {
+			continue
+		}
+
+		idle, err := d.isIdle(ctx, server.NodeName())
+		if err != nil {
+			logger.Error(err, "Failed to check node utilization", "server", server.Name)
+			continue
+		}
+		if !idle && d.drainStarted[key].IsZero() {
+			delete(d.idleSince, key)
+			continue
+		}
+		since, ok := d.idleSince[key]
+		if !ok {
+			d.idleSince[key] = time.Now()
+			continue
+		}
+		if time.Since(since) < d.options.After {
+			continue
+		}
+		if remaining <= d.options.MinActive {
+			if err := d.cancelDrain(ctx, server); err != nil {
+				logger.Error(err, "Failed to uncordon node", "server", server.Name)
+			}
+			continue
+		}
+
+		off, err := d.powerOff(ctx, server)
+		if err != nil {
+			logger.Error(err, "Failed to power off idle server", "server", server.Name)
+			continue
+		}
+		if off {
+			remaining--
+		}
+	}
+
+	// Forget servers that are no longer active, e.g. powered off
+	for key := range d.idleSince {
+		if !seen[key] {
+			delete(d.idleSince, key)
+			delete(d.drainStarted, key)
+		}
+	}
+	return nil
+}
+
+// eligible returns false for servers outside the selector, excluded from
+// autoscaling or protected by their role, which only change power state
+// when edited manually, and for standby servers, which are idle on purpose
+func (d *Detector) eligible(server *baremetalcontrollerv1.Server) bool {
+	if server.Annotations[baremetalcontrollerv1.AutoscalerExcludeAnnotation] == "true" ||
+		server.Annotations[baremetalcontrollerv1.StandbyAnnotation] == "true" ||
+		server.Spec.Role.Protected() {
+		return false
+	}
+	return d.selector.Matches(labels.Set(server.Labels))
+}
+
+// isIdle returns true if the node runs no workload pods, or uses less than
+// the CPU threshold
+func (d *Detector) isIdle(ctx context.Context, name string) (bool, error) {
+	empty, err := drain.Empty(ctx, d.reader, name)
+	if err != nil || empty || d.options.CPUThreshold == 0 {
+		return empty, err
+	}
+
+	var node corev1.Node
+	if err := d.client.Get(ctx, types.NamespacedName{Name: name}, &node); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	allocatable := node.Status.Allocatable.Cpu()
+	if allocatable.IsZero() {
+		return false, nil
+	}
+
+	metrics := &unstructured.Unstructured{}
+	metrics.SetGroupVersionKind(nodeMetricsKind)
+	if err := d.reader.Get(ctx, types.NamespacedName{Name: name}, metrics); err != nil {
+		return false, fmt.Errorf("failed to get metrics of node %s: %w", name, err)
+	}
+	value, _, _ := unstructured.NestedString(metrics.Object, "usage", "cpu")
+	usage, err := resource.ParseQuantity(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid CPU usage %q of node %s: %w", value, name, err)
+	}
+	return float64(usage.MilliValue()) < d.options.CPUThreshold*float64(allocatable.MilliValue()), nil
+}
+
+// powerOff drains the node and powers off the server once it's empty. A node
+// that doesn't drain within the drain timeout is uncordoned and its idle
+// time reset. It returns true once the server is powered off.
+func (d *Detector) powerOff(ctx context.Context, server *baremetalcontrollerv1.Server) (bool, error) {
+	key := client.ObjectKeyFromObject(server)
+	started, ok := d.drainStarted[key]
+	if !ok {
+		started = time.Now()
+		d.drainStarted[key] = started
+		d.recorder.Eventf(server, corev1.EventTypeNormal, "IdlePowerOff",
+			"Node idle for %s, draining before power off", d.options.After)
+	}
+
+	drained, err := drain.Node(ctx, d.client, d.reader, server.NodeName())
+	if err != nil {
+		return false, err
+	}
+	if !drained {
+		if time.Since(started) < d.options.DrainTimeout {
+			return false, nil
+		}
+		delete(d.idleSince, key)
+		d.recorder.Event(server, corev1.EventTypeWarning, "IdlePowerOff", "Node could not be drained in time, leaving it running")
+		return false, d.cancelDrain(ctx, server)
+	}
+
+	// The node stays cordoned until the server is powered on again
+	patch := client.MergeFrom(server.DeepCopy())
+	server.Spec.PowerState = baremetalcontrollerv1.PowerStateOff
+	if server.Annotations == nil {
+		server.Annotations = map[string]string{}
+	}
+	server.Annotations[baremetalcontrollerv1.IdlePowerOffAnnotation] = "true"
+	if err := d.client.Patch(ctx, server, patch); err != nil {
+		return false, fmt.Errorf("failed to power off server %s: %w", server.Name, err)
+	}
+	delete(d.drainStarted, key)
+	delete(d.idleSince, key)
+	d.recorder.Event(server, corev1.EventTypeNormal, "IdlePowerOff", "Powering off idle server")
+	return true, nil
+}
+
+// cancelDrain uncordons a node that was being drained
+func (d *Detector) cancelDrain(ctx context.Context, server *baremetalcontrollerv1.Server) error {
+	key := client.ObjectKeyFromObject(server)
+	if _, ok := d.drainStarted[key]; !ok {
+		return nil
+	}
+	delete(d.drainStarted, key)
+	return drain.Uncordon(ctx, d.client, server.NodeName())
+}
+
+// uncordon makes the node of a server powered on again after an idle
+// power-off schedulable, and clears the annotation
+func (d *Detector) uncordon(ctx context.Context, server *baremetalcontrollerv1.Server) error {
+	if err := drain.Uncordon(ctx, d.client, server.NodeName()); err != nil {
+		return err
+	}
+	patch := client.MergeFrom(server.DeepCopy())
+	delete(server.Annotations, baremetalcontrollerv1.IdlePowerOffAnnotation)
+	return d.client.Patch(ctx, server, patch)
+}
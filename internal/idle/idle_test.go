@@ -0,0 +1,285 @@
+package idle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+func activeServer(name string, annotations map[string]string) *baremetalcontrollerv1.Server {
+	return &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations, Labels: map[string]string{"pool": "batch"}},
+		Spec:       baremetalcontrollerv1.ServerSpec{PowerState: baremetalcontrollerv1.PowerStateOn},
+		Status:     baremetalcontrollerv1.ServerStatus{Status: baremetalcontrollerv1.StatusActive},
+	}
+}
+
+func node(name string, cpu string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     corev1.NodeStatus{Allocatable: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)}},
+	}
+}
+
+func workload(name string, nodeName string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: nodeName},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+// newTestDetector returns a detector whose nodes use the given CPU, as the
+// metrics API would report it
+func newTestDetector(t *testing.T, opts Options, usage map[string]string, objs ...client.Object) (*Detector, client.Client) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := baremetalcontrollerv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).
+		WithIndex(&corev1.Pod{}, "spec.nodeName", func(obj client.Object) []string {
+			return []string{obj.(*corev1.Pod).Spec.NodeName}
+		}).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				if metrics, ok := obj.(*unstructured.Unstructured); ok && metrics.GroupVersionKind() == nodeMetricsKind {
+					return unstructured.SetNestedField(metrics.Object, usage[key.Name], "usage", "cpu")
+				}
+				return c.Get(ctx, key, obj, opts...)
+			},
+		}).Build()
+	selector, err := labels.Parse(opts.Selector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Detector{
+		options:      opts,
+		selector:     selector,
+		client:       c,
+		reader:       c,
+		recorder:     record.NewFakeRecorder(100),
+		idleSince:    map[client.ObjectKey]time.Time{},
+		drainStarted: map[client.ObjectKey]time.Time{},
+	}, c
+}
+
+// checkAfterIdle checks twice, with the servers idle for long enough in
+// between
+func checkAfterIdle(t *testing.T, d *Detector) {
+	t.Helper()
+	ctx := context.Background()
+	if err := d.check(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for name, since := range d.idleSince {
+		d.idleSince[name] = since.Add(-d.options.After)
+	}
+	if err := d.check(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func powerState(t *testing.T, c client.Client, name string) baremetalcontrollerv1.PowerState {
+	t.Helper()
+	var server baremetalcontrollerv1.Server
+	if err := c.Get(context.Background(), client.ObjectKey{Name: name}, &server); err != nil {
+		t.Fatal(err)
+	}
+	return server.Spec.PowerState
+}
+
+func TestDetectorPowersOffIdleServers(t *testing.T) {
+	opts := DefaultOptions()
+	opts.After = time.Hour
+	opts.MinActive = 0
+	d, c := newTestDetector(t, opts, map[string]string{"busy-01": "2", "quiet-01": "50m"},
+		activeServer("empty-01", nil),
+		activeServer("busy-01", nil),
+		activeServer("quiet-01", nil),
+		activeServer("pinned-01", map[string]string{baremetalcontrollerv1.AutoscalerExcludeAnnotation: "true"}),
+		activeServer("standby-01", map[string]string{baremetalcontrollerv1.StandbyAnnotation: "true"}),
+		node("empty-01", "16"), node("busy-01", "16"), node("quiet-01", "16"),
+		workload("api", "busy-01"), workload("cron", "quiet-01"),
+	)
+
+	// Nothing is powered off before the servers were idle for long enough
+	if err := d.check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := powerState(t, c, "empty-01"); got != baremetalcontrollerv1.PowerStateOn {
+		t.Errorf("empty-01 powered off right away")
+	}
+
+	for name, since := range d.idleSince {
+		d.idleSince[name] = since.Add(-opts.After)
+	}
+	if err := d.check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]baremetalcontrollerv1.PowerState{
+		"empty-01":   baremetalcontrollerv1.PowerStateOff,
+		"quiet-01":   baremetalcontrollerv1.PowerStateOn,
+		"busy-01":    baremetalcontrollerv1.PowerStateOn,
+		"pinned-01":  baremetalcontrollerv1.PowerStateOn,
+		"standby-01": baremetalcontrollerv1.PowerStateOn,
+	}
+	for name, state := range want {
+		if got := powerState(t, c, name); got != state {
+			t.Errorf("%s powerState = %s, want %s", name, got, state)
+		}
+	}
+	var server baremetalcontrollerv1.Server
+	if err := c.Get(context.Background(), client.ObjectKey{Name: "empty-01"}, &server); err != nil {
+		t.Fatal(err)
+	}
+	if server.Annotations[baremetalcontrollerv1.IdlePowerOffAnnotation] != "true" {
+		t.Errorf("empty-01 not marked as powered off while idle")
+	}
+
+	// quiet-01 uses less than 5% of its CPU, but still has to be drained
+	if _, draining := d.drainStarted[client.ObjectKey{Name: "quiet-01"}]; !draining {
+		t.Errorf("quiet-01 not being drained")
+	}
+	if err := d.check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := powerState(t, c, "quiet-01"); got != baremetalcontrollerv1.PowerStateOff {
+		t.Errorf("quiet-01 powerState = %s once drained, want off", got)
+	}
+}
+
+func TestDetectorKeepsMinActive(t *testing.T) {
+	opts := DefaultOptions()
+	opts.After = time.Hour
+	opts.MinActive = 2
+	d, c := newTestDetector(t, opts, nil,
+		activeServer("worker-01", nil), activeServer("worker-02", nil), activeServer("worker-03", nil),
+	)
+
+	checkAfterIdle(t, d)
+	checkAfterIdle(t, d)
+	off := 0
+	for _, name := range []string{"worker-01", "worker-02", "worker-03"} {
+		if powerState(t, c, name) == baremetalcontrollerv1.PowerStateOff {
+			off++
+		}
+	}
+	if off != 1 {
+		t.Errorf("%d servers powered off, want 1 to keep 2 active", off)
+	}
+}
+
+func TestDetectorSelector(t *testing.T) {
+	opts := DefaultOptions()
+	opts.After = time.Hour
+	opts.MinActive = 0
+	opts.Selector = "pool=web"
+	d, c := newTestDetector(t, opts, nil, activeServer("worker-01", nil))
+
+	checkAfterIdle(t, d)
+	if got := powerState(t, c, "worker-01"); got != baremetalcontrollerv1.PowerStateOn {
+		t.Errorf("server outside the selector powered off")
+	}
+}
+
+func TestDetectorUncordonsServersPoweredOnAgain(t *testing.T) {
+	opts := DefaultOptions()
+	opts.After = time.Hour
+	server := activeServer("worker-01", map[string]string{baremetalcontrollerv1.IdlePowerOffAnnotation: "true"})
+	cordoned := node("worker-01", "16")
+	cordoned.Spec.Unschedulable = true
+	d, c := newTestDetector(t, opts, nil, server, cordoned, workload("api", "worker-01"))
+	ctx := context.Background()
+
+	if err := d.check(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var n corev1.Node
+	if err := c.Get(ctx, client.ObjectKey{Name: "worker-01"}, &n); err != nil || n.Spec.Unschedulable {
+		t.Errorf("node still cordoned after the server was powered on")
+	}
+	if err := c.Get(ctx, client.ObjectKey{Name: "worker-01"}, server); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := server.Annotations[baremetalcontrollerv1.IdlePowerOffAnnotation]; ok {
+		t.Errorf("idle power-off annotation not cleared")
+	}
+}
+
+func TestDetectorGivesUpDraining(t *testing.T) {
+	opts := DefaultOptions()
+	opts.After = time.Hour
+	opts.MinActive = 0
+	opts.CPUThreshold = 0.5
+	d, c := newTestDetector(t, opts, map[string]string{"worker-01": "100m"},
+		activeServer("worker-01", nil), node("worker-01", "16"), workload("api", "worker-01"))
+	ctx := context.Background()
+
+	// Evictions that never complete, e.g. blocked by a PodDisruptionBudget
+	d.client = interceptor.NewClient(c.(client.WithWatch), interceptor.Funcs{
+		SubResourceCreate: func(context.Context, client.Client, string, client.Object, client.Object, ...client.SubResourceCreateOption) error {
+			return nil
+		},
+	})
+	checkAfterIdle(t, d)
+	d.drainStarted[client.ObjectKey{Name: "worker-01"}] = d.drainStarted[client.ObjectKey{Name: "worker-01"}].Add(-opts.DrainTimeout)
+	if err := d.check(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := powerState(t, c, "worker-01"); got != baremetalcontrollerv1.PowerStateOn {
+		t.Errorf("worker-01 powered off without being drained")
+	}
+	var n corev1.Node
+	if err := c.Get(ctx, client.ObjectKey{Name: "worker-01"}, &n); err != nil || n.Spec.Unschedulable {
+		t.Errorf("node left cordoned after the drain timed out")
+	}
+	if _, ok := d.idleSince[client.ObjectKey{Name: "worker-01"}]; ok {
+		t.Errorf("idle time not reset after the drain timed out")
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    func(*Options)
+		wantErr bool
+	}{
+		{name: "disabled", opts: func(o *Options) { o.After = 0; o.Interval = 0 }},
+		{name: "defaults", opts: func(*Options) {}},
+		{name: "empty nodes only", opts: func(o *Options) { o.CPUThreshold = 0 }},
+		{name: "threshold above 1", opts: func(o *Options) { o.CPUThreshold = 1.5 }, wantErr: true},
+		{name: "negative min active", opts: func(o *Options) { o.MinActive = -1 }, wantErr: true},
+		{name: "no interval", opts: func(o *Options) { o.Interval = 0 }, wantErr: true},
+		{name: "negative drain timeout", opts: func(o *Options) { o.DrainTimeout = -time.Second }, wantErr: true},
+		{name: "invalid selector", opts: func(o *Options) { o.Selector = "pool in (batch" }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.After = time.Hour
+			tt.opts(&opts)
+			if err := opts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package importer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+// Defaults fill in fields missing from an inventory entry
+type Defaults struct {
+	// Type is the control type of hosts without a type field
+	Type baremetalcontrollerv1.ControlType
+
+	// SecretNamespace is the namespace of secret references given as a
+	// plain name
+	SecretNamespace string
+
+	// Fields are default values by field name, e.g. a shared
+	// credentialsSecret
+	Fields Host
+}
+
+// Fields returns the fields a control type requires and the optional ones it
+// understands, in the order they are best asked for.
+func Fields(controlType baremetalcontrollerv1.ControlType) (required []string, optional []string) {
+	switch controlType {
+	case baremetalcontrollerv1.ControlTypeWOL:
+		return []string{FieldAddress, FieldMAC}, []string{FieldBroadcast, FieldUser, FieldSSHSecret}
+	case baremetalcontrollerv1.ControlTypeIPMI:
+		return []string{FieldBMC}, []string{FieldCredentialsSecret, FieldBMCUsername, FieldBMCPassword}
+	case baremetalcontrollerv1.ControlTypeRedfish:
+		return []string{FieldBMC, FieldCredentialsSecret}, []string{FieldSystemID}
+	case baremetalcontrollerv1.ControlTypeMAAS:
+		return []string{FieldAddress, FieldEndpoint, FieldSystemID, FieldCredentialsSecret}, nil
+	case baremetalcontrollerv1.ControlTypeEquinix:
+		return []string{FieldAddress, FieldProjectID, FieldCredentialsSecret}, []string{FieldSystemID}
+	case baremetalcontrollerv1.ControlTypeHetzner:
+		return []string{FieldAddress, FieldSystemID, FieldCredentialsSecret}, nil
+	case baremetalcontrollerv1.ControlTypeESXi:
+		// ESXi hosts are powered on over IPMI or WoL, whichever is given
+		return []string{FieldAddress, FieldCredentialsSecret}, []string{FieldBMC, FieldBMCUsername, FieldBMCPassword, FieldMAC, FieldBroadcast}
+	}
+	return nil, nil
+}
+
+// ServerForHost converts an inventory entry into a Server.
+func ServerForHost(host Host, defaults Defaults) (*baremetalcontrollerv1.Server, error) {
+	get := func(field string) string {
+		if v := host[field]; v != "" {
+			return v
+		}
+		return defaults.Fields[field]
+	}
+
+	name := strings.ToLower(get(FieldName))
+	if name == "" {
+		return nil, fmt.Errorf("host has no %s", FieldName)
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return nil, fmt.Errorf("host %s: invalid name: %s", name, strings.Join(errs, ", "))
+	}
+
+	controlType := baremetalcontrollerv1.ControlType(get(FieldType))
+	if controlType == "" {
+		controlType = defaults.Type
+	}
+
+	powerState := baremetalcontrollerv1.PowerState(get(FieldPowerState))
+	if powerState == "" {
+		powerState = baremetalcontrollerv1.PowerStateOff
+	}
+	if powerState != baremetalcontrollerv1.PowerStateOn && powerState != baremetalcontrollerv1.PowerStateOff {
+		return nil, fmt.Errorf("host %s: invalid %s %q", name, FieldPowerState, powerState)
+	}
+
+	secretRef := func(field string) (*baremetalcontrollerv1.SecretReference, error) {
+		value := get(field)
+		if value == "" {
+			return nil, fmt.Errorf("host %s: %s is required for type %s", name, field, controlType)
+		}
+		namespace, secretName, ok := strings.Cut(value, "/")
+		if !ok {
+			namespace, secretName = defaults.SecretNamespace, value
+		}
+		if namespace == "" {
+			return nil, fmt.Errorf("host %s: %s %q needs a namespace", name, field, value)
+		}
+		return &baremetalcontrollerv1.SecretReference{Name: secretName, Namespace: namespace}, nil
+	}
+	required := func(field string) (string, error) {
+		value := get(field)
+		if value == "" {
+			return "", fmt.Errorf("host %s: %s is required for type %s", name, field, controlType)
+		}
+		return value, nil
+	}
+
+	var control baremetalcontrollerv1.ControlSpecs
+	var err error
+	switch controlType {
+	case baremetalcontrollerv1.ControlTypeWOL:
+		wol := &baremetalcontrollerv1.WOLSpecs{
+			BroadcastAddress: get(FieldBroadcast),
+			User:             get(FieldUser),
+		}
+		if wol.Address, err = required(FieldAddress); err != nil {
+			return nil, err
+		}
+		if wol.MACAddress, err = required(FieldMAC); err != nil {
+			return nil, err
+		}
+		// SSH shutdown is optional, without it the server can only be woken
+		if get(FieldSSHSecret) != "" {
+			if wol.SSHSecretRef, err = secretRef(FieldSSHSecret); err != nil {
+				return nil, err
+			}
+		}
+		control.WOL = wol
+
+	case baremetalcontrollerv1.ControlTypeIPMI:
+		ipmi := &baremetalcontrollerv1.IPMISpecs{
+			Username: get(FieldBMCUsername),
+			Password: get(FieldBMCPassword),
+		}
+		if ipmi.Address, err = required(FieldBMC); err != nil {
+			return nil, err
+		}
+		// Hosts without inline credentials read them from the credentials
+		// secret, which may be a default shared with Redfish hosts
+		if ipmi.Username == "" && ipmi.Password == "" && get(FieldCredentialsSecret) != "" {
+			ref, err := secretRef(FieldCredentialsSecret)
+			if err != nil {
+				return nil, err
+			}
+			ipmi.CredentialsSecretRef = &baremetalcontrollerv1.CredentialsSecretReference{SecretReference: *ref}
+		}
+		control.IPMI = ipmi
+
+	case baremetalcontrollerv1.ControlTypeRedfish:
+		redfish := &baremetalcontrollerv1.RedfishSpecs{
+			SystemID: get(FieldSystemID),
+		}
+		if redfish.Address, err = required(FieldBMC); err != nil {
+			return nil, err
+		}
+		if redfish.CredentialsSecretRef, err = secretRef(FieldCredentialsSecret); err != nil {
+			return nil, err
+		}
+		control.Redfish = redfish
+
+	case baremetalcontrollerv1.ControlTypeMAAS:
+		maas := &baremetalcontrollerv1.MAASSpecs{}
+		if maas.Address, err = required(FieldAddress); err != nil {
+			return nil, err
+		}
+		if maas.Endpoint, err = required(FieldEndpoint); err != nil {
+			return nil, err
+		}
+		if maas.SystemID, err = required(FieldSystemID); err != nil {
+			return nil, err
+		}
+		if maas.APIKeySecretRef, err = secretRef(FieldCredentialsSecret); err != nil {
+			return nil, err
+		}
+		control.MAAS = maas
+
+	case baremetalcontrollerv1.ControlTypeEquinix:
+		// Without a device ID the device is matched by the server name
+		equinix := &baremetalcontrollerv1.EquinixSpecs{
+			DeviceID: get(FieldSystemID),
+		}
+		if equinix.Address, err = required(FieldAddress); err != nil {
+			return nil, err
+		}
+		if equinix.ProjectID, err = required(FieldProjectID); err != nil {
+			return nil, err
+		}
+		if equinix.APITokenSecretRef, err = secretRef(FieldCredentialsSecret); err != nil {
+			return nil, err
+		}
+		control.Equinix = equinix
+
+	case baremetalcontrollerv1.ControlTypeHetzner:
+		hetzner := &baremetalcontrollerv1.HetznerSpecs{}
+		if hetzner.Address, err = required(FieldAddress); err != nil {
+			return nil, err
+		}
+		number, err := required(FieldSystemID)
+		if err != nil {
+			return nil, err
+		}
+		serverNumber, err := strconv.ParseInt(number, 10, 32)
+		if err != nil || serverNumber <= 0 {
+			return nil, fmt.Errorf("host %s: %s %q is not a Hetzner server number", name, FieldSystemID, number)
+		}
+		hetzner.ServerNumber = int32(serverNumber)
+		if hetzner.CredentialsSecretRef, err = secretRef(FieldCredentialsSecret); err != nil {
+			return nil, err
+		}
+		control.Hetzner = hetzner
+
+	case baremetalcontrollerv1.ControlTypeESXi:
+		esxi := &baremetalcontrollerv1.ESXiSpecs{}
+		if esxi.Address, err = required(FieldAddress); err != nil {
+			return nil, err
+		}
+		if esxi.CredentialsSecretRef, err = secretRef(FieldCredentialsSecret); err != nil {
+			return nil, err
+		}
+		control.ESXi = esxi
+
+		// The credentials secret holds the host user, so a BMC to power on
+		// with needs its credentials inline
+		switch {
+		case get(FieldBMC) != "":
+			ipmi := &baremetalcontrollerv1.IPMISpecs{Address: get(FieldBMC)}
+			if ipmi.Username, err = required(FieldBMCUsername); err != nil {
+				return nil, err
+			}
+			if ipmi.Password, err = required(FieldBMCPassword); err != nil {
+				return nil, err
+			}
+			control.IPMI = ipmi
+		case get(FieldMAC) != "":
+			control.WOL = &baremetalcontrollerv1.WOLSpecs{
+				Address:          esxi.Address,
+				MACAddress:       get(FieldMAC),
+				BroadcastAddress: get(FieldBroadcast),
+			}
+		default:
+			return nil, fmt.Errorf("host %s: %s or %s is required for type %s to power it on", name, FieldBMC, FieldMAC, controlType)
+		}
+
+	default:
+		return nil, fmt.Errorf("host %s: unknown type %q", name, controlType)
+	}
+
+	labels := map[string]string{}
+	for _, fields := range []Host{defaults.Fields, host} {
+		for field, value := range fields {
+			if key, ok := strings.CutPrefix(field, LabelPrefix); ok {
+				labels[key] = value
+			}
+		}
+	}
+	for key, value := range labels {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return nil, fmt.Errorf("host %s: invalid label key %q: %s", name, key, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return nil, fmt.Errorf("host %s: invalid label value %q: %s", name, value, strings.Join(errs, ", "))
+		}
+	}
+	if len(labels) == 0 {
+		labels = nil
+	}
+
+	return &baremetalcontrollerv1.Server{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: baremetalcontrollerv1.GroupVersion.String(),
+			Kind:       "Server",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+		Spec: baremetalcontrollerv1.ServerSpec{
+			PowerState:      powerState,
+			Type:            controlType,
+			Control:         control,
+			ServerClassName: get(FieldServerClass),
+		},
+	}, nil
+}
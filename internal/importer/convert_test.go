@@ -0,0 +1,236 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+func TestServerForHost(t *testing.T) {
+	defaults := Defaults{
+		Type:            baremetalcontrollerv1.ControlTypeWOL,
+		SecretNamespace: "bmc-system",
+		Fields:          Host{"label.site": "fra1"},
+	}
+
+	tests := []struct {
+		name    string
+		host    Host
+		want    baremetalcontrollerv1.ServerSpec
+		labels  map[string]string
+		wantErr string
+	}{
+		{
+			name: "WoL by default",
+			host: Host{FieldName: "Worker-01", FieldAddress: "10.0.0.11", FieldMAC: "00:11:22:33:44:55", FieldSSHSecret: "ssh-key"},
+			want: baremetalcontrollerv1.ServerSpec{
+				PowerState: baremetalcontrollerv1.PowerStateOff,
+				Type:       baremetalcontrollerv1.ControlTypeWOL,
+				Control: baremetalcontrollerv1.ControlSpecs{WOL: &baremetalcontrollerv1.WOLSpecs{
+					Address:      "10.0.0.11",
+					MACAddress:   "00:11:22:33:44:55",
+					SSHSecretRef: &baremetalcontrollerv1.SecretReference{Name: "ssh-key", Namespace: "bmc-system"},
+				}},
+			},
+			labels: map[string]string{"site": "fra1"},
+		},
+		{
+			name: "IPMI with labels",
+			host: Host{FieldName: "worker-02", FieldType: "ipmi", FieldBMC: "10.0.1.12", FieldBMCUsername: "admin", FieldPowerState: "on", "label.rack": "r1", "label.site": "ams1"},
+			want: baremetalcontrollerv1.ServerSpec{
+				PowerState: baremetalcontrollerv1.PowerStateOn,
+				Type:       baremetalcontrollerv1.ControlTypeIPMI,
+				Control:    baremetalcontrollerv1.ControlSpecs{IPMI: &baremetalcontrollerv1.IPMISpecs{Address: "10.0.1.12", Username: "admin"}},
+			},
+			// Host labels take precedence over default labels
+			labels: map[string]string{"rack": "r1", "site": "ams1"},
+		},
+		{
+			name: "IPMI with a credentials secret",
+			host: Host{FieldName: "worker-05", FieldType: "ipmi", FieldBMC: "10.0.1.15", FieldCredentialsSecret: "bmc"},
+			want: baremetalcontrollerv1.ServerSpec{
+				PowerState: baremetalcontrollerv1.PowerStateOff,
+				Type:       baremetalcontrollerv1.ControlTypeIPMI,
+				Control: baremetalcontrollerv1.ControlSpecs{IPMI: &baremetalcontrollerv1.IPMISpecs{
+					Address: "10.0.1.15",
+					CredentialsSecretRef: &baremetalcontrollerv1.CredentialsSecretReference{
+						SecretReference: baremetalcontrollerv1.SecretReference{Name: "bmc", Namespace: "bmc-system"},
+					},
+				}},
+			},
+			labels: map[string]string{"site": "fra1"},
+		},
+		{
+			name: "Redfish with a namespaced secret",
+			host: Host{FieldName: "worker-03", FieldType: "redfish", FieldBMC: "https://10.0.1.13", FieldCredentialsSecret: "tenant-a/bmc"},
+			want: baremetalcontrollerv1.ServerSpec{
+				PowerState: baremetalcontrollerv1.PowerStateOff,
+				Type:       baremetalcontrollerv1.ControlTypeRedfish,
+				Control: baremetalcontrollerv1.ControlSpecs{Redfish: &baremetalcontrollerv1.RedfishSpecs{
+					Address:              "https://10.0.1.13",
+					CredentialsSecretRef: &baremetalcontrollerv1.SecretReference{Name: "bmc", Namespace: "tenant-a"},
+				}},
+			},
+			labels: map[string]string{"site": "fra1"},
+		},
+		{
+			name: "Hetzner server number",
+			host: Host{FieldName: "worker-04", FieldType: "hetzner", FieldAddress: "203.0.113.4", FieldSystemID: "321", FieldCredentialsSecret: "robot"},
+			want: baremetalcontrollerv1.ServerSpec{
+				PowerState: baremetalcontrollerv1.PowerStateOff,
+				Type:       baremetalcontrollerv1.ControlTypeHetzner,
+				Control: baremetalcontrollerv1.ControlSpecs{Hetzner: &baremetalcontrollerv1.HetznerSpecs{
+					Address:              "203.0.113.4",
+					ServerNumber:         321,
+					CredentialsSecretRef: &baremetalcontrollerv1.SecretReference{Name: "robot", Namespace: "bmc-system"},
+				}},
+			},
+			labels: map[string]string{"site": "fra1"},
+		},
+		{
+			name: "ESXi powered on over IPMI",
+			host: Host{FieldName: "esxi-01", FieldType: "esxi", FieldAddress: "10.0.2.11", FieldCredentialsSecret: "esxi", FieldBMC: "10.0.1.21", FieldBMCUsername: "admin", FieldBMCPassword: "secret"},
+			want: baremetalcontrollerv1.ServerSpec{
+				PowerState: baremetalcontrollerv1.PowerStateOff,
+				Type:       baremetalcontrollerv1.ControlTypeESXi,
+				Control: baremetalcontrollerv1.ControlSpecs{
+					ESXi: &baremetalcontrollerv1.ESXiSpecs{
+						Address:              "10.0.2.11",
+						CredentialsSecretRef: &baremetalcontrollerv1.SecretReference{Name: "esxi", Namespace: "bmc-system"},
+					},
+					IPMI: &baremetalcontrollerv1.IPMISpecs{Address: "10.0.1.21", Username: "admin", Password: "secret"},
+				},
+			},
+			labels: map[string]string{"site": "fra1"},
+		},
+		{
+			name: "ESXi woken",
+			host: Host{FieldName: "esxi-02", FieldType: "esxi", FieldAddress: "10.0.2.12", FieldCredentialsSecret: "esxi", FieldMAC: "00:11:22:33:44:66"},
+			want: baremetalcontrollerv1.ServerSpec{
+				PowerState: baremetalcontrollerv1.PowerStateOff,
+				Type:       baremetalcontrollerv1.ControlTypeESXi,
+				Control: baremetalcontrollerv1.ControlSpecs{
+					ESXi: &baremetalcontrollerv1.ESXiSpecs{
+						Address:              "10.0.2.12",
+						CredentialsSecretRef: &baremetalcontrollerv1.SecretReference{Name: "esxi", Namespace: "bmc-system"},
+					},
+					WOL: &baremetalcontrollerv1.WOLSpecs{Address: "10.0.2.12", MACAddress: "00:11:22:33:44:66"},
+				},
+			},
+			labels: map[string]string{"site": "fra1"},
+		},
+		{
+			name:    "ESXi without a way to power on",
+			host:    Host{FieldName: "esxi-03", FieldType: "esxi", FieldAddress: "10.0.2.13", FieldCredentialsSecret: "esxi"},
+			wantErr: "bmc or mac is required",
+		},
+		{name: "no name", host: Host{FieldAddress: "10.0.0.11"}, wantErr: "has no name"},
+		{name: "invalid name", host: Host{FieldName: "worker_01"}, wantErr: "invalid name"},
+		{name: "invalid power state", host: Host{FieldName: "worker-01", FieldPowerState: "reboot"}, wantErr: "invalid powerState"},
+		{name: "missing MAC", host: Host{FieldName: "worker-01", FieldAddress: "10.0.0.11"}, wantErr: "mac is required"},
+		{name: "unknown type", host: Host{FieldName: "worker-01", FieldType: "pdu"}, wantErr: "unknown type"},
+		{
+			name:    "Hetzner system ID",
+			host:    Host{FieldName: "worker-04", FieldType: "hetzner", FieldAddress: "203.0.113.4", FieldSystemID: "ex-44", FieldCredentialsSecret: "robot"},
+			wantErr: "not a Hetzner server number",
+		},
+		{
+			name:    "invalid label",
+			host:    Host{FieldName: "worker-01", FieldAddress: "10.0.0.11", FieldMAC: "00:11:22:33:44:55", "label.rack": "row 1"},
+			wantErr: "invalid label value",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, err := ServerForHost(tt.host, defaults)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ServerForHost() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ServerForHost() error = %v", err)
+			}
+			if server.Name != strings.ToLower(tt.host[FieldName]) || server.Kind != "Server" {
+				t.Errorf("server %s %s", server.Kind, server.Name)
+			}
+			if !reflect.DeepEqual(server.Spec, tt.want) {
+				t.Errorf("spec = %+v, want %+v", server.Spec, tt.want)
+			}
+			if !reflect.DeepEqual(server.Labels, tt.labels) {
+				t.Errorf("labels = %v, want %v", server.Labels, tt.labels)
+			}
+		})
+	}
+}
+
+func TestServerForHostSecretNamespace(t *testing.T) {
+	host := Host{FieldName: "worker-03", FieldType: "redfish", FieldBMC: "https://10.0.1.13", FieldCredentialsSecret: "bmc"}
+	if _, err := ServerForHost(host, Defaults{}); err == nil || !strings.Contains(err.Error(), "needs a namespace") {
+		t.Errorf("ServerForHost() error = %v without a secret namespace", err)
+	}
+
+	// A shared secret from the defaults is enough
+	delete(host, FieldCredentialsSecret)
+	server, err := ServerForHost(host, Defaults{Fields: Host{FieldCredentialsSecret: "bmc-system/bmc"}})
+	if err != nil {
+		t.Fatalf("ServerForHost() error = %v", err)
+	}
+	if ref := server.Spec.Control.Redfish.CredentialsSecretRef; ref.Namespace != "bmc-system" || ref.Name != "bmc" {
+		t.Errorf("credentials = %s/%s", ref.Namespace, ref.Name)
+	}
+}
+
+func TestFields(t *testing.T) {
+	values := Host{
+		FieldAddress:           "10.0.0.11",
+		FieldMAC:               "00:11:22:33:44:55",
+		FieldBMC:               "10.0.1.11",
+		FieldCredentialsSecret: "bmc-system/credentials",
+		FieldEndpoint:          "http://maas:5240/MAAS",
+		FieldSystemID:          "42",
+		FieldProjectID:         "project",
+	}
+	for _, controlType := range []baremetalcontrollerv1.ControlType{
+		baremetalcontrollerv1.ControlTypeWOL,
+		baremetalcontrollerv1.ControlTypeIPMI,
+		baremetalcontrollerv1.ControlTypeRedfish,
+		baremetalcontrollerv1.ControlTypeMAAS,
+		baremetalcontrollerv1.ControlTypeEquinix,
+		baremetalcontrollerv1.ControlTypeHetzner,
+	} {
+		t.Run(string(controlType), func(t *testing.T) {
+			required, _ := Fields(controlType)
+			if len(required) == 0 {
+				t.Fatalf("Fields() has no required fields")
+			}
+
+			// The required fields are enough for a server, and each of
+			// them is needed
+			host := Host{FieldName: "worker-01", FieldType: string(controlType)}
+			for _, field := range required {
+				host[field] = values[field]
+			}
+			if _, err := ServerForHost(host, Defaults{}); err != nil {
+				t.Errorf("ServerForHost() error = %v with the required fields", err)
+			}
+			for _, field := range required {
+				without := Host{}
+				for k, v := range host {
+					without[k] = v
+				}
+				delete(without, field)
+				if _, err := ServerForHost(without, Defaults{}); err == nil {
+					t.Errorf("ServerForHost() succeeded without %s", field)
+				}
+			}
+		})
+	}
+
+	if required, optional := Fields("pdu"); required != nil || optional != nil {
+		t.Errorf("Fields(pdu) = %v, %v, want none", required, optional)
+	}
+}
@@ -0,0 +1,234 @@
+// Package importer converts host inventories (CSV, YAML or Ansible INI) into
+// Server manifests for onboarding machines in bulk.
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Host is one inventory entry, keyed by field name (see the Field constants)
+type Host map[string]string
+
+// Fields read from inventories. Inventory columns or variables with other
+// names can be mapped to them, see Mapping.
+const (
+	FieldName              = "name"
+	FieldType              = "type"
+	FieldAddress           = "address"
+	FieldMAC               = "mac"
+	FieldBroadcast         = "broadcast"
+	FieldUser              = "user"
+	FieldSSHSecret         = "sshSecret"
+	FieldBMC               = "bmc"
+	FieldBMCUsername       = "bmcUsername"
+	FieldBMCPassword       = "bmcPassword"
+	FieldCredentialsSecret = "credentialsSecret"
+	FieldSystemID          = "systemID"
+	FieldEndpoint          = "endpoint"
+	FieldProjectID         = "projectID"
+	FieldServerClass       = "serverClass"
+	FieldPowerState        = "powerState"
+
+	// LabelPrefix marks fields that become labels, e.g. "label.rack"
+	LabelPrefix = "label."
+)
+
+// Mapping renames inventory columns or variables to fields, e.g.
+// {"ipmi_ip": "bmc"}. Unmapped names are used as they are.
+type Mapping map[string]string
+
+// ParseMapping parses "column=field" pairs separated by commas.
+func ParseMapping(s string) (Mapping, error) {
+	mapping := Mapping{}
+	if strings.TrimSpace(s) == "" {
+		return mapping, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		from, to, ok := strings.Cut(pair, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid mapping %q, expected column=field", pair)
+		}
+		mapping[from] = to
+	}
+	return mapping, nil
+}
+
+func (m Mapping) field(name string) string {
+	if field, ok := m[name]; ok {
+		return field
+	}
+	return name
+}
+
+func (m Mapping) set(host Host, name string, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	host[m.field(name)] = value
+}
+
+// ReadCSV reads hosts from a CSV file with a header row.
+func ReadCSV(r io.Reader, mapping Mapping) ([]Host, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read CSV header: %w", err)
+	}
+
+	var hosts []Host
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CSV: %w", err)
+		}
+		host := Host{}
+		for i, value := range record {
+			mapping.set(host, strings.TrimSpace(header[i]), value)
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
+
+// ReadYAML reads hosts from a YAML (or JSON) list of objects with scalar
+// values.
+func ReadYAML(r io.Reader, mapping Mapping) ([]Host, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []map[string]interface{}
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("unable to parse YAML, expected a list of hosts: %w", err)
+	}
+
+	hosts := make([]Host, 0, len(entries))
+	for _, entry := range entries {
+		host := Host{}
+		for key, value := range entry {
+			switch value.(type) {
+			case map[string]interface{}, []interface{}:
+				return nil, fmt.Errorf("field %q must be a scalar", key)
+			case nil:
+				continue
+			}
+			mapping.set(host, key, fmt.Sprint(value))
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
+
+// ReadAnsible reads hosts from an Ansible INI inventory. The inventory host
+// name becomes the name, ansible_host the address and ansible_user the user,
+// unless mapped otherwise. Group variables ([group:vars]) apply to the hosts
+// listed directly in the group; host variables take precedence.
+func ReadAnsible(r io.Reader, mapping Mapping) ([]Host, error) {
+	defaults := Mapping{"ansible_host": FieldAddress, "ansible_user": FieldUser}
+	for k, v := range mapping {
+		defaults[k] = v
+	}
+	mapping = defaults
+
+	var order []string
+	hostVars := map[string]Host{}
+	groups := map[string][]string{}
+	groupVars := map[string]map[string]string{}
+
+	section, kind := "ungrouped", ""
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section, kind, _ = strings.Cut(line[1:len(line)-1], ":")
+			continue
+		}
+
+		switch kind {
+		case "vars":
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected key=value in [%s:vars]", lineNo, section)
+			}
+			if groupVars[section] == nil {
+				groupVars[section] = map[string]string{}
+			}
+			groupVars[section][strings.TrimSpace(key)] = unquote(strings.TrimSpace(value))
+		case "children":
+			// Nested groups only affect group variables, which are not
+			// inherited here
+			continue
+		default:
+			fields := strings.Fields(line)
+			name := fields[0]
+			if _, ok := hostVars[name]; !ok {
+				hostVars[name] = Host{}
+				order = append(order, name)
+			}
+			for _, field := range fields[1:] {
+				key, value, ok := strings.Cut(field, "=")
+				if !ok {
+					return nil, fmt.Errorf("line %d: expected key=value, got %q", lineNo, field)
+				}
+				hostVars[name][key] = unquote(value)
+			}
+			groups[section] = append(groups[section], name)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	hosts := make([]Host, 0, len(order))
+	for _, name := range order {
+		vars := map[string]string{}
+		for k, v := range groupVars["all"] {
+			vars[k] = v
+		}
+		for group, members := range groups {
+			for _, member := range members {
+				if member == name {
+					for k, v := range groupVars[group] {
+						vars[k] = v
+					}
+				}
+			}
+		}
+		for k, v := range hostVars[name] {
+			vars[k] = v
+		}
+
+		host := Host{FieldName: name}
+		for k, v := range vars {
+			mapping.set(host, k, v)
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
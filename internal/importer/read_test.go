@@ -0,0 +1,144 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMapping(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Mapping
+		wantErr bool
+	}{
+		{in: "", want: Mapping{}},
+		{in: "hostname=name, ipmi_ip = bmc", want: Mapping{"hostname": "name", "ipmi_ip": "bmc"}},
+		{in: "hostname", wantErr: true},
+		{in: "hostname=", wantErr: true},
+		{in: "=name", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMapping(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseMapping(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseMapping(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	in := `hostname, ipmi_ip, label.rack
+# decommissioned
+# old-01, 10.0.0.9, r0
+worker-01, 10.0.0.11, r1
+worker-02, 10.0.0.12,
+`
+	hosts, err := ReadCSV(strings.NewReader(in), Mapping{"hostname": FieldName, "ipmi_ip": FieldBMC})
+	if err != nil {
+		t.Fatalf("ReadCSV() error = %v", err)
+	}
+	want := []Host{
+		{FieldName: "worker-01", FieldBMC: "10.0.0.11", "label.rack": "r1"},
+		// Empty cells are left out so that defaults apply
+		{FieldName: "worker-02", FieldBMC: "10.0.0.12"},
+	}
+	if !reflect.DeepEqual(hosts, want) {
+		t.Errorf("ReadCSV() = %v, want %v", hosts, want)
+	}
+
+	if _, err := ReadCSV(strings.NewReader(""), nil); err == nil {
+		t.Errorf("ReadCSV() succeeded without a header")
+	}
+	if _, err := ReadCSV(strings.NewReader("name,bmc\nworker-01\n"), nil); err == nil {
+		t.Errorf("ReadCSV() succeeded for a short row")
+	}
+}
+
+func TestReadYAML(t *testing.T) {
+	in := `
+- name: worker-01
+  bmc: 10.0.0.11
+  systemID: 42
+  powerState: null
+- name: worker-02
+  type: ipmi
+`
+	hosts, err := ReadYAML(strings.NewReader(in), nil)
+	if err != nil {
+		t.Fatalf("ReadYAML() error = %v", err)
+	}
+	want := []Host{
+		{FieldName: "worker-01", FieldBMC: "10.0.0.11", FieldSystemID: "42"},
+		{FieldName: "worker-02", FieldType: "ipmi"},
+	}
+	if !reflect.DeepEqual(hosts, want) {
+		t.Errorf("ReadYAML() = %v, want %v", hosts, want)
+	}
+
+	for _, in := range []string{
+		"name: worker-01\n",
+		"- name: worker-01\n  labels:\n    rack: r1\n",
+		"- name: worker-01\n  macs: [a, b]\n",
+	} {
+		if _, err := ReadYAML(strings.NewReader(in), nil); err == nil {
+			t.Errorf("ReadYAML(%q) succeeded", in)
+		}
+	}
+}
+
+func TestReadAnsible(t *testing.T) {
+	in := `
+; lab machines
+bastion ansible_host=10.0.0.2
+
+[workers]
+worker-01 ansible_host=10.0.0.11 mac="00:11:22:33:44:55"
+worker-02 ansible_host=10.0.0.12 mac=00:11:22:33:44:66 ansible_user=ops
+
+[workers:vars]
+ansible_user=ubuntu
+broadcast='10.0.0.255'
+
+[all:vars]
+type=wol
+
+[lab:children]
+workers
+`
+	hosts, err := ReadAnsible(strings.NewReader(in), Mapping{"mac": FieldMAC})
+	if err != nil {
+		t.Fatalf("ReadAnsible() error = %v", err)
+	}
+	want := []Host{
+		{FieldName: "bastion", FieldAddress: "10.0.0.2", FieldType: "wol"},
+		{FieldName: "worker-01", FieldAddress: "10.0.0.11", FieldMAC: "00:11:22:33:44:55", FieldUser: "ubuntu", FieldBroadcast: "10.0.0.255", FieldType: "wol"},
+		// Host variables take precedence over group variables
+		{FieldName: "worker-02", FieldAddress: "10.0.0.12", FieldMAC: "00:11:22:33:44:66", FieldUser: "ops", FieldBroadcast: "10.0.0.255", FieldType: "wol"},
+	}
+	if !reflect.DeepEqual(hosts, want) {
+		t.Errorf("ReadAnsible() =\n%v\nwant\n%v", hosts, want)
+	}
+
+	for _, in := range []string{
+		"worker-01 ansible_host\n",
+		"[workers:vars]\nansible_user\n",
+	} {
+		if _, err := ReadAnsible(strings.NewReader(in), nil); err == nil {
+			t.Errorf("ReadAnsible(%q) succeeded", in)
+		}
+	}
+}
+
+func TestReadAnsibleMappingOverridesDefaults(t *testing.T) {
+	hosts, err := ReadAnsible(strings.NewReader("worker-01 ansible_host=10.0.0.21\n"), Mapping{"ansible_host": FieldBMC})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Host{FieldName: "worker-01", FieldBMC: "10.0.0.21"}); len(hosts) != 1 || !reflect.DeepEqual(hosts[0], want) {
+		t.Errorf("ReadAnsible() = %v, want %v", hosts, want)
+	}
+}
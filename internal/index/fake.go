@@ -0,0 +1,29 @@
+package index
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// NewFakeClientBuilder returns a fake client builder with the Server indexes
+// registered the way the manager's cache registers them, for tests of code
+// that looks servers up through the index.
+func NewFakeClientBuilder(scheme *runtime.Scheme) *fake.ClientBuilder {
+	builder := fake.NewClientBuilder().WithScheme(scheme)
+	// Registering with a builder can't fail
+	_ = Setup(context.Background(), builderIndexer{builder})
+	return builder
+}
+
+// builderIndexer registers indexes with a fake client builder
+type builderIndexer struct {
+	builder *fake.ClientBuilder
+}
+
+func (b builderIndexer) IndexField(_ context.Context, obj client.Object, field string, extract client.IndexerFunc) error {
+	b.builder.WithIndex(obj, field, extract)
+	return nil
+}
@@ -0,0 +1,215 @@
+// Package index registers cache indexes for looking up Servers by MAC
+// address, management address, provider ID, node name or name without
+// listing every server. The lookups only work with a client backed by the
+// manager's cache, except by name, which the API server indexes too.
+package index
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+const (
+	// ServerMACField indexes Servers by their lowercased MAC addresses
+	ServerMACField = "index.macAddress"
+	// ServerAddressField indexes Servers by their control and provisioning
+	// addresses, without scheme or port
+	ServerAddressField = "index.address"
+	// ServerProviderIDField indexes Servers by spec.providerID
+	ServerProviderIDField = "index.providerID"
+	// ServerNodeNameField indexes Servers by spec.nodeName
+	ServerNodeNameField = "index.nodeName"
+	// ServerNameField indexes Servers by name, to find namespaced Servers
+	// without knowing their namespace. The API server supports it as a field
+	// selector, so it works with uncached readers too.
+	ServerNameField = "metadata.name"
+)
+
+// Setup registers the Server indexes with the manager's field indexer.
+func Setup(ctx context.Context, indexer client.FieldIndexer) error {
+	indexes := map[string]func(*baremetalcontrollerv1.Server) []string{
+		ServerMACField:        macAddresses,
+		ServerAddressField:    addresses,
+		ServerProviderIDField: providerIDs,
+		ServerNodeNameField:   nodeNames,
+		ServerNameField:       name,
+	}
+	for field, extract := range indexes {
+		if err := indexer.IndexField(ctx, &baremetalcontrollerv1.Server{}, field, func(obj client.Object) []string {
+			server, ok := obj.(*baremetalcontrollerv1.Server)
+			if !ok {
+				return nil
+			}
+			return extract(server)
+		}); err != nil {
+			return fmt.Errorf("failed to index servers by %s: %w", field, err)
+		}
+	}
+	return nil
+}
+
+// ServerByMAC returns the server with the given MAC address, or nil if there
+// is none.
+func ServerByMAC(ctx context.Context, c client.Reader, mac string) (*baremetalcontrollerv1.Server, error) {
+	return lookup(ctx, c, ServerMACField, normalizeMAC(mac))
+}
+
+// ServerByAddress returns the server with the given address, or nil if there
+// is none.
+func ServerByAddress(ctx context.Context, c client.Reader, address string) (*baremetalcontrollerv1.Server, error) {
+	return lookup(ctx, c, ServerAddressField, host(address))
+}
+
+// ServerByProviderID returns the server with the given provider ID, or nil
+// if there is none.
+func ServerByProviderID(ctx context.Context, c client.Reader, providerID string) (*baremetalcontrollerv1.Server, error) {
+	return lookup(ctx, c, ServerProviderIDField, providerID)
+}
+
+// ServerByName returns the server with the given name, or nil if there is
+// none. Nodes are named after their server, without its namespace, so
+// namespaced servers are looked up in every namespace, and their names must
+// be unique across namespaces.
+func ServerByName(ctx context.Context, c client.Reader, serverName string, namespaced bool) (*baremetalcontrollerv1.Server, error) {
+	if namespaced {
+		return lookup(ctx, c, ServerNameField, serverName)
+	}
+	server := &baremetalcontrollerv1.Server{}
+	if err := c.Get(ctx, client.ObjectKey{Name: serverName}, server); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get server %s: %w", serverName, err)
+	}
+	return server, nil
+}
+
+// ServerByNodeName returns the server whose Node has the given name, or nil
+// if there is none. Servers named after their node are found by name, so
+// the index is only needed for servers with spec.nodeName.
+func ServerByNodeName(ctx context.Context, c client.Reader, nodeName string, namespaced bool) (*baremetalcontrollerv1.Server, error) {
+	server, err := ServerByName(ctx, c, nodeName, namespaced)
+	if err != nil {
+		return nil, err
+	}
+	if server != nil && server.NodeName() == nodeName {
+		return server, nil
+	}
+	return lookup(ctx, c, ServerNodeNameField, nodeName)
+}
+
+// Addresses returns the control, provisioning and discovered addresses of a
+// server, without scheme or port
+func Addresses(server *baremetalcontrollerv1.Server) []string {
+	return addresses(server)
+}
+
+func lookup(ctx context.Context, c client.Reader, field string, value string) (*baremetalcontrollerv1.Server, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var servers baremetalcontrollerv1.ServerList
+	if err := c.List(ctx, &servers, client.MatchingFields{field: value}); err != nil {
+		return nil, fmt.Errorf("failed to look up server by %s: %w", field, err)
+	}
+	switch len(servers.Items) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &servers.Items[0], nil
+	default:
+		return nil, fmt.Errorf("%d servers share %s %s", len(servers.Items), field, value)
+	}
+}
+
+func macAddresses(server *baremetalcontrollerv1.Server) []string {
+	var macs []string
+	if wol := server.Spec.Control.WOL; wol != nil {
+		macs = appendUnique(macs, normalizeMAC(wol.MACAddress))
+	}
+	if p := server.Spec.Provisioning; p != nil && p.Tinkerbell != nil {
+		macs = appendUnique(macs, normalizeMAC(p.Tinkerbell.MACAddress))
+	}
+	return macs
+}
+
+func addresses(server *baremetalcontrollerv1.Server) []string {
+	var addrs []string
+	control := server.Spec.Control
+	if control.WOL != nil {
+		addrs = appendUnique(addrs, host(control.WOL.Address))
+	}
+	if control.IPMI != nil {
+		addrs = appendUnique(addrs, host(control.IPMI.Address))
+	}
+	if control.MAAS != nil {
+		addrs = appendUnique(addrs, host(control.MAAS.Address))
+	}
+	if control.Redfish != nil {
+		addrs = appendUnique(addrs, host(control.Redfish.Address))
+	}
+	if p := server.Spec.Provisioning; p != nil && p.Tinkerbell != nil {
+		addrs = appendUnique(addrs, host(p.Tinkerbell.IPAddress))
+	}
+	for _, address := range server.Status.Addresses {
+		addrs = appendUnique(addrs, address.Address)
+	}
+	return addrs
+}
+
+func providerIDs(server *baremetalcontrollerv1.Server) []string {
+	if server.Spec.ProviderID == "" {
+		return nil
+	}
+	return []string{server.Spec.ProviderID}
+}
+
+func nodeNames(server *baremetalcontrollerv1.Server) []string {
+	if server.Spec.NodeName == "" {
+		return nil
+	}
+	return []string{server.Spec.NodeName}
+}
+
+func name(server *baremetalcontrollerv1.Server) []string {
+	return []string{server.Name}
+}
+
+func normalizeMAC(mac string) string {
+	if hw, err := net.ParseMAC(mac); err == nil {
+		return hw.String()
+	}
+	return strings.ToLower(mac)
+}
+
+// host strips the scheme, path and port from an address
+func host(address string) string {
+	if u, err := url.Parse(address); err == nil && u.Host != "" {
+		return u.Hostname()
+	}
+	if h, _, err := net.SplitHostPort(address); err == nil {
+		return h
+	}
+	return address
+}
+
+func appendUnique(values []string, value string) []string {
+	if value == "" {
+		return values
+	}
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
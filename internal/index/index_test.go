@@ -0,0 +1,186 @@
+package index
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+func wolServer(name string, address string, mac string) *baremetalcontrollerv1.Server {
+	return &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: baremetalcontrollerv1.ServerSpec{
+			Type: baremetalcontrollerv1.ControlTypeWOL,
+			Control: baremetalcontrollerv1.ControlSpecs{
+				WOL: &baremetalcontrollerv1.WOLSpecs{Address: address, MACAddress: mac},
+			},
+		},
+	}
+}
+
+func TestAddresses(t *testing.T) {
+	server := &baremetalcontrollerv1.Server{
+		Spec: baremetalcontrollerv1.ServerSpec{
+			Control: baremetalcontrollerv1.ControlSpecs{
+				IPMI:    &baremetalcontrollerv1.IPMISpecs{Address: "10.0.1.11:623"},
+				Redfish: &baremetalcontrollerv1.RedfishSpecs{Address: "https://10.0.1.11/redfish/v1"},
+			},
+			Provisioning: &baremetalcontrollerv1.ProvisioningSpec{
+				Tinkerbell: &baremetalcontrollerv1.TinkerbellSpec{IPAddress: "10.0.0.11", MACAddress: "AA-BB-CC-DD-EE-FF"},
+			},
+		},
+		Status: baremetalcontrollerv1.ServerStatus{
+			Addresses: []baremetalcontrollerv1.ServerAddress{{Address: "10.0.0.11"}, {Address: "fd00::11"}},
+		},
+	}
+	if got, want := Addresses(server), []string{"10.0.1.11", "10.0.0.11", "fd00::11"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Addresses() = %v, want %v", got, want)
+	}
+	if got, want := macAddresses(server), []string{"aa:bb:cc:dd:ee:ff"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("macAddresses() = %v, want %v", got, want)
+	}
+	if got := providerIDs(server); got != nil {
+		t.Errorf("providerIDs() = %v without a provider ID", got)
+	}
+}
+
+func TestServerPath(t *testing.T) {
+	tests := map[string]client.ObjectKey{
+		"worker-01":        {Name: "worker-01"},
+		"team-a/worker-01": {Namespace: "team-a", Name: "worker-01"},
+	}
+	for path, key := range tests {
+		if got := ServerPath(key); got != path {
+			t.Errorf("ServerPath(%v) = %q, want %q", key, got, path)
+		}
+		if got, ok := ParseServerPath(path); !ok || got != key {
+			t.Errorf("ParseServerPath(%q) = %v, %v, want %v", path, got, ok, key)
+		}
+	}
+	for _, path := range []string{"", "team-a/", "/worker-01", "team-a/worker-01/extra"} {
+		if got, ok := ParseServerPath(path); ok {
+			t.Errorf("ParseServerPath(%q) = %v, want no server", path, got)
+		}
+	}
+}
+
+func TestHost(t *testing.T) {
+	tests := map[string]string{
+		"10.0.0.11":                    "10.0.0.11",
+		"10.0.0.11:623":                "10.0.0.11",
+		"https://bmc-01.example.com/x": "bmc-01.example.com",
+		"https://[fd00::11]:8443":      "fd00::11",
+		"[fd00::11]:623":               "fd00::11",
+		"bmc-01":                       "bmc-01",
+	}
+	for address, want := range tests {
+		if got := host(address); got != want {
+			t.Errorf("host(%q) = %q, want %q", address, got, want)
+		}
+	}
+}
+
+func TestLookups(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := baremetalcontrollerv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	withProviderID := wolServer("worker-02", "10.0.0.12", "00:11:22:33:44:66")
+	withProviderID.Spec.ProviderID = "baremetal://worker-02"
+	withNodeName := wolServer("worker-03", "10.0.0.13", "00:11:22:33:44:77")
+	withNodeName.Spec.NodeName = "rack3-u12"
+	c := NewFakeClientBuilder(scheme).WithObjects(
+		wolServer("worker-01", "10.0.0.11", "00:11:22:33:44:55"),
+		withProviderID,
+		withNodeName,
+		wolServer("dup-01", "10.0.0.99", "00:11:22:33:44:99"),
+		wolServer("dup-02", "10.0.0.99", "00:11:22:33:44:98"),
+	).Build()
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		lookup  func() (*baremetalcontrollerv1.Server, error)
+		want    string
+		wantErr bool
+	}{
+		{name: "MAC in another notation", lookup: func() (*baremetalcontrollerv1.Server, error) {
+			return ServerByMAC(ctx, c, "00-11-22-33-44-55")
+		}, want: "worker-01"},
+		{name: "address with port", lookup: func() (*baremetalcontrollerv1.Server, error) {
+			return ServerByAddress(ctx, c, "10.0.0.12:22")
+		}, want: "worker-02"},
+		{name: "provider ID", lookup: func() (*baremetalcontrollerv1.Server, error) {
+			return ServerByProviderID(ctx, c, "baremetal://worker-02")
+		}, want: "worker-02"},
+		{name: "unknown MAC", lookup: func() (*baremetalcontrollerv1.Server, error) {
+			return ServerByMAC(ctx, c, "00:11:22:33:44:00")
+		}},
+		{name: "empty provider ID", lookup: func() (*baremetalcontrollerv1.Server, error) {
+			return ServerByProviderID(ctx, c, "")
+		}},
+		{name: "shared address", lookup: func() (*baremetalcontrollerv1.Server, error) {
+			return ServerByAddress(ctx, c, "10.0.0.99")
+		}, wantErr: true},
+		{name: "name", lookup: func() (*baremetalcontrollerv1.Server, error) {
+			return ServerByName(ctx, c, "worker-01", false)
+		}, want: "worker-01"},
+		{name: "unknown name", lookup: func() (*baremetalcontrollerv1.Server, error) {
+			return ServerByName(ctx, c, "worker-09", false)
+		}},
+		{name: "node named after its server", lookup: func() (*baremetalcontrollerv1.Server, error) {
+			return ServerByNodeName(ctx, c, "worker-01", false)
+		}, want: "worker-01"},
+		{name: "node name", lookup: func() (*baremetalcontrollerv1.Server, error) {
+			return ServerByNodeName(ctx, c, "rack3-u12", false)
+		}, want: "worker-03"},
+		{name: "server name of a renamed node", lookup: func() (*baremetalcontrollerv1.Server, error) {
+			return ServerByNodeName(ctx, c, "worker-03", false)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, err := tt.lookup()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("lookup error = %v, wantErr %v", err, tt.wantErr)
+			}
+			got := ""
+			if server != nil {
+				got = server.Name
+			}
+			if got != tt.want {
+				t.Errorf("lookup = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerByNameNamespaced(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := baremetalcontrollerv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	teamA := wolServer("worker-01", "10.0.0.11", "00:11:22:33:44:55")
+	teamA.Namespace = "team-a"
+	teamB := wolServer("worker-02", "10.0.0.12", "00:11:22:33:44:66")
+	teamB.Namespace = "team-b"
+	c := NewFakeClientBuilder(scheme).WithObjects(teamA, teamB).Build()
+	ctx := context.Background()
+
+	server, err := ServerByName(ctx, c, "worker-02", true)
+	if err != nil {
+		t.Fatalf("ServerByName() error = %v", err)
+	}
+	if server == nil || server.Namespace != "team-b" {
+		t.Errorf("ServerByName() = %v, want worker-02 of team-b", server)
+	}
+	if server, err := ServerByName(ctx, c, "worker-09", true); err != nil || server != nil {
+		t.Errorf("ServerByName() = %v, %v for an unknown name, want nil", server, err)
+	}
+}
@@ -0,0 +1,30 @@
+package index
+
+import (
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ServerPath returns how a server is named in URL paths and in references
+// from cluster-scoped resources: <name> for cluster-scoped Servers,
+// <namespace>/<name> for namespaced ones
+func ServerPath(server client.ObjectKey) string {
+	if server.Namespace == "" {
+		return server.Name
+	}
+	return server.Namespace + "/" + server.Name
+}
+
+// ParseServerPath is the reverse of ServerPath. It returns false if path
+// isn't a name, or a namespace and name.
+func ParseServerPath(path string) (client.ObjectKey, bool) {
+	namespace, name, namespaced := strings.Cut(path, "/")
+	if !namespaced {
+		namespace, name = "", path
+	}
+	if name == "" || strings.Contains(name, "/") || namespaced && namespace == "" {
+		return client.ObjectKey{}, false
+	}
+	return client.ObjectKey{Namespace: namespace, Name: name}, true
+}
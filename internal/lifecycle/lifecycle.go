@@ -0,0 +1,195 @@
+package lifecycle
+
+import (
+	"fmt"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+const (
+	// Reachable means the server answered the reachability check
+	Reachable Event = "Reachable"
+	// Unreachable means the server didn't answer the reachability check
+	Unreachable Event = "Unreachable"
+)
+
+// Failure is an error of a check, with the reason it is reported under
+type Failure struct {
+	Reason baremetalcontrollerv1.FailureReason
+	Err    error
+}
+
+// Server is the server a machine moves between states. The controller
+// implements it on top of a Server resource and its backends.
+type Server interface {
+	// ExitMaintenanceMode takes a hypervisor that booted back up out of
+	// maintenance mode
+	ExitMaintenanceMode() *Failure
+	// Attest checks the measured boot of the server. Servers that fail
+	// attestation are marked failed by Attest and it returns false; an
+	// error means attestation couldn't be done yet.
+	Attest() (bool, error)
+	// RecordFailure counts another failed check of the server
+	RecordFailure()
+	// SetFailure reports why the server is stuck in its state
+	SetFailure(message string, reason baremetalcontrollerv1.FailureReason)
+	// ClearFailure forgets the failed checks of the server
+	ClearFailure()
+	// CompleteBoot records that the server booted under its boot policy
+	CompleteBoot()
+	// RebootTimedOut reports whether a rebooted server stayed up for too
+	// long to have gone down for the reboot
+	RebootTimedOut() bool
+}
+
+// Run is a server taking one event. It runs the checks of the server for
+// the guards of the transitions, at most once each.
+type Run struct {
+	Server Server
+
+	maintenanceDone bool
+	maintenance     *Failure
+	attested        bool
+	trusted         bool
+	attestErr       error
+}
+
+// Maintenance returns the failure to leave maintenance mode, if any
+func (r *Run) Maintenance() *Failure {
+	if !r.maintenanceDone {
+		r.maintenance = r.Server.ExitMaintenanceMode()
+		r.maintenanceDone = true
+	}
+	return r.maintenance
+}
+
+// Attest returns whether the server passed attestation
+func (r *Run) Attest() (bool, error) {
+	if !r.attested {
+		r.trusted, r.attestErr = r.Server.Attest()
+		r.attested = true
+	}
+	return r.trusted, r.attestErr
+}
+
+func maintenanceFailed(run *Run) bool {
+	return run.Maintenance() != nil
+}
+
+func attestationPending(run *Run) bool {
+	_, err := run.Attest()
+	return err != nil
+}
+
+func trusted(run *Run) bool {
+	trusted, err := run.Attest()
+	return err == nil && trusted
+}
+
+func rebootTimedOut(run *Run) bool {
+	return run.Server.RebootTimedOut()
+}
+
+func recordFailure(run *Run) {
+	run.Server.RecordFailure()
+}
+
+func clearFailure(run *Run) {
+	run.Server.ClearFailure()
+}
+
+var (
+	pending   = baremetalcontrollerv1.StatusPending
+	active    = baremetalcontrollerv1.StatusActive
+	offline   = baremetalcontrollerv1.StatusOffline
+	draining  = baremetalcontrollerv1.StatusDraining
+	rebooting = baremetalcontrollerv1.StatusRebooting
+	failed    = baremetalcontrollerv1.StatusFailed
+	// unset is the state of servers that were never checked
+	unset = State("")
+)
+
+// ServerLifecycle moves servers between the states of status.status on the
+// result of their reachability check. Power actions move servers into
+// pending, draining and rebooting; the machine moves them on once they get
+// there.
+var ServerLifecycle = mustNew(
+	[]Event{Reachable, Unreachable},
+	[]StateInfo{
+		{State: unset},
+		{State: pending, Waiting: true},
+		{State: draining, Waiting: true},
+		{State: rebooting, Waiting: true},
+		{State: active},
+		{State: offline},
+		{State: failed, Terminal: true},
+	},
+	[]Transition{
+		// Waiting for the server to come online and pass attestation
+		{
+			From: []State{pending}, On: Reachable, Guard: maintenanceFailed, To: pending,
+			Action: func(run *Run) {
+				failure := run.Maintenance()
+				run.Server.RecordFailure()
+				run.Server.SetFailure(fmt.Sprintf("Leaving maintenance mode: %v", failure.Err), failure.Reason)
+			},
+		},
+		{
+			From: []State{pending}, On: Reachable, Guard: attestationPending, To: pending,
+			Action: func(run *Run) {
+				_, err := run.Attest()
+				run.Server.RecordFailure()
+				run.Server.SetFailure(fmt.Sprintf("Attestation pending: %v", err), baremetalcontrollerv1.ReasonAttestationFailed)
+			},
+		},
+		{
+			From: []State{pending}, On: Reachable, Guard: trusted, To: active,
+			Action: func(run *Run) {
+				run.Server.ClearFailure()
+				run.Server.CompleteBoot()
+			},
+		},
+		{From: []State{pending}, On: Reachable, To: failed},
+		{From: []State{pending}, On: Unreachable, To: pending, Action: recordFailure},
+
+		// Waiting for the server to go offline
+		{From: []State{draining}, On: Unreachable, To: offline, Action: clearFailure},
+		{From: []State{draining}, On: Reachable, To: draining, Action: recordFailure},
+
+		// Waiting for the server to go down for a reboot, then to come back
+		// up like any server that was powered on
+		{From: []State{rebooting}, On: Unreachable, To: pending, Action: clearFailure},
+		{
+			From: []State{rebooting}, On: Reachable, Guard: rebootTimedOut, To: failed,
+			Action: func(run *Run) {
+				run.Server.SetFailure("Server did not go down for the reboot", baremetalcontrollerv1.ReasonRebootTimeout)
+			},
+		},
+		{From: []State{rebooting}, On: Reachable, To: rebooting},
+
+		// Detect unexpected offline
+		{From: []State{active}, On: Unreachable, To: offline},
+		{From: []State{active}, On: Reachable, To: active},
+
+		// Detect unexpected online, or initialize status
+		{From: []State{offline, unset}, On: Unreachable, To: offline},
+		{
+			From: []State{offline, unset}, On: Reachable, Guard: attestationPending, To: pending,
+			Action: func(run *Run) {
+				// Retry attestation while pending
+				_, err := run.Attest()
+				run.Server.SetFailure(fmt.Sprintf("Attestation pending: %v", err), baremetalcontrollerv1.ReasonAttestationFailed)
+			},
+		},
+		{From: []State{offline, unset}, On: Reachable, Guard: trusted, To: active, Action: clearFailure},
+		{From: []State{offline, unset}, On: Reachable, To: failed},
+	},
+)
+
+func mustNew(events []Event, states []StateInfo, transitions []Transition) *Machine {
+	m, err := New(events, states, transitions)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
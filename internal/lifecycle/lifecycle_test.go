@@ -0,0 +1,366 @@
+package lifecycle
+
+import (
+	"errors"
+	"testing"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+// fakeServer records what the machine did to a server
+type fakeServer struct {
+	maintenance *Failure
+	trusted     bool
+	attestErr   error
+
+	maintenanceCalls int
+	attestCalls      int
+	failures         int
+	cleared          bool
+	booted           bool
+	rebootTimedOut   bool
+	message          string
+	reason           baremetalcontrollerv1.FailureReason
+}
+
+func (s *fakeServer) ExitMaintenanceMode() *Failure {
+	s.maintenanceCalls++
+	return s.maintenance
+}
+
+func (s *fakeServer) Attest() (bool, error) {
+	s.attestCalls++
+	return s.trusted, s.attestErr
+}
+
+func (s *fakeServer) RecordFailure() {
+	s.failures++
+}
+
+func (s *fakeServer) SetFailure(message string, reason baremetalcontrollerv1.FailureReason) {
+	s.message = message
+	s.reason = reason
+}
+
+func (s *fakeServer) ClearFailure() {
+	s.cleared = true
+}
+
+func (s *fakeServer) CompleteBoot() {
+	s.booted = true
+}
+
+func (s *fakeServer) RebootTimedOut() bool {
+	return s.rebootTimedOut
+}
+
+func TestServerLifecycle(t *testing.T) {
+	maintenanceErr := &Failure{Reason: baremetalcontrollerv1.ReasonBMCBusy, Err: errors.New("busy")}
+	attestErr := errors.New("no quote")
+
+	tests := []struct {
+		name    string
+		from    State
+		event   Event
+		server  fakeServer
+		to      State
+		changed bool
+		want    fakeServer
+	}{
+		{
+			name: "pending server that can't leave maintenance mode", from: pending, event: Reachable,
+			server: fakeServer{maintenance: maintenanceErr, trusted: true},
+			to:     pending, changed: true,
+			want: fakeServer{maintenanceCalls: 1, failures: 1,
+				message: "Leaving maintenance mode: busy", reason: baremetalcontrollerv1.ReasonBMCBusy},
+		},
+		{
+			name: "pending server waiting for attestation", from: pending, event: Reachable,
+			server: fakeServer{attestErr: attestErr},
+			to:     pending, changed: true,
+			want: fakeServer{maintenanceCalls: 1, attestCalls: 1, failures: 1,
+				message: "Attestation pending: no quote", reason: baremetalcontrollerv1.ReasonAttestationFailed},
+		},
+		{
+			name: "pending server that booted", from: pending, event: Reachable,
+			server: fakeServer{trusted: true},
+			to:     active, changed: true,
+			want: fakeServer{maintenanceCalls: 1, attestCalls: 1, cleared: true, booted: true},
+		},
+		{
+			name: "pending server that failed attestation", from: pending, event: Reachable,
+			to: failed, changed: true,
+			want: fakeServer{maintenanceCalls: 1, attestCalls: 1},
+		},
+		{
+			name: "pending server that isn't up yet", from: pending, event: Unreachable,
+			to: pending, changed: true,
+			want: fakeServer{failures: 1},
+		},
+		{
+			name: "draining server that shut down", from: draining, event: Unreachable,
+			to: offline, changed: true,
+			want: fakeServer{cleared: true},
+		},
+		{
+			name: "draining server that is still up", from: draining, event: Reachable,
+			to: draining, changed: true,
+			want: fakeServer{failures: 1},
+		},
+		{
+			name: "rebooting server that went down", from: rebooting, event: Unreachable,
+			to: pending, changed: true,
+			want: fakeServer{cleared: true},
+		},
+		{
+			name: "rebooting server that is still up", from: rebooting, event: Reachable,
+			to: rebooting,
+		},
+		{
+			name: "rebooting server that never went down", from: rebooting, event: Reachable,
+			server: fakeServer{rebootTimedOut: true},
+			to:     failed, changed: true,
+			want: fakeServer{message: "Server did not go down for the reboot", reason: baremetalcontrollerv1.ReasonRebootTimeout},
+		},
+		{
+			name: "active server that went down", from: active, event: Unreachable,
+			to: offline, changed: true,
+		},
+		{
+			name: "active server that is up", from: active, event: Reachable,
+			to: active,
+		},
+		{
+			name: "offline server that is down", from: offline, event: Unreachable,
+			to: offline,
+		},
+		{
+			name: "offline server that came up", from: offline, event: Reachable,
+			server: fakeServer{trusted: true},
+			to:     active, changed: true,
+			want: fakeServer{attestCalls: 1, cleared: true},
+		},
+		{
+			name: "offline server that came up waiting for attestation", from: offline, event: Reachable,
+			server: fakeServer{attestErr: attestErr},
+			to:     pending, changed: true,
+			want: fakeServer{attestCalls: 1,
+				message: "Attestation pending: no quote", reason: baremetalcontrollerv1.ReasonAttestationFailed},
+		},
+		{
+			name: "offline server that came up and failed attestation", from: offline, event: Reachable,
+			to: failed, changed: true,
+			want: fakeServer{attestCalls: 1},
+		},
+		{
+			name: "new server that is down", from: unset, event: Unreachable,
+			to: offline, changed: true,
+		},
+		{
+			name: "new server that is up", from: unset, event: Reachable,
+			server: fakeServer{trusted: true},
+			to:     active, changed: true,
+			want: fakeServer{attestCalls: 1, cleared: true},
+		},
+		{
+			name: "new server waiting for attestation", from: unset, event: Reachable,
+			server: fakeServer{attestErr: attestErr},
+			to:     pending, changed: true,
+			want: fakeServer{attestCalls: 1,
+				message: "Attestation pending: no quote", reason: baremetalcontrollerv1.ReasonAttestationFailed},
+		},
+		{
+			name: "new server that failed attestation", from: unset, event: Reachable,
+			to: failed, changed: true,
+			want: fakeServer{attestCalls: 1},
+		},
+		{
+			name: "failed server that is up", from: failed, event: Reachable,
+			to: failed,
+		},
+		{
+			name: "failed server that is down", from: failed, event: Unreachable,
+			to: failed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := tt.server
+			to, changed, err := ServerLifecycle.Fire(&server, tt.from, tt.event)
+			if err != nil {
+				t.Fatalf("Fire() error = %v", err)
+			}
+			if to != tt.to || changed != tt.changed {
+				t.Errorf("Fire() = %q, %v, want %q, %v", to, changed, tt.to, tt.changed)
+			}
+
+			// Compare only what the machine did, not what the server was set up with
+			tt.want.maintenance, tt.want.trusted, tt.want.attestErr = server.maintenance, server.trusted, server.attestErr
+			tt.want.rebootTimedOut = server.rebootTimedOut
+			if server != tt.want {
+				t.Errorf("server = %+v, want %+v", server, tt.want)
+			}
+		})
+	}
+}
+
+// TestServerLifecycleCoversAllStates makes sure every state and event is
+// covered by the cases above, so a new state can't be added without tests
+func TestServerLifecycleCoversAllStates(t *testing.T) {
+	states := map[State]bool{}
+	for _, info := range ServerLifecycle.States() {
+		states[info.State] = true
+	}
+	want := []State{unset, pending, draining, rebooting, active, offline, failed}
+	if len(states) != len(want) {
+		t.Fatalf("machine has %d states, want %d", len(states), len(want))
+	}
+	for _, state := range want {
+		if !states[state] {
+			t.Errorf("machine has no state %q", state)
+		}
+	}
+	if len(ServerLifecycle.Events()) != 2 {
+		t.Errorf("machine has %d events, want 2", len(ServerLifecycle.Events()))
+	}
+}
+
+func TestServerLifecycleStates(t *testing.T) {
+	for _, state := range []State{pending, draining, rebooting} {
+		if !ServerLifecycle.Waiting(state) {
+			t.Errorf("Waiting(%q) = false, want true", state)
+		}
+	}
+	for _, state := range []State{unset, active, offline, failed} {
+		if ServerLifecycle.Waiting(state) {
+			t.Errorf("Waiting(%q) = true, want false", state)
+		}
+	}
+	if !ServerLifecycle.Terminal(failed) {
+		t.Errorf("Terminal(%q) = false, want true", failed)
+	}
+}
+
+func TestFireUnknownState(t *testing.T) {
+	if _, _, err := ServerLifecycle.Fire(&fakeServer{}, "hibernating", Reachable); err == nil {
+		t.Error("Fire() from an unknown state succeeded")
+	}
+}
+
+func TestNew(t *testing.T) {
+	const (
+		on  Event = "On"
+		off Event = "Off"
+	)
+	states := []StateInfo{{State: offline}, {State: active}, {State: failed, Terminal: true}}
+	always := []Transition{
+		{From: []State{offline, active}, On: on, To: active},
+		{From: []State{offline, active}, On: off, To: offline},
+	}
+	guard := func(*Run) bool { return true }
+
+	tests := []struct {
+		name        string
+		states      []StateInfo
+		transitions []Transition
+		wantErr     bool
+	}{
+		{name: "complete machine", states: states, transitions: always},
+		{
+			name:        "state without a transition for an event",
+			states:      states,
+			transitions: always[:1],
+			wantErr:     true,
+		},
+		{
+			name:   "state with only guarded transitions for an event",
+			states: states,
+			transitions: []Transition{
+				always[0],
+				{From: []State{offline, active}, On: off, Guard: guard, To: offline},
+			},
+			wantErr: true,
+		},
+		{
+			name:   "guarded transition before the fallback",
+			states: states,
+			transitions: []Transition{
+				{From: []State{offline}, On: on, Guard: guard, To: failed},
+				always[0], always[1],
+			},
+		},
+		{
+			name:        "transition from a terminal state",
+			states:      states,
+			transitions: append([]Transition{{From: []State{failed}, On: on, To: active}}, always...),
+			wantErr:     true,
+		},
+		{
+			name:        "transition from an undeclared state",
+			states:      states,
+			transitions: append([]Transition{{From: []State{pending}, On: on, To: active}}, always...),
+			wantErr:     true,
+		},
+		{
+			name:        "transition to an undeclared state",
+			states:      states,
+			transitions: append([]Transition{{From: []State{offline}, On: on, To: pending}}, always...),
+			wantErr:     true,
+		},
+		{
+			name:        "transition on an undeclared event",
+			states:      states,
+			transitions: append([]Transition{{From: []State{offline}, On: "Reboot", To: active}}, always...),
+			wantErr:     true,
+		},
+		{
+			name:        "state declared twice",
+			states:      append([]StateInfo{{State: offline}}, states...),
+			transitions: always,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New([]Event{on, off}, tt.states, tt.transitions)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFireFirstMatchingTransition(t *testing.T) {
+	var calls []string
+	m, err := New([]Event{Reachable}, []StateInfo{{State: offline}, {State: active}, {State: pending}}, []Transition{
+		{From: []State{offline}, On: Reachable, Guard: func(*Run) bool { calls = append(calls, "no"); return false }, To: pending},
+		{From: []State{offline}, On: Reachable, Guard: func(*Run) bool { calls = append(calls, "yes"); return true }, To: active},
+		{From: []State{offline, active, pending}, On: Reachable, To: offline},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	to, changed, err := m.Fire(&fakeServer{}, offline, Reachable)
+	if err != nil || to != active || !changed {
+		t.Errorf("Fire() = %q, %v, %v, want %q, true, nil", to, changed, err, active)
+	}
+	if len(calls) != 2 || calls[0] != "no" || calls[1] != "yes" {
+		t.Errorf("guards called %v, want [no yes]", calls)
+	}
+}
+
+func TestRunChecksOnce(t *testing.T) {
+	server := &fakeServer{trusted: true}
+	run := &Run{Server: server}
+	for i := 0; i < 3; i++ {
+		run.Maintenance()
+		if trusted, err := run.Attest(); !trusted || err != nil {
+			t.Fatalf("Attest() = %v, %v, want true, nil", trusted, err)
+		}
+	}
+	if server.maintenanceCalls != 1 || server.attestCalls != 1 {
+		t.Errorf("checks ran %d and %d times, want once", server.maintenanceCalls, server.attestCalls)
+	}
+}
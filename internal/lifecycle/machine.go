@@ -0,0 +1,158 @@
+// Package lifecycle moves servers between their lifecycle states. The states,
+// the events that move a server between them, and the guards and actions of
+// each transition are declared in a table, so a new state is added as rows
+// of the table instead of another branch in the reconciler.
+package lifecycle
+
+import (
+	"fmt"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+// State is the lifecycle state of a server, as reported in status.status
+type State = baremetalcontrollerv1.CurrentStatus
+
+// Event is something the controller observed about a server
+type Event string
+
+// StateInfo declares a state of a machine
+type StateInfo struct {
+	State State
+	// Waiting states wait for the server to get somewhere, e.g. to boot, and
+	// the server is checked again until it leaves them. Power actions are
+	// only started outside of waiting states.
+	Waiting bool
+	// Terminal states have no transitions, and servers in them are left
+	// alone until something outside the machine moves them
+	Terminal bool
+}
+
+// Guard decides whether a transition applies. Guards may run the checks of
+// a Run, which are run at most once per event.
+type Guard func(run *Run) bool
+
+// Action changes the server while it takes a transition
+type Action func(run *Run)
+
+// Transition moves a server in one of the From states to the To state on an
+// event, if its guard allows it. Transitions for the same state and event are
+// tried in the order they are declared, and the first one whose guard allows
+// it is taken.
+type Transition struct {
+	From   []State
+	On     Event
+	Guard  Guard
+	To     State
+	Action Action
+}
+
+// Machine is a table of states and transitions
+type Machine struct {
+	events      []Event
+	states      map[State]StateInfo
+	transitions map[State]map[Event][]Transition
+}
+
+// New returns a machine for the states and transitions. Every event has to
+// move every state that isn't terminal somewhere, so the last transition of
+// each non-terminal state and event must not have a guard.
+func New(events []Event, states []StateInfo, transitions []Transition) (*Machine, error) {
+	m := &Machine{
+		events:      events,
+		states:      map[State]StateInfo{},
+		transitions: map[State]map[Event][]Transition{},
+	}
+	for _, info := range states {
+		if _, ok := m.states[info.State]; ok {
+			return nil, fmt.Errorf("state %q is declared twice", info.State)
+		}
+		m.states[info.State] = info
+		m.transitions[info.State] = map[Event][]Transition{}
+	}
+
+	known := map[Event]bool{}
+	for _, event := range events {
+		known[event] = true
+	}
+	for _, t := range transitions {
+		if !known[t.On] {
+			return nil, fmt.Errorf("transition on undeclared event %q", t.On)
+		}
+		if _, ok := m.states[t.To]; !ok {
+			return nil, fmt.Errorf("transition to undeclared state %q", t.To)
+		}
+		for _, from := range t.From {
+			info, ok := m.states[from]
+			if !ok {
+				return nil, fmt.Errorf("transition from undeclared state %q", from)
+			}
+			if info.Terminal {
+				return nil, fmt.Errorf("transition from terminal state %q", from)
+			}
+			m.transitions[from][t.On] = append(m.transitions[from][t.On], t)
+		}
+	}
+
+	for _, info := range states {
+		if info.Terminal {
+			continue
+		}
+		for _, event := range events {
+			candidates := m.transitions[info.State][event]
+			if len(candidates) == 0 || candidates[len(candidates)-1].Guard != nil {
+				return nil, fmt.Errorf("state %q doesn't always handle event %q", info.State, event)
+			}
+		}
+	}
+	return m, nil
+}
+
+// Fire moves a server in state from on an event and returns the state it
+// ends up in, and whether the transition changed the server. Servers in
+// terminal states stay where they are.
+func (m *Machine) Fire(server Server, from State, event Event) (State, bool, error) {
+	info, ok := m.states[from]
+	if !ok {
+		return from, false, fmt.Errorf("unknown state %q", from)
+	}
+	if info.Terminal {
+		return from, false, nil
+	}
+
+	run := &Run{Server: server}
+	for _, t := range m.transitions[from][event] {
+		if t.Guard != nil && !t.Guard(run) {
+			continue
+		}
+		if t.Action != nil {
+			t.Action(run)
+		}
+		return t.To, t.To != from || t.Action != nil, nil
+	}
+	return from, false, fmt.Errorf("no transition from state %q on event %q", from, event)
+}
+
+// Waiting reports whether a state waits for the server to get somewhere
+func (m *Machine) Waiting(state State) bool {
+	return m.states[state].Waiting
+}
+
+// Terminal reports whether a state has no way out within the machine
+func (m *Machine) Terminal(state State) bool {
+	return m.states[state].Terminal
+}
+
+// Events returns the events of the machine
+func (m *Machine) Events() []Event {
+	return m.events
+}
+
+// States returns the states of the machine
+func (m *Machine) States() []StateInfo {
+	states := make([]StateInfo, 0, len(m.states))
+	for _, info := range m.states {
+		states = append(states, info)
+	}
+	return states
+}
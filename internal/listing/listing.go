@@ -0,0 +1,58 @@
+// Package listing walks Server lists without holding a deep copy of every
+// Server at once, for fleets of thousands of servers. Reads from the API
+// server are paged with continue tokens. The manager's cache supports no
+// continue tokens, so cached reads skip the per-item deep copy instead.
+package listing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+// DefaultPageSize is the number of Servers requested per page from the API
+// server
+const DefaultPageSize = 500
+
+// ErrStop can be returned by the callback of Servers to stop early without
+// an error.
+var ErrStop = errors.New("stop listing")
+
+// Servers calls fn for every Server matching opts. With a pageSize above
+// zero, Servers are read in pages of that size, which only the API server
+// supports. Use zero for a cached reader, whose Servers are not deep copied.
+// Either way fn must not modify the Server or keep it after returning
+// without copying it first.
+func Servers(ctx context.Context, reader client.Reader, pageSize int64, fn func(*baremetalcontrollerv1.Server) error, opts ...client.ListOption) error {
+	continueToken := ""
+	for {
+		listOpts := append([]client.ListOption{}, opts...)
+		if pageSize > 0 {
+			listOpts = append(listOpts, client.Limit(pageSize), client.Continue(continueToken))
+		} else {
+			listOpts = append(listOpts, client.UnsafeDisableDeepCopy)
+		}
+
+		var servers baremetalcontrollerv1.ServerList
+		if err := reader.List(ctx, &servers, listOpts...); err != nil {
+			return fmt.Errorf("failed to list servers: %w", err)
+		}
+		for i := range servers.Items {
+			if err := fn(&servers.Items[i]); err != nil {
+				if errors.Is(err, ErrStop) {
+					return nil
+				}
+				return err
+			}
+		}
+
+		if pageSize <= 0 || servers.Continue == "" {
+			return nil
+		}
+		continueToken = servers.Continue
+	}
+}
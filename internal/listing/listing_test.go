@@ -0,0 +1,172 @@
+package listing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+// pagedReader serves servers in pages with continue tokens like the API
+// server, recording the options of every List
+type pagedReader struct {
+	client.Reader
+	servers []baremetalcontrollerv1.Server
+	calls   []client.ListOptions
+}
+
+func (r *pagedReader) List(_ context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOpts := client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	r.calls = append(r.calls, listOpts)
+
+	var matching []baremetalcontrollerv1.Server
+	for _, server := range r.servers {
+		if listOpts.LabelSelector == nil || listOpts.LabelSelector.Matches(labels.Set(server.Labels)) {
+			matching = append(matching, server)
+		}
+	}
+	start := 0
+	if listOpts.Continue != "" {
+		var err error
+		if start, err = strconv.Atoi(listOpts.Continue); err != nil {
+			return fmt.Errorf("invalid continue token %q", listOpts.Continue)
+		}
+	}
+	end := len(matching)
+	if listOpts.Limit > 0 && start+int(listOpts.Limit) < end {
+		end = start + int(listOpts.Limit)
+	}
+
+	servers := list.(*baremetalcontrollerv1.ServerList)
+	servers.Items = append([]baremetalcontrollerv1.Server(nil), matching[start:end]...)
+	if end < len(matching) {
+		servers.Continue = strconv.Itoa(end)
+	}
+	return nil
+}
+
+func newPagedReader(n int) *pagedReader {
+	r := &pagedReader{}
+	for i := 0; i < n; i++ {
+		rack := "r1"
+		if i%2 == 1 {
+			rack = "r2"
+		}
+		r.servers = append(r.servers, baremetalcontrollerv1.Server{ObjectMeta: metav1.ObjectMeta{
+			Name:   fmt.Sprintf("worker-%02d", i),
+			Labels: map[string]string{"rack": rack},
+		}})
+	}
+	return r
+}
+
+func TestServers(t *testing.T) {
+	tests := []struct {
+		name      string
+		servers   int
+		pageSize  int64
+		opts      []client.ListOption
+		wantNames int
+		wantCalls int
+	}{
+		{name: "pages", servers: 7, pageSize: 3, wantNames: 7, wantCalls: 3},
+		{name: "exact pages", servers: 6, pageSize: 3, wantNames: 6, wantCalls: 2},
+		{name: "empty", pageSize: 3, wantCalls: 1},
+		{name: "cached", servers: 7, wantNames: 7, wantCalls: 1},
+		{name: "options kept on every page", servers: 7, pageSize: 2, opts: []client.ListOption{client.MatchingLabels{"rack": "r1"}}, wantNames: 4, wantCalls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newPagedReader(tt.servers)
+			seen := map[string]bool{}
+			err := Servers(context.Background(), r, tt.pageSize, func(server *baremetalcontrollerv1.Server) error {
+				if seen[server.Name] {
+					t.Errorf("%s listed twice", server.Name)
+				}
+				seen[server.Name] = true
+				return nil
+			}, tt.opts...)
+			if err != nil {
+				t.Fatalf("Servers() error = %v", err)
+			}
+			if len(seen) != tt.wantNames {
+				t.Errorf("listed %d servers, want %d", len(seen), tt.wantNames)
+			}
+			if len(r.calls) != tt.wantCalls {
+				t.Errorf("%d List calls, want %d", len(r.calls), tt.wantCalls)
+			}
+			for _, call := range r.calls {
+				if call.Limit != tt.pageSize {
+					t.Errorf("Limit = %d, want %d", call.Limit, tt.pageSize)
+				}
+				// The cache returns its own objects unless told otherwise,
+				// which is why fn must not modify them
+				uncopied := call.UnsafeDisableDeepCopy != nil && *call.UnsafeDisableDeepCopy
+				if uncopied != (tt.pageSize == 0) {
+					t.Errorf("UnsafeDisableDeepCopy = %v with page size %d", uncopied, tt.pageSize)
+				}
+				if tt.opts != nil && call.LabelSelector == nil {
+					t.Errorf("label selector dropped")
+				}
+			}
+		})
+	}
+}
+
+func TestServersStops(t *testing.T) {
+	r := newPagedReader(7)
+	listed := 0
+	err := Servers(context.Background(), r, 2, func(server *baremetalcontrollerv1.Server) error {
+		listed++
+		if server.Name == "worker-02" {
+			return ErrStop
+		}
+		return nil
+	})
+	if err != nil || listed != 3 || len(r.calls) != 2 {
+		t.Errorf("Servers() = %v after %d servers and %d List calls, want to stop at worker-02", err, listed, len(r.calls))
+	}
+
+	// Wrapped ErrStop stops too, other errors are returned as is
+	err = Servers(context.Background(), newPagedReader(3), 0, func(*baremetalcontrollerv1.Server) error {
+		return fmt.Errorf("found: %w", ErrStop)
+	})
+	if err != nil {
+		t.Errorf("Servers() error = %v, want wrapped ErrStop to stop", err)
+	}
+	errFailed := errors.New("failed")
+	err = Servers(context.Background(), newPagedReader(3), 0, func(*baremetalcontrollerv1.Server) error {
+		return errFailed
+	})
+	if !errors.Is(err, errFailed) {
+		t.Errorf("Servers() error = %v, want %v", err, errFailed)
+	}
+}
+
+func TestServersListError(t *testing.T) {
+	err := Servers(context.Background(), failingReader{}, 0, func(*baremetalcontrollerv1.Server) error {
+		t.Errorf("fn called after List failed")
+		return nil
+	})
+	if !errors.Is(err, errList) {
+		t.Errorf("Servers() error = %v, want %v", err, errList)
+	}
+}
+
+var errList = errors.New("connection refused")
+
+type failingReader struct {
+	client.Reader
+}
+
+func (failingReader) List(context.Context, client.ObjectList, ...client.ListOption) error {
+	return errList
+}
@@ -0,0 +1,188 @@
+package metal3
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+// BareMetalHostGVK is the GroupVersionKind of the Metal3 BareMetalHost resource.
+// Metal3 types are handled as unstructured objects so the controller does not
+// need to depend on the baremetal-operator API module.
+var BareMetalHostGVK = schema.GroupVersionKind{
+	Group:   "metal3.io",
+	Version: "v1alpha1",
+	Kind:    "BareMetalHost",
+}
+
+const (
+	// SourceAnnotation records the namespace/name of the BareMetalHost a
+	// Server was imported from.
+	SourceAnnotation = "baremetal.io/metal3-source"
+
+	// ServerLabel records the name of the Server a BareMetalHost was
+	// exported from.
+	ServerLabel = "baremetal.io/server"
+
+	// Keys used by Metal3 BMC credential Secrets
+	usernameKey = "username"
+	passwordKey = "password"
+)
+
+// NewBareMetalHost returns an empty unstructured BareMetalHost.
+func NewBareMetalHost() *unstructured.Unstructured {
+	bmh := &unstructured.Unstructured{}
+	bmh.SetGroupVersionKind(BareMetalHostGVK)
+	return bmh
+}
+
+// NewBareMetalHostList returns an empty unstructured BareMetalHostList.
+func NewBareMetalHostList() *unstructured.UnstructuredList {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(BareMetalHostGVK.GroupVersion().WithKind(BareMetalHostGVK.Kind + "List"))
+	return list
+}
+
+// CredentialsName returns the name of the Secret holding the BMC credentials
+// of a BareMetalHost.
+func CredentialsName(bmh *unstructured.Unstructured) string {
+	name, _, _ := unstructured.NestedString(bmh.Object, "spec", "bmc", "credentialsName")
+	return name
+}
+
+// ServerFromBareMetalHost converts a BareMetalHost and its BMC credentials
+// Secret into a Server that reads its IPMI credentials from that Secret. Only
+// IPMI BMC addresses are supported, since that is the only BMC protocol
+// shared by both controllers.
+func ServerFromBareMetalHost(bmh *unstructured.Unstructured, credentials *corev1.Secret) (*baremetalcontrollerv1.Server, error) {
+	bmcAddress, _, _ := unstructured.NestedString(bmh.Object, "spec", "bmc", "address")
+	if bmcAddress == "" {
+		return nil, fmt.Errorf("BareMetalHost %s/%s has no BMC address", bmh.GetNamespace(), bmh.GetName())
+	}
+
+	address, err := parseIPMIAddress(bmcAddress)
+	if err != nil {
+		return nil, fmt.Errorf("BareMetalHost %s/%s: %w", bmh.GetNamespace(), bmh.GetName(), err)
+	}
+
+	if credentials == nil {
+		return nil, fmt.Errorf("BareMetalHost %s/%s has no BMC credentials", bmh.GetNamespace(), bmh.GetName())
+	}
+	if len(credentials.Data[usernameKey]) == 0 || len(credentials.Data[passwordKey]) == 0 {
+		return nil, fmt.Errorf("%s and %s are required in secret %s/%s",
+			usernameKey, passwordKey, credentials.Namespace, credentials.Name)
+	}
+
+	powerState := baremetalcontrollerv1.PowerStateOff
+	if online, _, _ := unstructured.NestedBool(bmh.Object, "spec", "online"); online {
+		powerState = baremetalcontrollerv1.PowerStateOn
+	}
+
+	return &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   bmh.GetName(),
+			Labels: bmh.GetLabels(),
+			Annotations: map[string]string{
+				SourceAnnotation: bmh.GetNamespace() + "/" + bmh.GetName(),
+			},
+		},
+		Spec: baremetalcontrollerv1.ServerSpec{
+			PowerState: powerState,
+			Type:       baremetalcontrollerv1.ControlTypeIPMI,
+			Control: baremetalcontrollerv1.ControlSpecs{
+				IPMI: &baremetalcontrollerv1.IPMISpecs{
+					Address: address,
+					CredentialsSecretRef: &baremetalcontrollerv1.CredentialsSecretReference{
+						SecretReference: baremetalcontrollerv1.SecretReference{
+							Name:      credentials.Name,
+							Namespace: credentials.Namespace,
+						},
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+// BareMetalHostFromServer converts an IPMI Server into a BareMetalHost in the
+// given namespace, along with the Secret holding its BMC credentials.
+// credentials is the Secret the server's IPMI credentials are read from, nil
+// if they are in its spec.
+func BareMetalHostFromServer(server *baremetalcontrollerv1.Server, credentials *corev1.Secret, namespace string) (*unstructured.Unstructured, *corev1.Secret, error) {
+	if server.Spec.Type != baremetalcontrollerv1.ControlTypeIPMI || server.Spec.Control.IPMI == nil {
+		return nil, nil, fmt.Errorf("server %s: only IPMI servers can be exported as BareMetalHosts", server.Name)
+	}
+	ipmi := server.Spec.Control.IPMI
+	if ipmi.Address == "" {
+		return nil, nil, fmt.Errorf("server %s: IPMI address is required", server.Name)
+	}
+	username, password := ipmi.Username, ipmi.Password
+	if ref := ipmi.CredentialsSecretRef; ref != nil {
+		if credentials == nil {
+			return nil, nil, fmt.Errorf("server %s: IPMI credentials secret is required", server.Name)
+		}
+		serverUsernameKey, serverPasswordKey := ref.Keys()
+		username = string(credentials.Data[serverUsernameKey])
+		password = string(credentials.Data[serverPasswordKey])
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      server.Name + "-bmc-secret",
+			Namespace: namespace,
+			Labels:    map[string]string{ServerLabel: server.Name},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			usernameKey: []byte(username),
+			passwordKey: []byte(password),
+		},
+	}
+
+	labels := map[string]string{}
+	for k, v := range server.Labels {
+		labels[k] = v
+	}
+	labels[ServerLabel] = server.Name
+
+	bmh := NewBareMetalHost()
+	bmh.SetName(server.Name)
+	bmh.SetNamespace(namespace)
+	bmh.SetLabels(labels)
+	bmh.Object["spec"] = map[string]interface{}{
+		"online": server.Spec.PowerState.Settled() == baremetalcontrollerv1.PowerStateOn,
+		"bmc": map[string]interface{}{
+			"address":         "ipmi://" + ipmi.Address,
+			"credentialsName": secret.Name,
+		},
+	}
+
+	return bmh, secret, nil
+}
+
+// parseIPMIAddress extracts the host[:port] from a Metal3 BMC address. Metal3
+// treats addresses without a scheme as IPMI.
+func parseIPMIAddress(bmcAddress string) (string, error) {
+	if !strings.Contains(bmcAddress, "://") {
+		return bmcAddress, nil
+	}
+
+	u, err := url.Parse(bmcAddress)
+	if err != nil {
+		return "", fmt.Errorf("invalid BMC address %q: %w", bmcAddress, err)
+	}
+	if u.Scheme != "ipmi" {
+		return "", fmt.Errorf("unsupported BMC protocol %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("invalid BMC address %q: missing host", bmcAddress)
+	}
+	return u.Host, nil
+}
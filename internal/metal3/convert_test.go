@@ -0,0 +1,279 @@
+package metal3
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+// bareMetalHost returns a BareMetalHost with the BMC address and
+// credentials Secret name, both left out if empty
+func bareMetalHost(name string, address string, credentialsName string, online bool) *unstructured.Unstructured {
+	bmh := NewBareMetalHost()
+	bmh.SetName(name)
+	bmh.SetNamespace("metal3")
+	bmh.SetLabels(map[string]string{"rack": "r1"})
+	bmc := map[string]interface{}{}
+	if address != "" {
+		bmc["address"] = address
+	}
+	if credentialsName != "" {
+		bmc["credentialsName"] = credentialsName
+	}
+	bmh.Object["spec"] = map[string]interface{}{"online": online, "bmc": bmc}
+	return bmh
+}
+
+func credentialsSecret(data map[string]string) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-01-bmc-secret", Namespace: "metal3"},
+		Data:       map[string][]byte{},
+	}
+	for k, v := range data {
+		secret.Data[k] = []byte(v)
+	}
+	return secret
+}
+
+func TestParseIPMIAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		want    string
+		wantErr string
+	}{
+		{name: "no scheme", address: "192.168.1.10", want: "192.168.1.10"},
+		{name: "no scheme with port", address: "192.168.1.10:623", want: "192.168.1.10:623"},
+		{name: "ipmi", address: "ipmi://192.168.1.10", want: "192.168.1.10"},
+		{name: "ipmi with port", address: "ipmi://bmc-01.example.com:623", want: "bmc-01.example.com:623"},
+		{name: "redfish", address: "redfish://192.168.1.10/redfish/v1/Systems/1", wantErr: `unsupported BMC protocol "redfish"`},
+		{name: "redfish virtual media", address: "redfish-virtualmedia+https://192.168.1.10/redfish/v1/Systems/1", wantErr: "unsupported BMC protocol"},
+		{name: "idrac", address: "idrac-redfish://192.168.1.10/redfish/v1/Systems/System.Embedded.1", wantErr: "unsupported BMC protocol"},
+		{name: "missing host", address: "ipmi://", wantErr: "missing host"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseIPMIAddress(tt.address)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseIPMIAddress(%q) error = %v, want %q", tt.address, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseIPMIAddress(%q) error = %v", tt.address, err)
+			}
+			if got != tt.want {
+				t.Errorf("parseIPMIAddress(%q) = %q, want %q", tt.address, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerFromBareMetalHost(t *testing.T) {
+	validCredentials := credentialsSecret(map[string]string{usernameKey: "admin", passwordKey: "secret"})
+
+	tests := []struct {
+		name        string
+		bmh         *unstructured.Unstructured
+		credentials *corev1.Secret
+		wantErr     string
+		wantAddress string
+		wantPower   baremetalcontrollerv1.PowerState
+	}{
+		{
+			name:        "online IPMI host",
+			bmh:         bareMetalHost("worker-01", "ipmi://192.168.1.10:623", "worker-01-bmc-secret", true),
+			credentials: validCredentials,
+			wantAddress: "192.168.1.10:623",
+			wantPower:   baremetalcontrollerv1.PowerStateOn,
+		},
+		{
+			name:        "offline host without scheme",
+			bmh:         bareMetalHost("worker-01", "192.168.1.10", "worker-01-bmc-secret", false),
+			credentials: validCredentials,
+			wantAddress: "192.168.1.10",
+			wantPower:   baremetalcontrollerv1.PowerStateOff,
+		},
+		{
+			name:        "Redfish host",
+			bmh:         bareMetalHost("worker-01", "redfish://192.168.1.10/redfish/v1/Systems/1", "worker-01-bmc-secret", true),
+			credentials: validCredentials,
+			wantErr:     "unsupported BMC protocol",
+		},
+		{
+			name:        "no BMC address",
+			bmh:         bareMetalHost("worker-01", "", "worker-01-bmc-secret", true),
+			credentials: validCredentials,
+			wantErr:     "has no BMC address",
+		},
+		{
+			name:    "missing Secret",
+			bmh:     bareMetalHost("worker-01", "ipmi://192.168.1.10", "worker-01-bmc-secret", true),
+			wantErr: "has no BMC credentials",
+		},
+		{
+			name:        "missing password",
+			bmh:         bareMetalHost("worker-01", "ipmi://192.168.1.10", "worker-01-bmc-secret", true),
+			credentials: credentialsSecret(map[string]string{usernameKey: "admin"}),
+			wantErr:     "username and password are required in secret metal3/worker-01-bmc-secret",
+		},
+		{
+			name:        "missing username",
+			bmh:         bareMetalHost("worker-01", "ipmi://192.168.1.10", "worker-01-bmc-secret", true),
+			credentials: credentialsSecret(map[string]string{passwordKey: "secret"}),
+			wantErr:     "username and password are required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, err := ServerFromBareMetalHost(tt.bmh, tt.credentials)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ServerFromBareMetalHost() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ServerFromBareMetalHost() error = %v", err)
+			}
+			if server.Name != "worker-01" || server.Labels["rack"] != "r1" {
+				t.Errorf("server name/labels = %s/%v, want worker-01 with the host's labels", server.Name, server.Labels)
+			}
+			if got := server.Annotations[SourceAnnotation]; got != "metal3/worker-01" {
+				t.Errorf("source annotation = %q, want metal3/worker-01", got)
+			}
+			if server.Spec.Type != baremetalcontrollerv1.ControlTypeIPMI || server.Spec.Control.IPMI == nil {
+				t.Fatalf("server control = %s, want IPMI", server.Spec.Type)
+			}
+			ipmi := server.Spec.Control.IPMI
+			wantRef := &baremetalcontrollerv1.CredentialsSecretReference{
+				SecretReference: baremetalcontrollerv1.SecretReference{Name: "worker-01-bmc-secret", Namespace: "metal3"},
+			}
+			if ipmi.Address != tt.wantAddress || ipmi.Username != "" || ipmi.Password != "" || !reflect.DeepEqual(ipmi.CredentialsSecretRef, wantRef) {
+				t.Errorf("IPMI spec = %+v, want address %s with credentials from the Secret", ipmi, tt.wantAddress)
+			}
+			if server.Spec.PowerState != tt.wantPower {
+				t.Errorf("power state = %s, want %s", server.Spec.PowerState, tt.wantPower)
+			}
+		})
+	}
+}
+
+func TestBareMetalHostFromServer(t *testing.T) {
+	ipmiServer := &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-01", Labels: map[string]string{"rack": "r1"}},
+		Spec: baremetalcontrollerv1.ServerSpec{
+			PowerState: baremetalcontrollerv1.PowerStateOn,
+			Type:       baremetalcontrollerv1.ControlTypeIPMI,
+			Control: baremetalcontrollerv1.ControlSpecs{
+				IPMI: &baremetalcontrollerv1.IPMISpecs{Address: "192.168.1.10:623", Username: "admin", Password: "secret"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		server  *baremetalcontrollerv1.Server
+		wantErr string
+	}{
+		{name: "IPMI server", server: ipmiServer},
+		{
+			name: "Redfish server",
+			server: &baremetalcontrollerv1.Server{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker-02"},
+				Spec: baremetalcontrollerv1.ServerSpec{
+					Type:    baremetalcontrollerv1.ControlTypeRedfish,
+					Control: baremetalcontrollerv1.ControlSpecs{Redfish: &baremetalcontrollerv1.RedfishSpecs{Address: "https://192.168.1.11"}},
+				},
+			},
+			wantErr: "only IPMI servers",
+		},
+		{
+			name: "IPMI server without address",
+			server: &baremetalcontrollerv1.Server{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker-03"},
+				Spec: baremetalcontrollerv1.ServerSpec{
+					Type:    baremetalcontrollerv1.ControlTypeIPMI,
+					Control: baremetalcontrollerv1.ControlSpecs{IPMI: &baremetalcontrollerv1.IPMISpecs{Username: "admin"}},
+				},
+			},
+			wantErr: "IPMI address is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmh, secret, err := BareMetalHostFromServer(tt.server, nil, "metal3")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("BareMetalHostFromServer() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BareMetalHostFromServer() error = %v", err)
+			}
+			if bmh.GetNamespace() != "metal3" || secret.Namespace != "metal3" {
+				t.Errorf("namespaces = %s and %s, want metal3", bmh.GetNamespace(), secret.Namespace)
+			}
+			if bmh.GetLabels()[ServerLabel] != "worker-01" || bmh.GetLabels()["rack"] != "r1" {
+				t.Errorf("host labels = %v, want the server's labels and %s", bmh.GetLabels(), ServerLabel)
+			}
+			if CredentialsName(bmh) != secret.Name {
+				t.Errorf("credentialsName = %q, want the Secret %q", CredentialsName(bmh), secret.Name)
+			}
+			if tt.server.Labels[ServerLabel] != "" {
+				t.Errorf("export modified the server's labels: %v", tt.server.Labels)
+			}
+		})
+	}
+}
+
+func TestBareMetalHostRoundTrip(t *testing.T) {
+	for _, powerState := range []baremetalcontrollerv1.PowerState{baremetalcontrollerv1.PowerStateOn, baremetalcontrollerv1.PowerStateOff} {
+		t.Run(string(powerState), func(t *testing.T) {
+			original := &baremetalcontrollerv1.Server{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker-01"},
+				Spec: baremetalcontrollerv1.ServerSpec{
+					PowerState: powerState,
+					Type:       baremetalcontrollerv1.ControlTypeIPMI,
+					Control: baremetalcontrollerv1.ControlSpecs{
+						IPMI: &baremetalcontrollerv1.IPMISpecs{Address: "192.168.1.10:623", Username: "admin", Password: "secret"},
+					},
+				},
+			}
+
+			bmh, secret, err := BareMetalHostFromServer(original, nil, "metal3")
+			if err != nil {
+				t.Fatalf("BareMetalHostFromServer() error = %v", err)
+			}
+			imported, err := ServerFromBareMetalHost(bmh, secret)
+			if err != nil {
+				t.Fatalf("ServerFromBareMetalHost() error = %v", err)
+			}
+			if imported.Spec.PowerState != original.Spec.PowerState {
+				t.Errorf("power state = %s, want %s", imported.Spec.PowerState, original.Spec.PowerState)
+			}
+			// The imported server reads the credentials from the exported
+			// Secret
+			ipmi := imported.Spec.Control.IPMI
+			if ipmi.Address != original.Spec.Control.IPMI.Address || ipmi.CredentialsSecretRef == nil ||
+				ipmi.CredentialsSecretRef.Name != secret.Name || ipmi.CredentialsSecretRef.Namespace != secret.Namespace {
+				t.Errorf("IPMI spec = %+v, want %s with credentials from secret %s/%s", *ipmi, original.Spec.Control.IPMI.Address, secret.Namespace, secret.Name)
+			}
+			_, reexported, err := BareMetalHostFromServer(imported, secret, "metal3")
+			if err != nil {
+				t.Fatalf("BareMetalHostFromServer() error = %v", err)
+			}
+			if !reflect.DeepEqual(reexported.Data, secret.Data) {
+				t.Errorf("credentials = %q, want %q", reexported.Data, secret.Data)
+			}
+		})
+	}
+}
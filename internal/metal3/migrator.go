@@ -0,0 +1,225 @@
+package metal3
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/listing"
+)
+
+// Mode selects the direction of a Metal3 migration.
+type Mode string
+
+const (
+	// ModeDisabled disables the migrator.
+	ModeDisabled Mode = ""
+	// ModeImport creates Servers from existing BareMetalHosts.
+	ModeImport Mode = "import"
+	// ModeExport creates BareMetalHosts from existing Servers.
+	ModeExport Mode = "export"
+)
+
+// Options contains configuration for the Metal3 migrator.
+type Options struct {
+	// Mode is the migration direction: "import", "export" or empty to disable
+	Mode Mode
+
+	// Namespace is the namespace BareMetalHosts are imported from or
+	// exported to. Empty imports from all namespaces.
+	Namespace string
+}
+
+// BindFlags binds the migrator options to command line flags.
+// The prefix can be used to namespace the flags (e.g., "metal3-").
+func (o *Options) BindFlags(fs *flag.FlagSet, prefix string) {
+	fs.StringVar((*string)(&o.Mode), prefix+"mode", string(o.Mode),
+		"Metal3 BareMetalHost migration mode at startup: import, export, or empty to disable.")
+	fs.StringVar(&o.Namespace, prefix+"namespace", o.Namespace,
+		"Namespace to import BareMetalHosts from (empty for all) or export them to.")
+}
+
+// Validate validates the options.
+func (o *Options) Validate() error {
+	switch o.Mode {
+	case ModeDisabled, ModeImport:
+		return nil
+	case ModeExport:
+		if o.Namespace == "" {
+			return fmt.Errorf("namespace is required to export BareMetalHosts")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown Metal3 migration mode: %s", o.Mode)
+	}
+}
+
+// Enabled returns true if a migration mode is configured.
+func (o *Options) Enabled() bool {
+	return o.Mode != ModeDisabled
+}
+
+// Migrator implements manager.Runnable and performs a one-shot migration
+// between Metal3 BareMetalHosts and Servers when the manager starts.
+// Existing objects are never overwritten, so it is safe to leave enabled
+// across restarts.
+type Migrator struct {
+	options Options
+	client  client.Client
+	reader  client.Reader
+}
+
+// Ensure Migrator implements manager.Runnable
+var _ manager.Runnable = &Migrator{}
+
+// NewMigrator creates a new Metal3 migrator runnable.
+func NewMigrator(opts Options, mgr manager.Manager) (*Migrator, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		options: opts,
+		client:  mgr.GetClient(),
+		reader:  mgr.GetAPIReader(),
+	}, nil
+}
+
+// +kubebuilder:rbac:groups=metal3.io,resources=baremetalhosts,verbs=get;list;create
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;create
+
+// Start implements manager.Runnable. It runs the migration once and returns.
+func (m *Migrator) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("metal3")
+
+	var err error
+	switch m.options.Mode {
+	case ModeImport:
+		err = m.importHosts(ctx)
+	case ModeExport:
+		err = m.exportServers(ctx)
+	default:
+		return nil
+	}
+
+	// A missing Metal3 CRD or a failed migration should not take the
+	// controller down with it.
+	if err != nil {
+		logger.Error(err, "Metal3 migration failed", "mode", m.options.Mode)
+	}
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+// Returns true so only one replica performs the migration.
+func (m *Migrator) NeedLeaderElection() bool {
+	return true
+}
+
+// importHosts creates a Server for every BareMetalHost that doesn't have one.
+func (m *Migrator) importHosts(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("metal3")
+
+	hosts := NewBareMetalHostList()
+	if err := m.reader.List(ctx, hosts, client.InNamespace(m.options.Namespace)); err != nil {
+		if meta.IsNoMatchError(err) {
+			return fmt.Errorf("BareMetalHost CRD is not installed: %w", err)
+		}
+		return fmt.Errorf("failed to list BareMetalHosts: %w", err)
+	}
+
+	imported := 0
+	for i := range hosts.Items {
+		bmh := &hosts.Items[i]
+
+		var credentials *corev1.Secret
+		if name := CredentialsName(bmh); name != "" {
+			credentials = &corev1.Secret{}
+			if err := m.reader.Get(ctx, types.NamespacedName{Name: name, Namespace: bmh.GetNamespace()}, credentials); err != nil {
+				logger.Error(err, "Skipping BareMetalHost, failed to get BMC credentials",
+					"host", bmh.GetNamespace()+"/"+bmh.GetName())
+				continue
+			}
+		}
+
+		server, err := ServerFromBareMetalHost(bmh, credentials)
+		if err != nil {
+			logger.Info("Skipping BareMetalHost", "reason", err.Error())
+			continue
+		}
+
+		if err := m.client.Create(ctx, server); err != nil {
+			if apierrors.IsAlreadyExists(err) {
+				logger.Info("Server already exists, skipping", "server", server.Name)
+				continue
+			}
+			return fmt.Errorf("failed to create server %s: %w", server.Name, err)
+		}
+		imported++
+	}
+
+	logger.Info("Imported BareMetalHosts", "imported", imported, "total", len(hosts.Items))
+	return nil
+}
+
+// exportServers creates a BareMetalHost for every IPMI Server that doesn't
+// have one.
+func (m *Migrator) exportServers(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("metal3")
+
+	exported, total := 0, 0
+	err := listing.Servers(ctx, m.reader, listing.DefaultPageSize, func(server *baremetalcontrollerv1.Server) error {
+		total++
+
+		var credentials *corev1.Secret
+		if ipmi := server.Spec.Control.IPMI; ipmi != nil && ipmi.CredentialsSecretRef != nil {
+			ref := ipmi.CredentialsSecretRef
+			if err := server.CheckSecretRef(&ref.SecretReference); err != nil {
+				logger.Info("Skipping server", "server", server.Name, "reason", err.Error())
+				return nil
+			}
+			credentials = &corev1.Secret{}
+			if err := m.reader.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: ref.Namespace}, credentials); err != nil {
+				logger.Error(err, "Skipping server, failed to get IPMI credentials", "server", server.Name)
+				return nil
+			}
+		}
+
+		bmh, secret, err := BareMetalHostFromServer(server, credentials, m.options.Namespace)
+		if err != nil {
+			logger.Info("Skipping server", "reason", err.Error())
+			return nil
+		}
+
+		if err := m.client.Create(ctx, secret); err != nil && !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create BMC secret for server %s: %w", server.Name, err)
+		}
+
+		if err := m.client.Create(ctx, bmh); err != nil {
+			if meta.IsNoMatchError(err) {
+				return fmt.Errorf("BareMetalHost CRD is not installed: %w", err)
+			}
+			if apierrors.IsAlreadyExists(err) {
+				logger.Info("BareMetalHost already exists, skipping", "host", bmh.GetName())
+				return nil
+			}
+			return fmt.Errorf("failed to create BareMetalHost for server %s: %w", server.Name, err)
+		}
+		exported++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Exported servers as BareMetalHosts", "exported", exported, "total", total)
+	return nil
+}
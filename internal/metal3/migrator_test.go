@@ -0,0 +1,215 @@
+package metal3
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+func newScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := baremetalcontrollerv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	scheme.AddKnownTypeWithName(BareMetalHostGVK, &unstructured.Unstructured{})
+	scheme.AddKnownTypeWithName(BareMetalHostGVK.GroupVersion().WithKind(BareMetalHostGVK.Kind+"List"), &unstructured.UnstructuredList{})
+	return scheme
+}
+
+func TestImportHosts(t *testing.T) {
+	existing := &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-02"},
+		Spec: baremetalcontrollerv1.ServerSpec{
+			PowerState: baremetalcontrollerv1.PowerStateOff,
+			Type:       baremetalcontrollerv1.ControlTypeIPMI,
+			Control: baremetalcontrollerv1.ControlSpecs{
+				IPMI: &baremetalcontrollerv1.IPMISpecs{Address: "10.0.0.2", Username: "operator", Password: "kept"},
+			},
+		},
+	}
+	credentials := credentialsSecret(map[string]string{usernameKey: "admin", passwordKey: "secret"})
+	c := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(
+		bareMetalHost("worker-01", "ipmi://192.168.1.10", credentials.Name, true),
+		// An existing Server is left alone
+		bareMetalHost("worker-02", "ipmi://192.168.1.20", credentials.Name, true),
+		// A host whose Secret is missing is skipped
+		bareMetalHost("worker-03", "ipmi://192.168.1.30", "missing-secret", true),
+		// A Redfish host is skipped
+		bareMetalHost("worker-04", "redfish://192.168.1.40/redfish/v1/Systems/1", credentials.Name, true),
+		credentials,
+		existing,
+	).Build()
+
+	m := &Migrator{options: Options{Mode: ModeImport, Namespace: "metal3"}, client: c, reader: c}
+	ctx := context.Background()
+	for run := 0; run < 2; run++ {
+		if err := m.importHosts(ctx); err != nil {
+			t.Fatalf("importHosts() run %d error = %v", run, err)
+		}
+	}
+
+	var imported baremetalcontrollerv1.Server
+	if err := c.Get(ctx, types.NamespacedName{Name: "worker-01"}, &imported); err != nil {
+		t.Fatalf("imported server: %v", err)
+	}
+	if ipmi := imported.Spec.Control.IPMI; ipmi == nil || ipmi.Address != "192.168.1.10" || ipmi.Password != "" ||
+		ipmi.CredentialsSecretRef == nil || ipmi.CredentialsSecretRef.Name != credentials.Name || ipmi.CredentialsSecretRef.Namespace != "metal3" {
+		t.Errorf("imported IPMI spec = %+v", ipmi)
+	}
+
+	var kept baremetalcontrollerv1.Server
+	if err := c.Get(ctx, types.NamespacedName{Name: "worker-02"}, &kept); err != nil {
+		t.Fatal(err)
+	}
+	if kept.Spec.Control.IPMI.Password != "kept" || kept.Spec.PowerState != baremetalcontrollerv1.PowerStateOff {
+		t.Errorf("existing server was overwritten: %+v", kept.Spec)
+	}
+	if _, ok := kept.Annotations[SourceAnnotation]; ok {
+		t.Errorf("existing server got the source annotation")
+	}
+
+	var servers baremetalcontrollerv1.ServerList
+	if err := c.List(ctx, &servers); err != nil {
+		t.Fatal(err)
+	}
+	if len(servers.Items) != 2 {
+		t.Errorf("got %d servers, want worker-01 and worker-02 only", len(servers.Items))
+	}
+}
+
+func TestExportServers(t *testing.T) {
+	server := &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-01"},
+		Spec: baremetalcontrollerv1.ServerSpec{
+			PowerState: baremetalcontrollerv1.PowerStateOn,
+			Type:       baremetalcontrollerv1.ControlTypeIPMI,
+			Control: baremetalcontrollerv1.ControlSpecs{
+				IPMI: &baremetalcontrollerv1.IPMISpecs{Address: "192.168.1.10", Username: "admin", Password: "secret"},
+			},
+		},
+	}
+	wol := &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-02"},
+		Spec: baremetalcontrollerv1.ServerSpec{
+			Type:    baremetalcontrollerv1.ControlTypeWOL,
+			Control: baremetalcontrollerv1.ControlSpecs{WOL: &baremetalcontrollerv1.WOLSpecs{MACAddress: "00:11:22:33:44:55"}},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(server, wol).Build()
+
+	m := &Migrator{options: Options{Mode: ModeExport, Namespace: "metal3"}, client: c, reader: c}
+	ctx := context.Background()
+	for run := 0; run < 2; run++ {
+		if err := m.exportServers(ctx); err != nil {
+			t.Fatalf("exportServers() run %d error = %v", run, err)
+		}
+	}
+
+	hosts := NewBareMetalHostList()
+	if err := c.List(ctx, hosts, client.InNamespace("metal3")); err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts.Items) != 1 || hosts.Items[0].GetName() != "worker-01" {
+		t.Fatalf("got hosts %v, want worker-01 only", hosts.Items)
+	}
+	address, _, _ := unstructured.NestedString(hosts.Items[0].Object, "spec", "bmc", "address")
+	if address != "ipmi://192.168.1.10" {
+		t.Errorf("BMC address = %q, want ipmi://192.168.1.10", address)
+	}
+
+	secret := credentialsSecret(nil)
+	if err := c.Get(ctx, types.NamespacedName{Name: CredentialsName(&hosts.Items[0]), Namespace: "metal3"}, secret); err != nil {
+		t.Fatalf("credentials secret: %v", err)
+	}
+	if string(secret.Data[usernameKey]) != "admin" || string(secret.Data[passwordKey]) != "secret" {
+		t.Errorf("credentials = %v, want the server's IPMI credentials", secret.Data)
+	}
+}
+
+func TestExportServersCredentialsSecret(t *testing.T) {
+	server := &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-01"},
+		Spec: baremetalcontrollerv1.ServerSpec{
+			Type: baremetalcontrollerv1.ControlTypeIPMI,
+			Control: baremetalcontrollerv1.ControlSpecs{
+				IPMI: &baremetalcontrollerv1.IPMISpecs{
+					Address: "192.168.1.10",
+					CredentialsSecretRef: &baremetalcontrollerv1.CredentialsSecretReference{
+						SecretReference: baremetalcontrollerv1.SecretReference{Name: "bmc", Namespace: "infra"},
+						UsernameKey:     "user",
+					},
+				},
+			},
+		},
+	}
+	credentials := credentialsSecret(map[string]string{"user": "admin", passwordKey: "secret"})
+	credentials.Name, credentials.Namespace = "bmc", "infra"
+	c := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(server, credentials).Build()
+
+	m := &Migrator{options: Options{Mode: ModeExport, Namespace: "metal3"}, client: c, reader: c}
+	ctx := context.Background()
+	if err := m.exportServers(ctx); err != nil {
+		t.Fatalf("exportServers() error = %v", err)
+	}
+
+	secret := credentialsSecret(nil)
+	if err := c.Get(ctx, types.NamespacedName{Name: "worker-01-bmc-secret", Namespace: "metal3"}, secret); err != nil {
+		t.Fatalf("credentials secret: %v", err)
+	}
+	if string(secret.Data[usernameKey]) != "admin" || string(secret.Data[passwordKey]) != "secret" {
+		t.Errorf("credentials = %v, want the credentials of the server's secret", secret.Data)
+	}
+}
+
+func TestExportServersCrossTenantSecret(t *testing.T) {
+	server := &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-01", Labels: map[string]string{baremetalcontrollerv1.TenantLabel: "tenant-a"}},
+		Spec: baremetalcontrollerv1.ServerSpec{
+			Type: baremetalcontrollerv1.ControlTypeIPMI,
+			Control: baremetalcontrollerv1.ControlSpecs{
+				IPMI: &baremetalcontrollerv1.IPMISpecs{
+					Address: "192.168.1.10",
+					CredentialsSecretRef: &baremetalcontrollerv1.CredentialsSecretReference{
+						SecretReference: baremetalcontrollerv1.SecretReference{Name: "bmc", Namespace: "tenant-b"},
+					},
+				},
+			},
+		},
+	}
+	credentials := credentialsSecret(map[string]string{usernameKey: "admin", passwordKey: "secret"})
+	credentials.Name, credentials.Namespace = "bmc", "tenant-b"
+	c := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(server, credentials).Build()
+
+	m := &Migrator{options: Options{Mode: ModeExport, Namespace: "metal3"}, client: c, reader: c}
+	ctx := context.Background()
+	if err := m.exportServers(ctx); err != nil {
+		t.Fatalf("exportServers() error = %v", err)
+	}
+
+	// Another tenant's credentials aren't copied into a BareMetalHost
+	secrets := &corev1.SecretList{}
+	if err := c.List(ctx, secrets, client.InNamespace("metal3")); err != nil {
+		t.Fatal(err)
+	}
+	hosts := NewBareMetalHostList()
+	if err := c.List(ctx, hosts, client.InNamespace("metal3")); err != nil {
+		t.Fatal(err)
+	}
+	if len(secrets.Items) != 0 || len(hosts.Items) != 0 {
+		t.Errorf("exported %d secrets and %d hosts, want none", len(secrets.Items), len(hosts.Items))
+	}
+}
@@ -0,0 +1,288 @@
+// Package neighbor resolves the current IP addresses of known MAC addresses
+// on the local segment. It sweeps IPv4 subnets so the kernel resolves every
+// host with ARP, then reads the ARP and NDP neighbor tables, for WoL servers
+// that have no reserved address.
+package neighbor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/Unbounder1/bare-metal-controller/internal/dhcp"
+)
+
+// maxSweep is the largest IPv4 subnet that is swept, a /20
+const maxSweep = 1 << 12
+
+// Neighbor is a host that answered on the segment
+type Neighbor struct {
+	MACAddress string
+	IPAddress  string
+}
+
+// Options contains configuration for neighbor discovery.
+type Options struct {
+	// Subnets are the CIDRs scanned from the controller, comma separated.
+	// Empty to not scan from the controller.
+	Subnets string
+
+	// Relays scans the subnets of every WoL relay agent
+	Relays bool
+
+	// Wait is how long to wait for ARP replies after a sweep
+	Wait time.Duration
+}
+
+// DefaultOptions returns the default neighbor discovery options, with
+// discovery disabled.
+func DefaultOptions() Options {
+	return Options{
+		Wait: 2 * time.Second,
+	}
+}
+
+// BindFlags binds the neighbor discovery options to command line flags.
+// The prefix can be used to namespace the flags (e.g., "neighbor-").
+func (o *Options) BindFlags(fs *flag.FlagSet, prefix string) {
+	fs.StringVar(&o.Subnets, prefix+"subnets", o.Subnets,
+		"Comma-separated CIDRs on the controller's segment to resolve server MAC addresses in with ARP and NDP. Empty to disable.")
+	fs.BoolVar(&o.Relays, prefix+"relays", o.Relays,
+		"If set, every WoL relay agent started with --scan-subnets resolves server MAC addresses on its subnets.")
+	fs.DurationVar(&o.Wait, prefix+"wait", o.Wait,
+		"How long to wait for ARP replies after sweeping a subnet.")
+}
+
+// Validate validates the options.
+func (o *Options) Validate() error {
+	if _, err := ParseSubnets(o.Subnets); err != nil {
+		return err
+	}
+	if o.Enabled() && o.Wait <= 0 {
+		return fmt.Errorf("neighbor wait must be positive")
+	}
+	return nil
+}
+
+// Enabled returns true if server addresses should be discovered.
+func (o *Options) Enabled() bool {
+	return o.Subnets != "" || o.Relays
+}
+
+// ParseSubnets parses comma-separated CIDRs. IPv4 subnets larger than a /20
+// are rejected, since every address of them is swept.
+func ParseSubnets(value string) ([]*net.IPNet, error) {
+	var subnets []*net.IPNet
+	for _, cidr := range strings.Split(value, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		_, subnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subnet %q: %w", cidr, err)
+		}
+		if subnet.IP.To4() != nil {
+			ones, bits := subnet.Mask.Size()
+			if 1<<(bits-ones) > maxSweep {
+				return nil, fmt.Errorf("subnet %s is larger than a /20", cidr)
+			}
+		}
+		subnets = append(subnets, subnet)
+	}
+	return subnets, nil
+}
+
+// Scanner finds the hosts of subnets on the local segment. IPv4 subnets are
+// swept with a UDP datagram to every address, which makes the kernel resolve
+// them with ARP. IPv6 subnets are too large to sweep, so only hosts already
+// in the NDP neighbor table are found.
+type Scanner struct {
+	Subnets []*net.IPNet
+	// Wait is how long to wait for ARP replies after a sweep
+	Wait time.Duration
+}
+
+// Scan sweeps the subnets and returns the neighbors in them
+func (s *Scanner) Scan(ctx context.Context) ([]Neighbor, error) {
+	if err := s.sweep(ctx); err != nil {
+		return nil, err
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(s.Wait):
+	}
+
+	var neighbors []Neighbor
+	if s.hasFamily(false) {
+		f, err := os.Open("/proc/net/arp")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ARP table: %w", err)
+		}
+		defer f.Close()
+		arp, err := parseARP(f)
+		if err != nil {
+			return nil, err
+		}
+		neighbors = append(neighbors, arp...)
+	}
+	if s.hasFamily(true) {
+		out, err := exec.CommandContext(ctx, "ip", "-6", "neighbor", "show").Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read NDP table: %w", err)
+		}
+		ndp, err := parseNeighbors(bytes.NewReader(out))
+		if err != nil {
+			return nil, err
+		}
+		neighbors = append(neighbors, ndp...)
+	}
+
+	var inSubnets []Neighbor
+	for _, neighbor := range neighbors {
+		if s.contains(net.ParseIP(neighbor.IPAddress)) {
+			inSubnets = append(inSubnets, neighbor)
+		}
+	}
+	return inSubnets, nil
+}
+
+// sweep sends a datagram to the discard port of every IPv4 address of the
+// subnets. Nothing needs to answer, the ARP request the kernel sends first
+// fills the neighbor table.
+func (s *Scanner) sweep(ctx context.Context) error {
+	if !s.hasFamily(false) {
+		return nil
+	}
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return fmt.Errorf("failed to open sweep socket: %w", err)
+	}
+	defer conn.Close()
+	for _, subnet := range s.Subnets {
+		network := subnet.IP.To4()
+		if network == nil {
+			continue
+		}
+		base := binary.BigEndian.Uint32(network)
+		ones, bits := subnet.Mask.Size()
+		size := uint32(1) << (bits - ones)
+		for i := uint32(0); i < size; i++ {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			ip := binary.BigEndian.AppendUint32(nil, base+i)
+			// Unreachable hosts fail the write, which is expected
+			_, _ = conn.WriteToUDP([]byte{0}, &net.UDPAddr{IP: ip, Port: 9})
+		}
+	}
+	return nil
+}
+
+func (s *Scanner) hasFamily(ipv6 bool) bool {
+	for _, subnet := range s.Subnets {
+		if (subnet.IP.To4() == nil) == ipv6 {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scanner) contains(ip net.IP) bool {
+	for _, subnet := range s.Subnets {
+		if ip != nil && subnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseARP reads /proc/net/arp, skipping incomplete entries
+func parseARP(r io.Reader) ([]Neighbor, error) {
+	var neighbors []Neighbor
+	scanner := bufio.NewScanner(r)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		// IP address, HW type, Flags, HW address, Mask, Device
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 || fields[2] == "0x0" {
+			continue
+		}
+		mac, err := net.ParseMAC(fields[3])
+		if err != nil || mac.String() == "00:00:00:00:00:00" {
+			continue
+		}
+		neighbors = append(neighbors, Neighbor{MACAddress: mac.String(), IPAddress: fields[0]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ARP table: %w", err)
+	}
+	return neighbors, nil
+}
+
+// parseNeighbors reads the output of "ip neighbor show", e.g.
+// "fd00::17 dev eth0 lladdr 00:11:22:33:44:55 REACHABLE", skipping entries
+// without a link-layer address
+func parseNeighbors(r io.Reader) ([]Neighbor, error) {
+	var neighbors []Neighbor
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		for i := 1; i+1 < len(fields); i++ {
+			if fields[i] != "lladdr" {
+				continue
+			}
+			if mac, err := net.ParseMAC(fields[i+1]); err == nil {
+				neighbors = append(neighbors, Neighbor{MACAddress: mac.String(), IPAddress: fields[0]})
+			}
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read NDP table: %w", err)
+	}
+	return neighbors, nil
+}
+
+// Source returns the scanner as a lease source for the lease tracker. The
+// addresses it finds don't expire.
+func (s *Scanner) Source() dhcp.Source {
+	return &source{name: "neighbor", scan: s.Scan}
+}
+
+// NewSource returns a lease source for neighbors found by scan, e.g. on a
+// relay agent
+func NewSource(name string, scan func(ctx context.Context) ([]Neighbor, error)) dhcp.Source {
+	return &source{name: name, scan: scan}
+}
+
+type source struct {
+	name string
+	scan func(ctx context.Context) ([]Neighbor, error)
+}
+
+func (s *source) Name() string {
+	return s.name
+}
+
+func (s *source) Leases(ctx context.Context) ([]dhcp.Lease, error) {
+	neighbors, err := s.scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	leases := make([]dhcp.Lease, 0, len(neighbors))
+	for _, neighbor := range neighbors {
+		leases = append(leases, dhcp.Lease{MACAddress: neighbor.MACAddress, IPAddress: neighbor.IPAddress})
+	}
+	return leases, nil
+}
@@ -0,0 +1,97 @@
+// Package nodefeatures picks the hardware labels node-feature-discovery and
+// GPU feature discovery put on Nodes. The controller keeps them in the
+// status of the Node's Server, so a powered off server is still known by
+// its hardware after its Node is gone.
+package nodefeatures
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+const (
+	// GPUProductLabel is the GPU model GPU feature discovery labels Nodes
+	// with, e.g. NVIDIA-A100-SXM4-80GB
+	GPUProductLabel = "nvidia.com/gpu.product"
+
+	// GPUTypeLabel is the GPU model Servers can be labeled with by hand. It
+	// takes precedence over the discovered one.
+	GPUTypeLabel = "gpu-type"
+)
+
+// Options contains configuration for recording node features.
+type Options struct {
+	// Enabled records the features of Nodes in their Servers' status
+	Enabled bool
+
+	// Prefixes are the label prefixes of the recorded Node labels, comma
+	// separated
+	Prefixes string
+}
+
+// DefaultOptions records the labels of node-feature-discovery and of the
+// NVIDIA GPU feature discovery.
+func DefaultOptions() Options {
+	return Options{
+		Prefixes: "feature.node.kubernetes.io/,nvidia.com/",
+	}
+}
+
+// BindFlags binds the node feature options to command line flags.
+// The prefix can be used to namespace the flags (e.g., "node-features-").
+func (o *Options) BindFlags(fs *flag.FlagSet, prefix string) {
+	fs.BoolVar(&o.Enabled, prefix+"enabled", o.Enabled,
+		"Record the node-feature-discovery labels of Nodes in status.nodeFeatures of their Servers.")
+	fs.StringVar(&o.Prefixes, prefix+"prefixes", o.Prefixes,
+		"Comma separated prefixes of the Node labels recorded as node features.")
+}
+
+// Validate validates the options.
+func (o *Options) Validate() error {
+	if o.Enabled && len(o.PrefixList()) == 0 {
+		return fmt.Errorf("at least one node feature prefix is required")
+	}
+	return nil
+}
+
+// PrefixList returns the label prefixes
+func (o *Options) PrefixList() []string {
+	var prefixes []string
+	for _, prefix := range strings.Split(o.Prefixes, ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+// Select returns the labels with one of the prefixes, or nil if there are
+// none
+func Select(labels map[string]string, prefixes []string) map[string]string {
+	var features map[string]string
+	for key, value := range labels {
+		for _, prefix := range prefixes {
+			if !strings.HasPrefix(key, prefix) {
+				continue
+			}
+			if features == nil {
+				features = map[string]string{}
+			}
+			features[key] = value
+			break
+		}
+	}
+	return features
+}
+
+// GPUType returns the GPU model of a server, from its gpu-type label or the
+// features of its Node, or "" if it has no GPU
+func GPUType(server *baremetalcontrollerv1.Server) string {
+	if gpuType, ok := server.Labels[GPUTypeLabel]; ok {
+		return gpuType
+	}
+	return server.Status.NodeFeatures[GPUProductLabel]
+}
@@ -0,0 +1,208 @@
+// Package pet receives IPMI Platform Event Traps, the SNMP traps BMCs send
+// when a sensor crosses a threshold or a component fails, e.g. a power
+// supply losing its input or a CPU tripping its thermal limit. Traps are
+// matched to the Server whose BMC address they came from, and the server
+// controller turns them into events and the HardwareFault condition within
+// seconds instead of at the next poll.
+package pet
+
+import (
+	"flag"
+	"fmt"
+	"net"
+)
+
+// Options contains configuration for the trap receiver.
+type Options struct {
+	// Address is the UDP address to listen on (e.g., ":162"), "0" to
+	// disable
+	Address string
+
+	// Community is the SNMP community traps must carry, empty to take
+	// traps of any community
+	Community string
+}
+
+// DefaultOptions returns the default trap receiver options.
+func DefaultOptions() Options {
+	return Options{
+		Address: "0",
+	}
+}
+
+// BindFlags binds the trap receiver options to command line flags.
+// The prefix can be used to namespace the flags (e.g., "pet-").
+func (o *Options) BindFlags(fs *flag.FlagSet, prefix string) {
+	fs.StringVar(&o.Address, prefix+"bind-address", o.Address,
+		"The UDP address the receiver of IPMI Platform Event Traps binds to, e.g. :162. Use 0 to disable it.")
+	fs.StringVar(&o.Community, prefix+"community", o.Community,
+		"SNMP community Platform Event Traps must carry. Empty takes traps of any community.")
+}
+
+// Validate validates the options.
+func (o *Options) Validate() error {
+	if !o.Enabled() {
+		return nil
+	}
+	if _, err := net.ResolveUDPAddr("udp", o.Address); err != nil {
+		return fmt.Errorf("invalid trap receiver address %q: %w", o.Address, err)
+	}
+	return nil
+}
+
+// Enabled returns true if the receiver should be started.
+func (o *Options) Enabled() bool {
+	return o.Address != "" && o.Address != "0"
+}
+
+// Severities of platform events, as the server controller reports them
+const (
+	SeverityOK       = "OK"
+	SeverityWarning  = "Warning"
+	SeverityCritical = "Critical"
+)
+
+// Event is a platform event a BMC sent
+type Event struct {
+	// SensorType is the kind of sensor, e.g. PowerSupply or Temperature
+	SensorType string
+	// Sensor is the number of the sensor on the BMC
+	Sensor uint8
+	// Description says what happened, e.g. "input lost"
+	Description string
+	// Deasserted is true if the event ended, e.g. the power supply got its
+	// input back
+	Deasserted bool
+	// Severity is OK, Warning or Critical
+	Severity string
+}
+
+// sensorTypes names the IPMI sensor types, of table 42-3 of the IPMI 2.0
+// specification, that BMCs send traps for
+var sensorTypes = map[uint8]string{
+	0x01: "Temperature",
+	0x02: "Voltage",
+	0x03: "Current",
+	0x04: "Fan",
+	0x05: "PhysicalSecurity",
+	0x07: "Processor",
+	0x08: "PowerSupply",
+	0x09: "PowerUnit",
+	0x0c: "Memory",
+	0x0d: "DriveSlot",
+	0x0f: "SystemFirmwareProgress",
+	0x10: "EventLoggingDisabled",
+	0x12: "SystemEvent",
+	0x13: "CriticalInterrupt",
+	0x23: "Watchdog",
+}
+
+// eventTypeThreshold is the event type of threshold sensors, whose offsets
+// are the threshold crossed
+const eventTypeThreshold = 0x01
+
+// thresholdOffsets describes the thresholds a sensor crossed, and whether
+// crossing them is critical
+var thresholdOffsets = map[uint8]struct {
+	description string
+	severity    string
+}{
+	0x00: {"lower non-critical going low", SeverityWarning},
+	0x01: {"lower non-critical going high", SeverityWarning},
+	0x02: {"lower critical going low", SeverityCritical},
+	0x03: {"lower critical going high", SeverityCritical},
+	0x04: {"lower non-recoverable going low", SeverityCritical},
+	0x05: {"lower non-recoverable going high", SeverityCritical},
+	0x06: {"upper non-critical going low", SeverityWarning},
+	0x07: {"upper non-critical going high", SeverityWarning},
+	0x08: {"upper critical going low", SeverityCritical},
+	0x09: {"upper critical going high", SeverityCritical},
+	0x0a: {"upper non-recoverable going low", SeverityCritical},
+	0x0b: {"upper non-recoverable going high", SeverityCritical},
+}
+
+// sensorOffsets describes the offsets of sensor-specific events, for the
+// sensor types whose failures take a server down
+var sensorOffsets = map[uint8]map[uint8]struct {
+	description string
+	severity    string
+}{
+	0x07: {
+		0x00: {"internal error", SeverityCritical},
+		0x01: {"thermal trip", SeverityCritical},
+		0x05: {"configuration error", SeverityCritical},
+		0x07: {"presence detected", SeverityOK},
+		0x0a: {"throttled", SeverityWarning},
+	},
+	0x08: {
+		0x00: {"presence detected", SeverityOK},
+		0x01: {"failure detected", SeverityCritical},
+		0x02: {"predictive failure", SeverityWarning},
+		0x03: {"input lost", SeverityCritical},
+		0x04: {"input lost or out of range", SeverityCritical},
+		0x05: {"input out of range", SeverityWarning},
+		0x06: {"configuration error", SeverityWarning},
+	},
+	0x0c: {
+		0x00: {"correctable ECC error", SeverityWarning},
+		0x01: {"uncorrectable ECC error", SeverityCritical},
+		0x05: {"correctable ECC error logging limit reached", SeverityWarning},
+	},
+}
+
+// petSeverities maps the event severity of a trap's data to the severities
+// of Event. Unspecified severities are taken from the event offset.
+var petSeverities = map[uint8]string{
+	0x01: SeverityOK,
+	0x02: SeverityOK,
+	0x04: SeverityOK,
+	0x08: SeverityWarning,
+	0x10: SeverityCritical,
+	0x20: SeverityCritical,
+}
+
+// newEvent decodes the specific trap number of a PET, which holds the
+// sensor type, event type and event offset, with the severity byte of the
+// trap's data if it has one
+func newEvent(specific uint32, sensor uint8, severity uint8) Event {
+	sensorType := uint8(specific >> 16)
+	eventType := uint8(specific >> 8)
+	offset := uint8(specific) & 0x0f
+
+	e := Event{
+		SensorType: sensorTypes[sensorType],
+		Sensor:     sensor,
+		Deasserted: specific&0x80 != 0,
+		Severity:   SeverityOK,
+	}
+	if e.SensorType == "" {
+		e.SensorType = fmt.Sprintf("SensorType%02X", sensorType)
+	}
+
+	known, ok := thresholdOffsets[offset]
+	if eventType != eventTypeThreshold {
+		known, ok = sensorOffsets[sensorType][offset]
+	}
+	if ok {
+		e.Description, e.Severity = known.description, known.severity
+	} else {
+		e.Description = fmt.Sprintf("offset %d", offset)
+	}
+	if s, ok := petSeverities[severity]; ok {
+		e.Severity = s
+	}
+	if e.Deasserted {
+		e.Description += " deasserted"
+	}
+	return e
+}
+
+// String describes the event for events and logs
+func (e Event) String() string {
+	return fmt.Sprintf("%s sensor 0x%02x: %s", e.SensorType, e.Sensor, e.Description)
+}
+
+// Fault reports whether the event is a critical failure still ongoing
+func (e Event) Fault() bool {
+	return e.Severity == SeverityCritical && !e.Deasserted
+}
@@ -0,0 +1,213 @@
+package pet
+
+import (
+	"testing"
+)
+
+// tlv encodes a BER element, with a long-form length if needed
+func tlv(tag byte, parts ...[]byte) []byte {
+	var value []byte
+	for _, part := range parts {
+		value = append(value, part...)
+	}
+	if len(value) < 0x80 {
+		return append([]byte{tag, byte(len(value))}, value...)
+	}
+	return append([]byte{tag, 0x82, byte(len(value) >> 8), byte(len(value))}, value...)
+}
+
+func encodeOID(first, second int, rest ...int) []byte {
+	b := []byte{byte(40*first + second)}
+	for _, n := range rest {
+		var chunk []byte
+		for chunk = []byte{byte(n & 0x7f)}; n > 0x7f; {
+			n >>= 7
+			chunk = append([]byte{byte(n&0x7f) | 0x80}, chunk...)
+		}
+		b = append(b, chunk...)
+	}
+	return tlv(tagOID, b)
+}
+
+func encodeInt(n uint32) []byte {
+	b := []byte{byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)}
+	for len(b) > 1 && b[0] == 0 && b[1]&0x80 == 0 {
+		b = b[1:]
+	}
+	if b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return tlv(tagInteger, b)
+}
+
+// petPacket encodes a Platform Event Trap as a Dell or Supermicro BMC sends
+// it, with the event severity and sensor number in its data
+func petPacket(community string, specific uint32, severity byte, sensor byte) []byte {
+	data := make([]byte, 47)
+	data[dataSeverity], data[dataSensor] = severity, sensor
+	return tlv(tagSequence,
+		encodeInt(0),
+		tlv(tagOctetString, []byte(community)),
+		tlv(tagTrapPDU,
+			encodeOID(1, 3, 6, 1, 4, 1, 3183, 1, 1),
+			tlv(tagIPAddress, []byte{10, 0, 1, 11}),
+			encodeInt(genericEnterpriseSpecific),
+			encodeInt(specific),
+			tlv(tagTimeTicks, []byte{0x01, 0x02}),
+			tlv(tagSequence,
+				tlv(tagSequence, encodeOID(1, 3, 6, 1, 2, 1, 1, 3, 0), tlv(tagTimeTicks, []byte{0x01})),
+				tlv(tagSequence, encodeOID(1, 3, 6, 1, 4, 1, 3183, 1, 1, 1), tlv(tagOctetString, data)),
+			),
+		),
+	)
+}
+
+func TestParseTrap(t *testing.T) {
+	// Power supply (0x08), sensor-specific (0x6f), input lost (3)
+	tr, err := parseTrap(petPacket("public", 0x086f03, 0x10, 0x31))
+	if err != nil {
+		t.Fatalf("parseTrap() error = %v", err)
+	}
+	if tr.community != "public" || tr.enterprise != petEnterprise || tr.generic != 6 || tr.specific != 0x086f03 {
+		t.Errorf("parseTrap() = %+v", tr)
+	}
+	e, err := tr.event()
+	if err != nil {
+		t.Fatalf("event() error = %v", err)
+	}
+	want := Event{SensorType: "PowerSupply", Sensor: 0x31, Description: "input lost", Severity: SeverityCritical}
+	if e != want {
+		t.Errorf("event() = %+v, want %+v", e, want)
+	}
+	if e.String() != "PowerSupply sensor 0x31: input lost" {
+		t.Errorf("String() = %q", e.String())
+	}
+}
+
+func TestParseTrapErrors(t *testing.T) {
+	valid := petPacket("public", 0x086f03, 0x10, 0x31)
+	v2 := append([]byte(nil), valid...)
+	v2[4] = 1 // SNMPv2c
+
+	tests := map[string][]byte{
+		"empty":     nil,
+		"truncated": valid[:len(valid)-10],
+		"trailing":  append(append([]byte(nil), valid...), 0),
+		"snmpv2c":   v2,
+		"not snmp":  []byte("<13>Oct 16 12:00:00 bmc-01 power supply lost"),
+	}
+	for name, packet := range tests {
+		if _, err := parseTrap(packet); err == nil {
+			t.Errorf("parseTrap(%s) succeeded", name)
+		}
+	}
+}
+
+func TestTrapEventNotPET(t *testing.T) {
+	tests := map[string]*trap{
+		"other enterprise": {enterprise: "1.3.6.1.4.1.674.10892.5", generic: genericEnterpriseSpecific},
+		"link down":        {enterprise: petEnterprise, generic: 2},
+	}
+	for name, tr := range tests {
+		if _, err := tr.event(); err != errNotPET {
+			t.Errorf("event(%s) error = %v, want not a PET", name, err)
+		}
+	}
+}
+
+func TestNewEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		specific uint32
+		severity byte
+		want     Event
+	}{
+		{
+			name:     "temperature upper critical",
+			specific: 0x010109,
+			want:     Event{SensorType: "Temperature", Description: "upper critical going high", Severity: SeverityCritical},
+		},
+		{
+			name:     "temperature back below upper critical",
+			specific: 0x010189,
+			want:     Event{SensorType: "Temperature", Description: "upper critical going high deasserted", Deasserted: true, Severity: SeverityCritical},
+		},
+		{
+			name:     "processor thermal trip",
+			specific: 0x076f01,
+			want:     Event{SensorType: "Processor", Description: "thermal trip", Severity: SeverityCritical},
+		},
+		{
+			name:     "fan non-critical",
+			specific: 0x040100,
+			want:     Event{SensorType: "Fan", Description: "lower non-critical going low", Severity: SeverityWarning},
+		},
+		{
+			name:     "severity of the data wins",
+			specific: 0x086f02,
+			severity: 0x10,
+			want:     Event{SensorType: "PowerSupply", Description: "predictive failure", Severity: SeverityCritical},
+		},
+		{
+			name:     "unknown sensor type",
+			specific: 0xc06f03,
+			want:     Event{SensorType: "SensorTypeC0", Description: "offset 3", Severity: SeverityOK},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newEvent(tt.specific, 0, tt.severity); got != tt.want {
+				t.Errorf("newEvent(0x%06x) = %+v, want %+v", tt.specific, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventFault(t *testing.T) {
+	if !(Event{Severity: SeverityCritical}).Fault() {
+		t.Errorf("critical event is no fault")
+	}
+	if (Event{Severity: SeverityCritical, Deasserted: true}).Fault() {
+		t.Errorf("deasserted event is a fault")
+	}
+	if (Event{Severity: SeverityWarning}).Fault() {
+		t.Errorf("warning is a fault")
+	}
+}
+
+func TestParseOID(t *testing.T) {
+	tests := map[string]string{
+		"\x2b\x06\x01\x04\x01\x98\x6f\x01\x01": "1.3.6.1.4.1.3183.1.1",
+		"\x2b":                                 "1.3",
+		"\x88\x37\x01":                         "2.999.1",
+	}
+	for b, want := range tests {
+		if got, err := parseOID([]byte(b)); err != nil || got != want {
+			t.Errorf("parseOID(%x) = %q, %v, want %q", b, got, err, want)
+		}
+	}
+	if _, err := parseOID([]byte{0x2b, 0x86}); err == nil {
+		t.Errorf("parseOID() succeeded with a truncated identifier")
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		opts    Options
+		enabled bool
+		wantErr bool
+	}{
+		{opts: DefaultOptions()},
+		{opts: Options{Address: ":162"}, enabled: true},
+		{opts: Options{Address: ":162", Community: "public"}, enabled: true},
+		{opts: Options{Address: "10.0.0.1:99999"}, enabled: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) error = %v, wantErr %v", tt.opts, err, tt.wantErr)
+		}
+		if tt.opts.Enabled() != tt.enabled {
+			t.Errorf("Enabled(%+v) = %v, want %v", tt.opts, !tt.enabled, tt.enabled)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package pet
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/index"
+)
+
+// maxPendingEvents limits the events kept per server until the controller
+// takes them
+const maxPendingEvents = 32
+
+// maxTrapSize is the largest datagram read, PETs are a few hundred bytes
+const maxTrapSize = 4096
+
+// Receiver implements manager.Runnable. It listens for Platform Event Traps,
+// looks up the server whose BMC sent each one by its source address, and
+// keeps the events until the controller takes them.
+type Receiver struct {
+	options Options
+	reader  client.Reader
+	log     logr.Logger
+
+	mu      sync.Mutex
+	pending map[client.ObjectKey][]Event
+
+	// notify reconciles a server once its BMC sent a trap
+	notify chan event.GenericEvent
+}
+
+// Ensure Receiver implements manager.Runnable
+var _ manager.Runnable = &Receiver{}
+
+// NewReceiver creates a new trap receiver. Servers are looked up with
+// reader, which needs the index of server addresses.
+func NewReceiver(opts Options, reader client.Reader) *Receiver {
+	return &Receiver{
+		options: opts,
+		reader:  reader,
+		log:     ctrl.Log.WithName("pet"),
+		pending: map[client.ObjectKey][]Event{},
+		notify:  make(chan event.GenericEvent, 1024),
+	}
+}
+
+// Notifications has an event for each server whose BMC sent a trap, for the
+// controller to reconcile it
+func (r *Receiver) Notifications() <-chan event.GenericEvent {
+	return r.notify
+}
+
+// TakeEvents returns the events received for a server since it was last
+// called
+func (r *Receiver) TakeEvents(server client.ObjectKey) []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := r.pending[server]
+	delete(r.pending, server)
+	return events
+}
+
+// Forget drops the events of a server that no longer exists
+func (r *Receiver) Forget(server client.ObjectKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pending, server)
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Every
+// replica receives traps, and replicas that don't reconcile the server
+// ignore them.
+func (r *Receiver) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable and receives traps until the context
+// is cancelled.
+func (r *Receiver) Start(ctx context.Context) error {
+	conn, err := net.ListenPacket("udp", r.options.Address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", r.options.Address, err)
+	}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	buf := make([]byte, maxTrapSize)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("trap receiver error: %w", err)
+		}
+		udp, ok := from.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+		r.handle(ctx, buf[:n], udp.IP)
+	}
+}
+
+// handle queues the event of a trap for the server whose BMC sent it. Traps
+// that aren't PETs, of another community or from unknown addresses are
+// dropped.
+func (r *Receiver) handle(ctx context.Context, packet []byte, from net.IP) {
+	t, err := parseTrap(packet)
+	if err != nil {
+		r.log.V(1).Info("Dropped invalid trap", "from", from.String(), "error", err.Error())
+		return
+	}
+	if r.options.Community != "" && t.community != r.options.Community {
+		r.log.Info("Dropped trap with a wrong community", "from", from.String())
+		return
+	}
+	e, err := t.event()
+	if err != nil {
+		r.log.V(1).Info("Dropped trap that is not a platform event", "from", from.String(), "enterprise", t.enterprise)
+		return
+	}
+
+	server, err := index.ServerByAddress(ctx, r.reader, from.String())
+	if err != nil {
+		r.log.Error(err, "Failed to look up server of trap", "from", from.String())
+		return
+	}
+	if server == nil {
+		r.log.Info("Dropped trap from an address of no server", "from", from.String(), "event", e.String())
+		return
+	}
+
+	key := client.ObjectKeyFromObject(server)
+	r.mu.Lock()
+	events := append(r.pending[key], e)
+	if extra := len(events) - maxPendingEvents; extra > 0 {
+		events = events[extra:]
+	}
+	r.pending[key] = events
+	r.mu.Unlock()
+	r.log.V(1).Info("Received platform event", "server", server.Name, "event", e.String(), "severity", e.Severity)
+
+	select {
+	case r.notify <- event.GenericEvent{Object: &baremetalcontrollerv1.Server{ObjectMeta: metav1.ObjectMeta{Name: server.Name, Namespace: server.Namespace}}}:
+	default:
+		// The events are taken at the server's next reconcile anyway
+	}
+}
@@ -0,0 +1,120 @@
+package pet
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/index"
+)
+
+// newTestReceiver returns a receiver for worker-01, whose BMC is at bmc
+func newTestReceiver(t *testing.T, opts Options, bmc string) *Receiver {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := baremetalcontrollerv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	builder := index.NewFakeClientBuilder(scheme).WithObjects(&baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-01"},
+		Spec: baremetalcontrollerv1.ServerSpec{
+			Type:    baremetalcontrollerv1.ControlTypeIPMI,
+			Control: baremetalcontrollerv1.ControlSpecs{IPMI: &baremetalcontrollerv1.IPMISpecs{Address: bmc}},
+		},
+	})
+	return NewReceiver(opts, builder.Build())
+}
+
+func TestReceiverHandle(t *testing.T) {
+	r := newTestReceiver(t, Options{Address: ":162", Community: "public"}, "10.0.1.11")
+	ctx := context.Background()
+	bmc := net.ParseIP("10.0.1.11")
+
+	r.handle(ctx, petPacket("public", 0x086f03, 0x10, 0x31), bmc)
+	// Dropped: another community, an unknown address and garbage
+	r.handle(ctx, petPacket("private", 0x086f03, 0x10, 0x31), bmc)
+	r.handle(ctx, petPacket("public", 0x086f03, 0x10, 0x31), net.ParseIP("10.0.1.99"))
+	r.handle(ctx, []byte("not a trap"), bmc)
+
+	select {
+	case e := <-r.Notifications():
+		if e.Object.GetName() != "worker-01" {
+			t.Errorf("notified %s, want worker-01", e.Object.GetName())
+		}
+	default:
+		t.Errorf("no notification for worker-01")
+	}
+	events := r.TakeEvents(client.ObjectKey{Name: "worker-01"})
+	if len(events) != 1 || events[0].SensorType != "PowerSupply" || !events[0].Fault() {
+		t.Errorf("TakeEvents() = %+v, want the power supply fault", events)
+	}
+	if events := r.TakeEvents(client.ObjectKey{Name: "worker-01"}); len(events) != 0 {
+		t.Errorf("TakeEvents() = %+v again", events)
+	}
+}
+
+func TestReceiverBoundsPendingEvents(t *testing.T) {
+	r := newTestReceiver(t, Options{Address: ":162"}, "10.0.1.11")
+	bmc := net.ParseIP("10.0.1.11")
+	for i := 0; i < maxPendingEvents+5; i++ {
+		r.handle(context.Background(), petPacket("public", 0x010107, 0x08, byte(i)), bmc)
+	}
+	events := r.TakeEvents(client.ObjectKey{Name: "worker-01"})
+	if len(events) != maxPendingEvents || events[len(events)-1].Sensor != maxPendingEvents+4 {
+		t.Errorf("%d events, want the latest %d", len(events), maxPendingEvents)
+	}
+
+	r.handle(context.Background(), petPacket("public", 0x010107, 0x08, 0), bmc)
+	r.Forget(client.ObjectKey{Name: "worker-01"})
+	if events := r.TakeEvents(client.ObjectKey{Name: "worker-01"}); len(events) != 0 {
+		t.Errorf("TakeEvents() = %+v after Forget()", events)
+	}
+}
+
+func TestReceiverStart(t *testing.T) {
+	// Find a free port for the receiver to listen on
+	probe, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := probe.LocalAddr().String()
+	probe.Close()
+
+	r := newTestReceiver(t, Options{Address: address}, "127.0.0.1")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- r.Start(ctx) }()
+
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// Traps sent before the receiver listens are lost, so keep sending
+	received := false
+	for i := 0; i < 50 && !received; i++ {
+		_, _ = conn.Write(petPacket("public", 0x010109, 0x10, 0x02))
+		select {
+		case <-r.Notifications():
+			received = true
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	if !received {
+		t.Fatalf("no trap received")
+	}
+	if events := r.TakeEvents(client.ObjectKey{Name: "worker-01"}); len(events) == 0 || events[0].SensorType != "Temperature" {
+		t.Errorf("TakeEvents() = %+v", events)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Start() error = %v", err)
+	}
+}
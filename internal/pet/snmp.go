@@ -0,0 +1,197 @@
+package pet
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BER tags of the parts of an SNMPv1 trap
+const (
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagOID         = 0x06
+	tagSequence    = 0x30
+	tagIPAddress   = 0x40
+	tagTimeTicks   = 0x43
+	tagTrapPDU     = 0xa4
+)
+
+// petEnterprise is the enterprise OID of Platform Event Traps, and
+// petDataOID the variable holding the event data
+const (
+	petEnterprise = "1.3.6.1.4.1.3183.1.1"
+	petDataOID    = "1.3.6.1.4.1.3183.1.1.1"
+)
+
+// genericEnterpriseSpecific is the generic trap number of traps whose
+// meaning is in the specific trap number
+const genericEnterpriseSpecific = 6
+
+// Offsets in the PET event data, of table 4 of the PET 1.0 specification
+const (
+	dataSeverity = 26
+	dataSensor   = 28
+)
+
+var errNotPET = errors.New("not a platform event trap")
+
+// trap is the part of an SNMPv1 trap the receiver reads
+type trap struct {
+	community  string
+	enterprise string
+	generic    int64
+	specific   int64
+	data       []byte
+}
+
+// parseTrap decodes an SNMPv1 trap message
+func parseTrap(packet []byte) (*trap, error) {
+	message, rest, err := expect(packet, tagSequence)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%d bytes after the message", len(rest))
+	}
+	value, message, err := expect(message, tagInteger)
+	if err != nil {
+		return nil, err
+	}
+	if version := parseInt(value); version != 0 {
+		return nil, fmt.Errorf("SNMP version %d, want an SNMPv1 trap", version+1)
+	}
+	community, message, err := expect(message, tagOctetString)
+	if err != nil {
+		return nil, err
+	}
+	pdu, _, err := expect(message, tagTrapPDU)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &trap{community: string(community)}
+	if value, pdu, err = expect(pdu, tagOID); err != nil {
+		return nil, err
+	}
+	if t.enterprise, err = parseOID(value); err != nil {
+		return nil, err
+	}
+	if _, pdu, err = expect(pdu, tagIPAddress); err != nil {
+		return nil, err
+	}
+	if value, pdu, err = expect(pdu, tagInteger); err != nil {
+		return nil, err
+	}
+	t.generic = parseInt(value)
+	if value, pdu, err = expect(pdu, tagInteger); err != nil {
+		return nil, err
+	}
+	t.specific = parseInt(value)
+	if _, pdu, err = expect(pdu, tagTimeTicks); err != nil {
+		return nil, err
+	}
+	bindings, _, err := expect(pdu, tagSequence)
+	if err != nil {
+		return nil, err
+	}
+	for len(bindings) > 0 {
+		var binding, name []byte
+		if binding, bindings, err = expect(bindings, tagSequence); err != nil {
+			return nil, err
+		}
+		if name, binding, err = expect(binding, tagOID); err != nil {
+			return nil, err
+		}
+		oid, err := parseOID(name)
+		if err != nil {
+			return nil, err
+		}
+		if oid != petDataOID {
+			continue
+		}
+		if t.data, _, err = expect(binding, tagOctetString); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// event returns the platform event of a trap
+func (t *trap) event() (Event, error) {
+	if t.enterprise != petEnterprise && !strings.HasPrefix(t.enterprise, petEnterprise+".") ||
+		t.generic != genericEnterpriseSpecific {
+		return Event{}, errNotPET
+	}
+	var sensor, severity uint8
+	if len(t.data) > dataSensor {
+		severity, sensor = t.data[dataSeverity], t.data[dataSensor]
+	}
+	return newEvent(uint32(t.specific), sensor, severity), nil
+}
+
+// expect reads a BER element with the given tag, and returns its value and
+// what follows it
+func expect(b []byte, tag byte) ([]byte, []byte, error) {
+	if len(b) < 2 {
+		return nil, nil, errors.New("truncated message")
+	}
+	if b[0] != tag {
+		return nil, nil, fmt.Errorf("tag 0x%02x, want 0x%02x", b[0], tag)
+	}
+	length, header := int(b[1]), 2
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 3 || len(b) < 2+n {
+			return nil, nil, errors.New("invalid length")
+		}
+		length = 0
+		for _, c := range b[2 : 2+n] {
+			length = length<<8 | int(c)
+		}
+		header += n
+	}
+	if length > len(b)-header {
+		return nil, nil, errors.New("truncated message")
+	}
+	return b[header : header+length], b[header+length:], nil
+}
+
+// parseInt decodes a two's complement BER integer
+func parseInt(b []byte) int64 {
+	var n int64
+	for i, c := range b {
+		if i == 0 && c&0x80 != 0 {
+			n = -1
+		}
+		n = n<<8 | int64(c)
+	}
+	return n
+}
+
+// parseOID decodes a BER object identifier into its dotted form
+func parseOID(b []byte) (string, error) {
+	var parts []string
+	var n uint64
+	for i, c := range b {
+		n = n<<7 | uint64(c&0x7f)
+		if c&0x80 != 0 {
+			if i == len(b)-1 {
+				return "", errors.New("truncated object identifier")
+			}
+			continue
+		}
+		if parts == nil {
+			first := min(n/40, 2)
+			parts = append(parts, strconv.FormatUint(first, 10), strconv.FormatUint(n-40*first, 10))
+		} else {
+			parts = append(parts, strconv.FormatUint(n, 10))
+		}
+		n = 0
+	}
+	if parts == nil {
+		return "", errors.New("empty object identifier")
+	}
+	return strings.Join(parts, "."), nil
+}
@@ -0,0 +1,83 @@
+package power
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// akHandlePattern matches a persistent TPM handle, e.g. 0x81010002
+var akHandlePattern = regexp.MustCompile(`^0x[0-9a-fA-F]{8}$`)
+
+// ValidAKHandle returns an error if handle isn't a persistent TPM handle.
+// It ends up in a shell command, so it is checked even though the CRD
+// validates it too.
+func ValidAKHandle(handle string) error {
+	if !akHandlePattern.MatchString(handle) {
+		return fmt.Errorf("invalid attestation key handle %q, expected 0x followed by 8 hex digits", handle)
+	}
+	return nil
+}
+
+// RealAttestor runs tpm2_quote on the server over SSH. The quote is only
+// trusted after its signature has been checked against the enrolled
+// attestation key, so the SSH connection itself doesn't need to be.
+type RealAttestor struct{}
+
+func (a *RealAttestor) Quote(ctx context.Context, host string, user string, key string, akHandle string, pcrs []int, nonce []byte) (TPMQuote, error) {
+	if len(pcrs) == 0 {
+		return TPMQuote{}, fmt.Errorf("at least one PCR is required")
+	}
+	if err := ValidAKHandle(akHandle); err != nil {
+		return TPMQuote{}, err
+	}
+
+	session, err := sshConnections.session(ctx, host, user, key)
+	if err != nil {
+		return TPMQuote{}, err
+	}
+	defer session.Close()
+
+	selection := make([]string, 0, len(pcrs))
+	for _, pcr := range pcrs {
+		selection = append(selection, strconv.Itoa(pcr))
+	}
+
+	// Print the attest structure and signature as one base64 line each
+	cmd := fmt.Sprintf(`d=$(mktemp -d) && `+
+		`sudo tpm2_quote -Q -c %s -l sha256:%s -q %s -m "$d/msg" -s "$d/sig" -f plain && `+
+		`base64 -w0 "$d/msg" && echo && base64 -w0 "$d/sig" && echo; rc=$?; rm -rf "$d"; exit $rc`,
+		shellQuote(akHandle), strings.Join(selection, ","), hex.EncodeToString(nonce))
+
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	if err := session.Run(cmd); err != nil {
+		return TPMQuote{}, fmt.Errorf("unable to run tpm2_quote: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	lines := strings.Fields(stdout.String())
+	if len(lines) != 2 {
+		return TPMQuote{}, fmt.Errorf("unexpected tpm2_quote output")
+	}
+	message, err := base64.StdEncoding.DecodeString(lines[0])
+	if err != nil {
+		return TPMQuote{}, fmt.Errorf("unable to decode quote message: %w", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil {
+		return TPMQuote{}, fmt.Errorf("unable to decode quote signature: %w", err)
+	}
+
+	return TPMQuote{Message: message, Signature: signature}, nil
+}
+
+// shellQuote quotes s as a single word for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
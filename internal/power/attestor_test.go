@@ -0,0 +1,48 @@
+package power
+
+import (
+	"context"
+	"testing"
+)
+
+func TestValidAKHandle(t *testing.T) {
+	tests := map[string]bool{
+		"0x81010002":           true,
+		"0x8101000A":           true,
+		"":                     false,
+		"81010002":             false,
+		"0x8101":               false,
+		"0x810100020":          false,
+		"0x8101000g":           false,
+		"0x81010002; reboot":   false,
+		"0x81010002\n":         false,
+		"$(touch /tmp/x)":      false,
+		"0x81010002' -c 'evil": false,
+	}
+	for handle, valid := range tests {
+		if err := ValidAKHandle(handle); (err == nil) != valid {
+			t.Errorf("ValidAKHandle(%q) error = %v, want valid %v", handle, err, valid)
+		}
+	}
+}
+
+func TestQuoteRejectsHandle(t *testing.T) {
+	// The handle is checked before connecting, so no SSH server is needed
+	a := &RealAttestor{}
+	if _, err := a.Quote(context.Background(), "127.0.0.1:1", "root", "", "0x81010002;reboot", []int{0}, nil); err == nil {
+		t.Errorf("Quote() accepted an invalid handle")
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"0x81010002": `'0x81010002'`,
+		"it's":       `'it'\''s'`,
+		"":           `''`,
+	}
+	for s, want := range tests {
+		if got := shellQuote(s); got != want {
+			t.Errorf("shellQuote(%q) = %s, want %s", s, got, want)
+		}
+	}
+}
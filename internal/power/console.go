@@ -0,0 +1,141 @@
+package power
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// RealSerialConsole opens serial consoles with ipmitool or over SSH to the BMC
+type RealSerialConsole struct{}
+
+// OpenIPMI activates Serial over LAN. It needs ipmitool on the PATH.
+func (c *RealSerialConsole) OpenIPMI(address string, username string, password string) (io.ReadWriteCloser, error) {
+	// -E reads the password from IPMI_PASSWORD so it doesn't show up in ps
+	cmd := exec.Command("ipmitool", "-I", "lanplus", "-H", address, "-U", username, "-E", "sol", "activate")
+	cmd.Env = append(os.Environ(), "IPMI_PASSWORD="+password)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("unable to open ipmitool stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("unable to open ipmitool stdout: %w", err)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("unable to start ipmitool: %w", err)
+	}
+	return &ipmiConsole{cmd: cmd, stdin: stdin, stdout: stdout}, nil
+}
+
+type ipmiConsole struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.Reader
+}
+
+func (c *ipmiConsole) Read(p []byte) (int, error)  { return c.stdout.Read(p) }
+func (c *ipmiConsole) Write(p []byte) (int, error) { return c.stdin.Write(p) }
+
+func (c *ipmiConsole) Close() error {
+	// Deactivate SOL with the ipmitool escape sequence so the BMC frees the
+	// session, then make sure the process is gone
+	_, _ = c.stdin.Write([]byte("\r~."))
+	_ = c.stdin.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- c.cmd.Wait() }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		_ = c.cmd.Process.Kill()
+		<-done
+	}
+	return nil
+}
+
+// OpenSSH connects to the BMC's SSH serial console. Command is the console
+// entry command of the BMC CLI, or empty if the port attaches directly to the
+// host console.
+func (c *RealSerialConsole) OpenSSH(address string, port int, username string, password string, command string) (io.ReadWriteCloser, error) {
+	config := &ssh.ClientConfig{
+		User: username,
+		Auth: []ssh.AuthMethod{
+			ssh.Password(password),
+			ssh.KeyboardInteractive(func(user, instruction string, questions []string, echos []bool) ([]string, error) {
+				answers := make([]string, len(questions))
+				for i := range answers {
+					answers[i] = password
+				}
+				return answers, nil
+			}),
+		},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         10 * time.Second,
+	}
+
+	client, err := ssh.Dial("tcp", net.JoinHostPort(address, strconv.Itoa(port)), config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to BMC SSH: %w", err)
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("unable to create SSH session: %w", err)
+	}
+
+	if err := session.RequestPty("vt100", 24, 80, ssh.TerminalModes{ssh.ECHO: 0}); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("unable to request pty: %w", err)
+	}
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("unable to open SSH stdin: %w", err)
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("unable to open SSH stdout: %w", err)
+	}
+
+	if command != "" {
+		err = session.Start(command)
+	} else {
+		err = session.Shell()
+	}
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("unable to start serial console: %w", err)
+	}
+
+	return &sshConsole{client: client, session: session, stdin: stdin, stdout: stdout}, nil
+}
+
+type sshConsole struct {
+	client  *ssh.Client
+	session *ssh.Session
+	stdin   io.WriteCloser
+	stdout  io.Reader
+}
+
+func (c *sshConsole) Read(p []byte) (int, error)  { return c.stdout.Read(p) }
+func (c *sshConsole) Write(p []byte) (int, error) { return c.stdin.Write(p) }
+
+func (c *sshConsole) Close() error {
+	c.session.Close()
+	return c.client.Close()
+}
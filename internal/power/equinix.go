@@ -0,0 +1,167 @@
+package power
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// equinixEndpoint is the Equinix Metal API
+const equinixEndpoint = "https://api.equinix.com/metal/v1"
+
+type RealEquinixClient struct {
+	// Endpoint overrides the Equinix Metal API URL
+	Endpoint   string
+	HTTPClient *http.Client
+	// Retry is the policy for retrying failed calls, DefaultRetryPolicy
+	// if nil
+	Retry *RetryPolicy
+}
+
+func (e *RealEquinixClient) PowerOn(token string, deviceID string) error {
+	return e.action(token, deviceID, "power_on")
+}
+
+func (e *RealEquinixClient) PowerOff(token string, deviceID string) error {
+	return e.action(token, deviceID, "power_off")
+}
+
+// GetPowerStatus reports a device as on while it is active. Devices that are
+// provisioning or powering on count as off until they are.
+func (e *RealEquinixClient) GetPowerStatus(token string, deviceID string) (bool, error) {
+	if deviceID == "" {
+		return false, fmt.Errorf("Equinix Metal device ID is required")
+	}
+	body, err := e.do(token, http.MethodGet, "/devices/"+url.PathEscape(deviceID), nil)
+	if err != nil {
+		return false, err
+	}
+
+	var device struct {
+		State string `json:"state"`
+	}
+	if err := json.Unmarshal(body, &device); err != nil {
+		return false, fmt.Errorf("unable to parse Equinix Metal device: %w", err)
+	}
+	return device.State == "active", nil
+}
+
+// FindDevice pages through the devices of the project for the one with the
+// hostname
+func (e *RealEquinixClient) FindDevice(token string, projectID string, hostname string) (string, error) {
+	if projectID == "" || hostname == "" {
+		return "", fmt.Errorf("Equinix Metal project ID and hostname are required")
+	}
+	path := "/projects/" + url.PathEscape(projectID) + "/devices?per_page=100&search=" + url.QueryEscape(hostname)
+	for path != "" {
+		body, err := e.do(token, http.MethodGet, path, nil)
+		if err != nil {
+			return "", err
+		}
+
+		var page struct {
+			Devices []struct {
+				ID       string `json:"id"`
+				Hostname string `json:"hostname"`
+			} `json:"devices"`
+			Meta struct {
+				Next *struct {
+					Href string `json:"href"`
+				} `json:"next"`
+			} `json:"meta"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return "", fmt.Errorf("unable to parse Equinix Metal devices: %w", err)
+		}
+		for _, device := range page.Devices {
+			if device.Hostname == hostname {
+				return device.ID, nil
+			}
+		}
+
+		path = ""
+		if page.Meta.Next != nil {
+			path = page.Meta.Next.Href
+		}
+	}
+	return "", fmt.Errorf("no Equinix Metal device with hostname %s in project %s", hostname, projectID)
+}
+
+// action calls POST /devices/{id}/actions
+func (e *RealEquinixClient) action(token string, deviceID string, actionType string) error {
+	if deviceID == "" {
+		return fmt.Errorf("Equinix Metal device ID is required")
+	}
+	payload, err := json.Marshal(map[string]string{"type": actionType})
+	if err != nil {
+		return err
+	}
+	_, err = e.do(token, http.MethodPost, "/devices/"+url.PathEscape(deviceID)+"/actions", payload)
+	return err
+}
+
+// do sends a request to a path of the API under the retry policy
+func (e *RealEquinixClient) do(token string, method string, path string, payload []byte) ([]byte, error) {
+	var respBody []byte
+	err := retry(context.Background(), e.Retry, func() error {
+		var err error
+		respBody, err = e.doOnce(token, method, path, payload)
+		return err
+	})
+	return respBody, err
+}
+
+// doOnce sends a request to a path of the API. Paths of pagination links
+// include the API's base path, which is dropped.
+func (e *RealEquinixClient) doOnce(token string, method string, path string, payload []byte) ([]byte, error) {
+	endpoint := e.Endpoint
+	if endpoint == "" {
+		endpoint = equinixEndpoint
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	if base, err := url.Parse(endpoint); err == nil {
+		path = strings.TrimPrefix(path, base.Path)
+	}
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequest(method, endpoint+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create Equinix Metal request: %w", err)
+	}
+	req.Header.Set("X-Auth-Token", token)
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := e.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, unreachable(fmt.Errorf("unable to reach Equinix Metal API: %w", err))
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read Equinix Metal response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, classifyStatus(resp.StatusCode, fmt.Errorf("Equinix Metal %s %s failed with status %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(respBody))))
+	}
+
+	return respBody, nil
+}
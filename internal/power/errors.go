@@ -0,0 +1,110 @@
+package power
+
+import "errors"
+
+var (
+	// ErrUnreachable means the backend could not be contacted at all
+	ErrUnreachable = errors.New("unreachable")
+	// ErrAuth means the backend rejected the supplied credentials
+	ErrAuth = errors.New("authentication failed")
+	// ErrCertificate means the backend's TLS certificate was not trusted
+	ErrCertificate = errors.New("certificate not trusted")
+	// ErrTransient means the backend answered with a temporary error, e.g.
+	// busy, rate limited or an internal error, and the call may succeed later
+	ErrTransient = errors.New("temporary failure")
+	// ErrUnsupported means the backend doesn't support the operation or one
+	// of its arguments
+	ErrUnsupported = errors.New("not supported")
+	// ErrPermanent means the backend rejected the request and retrying it
+	// won't help
+	ErrPermanent = errors.New("request rejected")
+)
+
+// ErrorClass groups backend errors by how callers should react to them
+type ErrorClass string
+
+const (
+	ClassTransient   ErrorClass = "Transient"
+	ClassAuthFailure ErrorClass = "AuthFailure"
+	ClassUnreachable ErrorClass = "Unreachable"
+	ClassUnsupported ErrorClass = "Unsupported"
+	ClassPermanent   ErrorClass = "Permanent"
+)
+
+// Classify returns the class of an error returned by a backend. Untrusted
+// certificates count as authentication failures, and errors without a class
+// as permanent, so nothing is retried unless a backend says it may help.
+func Classify(err error) ErrorClass {
+	switch {
+	case errors.Is(err, ErrUnreachable):
+		return ClassUnreachable
+	case errors.Is(err, ErrAuth), errors.Is(err, ErrCertificate):
+		return ClassAuthFailure
+	case errors.Is(err, ErrTransient):
+		return ClassTransient
+	case errors.Is(err, ErrUnsupported):
+		return ClassUnsupported
+	}
+	return ClassPermanent
+}
+
+// Retryable reports whether the call that returned err may succeed if it is
+// simply tried again
+func Retryable(err error) bool {
+	class := Classify(err)
+	return class == ClassTransient || class == ClassUnreachable
+}
+
+// classifiedError tags an error with one of the errors above without
+// changing its message, so callers can use errors.Is on either.
+type classifiedError struct {
+	kind error
+	err  error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+func unreachable(err error) error {
+	return &classifiedError{kind: ErrUnreachable, err: err}
+}
+
+func authFailed(err error) error {
+	return &classifiedError{kind: ErrAuth, err: err}
+}
+
+func untrustedCertificate(err error) error {
+	return &classifiedError{kind: ErrCertificate, err: err}
+}
+
+func transient(err error) error {
+	return &classifiedError{kind: ErrTransient, err: err}
+}
+
+func unsupported(err error) error {
+	return &classifiedError{kind: ErrUnsupported, err: err}
+}
+
+func permanent(err error) error {
+	return &classifiedError{kind: ErrPermanent, err: err}
+}
+
+// classifyStatus tags an HTTP error response by its status code
+func classifyStatus(statusCode int, err error) error {
+	switch {
+	case statusCode == 401 || statusCode == 403:
+		return authFailed(err)
+	case statusCode == 405 || statusCode == 501:
+		return unsupported(err)
+	case statusCode == 408 || statusCode == 429 || statusCode >= 500:
+		return transient(err)
+	case statusCode >= 400:
+		return permanent(err)
+	}
+	return err
+}
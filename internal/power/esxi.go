@@ -0,0 +1,309 @@
+package power
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Managed objects every standalone ESXi host has
+const (
+	esxiSessionManager    = `<_this type="SessionManager">ha-sessionmgr</_this>`
+	esxiPropertyCollector = `<_this type="PropertyCollector">ha-property-collector</_this>`
+	esxiHost              = `<_this type="HostSystem">ha-host</_this>`
+)
+
+// esxiTaskPollInterval is how often a running task is checked
+const esxiTaskPollInterval = 2 * time.Second
+
+// RealESXiClient talks to the vSphere SOAP API of a standalone ESXi host,
+// logging in for each call
+type RealESXiClient struct {
+	// HTTPClient overrides the client built from the target's TLS settings
+	HTTPClient *http.Client
+	// Retry is the policy for retrying failed calls, DefaultRetryPolicy
+	// if nil
+	Retry *RetryPolicy
+}
+
+func (e *RealESXiClient) EnterMaintenanceMode(target HypervisorTarget, timeout time.Duration) error {
+	return e.withSession(target, func(s *esxiSession) error {
+		inMaintenance, err := s.inMaintenanceMode()
+		if err != nil || inMaintenance {
+			return err
+		}
+		seconds := int(timeout.Seconds())
+		task, err := s.startTask(`<EnterMaintenanceMode_Task xmlns="urn:vim25">` + esxiHost +
+			`<timeout>` + strconv.Itoa(seconds) + `</timeout></EnterMaintenanceMode_Task>`)
+		if err != nil {
+			return err
+		}
+		// The host fails the task itself after the timeout
+		return s.waitTask(task, timeout+time.Minute)
+	})
+}
+
+func (e *RealESXiClient) ExitMaintenanceMode(target HypervisorTarget) error {
+	return e.withSession(target, func(s *esxiSession) error {
+		inMaintenance, err := s.inMaintenanceMode()
+		if err != nil || !inMaintenance {
+			return err
+		}
+		task, err := s.startTask(`<ExitMaintenanceMode_Task xmlns="urn:vim25">` + esxiHost +
+			`<timeout>0</timeout></ExitMaintenanceMode_Task>`)
+		if err != nil {
+			return err
+		}
+		return s.waitTask(task, 5*time.Minute)
+	})
+}
+
+// Shutdown doesn't force the shutdown, so the host refuses unless it is in
+// maintenance mode
+func (e *RealESXiClient) Shutdown(target HypervisorTarget) error {
+	return e.withSession(target, func(s *esxiSession) error {
+		task, err := s.startTask(`<ShutdownHost_Task xmlns="urn:vim25">` + esxiHost +
+			`<force>false</force></ShutdownHost_Task>`)
+		if err != nil {
+			return err
+		}
+		// The host may go away before reporting the task as done
+		if err := s.waitTask(task, time.Minute); err != nil && !errors.Is(err, ErrUnreachable) {
+			return err
+		}
+		return nil
+	})
+}
+
+// withSession logs in, runs fn and logs out again
+func (e *RealESXiClient) withSession(target HypervisorTarget, fn func(s *esxiSession) error) error {
+	httpClient, err := e.httpClient(target)
+	if err != nil {
+		return err
+	}
+	s := &esxiSession{client: httpClient, url: esxiURL(target.Address), retry: e.Retry}
+	if err := s.login(target.Username, target.Password); err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = s.call(`<Logout xmlns="urn:vim25">` + esxiSessionManager + `</Logout>`)
+	}()
+	return fn(s)
+}
+
+// httpClient builds a client with its own cookie jar for the session cookie
+func (e *RealESXiClient) httpClient(target HypervisorTarget) (*http.Client, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	if e.HTTPClient != nil {
+		client := *e.HTTPClient
+		client.Jar = jar
+		return &client, nil
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         target.TLS.ServerName,
+		InsecureSkipVerify: target.TLS.InsecureSkipVerify, //nolint:gosec
+	}
+	if len(target.TLS.CABundle) > 0 {
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(target.TLS.CABundle) {
+			return nil, fmt.Errorf("no PEM certificates found in the ESXi CA bundle")
+		}
+	}
+	return &http.Client{
+		Timeout:   30 * time.Second,
+		Jar:       jar,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}, nil
+}
+
+// esxiURL returns the SOAP endpoint of a host address with or without a
+// scheme
+func esxiURL(address string) string {
+	address = strings.TrimSuffix(address, "/")
+	if !strings.Contains(address, "://") {
+		address = "https://" + address
+	}
+	return address + "/sdk"
+}
+
+type esxiSession struct {
+	client *http.Client
+	url    string
+	retry  *RetryPolicy
+}
+
+func (s *esxiSession) login(username string, password string) error {
+	_, err := s.call(`<Login xmlns="urn:vim25">` + esxiSessionManager +
+		`<userName>` + xmlEscape(username) + `</userName><password>` + xmlEscape(password) + `</password></Login>`)
+	return err
+}
+
+// startTask calls a method returning a task and returns the task's ID
+func (s *esxiSession) startTask(body string) (string, error) {
+	resp, err := s.call(body)
+	if err != nil {
+		return "", err
+	}
+	var result struct {
+		Returnval string `xml:"returnval"`
+	}
+	if err := xml.Unmarshal(resp, &result); err != nil || result.Returnval == "" {
+		return "", fmt.Errorf("unable to parse ESXi task: %v", err)
+	}
+	return result.Returnval, nil
+}
+
+// waitTask polls a task until it succeeds, fails or the timeout passes
+func (s *esxiSession) waitTask(task string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		props, err := s.properties("Task", task, "info.state", "info.error")
+		if err != nil {
+			return err
+		}
+		switch props["info.state"] {
+		case "success":
+			return nil
+		case "error":
+			var fault struct {
+				LocalizedMessage string `xml:"localizedMessage"`
+			}
+			_ = xml.Unmarshal([]byte("<fault>"+props["info.error"]+"</fault>"), &fault)
+			return fmt.Errorf("ESXi task %s failed: %s", task, fault.LocalizedMessage)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("ESXi task %s did not finish within %s", task, timeout)
+		}
+		time.Sleep(esxiTaskPollInterval)
+	}
+}
+
+func (s *esxiSession) inMaintenanceMode() (bool, error) {
+	props, err := s.properties("HostSystem", "ha-host", "runtime.inMaintenanceMode")
+	if err != nil {
+		return false, err
+	}
+	return props["runtime.inMaintenanceMode"] == "true", nil
+}
+
+// properties reads properties of a managed object, returning the inner XML
+// of each value
+func (s *esxiSession) properties(objType string, id string, paths ...string) (map[string]string, error) {
+	var spec strings.Builder
+	spec.WriteString(`<RetrievePropertiesEx xmlns="urn:vim25">` + esxiPropertyCollector + `<specSet><propSet><type>` + objType + `</type>`)
+	for _, path := range paths {
+		spec.WriteString(`<pathSet>` + path + `</pathSet>`)
+	}
+	spec.WriteString(`</propSet><objectSet><obj type="` + objType + `">` + xmlEscape(id) + `</obj></objectSet></specSet><options/></RetrievePropertiesEx>`)
+
+	resp, err := s.call(spec.String())
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		PropSet []struct {
+			Name string `xml:"name"`
+			Val  struct {
+				Inner string `xml:",innerxml"`
+			} `xml:"val"`
+		} `xml:"returnval>objects>propSet"`
+	}
+	if err := xml.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("unable to parse ESXi properties: %w", err)
+	}
+	props := map[string]string{}
+	for _, prop := range result.PropSet {
+		props[prop.Name] = strings.TrimSpace(prop.Val.Inner)
+	}
+	return props, nil
+}
+
+// call sends a SOAP request under the retry policy and returns the content
+// of the response body
+func (s *esxiSession) call(body string) ([]byte, error) {
+	var resp []byte
+	err := retry(context.Background(), s.retry, func() error {
+		var err error
+		resp, err = s.callOnce(body)
+		return err
+	})
+	return resp, err
+}
+
+func (s *esxiSession) callOnce(body string) ([]byte, error) {
+	envelope := `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Body>` +
+		body + `</soapenv:Body></soapenv:Envelope>`
+	req, err := http.NewRequest(http.MethodPost, s.url, strings.NewReader(envelope))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create ESXi request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
+	req.Header.Set("SOAPAction", "urn:vim25/6.0")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		var verifyErr *tls.CertificateVerificationError
+		if errors.As(err, &verifyErr) {
+			return nil, untrustedCertificate(fmt.Errorf("ESXi host certificate is not trusted: %w", err))
+		}
+		return nil, unreachable(fmt.Errorf("unable to reach ESXi host: %w", err))
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read ESXi response: %w", err)
+	}
+
+	var parsed struct {
+		Body struct {
+			Inner []byte `xml:",innerxml"`
+			Fault *struct {
+				String string `xml:"faultstring"`
+				Detail struct {
+					Inner string `xml:",innerxml"`
+				} `xml:"detail"`
+			} `xml:"Fault"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(respBody, &parsed); err != nil {
+		return nil, fmt.Errorf("ESXi request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	if fault := parsed.Body.Fault; fault != nil {
+		err := fmt.Errorf("ESXi request failed: %s", fault.String)
+		switch detail := fault.Detail.Inner; {
+		case strings.Contains(detail, "InvalidLogin"), strings.Contains(detail, "NoPermission"):
+			return nil, authFailed(err)
+		case strings.Contains(detail, "NotSupported"):
+			return nil, unsupported(err)
+		case strings.Contains(detail, "TaskInProgress"), strings.Contains(detail, "HostCommunication"):
+			return nil, transient(err)
+		}
+		return nil, permanent(err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, classifyStatus(resp.StatusCode, fmt.Errorf("ESXi request failed with status %d", resp.StatusCode))
+	}
+	return bytes.TrimSpace(parsed.Body.Inner), nil
+}
+
+func xmlEscape(value string) string {
+	var escaped strings.Builder
+	_ = xml.EscapeText(&escaped, []byte(value))
+	return escaped.String()
+}
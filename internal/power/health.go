@@ -0,0 +1,155 @@
+package power
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Health check methods of HealthCheck
+const (
+	HealthCheckICMP = "icmp"
+	HealthCheckTCP  = "tcp"
+	HealthCheckHTTP = "http"
+	HealthCheckSSH  = "ssh"
+)
+
+// defaultHealthCheckTimeout bounds a check without a timeout
+const defaultHealthCheckTimeout = 2 * time.Second
+
+// sshBanner starts the identification string SSH servers send first
+const sshBanner = "SSH-"
+
+// HealthCheck describes how a host is checked for being up
+type HealthCheck struct {
+	// Method is icmp, tcp, http or ssh. Empty pings.
+	Method string
+	// Port checked by tcp, http and ssh. Defaults to 80 for http and 22
+	// for ssh.
+	Port int
+	// Path requested by http, / if empty
+	Path string
+	// Timeout of one check, or of one echo request for icmp. 2s if 0.
+	Timeout time.Duration
+}
+
+// RealProberFactory pings hosts with a RealPinger, and connects to them for
+// the other methods
+type RealProberFactory struct {
+	// Retry is the policy of the pinger, DefaultRetryPolicy if nil
+	Retry *RetryPolicy
+}
+
+// Prober returns the Prober of the check
+func (f *RealProberFactory) Prober(check HealthCheck) Prober {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	switch check.Method {
+	case HealthCheckTCP:
+		return &connectProber{port: check.Port, timeout: timeout}
+	case HealthCheckSSH:
+		port := check.Port
+		if port == 0 {
+			port = 22
+		}
+		return &connectProber{port: port, timeout: timeout, banner: sshBanner}
+	case HealthCheckHTTP:
+		port := check.Port
+		if port == 0 {
+			port = 80
+		}
+		path := check.Path
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		return &httpProber{port: port, path: path, timeout: timeout}
+	}
+	return &RealPinger{Retry: f.Retry, Timeout: timeout}
+}
+
+// checkStats reports a single check as a probe
+func checkStats(ok bool, rtt time.Duration) PingStats {
+	if !ok {
+		return PingStats{Sent: 1}
+	}
+	return PingStats{Sent: 1, Received: 1, RTT: rtt}
+}
+
+// connectProber opens a TCP connection, and reads the banner the server
+// must send first if set
+type connectProber struct {
+	port    int
+	timeout time.Duration
+	banner  string
+}
+
+func (p *connectProber) Probe(ctx context.Context, address string) PingStats {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	started := time.Now()
+	ok := p.check(ctx, address)
+	return checkStats(ok, time.Since(started))
+}
+
+func (p *connectProber) check(ctx context.Context, address string) bool {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(address, strconv.Itoa(p.port)))
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+	if p.banner == "" {
+		return true
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetReadDeadline(deadline)
+	}
+	banner := make([]byte, len(p.banner))
+	if _, err := io.ReadFull(conn, banner); err != nil {
+		return false
+	}
+	return string(banner) == p.banner
+}
+
+// httpProber sends a GET request, which must be answered with a 2xx or 3xx
+// status. Redirects aren't followed.
+type httpProber struct {
+	port    int
+	path    string
+	timeout time.Duration
+}
+
+func (p *httpProber) Probe(ctx context.Context, address string) PingStats {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	started := time.Now()
+	ok := p.check(ctx, address)
+	return checkStats(ok, time.Since(started))
+}
+
+func (p *httpProber) check(ctx context.Context, address string) bool {
+	url := "http://" + net.JoinHostPort(address, strconv.Itoa(p.port)) + p.path
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false
+	}
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	return resp.StatusCode >= 200 && resp.StatusCode < 400
+}
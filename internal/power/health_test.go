@@ -0,0 +1,126 @@
+package power
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// listen accepts connections on a local port, greeting them with banner
+func listen(t *testing.T, banner string) (host string, port int) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = conn.Write([]byte(banner))
+			conn.Close()
+		}
+	}()
+	addr := listener.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port
+}
+
+// closedPort returns a local port nothing listens on
+func closedPort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	return port
+}
+
+func TestProberFactory(t *testing.T) {
+	factory := &RealProberFactory{}
+	if _, ok := factory.Prober(HealthCheck{}).(*RealPinger); !ok {
+		t.Error("checks without a method should ping")
+	}
+	pinger, ok := factory.Prober(HealthCheck{Method: HealthCheckICMP, Timeout: 500 * time.Millisecond}).(*RealPinger)
+	if !ok || pinger.Timeout != 500*time.Millisecond {
+		t.Errorf("icmp prober = %#v, want a pinger with the check's timeout", pinger)
+	}
+	if prober := factory.Prober(HealthCheck{Method: HealthCheckSSH}).(*connectProber); prober.port != 22 {
+		t.Errorf("ssh port = %d, want 22", prober.port)
+	}
+	if prober := factory.Prober(HealthCheck{Method: HealthCheckHTTP}).(*httpProber); prober.port != 80 || prober.path != "/" {
+		t.Errorf("http prober = %d %q, want 80 /", prober.port, prober.path)
+	}
+}
+
+func TestConnectProbers(t *testing.T) {
+	host, port := listen(t, "SSH-2.0-OpenSSH_9.6\r\n")
+	_, otherPort := listen(t, "220 smtp ready\r\n")
+	factory := &RealProberFactory{}
+
+	cases := []struct {
+		name  string
+		check HealthCheck
+		want  bool
+	}{
+		{name: "tcp open", check: HealthCheck{Method: HealthCheckTCP, Port: port}, want: true},
+		{name: "tcp closed", check: HealthCheck{Method: HealthCheckTCP, Port: closedPort(t)}},
+		{name: "ssh banner", check: HealthCheck{Method: HealthCheckSSH, Port: port}, want: true},
+		{name: "ssh wrong banner", check: HealthCheck{Method: HealthCheckSSH, Port: otherPort}},
+		{name: "ssh closed", check: HealthCheck{Method: HealthCheckSSH, Port: closedPort(t)}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stats := factory.Prober(tc.check).Probe(context.Background(), host)
+			if stats.Sent != 1 || stats.Reachable() != tc.want {
+				t.Errorf("Probe() = %+v, want reachable %v", stats, tc.want)
+			}
+		})
+	}
+}
+
+func TestHTTPProber(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/healthz":
+			w.WriteHeader(http.StatusNoContent)
+		case "/login":
+			http.Redirect(w, r, "/healthz", http.StatusFound)
+		case "/slow":
+			time.Sleep(time.Second)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	host, portString, _ := net.SplitHostPort(server.Listener.Addr().String())
+	port, _ := strconv.Atoi(portString)
+	factory := &RealProberFactory{}
+
+	cases := []struct {
+		path string
+		want bool
+	}{
+		{path: "/healthz", want: true},
+		{path: "/login", want: true},
+		{path: "/missing"},
+		{path: "/slow"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.path, func(t *testing.T) {
+			check := HealthCheck{Method: HealthCheckHTTP, Port: port, Path: tc.path, Timeout: 200 * time.Millisecond}
+			stats := factory.Prober(check).Probe(context.Background(), host)
+			if stats.Reachable() != tc.want {
+				t.Errorf("Probe() = %+v, want reachable %v", stats, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package power
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// hetznerEndpoint is the Hetzner Robot webservice
+const hetznerEndpoint = "https://robot-ws.your-server.de"
+
+type RealHetznerClient struct {
+	// Endpoint overrides the Robot webservice URL
+	Endpoint   string
+	HTTPClient *http.Client
+	// Retry is the policy for retrying failed calls, DefaultRetryPolicy
+	// if nil
+	Retry *RetryPolicy
+}
+
+func (h *RealHetznerClient) Wake(username string, password string, serverNumber int32) error {
+	_, err := h.post(username, password, "/wol/"+strconv.Itoa(int(serverNumber)), url.Values{})
+	return err
+}
+
+func (h *RealHetznerClient) Reset(username string, password string, serverNumber int32, resetType string) error {
+	_, err := h.post(username, password, "/reset/"+strconv.Itoa(int(serverNumber)), url.Values{"type": {resetType}})
+	return err
+}
+
+// EnableRescue activates the rescue system. A rescue system that is already
+// active is left as it is.
+func (h *RealHetznerClient) EnableRescue(username string, password string, serverNumber int32, os string, authorizedKeys []string) error {
+	params := url.Values{"os": {os}}
+	for _, key := range authorizedKeys {
+		params.Add("authorized_key[]", key)
+	}
+	_, err := h.post(username, password, "/boot/"+strconv.Itoa(int(serverNumber))+"/rescue", params)
+	if robotErrorCode(err) == "BOOT_ALREADY_ENABLED" {
+		return nil
+	}
+	return err
+}
+
+// robotError is an error response of the Robot webservice
+type robotError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *robotError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("Hetzner Robot request failed with status %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("Hetzner Robot request failed with status %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+func robotErrorCode(err error) string {
+	var robotErr *robotError
+	if errors.As(err, &robotErr) {
+		return robotErr.Code
+	}
+	return ""
+}
+
+// post sends a form to a path of the webservice under the retry policy
+func (h *RealHetznerClient) post(username string, password string, path string, params url.Values) ([]byte, error) {
+	var respBody []byte
+	err := retry(context.Background(), h.Retry, func() error {
+		var err error
+		respBody, err = h.postOnce(username, password, path, params)
+		return err
+	})
+	return respBody, err
+}
+
+// postOnce sends a form to a path of the webservice with basic auth
+func (h *RealHetznerClient) postOnce(username string, password string, path string, params url.Values) ([]byte, error) {
+	if username == "" || password == "" {
+		return nil, fmt.Errorf("Hetzner Robot username and password are required")
+	}
+	endpoint := h.Endpoint
+	if endpoint == "" {
+		endpoint = hetznerEndpoint
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(endpoint, "/")+path, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create Hetzner Robot request: %w", err)
+	}
+	req.SetBasicAuth(username, password)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	httpClient := h.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, unreachable(fmt.Errorf("unable to reach Hetzner Robot: %w", err))
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read Hetzner Robot response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var body struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		robotErr := &robotError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(respBody))}
+		if json.Unmarshal(respBody, &body) == nil && body.Error.Code != "" {
+			robotErr.Code, robotErr.Message = body.Error.Code, body.Error.Message
+		}
+		return nil, classifyStatus(resp.StatusCode, robotErr)
+	}
+
+	return respBody, nil
+}
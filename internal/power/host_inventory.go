@@ -0,0 +1,222 @@
+package power
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Commands the host inventory is read with over SSH. PCI devices are read
+// from sysfs, since lspci isn't installed everywhere.
+const (
+	lscpuCommand   = "lscpu -J"
+	lsblkCommand   = "lsblk -J -b -d -o NAME,MODEL,SIZE,ROTA,TYPE"
+	ipLinkCommand  = "ip -j -d link show"
+	meminfoCommand = "cat /proc/meminfo"
+	pciCommand     = `for d in /sys/bus/pci/devices/*; do echo "$(cat $d/class) $(cat $d/vendor)"; done`
+)
+
+// gpuVendors are the PCI vendors whose display controllers are counted as
+// GPUs, which leaves out the VGA controllers of BMCs
+var gpuVendors = map[string]bool{
+	"0x10de": true, // NVIDIA
+	"0x1002": true, // AMD
+}
+
+// GetHostInventory reads the hardware of the host from its OS
+func (s *RealSSHClient) GetHostInventory(ctx context.Context, host string, user string, key string) (HostInventory, error) {
+	var inventory HostInventory
+	steps := []struct {
+		cmd   string
+		parse func([]byte, *HostInventory) error
+	}{
+		{lscpuCommand, parseLscpu},
+		{lsblkCommand, parseLsblk},
+		{ipLinkCommand, parseIPLink},
+		{meminfoCommand, parseMeminfo},
+		{pciCommand, parsePCIClasses},
+	}
+	for _, step := range steps {
+		output, err := s.output(ctx, host, user, key, step.cmd)
+		if err != nil {
+			return HostInventory{}, err
+		}
+		if err := step.parse(output, &inventory); err != nil {
+			return HostInventory{}, err
+		}
+	}
+	return inventory, nil
+}
+
+// output runs a command and returns what it wrote to stdout
+func (s *RealSSHClient) output(ctx context.Context, host string, user string, key string, cmd string) ([]byte, error) {
+	session, err := s.session(ctx, host, user, key)
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	if err := runSession(ctx, session, cmd); err != nil {
+		return nil, fmt.Errorf("unable to run %q: %w: %s", cmd, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+type lscpuField struct {
+	Field    string       `json:"field"`
+	Data     string       `json:"data"`
+	Children []lscpuField `json:"children"`
+}
+
+// parseLscpu reads the CPU model, cores and threads from lscpu -J, whose
+// fields are nested in newer versions
+func parseLscpu(data []byte, inventory *HostInventory) error {
+	var output struct {
+		Lscpu []lscpuField `json:"lscpu"`
+	}
+	if err := json.Unmarshal(data, &output); err != nil {
+		return fmt.Errorf("unable to parse lscpu output: %w", err)
+	}
+	fields := map[string]string{}
+	var collect func([]lscpuField)
+	collect = func(list []lscpuField) {
+		for _, f := range list {
+			fields[strings.TrimSuffix(f.Field, ":")] = strings.TrimSpace(f.Data)
+			collect(f.Children)
+		}
+	}
+	collect(output.Lscpu)
+
+	atoi := func(field string) int32 {
+		n, _ := strconv.Atoi(fields[field])
+		return int32(n)
+	}
+	inventory.CPUModel = fields["Model name"]
+	inventory.CPUThreads = atoi("CPU(s)")
+	sockets := atoi("Socket(s)")
+	if sockets == 0 {
+		sockets = 1
+	}
+	inventory.CPUCores = atoi("Core(s) per socket") * sockets
+	return nil
+}
+
+// lsblkInt is a number lsblk prints as a number or, in older versions, as a
+// string
+type lsblkInt int64
+
+func (n *lsblkInt) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" || s == "" {
+		*n = 0
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	*n = lsblkInt(v)
+	return err
+}
+
+// lsblkBool is a flag lsblk prints as a boolean or, in older versions, as
+// "0" or "1"
+type lsblkBool bool
+
+func (b *lsblkBool) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	*b = s == "true" || s == "1"
+	return nil
+}
+
+// parseLsblk reads the disks from lsblk -J -b -d
+func parseLsblk(data []byte, inventory *HostInventory) error {
+	var output struct {
+		BlockDevices []struct {
+			Name  string    `json:"name"`
+			Model string    `json:"model"`
+			Size  lsblkInt  `json:"size"`
+			Rota  lsblkBool `json:"rota"`
+			Type  string    `json:"type"`
+		} `json:"blockdevices"`
+	}
+	if err := json.Unmarshal(data, &output); err != nil {
+		return fmt.Errorf("unable to parse lsblk output: %w", err)
+	}
+	for _, device := range output.BlockDevices {
+		if device.Type != "disk" {
+			continue
+		}
+		inventory.Disks = append(inventory.Disks, Disk{
+			Name:       device.Name,
+			Model:      strings.TrimSpace(device.Model),
+			SizeBytes:  int64(device.Size),
+			Rotational: bool(device.Rota),
+		})
+	}
+	return nil
+}
+
+// parseIPLink reads the Ethernet interfaces from ip -j -d link show.
+// Bridges, bonds, VLANs and other virtual links have a link kind and are
+// left out.
+func parseIPLink(data []byte, inventory *HostInventory) error {
+	var links []struct {
+		Name     string `json:"ifname"`
+		LinkType string `json:"link_type"`
+		Address  string `json:"address"`
+		LinkInfo *struct {
+			Kind string `json:"info_kind"`
+		} `json:"linkinfo"`
+	}
+	if err := json.Unmarshal(data, &links); err != nil {
+		return fmt.Errorf("unable to parse ip link output: %w", err)
+	}
+	for _, link := range links {
+		if link.LinkType != "ether" || link.LinkInfo != nil && link.LinkInfo.Kind != "" {
+			continue
+		}
+		inventory.NICs = append(inventory.NICs, NIC{Name: link.Name, MACAddress: link.Address})
+	}
+	return nil
+}
+
+// parseMeminfo reads the system memory from /proc/meminfo
+func parseMeminfo(data []byte, inventory *HostInventory) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+		kib, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("unable to parse MemTotal %q: %w", fields[1], err)
+		}
+		inventory.MemoryBytes = kib << 10
+		return nil
+	}
+	return fmt.Errorf("no MemTotal in /proc/meminfo")
+}
+
+// parsePCIClasses counts the display controllers of GPU vendors among the
+// class and vendor of each PCI device
+func parsePCIClasses(data []byte, inventory *HostInventory) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		class, vendor := fields[0], fields[1]
+		// Base class 0x03 is display controllers
+		if strings.HasPrefix(class, "0x03") && gpuVendors[vendor] {
+			inventory.GPUs++
+		}
+	}
+	return nil
+}
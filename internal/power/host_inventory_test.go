@@ -0,0 +1,75 @@
+package power
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseHostInventory(t *testing.T) {
+	lscpu := `{"lscpu": [
+  {"field": "Architecture:", "data": "x86_64"},
+  {"field": "CPU(s):", "data": "64"},
+  {"field": "Vendor ID:", "data": "AuthenticAMD", "children": [
+    {"field": "Model name:", "data": "AMD EPYC 7543 32-Core Processor", "children": [
+      {"field": "Thread(s) per core:", "data": "2"},
+      {"field": "Core(s) per socket:", "data": "16"},
+      {"field": "Socket(s):", "data": "2"}
+    ]}
+  ]}
+]}`
+	lsblk := `{"blockdevices": [
+  {"name": "sda", "model": "ST4000NM0035-1V4 ", "size": 4000787030016, "rota": true, "type": "disk"},
+  {"name": "nvme0n1", "model": "SAMSUNG MZQL2960HCJR", "size": "960197124096", "rota": "0", "type": "disk"},
+  {"name": "sr0", "model": "Virtual CDROM", "size": 1073741312, "rota": true, "type": "rom"}
+]}`
+	ipLink := `[
+  {"ifname": "lo", "link_type": "loopback", "address": "00:00:00:00:00:00"},
+  {"ifname": "eno1", "link_type": "ether", "address": "3c:ec:ef:01:02:03"},
+  {"ifname": "eno2", "link_type": "ether", "address": "3c:ec:ef:01:02:04", "linkinfo": {"info_slave_kind": "bond"}},
+  {"ifname": "bond0", "link_type": "ether", "address": "3c:ec:ef:01:02:04", "linkinfo": {"info_kind": "bond"}}
+]`
+	meminfo := "MemTotal:       263855932 kB\nMemFree:        250000000 kB\n"
+	pci := "0x060000 0x1022\n0x030000 0x1a03\n0x030200 0x10de\n0x030200 0x10de\n0x020000 0x8086\n"
+
+	var inventory HostInventory
+	for name, step := range map[string]struct {
+		parse func([]byte, *HostInventory) error
+		data  string
+	}{
+		"lscpu":   {parseLscpu, lscpu},
+		"lsblk":   {parseLsblk, lsblk},
+		"ip link": {parseIPLink, ipLink},
+		"meminfo": {parseMeminfo, meminfo},
+		"pci":     {parsePCIClasses, pci},
+	} {
+		if err := step.parse([]byte(step.data), &inventory); err != nil {
+			t.Fatalf("parsing %s: %v", name, err)
+		}
+	}
+
+	want := HostInventory{
+		CPUModel:    "AMD EPYC 7543 32-Core Processor",
+		CPUCores:    32,
+		CPUThreads:  64,
+		MemoryBytes: 263855932 << 10,
+		Disks: []Disk{
+			{Name: "sda", Model: "ST4000NM0035-1V4", SizeBytes: 4000787030016, Rotational: true},
+			{Name: "nvme0n1", Model: "SAMSUNG MZQL2960HCJR", SizeBytes: 960197124096},
+		},
+		NICs: []NIC{
+			{Name: "eno1", MACAddress: "3c:ec:ef:01:02:03"},
+			{Name: "eno2", MACAddress: "3c:ec:ef:01:02:04"},
+		},
+		GPUs: 2,
+	}
+	if !reflect.DeepEqual(inventory, want) {
+		t.Errorf("inventory = %+v, want %+v", inventory, want)
+	}
+}
+
+func TestParseMeminfoWithoutTotal(t *testing.T) {
+	var inventory HostInventory
+	if err := parseMeminfo([]byte("MemFree: 100 kB\n"), &inventory); err == nil {
+		t.Error("expected an error without MemTotal")
+	}
+}
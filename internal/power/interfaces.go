@@ -1,23 +1,330 @@
 package power
 
+import (
+	"context"
+	"io"
+	"time"
+)
+
 // WolSender sends Wake-on-LAN magic packets
 type WolSender interface {
-	Wake(macAddress string, port int, broadcastAddress string) error
+	Wake(ctx context.Context, macAddress string, port int, broadcastAddress string) error
+}
+
+// WolRelay sends magic packets and pings hosts through a relay agent on the
+// host's subnet, for hosts behind a router broadcasts don't cross
+type WolRelay interface {
+	Wake(ctx context.Context, relay string, macAddress string, port int, broadcastAddress string) error
+	IsReachable(ctx context.Context, relay string, address string) bool
+}
+
+// LLDPNeighbor is a switch port seen on one of the host's interfaces
+type LLDPNeighbor struct {
+	Interface       string
+	ChassisID       string
+	SystemName      string
+	PortID          string
+	PortDescription string
+	VLAN            string
 }
 
 // SSHClient executes commands over SSH
 type SSHClient interface {
-	Shutdown(host string, user string, key string) error
+	Shutdown(ctx context.Context, host string, user string, key string) error
+	Reboot(ctx context.Context, host string, user string, key string) error
+	// GetHostInventory reads the CPUs, memory, disks, NICs and GPUs from
+	// the running OS
+	GetHostInventory(ctx context.Context, host string, user string, key string) (HostInventory, error)
+	GetLLDPNeighbors(ctx context.Context, host string, user string, key string) ([]LLDPNeighbor, error)
+}
+
+// Inventory is the hardware and firmware information reported by a BMC
+type Inventory struct {
+	Manufacturer       string
+	Model              string
+	SerialNumber       string
+	BIOSVersion        string
+	BMCFirmwareVersion string
+	// CPUs is the number of logical processors, 0 if the BMC doesn't say
+	CPUs int32
+	// MemoryGiB is the installed system memory, 0 if the BMC doesn't say
+	MemoryGiB float64
+}
+
+// HostInventory is the hardware the host's OS or its Redfish service sees,
+// in more detail than the BMC's FRU data
+type HostInventory struct {
+	CPUModel string
+	// CPUCores is the number of physical cores of all sockets
+	CPUCores int32
+	// CPUThreads is the number of logical processors
+	CPUThreads int32
+	// MemoryBytes is the system memory, 0 if unknown
+	MemoryBytes int64
+	Disks       []Disk
+	NICs        []NIC
+	// GPUs is the number of GPUs
+	GPUs int32
+}
+
+// Disk is a physical disk of a host
+type Disk struct {
+	Name       string
+	Model      string
+	SizeBytes  int64
+	Rotational bool
+}
+
+// NIC is a physical network interface of a host
+type NIC struct {
+	Name       string
+	MACAddress string
+}
+
+// PowerLimit is the power draw and the power limit reported by a BMC
+type PowerLimit struct {
+	// ConsumedWatts is the current power draw
+	ConsumedWatts int32
+	// LimitWatts is the active power limit, 0 if none is enforced
+	LimitWatts int32
 }
 
+// Temperature is a temperature sensor reading
+type Temperature struct {
+	Name    string
+	Celsius float64
+	// CriticalCelsius is the upper critical threshold, 0 if the BMC doesn't
+	// report one
+	CriticalCelsius float64
+}
+
+// Boot devices that can be forced through the BMC
+const (
+	BootDevicePXE   = "pxe"
+	BootDeviceDisk  = "disk"
+	BootDeviceCDROM = "cdrom"
+	BootDeviceBIOS  = "bios"
+)
+
+// BootOverride is the boot device the BMC forces, if any
+type BootOverride struct {
+	// Device is one of the boot devices, or "" if the BMC doesn't force
+	// one or forces one the controller doesn't know
+	Device string
+	// Persistent overrides apply to every boot, not just the next one
+	Persistent bool
+}
+
+// Actions of the BMC watchdog on expiry
+const (
+	WatchdogActionReset      = "reset"
+	WatchdogActionPowerCycle = "power-cycle"
+	WatchdogActionPowerOff   = "power-off"
+)
+
 // IPMIClient controls servers via IPMI
 type IPMIClient interface {
-	PowerOn(address string, username string, password string) error
-	PowerOff(address string, username string, password string) error
-	GetPowerStatus(address string, username string, password string) (bool, error)
+	PowerOn(ctx context.Context, address string, username string, password string) error
+	PowerOff(ctx context.Context, address string, username string, password string) error
+	// PowerCycle powers the host off and on again, without shutting the OS
+	// down
+	PowerCycle(ctx context.Context, address string, username string, password string) error
+	GetPowerStatus(ctx context.Context, address string, username string, password string) (bool, error)
+	GetInventory(ctx context.Context, address string, username string, password string) (Inventory, error)
+	SetBootDevice(ctx context.Context, address string, username string, password string, device string, persistent bool) error
+	GetBootDevice(ctx context.Context, address string, username string, password string) (BootOverride, error)
+	GetPowerLimit(ctx context.Context, address string, username string, password string) (PowerLimit, error)
+	// SetPowerLimit activates a DCMI power limit, or deactivates it for 0
+	SetPowerLimit(ctx context.Context, address string, username string, password string, watts int32) error
+	GetTemperatures(ctx context.Context, address string, username string, password string) ([]Temperature, error)
+	// SetWatchdog arms the BMC watchdog with the timeout and action, or
+	// disarms it for a timeout of 0
+	SetWatchdog(ctx context.Context, address string, username string, password string, timeout time.Duration, action string) error
+	// ColdReset restarts the BMC, leaving the host running
+	ColdReset(ctx context.Context, address string, username string, password string) error
+}
+
+// MAASClient controls machines through a MAAS region controller
+type MAASClient interface {
+	PowerOn(endpoint string, apiKey string, systemID string) error
+	PowerOff(endpoint string, apiKey string, systemID string) error
+	GetPowerStatus(endpoint string, apiKey string, systemID string) (bool, error)
+	Deploy(endpoint string, apiKey string, systemID string, distroSeries string) error
+	Release(endpoint string, apiKey string, systemID string) error
+}
+
+// EquinixClient controls devices through the Equinix Metal API
+type EquinixClient interface {
+	PowerOn(token string, deviceID string) error
+	PowerOff(token string, deviceID string) error
+	GetPowerStatus(token string, deviceID string) (bool, error)
+	// FindDevice returns the ID of the device with the hostname in the
+	// project
+	FindDevice(token string, projectID string, hostname string) (string, error)
+}
+
+// HypervisorTarget identifies a standalone hypervisor host
+type HypervisorTarget struct {
+	Address  string
+	Username string
+	Password string
+	TLS      RedfishTLS
+}
+
+// HypervisorClient shuts down standalone hypervisor hosts through their
+// management API
+type HypervisorClient interface {
+	// EnterMaintenanceMode waits up to the timeout for the host to enter
+	// maintenance mode
+	EnterMaintenanceMode(target HypervisorTarget, timeout time.Duration) error
+	// ExitMaintenanceMode takes the host out of maintenance mode, if it is
+	// in it
+	ExitMaintenanceMode(target HypervisorTarget) error
+	// Shutdown shuts down a host in maintenance mode
+	Shutdown(target HypervisorTarget) error
+}
+
+// HetznerClient controls dedicated servers through the Hetzner Robot API
+type HetznerClient interface {
+	// Wake sends a Wake-on-LAN packet to the server
+	Wake(username string, password string, serverNumber int32) error
+	// Reset triggers a reset of the type, e.g. "power" to press the power
+	// button or "hw" for a hardware reset
+	Reset(username string, password string, serverNumber int32, resetType string) error
+	// EnableRescue boots the server into the rescue system once, at its
+	// next boot
+	EnableRescue(username string, password string, serverNumber int32, os string, authorizedKeys []string) error
+}
+
+// RedfishTarget identifies a system behind a Redfish BMC
+type RedfishTarget struct {
+	Address  string
+	Username string
+	Password string
+	SystemID string
+	TLS      RedfishTLS
+}
+
+// RedfishTLS configures verification of the BMC's HTTPS certificate
+type RedfishTLS struct {
+	// CABundle holds PEM certificates to verify against instead of the
+	// system roots
+	CABundle []byte
+	// ServerName is verified instead of the host of the address
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+// Volume is a logical volume on a storage controller
+type Volume struct {
+	ID            string
+	Name          string
+	RAIDType      string
+	CapacityBytes int64
+	Drives        []string
+}
+
+// RedfishClient controls servers via the DMTF Redfish API
+type RedfishClient interface {
+	PowerOn(target RedfishTarget) error
+	PowerOff(target RedfishTarget) error
+	GetPowerStatus(target RedfishTarget) (bool, error)
+	GetInventory(target RedfishTarget) (Inventory, error)
+	// GetHostInventory reads the processors, memory, drives and Ethernet
+	// interfaces of the system
+	GetHostInventory(target RedfishTarget) (HostInventory, error)
+	SetBootDevice(target RedfishTarget, device string, persistent bool) error
+	GetBootDevice(target RedfishTarget) (BootOverride, error)
+	GetPowerLimit(target RedfishTarget) (PowerLimit, error)
+	// SetPowerLimit sets the chassis power limit, or removes it for 0
+	SetPowerLimit(target RedfishTarget, watts int32) error
+	GetTemperatures(target RedfishTarget) ([]Temperature, error)
+	// SetWatchdog enables the host watchdog timer with the action, or
+	// disables it for an empty action
+	SetWatchdog(target RedfishTarget, action string) error
+	GetSerialConsole(target RedfishTarget) (SerialConsoleInfo, error)
+	ListVolumes(target RedfishTarget, storageID string) ([]Volume, error)
+	CreateVolume(target RedfishTarget, storageID string, volume Volume) error
+	DeleteVolume(target RedfishTarget, storageID string, volumeID string) error
+	// SubscribeMetricReports subscribes the destination URL to the metric
+	// reports of the TelemetryService and returns the URI of the
+	// subscription. The BMC echoes the context in every report, so the
+	// receiver can tell them from forged ones. An existing subscription of
+	// the destination is reused and gets the new context.
+	SubscribeMetricReports(target RedfishTarget, destination string, context string) (string, error)
+	// SubscribeEvents subscribes the destination URL to the events of the
+	// EventService like SubscribeMetricReports
+	SubscribeEvents(target RedfishTarget, destination string, context string) (string, error)
+	// Unsubscribe deletes an event subscription
+	Unsubscribe(target RedfishTarget, subscriptionURI string) error
+}
+
+// SerialConsoleInfo describes how to reach a system's serial console over SSH
+// to the BMC
+type SerialConsoleInfo struct {
+	Port int
+	// Command entered in the BMC CLI to attach to the console, if any
+	Command string
+}
+
+// SerialConsole opens a host's serial console through its BMC
+type SerialConsole interface {
+	OpenIPMI(address string, username string, password string) (io.ReadWriteCloser, error)
+	OpenSSH(address string, port int, username string, password string, command string) (io.ReadWriteCloser, error)
+}
+
+// TPMQuote is a TPM 2.0 quote and its signature by the attestation key
+type TPMQuote struct {
+	// Message is the marshalled TPMS_ATTEST structure
+	Message []byte
+	// Signature over Message, as written by tpm2_quote -f plain
+	Signature []byte
+}
+
+// Attestor requests TPM quotes from a running server
+type Attestor interface {
+	Quote(ctx context.Context, host string, user string, key string, akHandle string, pcrs []int, nonce []byte) (TPMQuote, error)
 }
 
 // Pinger checks if a host is reachable
 type Pinger interface {
-	IsReachable(address string) bool
+	IsReachable(ctx context.Context, address string) bool
+	// Probe sends a few echo requests and reports how many were answered
+	// and how quickly
+	Probe(ctx context.Context, address string) PingStats
+}
+
+// Prober checks whether a host is up. Pinger is the Prober of ICMP checks.
+type Prober interface {
+	// Probe checks the host and reports how many of its attempts were
+	// answered and how quickly
+	Probe(ctx context.Context, address string) PingStats
+}
+
+// ProberFactory builds the Prober of a health check
+type ProberFactory interface {
+	Prober(check HealthCheck) Prober
+}
+
+// PingStats are the echo requests of one probe
+type PingStats struct {
+	// Sent is the number of echo requests sent
+	Sent int
+	// Received is the number of echo replies
+	Received int
+	// RTT is the average round trip time of the replies
+	RTT time.Duration
+}
+
+// Reachable reports whether any echo request was answered
+func (s PingStats) Reachable() bool {
+	return s.Received > 0
+}
+
+// Loss returns the fraction of echo requests without a reply
+func (s PingStats) Loss() float64 {
+	if s.Sent == 0 {
+		return 0
+	}
+	return float64(s.Sent-s.Received) / float64(s.Sent)
 }
@@ -0,0 +1,330 @@
+package power
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RealIPMIClient controls servers with ipmitool over lanplus. It needs
+// ipmitool on the PATH.
+type RealIPMIClient struct {
+	// Retry is the policy for retrying failed calls, DefaultRetryPolicy
+	// if nil
+	Retry *RetryPolicy
+}
+
+func (c *RealIPMIClient) PowerOn(ctx context.Context, address string, username string, password string) error {
+	_, err := c.run(ctx, address, username, password, "chassis", "power", "on")
+	return err
+}
+
+func (c *RealIPMIClient) PowerOff(ctx context.Context, address string, username string, password string) error {
+	// soft asks the OS to shut down through ACPI, like Redfish GracefulShutdown
+	_, err := c.run(ctx, address, username, password, "chassis", "power", "soft")
+	return err
+}
+
+// PowerCycle sends "chassis power cycle", which BMCs reject for a host that
+// is off
+func (c *RealIPMIClient) PowerCycle(ctx context.Context, address string, username string, password string) error {
+	_, err := c.run(ctx, address, username, password, "chassis", "power", "cycle")
+	return err
+}
+
+func (c *RealIPMIClient) GetPowerStatus(ctx context.Context, address string, username string, password string) (bool, error) {
+	out, err := c.run(ctx, address, username, password, "chassis", "power", "status")
+	if err != nil {
+		return false, err
+	}
+	// "Chassis Power is on"
+	return strings.HasSuffix(strings.TrimSpace(out), " on"), nil
+}
+
+func (c *RealIPMIClient) GetInventory(ctx context.Context, address string, username string, password string) (Inventory, error) {
+	mc, err := c.run(ctx, address, username, password, "mc", "info")
+	if err != nil {
+		return Inventory{}, err
+	}
+	fru, err := c.run(ctx, address, username, password, "fru", "print", "0")
+	if err != nil {
+		return Inventory{}, err
+	}
+
+	mcFields := parseIPMIFields(mc)
+	fruFields := parseIPMIFields(fru)
+	inventory := Inventory{
+		Manufacturer:       firstNonEmpty(fruFields["Product Manufacturer"], fruFields["Board Mfg"]),
+		Model:              firstNonEmpty(fruFields["Product Name"], fruFields["Board Product"]),
+		SerialNumber:       firstNonEmpty(fruFields["Product Serial"], fruFields["Board Serial"]),
+		BMCFirmwareVersion: mcFields["Firmware Revision"],
+	}
+	// IPMI has no standard BIOS version field, but many vendors put it in
+	// the product version
+	inventory.BIOSVersion = fruFields["Product Version"]
+	return inventory, nil
+}
+
+func (c *RealIPMIClient) SetBootDevice(ctx context.Context, address string, username string, password string, device string, persistent bool) error {
+	switch device {
+	case BootDevicePXE, BootDeviceDisk, BootDeviceCDROM, BootDeviceBIOS:
+	default:
+		return unsupported(fmt.Errorf("unsupported boot device %q", device))
+	}
+
+	args := []string{"chassis", "bootdev", device}
+	if persistent {
+		args = append(args, "options=persistent")
+	}
+	_, err := c.run(ctx, address, username, password, args...)
+	return err
+}
+
+// ipmiBootSelectors maps the boot device selectors ipmitool prints to boot
+// devices
+var ipmiBootSelectors = map[string]string{
+	"Force PXE":                          BootDevicePXE,
+	"Force Boot from default Hard-Drive": BootDeviceDisk,
+	"Force Boot from CD/DVD":             BootDeviceCDROM,
+	"Force Boot into BIOS Setup":         BootDeviceBIOS,
+}
+
+// GetBootDevice reads the boot flags boot parameter
+func (c *RealIPMIClient) GetBootDevice(ctx context.Context, address string, username string, password string) (BootOverride, error) {
+	out, err := c.run(ctx, address, username, password, "chassis", "bootparam", "get", "5")
+	if err != nil {
+		return BootOverride{}, err
+	}
+	return parseIPMIBootFlags(out), nil
+}
+
+func (c *RealIPMIClient) GetPowerLimit(ctx context.Context, address string, username string, password string) (PowerLimit, error) {
+	reading, err := c.run(ctx, address, username, password, "dcmi", "power", "reading")
+	if err != nil {
+		return PowerLimit{}, err
+	}
+	consumed, err := parseWatts(parseIPMIFields(reading)["Instantaneous power reading"])
+	if err != nil {
+		return PowerLimit{}, fmt.Errorf("unexpected DCMI power reading: %w", err)
+	}
+
+	result := PowerLimit{ConsumedWatts: consumed}
+	limit, err := c.run(ctx, address, username, password, "dcmi", "power", "get_limit")
+	if err != nil {
+		// Some BMCs fail get_limit with "No Active Set Power Limit"
+		if strings.Contains(err.Error(), "No Active") {
+			return result, nil
+		}
+		return PowerLimit{}, err
+	}
+	fields := parseIPMIFields(limit)
+	if !strings.Contains(fields["Current Limit State"], "No Active") {
+		if result.LimitWatts, err = parseWatts(fields["Power Limit"]); err != nil {
+			return PowerLimit{}, fmt.Errorf("unexpected DCMI power limit: %w", err)
+		}
+	}
+	return result, nil
+}
+
+func (c *RealIPMIClient) SetPowerLimit(ctx context.Context, address string, username string, password string, watts int32) error {
+	if watts == 0 {
+		_, err := c.run(ctx, address, username, password, "dcmi", "power", "deactivate")
+		return err
+	}
+	if _, err := c.run(ctx, address, username, password, "dcmi", "power", "set_limit", "limit", strconv.Itoa(int(watts))); err != nil {
+		return err
+	}
+	_, err := c.run(ctx, address, username, password, "dcmi", "power", "activate")
+	return err
+}
+
+// ipmiWatchdogActions maps watchdog actions to the timeout action of the
+// Set Watchdog Timer command
+var ipmiWatchdogActions = map[string]byte{
+	WatchdogActionReset:      0x01,
+	WatchdogActionPowerOff:   0x02,
+	WatchdogActionPowerCycle: 0x03,
+}
+
+// SetWatchdog programs the watchdog for the SMS/OS timer use, which the OS
+// watchdog driver takes over, and starts it. The countdown is in 100ms
+// units, so the timeout is at most 6553 seconds.
+func (c *RealIPMIClient) SetWatchdog(ctx context.Context, address string, username string, password string, timeout time.Duration, action string) error {
+	if timeout == 0 {
+		_, err := c.run(ctx, address, username, password, "mc", "watchdog", "off")
+		return err
+	}
+	timeoutAction, ok := ipmiWatchdogActions[action]
+	if !ok {
+		return unsupported(fmt.Errorf("unsupported watchdog action %q", action))
+	}
+	countdown := int64(timeout / (100 * time.Millisecond))
+	if countdown < 1 || countdown > 0xffff {
+		return unsupported(fmt.Errorf("watchdog timeout %s is out of range", timeout))
+	}
+
+	// Set Watchdog Timer: SMS/OS use without stopping a running timer, the
+	// timeout action, no pre-timeout, clear the SMS/OS expiration flag and
+	// the countdown LSB first
+	args := []string{"raw", "0x06", "0x24", "0x44", fmt.Sprintf("0x%02x", timeoutAction), "0x00", "0x10",
+		fmt.Sprintf("0x%02x", countdown&0xff), fmt.Sprintf("0x%02x", countdown>>8)}
+	if _, err := c.run(ctx, address, username, password, args...); err != nil {
+		return err
+	}
+	_, err := c.run(ctx, address, username, password, "mc", "watchdog", "reset")
+	return err
+}
+
+// ColdReset sends "mc reset cold". BMCs may restart before answering, so an
+// unreachable BMC counts as sent, and the command is never retried.
+func (c *RealIPMIClient) ColdReset(ctx context.Context, address string, username string, password string) error {
+	_, err := c.exec(ctx, address, username, password, "mc", "reset", "cold")
+	if errors.Is(err, ErrUnreachable) {
+		return nil
+	}
+	return err
+}
+
+// GetTemperatures reads the temperature sensors and their upper critical
+// thresholds from the sensor table
+func (c *RealIPMIClient) GetTemperatures(ctx context.Context, address string, username string, password string) ([]Temperature, error) {
+	out, err := c.run(ctx, address, username, password, "sensor")
+	if err != nil {
+		return nil, err
+	}
+	return parseIPMITemperatures(out), nil
+}
+
+// run executes an ipmitool command under the retry policy
+func (c *RealIPMIClient) run(ctx context.Context, address string, username string, password string, args ...string) (string, error) {
+	var out string
+	err := retry(ctx, c.Retry, func() error {
+		var err error
+		out, err = c.exec(ctx, address, username, password, args...)
+		return err
+	})
+	return out, err
+}
+
+// exec executes an ipmitool command once. The password is passed in the
+// environment so it doesn't show up in ps.
+func (c *RealIPMIClient) exec(ctx context.Context, address string, username string, password string, args ...string) (string, error) {
+	if address == "" {
+		return "", fmt.Errorf("IPMI address is required")
+	}
+
+	cmd := exec.CommandContext(ctx, "ipmitool", append([]string{"-I", "lanplus", "-H", address, "-U", username, "-E"}, args...)...)
+	cmd.Env = append(os.Environ(), "IPMI_PASSWORD="+password)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		err = fmt.Errorf("ipmitool %s failed: %w: %s", strings.Join(args, " "), err, msg)
+		switch {
+		case strings.Contains(msg, "RAKP"), strings.Contains(msg, "invalid user name"), strings.Contains(msg, "Unauthorized name"):
+			return "", authFailed(err)
+		case strings.Contains(msg, "Unable to establish"), strings.Contains(msg, "No response"):
+			return "", unreachable(err)
+		case strings.Contains(msg, "Node busy"), strings.Contains(msg, "Out of space"), ctx.Err() != nil:
+			return "", transient(err)
+		case strings.Contains(msg, "Invalid command"), strings.Contains(msg, "not supported"):
+			return "", unsupported(err)
+		}
+		return "", permanent(err)
+	}
+	return stdout.String(), nil
+}
+
+// parseIPMIFields parses "Key : Value" lines as printed by ipmitool
+func parseIPMIFields(out string) map[string]string {
+	fields := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if _, exists := fields[key]; !exists {
+			fields[key] = strings.TrimSpace(value)
+		}
+	}
+	return fields
+}
+
+// parseIPMIBootFlags parses the flags ipmitool prints for boot parameter 5,
+// like "- Options apply to all future boots" and "- Boot Device Selector :
+// Force PXE". Invalid flags force no device.
+func parseIPMIBootFlags(out string) BootOverride {
+	var override BootOverride
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "-"))
+		switch {
+		case line == "Boot Flag Invalid":
+			return BootOverride{}
+		case line == "Options apply to all future boots":
+			override.Persistent = true
+		case strings.HasPrefix(line, "Boot Device Selector"):
+			_, selector, _ := strings.Cut(line, ":")
+			override.Device = ipmiBootSelectors[strings.TrimSpace(selector)]
+		}
+	}
+	return override
+}
+
+// parseIPMITemperatures parses ipmitool sensor lines like
+// "Inlet Temp | 22.000 | degrees C | ok | na | na | na | 42.000 | 47.000 | na",
+// whose columns are name, reading, unit, status and the lower
+// non-recoverable, lower critical, lower non-critical, upper non-critical,
+// upper critical and upper non-recoverable thresholds. Sensors without a
+// reading are skipped.
+func parseIPMITemperatures(out string) []Temperature {
+	var temperatures []Temperature
+	for _, line := range strings.Split(out, "\n") {
+		columns := strings.Split(line, "|")
+		if len(columns) < 3 || strings.TrimSpace(columns[2]) != "degrees C" {
+			continue
+		}
+		celsius, err := strconv.ParseFloat(strings.TrimSpace(columns[1]), 64)
+		if err != nil {
+			continue
+		}
+		temperature := Temperature{Name: strings.TrimSpace(columns[0]), Celsius: celsius}
+		if len(columns) > 8 {
+			if critical, err := strconv.ParseFloat(strings.TrimSpace(columns[8]), 64); err == nil {
+				temperature.CriticalCelsius = critical
+			}
+		}
+		temperatures = append(temperatures, temperature)
+	}
+	return temperatures
+}
+
+// parseWatts parses a value like "215 Watts" as printed by ipmitool dcmi
+func parseWatts(value string) (int32, error) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("no value")
+	}
+	watts, err := strconv.ParseInt(fields[0], 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+	return int32(watts), nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,247 @@
+package power
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeIPMITool puts an ipmitool on the PATH that answers from canned output
+// by BMC address and logs its arguments and password, one call per line
+func fakeIPMITool(t *testing.T) (logFile string) {
+	t.Helper()
+	dir := t.TempDir()
+	logFile = filepath.Join(dir, "calls.log")
+	script := `#!/bin/sh
+# -I lanplus -H <address> -U <user> -E <command...>
+address=$4
+shift 7
+echo "$IPMI_PASSWORD $*" >> ` + logFile + `
+case "$address" in
+bad-auth) echo "Error: Unable to establish IPMI v2 / RMCP+ session: RAKP 2 message indicates an error" >&2; exit 1 ;;
+offline) echo "Error: Unable to establish IPMI v2 / RMCP+ session" >&2; exit 1 ;;
+busy) echo "Node busy" >&2; exit 1 ;;
+old) echo "Invalid command" >&2; exit 1 ;;
+resetting) echo "No response from remote controller" >&2; exit 1 ;;
+esac
+case "$*" in
+"chassis power status") echo "Chassis Power is on" ;;
+"mc info") printf 'Device ID                 : 32\nFirmware Revision         : 2.81\n' ;;
+"fru print 0") printf ' Board Mfg             : Supermicro\n Product Name          : SYS-1019P\n Product Serial        : S123\n Product Version       : 3.4\n' ;;
+"chassis bootparam get 5") printf 'Boot parameter data: e008000000\n Boot Flags :\n   - Boot Flag Valid\n   - Options apply to all future boots\n   - Boot Device Selector : Force PXE\n' ;;
+"dcmi power reading") echo "    Instantaneous power reading:                   215 Watts" ;;
+"dcmi power get_limit") printf '    Current Limit State: Power Limit Active\n    Power Limit:         400 Watts\n' ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, "ipmitool"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return logFile
+}
+
+func ipmiCalls(t *testing.T, logFile string) []string {
+	t.Helper()
+	data, err := os.ReadFile(logFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestRealIPMIClient(t *testing.T) {
+	logFile := fakeIPMITool(t)
+	c := &RealIPMIClient{Retry: &RetryPolicy{Attempts: 1}}
+	ctx := context.Background()
+
+	on, err := c.GetPowerStatus(ctx, "bmc-01", "admin", "secret")
+	if err != nil || !on {
+		t.Errorf("GetPowerStatus() = %v, %v, want on", on, err)
+	}
+	inventory, err := c.GetInventory(ctx, "bmc-01", "admin", "secret")
+	if err != nil {
+		t.Fatalf("GetInventory() error = %v", err)
+	}
+	want := Inventory{Manufacturer: "Supermicro", Model: "SYS-1019P", SerialNumber: "S123", BIOSVersion: "3.4", BMCFirmwareVersion: "2.81"}
+	if inventory != want {
+		t.Errorf("GetInventory() = %+v, want %+v", inventory, want)
+	}
+	boot, err := c.GetBootDevice(ctx, "bmc-01", "admin", "secret")
+	if err != nil || boot != (BootOverride{Device: BootDevicePXE, Persistent: true}) {
+		t.Errorf("GetBootDevice() = %+v, %v, want persistent PXE", boot, err)
+	}
+	limit, err := c.GetPowerLimit(ctx, "bmc-01", "admin", "secret")
+	if err != nil || limit != (PowerLimit{ConsumedWatts: 215, LimitWatts: 400}) {
+		t.Errorf("GetPowerLimit() = %+v, %v", limit, err)
+	}
+
+	if err := c.PowerOff(ctx, "bmc-01", "admin", "secret"); err != nil {
+		t.Fatalf("PowerOff() error = %v", err)
+	}
+	if err := c.SetBootDevice(ctx, "bmc-01", "admin", "secret", BootDeviceDisk, true); err != nil {
+		t.Fatalf("SetBootDevice() error = %v", err)
+	}
+	if err := c.SetWatchdog(ctx, "bmc-01", "admin", "secret", 5*time.Minute, WatchdogActionPowerCycle); err != nil {
+		t.Fatalf("SetWatchdog() error = %v", err)
+	}
+	calls := ipmiCalls(t, logFile)
+	wantCalls := []string{
+		"secret chassis power status",
+		"secret mc info",
+		"secret fru print 0",
+		"secret chassis bootparam get 5",
+		"secret dcmi power reading",
+		"secret dcmi power get_limit",
+		// Graceful shutdown through ACPI
+		"secret chassis power soft",
+		"secret chassis bootdev disk options=persistent",
+		// 3000 deciseconds is 0x0bb8, least significant byte first
+		"secret raw 0x06 0x24 0x44 0x03 0x00 0x10 0xb8 0x0b",
+		"secret mc watchdog reset",
+	}
+	if !reflect.DeepEqual(calls, wantCalls) {
+		t.Errorf("ipmitool calls =\n%s\nwant\n%s", strings.Join(calls, "\n"), strings.Join(wantCalls, "\n"))
+	}
+}
+
+func TestRealIPMIClientErrors(t *testing.T) {
+	logFile := fakeIPMITool(t)
+	c := &RealIPMIClient{Retry: &RetryPolicy{Attempts: 1}}
+	ctx := context.Background()
+
+	tests := []struct {
+		address string
+		want    ErrorClass
+	}{
+		{address: "bad-auth", want: ClassAuthFailure},
+		{address: "offline", want: ClassUnreachable},
+		{address: "busy", want: ClassTransient},
+		{address: "old", want: ClassUnsupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := c.PowerOn(ctx, tt.address, "admin", "secret")
+			if err == nil {
+				t.Fatalf("PowerOn() succeeded")
+			}
+			if got := Classify(err); got != tt.want {
+				t.Errorf("Classify(%v) = %s, want %s", err, got, tt.want)
+			}
+		})
+	}
+
+	if err := c.PowerOn(ctx, "", "admin", "secret"); err == nil {
+		t.Errorf("PowerOn() succeeded without an address")
+	}
+	if err := c.SetBootDevice(ctx, "bmc-01", "admin", "secret", "floppy", false); Classify(err) != ClassUnsupported {
+		t.Errorf("SetBootDevice(floppy) error = %v, want unsupported", err)
+	}
+	if err := c.SetWatchdog(ctx, "bmc-01", "admin", "secret", 2*time.Hour, WatchdogActionReset); Classify(err) != ClassUnsupported {
+		t.Errorf("SetWatchdog(2h) error = %v, want unsupported", err)
+	}
+	if calls := ipmiCalls(t, logFile); len(calls) != len(tests) {
+		t.Errorf("ipmitool ran %d times, want once per reachable call: %v", len(calls), calls)
+	}
+}
+
+func TestRealIPMIClientColdReset(t *testing.T) {
+	logFile := fakeIPMITool(t)
+	c := &RealIPMIClient{}
+	ctx := context.Background()
+
+	// BMCs restart before answering a cold reset
+	if err := c.ColdReset(ctx, "resetting", "admin", "secret"); err != nil {
+		t.Errorf("ColdReset() error = %v for a BMC restarting without a response", err)
+	}
+	if err := c.ColdReset(ctx, "bad-auth", "admin", "secret"); Classify(err) != ClassAuthFailure {
+		t.Errorf("ColdReset() error = %v, want an authentication failure", err)
+	}
+	// A reset is never sent twice, even to a BMC that is busy
+	if err := c.ColdReset(ctx, "busy", "admin", "secret"); err == nil {
+		t.Errorf("ColdReset() succeeded for a busy BMC")
+	}
+	if calls := ipmiCalls(t, logFile); len(calls) != 3 {
+		t.Errorf("ipmitool calls = %v, want one per reset", calls)
+	}
+}
+
+func TestParseIPMIBootFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want BootOverride
+	}{
+		{
+			name: "persistent PXE",
+			out:  " Boot Flags :\n   - Boot Flag Valid\n   - Options apply to all future boots\n   - Boot Device Selector : Force PXE\n",
+			want: BootOverride{Device: BootDevicePXE, Persistent: true},
+		},
+		{
+			name: "next boot from disk",
+			out:  " Boot Flags :\n   - Boot Flag Valid\n   - Options apply to only next boot\n   - Boot Device Selector : Force Boot from default Hard-Drive\n",
+			want: BootOverride{Device: BootDeviceDisk},
+		},
+		{
+			name: "BIOS setup",
+			out:  "   - Boot Device Selector : Force Boot into BIOS Setup\n",
+			want: BootOverride{Device: BootDeviceBIOS},
+		},
+		{
+			name: "no override",
+			out:  "   - Boot Flag Valid\n   - Boot Device Selector : No override\n",
+		},
+		{
+			name: "invalid flags",
+			out:  "   - Boot Flag Invalid\n   - Options apply to all future boots\n   - Boot Device Selector : Force PXE\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseIPMIBootFlags(tt.out); got != tt.want {
+				t.Errorf("parseIPMIBootFlags() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseIPMITemperatures(t *testing.T) {
+	out := `CPU Temp         | 45.000     | degrees C  | ok    | 0.000     | 0.000     | 0.000     | 85.000    | 90.000    | 95.000
+Inlet Temp       | 22.000     | degrees C  | ok    | na        | na        | na        | 42.000    | na        | na
+FAN1             | 3500.000   | RPM        | ok    | 300.000   | 500.000   | 700.000   | 25300.000 | 25400.000 | 25500.000
+DIMM Temp        | na         | degrees C  | na    | na        | na        | na        | na        | na        | na
+`
+	want := []Temperature{
+		{Name: "CPU Temp", Celsius: 45, CriticalCelsius: 90},
+		{Name: "Inlet Temp", Celsius: 22},
+	}
+	if got := parseIPMITemperatures(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseIPMITemperatures() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseWatts(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int32
+		wantErr bool
+	}{
+		{value: "215 Watts", want: 215},
+		{value: "0 Watts", want: 0},
+		{value: "", wantErr: true},
+		{value: "n/a Watts", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseWatts(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseWatts(%q) = %d, %v, want %d", tt.value, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package power
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// GetLLDPNeighbors reads the neighbors seen by lldpd on the host
+func (s *RealSSHClient) GetLLDPNeighbors(ctx context.Context, host string, user string, key string) ([]LLDPNeighbor, error) {
+	session, err := s.session(ctx, host, user, key)
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	// json0 keeps the same structure regardless of the number of neighbors
+	if err := runSession(ctx, session, "lldpctl -f json0"); err != nil {
+		return nil, fmt.Errorf("unable to run lldpctl: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return parseLLDPCtl(stdout.Bytes())
+}
+
+type lldpValue struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+func firstValue(values []lldpValue) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0].Value
+}
+
+// parseLLDPCtl parses the output of lldpctl -f json0
+func parseLLDPCtl(data []byte) ([]LLDPNeighbor, error) {
+	var output struct {
+		LLDP []struct {
+			Interface []struct {
+				Name    string `json:"name"`
+				Chassis []struct {
+					ID   []lldpValue `json:"id"`
+					Name []lldpValue `json:"name"`
+				} `json:"chassis"`
+				Port []struct {
+					ID    []lldpValue `json:"id"`
+					Descr []lldpValue `json:"descr"`
+				} `json:"port"`
+				VLAN []struct {
+					VLANID string `json:"vlan-id"`
+				} `json:"vlan"`
+			} `json:"interface"`
+		} `json:"lldp"`
+	}
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, fmt.Errorf("unable to parse lldpctl output: %w", err)
+	}
+
+	var neighbors []LLDPNeighbor
+	for _, lldp := range output.LLDP {
+		for _, iface := range lldp.Interface {
+			neighbor := LLDPNeighbor{Interface: iface.Name}
+			if len(iface.Chassis) > 0 {
+				neighbor.ChassisID = firstValue(iface.Chassis[0].ID)
+				neighbor.SystemName = firstValue(iface.Chassis[0].Name)
+			}
+			if len(iface.Port) > 0 {
+				neighbor.PortID = firstValue(iface.Port[0].ID)
+				neighbor.PortDescription = firstValue(iface.Port[0].Descr)
+			}
+			if len(iface.VLAN) > 0 {
+				neighbor.VLAN = iface.VLAN[0].VLANID
+			}
+			neighbors = append(neighbors, neighbor)
+		}
+	}
+	return neighbors, nil
+}
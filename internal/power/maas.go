@@ -0,0 +1,143 @@
+package power
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type RealMAASClient struct {
+	HTTPClient *http.Client
+	// Retry is the policy for retrying failed calls, DefaultRetryPolicy
+	// if nil
+	Retry *RetryPolicy
+}
+
+func (m *RealMAASClient) PowerOn(endpoint string, apiKey string, systemID string) error {
+	_, err := m.machineOp(endpoint, apiKey, systemID, http.MethodPost, "power_on", nil)
+	return err
+}
+
+func (m *RealMAASClient) PowerOff(endpoint string, apiKey string, systemID string) error {
+	_, err := m.machineOp(endpoint, apiKey, systemID, http.MethodPost, "power_off", nil)
+	return err
+}
+
+func (m *RealMAASClient) GetPowerStatus(endpoint string, apiKey string, systemID string) (bool, error) {
+	body, err := m.machineOp(endpoint, apiKey, systemID, http.MethodGet, "query_power_state", nil)
+	if err != nil {
+		return false, err
+	}
+
+	var result struct {
+		State string `json:"state"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return false, fmt.Errorf("unable to parse MAAS power state: %w", err)
+	}
+	return result.State == "on", nil
+}
+
+func (m *RealMAASClient) Deploy(endpoint string, apiKey string, systemID string, distroSeries string) error {
+	params := url.Values{}
+	if distroSeries != "" {
+		params.Set("distro_series", distroSeries)
+	}
+	_, err := m.machineOp(endpoint, apiKey, systemID, http.MethodPost, "deploy", params)
+	return err
+}
+
+func (m *RealMAASClient) Release(endpoint string, apiKey string, systemID string) error {
+	_, err := m.machineOp(endpoint, apiKey, systemID, http.MethodPost, "release", nil)
+	return err
+}
+
+// machineOp calls a MAAS 2.0 machine operation under the retry policy, e.g.
+// POST /MAAS/api/2.0/machines/{system_id}/op-power_on
+func (m *RealMAASClient) machineOp(endpoint string, apiKey string, systemID string, method string, op string, params url.Values) ([]byte, error) {
+	var respBody []byte
+	err := retry(context.Background(), m.Retry, func() error {
+		var err error
+		respBody, err = m.machineOpOnce(endpoint, apiKey, systemID, method, op, params)
+		return err
+	})
+	return respBody, err
+}
+
+func (m *RealMAASClient) machineOpOnce(endpoint string, apiKey string, systemID string, method string, op string, params url.Values) ([]byte, error) {
+	if systemID == "" {
+		return nil, fmt.Errorf("MAAS system ID is required")
+	}
+
+	authHeader, err := maasAuthHeader(apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	opURL := strings.TrimSuffix(endpoint, "/") + "/api/2.0/machines/" + url.PathEscape(systemID) + "/op-" + op
+
+	var body io.Reader
+	if method == http.MethodPost && params != nil {
+		body = strings.NewReader(params.Encode())
+	}
+
+	req, err := http.NewRequest(method, opURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create MAAS request: %w", err)
+	}
+	req.Header.Set("Authorization", authHeader)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	httpClient := m.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, unreachable(fmt.Errorf("unable to reach MAAS API: %w", err))
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read MAAS response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, classifyStatus(resp.StatusCode, fmt.Errorf("MAAS %s failed with status %d: %s", op, resp.StatusCode, strings.TrimSpace(string(respBody))))
+	}
+
+	return respBody, nil
+}
+
+// maasAuthHeader builds the OAuth 1.0 PLAINTEXT Authorization header from a
+// MAAS API key in "consumer_key:token_key:token_secret" format.
+func maasAuthHeader(apiKey string) (string, error) {
+	parts := strings.Split(strings.TrimSpace(apiKey), ":")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("invalid MAAS API key: expected consumer_key:token_key:token_secret")
+	}
+
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("unable to generate OAuth nonce: %w", err)
+	}
+
+	return fmt.Sprintf(`OAuth oauth_version="1.0", oauth_signature_method="PLAINTEXT", `+
+		`oauth_consumer_key="%s", oauth_token="%s", oauth_signature="&%s", `+
+		`oauth_nonce="%s", oauth_timestamp="%s"`,
+		parts[0], parts[1], parts[2],
+		hex.EncodeToString(nonce), strconv.FormatInt(time.Now().Unix(), 10)), nil
+}
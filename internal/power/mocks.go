@@ -1,5 +1,12 @@
 package power
 
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
 // MockWolSender is a mock implementation of WolSender
 type MockWolSender struct {
 	WakeCalled    bool
@@ -10,7 +17,7 @@ type MockWolSender struct {
 	ReturnError   error
 }
 
-func (m *MockWolSender) Wake(macAddress string, port int, broadcastIP string) error {
+func (m *MockWolSender) Wake(ctx context.Context, macAddress string, port int, broadcastIP string) error {
 	m.WakeCalled = true
 	m.WakeCallCount++
 	m.LastMAC = macAddress
@@ -19,16 +26,40 @@ func (m *MockWolSender) Wake(macAddress string, port int, broadcastIP string) er
 	return m.ReturnError
 }
 
+// MockWolRelay is a mock implementation of WolRelay
+type MockWolRelay struct {
+	WakeCalled  bool
+	LastRelay   string
+	LastMAC     string
+	Reachable   bool
+	ReturnError error
+}
+
+func (m *MockWolRelay) Wake(ctx context.Context, relay string, macAddress string, port int, broadcastAddress string) error {
+	m.WakeCalled = true
+	m.LastRelay = relay
+	m.LastMAC = macAddress
+	return m.ReturnError
+}
+
+func (m *MockWolRelay) IsReachable(ctx context.Context, relay string, address string) bool {
+	m.LastRelay = relay
+	return m.Reachable
+}
+
 // MockSSHClient is a mock implementation of SSHClient
 type MockSSHClient struct {
 	ShutdownCalled    bool
 	ShutdownCallCount int
+	RebootCalled      bool
 	LastHost          string
 	LastUser          string
+	LLDPNeighbors     []LLDPNeighbor
+	HostInventory     HostInventory
 	ReturnError       error
 }
 
-func (m *MockSSHClient) Shutdown(host string, user string, key string) error {
+func (m *MockSSHClient) Shutdown(ctx context.Context, host string, user string, key string) error {
 	m.ShutdownCalled = true
 	m.ShutdownCallCount++
 	m.LastHost = host
@@ -36,19 +67,47 @@ func (m *MockSSHClient) Shutdown(host string, user string, key string) error {
 	return m.ReturnError
 }
 
+func (m *MockSSHClient) Reboot(ctx context.Context, host string, user string, key string) error {
+	m.RebootCalled = true
+	m.LastHost = host
+	m.LastUser = user
+	return m.ReturnError
+}
+
+func (m *MockSSHClient) GetLLDPNeighbors(ctx context.Context, host string, user string, key string) ([]LLDPNeighbor, error) {
+	m.LastHost = host
+	m.LastUser = user
+	return m.LLDPNeighbors, m.ReturnError
+}
+
+func (m *MockSSHClient) GetHostInventory(ctx context.Context, host string, user string, key string) (HostInventory, error) {
+	m.LastHost = host
+	m.LastUser = user
+	return m.HostInventory, m.ReturnError
+}
+
 // MockIPMIClient is a mock implementation of IPMIClient
 type MockIPMIClient struct {
 	PowerOnCalled   bool
 	PowerOffCalled  bool
+	PowerCycled     bool
 	GetStatusCalled bool
 	LastAddress     string
 	LastUsername    string
 	LastPassword    string
 	PowerStatus     bool
+	Inventory       Inventory
+	BootDevice      string
+	BootPersistent  bool
+	PowerLimit      PowerLimit
+	Temperatures    []Temperature
+	WatchdogTimeout time.Duration
+	WatchdogAction  string
+	ColdResetCalled bool
 	ReturnError     error
 }
 
-func (m *MockIPMIClient) PowerOn(address string, username string, password string) error {
+func (m *MockIPMIClient) PowerOn(ctx context.Context, address string, username string, password string) error {
 	m.PowerOnCalled = true
 	m.LastAddress = address
 	m.LastUsername = username
@@ -56,7 +115,7 @@ func (m *MockIPMIClient) PowerOn(address string, username string, password strin
 	return m.ReturnError
 }
 
-func (m *MockIPMIClient) PowerOff(address string, username string, password string) error {
+func (m *MockIPMIClient) PowerOff(ctx context.Context, address string, username string, password string) error {
 	m.PowerOffCalled = true
 	m.LastAddress = address
 	m.LastUsername = username
@@ -64,7 +123,15 @@ func (m *MockIPMIClient) PowerOff(address string, username string, password stri
 	return m.ReturnError
 }
 
-func (m *MockIPMIClient) GetPowerStatus(address string, username string, password string) (bool, error) {
+func (m *MockIPMIClient) PowerCycle(ctx context.Context, address string, username string, password string) error {
+	m.PowerCycled = true
+	m.LastAddress = address
+	m.LastUsername = username
+	m.LastPassword = password
+	return m.ReturnError
+}
+
+func (m *MockIPMIClient) GetPowerStatus(ctx context.Context, address string, username string, password string) (bool, error) {
 	m.GetStatusCalled = true
 	m.LastAddress = address
 	m.LastUsername = username
@@ -72,15 +139,449 @@ func (m *MockIPMIClient) GetPowerStatus(address string, username string, passwor
 	return m.PowerStatus, m.ReturnError
 }
 
+func (m *MockIPMIClient) GetInventory(ctx context.Context, address string, username string, password string) (Inventory, error) {
+	m.LastAddress = address
+	m.LastUsername = username
+	m.LastPassword = password
+	return m.Inventory, m.ReturnError
+}
+
+func (m *MockIPMIClient) SetBootDevice(ctx context.Context, address string, username string, password string, device string, persistent bool) error {
+	m.LastAddress = address
+	m.LastUsername = username
+	m.LastPassword = password
+	m.BootDevice = device
+	m.BootPersistent = persistent
+	return m.ReturnError
+}
+
+func (m *MockIPMIClient) GetBootDevice(ctx context.Context, address string, username string, password string) (BootOverride, error) {
+	m.LastAddress = address
+	m.LastUsername = username
+	m.LastPassword = password
+	return BootOverride{Device: m.BootDevice, Persistent: m.BootPersistent}, m.ReturnError
+}
+
+func (m *MockIPMIClient) GetPowerLimit(ctx context.Context, address string, username string, password string) (PowerLimit, error) {
+	m.LastAddress = address
+	m.LastUsername = username
+	m.LastPassword = password
+	return m.PowerLimit, m.ReturnError
+}
+
+func (m *MockIPMIClient) SetPowerLimit(ctx context.Context, address string, username string, password string, watts int32) error {
+	m.LastAddress = address
+	m.LastUsername = username
+	m.LastPassword = password
+	if m.ReturnError == nil {
+		m.PowerLimit.LimitWatts = watts
+	}
+	return m.ReturnError
+}
+
+func (m *MockIPMIClient) GetTemperatures(ctx context.Context, address string, username string, password string) ([]Temperature, error) {
+	m.LastAddress = address
+	m.LastUsername = username
+	m.LastPassword = password
+	return m.Temperatures, m.ReturnError
+}
+
+func (m *MockIPMIClient) ColdReset(ctx context.Context, address string, username string, password string) error {
+	m.ColdResetCalled = true
+	m.LastAddress = address
+	m.LastUsername = username
+	m.LastPassword = password
+	return m.ReturnError
+}
+
+func (m *MockIPMIClient) SetWatchdog(ctx context.Context, address string, username string, password string, timeout time.Duration, action string) error {
+	m.LastAddress = address
+	m.LastUsername = username
+	m.LastPassword = password
+	if m.ReturnError == nil {
+		m.WatchdogTimeout = timeout
+		m.WatchdogAction = action
+	}
+	return m.ReturnError
+}
+
+// MockMAASClient is a mock implementation of MAASClient
+type MockMAASClient struct {
+	PowerOnCalled    bool
+	PowerOffCalled   bool
+	GetStatusCalled  bool
+	DeployCalled     bool
+	ReleaseCalled    bool
+	LastEndpoint     string
+	LastAPIKey       string
+	LastSystemID     string
+	LastDistroSeries string
+	PowerStatus      bool
+	ReturnError      error
+}
+
+func (m *MockMAASClient) PowerOn(endpoint string, apiKey string, systemID string) error {
+	m.PowerOnCalled = true
+	m.record(endpoint, apiKey, systemID)
+	return m.ReturnError
+}
+
+func (m *MockMAASClient) PowerOff(endpoint string, apiKey string, systemID string) error {
+	m.PowerOffCalled = true
+	m.record(endpoint, apiKey, systemID)
+	return m.ReturnError
+}
+
+func (m *MockMAASClient) GetPowerStatus(endpoint string, apiKey string, systemID string) (bool, error) {
+	m.GetStatusCalled = true
+	m.record(endpoint, apiKey, systemID)
+	return m.PowerStatus, m.ReturnError
+}
+
+func (m *MockMAASClient) Deploy(endpoint string, apiKey string, systemID string, distroSeries string) error {
+	m.DeployCalled = true
+	m.record(endpoint, apiKey, systemID)
+	m.LastDistroSeries = distroSeries
+	return m.ReturnError
+}
+
+func (m *MockMAASClient) Release(endpoint string, apiKey string, systemID string) error {
+	m.ReleaseCalled = true
+	m.record(endpoint, apiKey, systemID)
+	return m.ReturnError
+}
+
+func (m *MockMAASClient) record(endpoint string, apiKey string, systemID string) {
+	m.LastEndpoint = endpoint
+	m.LastAPIKey = apiKey
+	m.LastSystemID = systemID
+}
+
+// MockEquinixClient is a mock implementation of EquinixClient
+type MockEquinixClient struct {
+	PowerOnCalled   bool
+	PowerOffCalled  bool
+	GetStatusCalled bool
+	LastToken       string
+	LastDeviceID    string
+	LastHostname    string
+	// Devices maps hostnames to the device IDs FindDevice returns
+	Devices     map[string]string
+	PowerStatus bool
+	ReturnError error
+}
+
+func (m *MockEquinixClient) PowerOn(token string, deviceID string) error {
+	m.PowerOnCalled = true
+	m.LastToken, m.LastDeviceID = token, deviceID
+	return m.ReturnError
+}
+
+func (m *MockEquinixClient) PowerOff(token string, deviceID string) error {
+	m.PowerOffCalled = true
+	m.LastToken, m.LastDeviceID = token, deviceID
+	return m.ReturnError
+}
+
+func (m *MockEquinixClient) GetPowerStatus(token string, deviceID string) (bool, error) {
+	m.GetStatusCalled = true
+	m.LastToken, m.LastDeviceID = token, deviceID
+	return m.PowerStatus, m.ReturnError
+}
+
+func (m *MockEquinixClient) FindDevice(token string, projectID string, hostname string) (string, error) {
+	m.LastToken, m.LastHostname = token, hostname
+	if m.ReturnError != nil {
+		return "", m.ReturnError
+	}
+	id, ok := m.Devices[hostname]
+	if !ok {
+		return "", fmt.Errorf("no device with hostname %s in project %s", hostname, projectID)
+	}
+	return id, nil
+}
+
+// MockHetznerClient is a mock implementation of HetznerClient
+type MockHetznerClient struct {
+	WakeCalled         bool
+	ResetCalled        bool
+	RescueCalled       bool
+	LastUsername       string
+	LastPassword       string
+	LastServerNumber   int32
+	LastResetType      string
+	LastRescueOS       string
+	LastAuthorizedKeys []string
+	ReturnError        error
+}
+
+func (m *MockHetznerClient) Wake(username string, password string, serverNumber int32) error {
+	m.WakeCalled = true
+	m.record(username, password, serverNumber)
+	return m.ReturnError
+}
+
+func (m *MockHetznerClient) Reset(username string, password string, serverNumber int32, resetType string) error {
+	m.ResetCalled = true
+	m.record(username, password, serverNumber)
+	m.LastResetType = resetType
+	return m.ReturnError
+}
+
+func (m *MockHetznerClient) EnableRescue(username string, password string, serverNumber int32, os string, authorizedKeys []string) error {
+	m.RescueCalled = true
+	m.record(username, password, serverNumber)
+	m.LastRescueOS, m.LastAuthorizedKeys = os, authorizedKeys
+	return m.ReturnError
+}
+
+func (m *MockHetznerClient) record(username string, password string, serverNumber int32) {
+	m.LastUsername = username
+	m.LastPassword = password
+	m.LastServerNumber = serverNumber
+}
+
+// MockHypervisorClient is a mock implementation of HypervisorClient
+type MockHypervisorClient struct {
+	EnterMaintenanceCalled bool
+	ExitMaintenanceCalled  bool
+	ShutdownCalled         bool
+	LastTarget             HypervisorTarget
+	LastTimeout            time.Duration
+	ReturnError            error
+}
+
+func (m *MockHypervisorClient) EnterMaintenanceMode(target HypervisorTarget, timeout time.Duration) error {
+	m.EnterMaintenanceCalled = true
+	m.LastTarget, m.LastTimeout = target, timeout
+	return m.ReturnError
+}
+
+func (m *MockHypervisorClient) ExitMaintenanceMode(target HypervisorTarget) error {
+	m.ExitMaintenanceCalled = true
+	m.LastTarget = target
+	return m.ReturnError
+}
+
+func (m *MockHypervisorClient) Shutdown(target HypervisorTarget) error {
+	m.ShutdownCalled = true
+	m.LastTarget = target
+	return m.ReturnError
+}
+
+// MockRedfishClient is a mock implementation of RedfishClient
+type MockRedfishClient struct {
+	PowerOnCalled     bool
+	PowerOffCalled    bool
+	GetStatusCalled   bool
+	LastTarget        RedfishTarget
+	PowerStatus       bool
+	Inventory         Inventory
+	HostInventory     HostInventory
+	BootDevice        string
+	BootPersistent    bool
+	PowerLimit        PowerLimit
+	Temperatures      []Temperature
+	WatchdogAction    string
+	SerialConsole     SerialConsoleInfo
+	Volumes           []Volume
+	CreatedVolumes    []Volume
+	DeletedVolumes    []string
+	Subscription      string
+	EventSubscription string
+	// SubscriptionContext is the context of the last subscription
+	SubscriptionContext string
+	ReturnError         error
+}
+
+func (m *MockRedfishClient) PowerOn(target RedfishTarget) error {
+	m.PowerOnCalled = true
+	m.LastTarget = target
+	return m.ReturnError
+}
+
+func (m *MockRedfishClient) PowerOff(target RedfishTarget) error {
+	m.PowerOffCalled = true
+	m.LastTarget = target
+	return m.ReturnError
+}
+
+func (m *MockRedfishClient) GetPowerStatus(target RedfishTarget) (bool, error) {
+	m.GetStatusCalled = true
+	m.LastTarget = target
+	return m.PowerStatus, m.ReturnError
+}
+
+func (m *MockRedfishClient) GetInventory(target RedfishTarget) (Inventory, error) {
+	m.LastTarget = target
+	return m.Inventory, m.ReturnError
+}
+
+func (m *MockRedfishClient) GetHostInventory(target RedfishTarget) (HostInventory, error) {
+	m.LastTarget = target
+	return m.HostInventory, m.ReturnError
+}
+
+func (m *MockRedfishClient) SetBootDevice(target RedfishTarget, device string, persistent bool) error {
+	m.LastTarget = target
+	m.BootDevice = device
+	m.BootPersistent = persistent
+	return m.ReturnError
+}
+
+func (m *MockRedfishClient) GetBootDevice(target RedfishTarget) (BootOverride, error) {
+	m.LastTarget = target
+	return BootOverride{Device: m.BootDevice, Persistent: m.BootPersistent}, m.ReturnError
+}
+
+func (m *MockRedfishClient) GetPowerLimit(target RedfishTarget) (PowerLimit, error) {
+	m.LastTarget = target
+	return m.PowerLimit, m.ReturnError
+}
+
+func (m *MockRedfishClient) SetPowerLimit(target RedfishTarget, watts int32) error {
+	m.LastTarget = target
+	if m.ReturnError == nil {
+		m.PowerLimit.LimitWatts = watts
+	}
+	return m.ReturnError
+}
+
+func (m *MockRedfishClient) GetTemperatures(target RedfishTarget) ([]Temperature, error) {
+	m.LastTarget = target
+	return m.Temperatures, m.ReturnError
+}
+
+func (m *MockRedfishClient) SetWatchdog(target RedfishTarget, action string) error {
+	m.LastTarget = target
+	if m.ReturnError == nil {
+		m.WatchdogAction = action
+	}
+	return m.ReturnError
+}
+
+func (m *MockRedfishClient) GetSerialConsole(target RedfishTarget) (SerialConsoleInfo, error) {
+	m.LastTarget = target
+	return m.SerialConsole, m.ReturnError
+}
+
+func (m *MockRedfishClient) ListVolumes(target RedfishTarget, storageID string) ([]Volume, error) {
+	m.LastTarget = target
+	return m.Volumes, m.ReturnError
+}
+
+func (m *MockRedfishClient) CreateVolume(target RedfishTarget, storageID string, volume Volume) error {
+	m.LastTarget = target
+	m.CreatedVolumes = append(m.CreatedVolumes, volume)
+	return m.ReturnError
+}
+
+func (m *MockRedfishClient) DeleteVolume(target RedfishTarget, storageID string, volumeID string) error {
+	m.LastTarget = target
+	m.DeletedVolumes = append(m.DeletedVolumes, volumeID)
+	return m.ReturnError
+}
+
+func (m *MockRedfishClient) SubscribeMetricReports(target RedfishTarget, destination string, context string) (string, error) {
+	m.LastTarget = target
+	if m.ReturnError != nil {
+		return "", m.ReturnError
+	}
+	m.Subscription = destination
+	m.SubscriptionContext = context
+	return "/redfish/v1/EventService/Subscriptions/1", nil
+}
+
+func (m *MockRedfishClient) SubscribeEvents(target RedfishTarget, destination string, context string) (string, error) {
+	m.LastTarget = target
+	if m.ReturnError != nil {
+		return "", m.ReturnError
+	}
+	m.EventSubscription = destination
+	m.SubscriptionContext = context
+	return "/redfish/v1/EventService/Subscriptions/2", nil
+}
+
+func (m *MockRedfishClient) Unsubscribe(target RedfishTarget, subscriptionURI string) error {
+	m.LastTarget = target
+	if m.ReturnError == nil {
+		m.Subscription = ""
+	}
+	return m.ReturnError
+}
+
+// MockSerialConsole is a mock implementation of SerialConsole. Conn is
+// returned by every open.
+type MockSerialConsole struct {
+	Conn        io.ReadWriteCloser
+	LastAddress string
+	LastPort    int
+	LastUser    string
+	LastCommand string
+	ReturnError error
+}
+
+func (m *MockSerialConsole) OpenIPMI(address string, username string, password string) (io.ReadWriteCloser, error) {
+	m.LastAddress = address
+	m.LastUser = username
+	return m.Conn, m.ReturnError
+}
+
+func (m *MockSerialConsole) OpenSSH(address string, port int, username string, password string, command string) (io.ReadWriteCloser, error) {
+	m.LastAddress = address
+	m.LastPort = port
+	m.LastUser = username
+	m.LastCommand = command
+	return m.Conn, m.ReturnError
+}
+
+// MockAttestor is a mock implementation of Attestor. QuoteFunc builds the
+// quote so tests can sign over the nonce chosen by the controller.
+type MockAttestor struct {
+	QuoteCalled  bool
+	LastHost     string
+	LastUser     string
+	LastAKHandle string
+	LastPCRs     []int
+	LastNonce    []byte
+	QuoteFunc    func(pcrs []int, nonce []byte) (TPMQuote, error)
+	ReturnError  error
+}
+
+func (m *MockAttestor) Quote(ctx context.Context, host string, user string, key string, akHandle string, pcrs []int, nonce []byte) (TPMQuote, error) {
+	m.QuoteCalled = true
+	m.LastHost = host
+	m.LastUser = user
+	m.LastAKHandle = akHandle
+	m.LastPCRs = pcrs
+	m.LastNonce = nonce
+	if m.ReturnError != nil {
+		return TPMQuote{}, m.ReturnError
+	}
+	if m.QuoteFunc == nil {
+		return TPMQuote{}, nil
+	}
+	return m.QuoteFunc(pcrs, nonce)
+}
+
 // MockPinger is a mock implementation of Pinger
 type MockPinger struct {
 	Reachable     bool
 	LastAddress   string
 	PingCallCount int
+	// RTT is the round trip time probes report while reachable
+	RTT time.Duration
 }
 
-func (m *MockPinger) IsReachable(address string) bool {
+func (m *MockPinger) IsReachable(ctx context.Context, address string) bool {
 	m.PingCallCount++
 	m.LastAddress = address
 	return m.Reachable
 }
+
+func (m *MockPinger) Probe(ctx context.Context, address string) PingStats {
+	if !m.IsReachable(ctx, address) {
+		return PingStats{Sent: 1}
+	}
+	return PingStats{Sent: 1, Received: 1, RTT: m.RTT}
+}
@@ -1,42 +1,92 @@
 package power
 
 import (
+	"context"
+	"fmt"
 	"net"
 	"time"
 )
 
-type RealPinger struct{}
+// defaultPingCount is the number of echo requests of a probe
+const defaultPingCount = 3
 
-func (p *RealPinger) IsReachable(address string) bool {
-	const maxAttempts = 3
-	const retryDelay = 500 * time.Millisecond
+// pingInterval is the wait between the echo requests of a probe
+const pingInterval = 200 * time.Millisecond
 
-	for attempt := 0; attempt < maxAttempts; attempt++ {
-		if p.ping(address) {
-			return true
-		}
-		if attempt < maxAttempts-1 {
-			time.Sleep(retryDelay)
+// defaultPingTimeout is how long a reply is waited for
+const defaultPingTimeout = 2 * time.Second
+
+type RealPinger struct {
+	// Retry is the policy for retrying unanswered pings,
+	// DefaultRetryPolicy if nil
+	Retry *RetryPolicy
+
+	// Count is the number of echo requests sent by Probe, 3 if 0
+	Count int
+
+	// Timeout is how long the reply to an echo request is waited for, 2s
+	// if 0
+	Timeout time.Duration
+}
+
+// IsReachable gives up as unreachable once ctx is done
+func (p *RealPinger) IsReachable(ctx context.Context, address string) bool {
+	err := retry(ctx, p.Retry, func() error {
+		if _, ok := p.ping(ctx, address, 0); !ok {
+			return unreachable(fmt.Errorf("no echo reply from %s", address))
 		}
-	}
-	return false
+		return nil
+	})
+	return err == nil
 }
 
-func (p *RealPinger) ping(address string) bool {
-	netAddr, err := net.ResolveIPAddr("ip", address)
-	if err != nil {
-		return false
+// Probe sends Count echo requests, one after the other, and stops early
+// once ctx is done
+func (p *RealPinger) Probe(ctx context.Context, address string) PingStats {
+	count := p.Count
+	if count <= 0 {
+		count = defaultPingCount
 	}
 
-	conn, err := net.DialIP("ip4:icmp", nil, netAddr)
+	var stats PingStats
+	var total time.Duration
+	for seq := 0; seq < count && ctx.Err() == nil; seq++ {
+		if seq > 0 {
+			timer := time.NewTimer(pingInterval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				continue
+			case <-timer.C:
+			}
+		}
+		stats.Sent++
+		if rtt, ok := p.ping(ctx, address, seq); ok {
+			stats.Received++
+			total += rtt
+		}
+	}
+	if stats.Received > 0 {
+		stats.RTT = total / time.Duration(stats.Received)
+	}
+	return stats
+}
+
+// ping sends one echo request and returns the round trip time of the reply
+func (p *RealPinger) ping(ctx context.Context, address string, seq int) (time.Duration, bool) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "ip4:icmp", address)
 	if err != nil {
-		return false
+		return 0, false
 	}
 	defer conn.Close()
+	// Closing the connection aborts the read below
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
 
 	// Send ICMP Echo Request
 	msg := []byte{
-		8, 0, 0, 0, 0, 0, 0, 0, // Type, Code, Checksum, Identifier, Sequence Number
+		8, 0, 0, 0, 0, 0, byte(seq >> 8), byte(seq), // Type, Code, Checksum, Identifier, Sequence Number
 	}
 	checksum := 0
 	for i := 0; i < len(msg); i += 2 {
@@ -47,16 +97,21 @@ func (p *RealPinger) ping(address string) bool {
 	msg[2] = byte(checksum >> 8)
 	msg[3] = byte(checksum & 0xFF)
 
+	sent := time.Now()
 	_, err = conn.Write(msg)
 	if err != nil {
-		return false
+		return 0, false
 	}
 
 	// Set a read deadline
-	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = defaultPingTimeout
+	}
+	conn.SetReadDeadline(sent.Add(timeout))
 
 	// Wait for ICMP Echo Reply
 	reply := make([]byte, 1024)
 	_, err = conn.Read(reply)
-	return err == nil
+	return time.Since(sent), err == nil
 }
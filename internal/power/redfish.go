@@ -0,0 +1,850 @@
+package power
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type RealRedfishClient struct {
+	HTTPClient *http.Client
+
+	// SessionIdleTimeout keeps a Redfish session open per BMC and logs out
+	// of it after it has been idle this long, so repeated requests don't
+	// authenticate each time. Zero uses basic auth on every request.
+	SessionIdleTimeout time.Duration
+
+	// Retry is the policy for retrying failed calls, DefaultRetryPolicy
+	// if nil
+	Retry *RetryPolicy
+
+	mu       sync.Mutex
+	sessions map[redfishSessionKey]*redfishSession
+	clientMu sync.Mutex
+	clients  map[redfishTLSKey]*http.Client
+}
+
+// redfishTLSKey identifies the TLS settings of a shared HTTP client
+type redfishTLSKey struct {
+	caBundle           [sha256.Size]byte
+	serverName         string
+	insecureSkipVerify bool
+}
+
+func (c *RealRedfishClient) PowerOn(target RedfishTarget) error {
+	return c.reset(target, "On")
+}
+
+func (c *RealRedfishClient) PowerOff(target RedfishTarget) error {
+	return c.reset(target, "GracefulShutdown")
+}
+
+func (c *RealRedfishClient) GetPowerStatus(target RedfishTarget) (bool, error) {
+	systemURI, err := c.systemURI(target)
+	if err != nil {
+		return false, err
+	}
+
+	var system struct {
+		PowerState string `json:"PowerState"`
+	}
+	if err := c.get(target, systemURI, &system); err != nil {
+		return false, err
+	}
+	return system.PowerState == "On", nil
+}
+
+func (c *RealRedfishClient) GetInventory(target RedfishTarget) (Inventory, error) {
+	systemURI, err := c.systemURI(target)
+	if err != nil {
+		return Inventory{}, err
+	}
+
+	var system struct {
+		Manufacturer string `json:"Manufacturer"`
+		Model        string `json:"Model"`
+		SerialNumber string `json:"SerialNumber"`
+		BiosVersion  string `json:"BiosVersion"`
+		// Processors and memory are summed up by the BMC
+		ProcessorSummary struct {
+			LogicalProcessorCount int32 `json:"LogicalProcessorCount"`
+		} `json:"ProcessorSummary"`
+		MemorySummary struct {
+			TotalSystemMemoryGiB float64 `json:"TotalSystemMemoryGiB"`
+		} `json:"MemorySummary"`
+		Links struct {
+			ManagedBy []redfishLink `json:"ManagedBy"`
+		} `json:"Links"`
+	}
+	if err := c.get(target, systemURI, &system); err != nil {
+		return Inventory{}, err
+	}
+
+	inventory := Inventory{
+		Manufacturer: system.Manufacturer,
+		Model:        system.Model,
+		SerialNumber: system.SerialNumber,
+		BIOSVersion:  system.BiosVersion,
+		CPUs:         system.ProcessorSummary.LogicalProcessorCount,
+		MemoryGiB:    system.MemorySummary.TotalSystemMemoryGiB,
+	}
+
+	if len(system.Links.ManagedBy) > 0 {
+		var manager struct {
+			FirmwareVersion string `json:"FirmwareVersion"`
+		}
+		if err := c.get(target, system.Links.ManagedBy[0].ODataID, &manager); err != nil {
+			return Inventory{}, err
+		}
+		inventory.BMCFirmwareVersion = manager.FirmwareVersion
+	}
+	return inventory, nil
+}
+
+// GetHostInventory reads the processors, drives and Ethernet interfaces of
+// the system, which the BMC only knows of once the host ran its POST
+func (c *RealRedfishClient) GetHostInventory(target RedfishTarget) (HostInventory, error) {
+	systemURI, err := c.systemURI(target)
+	if err != nil {
+		return HostInventory{}, err
+	}
+
+	var system struct {
+		MemorySummary struct {
+			TotalSystemMemoryGiB float64 `json:"TotalSystemMemoryGiB"`
+		} `json:"MemorySummary"`
+		Processors         redfishLink `json:"Processors"`
+		Storage            redfishLink `json:"Storage"`
+		EthernetInterfaces redfishLink `json:"EthernetInterfaces"`
+	}
+	if err := c.get(target, systemURI, &system); err != nil {
+		return HostInventory{}, err
+	}
+	inventory := HostInventory{
+		MemoryBytes: int64(system.MemorySummary.TotalSystemMemoryGiB * (1 << 30)),
+	}
+
+	if system.Processors.ODataID != "" {
+		err := c.eachMember(target, system.Processors.ODataID, func(uri string) error {
+			var processor struct {
+				ProcessorType string `json:"ProcessorType"`
+				Model         string `json:"Model"`
+				TotalCores    int32  `json:"TotalCores"`
+				TotalThreads  int32  `json:"TotalThreads"`
+			}
+			if err := c.get(target, uri, &processor); err != nil {
+				return err
+			}
+			switch processor.ProcessorType {
+			case "CPU", "":
+				if inventory.CPUModel == "" {
+					inventory.CPUModel = strings.TrimSpace(processor.Model)
+				}
+				inventory.CPUCores += processor.TotalCores
+				inventory.CPUThreads += processor.TotalThreads
+			case "GPU":
+				inventory.GPUs++
+			}
+			return nil
+		})
+		if err != nil {
+			return HostInventory{}, err
+		}
+	}
+
+	if system.Storage.ODataID != "" {
+		err := c.eachMember(target, system.Storage.ODataID, func(uri string) error {
+			var storage struct {
+				Drives []redfishLink `json:"Drives"`
+			}
+			if err := c.get(target, uri, &storage); err != nil {
+				return err
+			}
+			for _, link := range storage.Drives {
+				var drive struct {
+					ID            string `json:"Id"`
+					Name          string `json:"Name"`
+					Model         string `json:"Model"`
+					CapacityBytes int64  `json:"CapacityBytes"`
+					MediaType     string `json:"MediaType"`
+				}
+				if err := c.get(target, link.ODataID, &drive); err != nil {
+					return err
+				}
+				name := drive.ID
+				if name == "" {
+					name = drive.Name
+				}
+				inventory.Disks = append(inventory.Disks, Disk{
+					Name:       name,
+					Model:      strings.TrimSpace(drive.Model),
+					SizeBytes:  drive.CapacityBytes,
+					Rotational: drive.MediaType == "HDD",
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return HostInventory{}, err
+		}
+	}
+
+	if system.EthernetInterfaces.ODataID != "" {
+		err := c.eachMember(target, system.EthernetInterfaces.ODataID, func(uri string) error {
+			var nic struct {
+				ID         string `json:"Id"`
+				MACAddress string `json:"MACAddress"`
+			}
+			if err := c.get(target, uri, &nic); err != nil {
+				return err
+			}
+			inventory.NICs = append(inventory.NICs, NIC{Name: nic.ID, MACAddress: strings.ToLower(nic.MACAddress)})
+			return nil
+		})
+		if err != nil {
+			return HostInventory{}, err
+		}
+	}
+	return inventory, nil
+}
+
+// eachMember calls fn with the URI of each member of a collection
+func (c *RealRedfishClient) eachMember(target RedfishTarget, collectionURI string, fn func(string) error) error {
+	var collection redfishCollection
+	if err := c.get(target, collectionURI, &collection); err != nil {
+		return err
+	}
+	for _, member := range collection.Members {
+		if err := fn(member.ODataID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// redfishBootTargets maps boot devices to BootSourceOverrideTarget values
+var redfishBootTargets = map[string]string{
+	BootDevicePXE:   "Pxe",
+	BootDeviceDisk:  "Hdd",
+	BootDeviceCDROM: "Cd",
+	BootDeviceBIOS:  "BiosSetup",
+}
+
+func (c *RealRedfishClient) SetBootDevice(target RedfishTarget, device string, persistent bool) error {
+	overrideTarget, ok := redfishBootTargets[device]
+	if !ok {
+		return unsupported(fmt.Errorf("unsupported boot device %q", device))
+	}
+
+	systemURI, err := c.systemURI(target)
+	if err != nil {
+		return err
+	}
+
+	enabled := "Once"
+	if persistent {
+		enabled = "Continuous"
+	}
+	return c.do(target, http.MethodPatch, systemURI, map[string]interface{}{
+		"Boot": map[string]string{
+			"BootSourceOverrideTarget":  overrideTarget,
+			"BootSourceOverrideEnabled": enabled,
+		},
+	}, nil)
+}
+
+// GetBootDevice reads the boot override of the system. Overrides that are
+// disabled force no device.
+func (c *RealRedfishClient) GetBootDevice(target RedfishTarget) (BootOverride, error) {
+	systemURI, err := c.systemURI(target)
+	if err != nil {
+		return BootOverride{}, err
+	}
+
+	var system struct {
+		Boot struct {
+			BootSourceOverrideTarget  string `json:"BootSourceOverrideTarget"`
+			BootSourceOverrideEnabled string `json:"BootSourceOverrideEnabled"`
+		} `json:"Boot"`
+	}
+	if err := c.get(target, systemURI, &system); err != nil {
+		return BootOverride{}, err
+	}
+	if system.Boot.BootSourceOverrideEnabled == "Disabled" {
+		return BootOverride{}, nil
+	}
+
+	override := BootOverride{Persistent: system.Boot.BootSourceOverrideEnabled == "Continuous"}
+	for device, overrideTarget := range redfishBootTargets {
+		if overrideTarget == system.Boot.BootSourceOverrideTarget {
+			override.Device = device
+		}
+	}
+	return override, nil
+}
+
+// GetPowerLimit reads the first power control of the system's chassis
+func (c *RealRedfishClient) GetPowerLimit(target RedfishTarget) (PowerLimit, error) {
+	chassisURI, err := c.chassisURI(target)
+	if err != nil {
+		return PowerLimit{}, err
+	}
+
+	var chassisPower struct {
+		PowerControl []struct {
+			PowerConsumedWatts *float64 `json:"PowerConsumedWatts"`
+			PowerLimit         struct {
+				LimitInWatts *float64 `json:"LimitInWatts"`
+			} `json:"PowerLimit"`
+		} `json:"PowerControl"`
+	}
+	if err := c.get(target, chassisURI+"/Power", &chassisPower); err != nil {
+		return PowerLimit{}, err
+	}
+	if len(chassisPower.PowerControl) == 0 {
+		return PowerLimit{}, fmt.Errorf("no power control found on %s", chassisURI)
+	}
+
+	var result PowerLimit
+	control := chassisPower.PowerControl[0]
+	if control.PowerConsumedWatts != nil {
+		result.ConsumedWatts = int32(*control.PowerConsumedWatts)
+	}
+	if control.PowerLimit.LimitInWatts != nil {
+		result.LimitWatts = int32(*control.PowerLimit.LimitInWatts)
+	}
+	return result, nil
+}
+
+func (c *RealRedfishClient) SetPowerLimit(target RedfishTarget, watts int32) error {
+	chassisURI, err := c.chassisURI(target)
+	if err != nil {
+		return err
+	}
+
+	// A null limit removes it
+	var limit interface{}
+	if watts > 0 {
+		limit = watts
+	}
+	return c.do(target, http.MethodPatch, chassisURI+"/Power", map[string]interface{}{
+		"PowerControl": []map[string]interface{}{
+			{"PowerLimit": map[string]interface{}{"LimitInWatts": limit}},
+		},
+	}, nil)
+}
+
+// redfishWatchdogActions maps watchdog actions to HostWatchdogTimer
+// TimeoutAction values
+var redfishWatchdogActions = map[string]string{
+	WatchdogActionReset:      "ResetSystem",
+	WatchdogActionPowerCycle: "PowerCycle",
+	WatchdogActionPowerOff:   "PowerDown",
+}
+
+// SetWatchdog patches the HostWatchdogTimer of the system. Redfish leaves
+// the timeout to the BMC.
+func (c *RealRedfishClient) SetWatchdog(target RedfishTarget, action string) error {
+	timer := map[string]interface{}{"FunctionEnabled": action != ""}
+	if action != "" {
+		timeoutAction, ok := redfishWatchdogActions[action]
+		if !ok {
+			return unsupported(fmt.Errorf("unsupported watchdog action %q", action))
+		}
+		timer["TimeoutAction"] = timeoutAction
+	}
+
+	systemURI, err := c.systemURI(target)
+	if err != nil {
+		return err
+	}
+	return c.do(target, http.MethodPatch, systemURI, map[string]interface{}{
+		"HostWatchdogTimer": timer,
+	}, nil)
+}
+
+// GetTemperatures reads the temperature sensors of the system's chassis
+func (c *RealRedfishClient) GetTemperatures(target RedfishTarget) ([]Temperature, error) {
+	chassisURI, err := c.chassisURI(target)
+	if err != nil {
+		return nil, err
+	}
+
+	var thermal struct {
+		Temperatures []struct {
+			Name                   string   `json:"Name"`
+			ReadingCelsius         *float64 `json:"ReadingCelsius"`
+			UpperThresholdCritical *float64 `json:"UpperThresholdCritical"`
+		} `json:"Temperatures"`
+	}
+	if err := c.get(target, chassisURI+"/Thermal", &thermal); err != nil {
+		return nil, err
+	}
+
+	temperatures := make([]Temperature, 0, len(thermal.Temperatures))
+	for _, t := range thermal.Temperatures {
+		// Absent sensors have no reading
+		if t.ReadingCelsius == nil {
+			continue
+		}
+		temperature := Temperature{Name: t.Name, Celsius: *t.ReadingCelsius}
+		if t.UpperThresholdCritical != nil {
+			temperature.CriticalCelsius = *t.UpperThresholdCritical
+		}
+		temperatures = append(temperatures, temperature)
+	}
+	return temperatures, nil
+}
+
+// GetSerialConsole reads the SSH serial console advertised by the system,
+// defaulting to the BMC's SSH port without an entry command.
+func (c *RealRedfishClient) GetSerialConsole(target RedfishTarget) (SerialConsoleInfo, error) {
+	systemURI, err := c.systemURI(target)
+	if err != nil {
+		return SerialConsoleInfo{}, err
+	}
+
+	var system struct {
+		SerialConsole struct {
+			SSH struct {
+				ServiceEnabled      *bool  `json:"ServiceEnabled"`
+				Port                int    `json:"Port"`
+				ConsoleEntryCommand string `json:"ConsoleEntryCommand"`
+			} `json:"SSH"`
+		} `json:"SerialConsole"`
+	}
+	if err := c.get(target, systemURI, &system); err != nil {
+		return SerialConsoleInfo{}, err
+	}
+
+	sshConsole := system.SerialConsole.SSH
+	if sshConsole.ServiceEnabled != nil && !*sshConsole.ServiceEnabled {
+		return SerialConsoleInfo{}, fmt.Errorf("SSH serial console is disabled on %s", systemURI)
+	}
+	info := SerialConsoleInfo{Port: sshConsole.Port, Command: sshConsole.ConsoleEntryCommand}
+	if info.Port == 0 {
+		info.Port = 22
+	}
+	return info, nil
+}
+
+func (c *RealRedfishClient) ListVolumes(target RedfishTarget, storageID string) ([]Volume, error) {
+	storageURI, err := c.storageURI(target, storageID)
+	if err != nil {
+		return nil, err
+	}
+
+	var collection redfishCollection
+	if err := c.get(target, storageURI+"/Volumes", &collection); err != nil {
+		return nil, err
+	}
+
+	volumes := make([]Volume, 0, len(collection.Members))
+	for _, member := range collection.Members {
+		var v struct {
+			ID            string `json:"Id"`
+			Name          string `json:"Name"`
+			RAIDType      string `json:"RAIDType"`
+			CapacityBytes int64  `json:"CapacityBytes"`
+			Links         struct {
+				Drives []redfishLink `json:"Drives"`
+			} `json:"Links"`
+		}
+		if err := c.get(target, member.ODataID, &v); err != nil {
+			return nil, err
+		}
+
+		drives := make([]string, 0, len(v.Links.Drives))
+		for _, d := range v.Links.Drives {
+			drives = append(drives, lastPathSegment(d.ODataID))
+		}
+		volumes = append(volumes, Volume{
+			ID:            v.ID,
+			Name:          v.Name,
+			RAIDType:      v.RAIDType,
+			CapacityBytes: v.CapacityBytes,
+			Drives:        drives,
+		})
+	}
+	return volumes, nil
+}
+
+func (c *RealRedfishClient) CreateVolume(target RedfishTarget, storageID string, volume Volume) error {
+	storageURI, err := c.storageURI(target, storageID)
+	if err != nil {
+		return err
+	}
+
+	// Resolve drive IDs to the URIs listed by the storage controller
+	var storage struct {
+		Drives []redfishLink `json:"Drives"`
+	}
+	if err := c.get(target, storageURI, &storage); err != nil {
+		return err
+	}
+	driveLinks := make([]redfishLink, 0, len(volume.Drives))
+	for _, id := range volume.Drives {
+		found := false
+		for _, d := range storage.Drives {
+			if lastPathSegment(d.ODataID) == id {
+				driveLinks = append(driveLinks, d)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("drive %s not found on storage controller %s", id, storageURI)
+		}
+	}
+
+	body := map[string]interface{}{
+		"Name":     volume.Name,
+		"RAIDType": volume.RAIDType,
+		"Links": map[string]interface{}{
+			"Drives": driveLinks,
+		},
+		// Most controllers can only apply layout changes on the next boot
+		"@Redfish.OperationApplyTime": "OnReset",
+	}
+	if volume.CapacityBytes > 0 {
+		body["CapacityBytes"] = volume.CapacityBytes
+	}
+
+	return c.do(target, http.MethodPost, storageURI+"/Volumes", body, nil)
+}
+
+func (c *RealRedfishClient) DeleteVolume(target RedfishTarget, storageID string, volumeID string) error {
+	storageURI, err := c.storageURI(target, storageID)
+	if err != nil {
+		return err
+	}
+	return c.do(target, http.MethodDelete, storageURI+"/Volumes/"+volumeID, nil, nil)
+}
+
+// SubscribeMetricReports checks that the service has a TelemetryService
+// before subscribing, since BMCs without one accept the subscription but
+// never send a report
+func (c *RealRedfishClient) SubscribeMetricReports(target RedfishTarget, destination string, context string) (string, error) {
+	return c.subscribe(target, destination, context, true, map[string]interface{}{
+		"EventFormatType": "MetricReport",
+	})
+}
+
+// SubscribeEvents subscribes to status changes, e.g. of the power state,
+// and alerts
+func (c *RealRedfishClient) SubscribeEvents(target RedfishTarget, destination string, context string) (string, error) {
+	return c.subscribe(target, destination, context, false, map[string]interface{}{
+		"EventFormatType": "Event",
+		"EventTypes":      []string{"StatusChange", "ResourceUpdated", "Alert"},
+	})
+}
+
+// subscribe creates an event subscription with the properties, unless the
+// destination already has one, which only gets the new context
+func (c *RealRedfishClient) subscribe(target RedfishTarget, destination string, context string, telemetry bool, properties map[string]interface{}) (string, error) {
+	var root struct {
+		TelemetryService *redfishLink `json:"TelemetryService"`
+		EventService     *redfishLink `json:"EventService"`
+	}
+	if err := c.get(target, "/redfish/v1", &root); err != nil {
+		return "", err
+	}
+	if root.EventService == nil {
+		return "", unsupported(fmt.Errorf("BMC %s has no Redfish EventService", target.Address))
+	}
+	if telemetry && root.TelemetryService == nil {
+		return "", unsupported(fmt.Errorf("BMC %s has no Redfish TelemetryService", target.Address))
+	}
+
+	subscriptionsURI := root.EventService.ODataID + "/Subscriptions"
+	find := func() (string, error) {
+		var subscriptions redfishCollection
+		if err := c.get(target, subscriptionsURI, &subscriptions); err != nil {
+			return "", err
+		}
+		for _, member := range subscriptions.Members {
+			var subscription struct {
+				Destination string `json:"Destination"`
+			}
+			if err := c.get(target, member.ODataID, &subscription); err != nil {
+				return "", err
+			}
+			if subscription.Destination == destination {
+				return member.ODataID, nil
+			}
+		}
+		return "", nil
+	}
+
+	uri, err := find()
+	if err != nil {
+		return "", err
+	}
+	if uri != "" {
+		if err := c.do(target, http.MethodPatch, uri, map[string]string{"Context": context}, nil); err != nil {
+			return "", err
+		}
+		return uri, nil
+	}
+	body := map[string]interface{}{
+		"Destination":      destination,
+		"Protocol":         "Redfish",
+		"SubscriptionType": "RedfishEvent",
+		"Context":          context,
+	}
+	for key, value := range properties {
+		body[key] = value
+	}
+	if err := c.do(target, http.MethodPost, subscriptionsURI, body, nil); err != nil {
+		return "", err
+	}
+	// Not every BMC returns the subscription or its location, so it is
+	// looked up like an existing one
+	uri, err = find()
+	if err == nil && uri == "" {
+		err = fmt.Errorf("subscription for %s not found after creating it", destination)
+	}
+	return uri, err
+}
+
+func (c *RealRedfishClient) Unsubscribe(target RedfishTarget, subscriptionURI string) error {
+	return c.do(target, http.MethodDelete, subscriptionURI, nil, nil)
+}
+
+func (c *RealRedfishClient) reset(target RedfishTarget, resetType string) error {
+	systemURI, err := c.systemURI(target)
+	if err != nil {
+		return err
+	}
+	return c.do(target, http.MethodPost, systemURI+"/Actions/ComputerSystem.Reset",
+		map[string]string{"ResetType": resetType}, nil)
+}
+
+// systemURI returns the URI of the target system, defaulting to the first
+// system exposed by the BMC.
+func (c *RealRedfishClient) systemURI(target RedfishTarget) (string, error) {
+	if target.SystemID != "" {
+		return "/redfish/v1/Systems/" + target.SystemID, nil
+	}
+
+	var systems redfishCollection
+	if err := c.get(target, "/redfish/v1/Systems", &systems); err != nil {
+		return "", err
+	}
+	if len(systems.Members) == 0 {
+		return "", fmt.Errorf("no systems found on BMC %s", target.Address)
+	}
+	return systems.Members[0].ODataID, nil
+}
+
+// chassisURI returns the URI of the chassis containing the target system,
+// defaulting to the first chassis exposed by the BMC.
+func (c *RealRedfishClient) chassisURI(target RedfishTarget) (string, error) {
+	systemURI, err := c.systemURI(target)
+	if err != nil {
+		return "", err
+	}
+
+	var system struct {
+		Links struct {
+			Chassis []redfishLink `json:"Chassis"`
+		} `json:"Links"`
+	}
+	if err := c.get(target, systemURI, &system); err != nil {
+		return "", err
+	}
+	if len(system.Links.Chassis) > 0 {
+		return system.Links.Chassis[0].ODataID, nil
+	}
+
+	var chassis redfishCollection
+	if err := c.get(target, "/redfish/v1/Chassis", &chassis); err != nil {
+		return "", err
+	}
+	if len(chassis.Members) == 0 {
+		return "", fmt.Errorf("no chassis found on BMC %s", target.Address)
+	}
+	return chassis.Members[0].ODataID, nil
+}
+
+// storageURI returns the URI of a storage controller, defaulting to the
+// first controller of the system.
+func (c *RealRedfishClient) storageURI(target RedfishTarget, storageID string) (string, error) {
+	systemURI, err := c.systemURI(target)
+	if err != nil {
+		return "", err
+	}
+	if storageID != "" {
+		return systemURI + "/Storage/" + storageID, nil
+	}
+
+	var storage redfishCollection
+	if err := c.get(target, systemURI+"/Storage", &storage); err != nil {
+		return "", err
+	}
+	if len(storage.Members) == 0 {
+		return "", fmt.Errorf("no storage controllers found on %s", systemURI)
+	}
+	return storage.Members[0].ODataID, nil
+}
+
+func (c *RealRedfishClient) get(target RedfishTarget, path string, out interface{}) error {
+	return c.do(target, http.MethodGet, path, nil, out)
+}
+
+// do sends a request under the retry policy
+func (c *RealRedfishClient) do(target RedfishTarget, method string, path string, body interface{}, out interface{}) error {
+	return retry(context.Background(), c.Retry, func() error {
+		return c.doOnce(target, method, path, body, out)
+	})
+}
+
+func (c *RealRedfishClient) doOnce(target RedfishTarget, method string, path string, body interface{}, out interface{}) error {
+	if target.Address == "" {
+		return fmt.Errorf("Redfish address is required")
+	}
+
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("unable to encode Redfish request: %w", err)
+		}
+	}
+
+	resp, err := c.send(target, method, path, data)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && c.SessionIdleTimeout > 0 {
+		// The BMC may have expired the session before we did
+		resp.Body.Close()
+		c.dropSession(target)
+		if resp, err = c.send(target, method, path, data); err != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read Redfish response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return classifyStatus(resp.StatusCode, fmt.Errorf("Redfish %s %s failed with status %d: %s",
+			method, path, resp.StatusCode, strings.TrimSpace(string(respBody))))
+	}
+
+	if out != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("unable to parse Redfish response: %w", err)
+		}
+	}
+	return nil
+}
+
+func (c *RealRedfishClient) send(target RedfishTarget, method string, path string, data []byte) (*http.Response, error) {
+	var reqBody io.Reader
+	if data != nil {
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, redfishBaseURL(target)+path, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create Redfish request: %w", err)
+	}
+	if err := c.authorize(req, target); err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return c.roundTrip(target, req)
+}
+
+// roundTrip sends req with the HTTP client for the target's TLS settings
+func (c *RealRedfishClient) roundTrip(target RedfishTarget, req *http.Request) (*http.Response, error) {
+	client, err := c.httpClient(target)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		var verifyErr *tls.CertificateVerificationError
+		if errors.As(err, &verifyErr) {
+			return nil, untrustedCertificate(fmt.Errorf("Redfish service certificate is not trusted: %w", err))
+		}
+		return nil, unreachable(fmt.Errorf("unable to reach Redfish service: %w", err))
+	}
+	return resp, nil
+}
+
+// httpClient returns HTTPClient or a client shared by all requests with the
+// same TLS settings, so TLS connections to a BMC are kept alive between
+// requests.
+func (c *RealRedfishClient) httpClient(target RedfishTarget) (*http.Client, error) {
+	if c.HTTPClient != nil {
+		return c.HTTPClient, nil
+	}
+
+	key := redfishTLSKey{
+		caBundle:           sha256.Sum256(target.TLS.CABundle),
+		serverName:         target.TLS.ServerName,
+		insecureSkipVerify: target.TLS.InsecureSkipVerify,
+	}
+	c.clientMu.Lock()
+	defer c.clientMu.Unlock()
+	if client, ok := c.clients[key]; ok {
+		return client, nil
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         target.TLS.ServerName,
+		InsecureSkipVerify: target.TLS.InsecureSkipVerify, //nolint:gosec
+	}
+	if len(target.TLS.CABundle) > 0 {
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(target.TLS.CABundle) {
+			return nil, fmt.Errorf("no PEM certificates found in the Redfish CA bundle")
+		}
+	}
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: tlsConfig,
+			IdleConnTimeout: 90 * time.Second,
+		},
+	}
+	if c.clients == nil {
+		c.clients = map[redfishTLSKey]*http.Client{}
+	}
+	c.clients[key] = client
+	return client, nil
+}
+
+type redfishLink struct {
+	ODataID string `json:"@odata.id"`
+}
+
+type redfishCollection struct {
+	Members []redfishLink `json:"Members"`
+}
+
+func lastPathSegment(uri string) string {
+	uri = strings.TrimSuffix(uri, "/")
+	return uri[strings.LastIndex(uri, "/")+1:]
+}
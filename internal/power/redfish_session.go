@@ -0,0 +1,170 @@
+package power
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const redfishSessionsPath = "/redfish/v1/SessionService/Sessions"
+
+type redfishSessionKey struct {
+	address  string
+	username string
+	password [sha256.Size]byte
+}
+
+// redfishSession is a SessionService login reused for requests to one BMC.
+// An empty token means the BMC has no usable SessionService and basic auth is
+// used instead.
+type redfishSession struct {
+	token    string
+	uri      string
+	tls      RedfishTLS
+	lastUsed time.Time
+}
+
+func sessionKey(target RedfishTarget) redfishSessionKey {
+	return redfishSessionKey{
+		address:  target.Address,
+		username: target.Username,
+		password: sha256.Sum256([]byte(target.Password)),
+	}
+}
+
+// authorize sets the session token on req, logging in if there is no
+// session yet. Without SessionIdleTimeout, or when the BMC doesn't support
+// sessions, basic auth is used.
+func (c *RealRedfishClient) authorize(req *http.Request, target RedfishTarget) error {
+	if c.SessionIdleTimeout <= 0 {
+		req.SetBasicAuth(target.Username, target.Password)
+		return nil
+	}
+
+	key := sessionKey(target)
+	c.mu.Lock()
+	c.expireSessions()
+	session, ok := c.sessions[key]
+	if ok {
+		session.lastUsed = time.Now()
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		var err error
+		session, err = c.login(target)
+		if err != nil {
+			return err
+		}
+		c.mu.Lock()
+		if c.sessions == nil {
+			c.sessions = map[redfishSessionKey]*redfishSession{}
+		}
+		c.sessions[key] = session
+		c.mu.Unlock()
+	}
+
+	if session.token == "" {
+		req.SetBasicAuth(target.Username, target.Password)
+	} else {
+		req.Header.Set("X-Auth-Token", session.token)
+	}
+	return nil
+}
+
+// dropSession forgets the session of a target, e.g. after the BMC expired it
+func (c *RealRedfishClient) dropSession(target RedfishTarget) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.sessions, sessionKey(target))
+}
+
+// login creates a session. BMCs without a SessionService get a session
+// without a token so they aren't asked again until it expires.
+func (c *RealRedfishClient) login(target RedfishTarget) (*redfishSession, error) {
+	body, err := json.Marshal(map[string]string{
+		"UserName": target.Username,
+		"Password": target.Password,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode Redfish session request: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, redfishBaseURL(target)+redfishSessionsPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create Redfish session request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.roundTrip(target, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed:
+		return &redfishSession{lastUsed: time.Now()}, nil
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return nil, classifyStatus(resp.StatusCode,
+			fmt.Errorf("Redfish login failed with status %d", resp.StatusCode))
+	}
+
+	token := resp.Header.Get("X-Auth-Token")
+	if token == "" {
+		return &redfishSession{lastUsed: time.Now()}, nil
+	}
+	return &redfishSession{
+		token:    token,
+		uri:      resp.Header.Get("Location"),
+		tls:      target.TLS,
+		lastUsed: time.Now(),
+	}, nil
+}
+
+// expireSessions logs out of sessions idle for longer than
+// SessionIdleTimeout. Callers must hold the lock.
+func (c *RealRedfishClient) expireSessions() {
+	for key, session := range c.sessions {
+		if time.Since(session.lastUsed) <= c.SessionIdleTimeout {
+			continue
+		}
+		delete(c.sessions, key)
+		if session.token != "" && session.uri != "" {
+			go c.logout(key.address, session)
+		}
+	}
+}
+
+// logout deletes a session, ignoring errors since the BMC expires it anyway
+func (c *RealRedfishClient) logout(address string, session *redfishSession) {
+	target := RedfishTarget{Address: address, TLS: session.tls}
+	uri := session.uri
+	if !strings.Contains(uri, "://") {
+		uri = redfishBaseURL(target) + uri
+	}
+	req, err := http.NewRequest(http.MethodDelete, uri, nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("X-Auth-Token", session.token)
+	resp, err := c.roundTrip(target, req)
+	if err == nil {
+		resp.Body.Close()
+	}
+}
+
+// redfishBaseURL returns the BMC URL without a trailing slash, defaulting
+// to https
+func redfishBaseURL(target RedfishTarget) string {
+	baseURL := target.Address
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "https://" + baseURL
+	}
+	return strings.TrimSuffix(baseURL, "/")
+}
@@ -0,0 +1,192 @@
+package power
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSessionBMC serves a system that requires a session token, or basic
+// auth if it has no SessionService
+type fakeSessionBMC struct {
+	sessionService bool
+
+	mu       sync.Mutex
+	logins   int
+	logouts  []string
+	tokens   map[string]bool
+	basicOKs int
+}
+
+func newFakeSessionBMC(t *testing.T, sessionService bool) (*fakeSessionBMC, RedfishTarget) {
+	t.Helper()
+	bmc := &fakeSessionBMC{sessionService: sessionService, tokens: map[string]bool{}}
+	server := httptest.NewServer(bmc)
+	t.Cleanup(server.Close)
+	return bmc, RedfishTarget{Address: server.URL, Username: "root", Password: "calvin", SystemID: "1"}
+}
+
+func (b *fakeSessionBMC) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case req.URL.Path == redfishSessionsPath && req.Method == http.MethodPost:
+		if !b.sessionService {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		b.logins++
+		token := fmt.Sprintf("token-%d", b.logins)
+		b.tokens[token] = true
+		w.Header().Set("X-Auth-Token", token)
+		w.Header().Set("Location", redfishSessionsPath+"/"+token)
+		w.WriteHeader(http.StatusCreated)
+
+	case req.Method == http.MethodDelete:
+		token := req.Header.Get("X-Auth-Token")
+		b.logouts = append(b.logouts, req.URL.Path)
+		delete(b.tokens, token)
+
+	case req.URL.Path == "/redfish/v1/Systems/1":
+		if user, password, ok := req.BasicAuth(); ok && user == "root" && password == "calvin" {
+			b.basicOKs++
+		} else if !b.tokens[req.Header.Get("X-Auth-Token")] {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"PowerState": "On"}`)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// expire drops every session, as a BMC does after its own timeout
+func (b *fakeSessionBMC) expire() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = map[string]bool{}
+}
+
+func (b *fakeSessionBMC) stats() (logins int, logouts []string, basicOKs int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.logins, append([]string(nil), b.logouts...), b.basicOKs
+}
+
+func getPower(t *testing.T, c *RealRedfishClient, target RedfishTarget) {
+	t.Helper()
+	on, err := c.GetPowerStatus(target)
+	if err != nil || !on {
+		t.Fatalf("GetPowerStatus() = %v, %v", on, err)
+	}
+}
+
+func TestRedfishSessionReuse(t *testing.T) {
+	bmc, target := newFakeSessionBMC(t, true)
+	c := &RealRedfishClient{SessionIdleTimeout: time.Minute, Retry: &RetryPolicy{Attempts: 1}}
+
+	getPower(t, c, target)
+	getPower(t, c, target)
+	if logins, _, basic := bmc.stats(); logins != 1 || basic != 0 {
+		t.Errorf("%d logins and %d basic auth requests, want one session", logins, basic)
+	}
+
+	// A session the BMC expired first is replaced transparently
+	bmc.expire()
+	getPower(t, c, target)
+	if logins, _, _ := bmc.stats(); logins != 2 {
+		t.Errorf("%d logins after the BMC expired the session, want 2", logins)
+	}
+
+	// Other credentials get their own session
+	other := target
+	other.Password = "changed"
+	if _, err := c.GetPowerStatus(other); err != nil {
+		t.Fatalf("GetPowerStatus() error = %v", err)
+	}
+	if logins, _, _ := bmc.stats(); logins != 3 {
+		t.Errorf("%d logins, want a session per credentials", logins)
+	}
+}
+
+func TestRedfishSessionIdleLogout(t *testing.T) {
+	bmc, target := newFakeSessionBMC(t, true)
+	c := &RealRedfishClient{SessionIdleTimeout: time.Minute, Retry: &RetryPolicy{Attempts: 1}}
+
+	getPower(t, c, target)
+	c.mu.Lock()
+	for _, session := range c.sessions {
+		session.lastUsed = time.Now().Add(-2 * time.Minute)
+	}
+	c.mu.Unlock()
+
+	getPower(t, c, target)
+	logins, logouts := 0, []string(nil)
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if logins, logouts, _ = bmc.stats(); len(logouts) > 0 {
+			break
+		}
+	}
+	if logins != 2 {
+		t.Errorf("%d logins, want the idle session replaced", logins)
+	}
+	if len(logouts) != 1 || logouts[0] != redfishSessionsPath+"/token-1" {
+		t.Errorf("logouts = %v, want the idle session deleted", logouts)
+	}
+}
+
+func TestRedfishSessionFallbacks(t *testing.T) {
+	// BMCs without a SessionService are asked once, then get basic auth
+	bmc, target := newFakeSessionBMC(t, false)
+	c := &RealRedfishClient{SessionIdleTimeout: time.Minute, Retry: &RetryPolicy{Attempts: 1}}
+	getPower(t, c, target)
+	getPower(t, c, target)
+	if _, _, basic := bmc.stats(); basic != 2 {
+		t.Errorf("%d basic auth requests, want 2", basic)
+	}
+	if session := c.sessions[sessionKey(target)]; session == nil || session.token != "" {
+		t.Errorf("session = %+v, want a session without token remembered", session)
+	}
+
+	// Without an idle timeout sessions are not used at all
+	bmc, target = newFakeSessionBMC(t, true)
+	c = &RealRedfishClient{Retry: &RetryPolicy{Attempts: 1}}
+	getPower(t, c, target)
+	if logins, _, basic := bmc.stats(); logins != 0 || basic != 1 {
+		t.Errorf("%d logins and %d basic auth requests, want basic auth", logins, basic)
+	}
+}
+
+func TestRedfishLoginFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	target := RedfishTarget{Address: server.URL, Username: "root", Password: "wrong", SystemID: "1"}
+
+	c := &RealRedfishClient{SessionIdleTimeout: time.Minute, Retry: &RetryPolicy{Attempts: 1}}
+	if _, err := c.GetPowerStatus(target); Classify(err) != ClassAuthFailure {
+		t.Errorf("GetPowerStatus() error = %v, want an authentication failure", err)
+	}
+	if len(c.sessions) != 0 {
+		t.Errorf("failed login remembered as a session")
+	}
+}
+
+func TestRedfishBaseURL(t *testing.T) {
+	tests := map[string]string{
+		"10.0.1.11":                 "https://10.0.1.11",
+		"10.0.1.11:8443/":           "https://10.0.1.11:8443",
+		"http://bmc-01.example.com": "http://bmc-01.example.com",
+	}
+	for address, want := range tests {
+		if got := redfishBaseURL(RedfishTarget{Address: address}); got != want {
+			t.Errorf("redfishBaseURL(%q) = %q, want %q", address, got, want)
+		}
+	}
+}
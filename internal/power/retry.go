@@ -0,0 +1,126 @@
+package power
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RetryPolicy says how the backends retry a failed call, by the class of its
+// error. Authentication failures are never retried, so a wrong password
+// doesn't lock out the BMC account.
+type RetryPolicy struct {
+	// Attempts is the number of times a call is made, including the first
+	Attempts int
+	// Backoff is the wait before the first retry, doubled for each retry
+	// after it
+	Backoff time.Duration
+	// MaxBackoff caps the wait between retries
+	MaxBackoff time.Duration
+	// Classes are the error classes that are retried
+	Classes []ErrorClass
+}
+
+// DefaultRetryPolicy returns the policy used by backends without one, which
+// retries temporary errors and unreachable backends twice.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Attempts:   3,
+		Backoff:    500 * time.Millisecond,
+		MaxBackoff: 5 * time.Second,
+		Classes:    []ErrorClass{ClassTransient, ClassUnreachable},
+	}
+}
+
+// BindFlags binds the retry policy to command line flags.
+// The prefix can be used to namespace the flags (e.g., "power-retry-").
+func (p *RetryPolicy) BindFlags(fs *flag.FlagSet, prefix string) {
+	fs.IntVar(&p.Attempts, prefix+"attempts", p.Attempts,
+		"Number of times a power backend call is made before its error is returned. 1 disables retries.")
+	fs.DurationVar(&p.Backoff, prefix+"backoff", p.Backoff,
+		"Wait before retrying a failed power backend call, doubled for each retry.")
+	fs.DurationVar(&p.MaxBackoff, prefix+"max-backoff", p.MaxBackoff,
+		"Longest wait between retries of a power backend call.")
+	fs.Func(prefix+"classes",
+		fmt.Sprintf("Comma-separated error classes of power backend calls that are retried, of Transient, Unreachable, Unsupported and Permanent. (default %q)", joinClasses(p.Classes)),
+		func(value string) error {
+			p.Classes = nil
+			for _, class := range strings.Split(value, ",") {
+				if class = strings.TrimSpace(class); class != "" {
+					p.Classes = append(p.Classes, ErrorClass(class))
+				}
+			}
+			return nil
+		})
+}
+
+// Validate validates the retry policy.
+func (p *RetryPolicy) Validate() error {
+	if p.Attempts < 1 {
+		return fmt.Errorf("retry attempts must be at least 1")
+	}
+	if p.Backoff < 0 || p.MaxBackoff < p.Backoff {
+		return fmt.Errorf("retry backoff must not be negative, with the maximum at least the backoff")
+	}
+	for _, class := range p.Classes {
+		switch class {
+		case ClassTransient, ClassUnreachable, ClassUnsupported, ClassPermanent:
+		case ClassAuthFailure:
+			return fmt.Errorf("authentication failures are never retried")
+		default:
+			return fmt.Errorf("unknown error class %q", class)
+		}
+	}
+	return nil
+}
+
+// retries reports whether the policy retries an error
+func (p *RetryPolicy) retries(err error) bool {
+	class := Classify(err)
+	if class == ClassAuthFailure {
+		return false
+	}
+	for _, retried := range p.Classes {
+		if retried == class {
+			return true
+		}
+	}
+	return false
+}
+
+// retry calls fn until it succeeds, fails with an error the policy doesn't
+// retry or runs out of attempts, and returns its last error. A nil policy
+// is the default one. Waiting between attempts stops once ctx is done.
+func retry(ctx context.Context, policy *RetryPolicy, fn func() error) error {
+	if policy == nil {
+		defaults := DefaultRetryPolicy()
+		policy = &defaults
+	}
+
+	backoff := policy.Backoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= policy.Attempts || !policy.retries(err) {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff = min(2*backoff, policy.MaxBackoff)
+	}
+}
+
+func joinClasses(classes []ErrorClass) string {
+	names := make([]string, 0, len(classes))
+	for _, class := range classes {
+		names = append(names, string(class))
+	}
+	return strings.Join(names, ",")
+}
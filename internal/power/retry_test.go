@@ -0,0 +1,126 @@
+package power
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  func(*RetryPolicy)
+		wantErr bool
+	}{
+		{name: "default", policy: func(*RetryPolicy) {}},
+		{name: "no retries", policy: func(p *RetryPolicy) { p.Attempts, p.Classes = 1, nil }},
+		{name: "no attempts", policy: func(p *RetryPolicy) { p.Attempts = 0 }, wantErr: true},
+		{name: "negative backoff", policy: func(p *RetryPolicy) { p.Backoff = -time.Second }, wantErr: true},
+		{name: "maximum below backoff", policy: func(p *RetryPolicy) { p.MaxBackoff = p.Backoff / 2 }, wantErr: true},
+		{name: "authentication failures", policy: func(p *RetryPolicy) { p.Classes = append(p.Classes, ClassAuthFailure) }, wantErr: true},
+		{name: "unknown class", policy: func(p *RetryPolicy) { p.Classes = []ErrorClass{"Busy"} }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := DefaultRetryPolicy()
+			tt.policy(&policy)
+			if err := policy.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBindFlags(t *testing.T) {
+	policy := DefaultRetryPolicy()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	policy.BindFlags(fs, "power-retry-")
+	if err := fs.Parse([]string{"--power-retry-attempts=5", "--power-retry-classes=Transient, Permanent,"}); err != nil {
+		t.Fatal(err)
+	}
+	if policy.Attempts != 5 || joinClasses(policy.Classes) != "Transient,Permanent" {
+		t.Errorf("policy = %+v", policy)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	policy := &RetryPolicy{Attempts: 3, Backoff: time.Millisecond, MaxBackoff: time.Millisecond, Classes: []ErrorClass{ClassTransient, ClassUnreachable}}
+	busy := transient(errors.New("503 Service Unavailable"))
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "success", errs: []error{nil}, wantCalls: 1},
+		{name: "transient then success", errs: []error{busy, nil}, wantCalls: 2},
+		{name: "unreachable until out of attempts", errs: []error{unreachable(errors.New("timeout"))}, wantCalls: 3, wantErr: ErrUnreachable},
+		{name: "authentication failure", errs: []error{authFailed(errors.New("401 Unauthorized"))}, wantCalls: 1, wantErr: ErrAuth},
+		{name: "untrusted certificate", errs: []error{untrustedCertificate(errors.New("x509"))}, wantCalls: 1, wantErr: ErrCertificate},
+		{name: "not retried class", errs: []error{unsupported(errors.New("405 Method Not Allowed"))}, wantCalls: 1, wantErr: ErrUnsupported},
+		{name: "unclassified", errs: []error{errors.New("bad request")}, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := retry(context.Background(), policy, func() error {
+				calls++
+				return tt.errs[min(calls, len(tt.errs))-1]
+			})
+			if calls != tt.wantCalls {
+				t.Errorf("%d calls, want %d", calls, tt.wantCalls)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("retry() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.errs[len(tt.errs)-1] == nil && err != nil {
+				t.Errorf("retry() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestRetryDefaultPolicy(t *testing.T) {
+	calls := 0
+	err := retry(context.Background(), nil, func() error {
+		calls++
+		if calls == 1 {
+			return transient(errors.New("busy"))
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("retry() = %v after %d calls, want the default policy to retry", err, calls)
+	}
+}
+
+func TestRetryStopsWhenCanceled(t *testing.T) {
+	policy := &RetryPolicy{Attempts: 3, Backoff: time.Hour, MaxBackoff: time.Hour, Classes: []ErrorClass{ClassTransient}}
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	start := time.Now()
+	err := retry(ctx, policy, func() error {
+		calls++
+		cancel()
+		return transient(errors.New("busy"))
+	})
+	if calls != 1 || !errors.Is(err, ErrTransient) {
+		t.Errorf("retry() = %v after %d calls, want the first error", err, calls)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("retry() took %s, want it to stop waiting when canceled", elapsed)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	// Waits double from 10ms and are capped at 20ms, 50ms in total
+	policy := &RetryPolicy{Attempts: 4, Backoff: 10 * time.Millisecond, MaxBackoff: 20 * time.Millisecond, Classes: []ErrorClass{ClassTransient}}
+	start := time.Now()
+	_ = retry(context.Background(), policy, func() error { return transient(errors.New("busy")) })
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > 5*time.Second {
+		t.Errorf("retry() took %s, want about 50ms", elapsed)
+	}
+}
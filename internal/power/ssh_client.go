@@ -1,22 +1,92 @@
 package power
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/ssh"
 )
 
-type RealSSHClient struct{}
+type RealSSHClient struct {
+	// Retry is the policy for retrying failed calls, DefaultRetryPolicy
+	// if nil
+	Retry *RetryPolicy
+}
+
+func (s *RealSSHClient) Shutdown(ctx context.Context, host string, user string, key string) error {
+	return s.runShutdown(ctx, host, user, key, "sudo shutdown -h now")
+}
+
+func (s *RealSSHClient) Reboot(ctx context.Context, host string, user string, key string) error {
+	return s.runShutdown(ctx, host, user, key, "sudo shutdown -r now")
+}
+
+// runShutdown runs a command that takes the host down, which drops the
+// connection
+func (s *RealSSHClient) runShutdown(ctx context.Context, host string, user string, key string, cmd string) error {
+	session, err := s.session(ctx, host, user, key)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	// The connection won't survive the shutdown
+	defer sshConnections.forget(host, user, key)
+
+	err = runSession(ctx, session, cmd)
+	if err != nil {
+		// Connection drop during shutdown is expected
+		if _, ok := err.(*ssh.ExitMissingError); ok {
+			return nil
+		}
+		return fmt.Errorf("unable to execute %q: %w", cmd, err)
+	}
+
+	return nil
+}
 
-func (s *RealSSHClient) Shutdown(host string, user string, key string) error {
+// session opens a pooled session under the retry policy. Commands aren't
+// retried, as they may have run before failing.
+func (s *RealSSHClient) session(ctx context.Context, host string, user string, key string) (*ssh.Session, error) {
+	var session *ssh.Session
+	err := retry(ctx, s.Retry, func() error {
+		var err error
+		session, err = sshConnections.session(ctx, host, user, key)
+		return err
+	})
+	return session, err
+}
+
+// runSession runs a command, closing the session if ctx is done first so
+// the command doesn't outlive its caller
+func runSession(ctx context.Context, session *ssh.Session, cmd string) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Run(cmd)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		session.Close()
+		return ctx.Err()
+	}
+}
+
+// dialSSH connects to host with a private key, defaulting to port 22. The
+// TCP connect and the handshake are bounded by sshDialTimeout each, and by
+// ctx.
+func dialSSH(ctx context.Context, host string, user string, key string) (*ssh.Client, error) {
 	if key == "" {
-		return fmt.Errorf("SSH private key is required")
+		return nil, fmt.Errorf("SSH private key is required")
 	}
 
 	signer, err := ssh.ParsePrivateKey([]byte(key))
 	if err != nil {
-		return fmt.Errorf("unable to parse private key: %w", err)
+		return nil, fmt.Errorf("unable to parse private key: %w", err)
 	}
 
 	config := &ssh.ClientConfig{
@@ -25,29 +95,53 @@ func (s *RealSSHClient) Shutdown(host string, user string, key string) error {
 			ssh.PublicKeys(signer),
 		},
 		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         10 * time.Second,
+		Timeout:         sshDialTimeout,
 	}
 
-	client, err := ssh.Dial("tcp", host, config)
+	host = sshHostPort(host)
+	client, err := dialSSHContext(ctx, host, config)
 	if err != nil {
-		return fmt.Errorf("unable to connect to SSH server: %w", err)
+		err = fmt.Errorf("unable to connect to SSH server: %w", err)
+		// The handshake reports rejected keys as a plain error, while
+		// network failures surface as net.Error
+		var netErr net.Error
+		if errors.As(err, &netErr) {
+			return nil, unreachable(err)
+		}
+		if strings.Contains(err.Error(), "unable to authenticate") {
+			return nil, authFailed(err)
+		}
+		return nil, err
 	}
-	defer client.Close()
+	return client, nil
+}
+
+// dialSSHContext is ssh.Dial with a deadline on the handshake, which
+// ssh.Dial leaves unbounded for hosts that accept but never answer.
+func dialSSHContext(ctx context.Context, host string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	ctx, cancel := context.WithTimeout(ctx, sshDialTimeout)
+	defer cancel()
 
-	session, err := client.NewSession()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", host)
 	if err != nil {
-		return fmt.Errorf("unable to create SSH session: %w", err)
+		return nil, err
 	}
-	defer session.Close()
 
-	err = session.Run("sudo shutdown -h now")
+	// The handshake shares the deadline of the connect
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, host, config)
 	if err != nil {
-		// Connection drop during shutdown is expected
-		if _, ok := err.(*ssh.ExitMissingError); ok {
-			return nil
-		}
-		return fmt.Errorf("unable to execute shutdown command: %w", err)
+		conn.Close()
+		return nil, err
 	}
-
-	return nil
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return ssh.NewClient(c, chans, reqs), nil
 }
@@ -0,0 +1,131 @@
+package power
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// sshIdleTimeout closes pooled connections that haven't been used
+	sshIdleTimeout = 5 * time.Minute
+	// sshDialTimeout bounds the TCP connect and the SSH handshake each
+	sshDialTimeout = 10 * time.Second
+)
+
+// sshConnections is shared by all SSH based backends, so shutdowns, LLDP
+// collection and attestation against the same host reuse one connection.
+var sshConnections = &sshPool{}
+
+// sshPool keeps SSH connections open between commands, keyed by host, user
+// and private key, so repeated commands don't pay for a handshake each time.
+type sshPool struct {
+	mu      sync.Mutex
+	clients map[sshPoolKey]*pooledSSHClient
+}
+
+type sshPoolKey struct {
+	host string
+	user string
+	key  [sha256.Size]byte
+}
+
+type pooledSSHClient struct {
+	client   *ssh.Client
+	lastUsed time.Time
+}
+
+// session opens a session on a pooled connection, dialing a new one if
+// there is none or the pooled one has gone away.
+func (p *sshPool) session(ctx context.Context, host string, user string, key string) (*ssh.Session, error) {
+	host = sshHostPort(host)
+	poolKey := sshPoolKey{host: host, user: user, key: sha256.Sum256([]byte(key))}
+
+	if client := p.get(poolKey); client != nil {
+		if session, err := client.NewSession(); err == nil {
+			return session, nil
+		}
+		p.discard(poolKey, client)
+	}
+
+	client, err := dialSSH(ctx, host, user, key)
+	if err != nil {
+		return nil, err
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("unable to create SSH session: %w", err)
+	}
+	p.put(poolKey, client)
+	return session, nil
+}
+
+// forget closes the pooled connection to a host, e.g. after shutting it down
+func (p *sshPool) forget(host string, user string, key string) {
+	poolKey := sshPoolKey{host: sshHostPort(host), user: user, key: sha256.Sum256([]byte(key))}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pooled, ok := p.clients[poolKey]; ok {
+		pooled.client.Close()
+		delete(p.clients, poolKey)
+	}
+}
+
+func (p *sshPool) get(poolKey sshPoolKey) *ssh.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.expire()
+
+	pooled, ok := p.clients[poolKey]
+	if !ok {
+		return nil
+	}
+	pooled.lastUsed = time.Now()
+	return pooled.client
+}
+
+func (p *sshPool) put(poolKey sshPoolKey, client *ssh.Client) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.clients == nil {
+		p.clients = map[sshPoolKey]*pooledSSHClient{}
+	}
+	// Another command may have dialed the same host concurrently
+	if pooled, ok := p.clients[poolKey]; ok && pooled.client != client {
+		pooled.client.Close()
+	}
+	p.clients[poolKey] = &pooledSSHClient{client: client, lastUsed: time.Now()}
+}
+
+func (p *sshPool) discard(poolKey sshPoolKey, client *ssh.Client) {
+	client.Close()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pooled, ok := p.clients[poolKey]; ok && pooled.client == client {
+		delete(p.clients, poolKey)
+	}
+}
+
+// expire closes idle connections. Callers must hold the lock.
+func (p *sshPool) expire() {
+	for poolKey, pooled := range p.clients {
+		if time.Since(pooled.lastUsed) > sshIdleTimeout {
+			pooled.client.Close()
+			delete(p.clients, poolKey)
+		}
+	}
+}
+
+// sshHostPort defaults host to port 22
+func sshHostPort(host string) string {
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		return net.JoinHostPort(host, "22")
+	}
+	return host
+}
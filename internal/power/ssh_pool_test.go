@@ -0,0 +1,277 @@
+package power
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/pem"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// testSSHServer accepts one user's key and runs every command successfully,
+// recording the handshakes and commands
+type testSSHServer struct {
+	address string
+	key     string
+
+	mu         sync.Mutex
+	handshakes int
+	commands   []string
+	conns      []ssh.Conn
+}
+
+func newTestSSHServer(t *testing.T) *testSSHServer {
+	t.Helper()
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userPublic, userKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorized, err := ssh.NewPublicKey(userPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(userKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if meta.User() == "ops" && bytes.Equal(key.Marshal(), authorized.Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("unknown key")
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &testSSHServer{address: listener.Addr().String(), key: string(pem.EncodeToMemory(block))}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn, config)
+		}
+	}()
+	t.Cleanup(s.closeConnections)
+	return s
+}
+
+func (s *testSSHServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	serverConn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	s.mu.Lock()
+	s.handshakes++
+	s.conns = append(s.conns, serverConn)
+	s.mu.Unlock()
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			defer channel.Close()
+			for req := range requests {
+				if req.Type != "exec" {
+					_ = req.Reply(false, nil)
+					continue
+				}
+				var exec struct{ Command string }
+				_ = ssh.Unmarshal(req.Payload, &exec)
+				s.mu.Lock()
+				s.commands = append(s.commands, exec.Command)
+				s.mu.Unlock()
+				_ = req.Reply(true, nil)
+				_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+				return
+			}
+		}()
+	}
+}
+
+// closeConnections drops every connection, as a rebooting host would
+func (s *testSSHServer) closeConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+func (s *testSSHServer) stats() (handshakes int, commands []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.handshakes, append([]string(nil), s.commands...)
+}
+
+func runPooled(t *testing.T, p *sshPool, address string, user string, key string) {
+	t.Helper()
+	session, err := p.session(context.Background(), address, user, key)
+	if err != nil {
+		t.Fatalf("session() error = %v", err)
+	}
+	defer session.Close()
+	if err := runSession(context.Background(), session, "uptime"); err != nil {
+		t.Fatalf("runSession() error = %v", err)
+	}
+}
+
+func TestSSHPoolReusesConnections(t *testing.T) {
+	server := newTestSSHServer(t)
+	p := &sshPool{}
+	defer p.forget(server.address, "ops", server.key)
+
+	runPooled(t, p, server.address, "ops", server.key)
+	runPooled(t, p, server.address, "ops", server.key)
+	if handshakes, commands := server.stats(); handshakes != 1 || len(commands) != 2 {
+		t.Errorf("%d handshakes for %d commands, want one connection", handshakes, len(commands))
+	}
+
+	// A connection the host dropped is replaced
+	server.closeConnections()
+	runPooled(t, p, server.address, "ops", server.key)
+	if handshakes, _ := server.stats(); handshakes != 2 {
+		t.Errorf("%d handshakes after the host dropped the connection, want 2", handshakes)
+	}
+
+	p.forget(server.address, "ops", server.key)
+	runPooled(t, p, server.address, "ops", server.key)
+	if handshakes, _ := server.stats(); handshakes != 3 {
+		t.Errorf("%d handshakes after forget(), want 3", handshakes)
+	}
+}
+
+func TestSSHPoolExpiresIdleConnections(t *testing.T) {
+	server := newTestSSHServer(t)
+	p := &sshPool{}
+	defer p.forget(server.address, "ops", server.key)
+
+	runPooled(t, p, server.address, "ops", server.key)
+	p.mu.Lock()
+	for _, pooled := range p.clients {
+		pooled.lastUsed = time.Now().Add(-sshIdleTimeout - time.Second)
+	}
+	p.mu.Unlock()
+
+	runPooled(t, p, server.address, "ops", server.key)
+	if handshakes, _ := server.stats(); handshakes != 2 {
+		t.Errorf("%d handshakes, want the idle connection replaced", handshakes)
+	}
+	if len(p.clients) != 1 {
+		t.Errorf("pool holds %d connections, want 1", len(p.clients))
+	}
+}
+
+func TestDialSSHErrors(t *testing.T) {
+	server := newTestSSHServer(t)
+	ctx := context.Background()
+
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(otherKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dialSSH(ctx, server.address, "ops", string(pem.EncodeToMemory(block))); Classify(err) != ClassAuthFailure {
+		t.Errorf("dialSSH() with another key error = %v, want an authentication failure", err)
+	}
+	if _, err := dialSSH(ctx, server.address, "ops", ""); err == nil {
+		t.Errorf("dialSSH() succeeded without a key")
+	}
+	if _, err := dialSSH(ctx, server.address, "ops", "not a key"); err == nil {
+		t.Errorf("dialSSH() succeeded with a malformed key")
+	}
+}
+
+func TestDialSSHBoundsHandshake(t *testing.T) {
+	// A host that accepts connections but never answers
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	server := newTestSSHServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = dialSSH(ctx, listener.Addr().String(), "ops", server.key)
+	if Classify(err) != ClassUnreachable {
+		t.Errorf("dialSSH() error = %v, want unreachable", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("dialSSH() took %s, want it bounded by the context", elapsed)
+	}
+}
+
+func TestRealSSHClientShutdown(t *testing.T) {
+	server := newTestSSHServer(t)
+	c := &RealSSHClient{Retry: &RetryPolicy{Attempts: 1}}
+
+	if err := c.Shutdown(context.Background(), server.address, "ops", server.key); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if _, commands := server.stats(); len(commands) != 1 || commands[0] != "sudo shutdown -h now" {
+		t.Errorf("commands = %v", commands)
+	}
+	// The connection to the host going down isn't kept
+	if client := sshConnections.get(sshPoolKey{host: server.address, user: "ops", key: sha256.Sum256([]byte(server.key))}); client != nil {
+		t.Errorf("connection still pooled after shutdown")
+	}
+}
+
+func TestSSHHostPort(t *testing.T) {
+	tests := map[string]string{
+		"10.0.0.11":       "10.0.0.11:22",
+		"10.0.0.11:2222":  "10.0.0.11:2222",
+		"worker-01":       "worker-01:22",
+		"fd00::11":        "[fd00::11]:22",
+		"[fd00::11]:2222": "[fd00::11]:2222",
+	}
+	for host, want := range tests {
+		if got := sshHostPort(host); got != want {
+			t.Errorf("sshHostPort(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
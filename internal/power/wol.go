@@ -1,6 +1,7 @@
 package power
 
 import (
+	"context"
 	"fmt"
 	"net"
 )
@@ -8,9 +9,12 @@ import (
 type RealWolSender struct {
 	DefaultPort             int
 	DefaultBroadcastAddress string
+	// Retry is the policy for retrying failed calls, DefaultRetryPolicy
+	// if nil
+	Retry *RetryPolicy
 }
 
-func (w *RealWolSender) Wake(macAddress string, port int, broadcastAddress string) error {
+func (w *RealWolSender) Wake(ctx context.Context, macAddress string, port int, broadcastAddress string) error {
 	// Implementation to send Wake-on-LAN magic packet
 	mac, err := net.ParseMAC(macAddress)
 	if err != nil {
@@ -32,15 +36,24 @@ func (w *RealWolSender) Wake(macAddress string, port int, broadcastAddress strin
 		port = w.DefaultPort
 	}
 
-	conn, err := net.Dial("udp", broadcastAddress+fmt.Sprintf(":%d", port))
+	return retry(ctx, w.Retry, func() error {
+		return sendPacket(ctx, broadcastAddress+fmt.Sprintf(":%d", port), packet)
+	})
+}
+
+// sendPacket sends a magic packet once. Failing to send it means the
+// network is unreachable, as UDP doesn't wait for an answer.
+func sendPacket(ctx context.Context, address string, packet []byte) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", address)
 	if err != nil {
-		return fmt.Errorf("failed to dial UDP broadcast: %w", err)
+		return unreachable(fmt.Errorf("failed to dial UDP broadcast: %w", err))
 	}
 	defer conn.Close()
 
 	_, err = conn.Write(packet)
 	if err != nil {
-		return fmt.Errorf("failed to send magic packet: %w", err)
+		return unreachable(fmt.Errorf("failed to send magic packet: %w", err))
 	}
 
 	return nil
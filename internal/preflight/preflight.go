@@ -0,0 +1,179 @@
+// Package preflight verifies that the host the controller runs on can reach
+// servers the way the power backends expect: raw ICMP sockets for
+// reachability checks, a broadcast-capable interface for Wake-on-LAN, and
+// membership in the server subnets. This mostly matters when running outside
+// the cluster, e.g. at edge sites, where nothing else checks the host.
+package preflight
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Options contains configuration for the preflight checks.
+type Options struct {
+	// Enabled runs the checks at startup and on every health check
+	Enabled bool
+
+	// Interface is the interface Wake-on-LAN broadcasts go out of. Empty
+	// accepts any interface that can broadcast.
+	Interface string
+
+	// Subnets are CIDRs the host must have an address in, comma separated
+	Subnets string
+}
+
+// DefaultOptions enables the checks when running outside a cluster.
+func DefaultOptions() Options {
+	return Options{
+		Enabled: os.Getenv("KUBERNETES_SERVICE_HOST") == "",
+	}
+}
+
+// BindFlags binds the preflight options to command line flags.
+// The prefix can be used to namespace the flags (e.g., "preflight-").
+func (o *Options) BindFlags(fs *flag.FlagSet, prefix string) {
+	fs.BoolVar(&o.Enabled, prefix+"enabled", o.Enabled,
+		"Check raw sockets, broadcast interfaces and subnets, and report failures through healthz. "+
+			"Enabled by default outside a cluster.")
+	fs.StringVar(&o.Interface, prefix+"interface", o.Interface,
+		"Interface that must be able to send Wake-on-LAN broadcasts. Empty for any.")
+	fs.StringVar(&o.Subnets, prefix+"subnets", o.Subnets,
+		"Comma separated CIDRs the host must have an address in, e.g. the server subnets.")
+}
+
+// Validate validates the options.
+func (o *Options) Validate() error {
+	_, err := o.subnets()
+	return err
+}
+
+func (o *Options) subnets() ([]*net.IPNet, error) {
+	var subnets []*net.IPNet
+	for _, cidr := range strings.Split(o.Subnets, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		_, subnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid preflight subnet: %w", err)
+		}
+		subnets = append(subnets, subnet)
+	}
+	return subnets, nil
+}
+
+// Checker runs the preflight checks.
+type Checker struct {
+	options Options
+	subnets []*net.IPNet
+}
+
+// NewChecker creates a checker for validated options.
+func NewChecker(opts Options) (*Checker, error) {
+	subnets, err := opts.subnets()
+	if err != nil {
+		return nil, err
+	}
+	return &Checker{options: opts, subnets: subnets}, nil
+}
+
+// Check runs all checks and joins their errors. It has the signature of a
+// healthz.Checker, so failures show up on the health probe.
+func (c *Checker) Check(_ *http.Request) error {
+	var errs []error
+	if err := checkRawSocket(); err != nil {
+		errs = append(errs, err)
+	}
+
+	addrs, err := interfaceAddrs()
+	if err != nil {
+		return fmt.Errorf("unable to list network interfaces: %w", err)
+	}
+	if err := c.checkBroadcast(addrs); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.checkSubnets(addrs); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// checkRawSocket opens the same kind of socket the pinger uses
+func checkRawSocket() error {
+	conn, err := net.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		return fmt.Errorf("raw ICMP sockets are unavailable, run as root or grant CAP_NET_RAW: %w", err)
+	}
+	return conn.Close()
+}
+
+func (c *Checker) checkBroadcast(addrs []interfaceAddr) error {
+	for _, a := range addrs {
+		if c.options.Interface != "" && a.iface.Name != c.options.Interface {
+			continue
+		}
+		if a.iface.Flags&net.FlagUp != 0 && a.iface.Flags&net.FlagBroadcast != 0 && a.ip.To4() != nil {
+			return nil
+		}
+	}
+	if c.options.Interface != "" {
+		return fmt.Errorf("interface %s is not up with an IPv4 broadcast address, Wake-on-LAN will not work", c.options.Interface)
+	}
+	return fmt.Errorf("no interface is up with an IPv4 broadcast address, Wake-on-LAN will not work")
+}
+
+func (c *Checker) checkSubnets(addrs []interfaceAddr) error {
+	var missing []string
+	for _, subnet := range c.subnets {
+		found := false
+		for _, a := range addrs {
+			if a.iface.Flags&net.FlagUp != 0 && subnet.Contains(a.ip) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, subnet.String())
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("host has no address in subnets %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+type interfaceAddr struct {
+	iface net.Interface
+	ip    net.IP
+}
+
+func interfaceAddrs() ([]interfaceAddr, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var addrs []interfaceAddr
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		ifaceAddrs, err := iface.Addrs()
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range ifaceAddrs {
+			if ipNet, ok := addr.(*net.IPNet); ok {
+				addrs = append(addrs, interfaceAddr{iface: iface, ip: ipNet.IP})
+			}
+		}
+	}
+	return addrs, nil
+}
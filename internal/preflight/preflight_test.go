@@ -0,0 +1,97 @@
+package preflight
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func addr(name string, flags net.Flags, ip string) interfaceAddr {
+	return interfaceAddr{iface: net.Interface{Name: name, Flags: flags}, ip: net.ParseIP(ip)}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		subnets string
+		wantErr bool
+	}{
+		{subnets: ""},
+		{subnets: "10.0.0.0/24"},
+		{subnets: " 10.0.0.0/24, ,fd00::/64 "},
+		{subnets: "10.0.0.0", wantErr: true},
+		{subnets: "10.0.0.0/24,lab", wantErr: true},
+	}
+	for _, tt := range tests {
+		opts := Options{Subnets: tt.subnets}
+		if err := opts.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%q) error = %v, wantErr %v", tt.subnets, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckBroadcast(t *testing.T) {
+	up := net.FlagUp | net.FlagBroadcast
+	tests := []struct {
+		name    string
+		iface   string
+		addrs   []interfaceAddr
+		wantErr string
+	}{
+		{name: "any interface", addrs: []interfaceAddr{addr("eth0", up, "10.0.0.5")}},
+		{
+			name:  "named interface",
+			iface: "eth1",
+			addrs: []interfaceAddr{addr("eth0", up, "10.0.0.5"), addr("eth1", up, "10.0.1.5")},
+		},
+		{name: "named interface missing", iface: "eth1", addrs: []interfaceAddr{addr("eth0", up, "10.0.0.5")}, wantErr: "interface eth1"},
+		{name: "interface down", addrs: []interfaceAddr{addr("eth0", net.FlagBroadcast, "10.0.0.5")}, wantErr: "no interface"},
+		{name: "point to point", addrs: []interfaceAddr{addr("wg0", net.FlagUp, "10.0.0.5")}, wantErr: "no interface"},
+		{name: "IPv6 only", addrs: []interfaceAddr{addr("eth0", up, "fd00::5")}, wantErr: "no interface"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewChecker(Options{Interface: tt.iface})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = c.checkBroadcast(tt.addrs)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkBroadcast() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkBroadcast() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckSubnets(t *testing.T) {
+	c, err := NewChecker(Options{Subnets: "10.0.0.0/24,10.0.1.0/24,fd00::/64"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addrs := []interfaceAddr{
+		addr("eth0", net.FlagUp, "10.0.0.5"),
+		addr("eth1", 0, "10.0.1.5"),
+		addr("eth0", net.FlagUp, "fd00::5"),
+	}
+	// Addresses on interfaces that are down don't count
+	err = c.checkSubnets(addrs)
+	if err == nil || !strings.Contains(err.Error(), "10.0.1.0/24") || strings.Contains(err.Error(), "10.0.0.0/24") {
+		t.Errorf("checkSubnets() error = %v, want only 10.0.1.0/24 missing", err)
+	}
+
+	addrs[1].iface.Flags = net.FlagUp
+	if err := c.checkSubnets(addrs); err != nil {
+		t.Errorf("checkSubnets() error = %v", err)
+	}
+}
+
+func TestNewCheckerRejectsInvalidSubnets(t *testing.T) {
+	if _, err := NewChecker(Options{Subnets: "10.0.0.0/33"}); err == nil {
+		t.Errorf("NewChecker() succeeded with an invalid subnet")
+	}
+}
@@ -0,0 +1,198 @@
+package pricing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAwattarURL = "https://api.awattar.de/v1/marketdata"
+	defaultTibberURL  = "https://api.tibber.com/v1-beta/gql"
+
+	// retryInterval is how long to wait after a failed fetch
+	retryInterval = 5 * time.Minute
+)
+
+// slot is a price that applies from start until end
+type slot struct {
+	start time.Time
+	end   time.Time
+	price float64
+}
+
+// slotProvider serves prices from slots fetched from an API. Slots are
+// fetched again once none covers the requested time, at most once per
+// retryInterval.
+type slotProvider struct {
+	fetch func(ctx context.Context) ([]slot, error)
+
+	mu      sync.Mutex
+	slots   []slot
+	retryAt time.Time
+	lastErr error
+}
+
+// Price implements Provider.
+func (p *slotProvider) Price(ctx context.Context, at time.Time) (float64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if price, ok := p.lookup(at); ok {
+		return price, nil
+	}
+	if at.Before(p.retryAt) {
+		if p.lastErr != nil {
+			return 0, p.lastErr
+		}
+		return 0, fmt.Errorf("no price known for %s", at.Format(time.RFC3339))
+	}
+
+	p.retryAt = at.Add(retryInterval)
+	slots, err := p.fetch(ctx)
+	p.lastErr = err
+	if err != nil {
+		return 0, err
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i].start.Before(slots[j].start) })
+	p.slots = slots
+	if price, ok := p.lookup(at); ok {
+		return price, nil
+	}
+	return 0, fmt.Errorf("no price known for %s", at.Format(time.RFC3339))
+}
+
+func (p *slotProvider) lookup(at time.Time) (float64, bool) {
+	for _, s := range p.slots {
+		if !at.Before(s.start) && at.Before(s.end) {
+			return s.price, true
+		}
+	}
+	return 0, false
+}
+
+// do sends a request and decodes the JSON response into out
+func do(req *http.Request, out interface{}) error {
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("API %s returned status %d: %s", req.URL.Host, resp.StatusCode, string(body))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response from %s: %w", req.URL.Host, err)
+	}
+	return nil
+}
+
+// newAwattar returns the day-ahead market prices of aWATTar, in Eur/MWh.
+// The API returns the prices from the current hour on and needs no token.
+func newAwattar(url string) Provider {
+	if url == "" {
+		url = defaultAwattarURL
+	}
+	return &slotProvider{fetch: func(ctx context.Context) ([]slot, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		var response struct {
+			Data []struct {
+				StartTimestamp int64   `json:"start_timestamp"`
+				EndTimestamp   int64   `json:"end_timestamp"`
+				MarketPrice    float64 `json:"marketprice"`
+			} `json:"data"`
+		}
+		if err := do(req, &response); err != nil {
+			return nil, err
+		}
+		slots := make([]slot, 0, len(response.Data))
+		for _, d := range response.Data {
+			slots = append(slots, slot{
+				start: time.UnixMilli(d.StartTimestamp),
+				end:   time.UnixMilli(d.EndTimestamp),
+				price: d.MarketPrice,
+			})
+		}
+		return slots, nil
+	}}
+}
+
+// tibberQuery reads today's and tomorrow's prices of the first home
+const tibberQuery = `{ viewer { homes { currentSubscription { priceInfo { ` +
+	`today { total startsAt } tomorrow { total startsAt } } } } } }`
+
+// newTibber returns the prices of the first home of a Tibber account,
+// including taxes, in its currency per kWh
+func newTibber(url string, token string) Provider {
+	if url == "" {
+		url = defaultTibberURL
+	}
+	return &slotProvider{fetch: func(ctx context.Context) ([]slot, error) {
+		body, err := json.Marshal(map[string]string{"query": tibberQuery})
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		type price struct {
+			Total    float64   `json:"total"`
+			StartsAt time.Time `json:"startsAt"`
+		}
+		var response struct {
+			Data struct {
+				Viewer struct {
+					Homes []struct {
+						CurrentSubscription *struct {
+							PriceInfo struct {
+								Today    []price `json:"today"`
+								Tomorrow []price `json:"tomorrow"`
+							} `json:"priceInfo"`
+						} `json:"currentSubscription"`
+					} `json:"homes"`
+				} `json:"viewer"`
+			} `json:"data"`
+			Errors []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		}
+		if err := do(req, &response); err != nil {
+			return nil, err
+		}
+		if len(response.Errors) > 0 {
+			return nil, fmt.Errorf("tibber API returned an error: %s", response.Errors[0].Message)
+		}
+		homes := response.Data.Viewer.Homes
+		if len(homes) == 0 || homes[0].CurrentSubscription == nil {
+			return nil, fmt.Errorf("tibber account has no home with a subscription")
+		}
+
+		info := homes[0].CurrentSubscription.PriceInfo
+		prices := append(info.Today, info.Tomorrow...)
+		slots := make([]slot, 0, len(prices))
+		for i, p := range prices {
+			// Each price lasts until the next one, the last one an hour
+			end := p.StartsAt.Add(time.Hour)
+			if i+1 < len(prices) {
+				end = prices[i+1].StartsAt
+			}
+			slots = append(slots, slot{start: p.StartsAt, end: end, price: p.Total})
+		}
+		return slots, nil
+	}}
+}
@@ -0,0 +1,138 @@
+package pricing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSlotProvider(t *testing.T) {
+	start := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	fetches := 0
+	var fetchErr error
+	p := &slotProvider{fetch: func(context.Context) ([]slot, error) {
+		fetches++
+		if fetchErr != nil {
+			return nil, fetchErr
+		}
+		// Out of order, as an API might return them
+		return []slot{
+			{start: start.Add(time.Hour), end: start.Add(2 * time.Hour), price: 0.2},
+			{start: start, end: start.Add(time.Hour), price: 0.1},
+		}, nil
+	}}
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		at   time.Time
+		want float64
+	}{
+		{at: start, want: 0.1},
+		{at: start.Add(59 * time.Minute), want: 0.1},
+		{at: start.Add(time.Hour), want: 0.2},
+	} {
+		if got, err := p.Price(ctx, tt.at); err != nil || got != tt.want {
+			t.Errorf("Price(%s) = %v, %v, want %v", tt.at.Format("15:04"), got, err, tt.want)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("%d fetches, want prices reused while they cover the time", fetches)
+	}
+
+	// Past the known slots prices are fetched again, at most once per
+	// retry interval
+	later := start.Add(2 * time.Hour)
+	if _, err := p.Price(ctx, later); err == nil {
+		t.Errorf("Price() succeeded without a slot")
+	}
+	fetchErr = errors.New("connection refused")
+	if _, err := p.Price(ctx, later.Add(time.Minute)); err == nil {
+		t.Errorf("Price() succeeded without a slot")
+	}
+	if fetches != 2 {
+		t.Errorf("%d fetches, want 2 within the retry interval", fetches)
+	}
+	if _, err := p.Price(ctx, later.Add(retryInterval)); !errors.Is(err, fetchErr) {
+		t.Errorf("Price() error = %v, want %v", err, fetchErr)
+	}
+	if _, err := p.Price(ctx, later.Add(retryInterval+time.Minute)); !errors.Is(err, fetchErr) {
+		t.Errorf("Price() error = %v, want the last fetch error", err)
+	}
+	if fetches != 3 {
+		t.Errorf("%d fetches, want 3", fetches)
+	}
+}
+
+func TestAwattar(t *testing.T) {
+	start := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, `{"object": "list", "data": [{"start_timestamp": %d, "end_timestamp": %d, "marketprice": 84.5, "unit": "Eur/MWh"}]}`,
+			start.UnixMilli(), start.Add(time.Hour).UnixMilli())
+	}))
+	defer server.Close()
+
+	price, err := newAwattar(server.URL).Price(context.Background(), start.Add(30*time.Minute))
+	if err != nil || price != 84.5 {
+		t.Errorf("Price() = %v, %v, want 84.5", price, err)
+	}
+}
+
+func TestTibber(t *testing.T) {
+	start := time.Date(2025, 3, 10, 23, 0, 0, 0, time.UTC)
+	var gotAuth, gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		gotAuth = req.Header.Get("Authorization")
+		var body map[string]string
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		gotQuery = body["query"]
+		fmt.Fprintf(w, `{"data": {"viewer": {"homes": [{"currentSubscription": {"priceInfo": {
+			"today": [{"total": 0.21, "startsAt": %q}],
+			"tomorrow": [{"total": 0.35, "startsAt": %q}]}}}]}}}`,
+			start.Format(time.RFC3339), start.Add(time.Hour).Format(time.RFC3339))
+	}))
+	defer server.Close()
+	p := newTibber(server.URL, "secret")
+	ctx := context.Background()
+
+	if price, err := p.Price(ctx, start.Add(30*time.Minute)); err != nil || price != 0.21 {
+		t.Errorf("Price() = %v, %v, want 0.21", price, err)
+	}
+	// The last price lasts an hour
+	if price, err := p.Price(ctx, start.Add(90*time.Minute)); err != nil || price != 0.35 {
+		t.Errorf("Price() = %v, %v, want tomorrow's 0.35", price, err)
+	}
+	if gotAuth != "Bearer secret" || gotQuery != tibberQuery {
+		t.Errorf("request with %q and query %q", gotAuth, gotQuery)
+	}
+}
+
+func TestTibberErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{name: "unauthorized", status: http.StatusUnauthorized, body: `{"errors": [{"message": "invalid token"}]}`},
+		{name: "GraphQL error", status: http.StatusOK, body: `{"errors": [{"message": "invalid token"}]}`},
+		{name: "no subscription", status: http.StatusOK, body: `{"data": {"viewer": {"homes": [{"currentSubscription": null}]}}}`},
+		{name: "no home", status: http.StatusOK, body: `{"data": {"viewer": {"homes": []}}}`},
+		{name: "malformed", status: http.StatusOK, body: `<html>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+			if _, err := newTibber(server.URL, "secret").Price(context.Background(), time.Now()); err == nil {
+				t.Errorf("Price() succeeded")
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package pricing
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Carbon intensity sources
+const (
+	CarbonSourceStatic          = "static"
+	CarbonSourceElectricityMaps = "electricitymaps"
+)
+
+const defaultElectricityMapsURL = "https://api.electricitymap.org/v3/carbon-intensity/latest"
+
+// CarbonOptions contains configuration for carbon-aware scaling.
+type CarbonOptions struct {
+	// Source is where carbon intensities come from: static or
+	// electricitymaps. Empty disables carbon-aware scaling.
+	Source string
+
+	// Schedule is the static intensity schedule, e.g. "00:00=250,17:00=420"
+	Schedule string
+
+	// URL overrides the API endpoint of the source
+	URL string
+
+	// TokenFile is the path to the API token of the source
+	TokenFile string
+
+	// Zone is the grid zone of the source, e.g. DE
+	Zone string
+
+	// HighAbove is the intensity, in gCO2eq/kWh, above which scale-ups of
+	// deferrable pools are held back
+	HighAbove float64
+
+	// High is the scale-down applied while the intensity is high
+	High ScaleDown
+}
+
+// DefaultCarbonOptions returns the default carbon options.
+func DefaultCarbonOptions() CarbonOptions {
+	return CarbonOptions{
+		HighAbove: 300,
+		High: ScaleDown{
+			UtilizationThreshold: 0.7,
+			UnneededTime:         2 * time.Minute,
+		},
+	}
+}
+
+// BindFlags binds the carbon options to command line flags.
+// The prefix can be used to namespace the flags (e.g., "carbon-").
+func (o *CarbonOptions) BindFlags(fs *flag.FlagSet, prefix string) {
+	fs.StringVar(&o.Source, prefix+"source", o.Source,
+		"Source of grid carbon intensity for carbon-aware scaling: static or electricitymaps. Empty to disable.")
+	fs.StringVar(&o.Schedule, prefix+"schedule", o.Schedule,
+		"Static carbon intensity schedule of local start times and gCO2eq/kWh, e.g. \"00:00=250,17:00=420,21:00=300\".")
+	fs.StringVar(&o.URL, prefix+"url", o.URL,
+		"API endpoint of the carbon intensity source. Empty for the source's default.")
+	fs.StringVar(&o.TokenFile, prefix+"token-file", o.TokenFile,
+		"Path to the API token of the carbon intensity source. Required for electricitymaps.")
+	fs.StringVar(&o.Zone, prefix+"zone", o.Zone,
+		"Grid zone to read the carbon intensity of, e.g. DE. Required for electricitymaps.")
+	fs.Float64Var(&o.HighAbove, prefix+"high-above", o.HighAbove,
+		"Carbon intensity, in gCO2eq/kWh, above which deferrable scale-ups are held back.")
+	fs.Float64Var(&o.High.UtilizationThreshold, prefix+"high-utilization-threshold", o.High.UtilizationThreshold,
+		"Scale-down utilization threshold reported to the cluster autoscaler while carbon intensity is high.")
+	fs.DurationVar(&o.High.UnneededTime, prefix+"high-unneeded-time", o.High.UnneededTime,
+		"Scale-down unneeded time reported to the cluster autoscaler while carbon intensity is high.")
+}
+
+// Enabled returns true if a carbon intensity source is configured.
+func (o *CarbonOptions) Enabled() bool {
+	return o.Source != ""
+}
+
+// Validate validates the options.
+func (o *CarbonOptions) Validate() error {
+	if !o.Enabled() {
+		return nil
+	}
+	switch o.Source {
+	case CarbonSourceStatic:
+		if _, err := parseSchedule(o.Schedule); err != nil {
+			return err
+		}
+	case CarbonSourceElectricityMaps:
+		if o.TokenFile == "" {
+			return fmt.Errorf("the electricitymaps carbon source requires a token file")
+		}
+		if o.Zone == "" {
+			return fmt.Errorf("the electricitymaps carbon source requires a zone")
+		}
+	default:
+		return fmt.Errorf("unknown carbon intensity source %q", o.Source)
+	}
+	if o.HighAbove <= 0 {
+		return fmt.Errorf("the high carbon intensity must be positive")
+	}
+	if o.High.UtilizationThreshold < 0 || o.High.UtilizationThreshold > 1 {
+		return fmt.Errorf("scale-down utilization thresholds must be between 0 and 1")
+	}
+	if o.High.UnneededTime <= 0 {
+		return fmt.Errorf("scale-down unneeded times must be positive")
+	}
+	return nil
+}
+
+// CarbonPolicy tells whether the grid's carbon intensity is high. An unknown
+// intensity is not high, so nothing is held back while the source is
+// unreachable.
+type CarbonPolicy struct {
+	options  CarbonOptions
+	provider Provider
+
+	mu        sync.Mutex
+	high      bool
+	highSince time.Time
+	failing   bool
+}
+
+// NewCarbonPolicy creates a carbon policy with the provider of the
+// configured source.
+func NewCarbonPolicy(opts CarbonOptions) (*CarbonPolicy, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	var provider Provider
+	switch opts.Source {
+	case CarbonSourceStatic:
+		schedule, err := parseSchedule(opts.Schedule)
+		if err != nil {
+			return nil, err
+		}
+		provider = schedule
+	case CarbonSourceElectricityMaps:
+		token, err := os.ReadFile(opts.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read electricitymaps token: %w", err)
+		}
+		provider = newElectricityMaps(opts.URL, opts.Zone, strings.TrimSpace(string(token)))
+	}
+	return &CarbonPolicy{options: opts, provider: provider}, nil
+}
+
+// High returns true if the carbon intensity is high at the given time, and
+// since when it has been high as far as the policy has seen
+func (p *CarbonPolicy) High(ctx context.Context, at time.Time) (bool, time.Time) {
+	logger := log.FromContext(ctx).WithName("carbon")
+	intensity, err := p.provider.Price(ctx, at)
+	high := err == nil && intensity > p.options.HighAbove
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// Only log changes, since the autoscaler asks every scan
+	if err != nil && !p.failing {
+		logger.Error(err, "Failed to get carbon intensity, not holding back scale-ups")
+	}
+	p.failing = err != nil
+	if high != p.high {
+		logger.Info("Carbon intensity level changed", "high", high, "intensity", intensity)
+		p.high = high
+		p.highSince = at
+	}
+	return high, p.highSince
+}
+
+// ScaleDown returns the scale-down settings applied while the intensity is
+// high
+func (p *CarbonPolicy) ScaleDown() ScaleDown {
+	return p.options.High
+}
+
+// newElectricityMaps returns the latest carbon intensity of a zone from
+// Electricity Maps, in gCO2eq/kWh. Each reading covers an hour.
+func newElectricityMaps(endpoint string, zone string, token string) Provider {
+	if endpoint == "" {
+		endpoint = defaultElectricityMapsURL
+	}
+	return &slotProvider{fetch: func(ctx context.Context) ([]slot, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?zone="+url.QueryEscape(zone), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("auth-token", token)
+		var response struct {
+			CarbonIntensity float64   `json:"carbonIntensity"`
+			Datetime        time.Time `json:"datetime"`
+		}
+		if err := do(req, &response); err != nil {
+			return nil, err
+		}
+		// A reading published late is used until the next fetch
+		end := response.Datetime.Add(time.Hour)
+		if now := time.Now(); end.Before(now) {
+			end = now.Add(retryInterval)
+		}
+		return []slot{{start: response.Datetime, end: end, price: response.CarbonIntensity}}, nil
+	}}
+}
@@ -0,0 +1,129 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCarbonOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    func(*CarbonOptions)
+		wantErr bool
+	}{
+		{name: "disabled", opts: func(o *CarbonOptions) { o.Source = "" }},
+		{name: "static", opts: func(*CarbonOptions) {}},
+		{name: "electricitymaps", opts: func(o *CarbonOptions) {
+			o.Source, o.TokenFile, o.Zone = CarbonSourceElectricityMaps, "/etc/electricitymaps/token", "DE"
+		}},
+		{name: "electricitymaps without token", opts: func(o *CarbonOptions) { o.Source, o.Zone = CarbonSourceElectricityMaps, "DE" }, wantErr: true},
+		{name: "electricitymaps without zone", opts: func(o *CarbonOptions) {
+			o.Source, o.TokenFile = CarbonSourceElectricityMaps, "/etc/electricitymaps/token"
+		}, wantErr: true},
+		{name: "unknown source", opts: func(o *CarbonOptions) { o.Source = "watttime" }, wantErr: true},
+		{name: "invalid schedule", opts: func(o *CarbonOptions) { o.Schedule = "17:00" }, wantErr: true},
+		{name: "no high intensity", opts: func(o *CarbonOptions) { o.HighAbove = 0 }, wantErr: true},
+		{name: "threshold above 1", opts: func(o *CarbonOptions) { o.High.UtilizationThreshold = 2 }, wantErr: true},
+		{name: "no unneeded time", opts: func(o *CarbonOptions) { o.High.UnneededTime = 0 }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultCarbonOptions()
+			opts.Source, opts.Schedule = CarbonSourceStatic, "00:00=250,17:00=420"
+			tt.opts(&opts)
+			if err := opts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCarbonPolicyHigh(t *testing.T) {
+	intensity := &fixedPrice{price: 250}
+	p := &CarbonPolicy{options: DefaultCarbonOptions(), provider: intensity}
+	ctx := context.Background()
+	start := time.Date(2025, 3, 10, 17, 0, 0, 0, time.UTC)
+
+	if high, _ := p.High(ctx, start); high {
+		t.Errorf("High() = true at 250 gCO2eq/kWh")
+	}
+	intensity.price = 420
+	if high, since := p.High(ctx, start.Add(time.Hour)); !high || !since.Equal(start.Add(time.Hour)) {
+		t.Errorf("High() = %v since %s, want high since it rose", high, since)
+	}
+	// Still high, since when doesn't move
+	if high, since := p.High(ctx, start.Add(2*time.Hour)); !high || !since.Equal(start.Add(time.Hour)) {
+		t.Errorf("High() = %v since %s, want high since it rose", high, since)
+	}
+
+	// An unknown intensity holds nothing back
+	intensity.err = errors.New("connection refused")
+	if high, _ := p.High(ctx, start.Add(3*time.Hour)); high {
+		t.Errorf("High() = true without an intensity")
+	}
+	if p.ScaleDown() != p.options.High {
+		t.Errorf("ScaleDown() = %+v, want %+v", p.ScaleDown(), p.options.High)
+	}
+}
+
+func TestElectricityMaps(t *testing.T) {
+	reading := time.Now().Truncate(time.Hour)
+	var gotZone, gotToken string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		gotZone, gotToken = req.URL.Query().Get("zone"), req.Header.Get("auth-token")
+		fmt.Fprintf(w, `{"zone": "DE", "carbonIntensity": 387, "datetime": %q}`, reading.UTC().Format(time.RFC3339))
+	}))
+	defer server.Close()
+
+	intensity, err := newElectricityMaps(server.URL, "DE", "secret").Price(context.Background(), time.Now())
+	if err != nil || intensity != 387 {
+		t.Errorf("Price() = %v, %v, want 387", intensity, err)
+	}
+	if gotZone != "DE" || gotToken != "secret" {
+		t.Errorf("request for zone %q with token %q", gotZone, gotToken)
+	}
+}
+
+func TestElectricityMapsLateReading(t *testing.T) {
+	// A reading from hours ago is still used until the next fetch
+	reading := time.Now().Add(-3 * time.Hour)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, `{"carbonIntensity": 120, "datetime": %q}`, reading.UTC().Format(time.RFC3339))
+	}))
+	defer server.Close()
+
+	if intensity, err := newElectricityMaps(server.URL, "FR", "secret").Price(context.Background(), time.Now()); err != nil || intensity != 120 {
+		t.Errorf("Price() = %v, %v, want the late reading", intensity, err)
+	}
+}
+
+func TestNewCarbonPolicy(t *testing.T) {
+	opts := DefaultCarbonOptions()
+	opts.Source, opts.Zone = CarbonSourceElectricityMaps, "DE"
+	opts.TokenFile = filepath.Join(t.TempDir(), "token")
+	if _, err := NewCarbonPolicy(opts); err == nil {
+		t.Errorf("NewCarbonPolicy() succeeded without the token file")
+	}
+	if err := os.WriteFile(opts.TokenFile, []byte("secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewCarbonPolicy(opts); err != nil {
+		t.Errorf("NewCarbonPolicy() error = %v", err)
+	}
+
+	opts.Source, opts.Schedule = CarbonSourceStatic, "00:00=500"
+	p, err := NewCarbonPolicy(opts)
+	if err != nil {
+		t.Fatalf("NewCarbonPolicy() error = %v", err)
+	}
+	if high, _ := p.High(context.Background(), time.Now()); !high {
+		t.Errorf("High() = false, want high all day")
+	}
+}
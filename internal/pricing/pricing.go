@@ -0,0 +1,239 @@
+// Package pricing classifies the current electricity price as cheap, normal
+// or expensive, and the grid's carbon intensity as high or not, so scaling
+// can be tuned to them.
+package pricing
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Price sources
+const (
+	SourceStatic  = "static"
+	SourceAwattar = "awattar"
+	SourceTibber  = "tibber"
+)
+
+// Provider returns the electricity price at a point in time, in the unit of
+// its source
+type Provider interface {
+	Price(ctx context.Context, at time.Time) (float64, error)
+}
+
+// Level is how the price compares to the configured thresholds
+type Level int
+
+const (
+	// Normal is a price between the thresholds, or an unknown price
+	Normal Level = iota
+	// Cheap is a price below the cheap threshold
+	Cheap
+	// Expensive is a price above the expensive threshold
+	Expensive
+)
+
+func (l Level) String() string {
+	switch l {
+	case Cheap:
+		return "cheap"
+	case Expensive:
+		return "expensive"
+	}
+	return "normal"
+}
+
+// Options contains configuration for price-aware scaling.
+type Options struct {
+	// Source is where prices come from: static, awattar or tibber. Empty
+	// disables price-aware scaling.
+	Source string
+
+	// Schedule is the static price schedule, e.g. "00:00=0.12,07:00=0.31"
+	Schedule string
+
+	// URL overrides the API endpoint of the source
+	URL string
+
+	// TokenFile is the path to the API token of the source
+	TokenFile string
+
+	// CheapBelow is the price below which power is cheap
+	CheapBelow float64
+
+	// ExpensiveAbove is the price above which power is expensive
+	ExpensiveAbove float64
+
+	// Cheap is the scale-down applied while power is cheap
+	Cheap ScaleDown
+
+	// Expensive is the scale-down applied while power is expensive
+	Expensive ScaleDown
+}
+
+// ScaleDown are the cluster autoscaler scale-down settings for a price level
+type ScaleDown struct {
+	// UtilizationThreshold is the utilization below which a node can be
+	// removed
+	UtilizationThreshold float64
+
+	// UnneededTime is how long a node must be unneeded before it's removed
+	UnneededTime time.Duration
+}
+
+// DefaultOptions returns the default pricing options.
+func DefaultOptions() Options {
+	return Options{
+		Cheap: ScaleDown{
+			UtilizationThreshold: 0.3,
+			UnneededTime:         30 * time.Minute,
+		},
+		Expensive: ScaleDown{
+			UtilizationThreshold: 0.7,
+			UnneededTime:         2 * time.Minute,
+		},
+	}
+}
+
+// BindFlags binds the pricing options to command line flags.
+// The prefix can be used to namespace the flags (e.g., "pricing-").
+func (o *Options) BindFlags(fs *flag.FlagSet, prefix string) {
+	fs.StringVar(&o.Source, prefix+"source", o.Source,
+		"Source of electricity prices for price-aware scale-down: static, awattar or tibber. Empty to disable.")
+	fs.StringVar(&o.Schedule, prefix+"schedule", o.Schedule,
+		"Static price schedule of local start times and prices, e.g. \"00:00=0.12,07:00=0.31,22:00=0.18\".")
+	fs.StringVar(&o.URL, prefix+"url", o.URL,
+		"API endpoint of the price source. Empty for the source's default.")
+	fs.StringVar(&o.TokenFile, prefix+"token-file", o.TokenFile,
+		"Path to the API token of the price source. Required for tibber.")
+	fs.Float64Var(&o.CheapBelow, prefix+"cheap-below", o.CheapBelow,
+		"Price, in the unit of the source, below which power is cheap.")
+	fs.Float64Var(&o.ExpensiveAbove, prefix+"expensive-above", o.ExpensiveAbove,
+		"Price, in the unit of the source, above which power is expensive.")
+	fs.Float64Var(&o.Cheap.UtilizationThreshold, prefix+"cheap-utilization-threshold", o.Cheap.UtilizationThreshold,
+		"Scale-down utilization threshold reported to the cluster autoscaler while power is cheap.")
+	fs.DurationVar(&o.Cheap.UnneededTime, prefix+"cheap-unneeded-time", o.Cheap.UnneededTime,
+		"Scale-down unneeded time reported to the cluster autoscaler while power is cheap.")
+	fs.Float64Var(&o.Expensive.UtilizationThreshold, prefix+"expensive-utilization-threshold", o.Expensive.UtilizationThreshold,
+		"Scale-down utilization threshold reported to the cluster autoscaler while power is expensive.")
+	fs.DurationVar(&o.Expensive.UnneededTime, prefix+"expensive-unneeded-time", o.Expensive.UnneededTime,
+		"Scale-down unneeded time reported to the cluster autoscaler while power is expensive.")
+}
+
+// Enabled returns true if a price source is configured.
+func (o *Options) Enabled() bool {
+	return o.Source != ""
+}
+
+// Validate validates the options.
+func (o *Options) Validate() error {
+	if !o.Enabled() {
+		return nil
+	}
+	switch o.Source {
+	case SourceStatic:
+		if _, err := parseSchedule(o.Schedule); err != nil {
+			return err
+		}
+	case SourceAwattar:
+	case SourceTibber:
+		if o.TokenFile == "" {
+			return fmt.Errorf("the tibber price source requires a token file")
+		}
+	default:
+		return fmt.Errorf("unknown price source %q", o.Source)
+	}
+	if o.ExpensiveAbove <= o.CheapBelow {
+		return fmt.Errorf("the expensive price must be above the cheap price")
+	}
+	for _, scaleDown := range []ScaleDown{o.Cheap, o.Expensive} {
+		if scaleDown.UtilizationThreshold < 0 || scaleDown.UtilizationThreshold > 1 {
+			return fmt.Errorf("scale-down utilization thresholds must be between 0 and 1")
+		}
+		if scaleDown.UnneededTime <= 0 {
+			return fmt.Errorf("scale-down unneeded times must be positive")
+		}
+	}
+	return nil
+}
+
+// Policy classifies the current price. An unknown price, e.g. while the
+// source is unreachable, is normal, leaving the autoscaler's defaults.
+type Policy struct {
+	options  Options
+	provider Provider
+
+	mu      sync.Mutex
+	level   Level
+	failing bool
+}
+
+// NewPolicy creates a pricing policy with the provider of the configured
+// source.
+func NewPolicy(opts Options) (*Policy, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	var provider Provider
+	switch opts.Source {
+	case SourceStatic:
+		schedule, err := parseSchedule(opts.Schedule)
+		if err != nil {
+			return nil, err
+		}
+		provider = schedule
+	case SourceAwattar:
+		provider = newAwattar(opts.URL)
+	case SourceTibber:
+		token, err := os.ReadFile(opts.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tibber token: %w", err)
+		}
+		provider = newTibber(opts.URL, strings.TrimSpace(string(token)))
+	}
+	return &Policy{options: opts, provider: provider}, nil
+}
+
+// Level returns the price level at the given time
+func (p *Policy) Level(ctx context.Context, at time.Time) Level {
+	logger := log.FromContext(ctx).WithName("pricing")
+	level := Normal
+	price, err := p.provider.Price(ctx, at)
+	if err == nil && price < p.options.CheapBelow {
+		level = Cheap
+	} else if err == nil && price > p.options.ExpensiveAbove {
+		level = Expensive
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// Only log changes, since the autoscaler asks every scan
+	if err != nil && !p.failing {
+		logger.Error(err, "Failed to get electricity price, using the autoscaler's defaults")
+	}
+	p.failing = err != nil
+	if level != p.level {
+		logger.Info("Electricity price level changed", "level", level.String(), "previous", p.level.String())
+		p.level = level
+	}
+	return level
+}
+
+// ScaleDown returns the scale-down settings of a price level. It returns
+// false for normal prices, which keep the autoscaler's defaults.
+func (p *Policy) ScaleDown(level Level) (ScaleDown, bool) {
+	switch level {
+	case Cheap:
+		return p.options.Cheap, true
+	case Expensive:
+		return p.options.Expensive, true
+	}
+	return ScaleDown{}, false
+}
@@ -0,0 +1,171 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fixedPrice is a Provider that always returns the same price or error
+type fixedPrice struct {
+	price float64
+	err   error
+}
+
+func (f *fixedPrice) Price(context.Context, time.Time) (float64, error) {
+	return f.price, f.err
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    func(*Options)
+		wantErr bool
+	}{
+		{name: "disabled", opts: func(o *Options) { o.Source = "" }},
+		{name: "static", opts: func(*Options) {}},
+		{name: "awattar", opts: func(o *Options) { o.Source = SourceAwattar }},
+		{name: "tibber", opts: func(o *Options) { o.Source = SourceTibber; o.TokenFile = "/etc/tibber/token" }},
+		{name: "tibber without token", opts: func(o *Options) { o.Source = SourceTibber }, wantErr: true},
+		{name: "unknown source", opts: func(o *Options) { o.Source = "entsoe" }, wantErr: true},
+		{name: "static without schedule", opts: func(o *Options) { o.Schedule = "" }, wantErr: true},
+		{name: "thresholds reversed", opts: func(o *Options) { o.CheapBelow, o.ExpensiveAbove = 0.3, 0.1 }, wantErr: true},
+		{name: "threshold above 1", opts: func(o *Options) { o.Cheap.UtilizationThreshold = 1.2 }, wantErr: true},
+		{name: "no unneeded time", opts: func(o *Options) { o.Expensive.UnneededTime = 0 }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.Source = SourceStatic
+			opts.Schedule = "00:00=0.12,07:00=0.31"
+			opts.CheapBelow, opts.ExpensiveAbove = 0.15, 0.25
+			tt.opts(&opts)
+			if err := opts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    schedule
+		wantErr bool
+	}{
+		{value: "00:00=0.12", want: schedule{{0, 0.12}}},
+		{value: "22:00=0.18, 07:30=0.31,00:00=0.12", want: schedule{{0, 0.12}, {7*time.Hour + 30*time.Minute, 0.31}, {22 * time.Hour, 0.18}}},
+		{value: "", wantErr: true},
+		{value: "07:00", wantErr: true},
+		{value: "7am=0.31", wantErr: true},
+		{value: "25:00=0.31", wantErr: true},
+		{value: "07:00=cheap", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseSchedule(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSchedule(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("parseSchedule(%q) = %v, want %v", tt.value, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("parseSchedule(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestSchedulePrice(t *testing.T) {
+	s, err := parseSchedule("07:00=0.31,22:00=0.18,03:00=0.12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		hour, minute int
+		want         float64
+	}{
+		// Before the first start, the last price of the day still applies
+		{hour: 1, want: 0.18},
+		{hour: 3, want: 0.12},
+		{hour: 6, minute: 59, want: 0.12},
+		{hour: 7, want: 0.31},
+		{hour: 23, minute: 30, want: 0.18},
+	}
+	for _, tt := range tests {
+		at := time.Date(2025, 3, 10, tt.hour, tt.minute, 0, 0, time.Local)
+		if got, err := s.Price(context.Background(), at); err != nil || got != tt.want {
+			t.Errorf("Price(%s) = %v, %v, want %v", at.Format("15:04"), got, err, tt.want)
+		}
+	}
+}
+
+func TestPolicyLevel(t *testing.T) {
+	opts := DefaultOptions()
+	opts.CheapBelow, opts.ExpensiveAbove = 0.15, 0.25
+	tests := []struct {
+		name     string
+		provider *fixedPrice
+		want     Level
+	}{
+		{name: "cheap", provider: &fixedPrice{price: 0.1}, want: Cheap},
+		{name: "at the cheap threshold", provider: &fixedPrice{price: 0.15}, want: Normal},
+		{name: "normal", provider: &fixedPrice{price: 0.2}, want: Normal},
+		{name: "expensive", provider: &fixedPrice{price: 0.4}, want: Expensive},
+		// An unreachable source leaves the autoscaler's defaults
+		{name: "unknown", provider: &fixedPrice{err: errors.New("connection refused")}, want: Normal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Policy{options: opts, provider: tt.provider}
+			if got := p.Level(context.Background(), time.Now()); got != tt.want {
+				t.Errorf("Level() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyScaleDown(t *testing.T) {
+	p := &Policy{options: DefaultOptions()}
+	if scaleDown, ok := p.ScaleDown(Cheap); !ok || scaleDown != p.options.Cheap {
+		t.Errorf("ScaleDown(cheap) = %+v, %v", scaleDown, ok)
+	}
+	if scaleDown, ok := p.ScaleDown(Expensive); !ok || scaleDown != p.options.Expensive {
+		t.Errorf("ScaleDown(expensive) = %+v, %v", scaleDown, ok)
+	}
+	if _, ok := p.ScaleDown(Normal); ok {
+		t.Errorf("ScaleDown(normal) overrides the autoscaler's defaults")
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Source = SourceTibber
+	opts.CheapBelow, opts.ExpensiveAbove = 0.15, 0.25
+	opts.TokenFile = filepath.Join(t.TempDir(), "token")
+	if _, err := NewPolicy(opts); err == nil {
+		t.Errorf("NewPolicy() succeeded without the token file")
+	}
+	if err := os.WriteFile(opts.TokenFile, []byte("secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewPolicy(opts); err != nil {
+		t.Errorf("NewPolicy() error = %v", err)
+	}
+
+	opts.Source = SourceStatic
+	opts.Schedule = "00:00=0.1"
+	p, err := NewPolicy(opts)
+	if err != nil {
+		t.Fatalf("NewPolicy() error = %v", err)
+	}
+	if level := p.Level(context.Background(), time.Now()); level != Cheap {
+		t.Errorf("Level() = %s, want cheap all day", level)
+	}
+}
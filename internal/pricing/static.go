@@ -0,0 +1,62 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schedule is a daily price schedule. Each price applies from its start
+// until the next one, and the last one wraps around midnight.
+type schedule []scheduleEntry
+
+type scheduleEntry struct {
+	// start is the offset into the local day
+	start time.Duration
+	price float64
+}
+
+// parseSchedule parses entries like "07:00=0.31" separated by commas
+func parseSchedule(value string) (schedule, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, fmt.Errorf("the static price source requires a schedule")
+	}
+	var entries schedule
+	for _, field := range strings.Split(value, ",") {
+		at, price, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid price schedule entry %q, expected HH:MM=price", field)
+		}
+		start, err := time.Parse("15:04", at)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start time in price schedule entry %q", field)
+		}
+		parsed, err := strconv.ParseFloat(price, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid price in price schedule entry %q", field)
+		}
+		entries = append(entries, scheduleEntry{
+			start: time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute,
+			price: parsed,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].start < entries[j].start })
+	return entries, nil
+}
+
+// Price implements Provider in the controller's local time zone.
+func (s schedule) Price(_ context.Context, at time.Time) (float64, error) {
+	at = at.Local()
+	offset := time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute
+	price := s[len(s)-1].price
+	for _, entry := range s {
+		if entry.start > offset {
+			break
+		}
+		price = entry.price
+	}
+	return price, nil
+}
@@ -0,0 +1,95 @@
+// Package relay wakes and probes servers through agents on their subnets.
+// Wake-on-LAN broadcasts don't cross routers, so the controller asks an
+// agent running on a remote site's subnet to send the magic packet and to
+// ping the server, over gRPC.
+package relay
+
+import (
+	"context"
+	"net"
+	"os"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Unbounder1/bare-metal-controller/internal/dhcp"
+	"github.com/Unbounder1/bare-metal-controller/internal/neighbor"
+	"github.com/Unbounder1/bare-metal-controller/internal/power"
+)
+
+// Agent serves the WakeRelay service on the local subnet
+type Agent struct {
+	UnimplementedWakeRelayServer
+
+	WolSender power.WolSender
+	Pinger    power.Pinger
+
+	// LeaseFile is the dnsmasq lease file of the subnet's DHCP server, empty
+	// if the agent doesn't serve leases
+	LeaseFile string
+
+	// Scanner resolves MAC addresses on the agent's subnets, nil if the agent
+	// doesn't scan
+	Scanner *neighbor.Scanner
+}
+
+// Wake sends a magic packet to the broadcast address of the request, or the
+// agent's default
+func (a *Agent) Wake(ctx context.Context, req *WakeRequest) (*WakeResponse, error) {
+	if _, err := net.ParseMAC(req.MacAddress); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid MAC address %q", req.MacAddress)
+	}
+	if err := a.WolSender.Wake(ctx, req.MacAddress, int(req.Port), req.BroadcastAddress); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to send magic packet: %v", err)
+	}
+	return &WakeResponse{}, nil
+}
+
+// Probe pings the address from the agent
+func (a *Agent) Probe(ctx context.Context, req *ProbeRequest) (*ProbeResponse, error) {
+	if req.Address == "" {
+		return nil, status.Error(codes.InvalidArgument, "address is required")
+	}
+	return &ProbeResponse{Reachable: a.Pinger.IsReachable(ctx, req.Address)}, nil
+}
+
+// Leases returns the leases of the agent's dnsmasq lease file
+func (a *Agent) Leases(ctx context.Context, req *LeasesRequest) (*LeasesResponse, error) {
+	if a.LeaseFile == "" {
+		return nil, status.Error(codes.FailedPrecondition, "no lease file configured")
+	}
+	f, err := os.Open(a.LeaseFile)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to open lease file: %v", err)
+	}
+	defer f.Close()
+	leases, err := dhcp.ParseDnsmasq(f)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to parse lease file: %v", err)
+	}
+	resp := &LeasesResponse{}
+	for _, lease := range leases {
+		l := &Lease{MacAddress: lease.MACAddress, IpAddress: lease.IPAddress, Hostname: lease.Hostname}
+		if !lease.Expires.IsZero() {
+			l.Expires = lease.Expires.Unix()
+		}
+		resp.Leases = append(resp.Leases, l)
+	}
+	return resp, nil
+}
+
+// Neighbors scans the agent's subnets
+func (a *Agent) Neighbors(ctx context.Context, req *NeighborsRequest) (*NeighborsResponse, error) {
+	if a.Scanner == nil {
+		return nil, status.Error(codes.FailedPrecondition, "no subnets to scan configured")
+	}
+	neighbors, err := a.Scanner.Scan(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to scan subnets: %v", err)
+	}
+	resp := &NeighborsResponse{}
+	for _, n := range neighbors {
+		resp.Neighbors = append(resp.Neighbors, &Neighbor{MacAddress: n.MACAddress, IpAddress: n.IPAddress})
+	}
+	return resp, nil
+}
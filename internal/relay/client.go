@@ -0,0 +1,335 @@
+package relay
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/Unbounder1/bare-metal-controller/internal/dhcp"
+	"github.com/Unbounder1/bare-metal-controller/internal/neighbor"
+)
+
+// Options configures the connections to relay agents
+type Options struct {
+	// Addresses maps relay names to agent addresses, as
+	// name=host:port,name=host:port
+	Addresses string
+
+	// CertFile and KeyFile are the client certificate presented to agents
+	CertFile string
+	KeyFile  string
+
+	// CAFile verifies the agents' certificates
+	CAFile string
+
+	// Timeout bounds each call to an agent
+	Timeout time.Duration
+}
+
+// DefaultOptions returns the default relay options, with no relays
+func DefaultOptions() Options {
+	return Options{
+		Timeout: 10 * time.Second,
+	}
+}
+
+// BindFlags binds the relay options to command line flags.
+// The prefix can be used to namespace the flags (e.g., "wol-relay-").
+func (o *Options) BindFlags(fs *flag.FlagSet, prefix string) {
+	fs.StringVar(&o.Addresses, prefix+"addresses", o.Addresses,
+		"Relay agents as name=host:port,... for WoL servers with spec.control.wol.relay. Empty to send every magic packet from the controller.")
+	fs.StringVar(&o.CertFile, prefix+"cert", o.CertFile,
+		"Path to the client certificate presented to relay agents. Empty for insecure.")
+	fs.StringVar(&o.KeyFile, prefix+"key", o.KeyFile,
+		"Path to the client key for relay agents. Empty for insecure.")
+	fs.StringVar(&o.CAFile, prefix+"ca", o.CAFile,
+		"Path to the CA certificate that signed the relay agents' certificates. Empty for insecure.")
+	fs.DurationVar(&o.Timeout, prefix+"timeout", o.Timeout,
+		"Timeout of each call to a relay agent.")
+}
+
+// Validate checks the relay options
+func (o *Options) Validate() error {
+	if _, err := parseAddresses(o.Addresses); err != nil {
+		return err
+	}
+	tlsOptions := []string{o.CertFile, o.KeyFile, o.CAFile}
+	set := 0
+	for _, option := range tlsOptions {
+		if option != "" {
+			set++
+		}
+	}
+	if set != 0 && set != len(tlsOptions) {
+		return fmt.Errorf("all relay TLS options (cert, key, ca) must be set together, or none")
+	}
+	if o.Timeout <= 0 {
+		return fmt.Errorf("relay timeout must be positive")
+	}
+	return nil
+}
+
+// Enabled returns true if any relay is configured
+func (o *Options) Enabled() bool {
+	return o.Addresses != ""
+}
+
+// parseAddresses parses name=host:port pairs
+func parseAddresses(value string) (map[string]string, error) {
+	addresses := map[string]string{}
+	if value == "" {
+		return addresses, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		name, address, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || name == "" || address == "" {
+			return nil, fmt.Errorf("relay must be name=host:port, got %q", pair)
+		}
+		if _, found := addresses[name]; found {
+			return nil, fmt.Errorf("relay %s is listed twice", name)
+		}
+		addresses[name] = address
+	}
+	return addresses, nil
+}
+
+// Client sends wake-ups and probes to the agent of each server's relay. It
+// connects to an agent on first use and keeps the connection.
+type Client struct {
+	addresses map[string]string
+	creds     credentials.TransportCredentials
+	timeout   time.Duration
+
+	mu    sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+// NewClient returns a client for the relays of the options
+func NewClient(opts Options) (*Client, error) {
+	addresses, err := parseAddresses(opts.Addresses)
+	if err != nil {
+		return nil, err
+	}
+	creds := insecure.NewCredentials()
+	if opts.CertFile != "" {
+		config, err := clientTLSConfig(opts.CertFile, opts.KeyFile, opts.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		creds = credentials.NewTLS(config)
+	}
+	return &Client{
+		addresses: addresses,
+		creds:     creds,
+		timeout:   opts.Timeout,
+		conns:     map[string]*grpc.ClientConn{},
+	}, nil
+}
+
+// Wake asks the relay's agent to send a magic packet
+func (c *Client) Wake(ctx context.Context, relay string, macAddress string, port int, broadcastAddress string) error {
+	agent, err := c.agent(relay)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	_, err = agent.Wake(ctx, &WakeRequest{
+		MacAddress:       macAddress,
+		BroadcastAddress: broadcastAddress,
+		Port:             int32(port),
+	})
+	if err != nil {
+		return fmt.Errorf("relay %s failed to wake %s: %w", relay, macAddress, err)
+	}
+	return nil
+}
+
+// IsReachable asks the relay's agent to ping the address. A relay that
+// can't be reached counts as the host not answering.
+func (c *Client) IsReachable(ctx context.Context, relay string, address string) bool {
+	agent, err := c.agent(relay)
+	if err != nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	resp, err := agent.Probe(ctx, &ProbeRequest{Address: address})
+	return err == nil && resp.Reachable
+}
+
+// Leases asks the relay's agent for the leases of its subnet's DHCP server
+func (c *Client) Leases(ctx context.Context, relay string) ([]dhcp.Lease, error) {
+	agent, err := c.agent(relay)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	resp, err := agent.Leases(ctx, &LeasesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("relay %s failed to read leases: %w", relay, err)
+	}
+	leases := make([]dhcp.Lease, 0, len(resp.Leases))
+	for _, l := range resp.Leases {
+		lease := dhcp.Lease{MACAddress: l.MacAddress, IPAddress: l.IpAddress, Hostname: l.Hostname}
+		if l.Expires != 0 {
+			lease.Expires = time.Unix(l.Expires, 0)
+		}
+		leases = append(leases, lease)
+	}
+	return leases, nil
+}
+
+// LeaseSources returns a lease source for the agent of every relay
+func (c *Client) LeaseSources() []dhcp.Source {
+	var sources []dhcp.Source
+	for _, relay := range c.relays() {
+		sources = append(sources, &leaseSource{client: c, relay: relay})
+	}
+	return sources
+}
+
+// leaseSource reads the leases of one relay's agent
+type leaseSource struct {
+	client *Client
+	relay  string
+}
+
+func (s *leaseSource) Name() string {
+	return "relay/" + s.relay
+}
+
+func (s *leaseSource) Leases(ctx context.Context) ([]dhcp.Lease, error) {
+	return s.client.Leases(ctx, s.relay)
+}
+
+// Neighbors asks the relay's agent to scan its subnets
+func (c *Client) Neighbors(ctx context.Context, relay string) ([]neighbor.Neighbor, error) {
+	agent, err := c.agent(relay)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	resp, err := agent.Neighbors(ctx, &NeighborsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("relay %s failed to scan: %w", relay, err)
+	}
+	neighbors := make([]neighbor.Neighbor, 0, len(resp.Neighbors))
+	for _, n := range resp.Neighbors {
+		neighbors = append(neighbors, neighbor.Neighbor{MACAddress: n.MacAddress, IPAddress: n.IpAddress})
+	}
+	return neighbors, nil
+}
+
+// NeighborSources returns a lease source that scans through the agent of
+// every relay
+func (c *Client) NeighborSources() []dhcp.Source {
+	var sources []dhcp.Source
+	for _, relay := range c.relays() {
+		sources = append(sources, neighbor.NewSource("relay/"+relay+"/neighbor", func(ctx context.Context) ([]neighbor.Neighbor, error) {
+			return c.Neighbors(ctx, relay)
+		}))
+	}
+	return sources
+}
+
+// relays returns the names of the relays, sorted
+func (c *Client) relays() []string {
+	relays := make([]string, 0, len(c.addresses))
+	for relay := range c.addresses {
+		relays = append(relays, relay)
+	}
+	sort.Strings(relays)
+	return relays
+}
+
+// Close closes the connections to the agents
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name, conn := range c.conns {
+		conn.Close()
+		delete(c.conns, name)
+	}
+	return nil
+}
+
+func (c *Client) agent(relay string) (WakeRelayClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if conn, ok := c.conns[relay]; ok {
+		return NewWakeRelayClient(conn), nil
+	}
+	address, ok := c.addresses[relay]
+	if !ok {
+		return nil, fmt.Errorf("unknown relay %q", relay)
+	}
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(c.creds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to relay %s: %w", relay, err)
+	}
+	c.conns[relay] = conn
+	return NewWakeRelayClient(conn), nil
+}
+
+// clientTLSConfig loads the client certificate and the CA that verifies the
+// agents
+func clientTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load relay certificate: %w", err)
+	}
+	pool, err := loadCA(caFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		RootCAs:      pool,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// ServerTLSConfig loads the agent's certificate and requires clients to
+// present a certificate signed by the CA
+func ServerTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+	pool, err := loadCA(caFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		Certificates: []tls.Certificate{certificate},
+		ClientCAs:    pool,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+func loadCA(caFile string) (*x509.CertPool, error) {
+	caBytes, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caBytes) {
+		return nil, fmt.Errorf("failed to append CA certificate")
+	}
+	return pool, nil
+}
@@ -0,0 +1,136 @@
+package relay
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/Unbounder1/bare-metal-controller/internal/power"
+)
+
+// blockingPinger answers only when the caller gives up
+type blockingPinger struct{}
+
+func (blockingPinger) IsReachable(ctx context.Context, address string) bool {
+	<-ctx.Done()
+	return true
+}
+
+func (p blockingPinger) Probe(ctx context.Context, address string) power.PingStats {
+	p.IsReachable(ctx, address)
+	return power.PingStats{Sent: 1, Received: 1}
+}
+
+// startAgent serves the agent on a local port and returns a client for it
+// as relay "site-a"
+func startAgent(t *testing.T, agent *Agent) *Client {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	RegisterWakeRelayServer(server, agent)
+	go server.Serve(listener) //nolint:errcheck
+	t.Cleanup(server.Stop)
+
+	opts := DefaultOptions()
+	opts.Addresses = "site-a=" + listener.Addr().String()
+	client, err := NewClient(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestClientWake(t *testing.T) {
+	sender := &power.MockWolSender{}
+	client := startAgent(t, &Agent{WolSender: sender, Pinger: &power.MockPinger{}})
+	ctx := context.Background()
+
+	if err := client.Wake(ctx, "site-a", "00:11:22:33:44:55", 9, "10.1.0.255"); err != nil {
+		t.Fatalf("Wake() error = %v", err)
+	}
+	if sender.LastMAC != "00:11:22:33:44:55" || sender.LastPort != 9 || sender.LastIP != "10.1.0.255" {
+		t.Errorf("agent sent to %s port %d via %s", sender.LastMAC, sender.LastPort, sender.LastIP)
+	}
+
+	if err := client.Wake(ctx, "site-a", "not-a-mac", 9, ""); err == nil {
+		t.Errorf("Wake() accepted an invalid MAC address")
+	}
+	if err := client.Wake(ctx, "site-b", "00:11:22:33:44:55", 9, ""); err == nil {
+		t.Errorf("Wake() through an unknown relay succeeded")
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	sender.WakeCallCount = 0
+	if err := client.Wake(cancelled, "site-a", "00:11:22:33:44:55", 9, ""); err == nil {
+		t.Errorf("Wake() succeeded with a cancelled context")
+	}
+	if sender.WakeCallCount != 0 {
+		t.Errorf("agent woke the server for a cancelled call")
+	}
+}
+
+func TestClientIsReachable(t *testing.T) {
+	pinger := &power.MockPinger{Reachable: true}
+	client := startAgent(t, &Agent{WolSender: &power.MockWolSender{}, Pinger: pinger})
+	ctx := context.Background()
+
+	if !client.IsReachable(ctx, "site-a", "10.1.0.20") {
+		t.Errorf("IsReachable() = false for a host answering the agent")
+	}
+	if pinger.LastAddress != "10.1.0.20" {
+		t.Errorf("agent pinged %q, want 10.1.0.20", pinger.LastAddress)
+	}
+	pinger.Reachable = false
+	if client.IsReachable(ctx, "site-a", "10.1.0.20") {
+		t.Errorf("IsReachable() = true for a host not answering the agent")
+	}
+	if client.IsReachable(ctx, "site-b", "10.1.0.20") {
+		t.Errorf("IsReachable() = true through an unknown relay")
+	}
+}
+
+func TestClientIsReachableHonorsContext(t *testing.T) {
+	client := startAgent(t, &Agent{WolSender: &power.MockWolSender{}, Pinger: blockingPinger{}})
+
+	// The caller's deadline, not the relay timeout, ends the probe
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if client.IsReachable(ctx, "site-a", "10.1.0.20") {
+		t.Errorf("IsReachable() = true after the caller gave up")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("IsReachable() took %s, want it to stop at the caller's deadline", elapsed)
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{name: "no relays", opts: DefaultOptions()},
+		{name: "relays", opts: Options{Addresses: "site-a=10.1.0.2:50052, site-b=10.2.0.2:50052", Timeout: time.Second}},
+		{name: "missing address", opts: Options{Addresses: "site-a=", Timeout: time.Second}, wantErr: true},
+		{name: "missing name", opts: Options{Addresses: "10.1.0.2:50052", Timeout: time.Second}, wantErr: true},
+		{name: "duplicate relay", opts: Options{Addresses: "site-a=10.1.0.2:50052,site-a=10.1.0.3:50052", Timeout: time.Second}, wantErr: true},
+		{name: "partial TLS", opts: Options{CertFile: "tls.crt", Timeout: time.Second}, wantErr: true},
+		{name: "no timeout", opts: Options{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
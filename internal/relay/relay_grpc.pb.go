@@ -0,0 +1,266 @@
+//
+//Copyright 2025.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.0
+// - protoc             v6.33.2
+// source: internal/relay/relay.proto
+
+package relay
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	WakeRelay_Wake_FullMethodName      = "/baremetal.relay.v1.WakeRelay/Wake"
+	WakeRelay_Probe_FullMethodName     = "/baremetal.relay.v1.WakeRelay/Probe"
+	WakeRelay_Leases_FullMethodName    = "/baremetal.relay.v1.WakeRelay/Leases"
+	WakeRelay_Neighbors_FullMethodName = "/baremetal.relay.v1.WakeRelay/Neighbors"
+)
+
+// WakeRelayClient is the client API for WakeRelay service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// WakeRelay runs on a remote subnet and does what the controller can't do
+// across routers: broadcast Wake-on-LAN packets, ping local hosts, read the
+// leases of the local DHCP server and resolve MAC addresses on the subnet.
+type WakeRelayClient interface {
+	// Wake broadcasts a magic packet on the relay's subnet.
+	Wake(ctx context.Context, in *WakeRequest, opts ...grpc.CallOption) (*WakeResponse, error)
+	// Probe pings a host from the relay.
+	Probe(ctx context.Context, in *ProbeRequest, opts ...grpc.CallOption) (*ProbeResponse, error)
+	// Leases returns the leases handed out by the relay's DHCP server.
+	Leases(ctx context.Context, in *LeasesRequest, opts ...grpc.CallOption) (*LeasesResponse, error)
+	// Neighbors scans the relay's subnets and returns the hosts that answered.
+	Neighbors(ctx context.Context, in *NeighborsRequest, opts ...grpc.CallOption) (*NeighborsResponse, error)
+}
+
+type wakeRelayClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWakeRelayClient(cc grpc.ClientConnInterface) WakeRelayClient {
+	return &wakeRelayClient{cc}
+}
+
+func (c *wakeRelayClient) Wake(ctx context.Context, in *WakeRequest, opts ...grpc.CallOption) (*WakeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WakeResponse)
+	err := c.cc.Invoke(ctx, WakeRelay_Wake_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wakeRelayClient) Probe(ctx context.Context, in *ProbeRequest, opts ...grpc.CallOption) (*ProbeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ProbeResponse)
+	err := c.cc.Invoke(ctx, WakeRelay_Probe_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wakeRelayClient) Leases(ctx context.Context, in *LeasesRequest, opts ...grpc.CallOption) (*LeasesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LeasesResponse)
+	err := c.cc.Invoke(ctx, WakeRelay_Leases_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wakeRelayClient) Neighbors(ctx context.Context, in *NeighborsRequest, opts ...grpc.CallOption) (*NeighborsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(NeighborsResponse)
+	err := c.cc.Invoke(ctx, WakeRelay_Neighbors_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// WakeRelayServer is the server API for WakeRelay service.
+// All implementations must embed UnimplementedWakeRelayServer
+// for forward compatibility.
+//
+// WakeRelay runs on a remote subnet and does what the controller can't do
+// across routers: broadcast Wake-on-LAN packets, ping local hosts, read the
+// leases of the local DHCP server and resolve MAC addresses on the subnet.
+type WakeRelayServer interface {
+	// Wake broadcasts a magic packet on the relay's subnet.
+	Wake(context.Context, *WakeRequest) (*WakeResponse, error)
+	// Probe pings a host from the relay.
+	Probe(context.Context, *ProbeRequest) (*ProbeResponse, error)
+	// Leases returns the leases handed out by the relay's DHCP server.
+	Leases(context.Context, *LeasesRequest) (*LeasesResponse, error)
+	// Neighbors scans the relay's subnets and returns the hosts that answered.
+	Neighbors(context.Context, *NeighborsRequest) (*NeighborsResponse, error)
+	mustEmbedUnimplementedWakeRelayServer()
+}
+
+// UnimplementedWakeRelayServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedWakeRelayServer struct{}
+
+func (UnimplementedWakeRelayServer) Wake(context.Context, *WakeRequest) (*WakeResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Wake not implemented")
+}
+func (UnimplementedWakeRelayServer) Probe(context.Context, *ProbeRequest) (*ProbeResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Probe not implemented")
+}
+func (UnimplementedWakeRelayServer) Leases(context.Context, *LeasesRequest) (*LeasesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Leases not implemented")
+}
+func (UnimplementedWakeRelayServer) Neighbors(context.Context, *NeighborsRequest) (*NeighborsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Neighbors not implemented")
+}
+func (UnimplementedWakeRelayServer) mustEmbedUnimplementedWakeRelayServer() {}
+func (UnimplementedWakeRelayServer) testEmbeddedByValue()                   {}
+
+// UnsafeWakeRelayServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to WakeRelayServer will
+// result in compilation errors.
+type UnsafeWakeRelayServer interface {
+	mustEmbedUnimplementedWakeRelayServer()
+}
+
+func RegisterWakeRelayServer(s grpc.ServiceRegistrar, srv WakeRelayServer) {
+	// If the following call panics, it indicates UnimplementedWakeRelayServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&WakeRelay_ServiceDesc, srv)
+}
+
+func _WakeRelay_Wake_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WakeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WakeRelayServer).Wake(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WakeRelay_Wake_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WakeRelayServer).Wake(ctx, req.(*WakeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WakeRelay_Probe_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProbeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WakeRelayServer).Probe(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WakeRelay_Probe_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WakeRelayServer).Probe(ctx, req.(*ProbeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WakeRelay_Leases_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LeasesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WakeRelayServer).Leases(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WakeRelay_Leases_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WakeRelayServer).Leases(ctx, req.(*LeasesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WakeRelay_Neighbors_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NeighborsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WakeRelayServer).Neighbors(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WakeRelay_Neighbors_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WakeRelayServer).Neighbors(ctx, req.(*NeighborsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// WakeRelay_ServiceDesc is the grpc.ServiceDesc for WakeRelay service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var WakeRelay_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "baremetal.relay.v1.WakeRelay",
+	HandlerType: (*WakeRelayServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Wake",
+			Handler:    _WakeRelay_Wake_Handler,
+		},
+		{
+			MethodName: "Probe",
+			Handler:    _WakeRelay_Probe_Handler,
+		},
+		{
+			MethodName: "Leases",
+			Handler:    _WakeRelay_Leases_Handler,
+		},
+		{
+			MethodName: "Neighbors",
+			Handler:    _WakeRelay_Neighbors_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "internal/relay/relay.proto",
+}
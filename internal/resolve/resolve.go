@@ -0,0 +1,313 @@
+// Package resolve turns the hostnames in server addresses into IP addresses
+// and resolves them again once their DNS records expire, so servers with
+// dynamic DNS names keep being reached after their addresses move.
+package resolve
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// errNoRecords is returned for names without A or AAAA records
+var errNoRecords = errors.New("no A or AAAA records")
+
+// Options contains configuration for resolving hostnames.
+type Options struct {
+	// Nameservers are the DNS servers queried, as comma-separated host:port.
+	// Empty uses the system resolver, which doesn't report TTLs.
+	Nameservers string
+
+	// MinTTL is the shortest time an answer is cached, and how long answers
+	// of the system resolver are cached
+	MinTTL time.Duration
+
+	// MaxTTL is the longest time an answer is cached
+	MaxTTL time.Duration
+
+	// Timeout is the timeout of each query
+	Timeout time.Duration
+}
+
+// DefaultOptions returns the default resolver options, which use the system
+// resolver.
+func DefaultOptions() Options {
+	return Options{
+		MinTTL:  30 * time.Second,
+		MaxTTL:  time.Hour,
+		Timeout: 5 * time.Second,
+	}
+}
+
+// BindFlags binds the resolver options to command line flags.
+// The prefix can be used to namespace the flags (e.g., "resolver-").
+func (o *Options) BindFlags(fs *flag.FlagSet, prefix string) {
+	fs.StringVar(&o.Nameservers, prefix+"nameservers", o.Nameservers,
+		"Comma-separated host:port of DNS servers that resolve hostnames in server addresses. Empty uses the system resolver.")
+	fs.DurationVar(&o.MinTTL, prefix+"min-ttl", o.MinTTL,
+		"Shortest time a resolved address is cached, and how long addresses from the system resolver are cached.")
+	fs.DurationVar(&o.MaxTTL, prefix+"max-ttl", o.MaxTTL,
+		"Longest time a resolved address is cached, whatever the TTL of its record.")
+	fs.DurationVar(&o.Timeout, prefix+"timeout", o.Timeout,
+		"Timeout of each DNS query.")
+}
+
+// Validate validates the options.
+func (o *Options) Validate() error {
+	for _, ns := range splitNameservers(o.Nameservers) {
+		if _, _, err := net.SplitHostPort(ns); err != nil {
+			return fmt.Errorf("invalid nameserver %q: %w", ns, err)
+		}
+	}
+	if o.MinTTL <= 0 || o.MaxTTL < o.MinTTL {
+		return fmt.Errorf("resolver TTLs must be positive, with the maximum at least the minimum")
+	}
+	if o.Timeout <= 0 {
+		return fmt.Errorf("resolver timeout must be positive")
+	}
+	return nil
+}
+
+// Resolver resolves hostnames and caches each answer for the TTL of its
+// records. When a name can't be resolved again, its last address is kept
+// and retried after the minimum TTL, so a DNS outage doesn't make servers
+// unreachable.
+type Resolver struct {
+	options     Options
+	nameservers []string
+
+	mu    sync.Mutex
+	cache map[string]entry
+	now   func() time.Time
+}
+
+// entry is a cached answer
+type entry struct {
+	address string
+	expires time.Time
+}
+
+// New returns a resolver for the options.
+func New(opts Options) (*Resolver, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return &Resolver{
+		options:     opts,
+		nameservers: splitNameservers(opts.Nameservers),
+		cache:       map[string]entry{},
+		now:         time.Now,
+	}, nil
+}
+
+// Resolve returns the IP address of a host, preferring IPv4. IP addresses
+// are returned as they are.
+func (r *Resolver) Resolve(ctx context.Context, host string) (string, error) {
+	if net.ParseIP(host) != nil {
+		return host, nil
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	r.mu.Lock()
+	cached, ok := r.cache[host]
+	r.mu.Unlock()
+	now := r.now()
+	if ok && now.Before(cached.expires) {
+		return cached.address, nil
+	}
+
+	address, ttl, err := r.lookup(ctx, host)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		if !ok {
+			return "", fmt.Errorf("failed to resolve %s: %w", host, err)
+		}
+		cached.expires = now.Add(r.options.MinTTL)
+		r.cache[host] = cached
+		return cached.address, nil
+	}
+	r.cache[host] = entry{address: address, expires: now.Add(r.clamp(ttl))}
+	return address, nil
+}
+
+func (r *Resolver) clamp(ttl time.Duration) time.Duration {
+	return min(max(ttl, r.options.MinTTL), r.options.MaxTTL)
+}
+
+// lookup asks each nameserver in turn, or the system resolver without any
+func (r *Resolver) lookup(ctx context.Context, host string) (string, time.Duration, error) {
+	if len(r.nameservers) == 0 {
+		ctx, cancel := context.WithTimeout(ctx, r.options.Timeout)
+		defer cancel()
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return "", 0, err
+		}
+		ips := make([]net.IP, 0, len(addrs))
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+		if ip := preferIPv4(ips); ip != nil {
+			return ip.String(), r.options.MinTTL, nil
+		}
+		return "", 0, errNoRecords
+	}
+
+	var lastErr error
+	for _, ns := range r.nameservers {
+		for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+			ip, ttl, err := r.query(ctx, ns, host, qtype)
+			if err == nil {
+				return ip.String(), ttl, nil
+			}
+			lastErr = err
+			if !errors.Is(err, errNoRecords) {
+				break
+			}
+		}
+	}
+	return "", 0, lastErr
+}
+
+// query sends one question over UDP, and again over TCP if the answer was
+// truncated. The TTL is the shortest of the answer's records, including the
+// CNAMEs leading to the address.
+func (r *Resolver) query(ctx context.Context, nameserver, host string, qtype dnsmessage.Type) (net.IP, time.Duration, error) {
+	name, err := dnsmessage.NewName(host + ".")
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid hostname %q: %w", host, err)
+	}
+	var idBytes [2]byte
+	if _, err := rand.Read(idBytes[:]); err != nil {
+		return nil, 0, err
+	}
+	id := binary.BigEndian.Uint16(idBytes[:])
+	question := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := question.Pack()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.options.Timeout)
+	defer cancel()
+	resp, err := exchange(ctx, "udp", nameserver, packed)
+	if err != nil {
+		return nil, 0, err
+	}
+	var answer dnsmessage.Message
+	if err := answer.Unpack(resp); err != nil {
+		return nil, 0, fmt.Errorf("invalid answer from %s: %w", nameserver, err)
+	}
+	if answer.Header.Truncated {
+		if resp, err = exchange(ctx, "tcp", nameserver, packed); err != nil {
+			return nil, 0, err
+		}
+		if err := answer.Unpack(resp); err != nil {
+			return nil, 0, fmt.Errorf("invalid answer from %s: %w", nameserver, err)
+		}
+	}
+	if answer.Header.ID != id {
+		return nil, 0, fmt.Errorf("answer from %s doesn't match the query", nameserver)
+	}
+	switch answer.Header.RCode {
+	case dnsmessage.RCodeSuccess:
+	case dnsmessage.RCodeNameError:
+		return nil, 0, errNoRecords
+	default:
+		return nil, 0, fmt.Errorf("%s answered %s", nameserver, answer.Header.RCode)
+	}
+
+	var ips []net.IP
+	ttl := uint32(0)
+	for _, rr := range answer.Answers {
+		if ttl == 0 || rr.Header.TTL < ttl {
+			ttl = rr.Header.TTL
+		}
+		switch body := rr.Body.(type) {
+		case *dnsmessage.AResource:
+			ips = append(ips, net.IP(body.A[:]))
+		case *dnsmessage.AAAAResource:
+			ips = append(ips, net.IP(body.AAAA[:]))
+		}
+	}
+	if len(ips) == 0 {
+		return nil, 0, errNoRecords
+	}
+	return ips[0], time.Duration(ttl) * time.Second, nil
+}
+
+// exchange sends a message and reads the answer, framed with its length over
+// TCP
+func exchange(ctx context.Context, network, nameserver string, msg []byte) ([]byte, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, nameserver)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", nameserver, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if network == "udp" {
+		if _, err := conn.Write(msg); err != nil {
+			return nil, fmt.Errorf("failed to query %s: %w", nameserver, err)
+		}
+		buf := make([]byte, 4096)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read answer from %s: %w", nameserver, err)
+		}
+		return buf[:n], nil
+	}
+
+	framed := binary.BigEndian.AppendUint16(nil, uint16(len(msg)))
+	if _, err := conn.Write(append(framed, msg...)); err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", nameserver, err)
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, fmt.Errorf("failed to read answer from %s: %w", nameserver, err)
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, fmt.Errorf("failed to read answer from %s: %w", nameserver, err)
+	}
+	return resp, nil
+}
+
+func preferIPv4(ips []net.IP) net.IP {
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			return ip
+		}
+	}
+	if len(ips) > 0 {
+		return ips[0]
+	}
+	return nil
+}
+
+func splitNameservers(value string) []string {
+	var nameservers []string
+	for _, ns := range strings.Split(value, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			nameservers = append(nameservers, ns)
+		}
+	}
+	return nameservers
+}
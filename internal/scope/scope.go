@@ -0,0 +1,93 @@
+// Package scope limits which objects a controller instance manages, e.g. to
+// run a canary controller against a subset of machines.
+package scope
+
+import (
+	"flag"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+// Options contains configuration for scoping.
+type Options struct {
+	// WatchNamespace limits namespaced objects, such as credential Secrets
+	// and Tinkerbell objects, to one namespace. Servers are only affected if
+	// they are namespaced.
+	WatchNamespace string
+
+	// NamespacedServers is set when the Server CRD is installed namespaced,
+	// so Servers are looked up by name in every namespace
+	NamespacedServers bool
+
+	// ServerSelector is the label selector of the Servers this instance
+	// manages
+	ServerSelector string
+}
+
+// BindFlags binds the scope options to command line flags.
+// The prefix can be used to namespace the flags.
+func (o *Options) BindFlags(fs *flag.FlagSet, prefix string) {
+	fs.StringVar(&o.WatchNamespace, prefix+"watch-namespace", o.WatchNamespace,
+		"Only read namespaced objects, such as credential Secrets, from this namespace. Empty for all namespaces.")
+	fs.BoolVar(&o.NamespacedServers, prefix+"namespaced-servers", o.NamespacedServers,
+		"Servers are namespaced, as installed by config/namespaced. Their names must be unique across namespaces.")
+	fs.StringVar(&o.ServerSelector, prefix+"server-selector", o.ServerSelector,
+		"Label selector of the Servers this instance manages, e.g. baremetal.io/canary=true. Empty for all servers.")
+}
+
+// Validate validates the options.
+func (o *Options) Validate() error {
+	_, err := o.serverSelector()
+	return err
+}
+
+func (o *Options) serverSelector() (labels.Selector, error) {
+	selector, err := labels.Parse(o.ServerSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server selector: %w", err)
+	}
+	return selector, nil
+}
+
+// Apply restricts the cache to the scope. The server selector is combined
+// with any selector already set for Servers, e.g. by a shard.
+func (o *Options) Apply(opts *cache.Options) error {
+	if o.WatchNamespace != "" {
+		opts.DefaultNamespaces = map[string]cache.Config{o.WatchNamespace: {}}
+	}
+
+	if o.ServerSelector == "" {
+		return nil
+	}
+	selector, err := o.serverSelector()
+	if err != nil {
+		return err
+	}
+
+	if opts.ByObject == nil {
+		opts.ByObject = map[client.Object]cache.ByObject{}
+	}
+	for obj, byObject := range opts.ByObject {
+		if _, ok := obj.(*baremetalcontrollerv1.Server); ok {
+			byObject.Label = and(byObject.Label, selector)
+			opts.ByObject[obj] = byObject
+			return nil
+		}
+	}
+	opts.ByObject[&baremetalcontrollerv1.Server{}] = cache.ByObject{Label: selector}
+	return nil
+}
+
+// and returns a selector matching both a and b
+func and(a, b labels.Selector) labels.Selector {
+	if a == nil {
+		return b
+	}
+	requirements, _ := b.Requirements()
+	return a.Add(requirements...)
+}
@@ -0,0 +1,89 @@
+package scope
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+// serverSelector returns the label selector of Servers in the cache options
+func serverSelector(t *testing.T, opts cache.Options) labels.Selector {
+	t.Helper()
+	for obj, byObject := range opts.ByObject {
+		if _, ok := obj.(*baremetalcontrollerv1.Server); ok {
+			return byObject.Label
+		}
+	}
+	return nil
+}
+
+func TestOptionsValidate(t *testing.T) {
+	for selector, wantErr := range map[string]bool{
+		"":                         false,
+		"baremetal.io/canary=true": false,
+		"rack in (r1, r2)":         false,
+		"rack in (r1":              true,
+	} {
+		opts := Options{ServerSelector: selector}
+		if err := opts.Validate(); (err != nil) != wantErr {
+			t.Errorf("Validate(%q) error = %v, wantErr %v", selector, err, wantErr)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	var opts cache.Options
+	if err := (&Options{}).Apply(&opts); err != nil {
+		t.Fatal(err)
+	}
+	if opts.DefaultNamespaces != nil || opts.ByObject != nil {
+		t.Errorf("Apply() = %+v without a scope, want no restrictions", opts)
+	}
+
+	scope := Options{WatchNamespace: "bmc-system", ServerSelector: "baremetal.io/canary=true"}
+	if err := scope.Apply(&opts); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if _, ok := opts.DefaultNamespaces["bmc-system"]; !ok || len(opts.DefaultNamespaces) != 1 {
+		t.Errorf("DefaultNamespaces = %v, want only bmc-system", opts.DefaultNamespaces)
+	}
+	selector := serverSelector(t, opts)
+	if selector == nil || !selector.Matches(labels.Set{"baremetal.io/canary": "true"}) || selector.Matches(labels.Set{}) {
+		t.Errorf("Server selector = %v", selector)
+	}
+}
+
+func TestApplyCombinesWithShard(t *testing.T) {
+	shard := labels.SelectorFromSet(labels.Set{"baremetal.io/site": "fra1"})
+	opts := cache.Options{
+		ByObject: map[client.Object]cache.ByObject{
+			&baremetalcontrollerv1.Server{}:      {Label: shard},
+			&baremetalcontrollerv1.PowerAction{}: {Label: shard},
+		},
+	}
+	if err := (&Options{ServerSelector: "baremetal.io/canary=true"}).Apply(&opts); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if len(opts.ByObject) != 2 {
+		t.Errorf("ByObject has %d entries, want the Server entry updated in place", len(opts.ByObject))
+	}
+
+	selector := serverSelector(t, opts)
+	tests := []struct {
+		labels labels.Set
+		want   bool
+	}{
+		{labels: labels.Set{"baremetal.io/site": "fra1", "baremetal.io/canary": "true"}, want: true},
+		{labels: labels.Set{"baremetal.io/site": "fra1"}},
+		{labels: labels.Set{"baremetal.io/site": "ams1", "baremetal.io/canary": "true"}},
+	}
+	for _, tt := range tests {
+		if got := selector.Matches(tt.labels); got != tt.want {
+			t.Errorf("selector %s matches %v = %v, want %v", selector, tt.labels, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Package serving serves the HTTPS endpoints of the console proxy and the
+// dashboard, with the certificate from files or a self-signed one.
+package serving
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+
+	certutil "k8s.io/client-go/util/cert"
+)
+
+// ServeTLS serves handler on address until ctx is cancelled. The
+// certificate is loaded from certFile and keyFile, or self-signed for host
+// if they are empty.
+func ServeTLS(ctx context.Context, address, certFile, keyFile, host string, handler http.Handler) error {
+	tlsConfig, err := TLSConfig(certFile, keyFile, host)
+	if err != nil {
+		return err
+	}
+
+	listener, err := tls.Listen("tcp", address, tlsConfig)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+			errChan <- err
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	case err := <-errChan:
+		return err
+	}
+}
+
+// TLSConfig loads the certificate from certFile and keyFile, or generates
+// one self-signed for host if they are empty. Only HTTP/1.1 is offered, as
+// websockets need it.
+func TLSConfig(certFile, keyFile, host string) (*tls.Config, error) {
+	var cert tls.Certificate
+	var err error
+	if certFile != "" {
+		cert, err = tls.LoadX509KeyPair(certFile, keyFile)
+	} else {
+		var certPEM, keyPEM []byte
+		certPEM, keyPEM, err = certutil.GenerateSelfSignedCertKey(host, nil, nil)
+		if err == nil {
+			cert, err = tls.X509KeyPair(certPEM, keyPEM)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"http/1.1"},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
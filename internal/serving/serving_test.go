@@ -0,0 +1,71 @@
+package serving
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTLSConfig(t *testing.T) {
+	config, err := TLSConfig("", "", "bare-metal-controller-test")
+	if err != nil {
+		t.Fatalf("TLSConfig() error = %v", err)
+	}
+	if len(config.Certificates) != 1 || config.MinVersion != tls.VersionTLS12 {
+		t.Errorf("TLSConfig() = %+v, want a self-signed certificate and TLS 1.2", config)
+	}
+
+	missing := filepath.Join(t.TempDir(), "tls.crt")
+	if _, err := TLSConfig(missing, missing, ""); err == nil || !strings.Contains(err.Error(), "failed to load TLS certificate") {
+		t.Errorf("TLSConfig() error = %v, want the certificate not loaded", err)
+	}
+}
+
+func TestServeTLS(t *testing.T) {
+	// Find a free port to listen on
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := l.Addr().String()
+	l.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- ServeTLS(ctx, address, "", "", "localhost", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = io.WriteString(w, "ok")
+		}))
+	}()
+
+	httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	var body []byte
+	for i := 0; i < 50; i++ {
+		resp, err := httpClient.Get("https://" + address + "/")
+		if err == nil {
+			body, _ = io.ReadAll(resp.Body)
+			resp.Body.Close()
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if string(body) != "ok" {
+		t.Errorf("GET = %q, want the handler's response", body)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("ServeTLS() error = %v, want a clean shutdown", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("ServeTLS() didn't return after the context was cancelled")
+	}
+}
@@ -0,0 +1,90 @@
+// Package shard splits the fleet between controller instances. Each instance
+// only watches Servers, PowerActions and RebootCampaigns matching its label
+// selector and holds its own leader election lease, so a site can run a local
+// controller next to its management network.
+package shard
+
+import (
+	"flag"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+// Options contains configuration for sharding.
+type Options struct {
+	// Name identifies the shard and is appended to the leader election ID
+	Name string
+
+	// Selector is the label selector of the objects this shard manages
+	Selector string
+}
+
+// BindFlags binds the shard options to command line flags.
+// The prefix can be used to namespace the flags (e.g., "shard-").
+func (o *Options) BindFlags(fs *flag.FlagSet, prefix string) {
+	fs.StringVar(&o.Name, prefix+"name", o.Name,
+		"Name of this controller shard. Each shard elects its own leader. Empty to manage all servers.")
+	fs.StringVar(&o.Selector, prefix+"selector", o.Selector,
+		"Label selector of the Servers, PowerActions and RebootCampaigns this shard manages, "+
+			"e.g. baremetal.io/site=fra1. Required with --shard-name.")
+}
+
+// Validate validates the options.
+func (o *Options) Validate() error {
+	if (o.Name == "") != (o.Selector == "") {
+		return fmt.Errorf("shard name and selector must be set together, or neither")
+	}
+	if !o.Enabled() {
+		return nil
+	}
+	if errs := validation.IsDNS1123Label(o.Name); len(errs) > 0 {
+		return fmt.Errorf("invalid shard name %q: %v", o.Name, errs)
+	}
+	selector, err := labels.Parse(o.Selector)
+	if err != nil {
+		return fmt.Errorf("invalid shard selector: %w", err)
+	}
+	if selector.Empty() {
+		return fmt.Errorf("shard selector must not be empty")
+	}
+	return nil
+}
+
+// Enabled returns true if this instance only manages a shard of the fleet.
+func (o *Options) Enabled() bool {
+	return o.Name != ""
+}
+
+// LeaderElectionID returns the leader election ID for this shard, derived
+// from the ID used without sharding.
+func (o *Options) LeaderElectionID(id string) string {
+	if !o.Enabled() {
+		return id
+	}
+	return o.Name + "." + id
+}
+
+// CacheOptions restricts the cache to the objects of this shard. ServerClasses
+// and Secrets are shared between shards and stay unfiltered.
+func (o *Options) CacheOptions() (cache.Options, error) {
+	if !o.Enabled() {
+		return cache.Options{}, nil
+	}
+	selector, err := labels.Parse(o.Selector)
+	if err != nil {
+		return cache.Options{}, fmt.Errorf("invalid shard selector: %w", err)
+	}
+	return cache.Options{
+		ByObject: map[client.Object]cache.ByObject{
+			&baremetalcontrollerv1.Server{}:         {Label: selector},
+			&baremetalcontrollerv1.PowerAction{}:    {Label: selector},
+			&baremetalcontrollerv1.RebootCampaign{}: {Label: selector},
+		},
+	}, nil
+}
@@ -0,0 +1,77 @@
+package shard
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{name: "disabled", opts: Options{}},
+		{name: "shard", opts: Options{Name: "fra1", Selector: "baremetal.io/site=fra1"}},
+		{name: "name without selector", opts: Options{Name: "fra1"}, wantErr: true},
+		{name: "selector without name", opts: Options{Selector: "baremetal.io/site=fra1"}, wantErr: true},
+		{name: "invalid name", opts: Options{Name: "Site_FRA1", Selector: "baremetal.io/site=fra1"}, wantErr: true},
+		{name: "invalid selector", opts: Options{Name: "fra1", Selector: "site in (fra1"}, wantErr: true},
+		{name: "empty selector", opts: Options{Name: "fra1", Selector: " "}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLeaderElectionID(t *testing.T) {
+	id := "e8d3a9c1.baremetal.io"
+	if got := (&Options{}).LeaderElectionID(id); got != id {
+		t.Errorf("LeaderElectionID() = %q without sharding, want %q", got, id)
+	}
+	opts := Options{Name: "fra1", Selector: "baremetal.io/site=fra1"}
+	if got := opts.LeaderElectionID(id); got != "fra1."+id {
+		t.Errorf("LeaderElectionID() = %q, want a lease per shard", got)
+	}
+}
+
+func TestCacheOptions(t *testing.T) {
+	opts, err := (&Options{}).CacheOptions()
+	if err != nil || opts.ByObject != nil {
+		t.Errorf("CacheOptions() = %+v, %v without sharding, want no filter", opts, err)
+	}
+
+	shard := Options{Name: "fra1", Selector: "baremetal.io/site=fra1"}
+	opts, err = shard.CacheOptions()
+	if err != nil {
+		t.Fatalf("CacheOptions() error = %v", err)
+	}
+	in := labels.Set{"baremetal.io/site": "fra1"}
+	out := labels.Set{"baremetal.io/site": "ams1"}
+	filtered := map[string]bool{}
+	for obj, byObject := range opts.ByObject {
+		switch obj.(type) {
+		case *baremetalcontrollerv1.Server:
+			filtered["Server"] = true
+		case *baremetalcontrollerv1.PowerAction:
+			filtered["PowerAction"] = true
+		case *baremetalcontrollerv1.RebootCampaign:
+			filtered["RebootCampaign"] = true
+		default:
+			t.Errorf("%T is shared between shards and must not be filtered", obj)
+		}
+		if !byObject.Label.Matches(in) || byObject.Label.Matches(out) {
+			t.Errorf("%T selector %s", obj, byObject.Label)
+		}
+	}
+	if len(filtered) != 3 {
+		t.Errorf("filtered %v, want Servers, PowerActions and RebootCampaigns", filtered)
+	}
+}
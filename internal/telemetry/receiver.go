@@ -0,0 +1,441 @@
+package telemetry
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/index"
+	"github.com/Unbounder1/bare-metal-controller/internal/power"
+)
+
+// maxReportSize limits the size of a posted report or event
+const maxReportSize = 1 << 20
+
+// maxPendingEvents limits the events kept per server until the controller
+// takes them
+const maxPendingEvents = 32
+
+// powerMessages are the keys of the registry messages that report a power
+// state change, e.g. ResourceEvent.1.3.PowerStateChanged or the vendor
+// iLOEvents.2.1.ServerPoweredOff
+var powerMessages = map[string]bool{
+	"PowerStateChanged": true,
+	"PowerOn":           true,
+	"PowerOff":          true,
+	"ServerPoweredOn":   true,
+	"ServerPoweredOff":  true,
+	"SystemPowerOn":     true,
+	"SystemPowerOff":    true,
+}
+
+// Event is a Redfish event a BMC posted, e.g. a power state change or a
+// hardware alert
+type Event struct {
+	Type      string
+	MessageID string
+	Message   string
+	// Severity is OK, Warning or Critical
+	Severity string
+	// Origin is the URI of the resource the event is about
+	Origin string
+}
+
+// PowerChange reports whether the event is about the power state of the
+// system: its message is one of powerMessages, or a change of the
+// ComputerSystem resource itself, whose PowerState the BMC doesn't report
+// with a message of its own. Alerts of power supplies or power controls
+// don't count.
+func (e Event) PowerChange() bool {
+	key := e.MessageID
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		key = key[i+1:]
+	}
+	if powerMessages[key] {
+		return true
+	}
+	if key != "ResourceChanged" && e.Type != "StatusChange" {
+		return false
+	}
+	path := strings.Trim(e.Origin, "/")
+	return strings.HasPrefix(path, "redfish/v1/Systems/") && strings.Count(path, "/") == 3
+}
+
+// reading is what the latest reports of a server's BMC said. Power and
+// temperatures may come in reports of their own, so each is kept with the
+// time it arrived.
+type reading struct {
+	consumedWatts        int32
+	powerReceived        time.Time
+	temperatures         []power.Temperature
+	temperaturesReceived time.Time
+	// events were posted since the controller last took them
+	events []Event
+
+	// token is the context of the server's subscriptions, which the BMC
+	// echoes in every post
+	token string
+	// origin is the address posts must come from, unset if the BMC has no
+	// IP address to check against
+	origin net.IP
+}
+
+// Receiver implements manager.Runnable and keeps the latest reading of each
+// server from the metric reports posted by their BMCs, and the events they
+// posted until the controller takes them.
+type Receiver struct {
+	options Options
+	log     logr.Logger
+
+	mu       sync.Mutex
+	readings map[types.NamespacedName]*reading
+
+	// notify reconciles a server once its BMC posted an event
+	notify chan event.GenericEvent
+}
+
+// Ensure Receiver implements manager.Runnable
+var _ manager.Runnable = &Receiver{}
+
+// NewReceiver creates a new metric report receiver.
+func NewReceiver(opts Options) *Receiver {
+	return &Receiver{
+		options:  opts,
+		log:      ctrl.Log.WithName("telemetry"),
+		readings: map[types.NamespacedName]*reading{},
+		notify:   make(chan event.GenericEvent, 1024),
+	}
+}
+
+// Destination returns the URL a server's BMC posts its reports to
+func (r *Receiver) Destination(server types.NamespacedName) string {
+	return r.options.Destination(server)
+}
+
+// EventDestination returns the URL a server's BMC posts its events to
+func (r *Receiver) EventDestination(server types.NamespacedName) string {
+	return r.options.EventDestination(server)
+}
+
+// Notifications has an event for each server whose BMC posted events, for
+// the controller to reconcile it
+func (r *Receiver) Notifications() <-chan event.GenericEvent {
+	return r.notify
+}
+
+// TakeEvents returns the events posted for a server since it was last
+// called
+func (r *Receiver) TakeEvents(server types.NamespacedName) []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	latest, ok := r.readings[server]
+	if !ok {
+		return nil
+	}
+	events := latest.events
+	latest.events = nil
+	return events
+}
+
+// ConsumedWatts returns the latest reported power draw of a server, if it
+// is no older than maxAge
+func (r *Receiver) ConsumedWatts(server types.NamespacedName, maxAge time.Duration) (int32, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	latest, ok := r.readings[server]
+	if !ok || latest.powerReceived.IsZero() || time.Since(latest.powerReceived) > maxAge {
+		return 0, false
+	}
+	return latest.consumedWatts, true
+}
+
+// Temperatures returns the latest reported temperatures of a server, if
+// they are no older than maxAge. Reports carry no critical thresholds.
+func (r *Receiver) Temperatures(server types.NamespacedName, maxAge time.Duration) ([]power.Temperature, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	latest, ok := r.readings[server]
+	if !ok || latest.temperaturesReceived.IsZero() || time.Since(latest.temperaturesReceived) > maxAge {
+		return nil, false
+	}
+	return append([]power.Temperature(nil), latest.temperatures...), true
+}
+
+// Expect makes the receiver take reports and events for a server, and
+// returns the token to subscribe its BMC with. Posts for other servers, or
+// without the token, are rejected, so nobody but the BMC can post for a
+// server. If origin, the BMC's address, is an IP address, posts must come
+// from it as well. The token stays the same until the server is forgotten.
+func (r *Receiver) Expect(server types.NamespacedName, origin string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	latest, ok := r.readings[server]
+	if !ok {
+		latest = &reading{token: newToken()}
+		r.readings[server] = latest
+	}
+	latest.origin = net.ParseIP(origin)
+	return latest.token
+}
+
+// Expecting reports whether the receiver takes posts for a server. It
+// forgets its servers when the controller restarts, so they have to be
+// subscribed again with a new token.
+func (r *Receiver) Expecting(server types.NamespacedName) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.readings[server]
+	return ok
+}
+
+func newToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to generate subscription token: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// Forget drops the reading of a server that no longer exists
+func (r *Receiver) Forget(server types.NamespacedName) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.readings, server)
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Every
+// replica receives reports, and replicas that don't reconcile the server
+// ignore them.
+func (r *Receiver) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable and receives reports until the context
+// is cancelled.
+func (r *Receiver) Start(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc(PathPrefix, r.ServeHTTP)
+	mux.HandleFunc(EventPathPrefix, r.ServeEvents)
+
+	listener, err := net.Listen("tcp", r.options.Address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", r.options.Address, err)
+	}
+
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+			errChan <- err
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	case err := <-errChan:
+		return fmt.Errorf("telemetry receiver error: %w", err)
+	}
+}
+
+// metricReport is the part of a Redfish MetricReport the receiver reads
+type metricReport struct {
+	Context      string        `json:"Context"`
+	MetricValues []metricValue `json:"MetricValues"`
+}
+
+type metricValue struct {
+	MetricID       string `json:"MetricId"`
+	MetricValue    string `json:"MetricValue"`
+	MetricProperty string `json:"MetricProperty"`
+}
+
+// ServeHTTP takes a report posted to PathPrefix+<server>, see
+// Options.Destination. Values missing
+// from a report, e.g. one of another metric definition, keep their previous
+// reading.
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var report metricReport
+	server, ok := decode(w, req, PathPrefix, &report)
+	if !ok {
+		return
+	}
+
+	watts, temperatures := parseReport(report)
+	now := time.Now()
+	r.mu.Lock()
+	latest, ok := r.authorize(w, req, server, report.Context)
+	if !ok {
+		r.mu.Unlock()
+		return
+	}
+	if watts != nil {
+		latest.consumedWatts, latest.powerReceived = *watts, now
+	}
+	if len(temperatures) > 0 {
+		latest.temperatures, latest.temperaturesReceived = temperatures, now
+	}
+	r.mu.Unlock()
+	r.log.V(1).Info("Received metric report", "server", server, "power", watts != nil, "temperatures", len(temperatures))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// eventRecord is the part of a Redfish Event the receiver reads
+type eventRecord struct {
+	Context string `json:"Context"`
+	Events  []struct {
+		EventType string `json:"EventType"`
+		MessageID string `json:"MessageId"`
+		Message   string `json:"Message"`
+		Severity  string `json:"Severity"`
+		// MessageSeverity replaces Severity in newer schema versions
+		MessageSeverity   string `json:"MessageSeverity"`
+		OriginOfCondition struct {
+			ODataID string `json:"@odata.id"`
+		} `json:"OriginOfCondition"`
+	} `json:"Events"`
+}
+
+// ServeEvents takes events posted to EventPathPrefix+<server> and has the
+// server reconciled. The oldest events are dropped if the controller
+// doesn't keep up.
+func (r *Receiver) ServeEvents(w http.ResponseWriter, req *http.Request) {
+	var record eventRecord
+	server, ok := decode(w, req, EventPathPrefix, &record)
+	if !ok {
+		return
+	}
+
+	r.mu.Lock()
+	latest, ok := r.authorize(w, req, server, record.Context)
+	if !ok {
+		r.mu.Unlock()
+		return
+	}
+	for _, e := range record.Events {
+		severity := e.MessageSeverity
+		if severity == "" {
+			severity = e.Severity
+		}
+		latest.events = append(latest.events, Event{
+			Type:      e.EventType,
+			MessageID: e.MessageID,
+			Message:   e.Message,
+			Severity:  severity,
+			Origin:    e.OriginOfCondition.ODataID,
+		})
+	}
+	if extra := len(latest.events) - maxPendingEvents; extra > 0 {
+		latest.events = latest.events[extra:]
+	}
+	r.mu.Unlock()
+	r.log.V(1).Info("Received events", "server", server, "events", len(record.Events))
+
+	select {
+	case r.notify <- event.GenericEvent{Object: &baremetalcontrollerv1.Server{ObjectMeta: metav1.ObjectMeta{Name: server.Name, Namespace: server.Namespace}}}:
+	default:
+		// The events are taken at the server's next reconcile anyway
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorize returns the reading of a server if the post carries its token
+// and comes from its BMC, or writes the error and returns false. r.mu must
+// be held.
+func (r *Receiver) authorize(w http.ResponseWriter, req *http.Request, server types.NamespacedName, token string) (*reading, bool) {
+	latest, ok := r.readings[server]
+	if !ok {
+		http.Error(w, fmt.Sprintf("no subscription for server %s", index.ServerPath(server)), http.StatusNotFound)
+		return nil, false
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(latest.token)) != 1 {
+		r.log.Info("Rejected post with a wrong context", "server", server, "remote", req.RemoteAddr)
+		http.Error(w, "wrong subscription context", http.StatusForbidden)
+		return nil, false
+	}
+	if latest.origin != nil {
+		host, _, err := net.SplitHostPort(req.RemoteAddr)
+		if err != nil {
+			host = req.RemoteAddr
+		}
+		if remote := net.ParseIP(host); remote == nil || !remote.Equal(latest.origin) {
+			r.log.Info("Rejected post from another address than the BMC", "server", server,
+				"remote", req.RemoteAddr, "bmc", latest.origin.String())
+			http.Error(w, "posts must come from the server's BMC", http.StatusForbidden)
+			return nil, false
+		}
+	}
+	return latest, true
+}
+
+// decode reads the JSON body posted to prefix+<server> and returns the
+// server, or writes the error and returns false
+func decode(w http.ResponseWriter, req *http.Request, prefix string, out interface{}) (types.NamespacedName, bool) {
+	server, ok := index.ParseServerPath(strings.TrimPrefix(req.URL.Path, prefix))
+	if !ok {
+		http.Error(w, "expected "+prefix+"[<namespace>/]<server>", http.StatusNotFound)
+		return server, false
+	}
+	if req.Method != http.MethodPost {
+		http.Error(w, "reports and events must be posted", http.StatusMethodNotAllowed)
+		return server, false
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxReportSize)).Decode(out); err != nil {
+		http.Error(w, fmt.Sprintf("invalid body: %v", err), http.StatusBadRequest)
+		return server, false
+	}
+	return server, true
+}
+
+// parseReport picks the power draw of the first power control and the
+// temperature readings out of a report, as identified by the properties of
+// the Power and Thermal resources they were read from. Temperatures are
+// named by their metric ID, or by their property if they have none.
+func parseReport(report metricReport) (*int32, []power.Temperature) {
+	var watts *int32
+	var temperatures []power.Temperature
+	for _, value := range report.MetricValues {
+		number, err := strconv.ParseFloat(strings.TrimSpace(value.MetricValue), 64)
+		if err != nil {
+			continue
+		}
+		_, pointer, _ := strings.Cut(value.MetricProperty, "#")
+		switch {
+		case strings.HasSuffix(pointer, "/PowerConsumedWatts"):
+			if watts == nil {
+				consumed := int32(number)
+				watts = &consumed
+			}
+		case strings.HasPrefix(pointer, "/Temperatures/") && strings.HasSuffix(pointer, "/ReadingCelsius"):
+			name := value.MetricID
+			if name == "" {
+				name = strings.TrimPrefix(pointer, "/")
+			}
+			temperatures = append(temperatures, power.Temperature{Name: name, Celsius: number})
+		}
+	}
+	return watts, temperatures
+}
@@ -0,0 +1,216 @@
+package telemetry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestEventPowerChange(t *testing.T) {
+	tests := []struct {
+		name  string
+		event Event
+		want  bool
+	}{
+		{
+			name:  "power state changed",
+			event: Event{Type: "Alert", MessageID: "ResourceEvent.1.3.PowerStateChanged"},
+			want:  true,
+		},
+		{
+			name:  "power on",
+			event: Event{MessageID: "Base.1.0.PowerOn"},
+			want:  true,
+		},
+		{
+			name:  "vendor power off",
+			event: Event{MessageID: "iLOEvents.2.1.ServerPoweredOff"},
+			want:  true,
+		},
+		{
+			name:  "computer system changed",
+			event: Event{Type: "StatusChange", MessageID: "ResourceEvent.1.0.ResourceChanged", Origin: "/redfish/v1/Systems/1"},
+			want:  true,
+		},
+		{
+			name:  "computer system changed without trailing path",
+			event: Event{MessageID: "ResourceEvent.1.0.ResourceChanged", Origin: "/redfish/v1/Systems/System.Embedded.1/"},
+			want:  true,
+		},
+		{
+			name:  "power supply failed",
+			event: Event{Type: "Alert", MessageID: "iDRAC.2.8.PSU0003", Message: "Power supply 1 is lost."},
+		},
+		{
+			name:  "power supply changed",
+			event: Event{Type: "StatusChange", MessageID: "ResourceEvent.1.0.ResourceChanged", Origin: "/redfish/v1/Chassis/1/PowerSubsystem/PowerSupplies/0"},
+		},
+		{
+			name:  "power control threshold",
+			event: Event{Type: "Alert", MessageID: "ResourceEvent.1.0.ResourceErrorThresholdExceeded", Message: "Power consumption above limit", Origin: "/redfish/v1/Chassis/1/Power#/PowerControl/0"},
+		},
+		{
+			name:  "memory of the system changed",
+			event: Event{Type: "StatusChange", MessageID: "ResourceEvent.1.0.ResourceChanged", Origin: "/redfish/v1/Systems/1/Memory/DIMM0"},
+		},
+		{
+			name:  "alert about the system",
+			event: Event{Type: "Alert", MessageID: "Base.1.0.GeneralError", Origin: "/redfish/v1/Systems/1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.event.PowerChange(); got != tt.want {
+				t.Errorf("PowerChange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServeEventsAuthorization(t *testing.T) {
+	server := types.NamespacedName{Name: "worker-01"}
+	receiver := NewReceiver(Options{Address: ":0", URL: "http://telemetry.test"})
+	token := receiver.Expect(server, "192.168.1.10")
+	if again := receiver.Expect(server, "192.168.1.10"); again != token {
+		t.Fatalf("Expect() returned token %q, then %q", token, again)
+	}
+	if other := receiver.Expect(types.NamespacedName{Name: "worker-02"}, ""); other == token {
+		t.Fatalf("servers share token %q", token)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		context string
+		remote  string
+		want    int
+		events  int
+	}{
+		{
+			name:    "subscribed BMC",
+			path:    EventPathPrefix + server.Name,
+			context: token,
+			remote:  "192.168.1.10:40000",
+			want:    http.StatusNoContent,
+			events:  1,
+		},
+		{
+			name:    "wrong context",
+			path:    EventPathPrefix + server.Name,
+			context: "guessed",
+			remote:  "192.168.1.10:40000",
+			want:    http.StatusForbidden,
+		},
+		{
+			name:   "no context",
+			path:   EventPathPrefix + server.Name,
+			remote: "192.168.1.10:40000",
+			want:   http.StatusForbidden,
+		},
+		{
+			name:    "other address",
+			path:    EventPathPrefix + server.Name,
+			context: token,
+			remote:  "10.0.0.7:40000",
+			want:    http.StatusForbidden,
+		},
+		{
+			name:    "unknown server",
+			path:    EventPathPrefix + "worker-99",
+			context: token,
+			remote:  "192.168.1.10:40000",
+			want:    http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := fmt.Sprintf(`{"Context": %q, "Events": [{"MessageId": "ResourceEvent.1.3.PowerStateChanged"}]}`, tt.context)
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(body))
+			req.RemoteAddr = tt.remote
+			recorder := httptest.NewRecorder()
+			receiver.ServeEvents(recorder, req)
+			if recorder.Code != tt.want {
+				t.Errorf("ServeEvents() status = %d, want %d", recorder.Code, tt.want)
+			}
+			if events := receiver.TakeEvents(server); len(events) != tt.events {
+				t.Errorf("TakeEvents() = %v, want %d events", events, tt.events)
+			}
+		})
+	}
+}
+
+func TestServeHTTPWithoutOrigin(t *testing.T) {
+	server := types.NamespacedName{Name: "worker-01"}
+	receiver := NewReceiver(Options{Address: ":0", URL: "http://telemetry.test"})
+	// BMCs addressed by hostname can post from any address
+	token := receiver.Expect(server, "bmc-01.example.com")
+
+	body := fmt.Sprintf(`{"Context": %q, "MetricValues": [{"MetricValue": "280",
+		"MetricProperty": "/redfish/v1/Chassis/1/Power#/PowerControl/0/PowerConsumedWatts"}]}`, token)
+	req := httptest.NewRequest(http.MethodPost, PathPrefix+server.Name, strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+	receiver.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("ServeHTTP() status = %d, want %d", recorder.Code, http.StatusNoContent)
+	}
+	if watts, ok := receiver.ConsumedWatts(server, time.Minute); !ok || watts != 280 {
+		t.Errorf("ConsumedWatts() = %d, %v, want 280, true", watts, ok)
+	}
+}
+
+func TestNamespacedServers(t *testing.T) {
+	teamA := types.NamespacedName{Namespace: "team-a", Name: "worker-01"}
+	teamB := types.NamespacedName{Namespace: "team-b", Name: "worker-01"}
+	receiver := NewReceiver(Options{Address: ":0", URL: "http://telemetry.test/"})
+	if got, want := receiver.EventDestination(teamA), "http://telemetry.test/events/team-a/worker-01"; got != want {
+		t.Errorf("EventDestination() = %q, want %q", got, want)
+	}
+	tokenA := receiver.Expect(teamA, "")
+	if tokenB := receiver.Expect(teamB, ""); tokenB == tokenA {
+		t.Fatalf("servers of the same name share token %q", tokenA)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{name: "namespaced", path: EventPathPrefix + "team-a/worker-01", want: http.StatusNoContent},
+		// The token of team-a's server doesn't authorize posts for team-b's
+		{name: "other namespace", path: EventPathPrefix + "team-b/worker-01", want: http.StatusForbidden},
+		{name: "without namespace", path: EventPathPrefix + "worker-01", want: http.StatusNotFound},
+		{name: "empty namespace", path: EventPathPrefix + "/worker-01", want: http.StatusNotFound},
+		{name: "too deep", path: EventPathPrefix + "team-a/worker-01/1", want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := fmt.Sprintf(`{"Context": %q, "Events": [{"MessageId": "ResourceEvent.1.3.PowerStateChanged"}]}`, tokenA)
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(body))
+			recorder := httptest.NewRecorder()
+			receiver.ServeEvents(recorder, req)
+			if recorder.Code != tt.want {
+				t.Errorf("ServeEvents() status = %d, want %d", recorder.Code, tt.want)
+			}
+		})
+	}
+
+	if events := receiver.TakeEvents(teamA); len(events) != 1 {
+		t.Errorf("TakeEvents(%s) = %v, want 1 event", teamA, events)
+	}
+	if events := receiver.TakeEvents(teamB); len(events) != 0 {
+		t.Errorf("TakeEvents(%s) = %v, want none", teamB, events)
+	}
+	select {
+	case e := <-receiver.Notifications():
+		if e.Object.GetNamespace() != teamA.Namespace || e.Object.GetName() != teamA.Name {
+			t.Errorf("notified %s/%s, want %s", e.Object.GetNamespace(), e.Object.GetName(), teamA)
+		}
+	default:
+		t.Errorf("no notification for %s", teamA)
+	}
+}
@@ -0,0 +1,87 @@
+// Package telemetry receives what Redfish BMCs push: metric reports of their
+// TelemetryService and events of their EventService. The server controller
+// subscribes BMCs to the receiver, takes power draw and temperatures from
+// the latest report instead of polling the BMC for them, and reconciles a
+// server as soon as its BMC posts an event, e.g. a power state change.
+package telemetry
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/Unbounder1/bare-metal-controller/internal/index"
+)
+
+const (
+	// PathPrefix is the URL path reports are received under, followed by
+	// the server name, or its namespace and name for namespaced Servers
+	PathPrefix = "/telemetry/"
+
+	// EventPathPrefix is the URL path events are received under, followed
+	// by the server name, or its namespace and name for namespaced Servers
+	EventPathPrefix = "/events/"
+)
+
+// Options contains configuration for the metric report receiver.
+type Options struct {
+	// Address is the address to listen on (e.g., ":8089"), "0" to disable
+	Address string
+
+	// URL is the base URL BMCs reach the receiver at, e.g. through a
+	// Service. Reports and events are posted to the URL followed by
+	// PathPrefix or EventPathPrefix and the server name.
+	URL string
+}
+
+// DefaultOptions returns the default telemetry options.
+func DefaultOptions() Options {
+	return Options{
+		Address: "0",
+	}
+}
+
+// BindFlags binds the telemetry options to command line flags.
+// The prefix can be used to namespace the flags (e.g., "telemetry-").
+func (o *Options) BindFlags(fs *flag.FlagSet, prefix string) {
+	fs.StringVar(&o.Address, prefix+"bind-address", o.Address,
+		"The address the receiver of Redfish metric reports and events binds to. Use 0 to disable it and poll BMCs.")
+	fs.StringVar(&o.URL, prefix+"url", o.URL,
+		"Base URL Redfish BMCs post metric reports and events to, e.g. http://bare-metal-controller-telemetry.bare-metal-system:8089.")
+}
+
+// Validate validates the options.
+func (o *Options) Validate() error {
+	if !o.Enabled() {
+		return nil
+	}
+	if o.URL == "" {
+		return fmt.Errorf("a telemetry URL is required for BMCs to reach the receiver")
+	}
+	u, err := url.Parse(o.URL)
+	if err != nil {
+		return fmt.Errorf("invalid telemetry URL: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("telemetry URL %q must be an absolute http or https URL", o.URL)
+	}
+	return nil
+}
+
+// Enabled returns true if the receiver should be started.
+func (o *Options) Enabled() bool {
+	return o.Address != "" && o.Address != "0"
+}
+
+// Destination returns the URL a server's BMC posts its reports to
+func (o *Options) Destination(server types.NamespacedName) string {
+	return strings.TrimSuffix(o.URL, "/") + PathPrefix + index.ServerPath(server)
+}
+
+// EventDestination returns the URL a server's BMC posts its events to
+func (o *Options) EventDestination(server types.NamespacedName) string {
+	return strings.TrimSuffix(o.URL, "/") + EventPathPrefix + index.ServerPath(server)
+}
@@ -0,0 +1,182 @@
+package tinkerbell
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+// Tinkerbell types are handled as unstructured objects so the controller does
+// not need to depend on the Tinkerbell API module.
+var (
+	// HardwareGVK is the GroupVersionKind of the Tinkerbell Hardware resource.
+	HardwareGVK = schema.GroupVersionKind{Group: "tinkerbell.org", Version: "v1alpha1", Kind: "Hardware"}
+
+	// WorkflowGVK is the GroupVersionKind of the Tinkerbell Workflow resource.
+	WorkflowGVK = schema.GroupVersionKind{Group: "tinkerbell.org", Version: "v1alpha1", Kind: "Workflow"}
+)
+
+// ServerLabel records the name of the Server a Tinkerbell object belongs to.
+const ServerLabel = "baremetal.io/server"
+
+// Workflow states reported by Tinkerbell in status.state
+const (
+	StatePending = "STATE_PENDING"
+	StateRunning = "STATE_RUNNING"
+	StateSuccess = "STATE_SUCCESS"
+	StateFailed  = "STATE_FAILED"
+	StateTimeout = "STATE_TIMEOUT"
+)
+
+// IsTerminal returns true if the workflow state will not change anymore.
+func IsTerminal(state string) bool {
+	return state == StateSuccess || state == StateFailed || state == StateTimeout
+}
+
+// NewHardware returns an empty unstructured Hardware.
+func NewHardware() *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(HardwareGVK)
+	return obj
+}
+
+// NewWorkflow returns an empty unstructured Workflow.
+func NewWorkflow() *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(WorkflowGVK)
+	return obj
+}
+
+// HardwareName returns the name of the Hardware created for a server.
+func HardwareName(server *baremetalcontrollerv1.Server) string {
+	return server.Name
+}
+
+// WorkflowName returns the name of the Workflow created for a server.
+func WorkflowName(server *baremetalcontrollerv1.Server) string {
+	return server.Name + "-provision"
+}
+
+// macAddress returns the netboot MAC address, defaulting to the WOL MAC.
+func macAddress(server *baremetalcontrollerv1.Server) string {
+	tb := server.Spec.Provisioning.Tinkerbell
+	if tb.MACAddress != "" {
+		return tb.MACAddress
+	}
+	if server.Spec.Control.WOL != nil {
+		return server.Spec.Control.WOL.MACAddress
+	}
+	return ""
+}
+
+// ipAddress returns the DHCP address, defaulting to the WOL address.
+func ipAddress(server *baremetalcontrollerv1.Server) string {
+	tb := server.Spec.Provisioning.Tinkerbell
+	if tb.IPAddress != "" {
+		return tb.IPAddress
+	}
+	if server.Spec.Control.WOL != nil {
+		return server.Spec.Control.WOL.Address
+	}
+	return ""
+}
+
+// HardwareForServer builds the Hardware describing a server's netboot
+// interface. Netboot is allowed until provisioning succeeds.
+func HardwareForServer(server *baremetalcontrollerv1.Server) (*unstructured.Unstructured, error) {
+	tb := server.Spec.Provisioning.Tinkerbell
+
+	mac := macAddress(server)
+	if mac == "" {
+		return nil, fmt.Errorf("tinkerbell MAC address is required")
+	}
+	ip := ipAddress(server)
+	if ip == "" {
+		return nil, fmt.Errorf("tinkerbell IP address is required")
+	}
+
+	ipSpec := map[string]interface{}{"address": ip}
+	if tb.Netmask != "" {
+		ipSpec["netmask"] = tb.Netmask
+	}
+	if tb.Gateway != "" {
+		ipSpec["gateway"] = tb.Gateway
+	}
+
+	hw := NewHardware()
+	hw.SetName(HardwareName(server))
+	hw.SetNamespace(tb.Namespace)
+	hw.SetLabels(map[string]string{ServerLabel: server.Name})
+	hw.Object["spec"] = map[string]interface{}{
+		"interfaces": []interface{}{
+			map[string]interface{}{
+				"dhcp": map[string]interface{}{
+					"hostname": server.Name,
+					"mac":      mac,
+					"ip":       ipSpec,
+				},
+				"netboot": map[string]interface{}{
+					"allowPXE":      true,
+					"allowWorkflow": true,
+				},
+			},
+		},
+	}
+	return hw, nil
+}
+
+// WorkflowForServer builds the Workflow running the server's template
+// against its Hardware.
+func WorkflowForServer(server *baremetalcontrollerv1.Server) *unstructured.Unstructured {
+	tb := server.Spec.Provisioning.Tinkerbell
+
+	wf := NewWorkflow()
+	wf.SetName(WorkflowName(server))
+	wf.SetNamespace(tb.Namespace)
+	wf.SetLabels(map[string]string{ServerLabel: server.Name})
+	wf.Object["spec"] = map[string]interface{}{
+		"templateRef": tb.TemplateRef,
+		"hardwareRef": HardwareName(server),
+		"hardwareMap": map[string]interface{}{
+			"device_1": macAddress(server),
+		},
+	}
+	return wf
+}
+
+// WorkflowState returns the state reported in a Workflow's status.
+func WorkflowState(wf *unstructured.Unstructured) string {
+	state, _, _ := unstructured.NestedString(wf.Object, "status", "state")
+	if state == "" {
+		return StatePending
+	}
+	return state
+}
+
+// DisableNetboot turns off PXE on every interface of a Hardware so a
+// provisioned machine boots from disk instead of looping back into the
+// installer. It returns true if the Hardware was modified.
+func DisableNetboot(hw *unstructured.Unstructured) bool {
+	interfaces, _, _ := unstructured.NestedSlice(hw.Object, "spec", "interfaces")
+
+	changed := false
+	for _, iface := range interfaces {
+		m, ok := iface.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		allowPXE, _, _ := unstructured.NestedBool(m, "netboot", "allowPXE")
+		if allowPXE {
+			_ = unstructured.SetNestedField(m, false, "netboot", "allowPXE")
+			changed = true
+		}
+	}
+
+	if changed {
+		_ = unstructured.SetNestedSlice(hw.Object, interfaces, "spec", "interfaces")
+	}
+	return changed
+}
@@ -0,0 +1,168 @@
+package tinkerbell
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+// tinkerbellServer returns a WoL server provisioned with the Tinkerbell spec
+func tinkerbellServer(spec baremetalcontrollerv1.TinkerbellSpec) *baremetalcontrollerv1.Server {
+	spec.Namespace = "tink-system"
+	spec.TemplateRef = "ubuntu-2404"
+	return &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-01"},
+		Spec: baremetalcontrollerv1.ServerSpec{
+			Type: baremetalcontrollerv1.ControlTypeWOL,
+			Control: baremetalcontrollerv1.ControlSpecs{
+				WOL: &baremetalcontrollerv1.WOLSpecs{Address: "192.168.1.100", MACAddress: "00:11:22:33:44:55"},
+			},
+			Provisioning: &baremetalcontrollerv1.ProvisioningSpec{Tinkerbell: &spec},
+		},
+	}
+}
+
+func TestHardwareForServer(t *testing.T) {
+	noWOL := tinkerbellServer(baremetalcontrollerv1.TinkerbellSpec{})
+	noWOL.Spec.Control.WOL = nil
+	noIP := tinkerbellServer(baremetalcontrollerv1.TinkerbellSpec{MACAddress: "aa:bb:cc:dd:ee:ff"})
+	noIP.Spec.Control.WOL = nil
+
+	tests := []struct {
+		name    string
+		server  *baremetalcontrollerv1.Server
+		wantMAC string
+		wantIP  map[string]interface{}
+		wantErr string
+	}{
+		{
+			name:    "addresses from WoL",
+			server:  tinkerbellServer(baremetalcontrollerv1.TinkerbellSpec{}),
+			wantMAC: "00:11:22:33:44:55",
+			wantIP:  map[string]interface{}{"address": "192.168.1.100"},
+		},
+		{
+			name: "netboot interface",
+			server: tinkerbellServer(baremetalcontrollerv1.TinkerbellSpec{
+				MACAddress: "aa:bb:cc:dd:ee:ff",
+				IPAddress:  "10.0.0.20",
+				Netmask:    "255.255.255.0",
+				Gateway:    "10.0.0.1",
+			}),
+			wantMAC: "aa:bb:cc:dd:ee:ff",
+			wantIP:  map[string]interface{}{"address": "10.0.0.20", "netmask": "255.255.255.0", "gateway": "10.0.0.1"},
+		},
+		{name: "no MAC address", server: noWOL, wantErr: "MAC address is required"},
+		{name: "no IP address", server: noIP, wantErr: "IP address is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hw, err := HardwareForServer(tt.server)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("HardwareForServer() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("HardwareForServer() error = %v", err)
+			}
+			if hw.GetName() != "worker-01" || hw.GetNamespace() != "tink-system" || hw.GetLabels()[ServerLabel] != "worker-01" {
+				t.Errorf("hardware %s/%s labels %v", hw.GetNamespace(), hw.GetName(), hw.GetLabels())
+			}
+			interfaces, _, _ := unstructured.NestedSlice(hw.Object, "spec", "interfaces")
+			if len(interfaces) != 1 {
+				t.Fatalf("interfaces = %v, want one", interfaces)
+			}
+			iface := interfaces[0].(map[string]interface{})
+			if mac, _, _ := unstructured.NestedString(iface, "dhcp", "mac"); mac != tt.wantMAC {
+				t.Errorf("mac = %q, want %q", mac, tt.wantMAC)
+			}
+			ip, _, _ := unstructured.NestedMap(iface, "dhcp", "ip")
+			if len(ip) != len(tt.wantIP) {
+				t.Errorf("ip = %v, want %v", ip, tt.wantIP)
+			}
+			for key, want := range tt.wantIP {
+				if ip[key] != want {
+					t.Errorf("ip %s = %v, want %v", key, ip[key], want)
+				}
+			}
+			if allowPXE, _, _ := unstructured.NestedBool(iface, "netboot", "allowPXE"); !allowPXE {
+				t.Errorf("new hardware doesn't allow PXE")
+			}
+		})
+	}
+}
+
+func TestWorkflowForServer(t *testing.T) {
+	wf := WorkflowForServer(tinkerbellServer(baremetalcontrollerv1.TinkerbellSpec{MACAddress: "aa:bb:cc:dd:ee:ff"}))
+	if wf.GetName() != "worker-01-provision" || wf.GetNamespace() != "tink-system" {
+		t.Errorf("workflow = %s/%s", wf.GetNamespace(), wf.GetName())
+	}
+	if ref, _, _ := unstructured.NestedString(wf.Object, "spec", "templateRef"); ref != "ubuntu-2404" {
+		t.Errorf("templateRef = %q", ref)
+	}
+	if ref, _, _ := unstructured.NestedString(wf.Object, "spec", "hardwareRef"); ref != "worker-01" {
+		t.Errorf("hardwareRef = %q, want the server's Hardware", ref)
+	}
+	if mac, _, _ := unstructured.NestedString(wf.Object, "spec", "hardwareMap", "device_1"); mac != "aa:bb:cc:dd:ee:ff" {
+		t.Errorf("device_1 = %q, want the netboot MAC", mac)
+	}
+}
+
+func TestWorkflowState(t *testing.T) {
+	tests := []struct {
+		state    string
+		want     string
+		terminal bool
+	}{
+		{state: "", want: StatePending},
+		{state: StateRunning, want: StateRunning},
+		{state: StateSuccess, want: StateSuccess, terminal: true},
+		{state: StateFailed, want: StateFailed, terminal: true},
+		{state: StateTimeout, want: StateTimeout, terminal: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			wf := NewWorkflow()
+			if tt.state != "" {
+				_ = unstructured.SetNestedField(wf.Object, tt.state, "status", "state")
+			}
+			got := WorkflowState(wf)
+			if got != tt.want {
+				t.Errorf("WorkflowState() = %q, want %q", got, tt.want)
+			}
+			if IsTerminal(got) != tt.terminal {
+				t.Errorf("IsTerminal(%q) = %v, want %v", got, !tt.terminal, tt.terminal)
+			}
+		})
+	}
+}
+
+func TestDisableNetboot(t *testing.T) {
+	hw, err := HardwareForServer(tinkerbellServer(baremetalcontrollerv1.TinkerbellSpec{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !DisableNetboot(hw) {
+		t.Fatalf("DisableNetboot() = false for hardware allowing PXE")
+	}
+	interfaces, _, _ := unstructured.NestedSlice(hw.Object, "spec", "interfaces")
+	iface := interfaces[0].(map[string]interface{})
+	if allowPXE, _, _ := unstructured.NestedBool(iface, "netboot", "allowPXE"); allowPXE {
+		t.Errorf("allowPXE still set")
+	}
+	if allowWorkflow, _, _ := unstructured.NestedBool(iface, "netboot", "allowWorkflow"); !allowWorkflow {
+		t.Errorf("allowWorkflow was cleared too")
+	}
+
+	// Nothing to update once netboot is off
+	if DisableNetboot(hw) {
+		t.Errorf("DisableNetboot() = true for hardware already booting from disk")
+	}
+}
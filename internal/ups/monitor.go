@@ -0,0 +1,372 @@
+// Package ups shuts servers down in priority order when a UPS monitored by
+// Network UPS Tools runs on battery, and powers them back on once utility
+// power returns.
+package ups
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/drain"
+	"github.com/Unbounder1/bare-metal-controller/internal/listing"
+)
+
+// Options contains configuration for the UPS monitor.
+type Options struct {
+	// Address is the host:port of the upsd server. Empty disables the
+	// monitor.
+	Address string
+
+	// UPS is the name of the UPS on the upsd server
+	UPS string
+
+	// PollInterval is how often ups.status is read
+	PollInterval time.Duration
+
+	// OnBatteryGrace is how long the UPS must run on battery before servers
+	// are shut down, to ride out short outages. Low battery shuts down
+	// right away.
+	OnBatteryGrace time.Duration
+
+	// DrainTimeout bounds how long a server's node is drained before it is
+	// powered off anyway
+	DrainTimeout time.Duration
+
+	// RestoreDelay is how long utility power must be back before servers
+	// are powered on again
+	RestoreDelay time.Duration
+}
+
+// DefaultOptions returns the default UPS monitor options.
+func DefaultOptions() Options {
+	return Options{
+		UPS:            "ups",
+		PollInterval:   5 * time.Second,
+		OnBatteryGrace: time.Minute,
+		DrainTimeout:   2 * time.Minute,
+		RestoreDelay:   5 * time.Minute,
+	}
+}
+
+// BindFlags binds the UPS options to command line flags.
+// The prefix can be used to namespace the flags (e.g., "ups-").
+func (o *Options) BindFlags(fs *flag.FlagSet, prefix string) {
+	fs.StringVar(&o.Address, prefix+"address", o.Address,
+		"host:port of the NUT upsd server, e.g. nut.example.com:3493. Empty to disable power-loss shutdown.")
+	fs.StringVar(&o.UPS, prefix+"name", o.UPS,
+		"Name of the UPS on the upsd server.")
+	fs.DurationVar(&o.PollInterval, prefix+"poll-interval", o.PollInterval,
+		"How often to read the UPS status.")
+	fs.DurationVar(&o.OnBatteryGrace, prefix+"on-battery-grace", o.OnBatteryGrace,
+		"How long the UPS must run on battery before servers are shut down. Low battery shuts down right away.")
+	fs.DurationVar(&o.DrainTimeout, prefix+"drain-timeout", o.DrainTimeout,
+		"How long to drain a server's node before powering it off anyway.")
+	fs.DurationVar(&o.RestoreDelay, prefix+"restore-delay", o.RestoreDelay,
+		"How long utility power must be back before servers are powered on again.")
+}
+
+// Validate validates the options.
+func (o *Options) Validate() error {
+	if !o.Enabled() {
+		return nil
+	}
+	if o.UPS == "" {
+		return fmt.Errorf("UPS name is required")
+	}
+	if o.PollInterval <= 0 {
+		return fmt.Errorf("UPS poll interval must be positive")
+	}
+	if o.OnBatteryGrace < 0 || o.DrainTimeout < 0 || o.RestoreDelay < 0 {
+		return fmt.Errorf("UPS delays must not be negative")
+	}
+	return nil
+}
+
+// Enabled returns true if a UPS should be monitored.
+func (o *Options) Enabled() bool {
+	return o.Address != ""
+}
+
+// Monitor implements manager.Runnable. It polls the UPS and shuts down the
+// Servers labeled with a priority, lowest priority first, one pool after
+// another. Servers it powered off are annotated, so they are powered back on
+// highest priority first after a controller restart too.
+type Monitor struct {
+	options  Options
+	nut      *nutClient
+	client   client.Client
+	reader   client.Reader
+	recorder record.EventRecorder
+
+	onBatterySince time.Time
+	onLineSince    time.Time
+	drainStarted   map[string]time.Time
+}
+
+// Ensure Monitor implements manager.Runnable
+var _ manager.Runnable = &Monitor{}
+
+// NewMonitor creates a new UPS monitor.
+func NewMonitor(opts Options, mgr manager.Manager) (*Monitor, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return &Monitor{
+		options:      opts,
+		nut:          &nutClient{address: opts.Address, timeout: 10 * time.Second},
+		client:       mgr.GetClient(),
+		reader:       mgr.GetAPIReader(),
+		recorder:     mgr.GetEventRecorderFor("ups-monitor"),
+		drainStarted: map[string]time.Time{},
+	}, nil
+}
+
+// Start implements manager.Runnable. It polls the UPS until ctx is done.
+func (m *Monitor) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("ups")
+	logger.Info("Monitoring UPS", "address", m.options.Address, "ups", m.options.UPS)
+
+	ticker := time.NewTicker(m.options.PollInterval)
+	defer ticker.Stop()
+
+	var last Status
+	for {
+		status, err := m.nut.status(m.options.UPS)
+		if err != nil {
+			// Don't shut anything down just because upsd is unreachable
+			logger.Error(err, "Failed to read UPS status")
+		} else {
+			if status != last {
+				logger.Info("UPS status changed", "status", status.String())
+				last = status
+			}
+			if err := m.step(ctx, status); err != nil {
+				logger.Error(err, "Failed to handle UPS status", "status", status.String())
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+// Returns true so only one replica powers servers off and on.
+func (m *Monitor) NeedLeaderElection() bool {
+	return true
+}
+
+// step acts on one UPS status reading
+func (m *Monitor) step(ctx context.Context, status Status) error {
+	now := time.Now()
+	if status.OnLine() {
+		m.onBatterySince = time.Time{}
+		if m.onLineSince.IsZero() {
+			m.onLineSince = now
+		}
+		if err := m.cancelDrains(ctx); err != nil {
+			return err
+		}
+		if now.Sub(m.onLineSince) < m.options.RestoreDelay {
+			return nil
+		}
+		return m.restore(ctx)
+	}
+
+	m.onLineSince = time.Time{}
+	if m.onBatterySince.IsZero() {
+		m.onBatterySince = now
+	}
+	if !status.LowBattery && now.Sub(m.onBatterySince) < m.options.OnBatteryGrace {
+		return nil
+	}
+	return m.shed(ctx, status.LowBattery)
+}
+
+// shed drains and powers off the labeled servers, one pool at a time in
+// ascending priority. The next pool waits until the previous one is off,
+// unless the battery is low, in which case every pool is powered off at once
+// without draining.
+func (m *Monitor) shed(ctx context.Context, urgent bool) error {
+	pools, err := m.pools(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, pool := range pools {
+		done := true
+		for i := range pool.servers {
+			server := &pool.servers[i]
+			if server.Annotations[baremetalcontrollerv1.UPSShutdownAnnotation] == "true" {
+				if server.Status.Status != baremetalcontrollerv1.StatusOffline &&
+					server.Status.Status != baremetalcontrollerv1.StatusFailed {
+					done = false
+				}
+				continue
+			}
+			if server.Spec.PowerState.Settled() != baremetalcontrollerv1.PowerStateOn {
+				// Already off, and not ours to power on later
+				continue
+			}
+
+			done = false
+			if !urgent && !m.drained(ctx, server) {
+				continue
+			}
+			if err := m.setPower(ctx, server, baremetalcontrollerv1.PowerStateOff); err != nil {
+				return err
+			}
+		}
+		if !done && !urgent {
+			return nil
+		}
+	}
+	return nil
+}
+
+// drained drains the server's node until it is empty or the drain timeout
+// passes
+func (m *Monitor) drained(ctx context.Context, server *baremetalcontrollerv1.Server) bool {
+	started, ok := m.drainStarted[server.NodeName()]
+	if !ok {
+		started = time.Now()
+		m.drainStarted[server.NodeName()] = started
+		m.recorder.Event(server, corev1.EventTypeNormal, "UPSShutdown", "UPS is on battery, draining before power off")
+	}
+
+	drained, err := drain.Node(ctx, m.client, m.reader, server.NodeName())
+	if err != nil {
+		log.FromContext(ctx).WithName("ups").Error(err, "Failed to drain node", "server", server.Name)
+	}
+	return drained || time.Since(started) >= m.options.DrainTimeout
+}
+
+// cancelDrains uncordons the nodes still being drained when utility power
+// returned before their servers were powered off
+func (m *Monitor) cancelDrains(ctx context.Context) error {
+	for name := range m.drainStarted {
+		if err := drain.Uncordon(ctx, m.client, name); err != nil {
+			return fmt.Errorf("failed to uncordon node %s: %w", name, err)
+		}
+		delete(m.drainStarted, name)
+	}
+	return nil
+}
+
+// restore powers on the servers shut down for power loss, one pool at a time
+// in descending priority. The next pool waits until the previous one is
+// active.
+func (m *Monitor) restore(ctx context.Context) error {
+	pools, err := m.pools(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := len(pools) - 1; i >= 0; i-- {
+		done := true
+		for j := range pools[i].servers {
+			server := &pools[i].servers[j]
+			if server.Annotations[baremetalcontrollerv1.UPSShutdownAnnotation] == "true" {
+				if err := m.setPower(ctx, server, baremetalcontrollerv1.PowerStateOn); err != nil {
+					return err
+				}
+				if err := drain.Uncordon(ctx, m.client, server.NodeName()); err != nil {
+					return fmt.Errorf("failed to uncordon node %s: %w", server.NodeName(), err)
+				}
+				done = false
+				continue
+			}
+			if server.Spec.PowerState.Settled() == baremetalcontrollerv1.PowerStateOn &&
+				server.Status.Status != baremetalcontrollerv1.StatusActive &&
+				server.Status.Status != baremetalcontrollerv1.StatusFailed {
+				done = false
+			}
+		}
+		if !done {
+			return nil
+		}
+	}
+	return nil
+}
+
+// setPower changes the desired power state, marking servers powered off for
+// power loss so only those are powered on again
+func (m *Monitor) setPower(ctx context.Context, server *baremetalcontrollerv1.Server, state baremetalcontrollerv1.PowerState) error {
+	patch := client.MergeFrom(server.DeepCopy())
+	server.Spec.PowerState = state
+	if state == baremetalcontrollerv1.PowerStateOff {
+		if server.Annotations == nil {
+			server.Annotations = map[string]string{}
+		}
+		server.Annotations[baremetalcontrollerv1.UPSShutdownAnnotation] = "true"
+	} else {
+		delete(server.Annotations, baremetalcontrollerv1.UPSShutdownAnnotation)
+	}
+	if err := m.client.Patch(ctx, server, patch); err != nil {
+		return fmt.Errorf("failed to power %s server %s: %w", state, server.Name, err)
+	}
+
+	delete(m.drainStarted, server.Name)
+	if state == baremetalcontrollerv1.PowerStateOff {
+		m.recorder.Event(server, corev1.EventTypeNormal, "UPSShutdown", "Powering off for UPS power loss")
+	} else {
+		m.recorder.Event(server, corev1.EventTypeNormal, "UPSRestore", "Utility power restored, powering on")
+	}
+	return nil
+}
+
+// pool is the servers sharing a priority
+type pool struct {
+	priority int
+	servers  []baremetalcontrollerv1.Server
+}
+
+// pools returns the labeled servers grouped by priority, lowest first.
+// Servers with a priority that isn't a number are skipped.
+func (m *Monitor) pools(ctx context.Context) ([]pool, error) {
+	hasPriority, err := labels.NewRequirement(baremetalcontrollerv1.UPSPriorityLabel, selection.Exists, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	byPriority := map[int]*pool{}
+	err = listing.Servers(ctx, m.client, 0, func(server *baremetalcontrollerv1.Server) error {
+		priority, err := strconv.Atoi(server.Labels[baremetalcontrollerv1.UPSPriorityLabel])
+		if err != nil {
+			return nil
+		}
+		p, ok := byPriority[priority]
+		if !ok {
+			p = &pool{priority: priority}
+			byPriority[priority] = p
+		}
+		p.servers = append(p.servers, *server.DeepCopy())
+		return nil
+	}, client.MatchingLabelsSelector{Selector: labels.NewSelector().Add(*hasPriority)})
+	if err != nil {
+		return nil, err
+	}
+
+	pools := make([]pool, 0, len(byPriority))
+	for _, p := range byPriority {
+		pools = append(pools, *p)
+	}
+	sort.Slice(pools, func(i, j int) bool { return pools[i].priority < pools[j].priority })
+	return pools, nil
+}
@@ -0,0 +1,225 @@
+package ups
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+func upsServer(name string, priority string, power baremetalcontrollerv1.PowerState) *baremetalcontrollerv1.Server {
+	server := &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       baremetalcontrollerv1.ServerSpec{PowerState: power},
+	}
+	if priority != "" {
+		server.Labels = map[string]string{baremetalcontrollerv1.UPSPriorityLabel: priority}
+	}
+	return server
+}
+
+func newTestMonitor(t *testing.T, objs ...client.Object) (*Monitor, client.Client) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := baremetalcontrollerv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).
+		WithIndex(&corev1.Pod{}, "spec.nodeName", func(obj client.Object) []string {
+			return []string{obj.(*corev1.Pod).Spec.NodeName}
+		}).Build()
+	opts := DefaultOptions()
+	opts.Address = "127.0.0.1:3493"
+	return &Monitor{
+		options:      opts,
+		client:       c,
+		reader:       c,
+		recorder:     record.NewFakeRecorder(100),
+		drainStarted: map[string]time.Time{},
+	}, c
+}
+
+func getServer(t *testing.T, c client.Client, name string) *baremetalcontrollerv1.Server {
+	t.Helper()
+	var server baremetalcontrollerv1.Server
+	if err := c.Get(context.Background(), client.ObjectKey{Name: name}, &server); err != nil {
+		t.Fatal(err)
+	}
+	return &server
+}
+
+// setStatus reports the server reached a state, as the server controller
+// would
+func setStatus(t *testing.T, c client.Client, name string, status baremetalcontrollerv1.CurrentStatus) {
+	t.Helper()
+	server := getServer(t, c, name)
+	server.Status.Status = status
+	if err := c.Update(context.Background(), server); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMonitorShedsAndRestoresByPriority(t *testing.T) {
+	on, off := baremetalcontrollerv1.PowerStateOn, baremetalcontrollerv1.PowerStateOff
+	m, c := newTestMonitor(t,
+		upsServer("web-01", "1", on),
+		upsServer("web-02", "1", off),
+		upsServer("db-01", "10", on),
+		upsServer("storage-01", "", on),
+		upsServer("gpu-01", "high", on),
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "web-01"}},
+	)
+	ctx := context.Background()
+	powers := func(want map[string]baremetalcontrollerv1.PowerState) {
+		t.Helper()
+		for name, state := range want {
+			if got := getServer(t, c, name).Spec.PowerState; got != state {
+				t.Errorf("%s powerState = %s, want %s", name, got, state)
+			}
+		}
+	}
+
+	// Short outages are ridden out
+	onBattery := Status{OnBattery: true}
+	if err := m.step(ctx, onBattery); err != nil {
+		t.Fatal(err)
+	}
+	powers(map[string]baremetalcontrollerv1.PowerState{"web-01": on, "db-01": on})
+
+	// The lowest priority goes first, the next waits until it is off
+	m.onBatterySince = m.onBatterySince.Add(-m.options.OnBatteryGrace)
+	if err := m.step(ctx, onBattery); err != nil {
+		t.Fatal(err)
+	}
+	powers(map[string]baremetalcontrollerv1.PowerState{"web-01": off, "db-01": on})
+	var node corev1.Node
+	if err := c.Get(ctx, client.ObjectKey{Name: "web-01"}, &node); err != nil || !node.Spec.Unschedulable {
+		t.Errorf("web-01 node not cordoned before power off")
+	}
+	setStatus(t, c, "web-01", baremetalcontrollerv1.StatusOffline)
+	if err := m.step(ctx, onBattery); err != nil {
+		t.Fatal(err)
+	}
+	powers(map[string]baremetalcontrollerv1.PowerState{"db-01": off, "storage-01": on, "gpu-01": on})
+	if web02 := getServer(t, c, "web-02"); web02.Annotations[baremetalcontrollerv1.UPSShutdownAnnotation] != "" {
+		t.Errorf("server off before the outage marked as shut down for it")
+	}
+	setStatus(t, c, "db-01", baremetalcontrollerv1.StatusOffline)
+
+	// Power must stay back for the restore delay
+	if err := m.step(ctx, Status{}); err != nil {
+		t.Fatal(err)
+	}
+	powers(map[string]baremetalcontrollerv1.PowerState{"web-01": off, "db-01": off})
+
+	// The highest priority comes back first
+	m.onLineSince = m.onLineSince.Add(-m.options.RestoreDelay)
+	if err := m.step(ctx, Status{}); err != nil {
+		t.Fatal(err)
+	}
+	powers(map[string]baremetalcontrollerv1.PowerState{"web-01": off, "db-01": on})
+	setStatus(t, c, "db-01", baremetalcontrollerv1.StatusActive)
+	if err := m.step(ctx, Status{}); err != nil {
+		t.Fatal(err)
+	}
+	powers(map[string]baremetalcontrollerv1.PowerState{"web-01": on, "web-02": off})
+	if web01 := getServer(t, c, "web-01"); web01.Annotations[baremetalcontrollerv1.UPSShutdownAnnotation] != "" {
+		t.Errorf("web-01 still marked as shut down for power loss")
+	}
+	if err := c.Get(ctx, client.ObjectKey{Name: "web-01"}, &node); err != nil || node.Spec.Unschedulable {
+		t.Errorf("web-01 node still cordoned after restore")
+	}
+}
+
+func TestMonitorLowBatteryShedsEverything(t *testing.T) {
+	on, off := baremetalcontrollerv1.PowerStateOn, baremetalcontrollerv1.PowerStateOff
+	m, c := newTestMonitor(t,
+		upsServer("web-01", "1", on),
+		upsServer("db-01", "10", on),
+		upsServer("storage-01", "", on),
+	)
+
+	// No grace and no waiting for pools to power off
+	if err := m.step(context.Background(), Status{OnBattery: true, LowBattery: true}); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]baremetalcontrollerv1.PowerState{"web-01": off, "db-01": off, "storage-01": on} {
+		if got := getServer(t, c, name).Spec.PowerState; got != want {
+			t.Errorf("%s powerState = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestMonitorCancelsDrains(t *testing.T) {
+	m, c := newTestMonitor(t,
+		upsServer("web-01", "1", baremetalcontrollerv1.PowerStateOn),
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "web-01"}},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:       corev1.PodSpec{NodeName: "web-01"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+	)
+	ctx := context.Background()
+	m.options.OnBatteryGrace = 0
+
+	// The pod is still being evicted, so the server stays on
+	if err := m.step(ctx, Status{OnBattery: true}); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.drainStarted) != 1 {
+		t.Fatalf("drains = %v, want web-01 draining", m.drainStarted)
+	}
+	if got := getServer(t, c, "web-01").Spec.PowerState; got != baremetalcontrollerv1.PowerStateOn {
+		t.Errorf("web-01 powered off before its node was drained")
+	}
+
+	// Power returns before the drain finished
+	if err := m.step(ctx, Status{}); err != nil {
+		t.Fatal(err)
+	}
+	var node corev1.Node
+	if err := c.Get(ctx, client.ObjectKey{Name: "web-01"}, &node); err != nil || node.Spec.Unschedulable {
+		t.Errorf("web-01 node still cordoned after power returned")
+	}
+	if len(m.drainStarted) != 0 {
+		t.Errorf("drains = %v after power returned", m.drainStarted)
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    func(*Options)
+		wantErr bool
+	}{
+		{name: "disabled", opts: func(o *Options) { o.Address = ""; o.UPS = "" }},
+		{name: "defaults", opts: func(*Options) {}},
+		{name: "no UPS name", opts: func(o *Options) { o.UPS = "" }, wantErr: true},
+		{name: "no poll interval", opts: func(o *Options) { o.PollInterval = 0 }, wantErr: true},
+		{name: "negative grace", opts: func(o *Options) { o.OnBatteryGrace = -time.Second }, wantErr: true},
+		{name: "immediate shutdown", opts: func(o *Options) { o.OnBatteryGrace = 0; o.RestoreDelay = 0 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.Address = "nut.example.com:3493"
+			tt.opts(&opts)
+			if err := opts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
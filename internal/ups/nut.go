@@ -0,0 +1,104 @@
+package ups
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Status is the state of a UPS as reported in ups.status
+type Status struct {
+	// OnBattery is true while the UPS runs on battery (OB)
+	OnBattery bool
+	// LowBattery is true once the battery is nearly exhausted (LB), or the
+	// UPS is about to be shut down by its primary (FSD)
+	LowBattery bool
+}
+
+// OnLine returns true if the UPS runs on utility power
+func (s Status) OnLine() bool {
+	return !s.OnBattery && !s.LowBattery
+}
+
+func (s Status) String() string {
+	switch {
+	case s.LowBattery:
+		return "low battery"
+	case s.OnBattery:
+		return "on battery"
+	}
+	return "online"
+}
+
+// parseStatus parses the space separated flags of ups.status, e.g. "OB LB"
+func parseStatus(value string) Status {
+	var status Status
+	for _, flag := range strings.Fields(value) {
+		switch flag {
+		case "OB":
+			status.OnBattery = true
+		case "LB", "FSD":
+			status.LowBattery = true
+		}
+	}
+	return status
+}
+
+// nutClient reads UPS variables from a Network UPS Tools upsd server. Reading
+// variables needs no login.
+type nutClient struct {
+	address string
+	timeout time.Duration
+}
+
+// status reads ups.status of the named UPS
+func (c *nutClient) status(ups string) (Status, error) {
+	value, err := c.getVar(ups, "ups.status")
+	if err != nil {
+		return Status{}, err
+	}
+	return parseStatus(value), nil
+}
+
+// getVar sends GET VAR over a new connection, since polls are seconds apart
+func (c *nutClient) getVar(ups string, name string) (string, error) {
+	conn, err := net.DialTimeout("tcp", c.address, c.timeout)
+	if err != nil {
+		return "", fmt.Errorf("unable to reach upsd at %s: %w", c.address, err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		return "", err
+	}
+
+	if _, err := fmt.Fprintf(conn, "GET VAR %s %s\n", ups, name); err != nil {
+		return "", fmt.Errorf("unable to send request to upsd: %w", err)
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("unable to read response from upsd: %w", err)
+	}
+	// Best effort, upsd closes the connection either way
+	_, _ = fmt.Fprint(conn, "LOGOUT\n")
+
+	return parseVar(strings.TrimSpace(line), ups, name)
+}
+
+// parseVar parses a response like VAR ups ups.status "OL CHRG"
+func parseVar(line string, ups string, name string) (string, error) {
+	if strings.HasPrefix(line, "ERR ") {
+		return "", fmt.Errorf("upsd returned %s for %s on %s", strings.TrimPrefix(line, "ERR "), name, ups)
+	}
+	prefix := fmt.Sprintf("VAR %s %s ", ups, name)
+	if !strings.HasPrefix(line, prefix) {
+		return "", fmt.Errorf("unexpected response from upsd: %q", line)
+	}
+	value, err := strconv.Unquote(strings.TrimPrefix(line, prefix))
+	if err != nil {
+		return "", fmt.Errorf("unexpected response from upsd: %q", line)
+	}
+	return value, nil
+}
@@ -0,0 +1,133 @@
+package ups
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Status
+	}{
+		{value: "OL", want: Status{}},
+		{value: "OL CHRG", want: Status{}},
+		{value: "OB DISCHRG", want: Status{OnBattery: true}},
+		{value: "OB LB", want: Status{OnBattery: true, LowBattery: true}},
+		{value: "OL FSD", want: Status{LowBattery: true}},
+		{value: "", want: Status{}},
+	}
+	for _, tt := range tests {
+		if got := parseStatus(tt.value); got != tt.want {
+			t.Errorf("parseStatus(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestStatusOnLine(t *testing.T) {
+	tests := []struct {
+		status     Status
+		wantOnLine bool
+		wantString string
+	}{
+		{status: Status{}, wantOnLine: true, wantString: "online"},
+		{status: Status{OnBattery: true}, wantString: "on battery"},
+		{status: Status{OnBattery: true, LowBattery: true}, wantString: "low battery"},
+		{status: Status{LowBattery: true}, wantString: "low battery"},
+	}
+	for _, tt := range tests {
+		if got := tt.status.OnLine(); got != tt.wantOnLine {
+			t.Errorf("%+v OnLine() = %v, want %v", tt.status, got, tt.wantOnLine)
+		}
+		if got := tt.status.String(); got != tt.wantString {
+			t.Errorf("%+v String() = %q, want %q", tt.status, got, tt.wantString)
+		}
+	}
+}
+
+func TestParseVar(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    string
+		wantErr bool
+	}{
+		{line: `VAR ups ups.status "OL CHRG"`, want: "OL CHRG"},
+		{line: `VAR ups ups.status ""`, want: ""},
+		{line: `ERR UNKNOWN-UPS`, wantErr: true},
+		{line: `VAR other ups.status "OL"`, wantErr: true},
+		{line: `VAR ups ups.status OL`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseVar(tt.line, "ups", "ups.status")
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseVar(%q) = %q, %v, want %q, wantErr %v", tt.line, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// fakeUpsd answers GET VAR requests for ups.status of the UPS named ups
+func fakeUpsd(t *testing.T, status string) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				fields := strings.Fields(line)
+				if len(fields) != 4 || fields[0] != "GET" || fields[1] != "VAR" {
+					conn.Write([]byte("ERR INVALID-ARGUMENT\n"))
+					return
+				}
+				if fields[2] != "ups" || fields[3] != "ups.status" {
+					conn.Write([]byte("ERR UNKNOWN-UPS\n"))
+					return
+				}
+				conn.Write([]byte(`VAR ups ups.status "` + status + "\"\n"))
+				_, _ = reader.ReadString('\n')
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestNUTClientStatus(t *testing.T) {
+	c := &nutClient{address: fakeUpsd(t, "OB LB"), timeout: time.Second}
+	status, err := c.status("ups")
+	if err != nil {
+		t.Fatalf("status() error = %v", err)
+	}
+	if !status.OnBattery || !status.LowBattery {
+		t.Errorf("status() = %+v, want on low battery", status)
+	}
+	if _, err := c.status("rack-ups"); err == nil || !strings.Contains(err.Error(), "UNKNOWN-UPS") {
+		t.Errorf("status() of an unknown UPS error = %v", err)
+	}
+
+	// An unreachable upsd is an error, not a status
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+	c = &nutClient{address: address, timeout: time.Second}
+	if _, err := c.status("ups"); err == nil {
+		t.Errorf("status() succeeded without upsd")
+	}
+}
@@ -0,0 +1,369 @@
+// Package wake powers on servers for pods that can't be scheduled, for
+// scale-from-zero without the cluster autoscaler.
+package wake
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/budget"
+	"github.com/Unbounder1/bare-metal-controller/internal/listing"
+)
+
+// Options contains configuration for waking servers on demand.
+type Options struct {
+	// Enabled wakes powered off servers for unschedulable pods
+	Enabled bool
+
+	// Interval is how often pending pods are checked
+	Interval time.Duration
+
+	// Selector limits waking to matching Servers
+	Selector string
+}
+
+// DefaultOptions returns the default wake options.
+func DefaultOptions() Options {
+	return Options{
+		Interval: 15 * time.Second,
+	}
+}
+
+// BindFlags binds the wake options to command line flags.
+// The prefix can be used to namespace the flags (e.g., "wake-").
+func (o *Options) BindFlags(fs *flag.FlagSet, prefix string) {
+	fs.BoolVar(&o.Enabled, prefix+"on-pending-pods", o.Enabled,
+		"Power on servers for pods that can't be scheduled, without the cluster autoscaler.")
+	fs.DurationVar(&o.Interval, prefix+"interval", o.Interval,
+		"How often to check for unschedulable pods.")
+	fs.StringVar(&o.Selector, prefix+"selector", o.Selector,
+		"Label selector of the Servers that may be powered on for pending pods. Empty for all.")
+}
+
+// Validate validates the options.
+func (o *Options) Validate() error {
+	if !o.Enabled {
+		return nil
+	}
+	if o.Interval <= 0 {
+		return fmt.Errorf("wake interval must be positive")
+	}
+	if _, err := labels.Parse(o.Selector); err != nil {
+		return fmt.Errorf("invalid wake selector: %w", err)
+	}
+	return nil
+}
+
+// Waker implements manager.Runnable. It polls unschedulable pods and powers
+// on one matching server for each pod that no booting server matches.
+// Servers are matched by their labels, the node features recorded in their
+// status and, if a Node of the same name is left from an earlier boot, its
+// labels and taints.
+type Waker struct {
+	options  Options
+	selector labels.Selector
+	client   client.Client
+	reader   client.Reader
+	recorder record.EventRecorder
+}
+
+// Ensure Waker implements manager.Runnable
+var _ manager.Runnable = &Waker{}
+
+// NewWaker creates a new wake-on-demand runnable.
+func NewWaker(opts Options, mgr manager.Manager) (*Waker, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	selector, err := labels.Parse(opts.Selector)
+	if err != nil {
+		return nil, err
+	}
+	return &Waker{
+		options:  opts,
+		selector: selector,
+		client:   mgr.GetClient(),
+		reader:   mgr.GetAPIReader(),
+		recorder: mgr.GetEventRecorderFor("pod-waker"),
+	}, nil
+}
+
+// Start implements manager.Runnable. It checks pending pods until ctx is
+// done.
+func (w *Waker) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("wake")
+	logger.Info("Waking servers for unschedulable pods", "interval", w.options.Interval)
+
+	ticker := time.NewTicker(w.options.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := w.wake(ctx); err != nil {
+				logger.Error(err, "Failed to wake servers for pending pods")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+// Returns true so only one replica powers servers on.
+func (w *Waker) NeedLeaderElection() bool {
+	return true
+}
+
+// candidate is a server that may run pending pods, with the labels and
+// taints of its node
+type candidate struct {
+	server *baremetalcontrollerv1.Server
+	labels labels.Set
+	taints []corev1.Taint
+}
+
+// wake powers on a server for each unschedulable pod that no booting server
+// matches. A server that comes up without room for the pod leaves it
+// unschedulable, so the next check wakes another one.
+func (w *Waker) wake(ctx context.Context) error {
+	// The cache doesn't hold pods, so ask the API server directly
+	var pods corev1.PodList
+	if err := w.reader.List(ctx, &pods, client.MatchingFields{"status.phase": string(corev1.PodPending)}); err != nil {
+		return fmt.Errorf("failed to list pending pods: %w", err)
+	}
+	var unschedulable []*corev1.Pod
+	for i := range pods.Items {
+		if isUnschedulable(&pods.Items[i]) {
+			unschedulable = append(unschedulable, &pods.Items[i])
+		}
+	}
+	if len(unschedulable) == 0 {
+		return nil
+	}
+
+	booting, off, err := w.candidates(ctx)
+	if err != nil {
+		return err
+	}
+
+	woken := map[client.ObjectKey]bool{}
+	for _, pod := range unschedulable {
+		if matchesAny(pod, booting) {
+			continue
+		}
+		for i, c := range off {
+			if !matches(pod, c) {
+				continue
+			}
+			// A server on a full rack or circuit would only wait
+			if err := budget.Check(ctx, w.client, c.server, woken); err != nil {
+				var exceeded *budget.ExceededError
+				if !errors.As(err, &exceeded) {
+					return err
+				}
+				log.FromContext(ctx).WithName("wake").V(1).Info("Not waking server", "server", c.server.Name, "reason", err.Error())
+				continue
+			}
+			if err := w.powerOn(ctx, c.server, pod); err != nil {
+				return err
+			}
+			woken[client.ObjectKeyFromObject(c.server)] = true
+			booting = append(booting, c)
+			off = append(off[:i], off[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// candidates returns the servers that are booting and the ones that can be
+// woken, ordered by name
+func (w *Waker) candidates(ctx context.Context) (booting []candidate, off []candidate, err error) {
+	err = listing.Servers(ctx, w.client, 0, func(server *baremetalcontrollerv1.Server) error {
+		if server.Status.Status == baremetalcontrollerv1.StatusFailed || !w.eligible(server) {
+			return nil
+		}
+		if server.Spec.PowerState.Settled() == baremetalcontrollerv1.PowerStateOn {
+			if server.Status.Status != baremetalcontrollerv1.StatusActive {
+				booting = append(booting, candidate{server: server.DeepCopy()})
+			}
+			return nil
+		}
+		off = append(off, candidate{server: server.DeepCopy()})
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, list := range [][]candidate{booting, off} {
+		for i := range list {
+			if err := w.loadNode(ctx, &list[i]); err != nil {
+				return nil, nil, err
+			}
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].server.Name < list[j].server.Name })
+	}
+	return booting, off, nil
+}
+
+// eligible returns false for servers outside the selector, excluded from
+// autoscaling, protected by their role, kept on standby, hibernated, or
+// shut down for power loss or overheating
+func (w *Waker) eligible(server *baremetalcontrollerv1.Server) bool {
+	if server.Annotations[baremetalcontrollerv1.AutoscalerExcludeAnnotation] == "true" ||
+		server.Spec.Role.Protected() ||
+		server.Annotations[baremetalcontrollerv1.StandbyAnnotation] == "true" ||
+		server.Annotations[baremetalcontrollerv1.HibernatedAnnotation] != "" ||
+		server.Annotations[baremetalcontrollerv1.UPSShutdownAnnotation] == "true" ||
+		server.Annotations[baremetalcontrollerv1.ThermalShutdownAnnotation] == "true" {
+		return false
+	}
+	return w.selector.Matches(labels.Set(server.Labels))
+}
+
+// loadNode merges the recorded node features, and the labels and taints of
+// the server's node if it exists
+func (w *Waker) loadNode(ctx context.Context, c *candidate) error {
+	c.labels = labels.Set{}
+	for k, v := range c.server.Status.NodeFeatures {
+		c.labels[k] = v
+	}
+	for k, v := range c.server.Labels {
+		c.labels[k] = v
+	}
+
+	var node corev1.Node
+	if err := w.client.Get(ctx, types.NamespacedName{Name: c.server.NodeName()}, &node); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get node %s: %w", c.server.NodeName(), err)
+	}
+	for k, v := range node.Labels {
+		c.labels[k] = v
+	}
+	for _, taint := range node.Spec.Taints {
+		// Taints of a node that is down go away once it's back
+		if taint.Key == corev1.TaintNodeNotReady || taint.Key == corev1.TaintNodeUnreachable ||
+			taint.Key == corev1.TaintNodeUnschedulable {
+			continue
+		}
+		c.taints = append(c.taints, taint)
+	}
+	return nil
+}
+
+func (w *Waker) powerOn(ctx context.Context, server *baremetalcontrollerv1.Server, pod *corev1.Pod) error {
+	patch := client.MergeFrom(server.DeepCopy())
+	server.Spec.PowerState = baremetalcontrollerv1.PowerStateOn
+	if err := w.client.Patch(ctx, server, patch); err != nil {
+		return fmt.Errorf("failed to power on server %s: %w", server.Name, err)
+	}
+	log.FromContext(ctx).WithName("wake").Info("Powering on server for unschedulable pod",
+		"server", server.Name, "pod", pod.Namespace+"/"+pod.Name)
+	w.recorder.Eventf(server, corev1.EventTypeNormal, "WokenForPod",
+		"Powering on for unschedulable pod %s/%s", pod.Namespace, pod.Name)
+	return nil
+}
+
+// isUnschedulable returns true for pods the scheduler found no node for
+func isUnschedulable(pod *corev1.Pod) bool {
+	if pod.Spec.NodeName != "" || pod.DeletionTimestamp != nil {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled {
+			return condition.Status == corev1.ConditionFalse && condition.Reason == corev1.PodReasonUnschedulable
+		}
+	}
+	return false
+}
+
+func matchesAny(pod *corev1.Pod, candidates []candidate) bool {
+	for _, c := range candidates {
+		if matches(pod, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches checks the pod's node selector, required node affinity and
+// tolerations against the candidate. Resources are not compared, since a
+// powered off server reports no capacity.
+func matches(pod *corev1.Pod, c candidate) bool {
+	if !labels.SelectorFromSet(pod.Spec.NodeSelector).Matches(c.labels) {
+		return false
+	}
+	if affinity := pod.Spec.Affinity; affinity != nil && affinity.NodeAffinity != nil {
+		if required := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution; required != nil &&
+			!matchesNodeSelector(required, c.labels) {
+			return false
+		}
+	}
+	for i := range c.taints {
+		taint := &c.taints[i]
+		if taint.Effect == corev1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		if !toleratesTaint(pod.Spec.Tolerations, taint) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesNodeSelector returns true if any term matches. Field selectors on
+// the node name are ignored.
+func matchesNodeSelector(nodeSelector *corev1.NodeSelector, set labels.Set) bool {
+	for _, term := range nodeSelector.NodeSelectorTerms {
+		selector := labels.NewSelector()
+		valid := true
+		for _, expr := range term.MatchExpressions {
+			requirement, err := labels.NewRequirement(expr.Key, nodeSelectorOperators[expr.Operator], expr.Values)
+			if err != nil {
+				valid = false
+				break
+			}
+			selector = selector.Add(*requirement)
+		}
+		if valid && selector.Matches(set) {
+			return true
+		}
+	}
+	return false
+}
+
+var nodeSelectorOperators = map[corev1.NodeSelectorOperator]selection.Operator{
+	corev1.NodeSelectorOpIn:           selection.In,
+	corev1.NodeSelectorOpNotIn:        selection.NotIn,
+	corev1.NodeSelectorOpExists:       selection.Exists,
+	corev1.NodeSelectorOpDoesNotExist: selection.DoesNotExist,
+	corev1.NodeSelectorOpGt:           selection.GreaterThan,
+	corev1.NodeSelectorOpLt:           selection.LessThan,
+}
+
+func toleratesTaint(tolerations []corev1.Toleration, taint *corev1.Taint) bool {
+	for i := range tolerations {
+		if tolerations[i].ToleratesTaint(taint) {
+			return true
+		}
+	}
+	return false
+}
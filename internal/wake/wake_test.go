@@ -0,0 +1,293 @@
+package wake
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+)
+
+func pendingPod(name string, mutate func(*corev1.Pod)) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			Conditions: []corev1.PodCondition{{
+				Type:   corev1.PodScheduled,
+				Status: corev1.ConditionFalse,
+				Reason: corev1.PodReasonUnschedulable,
+			}},
+		},
+	}
+	if mutate != nil {
+		mutate(pod)
+	}
+	return pod
+}
+
+func TestIsUnschedulable(t *testing.T) {
+	tests := []struct {
+		name string
+		pod  *corev1.Pod
+		want bool
+	}{
+		{name: "unschedulable", pod: pendingPod("web", nil), want: true},
+		{name: "scheduled", pod: pendingPod("web", func(p *corev1.Pod) { p.Spec.NodeName = "worker-01" })},
+		{name: "deleting", pod: pendingPod("web", func(p *corev1.Pod) { p.DeletionTimestamp = &metav1.Time{Time: time.Now()} })},
+		{name: "not yet tried", pod: pendingPod("web", func(p *corev1.Pod) { p.Status.Conditions = nil })},
+		{name: "scheduling gated", pod: pendingPod("web", func(p *corev1.Pod) { p.Status.Conditions[0].Reason = "SchedulingGated" })},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isUnschedulable(tt.pod); got != tt.want {
+				t.Errorf("isUnschedulable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	gpu := candidate{labels: labels.Set{"gpu": "true", "cores": "64"}}
+	tainted := candidate{
+		labels: labels.Set{"gpu": "true"},
+		taints: []corev1.Taint{{Key: "dedicated", Value: "ml", Effect: corev1.TaintEffectNoSchedule}},
+	}
+	preferred := candidate{taints: []corev1.Taint{{Key: "spot", Effect: corev1.TaintEffectPreferNoSchedule}}}
+	affinity := func(exprs ...corev1.NodeSelectorRequirement) func(*corev1.Pod) {
+		return func(p *corev1.Pod) {
+			p.Spec.Affinity = &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{{MatchExpressions: exprs}},
+				},
+			}}
+		}
+	}
+
+	tests := []struct {
+		name      string
+		pod       *corev1.Pod
+		candidate candidate
+		want      bool
+	}{
+		{name: "no constraints", pod: pendingPod("web", nil), candidate: gpu, want: true},
+		{name: "node selector", pod: pendingPod("ml", func(p *corev1.Pod) { p.Spec.NodeSelector = map[string]string{"gpu": "true"} }), candidate: gpu, want: true},
+		{name: "node selector mismatch", pod: pendingPod("ml", func(p *corev1.Pod) { p.Spec.NodeSelector = map[string]string{"gpu": "false"} }), candidate: gpu},
+		{
+			name:      "affinity in",
+			pod:       pendingPod("ml", affinity(corev1.NodeSelectorRequirement{Key: "gpu", Operator: corev1.NodeSelectorOpIn, Values: []string{"true"}})),
+			candidate: gpu, want: true,
+		},
+		{
+			name:      "affinity greater than",
+			pod:       pendingPod("ml", affinity(corev1.NodeSelectorRequirement{Key: "cores", Operator: corev1.NodeSelectorOpGt, Values: []string{"128"}})),
+			candidate: gpu,
+		},
+		{
+			name:      "affinity does not exist",
+			pod:       pendingPod("web", affinity(corev1.NodeSelectorRequirement{Key: "gpu", Operator: corev1.NodeSelectorOpDoesNotExist})),
+			candidate: gpu,
+		},
+		{name: "untolerated taint", pod: pendingPod("ml", nil), candidate: tainted},
+		{
+			name: "tolerated taint",
+			pod: pendingPod("ml", func(p *corev1.Pod) {
+				p.Spec.Tolerations = []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "ml", Effect: corev1.TaintEffectNoSchedule}}
+			}),
+			candidate: tainted, want: true,
+		},
+		{name: "preferred taint", pod: pendingPod("web", nil), candidate: preferred, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matches(tt.pod, tt.candidate); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func offServer(name string, serverLabels map[string]string, annotations map[string]string) *baremetalcontrollerv1.Server {
+	return &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: serverLabels, Annotations: annotations},
+		Spec:       baremetalcontrollerv1.ServerSpec{PowerState: baremetalcontrollerv1.PowerStateOff},
+		Status:     baremetalcontrollerv1.ServerStatus{Status: baremetalcontrollerv1.StatusOffline},
+	}
+}
+
+func newTestWaker(t *testing.T, objs ...client.Object) (*Waker, client.Client) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := baremetalcontrollerv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).
+		WithIndex(&corev1.Pod{}, "status.phase", func(obj client.Object) []string {
+			return []string{string(obj.(*corev1.Pod).Status.Phase)}
+		}).Build()
+	return &Waker{
+		options:  Options{Enabled: true, Interval: time.Second},
+		selector: labels.Everything(),
+		client:   c,
+		reader:   c,
+		recorder: record.NewFakeRecorder(100),
+	}, c
+}
+
+func powerStates(t *testing.T, c client.Client) map[string]baremetalcontrollerv1.PowerState {
+	t.Helper()
+	var servers baremetalcontrollerv1.ServerList
+	if err := c.List(context.Background(), &servers); err != nil {
+		t.Fatal(err)
+	}
+	states := map[string]baremetalcontrollerv1.PowerState{}
+	for _, server := range servers.Items {
+		states[server.Name] = server.Spec.PowerState
+	}
+	return states
+}
+
+func TestWake(t *testing.T) {
+	on, off := baremetalcontrollerv1.PowerStateOn, baremetalcontrollerv1.PowerStateOff
+	// gpu-01 is left with a taint from its last boot, gpu-02 comes up
+	// without it
+	gpuNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "gpu-01", Labels: map[string]string{"gpu": "true"}},
+		Spec: corev1.NodeSpec{Taints: []corev1.Taint{
+			{Key: "dedicated", Value: "ml", Effect: corev1.TaintEffectNoSchedule},
+			{Key: corev1.TaintNodeUnreachable, Effect: corev1.TaintEffectNoExecute},
+		}},
+	}
+	gpu02 := offServer("gpu-02", nil, nil)
+	gpu02.Status.NodeFeatures = map[string]string{"gpu": "true"}
+
+	w, c := newTestWaker(t,
+		gpuNode,
+		offServer("gpu-01", nil, nil),
+		gpu02,
+		offServer("worker-01", nil, nil),
+		offServer("worker-02", nil, nil),
+		offServer("pinned-01", nil, map[string]string{baremetalcontrollerv1.AutoscalerExcludeAnnotation: "true"}),
+		offServer("standby-01", nil, map[string]string{baremetalcontrollerv1.StandbyAnnotation: "true"}),
+		pendingPod("ml", func(p *corev1.Pod) {
+			p.Spec.NodeSelector = map[string]string{"gpu": "true"}
+			p.Spec.Tolerations = []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}}
+		}),
+		pendingPod("render", func(p *corev1.Pod) { p.Spec.NodeSelector = map[string]string{"gpu": "true"} }),
+		pendingPod("web", nil),
+	)
+
+	if err := w.wake(context.Background()); err != nil {
+		t.Fatalf("wake() error = %v", err)
+	}
+	// render can't tolerate gpu-01's taint, and web fits on gpu-02 too
+	want := map[string]baremetalcontrollerv1.PowerState{
+		"gpu-01": on, "gpu-02": on, "worker-01": off, "worker-02": off, "pinned-01": off, "standby-01": off,
+	}
+	got := powerStates(t, c)
+	for name, state := range want {
+		if got[name] != state {
+			t.Errorf("%s powerState = %s, want %s", name, got[name], state)
+		}
+	}
+
+	// Pods still pending while their servers boot wake nothing else
+	for _, name := range []string{"gpu-01", "gpu-02"} {
+		var server baremetalcontrollerv1.Server
+		if err := c.Get(context.Background(), client.ObjectKey{Name: name}, &server); err != nil {
+			t.Fatal(err)
+		}
+		server.Status.Status = baremetalcontrollerv1.StatusPending
+		if err := c.Update(context.Background(), &server); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.wake(context.Background()); err != nil {
+		t.Fatalf("wake() error = %v", err)
+	}
+	if got := powerStates(t, c)["worker-01"]; got != off {
+		t.Errorf("worker-01 woken for a pod a booting server matches")
+	}
+
+	// Once gpu-02 is up without room, web wakes another server
+	var gpu02Up baremetalcontrollerv1.Server
+	if err := c.Get(context.Background(), client.ObjectKey{Name: "gpu-02"}, &gpu02Up); err != nil {
+		t.Fatal(err)
+	}
+	gpu02Up.Status.Status = baremetalcontrollerv1.StatusActive
+	if err := c.Update(context.Background(), &gpu02Up); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.wake(context.Background()); err != nil {
+		t.Fatalf("wake() error = %v", err)
+	}
+	if got := powerStates(t, c); got["worker-01"] != on || got["worker-02"] != off {
+		t.Errorf("power states = %v, want worker-01 woken", got)
+	}
+}
+
+func TestWakeRespectsBudgetAndSelector(t *testing.T) {
+	rack := map[string]string{"rack": "r1"}
+	active := offServer("worker-01", rack, nil)
+	active.Spec.PowerState = baremetalcontrollerv1.PowerStateOn
+	active.Status.Status = baremetalcontrollerv1.StatusActive
+
+	w, c := newTestWaker(t,
+		&baremetalcontrollerv1.PowerBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "rack-r1"},
+			Spec: baremetalcontrollerv1.PowerBudgetSpec{
+				Selector:     metav1.LabelSelector{MatchLabels: rack},
+				MaxPoweredOn: ptr.To[int32](1),
+			},
+		},
+		active,
+		offServer("worker-02", rack, nil),
+		offServer("worker-03", map[string]string{"rack": "r2", "pool": "web"}, nil),
+		offServer("worker-04", map[string]string{"rack": "r2", "pool": "batch"}, nil),
+		pendingPod("web", nil),
+	)
+	w.selector = labels.SelectorFromSet(labels.Set{"pool": "batch"})
+
+	if err := w.wake(context.Background()); err != nil {
+		t.Fatalf("wake() error = %v", err)
+	}
+	// worker-02 is on a full rack and worker-03 outside the selector
+	got := powerStates(t, c)
+	if got["worker-02"] != baremetalcontrollerv1.PowerStateOff || got["worker-03"] != baremetalcontrollerv1.PowerStateOff ||
+		got["worker-04"] != baremetalcontrollerv1.PowerStateOn {
+		t.Errorf("power states = %v, want only worker-04 woken", got)
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{name: "disabled", opts: Options{}},
+		{name: "enabled", opts: Options{Enabled: true, Interval: time.Second, Selector: "pool=batch"}},
+		{name: "no interval", opts: Options{Enabled: true}, wantErr: true},
+		{name: "invalid selector", opts: Options{Enabled: true, Interval: time.Second, Selector: "pool in (batch"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
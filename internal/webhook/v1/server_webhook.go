@@ -0,0 +1,484 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/index"
+)
+
+// SetupServerWebhookWithManager registers the defaulting and validating
+// webhooks for Servers in the manager. Wake-on-LAN servers without a
+// broadcast address are defaulted to defaultBroadcastAddress.
+func SetupServerWebhookWithManager(mgr ctrl.Manager, defaultBroadcastAddress string) error {
+	defaulter := &ServerCustomDefaulter{DefaultBroadcastAddress: defaultBroadcastAddress}
+	return ctrl.NewWebhookManagedBy(mgr).For(&baremetalcontrollerv1.Server{}).
+		WithDefaulter(defaulter).
+		WithValidator(&ServerCustomValidator{Defaulter: defaulter, Reader: mgr.GetAPIReader()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-bare-metal-controller-bare-metal-io-v1-server,mutating=true,failurePolicy=fail,sideEffects=None,groups=bare-metal-controller.bare-metal.io,resources=servers,verbs=create;update,versions=v1,name=mserver-v1.kb.io,admissionReviewVersions=v1
+
+// ServerCustomDefaulter fills in the defaults of a Server's spec, so the
+// spec users read back is the one the controller acts on.
+type ServerCustomDefaulter struct {
+	// DefaultBroadcastAddress is the address Wake-on-LAN packets are
+	// broadcast to for servers that don't set one. Empty leaves it unset.
+	DefaultBroadcastAddress string
+}
+
+var _ webhook.CustomDefaulter = &ServerCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter.
+func (d *ServerCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	server, ok := obj.(*baremetalcontrollerv1.Server)
+	if !ok {
+		return fmt.Errorf("expected a Server object but got %T", obj)
+	}
+	spec := &server.Spec
+	if spec.PowerState == "" {
+		spec.PowerState = baremetalcontrollerv1.PowerStateOff
+	}
+	if spec.Type == "" {
+		spec.Type = inferControlType(&spec.Control)
+	}
+	if wol := spec.Control.WOL; wol != nil {
+		if wol.Port == 0 {
+			wol.Port = 9
+		}
+		// Relays broadcast on their own subnet, to their own default
+		if wol.BroadcastAddress == "" && wol.Relay == "" {
+			wol.BroadcastAddress = d.DefaultBroadcastAddress
+		}
+	}
+	return nil
+}
+
+// inferControlType returns the type of the only block of control that is
+// set, or empty if it is ambiguous. ESXi hosts also set the block of the
+// backend they are powered on with, so an ESXi block always wins.
+func inferControlType(control *baremetalcontrollerv1.ControlSpecs) baremetalcontrollerv1.ControlType {
+	if control.ESXi != nil {
+		return baremetalcontrollerv1.ControlTypeESXi
+	}
+	var types []baremetalcontrollerv1.ControlType
+	if control.WOL != nil {
+		types = append(types, baremetalcontrollerv1.ControlTypeWOL)
+	}
+	if control.IPMI != nil {
+		types = append(types, baremetalcontrollerv1.ControlTypeIPMI)
+	}
+	if control.MAAS != nil {
+		types = append(types, baremetalcontrollerv1.ControlTypeMAAS)
+	}
+	if control.Redfish != nil {
+		types = append(types, baremetalcontrollerv1.ControlTypeRedfish)
+	}
+	if control.Equinix != nil {
+		types = append(types, baremetalcontrollerv1.ControlTypeEquinix)
+	}
+	if control.Hetzner != nil {
+		types = append(types, baremetalcontrollerv1.ControlTypeHetzner)
+	}
+	if len(types) != 1 {
+		return ""
+	}
+	return types[0]
+}
+
+// +kubebuilder:webhook:path=/validate-bare-metal-controller-bare-metal-io-v1-server,mutating=false,failurePolicy=fail,sideEffects=None,groups=bare-metal-controller.bare-metal.io,resources=servers,verbs=create;update,versions=v1,name=vserver-v1.kb.io,admissionReviewVersions=v1
+
+// ServerCustomValidator rejects Servers whose spec the controller would
+// fail with SpecInvalid, e.g. a wol server without a WOL block, so they
+// are rejected when applied instead of at their first reconcile.
+type ServerCustomValidator struct {
+	// Defaulter is the defaulter updates went through, nil if none
+	Defaulter *ServerCustomDefaulter
+
+	// Reader looks up namespaced Servers of the same name, which are
+	// rejected because nodes are named after their Server without its
+	// namespace. Nil skips the check.
+	Reader client.Reader
+}
+
+var _ webhook.CustomValidator = &ServerCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator.
+func (v *ServerCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	server, ok := obj.(*baremetalcontrollerv1.Server)
+	if !ok {
+		return nil, fmt.Errorf("expected a Server object but got %T", obj)
+	}
+	errs := validateSpec(&server.Spec)
+	duplicate, err := v.validateUniqueName(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+	errs = append(errs, duplicate...)
+	return nil, invalid(server, errs)
+}
+
+// validateUniqueName rejects namespaced servers named like a server in
+// another namespace. Names of cluster-scoped servers are unique anyway. The
+// other server belongs to another tenant, so the error doesn't say where it
+// is.
+func (v *ServerCustomValidator) validateUniqueName(ctx context.Context, server *baremetalcontrollerv1.Server) (field.ErrorList, error) {
+	if v.Reader == nil || server.Namespace == "" || server.Name == "" {
+		return nil, nil
+	}
+	var servers baremetalcontrollerv1.ServerList
+	if err := v.Reader.List(ctx, &servers, client.MatchingFields{index.ServerNameField: server.Name}); err != nil {
+		return nil, fmt.Errorf("failed to look up servers named %s: %w", server.Name, err)
+	}
+	for _, other := range servers.Items {
+		if other.Namespace != server.Namespace {
+			return field.ErrorList{field.Invalid(field.NewPath("metadata", "name"), server.Name,
+				"name is already in use, server names must be unique across namespaces")}, nil
+		}
+	}
+	return nil, nil
+}
+
+// ValidateUpdate implements webhook.CustomValidator. Updates that leave the
+// spec alone, e.g. of labels or finalizers, are allowed even if the spec
+// predates the webhook and is invalid. Defaults filled in by the defaulter
+// don't count as changes.
+func (v *ServerCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	old, ok := oldObj.(*baremetalcontrollerv1.Server)
+	if !ok {
+		return nil, fmt.Errorf("expected a Server object for the old object but got %T", oldObj)
+	}
+	server, ok := newObj.(*baremetalcontrollerv1.Server)
+	if !ok {
+		return nil, fmt.Errorf("expected a Server object for the new object but got %T", newObj)
+	}
+	if v.Defaulter != nil {
+		old = old.DeepCopy()
+		if err := v.Defaulter.Default(ctx, old); err != nil {
+			return nil, err
+		}
+	}
+	if equality.Semantic.DeepEqual(old.Spec, server.Spec) {
+		return nil, nil
+	}
+	errs := validateSpec(&server.Spec)
+	errs = append(errs, validateTransition(old, server)...)
+	return nil, invalid(server, errs)
+}
+
+// ValidateDelete implements webhook.CustomValidator. Servers can always be
+// deleted.
+func (v *ServerCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func invalid(server *baremetalcontrollerv1.Server, errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(baremetalcontrollerv1.GroupVersion.WithKind("Server").GroupKind(), server.Name, errs)
+}
+
+// settling are the statuses of a server a power action is in progress for
+var settling = map[baremetalcontrollerv1.CurrentStatus]bool{
+	baremetalcontrollerv1.StatusPending:   true,
+	baremetalcontrollerv1.StatusDraining:  true,
+	baremetalcontrollerv1.StatusRebooting: true,
+}
+
+// validateTransition rejects changes that would pull the control config out
+// from under a power action in progress
+func validateTransition(old, server *baremetalcontrollerv1.Server) field.ErrorList {
+	var errs field.ErrorList
+	if old.Spec.Type != server.Spec.Type && settling[old.Status.Status] {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "type"),
+			fmt.Sprintf("can't change from %s to %s while the server is %s, wait until it is active, offline or failed",
+				old.Spec.Type, server.Spec.Type, old.Status.Status)))
+	}
+	return errs
+}
+
+func validateSpec(spec *baremetalcontrollerv1.ServerSpec) field.ErrorList {
+	var errs field.ErrorList
+	specPath := field.NewPath("spec")
+	control := specPath.Child("control")
+
+	switch spec.Type {
+	case "":
+		errs = append(errs, field.Required(specPath.Child("type"), "or exactly one block of control to infer it from"))
+	case baremetalcontrollerv1.ControlTypeWOL:
+		errs = append(errs, validateWOL(spec.Control.WOL, control.Child("wol"))...)
+	case baremetalcontrollerv1.ControlTypeIPMI:
+		errs = append(errs, validateIPMI(spec.Control.IPMI, control.Child("ipmi"))...)
+	case baremetalcontrollerv1.ControlTypeMAAS:
+		errs = append(errs, validateMAAS(spec.Control.MAAS, control.Child("maas"))...)
+	case baremetalcontrollerv1.ControlTypeRedfish:
+		errs = append(errs, validateRedfish(spec.Control.Redfish, control.Child("redfish"))...)
+	case baremetalcontrollerv1.ControlTypeEquinix:
+		errs = append(errs, validateEquinix(spec.Control.Equinix, control.Child("equinix"))...)
+	case baremetalcontrollerv1.ControlTypeHetzner:
+		errs = append(errs, validateHetzner(spec.Control.Hetzner, control.Child("hetzner"))...)
+	case baremetalcontrollerv1.ControlTypeESXi:
+		errs = append(errs, validateESXi(spec.Control.ESXi, control.Child("esxi"))...)
+	}
+
+	// Blocks of other types are checked too when set, e.g. the WOL block
+	// ESXi hosts are powered on with
+	if spec.Type != baremetalcontrollerv1.ControlTypeWOL && spec.Control.WOL != nil {
+		errs = append(errs, validateWOL(spec.Control.WOL, control.Child("wol"))...)
+	}
+	if spec.Type != baremetalcontrollerv1.ControlTypeIPMI && spec.Control.IPMI != nil {
+		errs = append(errs, validateIPMI(spec.Control.IPMI, control.Child("ipmi"))...)
+	}
+	if spec.Type != baremetalcontrollerv1.ControlTypeRedfish && spec.Control.Redfish != nil {
+		errs = append(errs, validateRedfish(spec.Control.Redfish, control.Child("redfish"))...)
+	}
+	if spec.Type == baremetalcontrollerv1.ControlTypeESXi &&
+		spec.Control.Redfish == nil && spec.Control.IPMI == nil && spec.Control.WOL == nil {
+		errs = append(errs, field.Required(control, "ESXi hosts need a redfish, ipmi or wol config to be powered on"))
+	}
+
+	if spec.PowerState == baremetalcontrollerv1.PowerStateReboot {
+		switch {
+		case spec.Type == baremetalcontrollerv1.ControlTypeWOL:
+			if spec.Control.WOL != nil && spec.Control.WOL.SSHSecretRef == nil {
+				errs = append(errs, field.Required(control.Child("wol", "sshSecretRef"), "wol servers are rebooted over SSH"))
+			}
+		case spec.Type != baremetalcontrollerv1.ControlTypeIPMI:
+			errs = append(errs, field.NotSupported(specPath.Child("powerState"), spec.PowerState,
+				[]baremetalcontrollerv1.PowerState{baremetalcontrollerv1.PowerStateOn, baremetalcontrollerv1.PowerStateOff}))
+		}
+	}
+	if spec.NodeName != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(spec.NodeName) {
+			errs = append(errs, field.Invalid(specPath.Child("nodeName"), spec.NodeName, msg))
+		}
+	}
+	if spec.Storage != nil && spec.Type != baremetalcontrollerv1.ControlTypeRedfish {
+		errs = append(errs, field.Forbidden(specPath.Child("storage"), "storage layouts require the redfish control type"))
+	}
+	if spec.BootPolicy != nil && spec.Type != baremetalcontrollerv1.ControlTypeIPMI && spec.Type != baremetalcontrollerv1.ControlTypeRedfish {
+		errs = append(errs, field.Forbidden(specPath.Child("bootPolicy"), "boot policies require the ipmi or redfish control type"))
+	}
+	if spec.BootDevice != "" {
+		switch {
+		case spec.BootPolicy != nil:
+			errs = append(errs, field.Forbidden(specPath.Child("bootDevice"), "can't be combined with bootPolicy"))
+		case spec.Type != baremetalcontrollerv1.ControlTypeIPMI && spec.Type != baremetalcontrollerv1.ControlTypeRedfish:
+			errs = append(errs, field.Forbidden(specPath.Child("bootDevice"), "boot devices require the ipmi or redfish control type"))
+		}
+	}
+	if hc := spec.HealthCheck; hc != nil {
+		healthCheck := specPath.Child("healthCheck")
+		if hc.Method == baremetalcontrollerv1.HealthCheckTCP && hc.Port == 0 {
+			errs = append(errs, field.Required(healthCheck.Child("port"), "tcp checks connect to it"))
+		}
+		if hc.Path != "" && !strings.HasPrefix(hc.Path, "/") {
+			errs = append(errs, field.Invalid(healthCheck.Child("path"), hc.Path, "must start with /"))
+		}
+		if hc.Method != "" && hc.Method != baremetalcontrollerv1.HealthCheckICMP &&
+			spec.Type == baremetalcontrollerv1.ControlTypeWOL && spec.Control.WOL != nil && spec.Control.WOL.Relay != "" {
+			errs = append(errs, field.Forbidden(healthCheck.Child("method"), "servers woken through a relay are pinged by the relay"))
+		}
+	}
+	if p := spec.Provisioning; p != nil && p.Tinkerbell != nil {
+		tinkerbell := specPath.Child("provisioning", "tinkerbell")
+		errs = append(errs, validateMAC(p.Tinkerbell.MACAddress, tinkerbell.Child("macAddress"))...)
+		if p.Tinkerbell.IPAddress != "" && net.ParseIP(p.Tinkerbell.IPAddress) == nil {
+			errs = append(errs, field.Invalid(tinkerbell.Child("ipAddress"), p.Tinkerbell.IPAddress, "must be an IP address"))
+		}
+	}
+	return errs
+}
+
+func validateWOL(wol *baremetalcontrollerv1.WOLSpecs, path *field.Path) field.ErrorList {
+	if wol == nil {
+		return field.ErrorList{field.Required(path, "required for the wol control type")}
+	}
+	var errs field.ErrorList
+	if wol.MACAddress == "" {
+		errs = append(errs, field.Required(path.Child("macAddress"), "Wake-on-LAN packets are addressed to it"))
+	} else {
+		errs = append(errs, validateMAC(wol.MACAddress, path.Child("macAddress"))...)
+	}
+	errs = append(errs, validateHost(wol.Address, path.Child("address"))...)
+	errs = append(errs, validateHost(wol.SSHAddress, path.Child("sshAddress"))...)
+	if wol.BroadcastAddress != "" && net.ParseIP(wol.BroadcastAddress) == nil {
+		errs = append(errs, field.Invalid(path.Child("broadcastAddress"), wol.BroadcastAddress, "must be an IP address"))
+	}
+	errs = append(errs, validatePort(int64(wol.Port), path.Child("port"))...)
+	errs = append(errs, validatePort(int64(wol.SSHPort), path.Child("sshPort"))...)
+	return errs
+}
+
+func validateIPMI(ipmi *baremetalcontrollerv1.IPMISpecs, path *field.Path) field.ErrorList {
+	if ipmi == nil {
+		return field.ErrorList{field.Required(path, "required for the ipmi control type")}
+	}
+	errs := requireHost(ipmi.Address, path.Child("address"))
+	if ipmi.CredentialsSecretRef == nil && (ipmi.Username == "" || ipmi.Password == "") {
+		errs = append(errs, field.Required(path.Child("credentialsSecretRef"), "or both username and password"))
+	}
+	return errs
+}
+
+func validateMAAS(maas *baremetalcontrollerv1.MAASSpecs, path *field.Path) field.ErrorList {
+	if maas == nil {
+		return field.ErrorList{field.Required(path, "required for the maas control type")}
+	}
+	errs := requireHost(maas.Address, path.Child("address"))
+	if maas.Endpoint == "" {
+		errs = append(errs, field.Required(path.Child("endpoint"), ""))
+	} else if u, err := url.Parse(maas.Endpoint); err != nil || u.Host == "" || u.Scheme != "http" && u.Scheme != "https" {
+		errs = append(errs, field.Invalid(path.Child("endpoint"), maas.Endpoint, "must be an http or https URL"))
+	}
+	if maas.SystemID == "" {
+		errs = append(errs, field.Required(path.Child("systemID"), ""))
+	}
+	if maas.APIKeySecretRef == nil {
+		errs = append(errs, field.Required(path.Child("apiKeySecretRef"), ""))
+	}
+	return errs
+}
+
+func validateRedfish(redfish *baremetalcontrollerv1.RedfishSpecs, path *field.Path) field.ErrorList {
+	if redfish == nil {
+		return field.ErrorList{field.Required(path, "required for the redfish control type")}
+	}
+	errs := requireBMCAddress(redfish.Address, path.Child("address"))
+	if redfish.CredentialsSecretRef == nil {
+		errs = append(errs, field.Required(path.Child("credentialsSecretRef"), ""))
+	}
+	return errs
+}
+
+func validateEquinix(equinix *baremetalcontrollerv1.EquinixSpecs, path *field.Path) field.ErrorList {
+	if equinix == nil {
+		return field.ErrorList{field.Required(path, "required for the equinix control type")}
+	}
+	errs := requireHost(equinix.Address, path.Child("address"))
+	if equinix.ProjectID == "" {
+		errs = append(errs, field.Required(path.Child("projectID"), ""))
+	}
+	if equinix.APITokenSecretRef == nil {
+		errs = append(errs, field.Required(path.Child("apiTokenSecretRef"), ""))
+	}
+	return errs
+}
+
+func validateHetzner(hetzner *baremetalcontrollerv1.HetznerSpecs, path *field.Path) field.ErrorList {
+	if hetzner == nil {
+		return field.ErrorList{field.Required(path, "required for the hetzner control type")}
+	}
+	errs := requireHost(hetzner.Address, path.Child("address"))
+	if hetzner.ServerNumber == 0 {
+		errs = append(errs, field.Required(path.Child("serverNumber"), ""))
+	}
+	if hetzner.CredentialsSecretRef == nil {
+		errs = append(errs, field.Required(path.Child("credentialsSecretRef"), ""))
+	}
+	return errs
+}
+
+func validateESXi(esxi *baremetalcontrollerv1.ESXiSpecs, path *field.Path) field.ErrorList {
+	if esxi == nil {
+		return field.ErrorList{field.Required(path, "required for the esxi control type")}
+	}
+	errs := requireBMCAddress(esxi.Address, path.Child("address"))
+	if esxi.CredentialsSecretRef == nil {
+		errs = append(errs, field.Required(path.Child("credentialsSecretRef"), ""))
+	}
+	return errs
+}
+
+func validateMAC(mac string, path *field.Path) field.ErrorList {
+	if mac == "" {
+		return nil
+	}
+	if _, err := net.ParseMAC(mac); err != nil {
+		return field.ErrorList{field.Invalid(path, mac, "must be a MAC address, e.g. 00:11:22:33:44:55")}
+	}
+	return nil
+}
+
+func validatePort(port int64, path *field.Path) field.ErrorList {
+	// Zero is unset and takes the default port
+	if port < 0 || port > 65535 {
+		return field.ErrorList{field.Invalid(path, port, "must be between 1 and 65535")}
+	}
+	return nil
+}
+
+func requireHost(address string, path *field.Path) field.ErrorList {
+	if address == "" {
+		return field.ErrorList{field.Required(path, "")}
+	}
+	return validateHost(address, path)
+}
+
+// validateHost checks an address of an IP address or hostname, with an
+// optional port
+func validateHost(address string, path *field.Path) field.ErrorList {
+	if address == "" {
+		return nil
+	}
+	host := address
+	if h, port, err := net.SplitHostPort(address); err == nil {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return field.ErrorList{field.Invalid(path, address, "port must be between 1 and 65535")}
+		}
+		host = h
+	}
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+	if msgs := validation.IsDNS1123Subdomain(host); len(msgs) > 0 {
+		return field.ErrorList{field.Invalid(path, address, "must be an IP address or a hostname, with an optional port: "+strings.Join(msgs, "; "))}
+	}
+	return nil
+}
+
+// requireBMCAddress checks the address of a Redfish or ESXi endpoint, which
+// may also be an https URL
+func requireBMCAddress(address string, path *field.Path) field.ErrorList {
+	if !strings.Contains(address, "://") {
+		return requireHost(address, path)
+	}
+	u, err := url.Parse(address)
+	if err != nil || u.Host == "" || u.Scheme != "https" && u.Scheme != "http" {
+		return field.ErrorList{field.Invalid(path, address, "must be an IP address, a hostname or an https URL")}
+	}
+	return validateHost(u.Host, path)
+}
@@ -0,0 +1,394 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	baremetalcontrollerv1 "github.com/Unbounder1/bare-metal-controller/api/v1"
+	"github.com/Unbounder1/bare-metal-controller/internal/index"
+)
+
+func wolServer(mutate func(*baremetalcontrollerv1.Server)) *baremetalcontrollerv1.Server {
+	server := &baremetalcontrollerv1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-server"},
+		Spec: baremetalcontrollerv1.ServerSpec{
+			Type:       baremetalcontrollerv1.ControlTypeWOL,
+			PowerState: baremetalcontrollerv1.PowerStateOn,
+			Control: baremetalcontrollerv1.ControlSpecs{
+				WOL: &baremetalcontrollerv1.WOLSpecs{
+					Address:    "192.168.1.100",
+					MACAddress: "00:11:22:33:44:55",
+				},
+			},
+		},
+	}
+	if mutate != nil {
+		mutate(server)
+	}
+	return server
+}
+
+func TestValidateCreate(t *testing.T) {
+	secret := &baremetalcontrollerv1.SecretReference{Name: "creds", Namespace: "default"}
+	cases := []struct {
+		name    string
+		mutate  func(*baremetalcontrollerv1.Server)
+		wantErr string
+	}{
+		{name: "valid wol"},
+		{name: "missing type", mutate: func(s *baremetalcontrollerv1.Server) {
+			s.Spec.Type = ""
+		}, wantErr: "spec.type: Required value"},
+		{name: "missing wol block", mutate: func(s *baremetalcontrollerv1.Server) {
+			s.Spec.Control.WOL = nil
+		}, wantErr: "spec.control.wol: Required value"},
+		{name: "missing mac", mutate: func(s *baremetalcontrollerv1.Server) {
+			s.Spec.Control.WOL.MACAddress = ""
+		}, wantErr: "spec.control.wol.macAddress: Required value"},
+		{name: "bad mac", mutate: func(s *baremetalcontrollerv1.Server) {
+			s.Spec.Control.WOL.MACAddress = "00:11:22:33:44"
+		}, wantErr: "spec.control.wol.macAddress: Invalid value"},
+		{name: "bad address", mutate: func(s *baremetalcontrollerv1.Server) {
+			s.Spec.Control.WOL.Address = "not_a host"
+		}, wantErr: "spec.control.wol.address: Invalid value"},
+		{name: "hostname with port", mutate: func(s *baremetalcontrollerv1.Server) {
+			s.Spec.Control.WOL.Address = "node-1.lab.example:22"
+		}},
+		{name: "port out of range in address", mutate: func(s *baremetalcontrollerv1.Server) {
+			s.Spec.Control.WOL.Address = "192.168.1.100:70000"
+		}, wantErr: "port must be between 1 and 65535"},
+		{name: "wol port out of range", mutate: func(s *baremetalcontrollerv1.Server) {
+			s.Spec.Control.WOL.Port = 70000
+		}, wantErr: "spec.control.wol.port: Invalid value"},
+		{name: "bad broadcast address", mutate: func(s *baremetalcontrollerv1.Server) {
+			s.Spec.Control.WOL.BroadcastAddress = "broadcast"
+		}, wantErr: "spec.control.wol.broadcastAddress: Invalid value"},
+		{name: "wol reboot without ssh", mutate: func(s *baremetalcontrollerv1.Server) {
+			s.Spec.PowerState = baremetalcontrollerv1.PowerStateReboot
+		}, wantErr: "spec.control.wol.sshSecretRef: Required value"},
+		{name: "ipmi without credentials", mutate: func(s *baremetalcontrollerv1.Server) {
+			s.Spec.Type = baremetalcontrollerv1.ControlTypeIPMI
+			s.Spec.Control = baremetalcontrollerv1.ControlSpecs{
+				IPMI: &baremetalcontrollerv1.IPMISpecs{Address: "10.0.0.10"},
+			}
+		}, wantErr: "spec.control.ipmi.credentialsSecretRef: Required value"},
+		{name: "valid redfish url", mutate: func(s *baremetalcontrollerv1.Server) {
+			s.Spec.Type = baremetalcontrollerv1.ControlTypeRedfish
+			s.Spec.Control = baremetalcontrollerv1.ControlSpecs{
+				Redfish: &baremetalcontrollerv1.RedfishSpecs{Address: "https://10.0.0.10:8443", CredentialsSecretRef: secret},
+			}
+			s.Spec.Storage = &baremetalcontrollerv1.StorageSpec{}
+		}},
+		{name: "redfish bad url", mutate: func(s *baremetalcontrollerv1.Server) {
+			s.Spec.Type = baremetalcontrollerv1.ControlTypeRedfish
+			s.Spec.Control = baremetalcontrollerv1.ControlSpecs{
+				Redfish: &baremetalcontrollerv1.RedfishSpecs{Address: "ftp://10.0.0.10", CredentialsSecretRef: secret},
+			}
+		}, wantErr: "spec.control.redfish.address: Invalid value"},
+		{name: "maas endpoint not a url", mutate: func(s *baremetalcontrollerv1.Server) {
+			s.Spec.Type = baremetalcontrollerv1.ControlTypeMAAS
+			s.Spec.Control = baremetalcontrollerv1.ControlSpecs{
+				MAAS: &baremetalcontrollerv1.MAASSpecs{Address: "10.0.0.10", Endpoint: "maas:5240", SystemID: "abc123", APIKeySecretRef: secret},
+			}
+		}, wantErr: "spec.control.maas.endpoint: Invalid value"},
+		{name: "bad node name", mutate: func(s *baremetalcontrollerv1.Server) {
+			s.Spec.NodeName = "Rack3_U12"
+		}, wantErr: "spec.nodeName: Invalid value"},
+		{name: "storage needs redfish", mutate: func(s *baremetalcontrollerv1.Server) {
+			s.Spec.Storage = &baremetalcontrollerv1.StorageSpec{}
+		}, wantErr: "spec.storage: Forbidden"},
+		{name: "boot policy needs a bmc", mutate: func(s *baremetalcontrollerv1.Server) {
+			s.Spec.BootPolicy = &baremetalcontrollerv1.BootPolicySpec{}
+		}, wantErr: "spec.bootPolicy: Forbidden"},
+		{name: "boot device needs a bmc", mutate: func(s *baremetalcontrollerv1.Server) {
+			s.Spec.BootDevice = baremetalcontrollerv1.BootSourcePXE
+		}, wantErr: "spec.bootDevice: Forbidden"},
+		{name: "boot device with a boot policy", mutate: func(s *baremetalcontrollerv1.Server) {
+			s.Spec.Type = baremetalcontrollerv1.ControlTypeRedfish
+			s.Spec.Control = baremetalcontrollerv1.ControlSpecs{
+				Redfish: &baremetalcontrollerv1.RedfishSpecs{Address: "10.0.0.10", CredentialsSecretRef: secret},
+			}
+			s.Spec.BootDevice = baremetalcontrollerv1.BootSourceDisk
+			s.Spec.BootPolicy = &baremetalcontrollerv1.BootPolicySpec{}
+		}, wantErr: "spec.bootDevice: Forbidden"},
+		{name: "valid http health check", mutate: func(s *baremetalcontrollerv1.Server) {
+			s.Spec.HealthCheck = &baremetalcontrollerv1.HealthCheckSpec{Method: baremetalcontrollerv1.HealthCheckHTTP, Path: "/healthz"}
+		}},
+		{name: "tcp health check without a port", mutate: func(s *baremetalcontrollerv1.Server) {
+			s.Spec.HealthCheck = &baremetalcontrollerv1.HealthCheckSpec{Method: baremetalcontrollerv1.HealthCheckTCP}
+		}, wantErr: "spec.healthCheck.port: Required value"},
+		{name: "health check path without a slash", mutate: func(s *baremetalcontrollerv1.Server) {
+			s.Spec.HealthCheck = &baremetalcontrollerv1.HealthCheckSpec{Method: baremetalcontrollerv1.HealthCheckHTTP, Path: "healthz"}
+		}, wantErr: "spec.healthCheck.path: Invalid value"},
+		{name: "ssh health check through a relay", mutate: func(s *baremetalcontrollerv1.Server) {
+			s.Spec.Control.WOL.Relay = "rack-b"
+			s.Spec.HealthCheck = &baremetalcontrollerv1.HealthCheckSpec{Method: baremetalcontrollerv1.HealthCheckSSH}
+		}, wantErr: "spec.healthCheck.method: Forbidden"},
+		{name: "reboot unsupported", mutate: func(s *baremetalcontrollerv1.Server) {
+			s.Spec.Type = baremetalcontrollerv1.ControlTypeEquinix
+			s.Spec.Control = baremetalcontrollerv1.ControlSpecs{
+				Equinix: &baremetalcontrollerv1.EquinixSpecs{Address: "10.0.0.10", ProjectID: "p", APITokenSecretRef: secret},
+			}
+			s.Spec.PowerState = baremetalcontrollerv1.PowerStateReboot
+		}, wantErr: "spec.powerState: Unsupported value"},
+		{name: "esxi without a power on backend", mutate: func(s *baremetalcontrollerv1.Server) {
+			s.Spec.Type = baremetalcontrollerv1.ControlTypeESXi
+			s.Spec.Control = baremetalcontrollerv1.ControlSpecs{
+				ESXi: &baremetalcontrollerv1.ESXiSpecs{Address: "https://esxi-1.lab.example", CredentialsSecretRef: secret},
+			}
+		}, wantErr: "spec.control: Required value"},
+		{name: "bad tinkerbell mac", mutate: func(s *baremetalcontrollerv1.Server) {
+			s.Spec.Provisioning = &baremetalcontrollerv1.ProvisioningSpec{
+				Tinkerbell: &baremetalcontrollerv1.TinkerbellSpec{MACAddress: "zz:11:22:33:44:55"},
+			}
+		}, wantErr: "spec.provisioning.tinkerbell.macAddress: Invalid value"},
+	}
+
+	v := &ServerCustomValidator{}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := v.ValidateCreate(context.Background(), wolServer(tc.mutate))
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !apierrors.IsInvalid(err) {
+				t.Fatalf("expected an Invalid error, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("error %q does not contain %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateCreateUniqueName(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := baremetalcontrollerv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	existing := wolServer(nil)
+	existing.Namespace = "team-a"
+	reader := index.NewFakeClientBuilder(scheme).WithObjects(existing).Build()
+	v := &ServerCustomValidator{Reader: reader}
+
+	cases := []struct {
+		name      string
+		namespace string
+		rename    string
+		wantErr   string
+	}{
+		{name: "same name in another namespace", namespace: "team-b", wantErr: "name is already in use"},
+		{name: "other name", namespace: "team-b", rename: "test-server-2"},
+		// Cluster-scoped names are unique already
+		{name: "cluster-scoped", namespace: ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := wolServer(func(s *baremetalcontrollerv1.Server) {
+				s.Namespace = tc.namespace
+				if tc.rename != "" {
+					s.Name = tc.rename
+				}
+			})
+			_, err := v.ValidateCreate(context.Background(), server)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !apierrors.IsInvalid(err) || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("error %v does not contain %q", err, tc.wantErr)
+			}
+			// The other tenant's namespace isn't revealed
+			if strings.Contains(err.Error(), "team-a") {
+				t.Errorf("error %v names the namespace of the other server", err)
+			}
+		})
+	}
+}
+
+func TestValidateUpdate(t *testing.T) {
+	toIPMI := func(s *baremetalcontrollerv1.Server) {
+		s.Spec.Type = baremetalcontrollerv1.ControlTypeIPMI
+		s.Spec.Control.IPMI = &baremetalcontrollerv1.IPMISpecs{
+			Address: "10.0.0.10",
+			CredentialsSecretRef: &baremetalcontrollerv1.CredentialsSecretReference{
+				SecretReference: baremetalcontrollerv1.SecretReference{Name: "creds", Namespace: "default"},
+			},
+		}
+	}
+	cases := []struct {
+		name    string
+		status  baremetalcontrollerv1.CurrentStatus
+		old     func(*baremetalcontrollerv1.Server)
+		mutate  func(*baremetalcontrollerv1.Server)
+		wantErr string
+	}{
+		{name: "type change while active", status: baremetalcontrollerv1.StatusActive, mutate: toIPMI},
+		{name: "type change while pending", status: baremetalcontrollerv1.StatusPending, mutate: toIPMI,
+			wantErr: "spec.type: Forbidden"},
+		{name: "type change while rebooting", status: baremetalcontrollerv1.StatusRebooting, mutate: toIPMI,
+			wantErr: "spec.type: Forbidden"},
+		{name: "power change while pending", status: baremetalcontrollerv1.StatusPending, mutate: func(s *baremetalcontrollerv1.Server) {
+			s.Spec.PowerState = baremetalcontrollerv1.PowerStateOff
+		}},
+		{name: "invalid spec left alone", status: baremetalcontrollerv1.StatusFailed,
+			old: func(s *baremetalcontrollerv1.Server) {
+				s.Spec.Control.WOL = nil
+			},
+			mutate: func(s *baremetalcontrollerv1.Server) {
+				s.Spec.Control.WOL = nil
+				s.Labels = map[string]string{"rack": "a"}
+			}},
+		{name: "invalid spec defaulted", status: baremetalcontrollerv1.StatusFailed,
+			old: func(s *baremetalcontrollerv1.Server) {
+				s.Spec.PowerState = baremetalcontrollerv1.PowerStateReboot
+			},
+			mutate: func(s *baremetalcontrollerv1.Server) {
+				s.Spec.PowerState = baremetalcontrollerv1.PowerStateReboot
+				s.Spec.Control.WOL.Port = 9
+				s.Spec.Control.WOL.BroadcastAddress = "10.20.0.255"
+			}},
+	}
+
+	v := &ServerCustomValidator{Defaulter: &ServerCustomDefaulter{DefaultBroadcastAddress: "10.20.0.255"}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			old := wolServer(tc.old)
+			old.Status.Status = tc.status
+			server := wolServer(tc.mutate)
+			_, err := v.ValidateUpdate(context.Background(), old, server)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("error %v does not contain %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestDefault(t *testing.T) {
+	secret := &baremetalcontrollerv1.SecretReference{Name: "creds", Namespace: "default"}
+	cases := []struct {
+		name          string
+		spec          baremetalcontrollerv1.ServerSpec
+		wantPower     baremetalcontrollerv1.PowerState
+		wantType      baremetalcontrollerv1.ControlType
+		wantPort      int
+		wantBroadcast string
+	}{
+		{
+			name: "wol",
+			spec: baremetalcontrollerv1.ServerSpec{
+				Control: baremetalcontrollerv1.ControlSpecs{
+					WOL: &baremetalcontrollerv1.WOLSpecs{MACAddress: "00:11:22:33:44:55"},
+				},
+			},
+			wantPower:     baremetalcontrollerv1.PowerStateOff,
+			wantType:      baremetalcontrollerv1.ControlTypeWOL,
+			wantPort:      9,
+			wantBroadcast: "10.20.0.255",
+		},
+		{
+			name: "explicit values are kept",
+			spec: baremetalcontrollerv1.ServerSpec{
+				PowerState: baremetalcontrollerv1.PowerStateOn,
+				Type:       baremetalcontrollerv1.ControlTypeWOL,
+				Control: baremetalcontrollerv1.ControlSpecs{
+					WOL: &baremetalcontrollerv1.WOLSpecs{MACAddress: "00:11:22:33:44:55", Port: 7, BroadcastAddress: "10.30.0.255"},
+				},
+			},
+			wantPower:     baremetalcontrollerv1.PowerStateOn,
+			wantType:      baremetalcontrollerv1.ControlTypeWOL,
+			wantPort:      7,
+			wantBroadcast: "10.30.0.255",
+		},
+		{
+			name: "relayed wol keeps the relay's broadcast address",
+			spec: baremetalcontrollerv1.ServerSpec{
+				Control: baremetalcontrollerv1.ControlSpecs{
+					WOL: &baremetalcontrollerv1.WOLSpecs{MACAddress: "00:11:22:33:44:55", Relay: "rack-b"},
+				},
+			},
+			wantPower: baremetalcontrollerv1.PowerStateOff,
+			wantType:  baremetalcontrollerv1.ControlTypeWOL,
+			wantPort:  9,
+		},
+		{
+			name: "esxi powered on over wol",
+			spec: baremetalcontrollerv1.ServerSpec{
+				Control: baremetalcontrollerv1.ControlSpecs{
+					ESXi: &baremetalcontrollerv1.ESXiSpecs{Address: "esxi-1.lab.example", CredentialsSecretRef: secret},
+					WOL:  &baremetalcontrollerv1.WOLSpecs{MACAddress: "00:11:22:33:44:55"},
+				},
+			},
+			wantPower:     baremetalcontrollerv1.PowerStateOff,
+			wantType:      baremetalcontrollerv1.ControlTypeESXi,
+			wantPort:      9,
+			wantBroadcast: "10.20.0.255",
+		},
+		{
+			name: "ambiguous type",
+			spec: baremetalcontrollerv1.ServerSpec{
+				Control: baremetalcontrollerv1.ControlSpecs{
+					Redfish: &baremetalcontrollerv1.RedfishSpecs{Address: "10.0.0.10", CredentialsSecretRef: secret},
+					IPMI:    &baremetalcontrollerv1.IPMISpecs{Address: "10.0.0.10"},
+				},
+			},
+			wantPower: baremetalcontrollerv1.PowerStateOff,
+		},
+	}
+
+	d := &ServerCustomDefaulter{DefaultBroadcastAddress: "10.20.0.255"}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := &baremetalcontrollerv1.Server{Spec: tc.spec}
+			if err := d.Default(context.Background(), server); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if server.Spec.PowerState != tc.wantPower {
+				t.Errorf("powerState = %q, want %q", server.Spec.PowerState, tc.wantPower)
+			}
+			if server.Spec.Type != tc.wantType {
+				t.Errorf("type = %q, want %q", server.Spec.Type, tc.wantType)
+			}
+			if wol := server.Spec.Control.WOL; wol != nil {
+				if wol.Port != tc.wantPort {
+					t.Errorf("port = %d, want %d", wol.Port, tc.wantPort)
+				}
+				if wol.BroadcastAddress != tc.wantBroadcast {
+					t.Errorf("broadcastAddress = %q, want %q", wol.BroadcastAddress, tc.wantBroadcast)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bmc
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Unbounder1/bare-metal-controller/test/utils"
+)
+
+var (
+	// Optional Environment Variables:
+	// - KIND_CLUSTER: kind cluster the tests run in, created if it doesn't
+	//   exist (default "bmc-e2e").
+	// - E2E_KEEP_CLUSTER=true: keeps a cluster the suite created, e.g. to
+	//   look at the controller's logs after a failure.
+	kindCluster      = "bmc-e2e"
+	keepKindCluster  = os.Getenv("E2E_KEEP_CLUSTER") == "true"
+	kindClusterOwned = false
+
+	// managerBaseImage is the image built by make docker-build, and
+	// managerImage the one from Dockerfile.manager the tests deploy
+	managerBaseImage = "example.com/bare-metal-controller:v0.0.1"
+	managerImage     = "example.com/bare-metal-controller:e2e-bmc"
+
+	// ipmiEmulator and redfishEmulator each emulate one host, behind
+	// virtualbmc and sushy-emulator
+	ipmiEmulator    *utils.Emulator
+	redfishEmulator *utils.Emulator
+)
+
+// namespace where the project is deployed in
+const namespace = "bare-metal-controller-system"
+
+// kindNetwork is the docker network kind puts its nodes on
+const kindNetwork = "kind"
+
+// TestBMC runs the BMC end-to-end suite, which deploys the controller to a
+// kind cluster and powers emulated hosts on and off through real IPMI and
+// Redfish BMC emulators. It needs docker and kind.
+func TestBMC(t *testing.T) {
+	RegisterFailHandler(Fail)
+	_, _ = fmt.Fprintf(GinkgoWriter, "Starting bare-metal-controller BMC e2e test suite\n")
+	RunSpecs(t, "BMC e2e suite")
+}
+
+var _ = BeforeSuite(func() {
+	if v, ok := os.LookupEnv("KIND_CLUSTER"); ok {
+		kindCluster = v
+	}
+	// LoadImageToKindClusterWithName reads the cluster from the environment
+	Expect(os.Setenv("KIND_CLUSTER", kindCluster)).To(Succeed())
+
+	By("creating the kind cluster")
+	var err error
+	kindClusterOwned, err = utils.CreateKindCluster(kindCluster)
+	Expect(err).NotTo(HaveOccurred(), "Failed to create the kind cluster")
+
+	By("building the manager image")
+	cmd := exec.Command("make", "docker-build", fmt.Sprintf("IMG=%s", managerBaseImage))
+	_, err = utils.Run(cmd)
+	Expect(err).NotTo(HaveOccurred(), "Failed to build the manager image")
+	cmd = exec.Command("docker", "build", "-f", "test/e2e/bmc/Dockerfile.manager",
+		"--build-arg", "MANAGER_IMAGE="+managerBaseImage, "-t", managerImage, "test/e2e/bmc")
+	_, err = utils.Run(cmd)
+	Expect(err).NotTo(HaveOccurred(), "Failed to build the e2e manager image")
+	Expect(utils.LoadImageToKindClusterWithName(managerImage)).To(Succeed(), "Failed to load the manager image into kind")
+
+	By("starting the BMC emulators")
+	Expect(utils.BuildEmulatorImage()).To(Succeed(), "Failed to build the BMC emulator image")
+	ipmiEmulator, err = utils.StartEmulator(kindCluster+"-ipmi", "ipmi", kindNetwork)
+	Expect(err).NotTo(HaveOccurred(), "Failed to start the IPMI emulator")
+	redfishEmulator, err = utils.StartEmulator(kindCluster+"-redfish", "redfish", kindNetwork)
+	Expect(err).NotTo(HaveOccurred(), "Failed to start the Redfish emulator")
+	Eventually(ipmiEmulator.Ready, 2*time.Minute, time.Second).Should(BeTrue(), "IPMI emulator didn't start")
+	Eventually(redfishEmulator.Ready, 2*time.Minute, time.Second).Should(BeTrue(), "Redfish emulator didn't start")
+
+	By("deploying the controller-manager")
+	cmd = exec.Command("kubectl", "apply", "--server-side", "-k", "test/e2e/bmc/config")
+	_, err = utils.Run(cmd)
+	Expect(err).NotTo(HaveOccurred(), "Failed to deploy the controller-manager")
+	cmd = exec.Command("kubectl", "rollout", "status", "deployment/bare-metal-controller-controller-manager",
+		"-n", namespace, "--timeout=3m")
+	_, err = utils.Run(cmd)
+	Expect(err).NotTo(HaveOccurred(), "Controller-manager didn't become ready")
+})
+
+var _ = AfterSuite(func() {
+	By("undeploying the controller-manager")
+	cmd := exec.Command("kubectl", "delete", "--ignore-not-found", "-k", "test/e2e/bmc/config")
+	_, _ = utils.Run(cmd)
+
+	By("removing the BMC emulators")
+	for _, emulator := range []*utils.Emulator{ipmiEmulator, redfishEmulator} {
+		if emulator != nil {
+			emulator.Remove()
+		}
+	}
+
+	if kindClusterOwned && !keepKindCluster {
+		By("deleting the kind cluster")
+		utils.DeleteKindCluster(kindCluster)
+	}
+})
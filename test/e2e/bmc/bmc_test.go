@@ -0,0 +1,216 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bmc
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Unbounder1/bare-metal-controller/test/utils"
+)
+
+// powerTimeout bounds each power change, including the controller noticing
+// that the host answers pings or stopped answering them
+const powerTimeout = 3 * time.Minute
+
+// serverManifests render the Server of each emulator protocol, with the
+// emulator's address and the desired power state
+var serverManifests = map[string]string{
+	"ipmi": `apiVersion: bare-metal-controller.bare-metal.io/v1
+kind: Server
+metadata:
+  name: %s
+spec:
+  powerState: "%s"
+  type: ipmi
+  reconcileInterval: 10s
+  control:
+    ipmi:
+      address: %s
+      username: admin
+      password: password
+`,
+	"redfish": `apiVersion: bare-metal-controller.bare-metal.io/v1
+kind: Server
+metadata:
+  name: %s
+spec:
+  powerState: "%s"
+  type: redfish
+  reconcileInterval: 10s
+  control:
+    redfish:
+      address: http://%s:8000
+      credentialsSecretRef:
+        name: bmc-credentials
+        namespace: ` + namespace + `
+`,
+}
+
+var _ = Describe("BMC flows", Ordered, func() {
+	BeforeAll(func() {
+		By("creating the Redfish credentials")
+		cmd := exec.Command("kubectl", "create", "secret", "generic", "bmc-credentials", "-n", namespace,
+			"--from-literal=username=admin", "--from-literal=password=password")
+		_, err := utils.Run(cmd)
+		Expect(err).NotTo(HaveOccurred(), "Failed to create the Redfish credentials")
+	})
+
+	AfterAll(func() {
+		cmd := exec.Command("kubectl", "delete", "secret", "bmc-credentials", "-n", namespace, "--ignore-not-found")
+		_, _ = utils.Run(cmd)
+	})
+
+	AfterEach(func() {
+		if CurrentSpecReport().Failed() {
+			By("fetching the controller logs")
+			cmd := exec.Command("kubectl", "logs", "deployment/bare-metal-controller-controller-manager",
+				"-n", namespace, "--tail=200")
+			if logs, err := utils.Run(cmd); err == nil {
+				_, _ = fmt.Fprintf(GinkgoWriter, "Controller logs:\n%s", logs)
+			}
+		}
+	})
+
+	for _, protocol := range []string{"ipmi", "redfish"} {
+		protocol := protocol
+		serverName := "e2e-" + protocol
+
+		Context("with an "+protocol+" BMC", Ordered, func() {
+			var emulator *utils.Emulator
+
+			BeforeAll(func() {
+				emulator = ipmiEmulator
+				if protocol == "redfish" {
+					emulator = redfishEmulator
+				}
+			})
+
+			AfterAll(func() {
+				cmd := exec.Command("kubectl", "delete", "server", serverName, "--ignore-not-found", "--wait=false")
+				_, _ = utils.Run(cmd)
+			})
+
+			It("should power the server on", func() {
+				By("creating the Server")
+				manifest := fmt.Sprintf(serverManifests[protocol], serverName, "on", emulator.IP)
+				Expect(applyManifest(manifest)).To(Succeed())
+
+				Eventually(emulator.PowerState, powerTimeout, time.Second).Should(Equal("running"))
+				Eventually(serverStatus(serverName), powerTimeout, 2*time.Second).Should(Equal("active"))
+			})
+
+			It("should power the server off", func() {
+				Expect(setPowerState(serverName, "off")).To(Succeed())
+
+				Eventually(emulator.PowerState, powerTimeout, time.Second).Should(Equal("shut off"))
+				Eventually(serverStatus(serverName), powerTimeout, 2*time.Second).Should(Equal("offline"))
+			})
+
+			It("should reboot the server with a power action", func() {
+				Expect(setPowerState(serverName, "on")).To(Succeed())
+				Eventually(serverStatus(serverName), powerTimeout, 2*time.Second).Should(Equal("active"))
+
+				By("cycling the server")
+				actionName := serverName + "-reboot"
+				Expect(applyManifest(fmt.Sprintf(`apiVersion: bare-metal-controller.bare-metal.io/v1
+kind: PowerAction
+metadata:
+  name: %s
+spec:
+  action: cycle
+  servers: [%s]
+`, actionName, serverName))).To(Succeed())
+				DeferCleanup(func() {
+					_, _ = utils.Run(exec.Command("kubectl", "delete", "poweraction", actionName, "--ignore-not-found"))
+				})
+
+				Eventually(jsonPath("poweraction", actionName, "{.status.phase}"), 2*powerTimeout, 2*time.Second).
+					Should(Equal("Succeeded"))
+				Expect(emulator.PowerState()).To(Equal("running"))
+				Eventually(serverStatus(serverName), powerTimeout, 2*time.Second).Should(Equal("active"))
+			})
+
+			if protocol != "ipmi" {
+				return
+			}
+
+			It("should recover a server whose BMC stopped answering", func() {
+				By("stopping the BMC while the host keeps running")
+				Expect(emulator.StopBMC()).To(Succeed())
+				DeferCleanup(emulator.StartBMC)
+				Expect(setPowerState(serverName, "off")).To(Succeed())
+
+				By("waiting for the power off to fail and the BMC to be reset")
+				Eventually(jsonPath("server", serverName, "{.status.reason}"), powerTimeout, 2*time.Second).
+					Should(Equal("BMCUnreachable"))
+				Eventually(serverStatus(serverName), powerTimeout, 2*time.Second).Should(Equal("failed"))
+				Eventually(func() (int, error) {
+					count, err := jsonPath("server", serverName, "{.status.bmcReset.count}")()
+					if err != nil || count == "" {
+						return 0, err
+					}
+					return strconv.Atoi(count)
+				}, powerTimeout, 2*time.Second).Should(BeNumerically(">=", 1))
+
+				By("bringing the BMC back, as a cold reset would")
+				Expect(emulator.StartBMC()).To(Succeed())
+
+				Eventually(emulator.PowerState, 2*powerTimeout, 2*time.Second).Should(Equal("shut off"))
+				Eventually(serverStatus(serverName), powerTimeout, 2*time.Second).Should(Equal("offline"))
+				Expect(jsonPath("server", serverName, "{.status.reason}")()).To(BeEmpty())
+			})
+		})
+	}
+})
+
+// applyManifest applies a YAML manifest with kubectl
+func applyManifest(manifest string) error {
+	cmd := exec.Command("kubectl", "apply", "-f", "-")
+	cmd.Stdin = strings.NewReader(manifest)
+	_, err := utils.Run(cmd)
+	return err
+}
+
+// setPowerState patches the desired power state of a Server
+func setPowerState(name, state string) error {
+	cmd := exec.Command("kubectl", "patch", "server", name, "--type=merge",
+		"-p", fmt.Sprintf(`{"spec":{"powerState":%q}}`, state))
+	_, err := utils.Run(cmd)
+	return err
+}
+
+// jsonPath returns a function reading a field of a cluster-scoped object,
+// for use with Eventually
+func jsonPath(kind, name, path string) func() (string, error) {
+	return func() (string, error) {
+		cmd := exec.Command("kubectl", "get", kind, name, "-o", "jsonpath="+path)
+		output, err := utils.Run(cmd)
+		return strings.TrimSpace(output), err
+	}
+}
+
+// serverStatus returns a function reading the status of a Server
+func serverStatus(name string) func() (string, error) {
+	return jsonPath("server", name, "{.status.status}")
+}
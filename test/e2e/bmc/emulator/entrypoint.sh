@@ -0,0 +1,39 @@
+#!/bin/sh
+# Starts libvirtd, the emulated host and the BMC for BMC_PROTOCOL (ipmi or
+# redfish). The BMC runs in the background so the tests can stop and start
+# it while the host keeps running. BMC_USERNAME and BMC_PASSWORD only apply
+# to IPMI.
+set -e
+
+BMC_USERNAME=${BMC_USERNAME:-admin}
+BMC_PASSWORD=${BMC_PASSWORD:-password}
+URI=test+unix:///default
+
+mkdir -p /run/libvirt
+libvirtd --daemon
+until virsh -c "$URI" version >/dev/null 2>&1; do sleep 0.5; done
+
+python3 /usr/local/bin/mirror.py &
+mirror=$!
+until [ -f /run/bmc-emulator/ready ]; do sleep 0.5; done
+
+case "$BMC_PROTOCOL" in
+ipmi)
+    vbmcd
+    until vbmc list >/dev/null 2>&1; do sleep 0.5; done
+    vbmc add host --port 623 --username "$BMC_USERNAME" --password "$BMC_PASSWORD" --libvirt-uri "$URI"
+    vbmc start host
+    ;;
+redfish)
+    # Without an auth file, sushy-emulator accepts any credentials
+    printf '%s\n' "SUSHY_EMULATOR_LIBVIRT_URI = '$URI'" > /etc/bmc-emulator/sushy.conf
+    sushy-emulator --interface 0.0.0.0 --port 8000 --config /etc/bmc-emulator/sushy.conf &
+    ;;
+*)
+    echo "BMC_PROTOCOL must be ipmi or redfish" >&2
+    exit 1
+    ;;
+esac
+
+echo "BMC emulator ready"
+wait "$mirror"
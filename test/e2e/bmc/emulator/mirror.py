@@ -0,0 +1,59 @@
+#!/usr/bin/env python3
+"""Defines the emulated host and mirrors its power state into reachability.
+
+The test driver keeps its state only while a connection to it is open, so
+this process holds one for the lifetime of the container. While the host is
+powered off, pings to the container are dropped, like a real server whose
+OS is down, while its BMC keeps answering.
+"""
+
+import os
+import subprocess
+import sys
+import time
+
+import libvirt
+
+URI = "test+unix:///default"
+READY = "/run/bmc-emulator/ready"
+DROP_PINGS = ["INPUT", "-p", "icmp", "--icmp-type", "echo-request", "-j", "DROP"]
+
+
+def iptables(action, rule):
+    return subprocess.run(["iptables", action] + rule, capture_output=True).returncode == 0
+
+
+def set_reachable(reachable):
+    dropping = iptables("-C", DROP_PINGS)
+    if reachable and dropping:
+        iptables("-D", DROP_PINGS)
+    elif not reachable and not dropping:
+        iptables("-I", DROP_PINGS)
+
+
+def main():
+    conn = libvirt.open(URI)
+    # The test driver starts with a running example domain, which would be
+    # the first system sushy-emulator lists
+    for domain in conn.listAllDomains():
+        if domain.name() != "host":
+            if domain.isActive():
+                domain.destroy()
+            domain.undefine()
+    try:
+        host = conn.lookupByName("host")
+    except libvirt.libvirtError:
+        with open("/etc/bmc-emulator/host.xml") as f:
+            host = conn.defineXML(f.read())
+
+    os.makedirs(os.path.dirname(READY), exist_ok=True)
+    open(READY, "w").close()
+    print("emulated host defined", flush=True)
+
+    while True:
+        set_reachable(host.isActive() == 1)
+        time.sleep(1)
+
+
+if __name__ == "__main__":
+    sys.exit(main())
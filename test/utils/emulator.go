@@ -0,0 +1,126 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// EmulatorImage is the BMC emulator image built from test/e2e/bmc/emulator
+const EmulatorImage = "bare-metal-controller-bmc-emulator:e2e"
+
+// emulatorURI is the libvirt connection of the emulated host
+const emulatorURI = "test+unix:///default"
+
+// Emulator is a BMC emulator container on the kind network, emulating one
+// host behind an IPMI or Redfish BMC
+type Emulator struct {
+	Name     string
+	Protocol string
+	// IP is the container's address on the kind network, where the
+	// controller reaches the BMC and pings the host
+	IP string
+}
+
+// BuildEmulatorImage builds the BMC emulator image
+func BuildEmulatorImage() error {
+	cmd := exec.Command("docker", "build", "-t", EmulatorImage, "test/e2e/bmc/emulator")
+	_, err := Run(cmd)
+	return err
+}
+
+// StartEmulator starts an emulator for protocol, ipmi or redfish, on the
+// docker network of the kind cluster and waits until its BMC is up
+func StartEmulator(name, protocol, network string) (*Emulator, error) {
+	cmd := exec.Command("docker", "run", "-d", "--name", name, "--network", network,
+		"--cap-add", "NET_ADMIN", "-e", "BMC_PROTOCOL="+protocol, EmulatorImage)
+	if _, err := Run(cmd); err != nil {
+		return nil, err
+	}
+
+	cmd = exec.Command("docker", "inspect", "-f",
+		fmt.Sprintf("{{(index .NetworkSettings.Networks %q).IPAddress}}", network), name)
+	output, err := Run(cmd)
+	if err != nil {
+		return nil, err
+	}
+	emulator := &Emulator{Name: name, Protocol: protocol, IP: strings.TrimSpace(output)}
+	if emulator.IP == "" {
+		return nil, fmt.Errorf("emulator %s has no address on network %s", name, network)
+	}
+	return emulator, nil
+}
+
+// Ready reports whether the emulator has started its BMC
+func (e *Emulator) Ready() bool {
+	output, err := Run(exec.Command("docker", "logs", e.Name))
+	return err == nil && strings.Contains(output, "BMC emulator ready")
+}
+
+// PowerState returns the power state of the emulated host as libvirt
+// reports it, e.g. "running" or "shut off"
+func (e *Emulator) PowerState() (string, error) {
+	output, err := Run(exec.Command("docker", "exec", e.Name, "virsh", "-c", emulatorURI, "domstate", "host"))
+	return strings.TrimSpace(output), err
+}
+
+// StopBMC stops the IPMI BMC while the host keeps running and answering
+// pings, which is what a wedged BMC looks like
+func (e *Emulator) StopBMC() error {
+	_, err := Run(exec.Command("docker", "exec", e.Name, "vbmc", "stop", "host"))
+	return err
+}
+
+// StartBMC starts the IPMI BMC again
+func (e *Emulator) StartBMC() error {
+	_, err := Run(exec.Command("docker", "exec", e.Name, "vbmc", "start", "host"))
+	return err
+}
+
+// Remove removes the emulator container
+func (e *Emulator) Remove() {
+	if _, err := Run(exec.Command("docker", "rm", "-f", e.Name)); err != nil {
+		warnError(err)
+	}
+}
+
+// CreateKindCluster creates a kind cluster unless one with the name exists,
+// and makes it kubectl's current context. It returns whether the cluster
+// was created.
+func CreateKindCluster(name string) (bool, error) {
+	output, err := Run(exec.Command("kind", "get", "clusters"))
+	if err != nil {
+		return false, err
+	}
+	for _, cluster := range GetNonEmptyLines(output) {
+		if cluster == name {
+			_, err := Run(exec.Command("kind", "export", "kubeconfig", "--name", name))
+			return false, err
+		}
+	}
+	_, err = Run(exec.Command("kind", "create", "cluster", "--name", name))
+	return err == nil, err
+}
+
+// DeleteKindCluster deletes a kind cluster
+func DeleteKindCluster(name string) {
+	if _, err := Run(exec.Command("kind", "delete", "cluster", "--name", name)); err != nil {
+		warnError(err)
+	}
+}
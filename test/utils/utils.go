@@ -197,7 +197,10 @@ func GetProjectDir() (string, error) {
 	if err != nil {
 		return wd, err
 	}
-	wd = strings.Replace(wd, "/test/e2e", "", -1)
+	// Suites live in test/e2e and directories below it
+	if i := strings.Index(wd, "/test/e2e"); i >= 0 {
+		wd = wd[:i]
+	}
 	return wd, nil
 }
 